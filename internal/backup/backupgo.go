@@ -6,6 +6,7 @@ import (
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/models"
 	"Q115-STRM/internal/synccron"
+	ws "Q115-STRM/internal/websocket"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -48,6 +49,13 @@ func SetRunningResult(t string, desc string, total int, count int, errorMsg stri
 	runningResult.ErrorMsg = errorMsg
 	runningResult.IsRunning = IsRunning()
 	runningResult.Elapsed = time.Since(runningResult.StartTime).Seconds()
+	ws.BroadcastEvent(ws.EventBackupProgress, ws.BackupProgressData{
+		Type:  t,
+		Desc:  desc,
+		Total: total,
+		Count: count,
+		Error: errorMsg,
+	})
 }
 
 func IsRunning() bool {
@@ -114,7 +122,7 @@ func startAllTasks() error {
 // 然后将运行中状态为1
 
 // 遍历每一个模型，生成json格式的备份文件
-func Backup(backupType string, reason string) error {
+func Backup(backupType string, reason string) (err error) {
 	totalTable := len(models.AllTables)
 	count := 0
 	// config := models.GetOrCreateBackupConfig()
@@ -127,6 +135,13 @@ func Backup(backupType string, reason string) error {
 	}
 	SetRunning(true)
 	defer SetRunning(false)
+	defer func() {
+		data := ws.BackupCompleteData{BackupType: backupType, Success: err == nil}
+		if err != nil {
+			data.Error = err.Error()
+		}
+		ws.BroadcastEvent(ws.EventBackupComplete, data)
+	}()
 	SetRunningResult("backup", fmt.Sprintf("开始%s备份", backupType), totalTable, count, "", true)
 	// 清理旧备份
 	models.GetBackupService().CleanupOldBackups()
@@ -305,7 +305,7 @@ func CalculateChunkSizeByFileSize(fileSize int64) int64 {
 }
 
 // 预上传
-func (c *Client) PreCreate(ctx context.Context, localPath string, remotePath string) (*openapiclient.Fileprecreateresponse, *helpers.FileChunkMD5Result, error) {
+func (c *Client) PreCreate(ctx context.Context, localPath string, remotePath string, rtype int32) (*openapiclient.Fileprecreateresponse, *helpers.FileChunkMD5Result, error) {
 	stat, err := os.Stat(localPath)
 	if err != nil {
 		return nil, nil, fmt.Errorf("本地文件不存在")
@@ -327,7 +327,7 @@ func (c *Client) PreCreate(ctx context.Context, localPath string, remotePath str
 	}
 	chunkMD5s := string(chunkMD5sJson)
 	chunkMD5.ChunkMD5sJsonStr = chunkMD5s
-	req := c.client.FileuploadApi.Xpanfileprecreate(ctx).AccessToken(c.accessToken).Path(remotePath).Size(int32(size)).Isdir(0).Autoinit(1).Rtype(2).BlockList(chunkMD5s)
+	req := c.client.FileuploadApi.Xpanfileprecreate(ctx).AccessToken(c.accessToken).Path(remotePath).Size(int32(size)).Isdir(0).Autoinit(1).Rtype(rtype).BlockList(chunkMD5s)
 	resp, r, err := req.Execute()
 	// 统一处理错误
 	if c.handleError(err, r, resp) != nil {
@@ -337,9 +337,15 @@ func (c *Client) PreCreate(ctx context.Context, localPath string, remotePath str
 }
 
 // 上传文件
-func (c *Client) Upload(ctx context.Context, localPath string, remotePath string) (*openapiclient.Filecreateresponse, error) {
+// overwrite 为true时覆盖同名文件，否则同名文件内容不同时自动重命名
+func (c *Client) Upload(ctx context.Context, localPath string, remotePath string, overwrite bool) (*openapiclient.Filecreateresponse, error) {
+	// 文件命名策略：2 路径冲突且block_list不同才重命名，3 覆盖
+	var rtype int32 = 2
+	if overwrite {
+		rtype = 3
+	}
 	// 预上传
-	preResp, chunkMD5, err := c.PreCreate(ctx, localPath, remotePath)
+	preResp, chunkMD5, err := c.PreCreate(ctx, localPath, remotePath, rtype)
 	if err != nil {
 		return nil, fmt.Errorf("预上传失败：%w", err)
 	}
@@ -367,7 +373,7 @@ func (c *Client) Upload(ctx context.Context, localPath string, remotePath string
 		os.Remove(tempFilePath)
 	}
 	// 创建文件
-	resp, r, err := c.client.FileuploadApi.Xpanfilecreate(ctx).AccessToken(c.accessToken).Path(remotePath).Isdir(0).Size(int32(chunkMD5.FileSize)).Uploadid(*preResp.Uploadid).BlockList(chunkMD5.ChunkMD5sJsonStr).Rtype(2).Execute()
+	resp, r, err := c.client.FileuploadApi.Xpanfilecreate(ctx).AccessToken(c.accessToken).Path(remotePath).Isdir(0).Size(int32(chunkMD5.FileSize)).Uploadid(*preResp.Uploadid).BlockList(chunkMD5.ChunkMD5sJsonStr).Rtype(rtype).Execute()
 	// 统一处理错误
 	if c.handleError(err, r, resp) != nil {
 		return nil, fmt.Errorf("创建文件失败：%w", err)
@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/syncstrm"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FindDuplicates 查找重复文件
+// @Summary 查找重复文件
+// @Description 在后台按SHA1和TMDB ID（剧集还包括季和集）查找所有同步目录和刮削目录中的重复视频，按策略预选保留的文件，结果需要确认后才会删除
+// @Tags 重复文件
+// @Accept json
+// @Produce json
+// @Param policy body string false "保留策略：highest_resolution-保留分辨率最高的（默认） preferred_account-保留首选账号中的"
+// @Param preferred_account_id body integer false "首选账号ID，本地为0"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /duplicate/find [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func FindDuplicates(c *gin.Context) {
+	type findReq struct {
+		Policy             models.DuplicatePolicy `json:"policy"`
+		PreferredAccountId uint                   `json:"preferred_account_id"`
+	}
+	var req findReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "请求参数错误: " + err.Error(), Data: nil})
+		return
+	}
+	switch req.Policy {
+	case "":
+		req.Policy = models.DuplicatePolicyHighestResolution
+	case models.DuplicatePolicyHighestResolution:
+	case models.DuplicatePolicyPreferredAccount:
+		if req.PreferredAccountId == 0 {
+			c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "请选择首选账号", Data: nil})
+			return
+		}
+	default:
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "不支持的保留策略", Data: nil})
+		return
+	}
+	if err := syncstrm.StartFindDuplicates(req.Policy, req.PreferredAccountId); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "已开始查找重复文件", Data: nil})
+}
+
+// GetDuplicateStatus 获取重复文件任务状态
+// @Summary 获取重复文件任务状态
+// @Description 返回最近一次查找或删除任务的进度
+// @Tags 重复文件
+// @Accept json
+// @Produce json
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /duplicate/status [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetDuplicateStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "", Data: syncstrm.GetDuplicateJobStatus()})
+}
+
+// GetDuplicateGroups 获取重复文件组列表
+// @Summary 获取重复文件组列表
+// @Description 分页返回重复文件组和组内文件，按浪费的空间从大到小排列
+// @Tags 重复文件
+// @Accept json
+// @Produce json
+// @Param status query string false "状态：pending-待确认 deleting-正在删除 done-已删除 failed-删除失败 ignored-已忽略"
+// @Param match_type query string false "匹配方式：hash-SHA1 tmdb-TMDB ID"
+// @Param page query integer false "页码，默认1"
+// @Param pageSize query integer false "每页数量，默认20"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /duplicate/groups [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetDuplicateGroups(c *gin.Context) {
+	page := helpers.StringToInt(c.Query("page"))
+	if page == 0 {
+		page = 1
+	}
+	pageSize := helpers.StringToInt(c.Query("pageSize"))
+	if pageSize == 0 {
+		pageSize = 20
+	}
+	status := models.DuplicateGroupStatus(c.Query("status"))
+	matchType := models.DuplicateMatchType(c.Query("match_type"))
+	groups, total := models.GetDuplicateGroups(status, matchType, page, pageSize)
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "获取重复文件组成功", Data: map[string]any{"total": total, "list": groups}})
+}
+
+// GetDuplicateSummary 获取各账号的重复文件占用
+// @Summary 获取各账号的重复文件占用
+// @Description 按账号统计待确认和删除失败的重复文件组中不保留的文件数和占用空间
+// @Tags 重复文件
+// @Accept json
+// @Produce json
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /duplicate/summary [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetDuplicateSummary(c *gin.Context) {
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "", Data: models.GetDuplicateAccountSummary()})
+}
+
+// SetDuplicateKeepFiles 选择重复文件组中保留的文件
+// @Summary 选择保留的文件
+// @Description 修改自动选择的结果，至少保留一个文件
+// @Tags 重复文件
+// @Accept json
+// @Produce json
+// @Param group_id body integer true "重复文件组ID"
+// @Param file_ids body []integer true "保留的文件ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /duplicate/keep [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func SetDuplicateKeepFiles(c *gin.Context) {
+	type keepReq struct {
+		GroupId uint   `json:"group_id"`
+		FileIds []uint `json:"file_ids"`
+	}
+	var req keepReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "请求参数错误: " + err.Error(), Data: nil})
+		return
+	}
+	group := models.GetDuplicateGroupById(req.GroupId)
+	if group == nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "重复文件组不存在", Data: nil})
+		return
+	}
+	if err := group.SetKeepFiles(req.FileIds); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "保存成功", Data: group})
+}
+
+// IgnoreDuplicateGroups 忽略重复文件组
+// @Summary 忽略重复文件组
+// @Description 忽略后不会删除组内的文件，重新查找时也不再出现
+// @Tags 重复文件
+// @Accept json
+// @Produce json
+// @Param group_ids body []integer true "重复文件组ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /duplicate/ignore [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func IgnoreDuplicateGroups(c *gin.Context) {
+	type ignoreReq struct {
+		GroupIds []uint `json:"group_ids"`
+	}
+	var req ignoreReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "请求参数错误: " + err.Error(), Data: nil})
+		return
+	}
+	for _, id := range req.GroupIds {
+		group := models.GetDuplicateGroupById(id)
+		if group == nil || group.Status == models.DuplicateGroupStatusDeleting {
+			continue
+		}
+		group.UpdateStatus(models.DuplicateGroupStatusIgnored)
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "已忽略", Data: nil})
+}
+
+// DeleteDuplicates 删除重复文件
+// @Summary 删除重复文件
+// @Description 确认后在后台删除所选重复文件组中不保留的文件（网盘文件通过对应的网盘接口删除），并清理同步记录、STRM文件和刮削记录
+// @Tags 重复文件
+// @Accept json
+// @Produce json
+// @Param group_ids body []integer true "已确认的重复文件组ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /duplicate/delete [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func DeleteDuplicates(c *gin.Context) {
+	type deleteReq struct {
+		GroupIds []uint `json:"group_ids"`
+	}
+	var req deleteReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "请求参数错误: " + err.Error(), Data: nil})
+		return
+	}
+	if err := syncstrm.StartDeleteDuplicates(req.GroupIds); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "已开始删除，可以在重复文件组中查看每个文件的删除结果", Data: nil})
+}
@@ -429,7 +429,7 @@ func sendNewMovieNotification(itemId string) {
 	// seasonepisodes占位符替换为空
 	content = strings.ReplaceAll(content, "{{seasonepisodes}}", "")
 	helpers.AppLogger.Infof("已格式化完成通知内容 movieId=%s\n%s", itemId, content)
-	sendNewItemNotification(content, detail, "电影")
+	sendNewItemNotification(content, detail, "电影", &notification.DigestData{MediaType: "movie", MediaName: detail.Name})
 }
 
 func sendNewSeriesNotification(seriesId string, seasons map[int][]int) {
@@ -490,10 +490,10 @@ func sendNewSeriesNotification(seriesId string, seasons map[int][]int) {
 		seasonEpisodes = fmt.Sprintf("📺 入库季集: %s\n", seasonEpisodes)
 	}
 	content = strings.ReplaceAll(content, "⏰ 入库时间:", fmt.Sprintf("%s\n⏰ 入库时间: ", seasonEpisodes))
-	sendNewItemNotification(content, detail, "电视剧")
+	sendNewItemNotification(content, detail, "电视剧", &notification.DigestData{MediaType: "tvshow", MediaName: detail.Name, Episodes: []string{formatSeasonEpisodes(seasons)}})
 }
 
-func sendNewItemNotification(content string, detail *embyclientrestgo.BaseItemDtoV2, mediaType string) {
+func sendNewItemNotification(content string, detail *embyclientrestgo.BaseItemDtoV2, mediaType string, digest *notification.DigestData) {
 	imagePath := ""
 	if detail.ImageTags != nil {
 		imageUrl := ""
@@ -520,19 +520,28 @@ func sendNewItemNotification(content string, detail *embyclientrestgo.BaseItemDt
 		Content:   content,
 		Timestamp: time.Now(),
 		Priority:  models.NormalPriority,
+		Library:   models.GetEmbyLibraryNameByItemId(detail.Id),
+		Digest:    digest,
 	}
 	if imagePath != "" {
 		notif.Image = imagePath
+		notif.RemoveImage = true
 	}
 	if notificationmanager.GlobalEnhancedNotificationManager != nil {
 		if err := notificationmanager.GlobalEnhancedNotificationManager.SendNotification(context.Background(), notif); err != nil {
 			helpers.AppLogger.Errorf("发送媒体入库通知失败: %v", err)
 		}
+	} else {
+		removeNotificationImage(imagePath)
 	}
-	// 删除临时图片文件
-	if imagePath != "" {
-		os.Remove(imagePath)
+}
+
+// 通知在发送队列中异步发送，临时图片由发送队列在所有渠道发送完成后删除，没有启用通知时直接删除
+func removeNotificationImage(imagePath string) {
+	if imagePath == "" {
+		return
 	}
+	os.Remove(imagePath)
 }
 
 // 发送删除电影通知
@@ -544,6 +553,7 @@ func sendDeletedMovieNotification(itemId, itemName string) {
 		Content:   content,
 		Timestamp: time.Now(),
 		Priority:  models.NormalPriority,
+		Library:   models.GetEmbyLibraryNameByItemId(itemId),
 	}
 	if notificationmanager.GlobalEnhancedNotificationManager != nil {
 		if err := notificationmanager.GlobalEnhancedNotificationManager.SendNotification(context.Background(), notif); err != nil {
@@ -564,6 +574,7 @@ func sendDeletedSeriesNotification(seriesId string, seriesName string, seasons m
 		Content:   content,
 		Timestamp: time.Now(),
 		Priority:  models.NormalPriority,
+		Library:   models.GetEmbyLibraryNameByItemId(seriesId),
 	}
 	if notificationmanager.GlobalEnhancedNotificationManager != nil {
 		if err := notificationmanager.GlobalEnhancedNotificationManager.SendNotification(context.Background(), notif); err != nil {
@@ -671,16 +682,13 @@ func handlePlaybackEvent(body []byte, event EmbyEvent) {
 
 	// 构造并发送通知
 	notif := createPlaybackNotification(&playbackWebhook)
-	imagePath := notif.Image // 保存图片路径以便后续清理
+	notif.RemoveImage = notif.Image != ""
 	if notificationmanager.GlobalEnhancedNotificationManager != nil {
 		if err := notificationmanager.GlobalEnhancedNotificationManager.SendNotification(context.Background(), notif); err != nil {
 			helpers.AppLogger.Errorf("发送播放通知失败: %v", err)
 		}
-	}
-
-	// 删除临时图片文件
-	if imagePath != "" {
-		os.Remove(imagePath)
+	} else {
+		removeNotificationImage(notif.Image)
 	}
 }
 
@@ -723,6 +731,8 @@ func createPlaybackNotification(webhook *models.EmbyPlaybackWebhook) *notificati
 		Metadata:  metadata,
 		Timestamp: time.Now(),
 		Priority:  notification.NormalPriority,
+		Library:   models.GetEmbyLibraryNameByItemId(webhook.Item.ID),
+		User:      webhook.User.Name,
 	}
 
 	// 如果有图片，添加到通知
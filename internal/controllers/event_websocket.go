@@ -2,8 +2,12 @@ package controllers
 
 import (
 	"Q115-STRM/internal/websocket"
+	"encoding/json"
+	"fmt"
 	gorillaws "github.com/gorilla/websocket"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,17 +18,46 @@ var eventUpgrader = gorillaws.Upgrader{
 	},
 }
 
+// SSE连接的心跳间隔，避免代理因为长时间没有数据断开连接
+const sseHeartbeatInterval = 30 * time.Second
+
+// 解析客户端最后收到的事件ID，优先使用 since 参数，其次是SSE重连时浏览器带上的 Last-Event-ID 头
+func parseEventSince(c *gin.Context) (uint64, bool) {
+	since := c.Query("since")
+	if since == "" {
+		since = c.GetHeader("Last-Event-ID")
+	}
+	if since == "" {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(since, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
+
 // EventWebSocket WebSocket事件推送端点
+// @Summary WebSocket事件推送
+// @Description 推送任务、队列、限流、账号、备份和Emby同步事件，每个事件带有递增的id。传入since时先重放缓冲区中id大于since的事件，缓冲区中已经没有需要的事件时先推送 replay_truncated 事件
+// @Tags 事件推送
+// @Param since query integer false "最后收到的事件ID"
+// @Router /api/events/ws [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
 func EventWebSocket(c *gin.Context) {
+	since, replay := parseEventSince(c)
 	conn, err := eventUpgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		return
 	}
 
 	client := &websocket.Client{
-		Hub:  websocket.GlobalEventHub,
-		Conn: conn,
-		Send: make(chan []byte, 256),
+		Hub:    websocket.GlobalEventHub,
+		Conn:   conn,
+		Send:   make(chan []byte, 256+websocket.EventBufferSize),
+		Replay: replay,
+		Since:  since,
 	}
 
 	client.Hub.RegisterClient(client)
@@ -32,3 +65,74 @@ func EventWebSocket(c *gin.Context) {
 	go client.WritePump()
 	go client.ReadPump()
 }
+
+// EventStream SSE事件推送端点，供不方便使用WebSocket的客户端使用
+// @Summary SSE事件推送
+// @Description 和WebSocket推送相同的事件，使用Server-Sent Events格式，每条消息的id为事件ID，data为事件JSON。断线重连时浏览器会自动带上Last-Event-ID重放错过的事件
+// @Tags 事件推送
+// @Produce text/event-stream
+// @Param since query integer false "最后收到的事件ID"
+// @Router /api/events/sse [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func EventStream(c *gin.Context) {
+	since, replay := parseEventSince(c)
+	if !replay {
+		// 不重放时只推送之后的事件
+		since = websocket.LastEventID()
+	}
+	events, ch, unsubscribe := websocket.SubscribeSince(since, 256)
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	fmt.Fprint(w, "retry: 3000\n\n")
+	for _, event := range events {
+		if writeSSEEvent(w, event) != nil {
+			return
+		}
+	}
+	w.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-ch:
+			if !ok {
+				// 处理太慢被取消订阅，客户端重连后会从Last-Event-ID继续
+				return
+			}
+			if writeSSEEvent(w, event) != nil {
+				return
+			}
+			w.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			w.Flush()
+		}
+	}
+}
+
+// 写入一条SSE消息，不写 event 字段，客户端统一在 onmessage 中按 event_type 处理
+func writeSSEEvent(w gin.ResponseWriter, event websocket.WSEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil
+	}
+	if event.ID > 0 {
+		_, err = fmt.Fprintf(w, "id: %d\ndata: %s\n\n", event.ID, data)
+	} else {
+		_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+	}
+	return err
+}
@@ -0,0 +1,28 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/health"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetHealth 获取健康检查结果
+// @Summary 获取健康检查结果
+// @Description 返回数据库、网盘账号、HTTP代理、TMDB、AI识别接口和Emby的健康状态，没有配置的组件不检查。
+// @Description 每5分钟检查一次，连续失败2次才认为不可用，状态变化时发送系统告警。refresh=1 时立即检查并等待结果。
+// @Tags 系统设置
+// @Accept json
+// @Produce json
+// @Param refresh query string false "是否立即检查，1表示立即检查"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /health [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetHealth(c *gin.Context) {
+	if c.Query("refresh") == "1" {
+		health.GlobalMonitor.CheckAll()
+	}
+	c.JSON(http.StatusOK, APIResponse[*health.Report]{Code: Success, Message: "", Data: health.GlobalMonitor.Report()})
+}
@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/synccron"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 入站Webhook支持的来源
+var inboundSources = []string{"qbittorrent", "aria2", "cd2", "rclone", "generic"}
+
+// InboundWebhookRequest 入站Webhook请求，不同来源使用不同的字段
+type InboundWebhookRequest struct {
+	// 通用格式，rclone 也使用这个格式
+	Path  string   `form:"path" json:"path"`
+	Paths []string `form:"paths" json:"paths"`
+	IsDir *bool    `form:"is_dir" json:"is_dir"`
+	// qBittorrent 完成时运行外部程序：%F 内容路径，%R 根目录，%D 保存路径，%N 名称
+	ContentPath string `form:"content_path" json:"content_path"`
+	RootPath    string `form:"root_path" json:"root_path"`
+	SavePath    string `form:"save_path" json:"save_path"`
+	Name        string `form:"name" json:"name"`
+	// Aria2 aria2.tellStatus 返回的 dir 和 files
+	Dir   string `json:"dir"`
+	Files []struct {
+		Path string `json:"path"`
+	} `json:"files"`
+	// CD2 文件变更通知
+	Data []struct {
+		Action          string `json:"action"`
+		IsDir           string `json:"is_dir"`
+		SourceFile      string `json:"source_file"`
+		DestinationFile string `json:"destination_file"`
+	} `json:"data"`
+	// 选项，也可以放在URL参数中
+	Scrape      bool   `form:"scrape" json:"scrape"`
+	StripPrefix string `form:"strip_prefix" json:"strip_prefix"`
+	AddPrefix   string `form:"add_prefix" json:"add_prefix"`
+}
+
+// 按来源取出变化的路径
+func (r *InboundWebhookRequest) changes(source string) []synccron.InboundChange {
+	changes := make([]synccron.InboundChange, 0)
+	add := func(path string, isDir *bool) {
+		if strings.TrimSpace(path) != "" {
+			changes = append(changes, synccron.InboundChange{Path: path, IsDir: isDir})
+		}
+	}
+	isDir := true
+	switch source {
+	case "qbittorrent":
+		switch {
+		case r.RootPath != "":
+			// 多文件种子才有根目录
+			add(r.RootPath, &isDir)
+		case r.ContentPath != "":
+			add(r.ContentPath, r.IsDir)
+		case r.SavePath != "" && r.Name != "":
+			add(filepath.Join(r.SavePath, r.Name), r.IsDir)
+		default:
+			add(r.Path, r.IsDir)
+		}
+	case "aria2":
+		if r.Path != "" {
+			add(r.Path, r.IsDir)
+			break
+		}
+		folders := make([]string, 0)
+		for _, file := range r.Files {
+			if file.Path == "" {
+				continue
+			}
+			folder := filepath.Dir(file.Path)
+			if !slices.Contains(folders, folder) {
+				folders = append(folders, folder)
+			}
+		}
+		if len(folders) == 0 && r.Dir != "" {
+			folders = append(folders, r.Dir)
+		}
+		for _, folder := range folders {
+			add(folder, &isDir)
+		}
+	case "cd2":
+		for _, item := range r.Data {
+			itemIsDir := item.IsDir == "true"
+			// 重命名和移动时两边的目录都要同步，删除旧的STRM文件
+			add(item.SourceFile, &itemIsDir)
+			add(item.DestinationFile, &itemIsDir)
+		}
+	default:
+		add(r.Path, r.IsDir)
+		for _, path := range r.Paths {
+			add(path, r.IsDir)
+		}
+	}
+	return changes
+}
+
+// InboundWebhook 外部程序通知路径变化，触发子目录同步
+// @Summary 入站Webhook
+// @Description 下载器完成、CD2或rclone文件变化时调用，找到覆盖该路径的同步目录，只同步变化的子目录，可选同步完成后刮削关联的刮削目录。
+// @Description 同一个目录在短时间内多次通知时只同步一次，最后一次通知30秒后加入队列。使用 api_key 参数认证。
+// @Tags 同步管理
+// @Accept json
+// @Produce json
+// @Param source path string true "来源：qbittorrent、aria2、cd2、rclone、generic"
+// @Param path body string false "变化的路径，generic和rclone使用"
+// @Param is_dir body bool false "路径是否是目录，为空时根据扩展名判断"
+// @Param scrape query bool false "同步完成后刮削关联的刮削目录"
+// @Param strip_prefix query string false "去掉路径开头的部分，比如下载器中的挂载目录"
+// @Param add_prefix query string false "去掉前缀后再加上的部分，对应同步目录的源路径"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /webhooks/inbound/{source} [post]
+// @Security ApiKeyAuth
+func InboundWebhook(c *gin.Context) {
+	source := strings.ToLower(c.Param("source"))
+	if !slices.Contains(inboundSources, source) {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("不支持的来源: %s", source), Data: nil})
+		return
+	}
+	var req InboundWebhookRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("请求参数错误: %v", err), Data: nil})
+		return
+	}
+	opts := synccron.InboundOptions{
+		Scrape:      req.Scrape || c.Query("scrape") == "1" || c.Query("scrape") == "true",
+		StripPrefix: req.StripPrefix,
+		AddPrefix:   req.AddPrefix,
+	}
+	if prefix := c.Query("strip_prefix"); prefix != "" {
+		opts.StripPrefix = prefix
+	}
+	if prefix := c.Query("add_prefix"); prefix != "" {
+		opts.AddPrefix = prefix
+	}
+	changes := req.changes(source)
+	if len(changes) == 0 {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "请求中没有变化的路径", Data: nil})
+		return
+	}
+	results := synccron.HandleInboundChanges(changes, opts)
+	matched := 0
+	for _, result := range results {
+		if result.Matched {
+			matched++
+		}
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: fmt.Sprintf("%d 个路径匹配到同步目录，将在 %s 后加入同步队列", matched, synccron.InboundDebounceDelay), Data: results})
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/models"
 	"Q115-STRM/internal/notification"
 	"Q115-STRM/internal/notificationmanager"
@@ -54,7 +55,7 @@ func GetNotificationChannels(c *gin.Context) {
 // @Produce json
 // @Param channel_name body string true "渠道名称"
 // @Param bot_token body string true "机器人Token"
-// @Param chat_id body string true "聊天ID"
+// @Param chat_id body string true "聊天ID，可以是私聊或者群组，多个用逗号分隔"
 // @Success 200 {object} object
 // @Failure 200 {object} object
 // @Router /setting/notification/channels/telegram [post]
@@ -76,6 +77,10 @@ func CreateTelegramChannel(c *gin.Context) {
 		})
 		return
 	}
+	if !validTelegramChatIDs(r.ChatID) {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": "聊天ID格式错误，多个聊天用逗号分隔", "data": nil})
+		return
+	}
 
 	// 创建渠道
 	channel := models.NotificationChannel{
@@ -801,7 +806,7 @@ func UpdateCustomWebhookChannel(c *gin.Context) {
 // @Param channel_id body integer true "渠道ID"
 // @Param channel_name body string false "渠道名称"
 // @Param bot_token body string false "机器人Token"
-// @Param chat_id body string false "聊天ID"
+// @Param chat_id body string false "聊天ID，可以是私聊或者群组，多个用逗号分隔"
 // @Param description body string false "描述"
 // @Success 200 {object} object
 // @Failure 200 {object} object
@@ -833,6 +838,10 @@ func UpdateTelegramChannel(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"code": 1, "message": "该渠道不是 Telegram 类型", "data": nil})
 		return
 	}
+	if r.ChatID != "" && !validTelegramChatIDs(r.ChatID) {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": "聊天ID格式错误，多个聊天用逗号分隔", "data": nil})
+		return
+	}
 
 	// 查找配置
 	var cfg models.TelegramChannelConfig
@@ -1435,6 +1444,10 @@ func DeleteChannel(c *gin.Context) {
 		if err := tx.Where("channel_id = ?", channelID).Delete(&models.NotificationRule{}).Error; err != nil {
 			return err
 		}
+		// 删除待汇总的通知
+		if err := tx.Where("channel_id = ?", channelID).Delete(&models.NotificationDigestItem{}).Error; err != nil {
+			return err
+		}
 		// 删除特定类型的配置
 		var channel models.NotificationChannel
 		if err := tx.Where("id = ?", channelID).First(&channel).Error; err != nil {
@@ -1444,6 +1457,8 @@ func DeleteChannel(c *gin.Context) {
 		switch channel.ChannelType {
 		case "telegram":
 			tx.Where("channel_id = ?", channelID).Delete(&models.TelegramChannelConfig{})
+			tx.Where("channel_id = ?", channelID).Delete(&models.TelegramUser{})
+			// 命令记录用于审计，删除渠道时保留
 		case "meow":
 			tx.Where("channel_id = ?", channelID).Delete(&models.MeoWChannelConfig{})
 		case "bark":
@@ -1452,6 +1467,20 @@ func DeleteChannel(c *gin.Context) {
 			tx.Where("channel_id = ?", channelID).Delete(&models.ServerChanChannelConfig{})
 		case "webhook":
 			tx.Where("channel_id = ?", channelID).Delete(&models.CustomWebhookChannelConfig{})
+		case "wecom":
+			tx.Where("channel_id = ?", channelID).Delete(&models.WeComChannelConfig{})
+		case "dingtalk":
+			tx.Where("channel_id = ?", channelID).Delete(&models.DingTalkChannelConfig{})
+		case "feishu":
+			tx.Where("channel_id = ?", channelID).Delete(&models.FeishuChannelConfig{})
+		case "gotify":
+			tx.Where("channel_id = ?", channelID).Delete(&models.GotifyChannelConfig{})
+		case "ntfy":
+			tx.Where("channel_id = ?", channelID).Delete(&models.NtfyChannelConfig{})
+		case "discord":
+			tx.Where("channel_id = ?", channelID).Delete(&models.DiscordChannelConfig{})
+		case "email":
+			tx.Where("channel_id = ?", channelID).Delete(&models.EmailChannelConfig{})
 		}
 
 		// 删除渠道
@@ -1517,6 +1546,15 @@ func GetNotificationRules(c *gin.Context) {
 // @Param channel_id body integer true "渠道ID"
 // @Param event_type body string true "事件类型"
 // @Param is_enabled body boolean false "是否启用"
+// @Param sync_paths body string false "只通知这些同步路径，多个用英文逗号分隔，不传则不修改"
+// @Param libraries body string false "只通知这些Emby媒体库，多个用英文逗号分隔，不传则不修改"
+// @Param categories body string false "只通知这些刮削分类，多个用英文逗号分隔，不传则不修改"
+// @Param users body string false "只通知这些用户的播放，多个用英文逗号分隔，不传则不修改"
+// @Param min_priority body string false "最低优先级：low/normal/high，空字符串表示不限制，不传则不修改"
+// @Param title_template body string false "标题模板，空字符串表示使用原标题，不传则不修改"
+// @Param body_template body string false "内容模板，空字符串表示使用原内容，不传则不修改"
+// @Param digest_mode body string false "汇总模式：hourly/daily/weekly，空字符串表示立即发送，不传则不修改"
+// @Param digest_cron body string false "汇总发送时间的cron表达式，空字符串表示使用汇总模式的默认时间，不传则不修改"
 // @Success 200 {object} object
 // @Failure 200 {object} object
 // @Router /setting/notification/rules [put]
@@ -1524,9 +1562,18 @@ func GetNotificationRules(c *gin.Context) {
 // @Security ApiKeyAuth
 func UpdateNotificationRule(c *gin.Context) {
 	type req struct {
-		ChannelID uint   `json:"channel_id" binding:"required"`
-		EventType string `json:"event_type" binding:"required"`
-		IsEnabled bool   `json:"is_enabled"`
+		ChannelID     uint    `json:"channel_id" binding:"required"`
+		EventType     string  `json:"event_type" binding:"required"`
+		IsEnabled     bool    `json:"is_enabled"`
+		SyncPaths     *string `json:"sync_paths"`
+		Libraries     *string `json:"libraries"`
+		Categories    *string `json:"categories"`
+		Users         *string `json:"users"`
+		MinPriority   *string `json:"min_priority"`
+		TitleTemplate *string `json:"title_template"`
+		BodyTemplate  *string `json:"body_template"`
+		DigestMode    *string `json:"digest_mode"`
+		DigestCron    *string `json:"digest_cron"`
 	}
 
 	var r req
@@ -1538,6 +1585,55 @@ func UpdateNotificationRule(c *gin.Context) {
 		})
 		return
 	}
+	if r.MinPriority != nil {
+		switch notification.NotificationPriority(*r.MinPriority) {
+		case "", notification.LowPriority, notification.NormalPriority, notification.HighPriority:
+		default:
+			c.JSON(http.StatusOK, gin.H{
+				"code":    1,
+				"message": "最低优先级只能是 low、normal 或 high",
+				"data":    nil,
+			})
+			return
+		}
+	}
+	if r.DigestMode != nil {
+		switch notification.DigestMode(*r.DigestMode) {
+		case notification.DigestNone, notification.DigestHourly, notification.DigestDaily, notification.DigestWeekly:
+		default:
+			c.JSON(http.StatusOK, gin.H{
+				"code":    1,
+				"message": "汇总模式只能是 hourly、daily 或 weekly",
+				"data":    nil,
+			})
+			return
+		}
+	}
+	if r.DigestCron != nil && strings.TrimSpace(*r.DigestCron) != "" && helpers.GetNextTimeByCronStr(strings.TrimSpace(*r.DigestCron), 1) == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    1,
+			"message": "汇总发送时间的cron表达式格式错误",
+			"data":    nil,
+		})
+		return
+	}
+	// 过滤条件、模板和汇总设置只更新传了的字段
+	filters := map[string]interface{}{}
+	for column, value := range map[string]*string{
+		"sync_paths":     r.SyncPaths,
+		"libraries":      r.Libraries,
+		"categories":     r.Categories,
+		"users":          r.Users,
+		"min_priority":   r.MinPriority,
+		"title_template": r.TitleTemplate,
+		"body_template":  r.BodyTemplate,
+		"digest_mode":    r.DigestMode,
+		"digest_cron":    r.DigestCron,
+	} {
+		if value != nil {
+			filters[column] = strings.TrimSpace(*value)
+		}
+	}
 
 	// 先检查规则是否存在
 	var rule models.NotificationRule
@@ -1565,16 +1661,20 @@ func UpdateNotificationRule(c *gin.Context) {
 			})
 			return
 		}
-	} else {
-		// 存在则更新
-		if err := db.Db.Model(&rule).Update("is_enabled", r.IsEnabled).Error; err != nil {
-			c.JSON(http.StatusOK, gin.H{
-				"code":    1,
-				"message": "更新规则失败",
-				"data":    nil,
-			})
-			return
-		}
+	}
+	// 更新启用状态和传了的过滤条件、模板、汇总设置
+	filters["is_enabled"] = r.IsEnabled
+	if err := db.Db.Model(&rule).Updates(filters).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    1,
+			"message": "更新规则失败",
+			"data":    nil,
+		})
+		return
+	}
+	// 关闭汇总后还没发送的待汇总通知不再发送
+	if r.DigestMode != nil && *r.DigestMode == "" {
+		db.Db.Where("channel_id = ? AND event_type = ?", rule.ChannelID, rule.EventType).Delete(&models.NotificationDigestItem{})
 	}
 
 	// 重新加载规则
@@ -1700,12 +1800,16 @@ func TestChannelConnection(c *gin.Context) {
 		handler = notificationmanager.NewCustomWebhookChannelHandler(&config)
 
 	default:
-		c.JSON(http.StatusOK, gin.H{
-			"code":    1,
-			"message": "未知的渠道类型",
-			"data":    nil,
-		})
-		return
+		h, err := notificationmanager.LoadChannelHandler(db.Db, &channel)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"code":    1,
+				"message": err.Error(),
+				"data":    nil,
+			})
+			return
+		}
+		handler = h
 	}
 
 	// 发送测试消息
@@ -1727,3 +1831,107 @@ func TestChannelConnection(c *gin.Context) {
 		"data":    nil,
 	})
 }
+
+// GetNotificationLogs 获取通知发送记录
+// @Summary 获取通知发送记录
+// @Description 分页获取通知发送队列的记录，包括待发送、已发送、发送失败和被去重的通知
+// @Tags 通知管理
+// @Accept json
+// @Produce json
+// @Param page query integer false "页码"
+// @Param page_size query integer false "每页数量"
+// @Param status query string false "状态：pending/sent/failed/suppressed"
+// @Param channel_id query integer false "渠道ID"
+// @Param event_type query string false "事件类型"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/notification/logs [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetNotificationLogs(c *gin.Context) {
+	page := helpers.StringToInt(c.Query("page"))
+	if page <= 0 {
+		page = 1
+	}
+	pageSize := helpers.StringToInt(c.Query("page_size"))
+	if pageSize <= 0 {
+		pageSize = 20
+	}
+	query := db.Db.Model(&models.NotificationLog{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if channelID := c.Query("channel_id"); channelID != "" {
+		query = query.Where("channel_id = ?", channelID)
+	}
+	if eventType := c.Query("event_type"); eventType != "" {
+		query = query.Where("event_type = ?", eventType)
+	}
+	var total int64
+	query.Count(&total)
+	logs := make([]models.NotificationLog, 0)
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&logs).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    1,
+			"message": "获取发送记录失败",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "获取成功",
+		"data":    gin.H{"total": total, "list": logs},
+	})
+}
+
+// RetryNotificationLog 重新发送通知
+// @Summary 重新发送通知
+// @Description 将发送失败或被去重的通知重新放入发送队列
+// @Tags 通知管理
+// @Accept json
+// @Produce json
+// @Param id body integer true "发送记录ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/notification/logs/retry [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func RetryNotificationLog(c *gin.Context) {
+	type req struct {
+		ID uint `json:"id" binding:"required"`
+	}
+
+	var r req
+	if err := c.ShouldBindJSON(&r); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": "参数错误",
+			"data":    nil,
+		})
+		return
+	}
+	if notificationmanager.GlobalEnhancedNotificationManager == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    1,
+			"message": "通知管理器未初始化",
+			"data":    nil,
+		})
+		return
+	}
+	if err := notificationmanager.GlobalEnhancedNotificationManager.RetryLog(r.ID); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    1,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "已重新放入发送队列",
+		"data":    nil,
+	})
+}
@@ -0,0 +1,859 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/notification"
+	"Q115-STRM/internal/notificationmanager"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============ 企业微信、钉钉、飞书、Gotify、ntfy、Discord、邮件渠道 ============
+// 这些渠道的增删改查流程一致，公共部分放在下面几个函数中
+
+// 创建渠道、保存配置、创建默认规则并刷新通知管理器
+// newConfig 根据渠道ID返回要保存的配置
+func createNotificationChannel(c *gin.Context, channelType string, channelName string, newConfig func(channelID uint) any) {
+	channel := models.NotificationChannel{
+		ChannelType: channelType,
+		ChannelName: channelName,
+		IsEnabled:   true,
+	}
+	if err := db.Db.Save(&channel).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": "创建渠道失败", "data": nil})
+		return
+	}
+
+	if err := db.Db.Save(newConfig(channel.ID)).Error; err != nil {
+		// 回滚
+		db.Db.Delete(&channel)
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": "创建配置失败", "data": nil})
+		return
+	}
+
+	// 创建默认规则
+	for _, eventType := range notification.AllNotificationTypes {
+		rule := models.NotificationRule{
+			ChannelID: channel.ID,
+			EventType: string(eventType),
+			IsEnabled: true,
+		}
+		db.Db.Save(&rule)
+	}
+
+	if notificationmanager.GlobalEnhancedNotificationManager != nil {
+		notificationmanager.GlobalEnhancedNotificationManager.ReloadChannel(channel.ID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "创建成功", "data": channel})
+}
+
+// 查询要更新的渠道和配置，失败时已经写入响应
+func loadNotificationChannelConfig(c *gin.Context, channelID uint, channelType string, cfg any) (*models.NotificationChannel, bool) {
+	var channel models.NotificationChannel
+	if err := db.Db.First(&channel, channelID).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": "渠道不存在", "data": nil})
+		return nil, false
+	}
+	if channel.ChannelType != channelType {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": "渠道类型不匹配", "data": nil})
+		return nil, false
+	}
+	if err := db.Db.Where("channel_id = ?", channel.ID).First(cfg).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": "配置不存在", "data": nil})
+		return nil, false
+	}
+	return &channel, true
+}
+
+// 保存渠道名称、描述和配置中有变化的字段，并刷新通知管理器
+// 请求中没有的字段为nil不修改，描述和可选的密钥等字段传空字符串时清空
+func updateNotificationChannel(c *gin.Context, channel *models.NotificationChannel, cfg any, channelName *string, description *string, updates map[string]interface{}) {
+	if channelName != nil && *channelName != "" {
+		channel.ChannelName = *channelName
+	}
+	if description != nil {
+		channel.Description = *description
+	}
+	if len(updates) > 0 {
+		if err := db.Db.Model(cfg).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusOK, gin.H{"code": 1, "message": "更新配置失败", "data": nil})
+			return
+		}
+	}
+	if err := db.Db.Save(channel).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": "更新渠道失败", "data": nil})
+		return
+	}
+
+	if notificationmanager.GlobalEnhancedNotificationManager != nil {
+		notificationmanager.GlobalEnhancedNotificationManager.ReloadChannel(channel.ID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "更新成功", "data": channel})
+}
+
+// 查询单个渠道及配置
+func getNotificationChannel(c *gin.Context, channelType string, cfg any) {
+	var channel models.NotificationChannel
+	if err := db.Db.First(&channel, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": "渠道不存在", "data": nil})
+		return
+	}
+	if channel.ChannelType != channelType {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": "渠道类型不匹配", "data": nil})
+		return
+	}
+	if err := db.Db.Where("channel_id = ?", channel.ID).First(cfg).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": "配置不存在", "data": nil})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "获取成功", "data": gin.H{
+		"channel": channel,
+		"config":  cfg,
+	}})
+}
+
+// 参数错误时写入响应并返回false
+func bindNotificationChannelRequest(c *gin.Context, r any) bool {
+	if err := c.ShouldBindJSON(r); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 1, "message": "参数错误", "data": nil})
+		return false
+	}
+	return true
+}
+
+// ============ 企业微信 ============
+
+// CreateWeComChannel 创建企业微信渠道
+// @Summary 创建企业微信渠道
+// @Description 创建企业微信群机器人通知渠道并保存配置
+// @Tags 通知管理
+// @Accept json
+// @Produce json
+// @Param channel_name body string true "渠道名称"
+// @Param webhook_url body string true "群机器人Webhook地址"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/notification/channels/wecom [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func CreateWeComChannel(c *gin.Context) {
+	type req struct {
+		ChannelName string `json:"channel_name" binding:"required"`
+		WebhookURL  string `json:"webhook_url" binding:"required"`
+	}
+	var r req
+	if !bindNotificationChannelRequest(c, &r) {
+		return
+	}
+	createNotificationChannel(c, "wecom", r.ChannelName, func(channelID uint) any {
+		return &models.WeComChannelConfig{ChannelID: channelID, WebhookURL: r.WebhookURL}
+	})
+}
+
+// UpdateWeComChannel 更新企业微信渠道配置
+// @Summary 更新企业微信渠道
+// @Description 更新企业微信渠道名称与配置
+// @Tags 通知管理
+// @Accept json
+// @Produce json
+// @Param channel_id body integer true "渠道ID"
+// @Param channel_name body string false "渠道名称"
+// @Param webhook_url body string false "群机器人Webhook地址"
+// @Param description body string false "描述"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/notification/channels/wecom [put]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func UpdateWeComChannel(c *gin.Context) {
+	type req struct {
+		ChannelID   uint    `json:"channel_id" binding:"required"`
+		ChannelName *string `json:"channel_name"`
+		WebhookURL  string  `json:"webhook_url"`
+		Description *string `json:"description"`
+	}
+	var r req
+	if !bindNotificationChannelRequest(c, &r) {
+		return
+	}
+	var cfg models.WeComChannelConfig
+	channel, ok := loadNotificationChannelConfig(c, r.ChannelID, "wecom", &cfg)
+	if !ok {
+		return
+	}
+	updates := make(map[string]interface{})
+	if r.WebhookURL != "" {
+		updates["webhook_url"] = r.WebhookURL
+	}
+	updateNotificationChannel(c, channel, &cfg, r.ChannelName, r.Description, updates)
+}
+
+// GetWeComChannel 查询单个企业微信渠道配置
+// @Summary 获取企业微信渠道
+// @Description 根据ID获取企业微信渠道及配置
+// @Tags 通知管理
+// @Accept json
+// @Produce json
+// @Param id path integer true "渠道ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/notification/channels/wecom/{id} [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetWeComChannel(c *gin.Context) {
+	getNotificationChannel(c, "wecom", &models.WeComChannelConfig{})
+}
+
+// ============ 钉钉 ============
+
+// CreateDingTalkChannel 创建钉钉渠道
+// @Summary 创建钉钉渠道
+// @Description 创建钉钉群机器人通知渠道并保存配置
+// @Tags 通知管理
+// @Accept json
+// @Produce json
+// @Param channel_name body string true "渠道名称"
+// @Param webhook_url body string true "群机器人Webhook地址"
+// @Param secret body string false "加签密钥"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/notification/channels/dingtalk [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func CreateDingTalkChannel(c *gin.Context) {
+	type req struct {
+		ChannelName string `json:"channel_name" binding:"required"`
+		WebhookURL  string `json:"webhook_url" binding:"required"`
+		Secret      string `json:"secret"`
+	}
+	var r req
+	if !bindNotificationChannelRequest(c, &r) {
+		return
+	}
+	createNotificationChannel(c, "dingtalk", r.ChannelName, func(channelID uint) any {
+		return &models.DingTalkChannelConfig{ChannelID: channelID, WebhookURL: r.WebhookURL, Secret: r.Secret}
+	})
+}
+
+// UpdateDingTalkChannel 更新钉钉渠道配置
+// @Summary 更新钉钉渠道
+// @Description 更新钉钉渠道名称与配置
+// @Tags 通知管理
+// @Accept json
+// @Produce json
+// @Param channel_id body integer true "渠道ID"
+// @Param channel_name body string false "渠道名称"
+// @Param webhook_url body string false "群机器人Webhook地址"
+// @Param secret body string false "加签密钥"
+// @Param description body string false "描述"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/notification/channels/dingtalk [put]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func UpdateDingTalkChannel(c *gin.Context) {
+	type req struct {
+		ChannelID   uint    `json:"channel_id" binding:"required"`
+		ChannelName *string `json:"channel_name"`
+		WebhookURL  string  `json:"webhook_url"`
+		Secret      *string `json:"secret"`
+		Description *string `json:"description"`
+	}
+	var r req
+	if !bindNotificationChannelRequest(c, &r) {
+		return
+	}
+	var cfg models.DingTalkChannelConfig
+	channel, ok := loadNotificationChannelConfig(c, r.ChannelID, "dingtalk", &cfg)
+	if !ok {
+		return
+	}
+	updates := make(map[string]interface{})
+	if r.WebhookURL != "" {
+		updates["webhook_url"] = r.WebhookURL
+	}
+	if r.Secret != nil {
+		updates["secret"] = *r.Secret
+	}
+	updateNotificationChannel(c, channel, &cfg, r.ChannelName, r.Description, updates)
+}
+
+// GetDingTalkChannel 查询单个钉钉渠道配置
+// @Summary 获取钉钉渠道
+// @Description 根据ID获取钉钉渠道及配置
+// @Tags 通知管理
+// @Accept json
+// @Produce json
+// @Param id path integer true "渠道ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/notification/channels/dingtalk/{id} [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetDingTalkChannel(c *gin.Context) {
+	getNotificationChannel(c, "dingtalk", &models.DingTalkChannelConfig{})
+}
+
+// ============ 飞书 ============
+
+// CreateFeishuChannel 创建飞书渠道
+// @Summary 创建飞书渠道
+// @Description 创建飞书群机器人通知渠道并保存配置
+// @Tags 通知管理
+// @Accept json
+// @Produce json
+// @Param channel_name body string true "渠道名称"
+// @Param webhook_url body string true "群机器人Webhook地址"
+// @Param secret body string false "签名校验密钥"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/notification/channels/feishu [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func CreateFeishuChannel(c *gin.Context) {
+	type req struct {
+		ChannelName string `json:"channel_name" binding:"required"`
+		WebhookURL  string `json:"webhook_url" binding:"required"`
+		Secret      string `json:"secret"`
+	}
+	var r req
+	if !bindNotificationChannelRequest(c, &r) {
+		return
+	}
+	createNotificationChannel(c, "feishu", r.ChannelName, func(channelID uint) any {
+		return &models.FeishuChannelConfig{ChannelID: channelID, WebhookURL: r.WebhookURL, Secret: r.Secret}
+	})
+}
+
+// UpdateFeishuChannel 更新飞书渠道配置
+// @Summary 更新飞书渠道
+// @Description 更新飞书渠道名称与配置
+// @Tags 通知管理
+// @Accept json
+// @Produce json
+// @Param channel_id body integer true "渠道ID"
+// @Param channel_name body string false "渠道名称"
+// @Param webhook_url body string false "群机器人Webhook地址"
+// @Param secret body string false "签名校验密钥"
+// @Param description body string false "描述"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/notification/channels/feishu [put]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func UpdateFeishuChannel(c *gin.Context) {
+	type req struct {
+		ChannelID   uint    `json:"channel_id" binding:"required"`
+		ChannelName *string `json:"channel_name"`
+		WebhookURL  string  `json:"webhook_url"`
+		Secret      *string `json:"secret"`
+		Description *string `json:"description"`
+	}
+	var r req
+	if !bindNotificationChannelRequest(c, &r) {
+		return
+	}
+	var cfg models.FeishuChannelConfig
+	channel, ok := loadNotificationChannelConfig(c, r.ChannelID, "feishu", &cfg)
+	if !ok {
+		return
+	}
+	updates := make(map[string]interface{})
+	if r.WebhookURL != "" {
+		updates["webhook_url"] = r.WebhookURL
+	}
+	if r.Secret != nil {
+		updates["secret"] = *r.Secret
+	}
+	updateNotificationChannel(c, channel, &cfg, r.ChannelName, r.Description, updates)
+}
+
+// GetFeishuChannel 查询单个飞书渠道配置
+// @Summary 获取飞书渠道
+// @Description 根据ID获取飞书渠道及配置
+// @Tags 通知管理
+// @Accept json
+// @Produce json
+// @Param id path integer true "渠道ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/notification/channels/feishu/{id} [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetFeishuChannel(c *gin.Context) {
+	getNotificationChannel(c, "feishu", &models.FeishuChannelConfig{})
+}
+
+// ============ Gotify ============
+
+// CreateGotifyChannel 创建Gotify渠道
+// @Summary 创建Gotify渠道
+// @Description 创建Gotify通知渠道并保存配置
+// @Tags 通知管理
+// @Accept json
+// @Produce json
+// @Param channel_name body string true "渠道名称"
+// @Param server_url body string true "服务器地址"
+// @Param app_token body string true "应用Token"
+// @Param priority body integer false "消息优先级，默认5"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/notification/channels/gotify [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func CreateGotifyChannel(c *gin.Context) {
+	type req struct {
+		ChannelName string `json:"channel_name" binding:"required"`
+		ServerURL   string `json:"server_url" binding:"required"`
+		AppToken    string `json:"app_token" binding:"required"`
+		Priority    int    `json:"priority"`
+	}
+	var r req
+	if !bindNotificationChannelRequest(c, &r) {
+		return
+	}
+	if r.Priority <= 0 {
+		r.Priority = 5
+	}
+	createNotificationChannel(c, "gotify", r.ChannelName, func(channelID uint) any {
+		return &models.GotifyChannelConfig{ChannelID: channelID, ServerURL: r.ServerURL, AppToken: r.AppToken, Priority: r.Priority}
+	})
+}
+
+// UpdateGotifyChannel 更新Gotify渠道配置
+// @Summary 更新Gotify渠道
+// @Description 更新Gotify渠道名称与配置
+// @Tags 通知管理
+// @Accept json
+// @Produce json
+// @Param channel_id body integer true "渠道ID"
+// @Param channel_name body string false "渠道名称"
+// @Param server_url body string false "服务器地址"
+// @Param app_token body string false "应用Token"
+// @Param priority body integer false "消息优先级"
+// @Param description body string false "描述"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/notification/channels/gotify [put]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func UpdateGotifyChannel(c *gin.Context) {
+	type req struct {
+		ChannelID   uint    `json:"channel_id" binding:"required"`
+		ChannelName *string `json:"channel_name"`
+		ServerURL   string  `json:"server_url"`
+		AppToken    string  `json:"app_token"`
+		Priority    int     `json:"priority"`
+		Description *string `json:"description"`
+	}
+	var r req
+	if !bindNotificationChannelRequest(c, &r) {
+		return
+	}
+	var cfg models.GotifyChannelConfig
+	channel, ok := loadNotificationChannelConfig(c, r.ChannelID, "gotify", &cfg)
+	if !ok {
+		return
+	}
+	updates := make(map[string]interface{})
+	if r.ServerURL != "" {
+		updates["server_url"] = r.ServerURL
+	}
+	if r.AppToken != "" {
+		updates["app_token"] = r.AppToken
+	}
+	if r.Priority > 0 {
+		updates["priority"] = r.Priority
+	}
+	updateNotificationChannel(c, channel, &cfg, r.ChannelName, r.Description, updates)
+}
+
+// GetGotifyChannel 查询单个Gotify渠道配置
+// @Summary 获取Gotify渠道
+// @Description 根据ID获取Gotify渠道及配置
+// @Tags 通知管理
+// @Accept json
+// @Produce json
+// @Param id path integer true "渠道ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/notification/channels/gotify/{id} [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetGotifyChannel(c *gin.Context) {
+	getNotificationChannel(c, "gotify", &models.GotifyChannelConfig{})
+}
+
+// ============ ntfy ============
+
+// CreateNtfyChannel 创建ntfy渠道
+// @Summary 创建ntfy渠道
+// @Description 创建ntfy通知渠道并保存配置
+// @Tags 通知管理
+// @Accept json
+// @Produce json
+// @Param channel_name body string true "渠道名称"
+// @Param topic body string true "主题"
+// @Param server_url body string false "服务器地址，默认https://ntfy.sh"
+// @Param token body string false "访问Token"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/notification/channels/ntfy [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func CreateNtfyChannel(c *gin.Context) {
+	type req struct {
+		ChannelName string `json:"channel_name" binding:"required"`
+		Topic       string `json:"topic" binding:"required"`
+		ServerURL   string `json:"server_url"`
+		Token       string `json:"token"`
+	}
+	var r req
+	if !bindNotificationChannelRequest(c, &r) {
+		return
+	}
+	if r.ServerURL == "" {
+		r.ServerURL = "https://ntfy.sh"
+	}
+	createNotificationChannel(c, "ntfy", r.ChannelName, func(channelID uint) any {
+		return &models.NtfyChannelConfig{ChannelID: channelID, ServerURL: r.ServerURL, Topic: r.Topic, Token: r.Token}
+	})
+}
+
+// UpdateNtfyChannel 更新ntfy渠道配置
+// @Summary 更新ntfy渠道
+// @Description 更新ntfy渠道名称与配置
+// @Tags 通知管理
+// @Accept json
+// @Produce json
+// @Param channel_id body integer true "渠道ID"
+// @Param channel_name body string false "渠道名称"
+// @Param topic body string false "主题"
+// @Param server_url body string false "服务器地址"
+// @Param token body string false "访问Token"
+// @Param description body string false "描述"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/notification/channels/ntfy [put]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func UpdateNtfyChannel(c *gin.Context) {
+	type req struct {
+		ChannelID   uint    `json:"channel_id" binding:"required"`
+		ChannelName *string `json:"channel_name"`
+		Topic       string  `json:"topic"`
+		ServerURL   string  `json:"server_url"`
+		Token       *string `json:"token"`
+		Description *string `json:"description"`
+	}
+	var r req
+	if !bindNotificationChannelRequest(c, &r) {
+		return
+	}
+	var cfg models.NtfyChannelConfig
+	channel, ok := loadNotificationChannelConfig(c, r.ChannelID, "ntfy", &cfg)
+	if !ok {
+		return
+	}
+	updates := make(map[string]interface{})
+	if r.Topic != "" {
+		updates["topic"] = r.Topic
+	}
+	if r.ServerURL != "" {
+		updates["server_url"] = r.ServerURL
+	}
+	if r.Token != nil {
+		updates["token"] = *r.Token
+	}
+	updateNotificationChannel(c, channel, &cfg, r.ChannelName, r.Description, updates)
+}
+
+// GetNtfyChannel 查询单个ntfy渠道配置
+// @Summary 获取ntfy渠道
+// @Description 根据ID获取ntfy渠道及配置
+// @Tags 通知管理
+// @Accept json
+// @Produce json
+// @Param id path integer true "渠道ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/notification/channels/ntfy/{id} [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetNtfyChannel(c *gin.Context) {
+	getNotificationChannel(c, "ntfy", &models.NtfyChannelConfig{})
+}
+
+// ============ Discord ============
+
+// CreateDiscordChannel 创建Discord渠道
+// @Summary 创建Discord渠道
+// @Description 创建Discord通知渠道并保存配置
+// @Tags 通知管理
+// @Accept json
+// @Produce json
+// @Param channel_name body string true "渠道名称"
+// @Param webhook_url body string true "频道Webhook地址"
+// @Param username body string false "显示名称"
+// @Param avatar_url body string false "头像URL"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/notification/channels/discord [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func CreateDiscordChannel(c *gin.Context) {
+	type req struct {
+		ChannelName string `json:"channel_name" binding:"required"`
+		WebhookURL  string `json:"webhook_url" binding:"required"`
+		Username    string `json:"username"`
+		AvatarURL   string `json:"avatar_url"`
+	}
+	var r req
+	if !bindNotificationChannelRequest(c, &r) {
+		return
+	}
+	createNotificationChannel(c, "discord", r.ChannelName, func(channelID uint) any {
+		return &models.DiscordChannelConfig{ChannelID: channelID, WebhookURL: r.WebhookURL, Username: r.Username, AvatarURL: r.AvatarURL}
+	})
+}
+
+// UpdateDiscordChannel 更新Discord渠道配置
+// @Summary 更新Discord渠道
+// @Description 更新Discord渠道名称与配置
+// @Tags 通知管理
+// @Accept json
+// @Produce json
+// @Param channel_id body integer true "渠道ID"
+// @Param channel_name body string false "渠道名称"
+// @Param webhook_url body string false "频道Webhook地址"
+// @Param username body string false "显示名称"
+// @Param avatar_url body string false "头像URL"
+// @Param description body string false "描述"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/notification/channels/discord [put]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func UpdateDiscordChannel(c *gin.Context) {
+	type req struct {
+		ChannelID   uint    `json:"channel_id" binding:"required"`
+		ChannelName *string `json:"channel_name"`
+		WebhookURL  string  `json:"webhook_url"`
+		Username    *string `json:"username"`
+		AvatarURL   *string `json:"avatar_url"`
+		Description *string `json:"description"`
+	}
+	var r req
+	if !bindNotificationChannelRequest(c, &r) {
+		return
+	}
+	var cfg models.DiscordChannelConfig
+	channel, ok := loadNotificationChannelConfig(c, r.ChannelID, "discord", &cfg)
+	if !ok {
+		return
+	}
+	updates := make(map[string]interface{})
+	if r.WebhookURL != "" {
+		updates["webhook_url"] = r.WebhookURL
+	}
+	if r.Username != nil {
+		updates["username"] = *r.Username
+	}
+	if r.AvatarURL != nil {
+		updates["avatar_url"] = *r.AvatarURL
+	}
+	updateNotificationChannel(c, channel, &cfg, r.ChannelName, r.Description, updates)
+}
+
+// GetDiscordChannel 查询单个Discord渠道配置
+// @Summary 获取Discord渠道
+// @Description 根据ID获取Discord渠道及配置
+// @Tags 通知管理
+// @Accept json
+// @Produce json
+// @Param id path integer true "渠道ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/notification/channels/discord/{id} [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetDiscordChannel(c *gin.Context) {
+	getNotificationChannel(c, "discord", &models.DiscordChannelConfig{})
+}
+
+// ============ 邮件 ============
+
+// CreateEmailChannel 创建邮件渠道
+// @Summary 创建邮件渠道
+// @Description 创建SMTP邮件通知渠道并保存配置
+// @Tags 通知管理
+// @Accept json
+// @Produce json
+// @Param channel_name body string true "渠道名称"
+// @Param smtp_host body string true "SMTP服务器"
+// @Param smtp_port body integer false "SMTP端口，为空时按加密方式使用465/587/25"
+// @Param encryption body string false "加密方式：none/starttls/ssl，默认starttls"
+// @Param username body string false "登录用户名"
+// @Param password body string false "登录密码"
+// @Param from body string false "发件人，为空时使用用户名"
+// @Param to body string true "收件人，多个用英文逗号分隔"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/notification/channels/email [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func CreateEmailChannel(c *gin.Context) {
+	type req struct {
+		ChannelName string `json:"channel_name" binding:"required"`
+		SMTPHost    string `json:"smtp_host" binding:"required"`
+		SMTPPort    int    `json:"smtp_port"`
+		Encryption  string `json:"encryption"`
+		Username    string `json:"username"`
+		Password    string `json:"password"`
+		From        string `json:"from"`
+		To          string `json:"to" binding:"required"`
+	}
+	var r req
+	if !bindNotificationChannelRequest(c, &r) {
+		return
+	}
+	switch r.Encryption {
+	case "":
+		r.Encryption = "starttls"
+	case "none", "starttls", "ssl":
+	default:
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": "加密方式只能是 none、starttls 或 ssl", "data": nil})
+		return
+	}
+	if err := checkEmailAuth(r.Encryption, r.Username); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": err.Error(), "data": nil})
+		return
+	}
+	createNotificationChannel(c, "email", r.ChannelName, func(channelID uint) any {
+		return &models.EmailChannelConfig{
+			ChannelID:  channelID,
+			SMTPHost:   r.SMTPHost,
+			SMTPPort:   r.SMTPPort,
+			Encryption: r.Encryption,
+			Username:   r.Username,
+			Password:   r.Password,
+			From:       r.From,
+			To:         r.To,
+		}
+	})
+}
+
+// UpdateEmailChannel 更新邮件渠道配置
+// @Summary 更新邮件渠道
+// @Description 更新邮件渠道名称与配置
+// @Tags 通知管理
+// @Accept json
+// @Produce json
+// @Param channel_id body integer true "渠道ID"
+// @Param channel_name body string false "渠道名称"
+// @Param smtp_host body string false "SMTP服务器"
+// @Param smtp_port body integer false "SMTP端口"
+// @Param encryption body string false "加密方式：none/starttls/ssl"
+// @Param username body string false "登录用户名"
+// @Param password body string false "登录密码"
+// @Param from body string false "发件人"
+// @Param to body string false "收件人，多个用英文逗号分隔"
+// @Param description body string false "描述"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/notification/channels/email [put]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func UpdateEmailChannel(c *gin.Context) {
+	type req struct {
+		ChannelID   uint    `json:"channel_id" binding:"required"`
+		ChannelName *string `json:"channel_name"`
+		SMTPHost    string  `json:"smtp_host"`
+		SMTPPort    int     `json:"smtp_port"`
+		Encryption  string  `json:"encryption"`
+		Username    *string `json:"username"`
+		Password    *string `json:"password"`
+		From        *string `json:"from"`
+		To          string  `json:"to"`
+		Description *string `json:"description"`
+	}
+	var r req
+	if !bindNotificationChannelRequest(c, &r) {
+		return
+	}
+	var cfg models.EmailChannelConfig
+	channel, ok := loadNotificationChannelConfig(c, r.ChannelID, "email", &cfg)
+	if !ok {
+		return
+	}
+	updates := make(map[string]interface{})
+	if r.SMTPHost != "" {
+		updates["smtp_host"] = r.SMTPHost
+	}
+	if r.SMTPPort > 0 {
+		updates["smtp_port"] = r.SMTPPort
+	}
+	switch r.Encryption {
+	case "":
+	case "none", "starttls", "ssl":
+		updates["encryption"] = r.Encryption
+	default:
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": "加密方式只能是 none、starttls 或 ssl", "data": nil})
+		return
+	}
+	if r.Username != nil {
+		updates["username"] = *r.Username
+	}
+	if r.Password != nil {
+		updates["password"] = *r.Password
+	}
+	if r.From != nil {
+		updates["from"] = *r.From
+	}
+	// 按更新后的配置检查加密方式和登录用户名
+	encryption, username := cfg.Encryption, cfg.Username
+	if r.Encryption != "" {
+		encryption = r.Encryption
+	}
+	if r.Username != nil {
+		username = *r.Username
+	}
+	if err := checkEmailAuth(encryption, username); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": err.Error(), "data": nil})
+		return
+	}
+	if r.To != "" {
+		updates["to"] = r.To
+	}
+	updateNotificationChannel(c, channel, &cfg, r.ChannelName, r.Description, updates)
+}
+
+// 不加密的连接不能登录，SMTP的PLAIN认证只允许在TLS连接上发送密码
+func checkEmailAuth(encryption, username string) error {
+	if encryption == "none" && username != "" {
+		return errors.New("不加密的连接不支持登录，请选择 starttls 或 ssl，或者清空用户名")
+	}
+	return nil
+}
+
+// GetEmailChannel 查询单个邮件渠道配置
+// @Summary 获取邮件渠道
+// @Description 根据ID获取邮件渠道及配置
+// @Tags 通知管理
+// @Accept json
+// @Produce json
+// @Param id path integer true "渠道ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/notification/channels/email/{id} [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetEmailChannel(c *gin.Context) {
+	getNotificationChannel(c, "email", &models.EmailChannelConfig{})
+}
@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/notification"
+	"Q115-STRM/internal/notificationmanager"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 检查逗号分隔的聊天ID是否都是数字
+func validTelegramChatIDs(chatID string) bool {
+	count := 0
+	for _, part := range strings.Split(chatID, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if _, err := strconv.ParseInt(part, 10, 64); err != nil {
+			return false
+		}
+		count++
+	}
+	return count > 0
+}
+
+// 查询Telegram渠道，不存在或者类型不对时返回错误响应
+func getTelegramChannelByParam(c *gin.Context) *models.NotificationChannel {
+	var channel models.NotificationChannel
+	if err := db.Db.First(&channel, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": "渠道不存在", "data": nil})
+		return nil
+	}
+	if channel.ChannelType != "telegram" {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": "该渠道不是 Telegram 类型", "data": nil})
+		return nil
+	}
+	return &channel
+}
+
+// GetTelegramUsers 获取Telegram机器人用户
+// @Summary 获取Telegram机器人用户
+// @Description 获取允许控制Telegram机器人的用户和角色，没有用户时只有配置的私聊可以执行用户管理以外的命令
+// @Tags 通知管理
+// @Accept json
+// @Produce json
+// @Param id path integer true "渠道ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/notification/channels/telegram/{id}/users [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetTelegramUsers(c *gin.Context) {
+	channel := getTelegramChannelByParam(c)
+	if channel == nil {
+		return
+	}
+	users := make([]models.TelegramUser, 0)
+	if err := db.Db.Where("channel_id = ?", channel.ID).Order("id ASC").Find(&users).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": "获取用户失败", "data": nil})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "获取成功", "data": users})
+}
+
+// SaveTelegramUser 添加或修改Telegram机器人用户
+// @Summary 添加或修改Telegram机器人用户
+// @Description 按用户ID添加或修改用户角色，viewer只能查询状态，operator可以执行同步、刮削等任务，admin可以修改设置和管理用户。第一个用户必须是管理员
+// @Tags 通知管理
+// @Accept json
+// @Produce json
+// @Param id path integer true "渠道ID"
+// @Param user_id body integer true "Telegram用户ID，可以在机器人中发送 /whoami 查看"
+// @Param username body string false "Telegram用户名"
+// @Param role body string true "角色：viewer/operator/admin"
+// @Param remark body string false "备注"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/notification/channels/telegram/{id}/users [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func SaveTelegramUser(c *gin.Context) {
+	type req struct {
+		UserID   int64                     `json:"user_id" binding:"required"`
+		Username string                    `json:"username"`
+		Role     notification.TelegramRole `json:"role" binding:"required"`
+		Remark   string                    `json:"remark"`
+	}
+	var r req
+	if err := c.ShouldBindJSON(&r); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 1, "message": "参数错误", "data": nil})
+		return
+	}
+	if r.Role.Level() == 0 {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": "角色只能是 viewer、operator、admin", "data": nil})
+		return
+	}
+	channel := getTelegramChannelByParam(c)
+	if channel == nil {
+		return
+	}
+	user := &models.TelegramUser{
+		ChannelID: channel.ID,
+		UserID:    r.UserID,
+		Username:  strings.TrimPrefix(r.Username, "@"),
+		Role:      r.Role,
+		Remark:    r.Remark,
+	}
+	if err := notificationmanager.SaveTelegramUser(db.Db, user); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": "保存用户失败: " + err.Error(), "data": nil})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "保存成功", "data": nil})
+}
+
+// DeleteTelegramUser 删除Telegram机器人用户
+// @Summary 删除Telegram机器人用户
+// @Description 删除用户，不能删除最后一个管理员，删除所有用户后只有配置的私聊可以执行用户管理以外的命令
+// @Tags 通知管理
+// @Accept json
+// @Produce json
+// @Param id path integer true "渠道ID"
+// @Param user_id path integer true "Telegram用户ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/notification/channels/telegram/{id}/users/{user_id} [delete]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func DeleteTelegramUser(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 1, "message": "参数错误", "data": nil})
+		return
+	}
+	channel := getTelegramChannelByParam(c)
+	if channel == nil {
+		return
+	}
+	if err := notificationmanager.DeleteTelegramUser(db.Db, channel.ID, userID); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": "删除用户失败: " + err.Error(), "data": nil})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "删除成功", "data": nil})
+}
+
+// GetTelegramCommandLogs 获取Telegram机器人命令记录
+// @Summary 获取Telegram机器人命令记录
+// @Description 分页获取通过Telegram机器人执行的命令，包括没有权限被拒绝的命令
+// @Tags 通知管理
+// @Accept json
+// @Produce json
+// @Param id path integer true "渠道ID"
+// @Param page query integer false "页码"
+// @Param page_size query integer false "每页数量"
+// @Param user_id query integer false "Telegram用户ID"
+// @Param command query string false "命令"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/notification/channels/telegram/{id}/audit [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetTelegramCommandLogs(c *gin.Context) {
+	channel := getTelegramChannelByParam(c)
+	if channel == nil {
+		return
+	}
+	page := helpers.StringToInt(c.Query("page"))
+	if page <= 0 {
+		page = 1
+	}
+	pageSize := helpers.StringToInt(c.Query("page_size"))
+	if pageSize <= 0 {
+		pageSize = 20
+	}
+	query := db.Db.Model(&models.TelegramCommandLog{}).Where("channel_id = ?", channel.ID)
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if command := c.Query("command"); command != "" {
+		query = query.Where("command = ?", command)
+	}
+	var total int64
+	query.Count(&total)
+	logs := make([]models.TelegramCommandLog, 0)
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&logs).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 1, "message": "获取命令记录失败", "data": nil})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "获取成功",
+		"data":    gin.H{"total": total, "list": logs},
+	})
+}
@@ -3,6 +3,7 @@ package controllers
 import (
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/scrape"
 	"Q115-STRM/internal/synccron"
 	"encoding/json"
 	"io"
@@ -48,6 +49,13 @@ type AiSettings struct {
 	AiTimeout   int             `json:"ai_timeout" form:"ai_timeout"`
 }
 
+type SubtitleSettings struct {
+	SubtitleLanguages string `json:"subtitle_languages" form:"subtitle_languages"`
+	OpenSubtitlesKey  string `json:"opensubtitles_key" form:"opensubtitles_key"`
+	AssrtToken        string `json:"assrt_token" form:"assrt_token"`
+	AssrtUrl          string `json:"assrt_url" form:"assrt_url"`
+}
+
 type MovieCategoryReq struct {
 	ID            uint     `json:"id" form:"id"`
 	Name          string   `json:"name" form:"name"`
@@ -146,11 +154,77 @@ func TestTmdbSettings(c *gin.Context) {
 		TmdbLanguage:      reqData.TmdbLanguage,
 		TmdbImageLanguage: reqData.TmdbImageLanguage,
 		TmdbEnableProxy:   reqData.TmdbEnableProxy,
+		TmdbOffline:       models.GlobalScrapeSettings.TmdbOffline, // 客户端是全局共享的，不能改变离线模式
 	}
 	testResult := tmpScrapeSetting.TestTmdb()
 	c.JSON(http.StatusOK, APIResponse[bool]{Code: Success, Message: "", Data: testResult})
 }
 
+// GetTmdbCache 获取TMDB缓存状态
+// @Summary 获取TMDB缓存状态
+// @Description 获取本地缓存的TMDB接口数量和离线模式开关
+// @Tags 刮削管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /scrape/tmdb-cache [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetTmdbCache(c *gin.Context) {
+	data := make(map[string]any)
+	data["total"] = models.GetTmdbCacheCount()
+	data["offline"] = models.GlobalScrapeSettings.TmdbOffline
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "", Data: data})
+}
+
+// SetTmdbOffline 开启或关闭TMDB离线模式
+// @Summary 开启或关闭TMDB离线模式
+// @Description 离线模式下刮削只使用本地缓存的TMDB数据，不请求TMDB，也不下载图片和字幕，用于无网络时重新生成nfo
+// @Tags 刮削管理
+// @Accept json
+// @Produce json
+// @Param offline body boolean true "是否开启离线模式"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /scrape/tmdb-cache/offline [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func SetTmdbOffline(c *gin.Context) {
+	type offlineReq struct {
+		Offline bool `json:"offline"`
+	}
+	var req offlineReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "请求参数错误: " + err.Error(), Data: nil})
+		return
+	}
+	if err := models.GlobalScrapeSettings.SaveTmdbOffline(req.Offline); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "保存TMDB离线模式成功", Data: nil})
+}
+
+// ClearTmdbCache 清空TMDB缓存
+// @Summary 清空TMDB缓存
+// @Description 删除本地缓存的所有TMDB接口数据
+// @Tags 刮削管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /scrape/tmdb-cache [delete]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func ClearTmdbCache(c *gin.Context) {
+	if err := models.ClearTmdbCache(); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "TMDB缓存已清空", Data: nil})
+}
+
 // SaveAiSettings 保存AI识别设置
 // @Summary 保存AI识别设置
 // @Description 保存或更新AI识别模型的配置
@@ -238,6 +312,55 @@ func GetAiSettings(c *gin.Context) {
 	c.JSON(http.StatusOK, APIResponse[AiSettings]{Code: Success, Message: "", Data: aiSettings})
 }
 
+// GetSubtitleSettings 获取字幕设置
+// @Summary 获取字幕设置
+// @Description 获取字幕语言偏好和字幕提供者配置
+// @Tags 刮削管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /scrape/subtitle-settings [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetSubtitleSettings(c *gin.Context) {
+	subtitleSettings := SubtitleSettings{
+		SubtitleLanguages: models.GlobalScrapeSettings.SubtitleLanguages,
+		OpenSubtitlesKey:  models.GlobalScrapeSettings.OpenSubtitlesKey,
+		AssrtToken:        models.GlobalScrapeSettings.AssrtToken,
+		AssrtUrl:          models.GlobalScrapeSettings.AssrtUrl,
+	}
+	c.JSON(http.StatusOK, APIResponse[SubtitleSettings]{Code: Success, Message: "", Data: subtitleSettings})
+}
+
+// SaveSubtitleSettings 保存字幕设置
+// @Summary 保存字幕设置
+// @Description 保存字幕语言偏好和字幕提供者配置，刮削目录开启下载字幕后生效
+// @Tags 刮削管理
+// @Accept json
+// @Produce json
+// @Param subtitle_languages body string false "字幕语言偏好，逗号分隔，例如：zh-CN,zh-TW,en"
+// @Param opensubtitles_key body string false "OpenSubtitles API Key"
+// @Param assrt_token body string false "射手网(伪) API Token"
+// @Param assrt_url body string false "射手网(伪) API地址"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /scrape/subtitle-settings [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func SaveSubtitleSettings(c *gin.Context) {
+	reqData := SubtitleSettings{}
+	if err := c.ShouldBindJSON(&reqData); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
+	if err := models.GlobalScrapeSettings.SaveSubtitle(reqData.SubtitleLanguages, reqData.OpenSubtitlesKey, reqData.AssrtToken, reqData.AssrtUrl); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "保存字幕设置成功", Data: nil})
+}
+
 // GetMovieGenre 获取电影分类
 // @Summary 获取电影分类
 // @Description 获取TMDB电影分类列表
@@ -501,6 +624,8 @@ func GetScrapePath(c *gin.Context) {
 // @Param source_type body integer true "来源类型"
 // @Param source_path body string true "来源路径"
 // @Param dest_path body string true "目标路径"
+// @Param dest_account_id body integer false "目标路径的账号ID，不填和来源相同"
+// @Param dest_source_type body string false "目标路径类型，不填和来源相同，和来源不同时通过上传队列转移文件"
 // @Success 200 {object} object
 // @Failure 200 {object} object
 // @Router /scrape/pathes [post]
@@ -527,14 +652,25 @@ func SaveScrapePath(c *gin.Context) {
 			return
 		}
 		reqData.SourcePath = sourcePath
-		if reqData.DestPathId != "" {
-			destPath := models.GetPathByPathFileId(account, reqData.DestPathId)
-			if destPath == "" {
-				c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "查询目标目录失败", Data: nil})
-				return
-			}
-			reqData.DestPath = destPath
+	}
+	// 目标是115时用目标账号查询实际的目录
+	if reqData.GetDestSourceType() == models.SourceType115 && reqData.DestPathId != "" {
+		account, err := models.GetAccountById(reqData.GetDestAccountId())
+		if err != nil {
+			c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+			return
 		}
+		destPath := models.GetPathByPathFileId(account, reqData.DestPathId)
+		if destPath == "" {
+			c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "查询目标目录失败", Data: nil})
+			return
+		}
+		reqData.DestPath = destPath
+	}
+	// 跨存储整理通过上传队列转移文件，不能创建链接
+	if reqData.IsCrossStorage() && reqData.RenameType != models.RenameTypeMove && reqData.RenameType != models.RenameTypeCopy {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "目标路径和来源不在同一个存储时只支持移动或复制", Data: nil})
+		return
 	}
 	helpers.AppLogger.Infof("最大线程数：%d", reqData.MaxThreads)
 
@@ -610,6 +746,7 @@ func DeleteScrapePath(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id body integer true "路径ID"
+// @Param full_scan body boolean false "是否强制全量扫描，开启增量扫描时也扫描所有目录"
 // @Success 200 {object} object
 // @Failure 200 {object} object
 // @Router /scrape/pathes/start [post]
@@ -617,7 +754,8 @@ func DeleteScrapePath(c *gin.Context) {
 // @Security ApiKeyAuth
 func ScanScrapePath(c *gin.Context) {
 	type ScanScrapePathReq struct {
-		ID uint `json:"id" form:"id"`
+		ID       uint `json:"id" form:"id"`
+		FullScan bool `json:"full_scan" form:"full_scan"`
 	}
 	reqData := ScanScrapePathReq{}
 	if err := c.ShouldBindJSON(&reqData); err != nil {
@@ -640,6 +778,7 @@ func ScanScrapePath(c *gin.Context) {
 		SourceType:   scrapePath.SourceType,
 		IsFile:       false,
 		TaskType:     synccron.SyncTaskTypeScrape,
+		FullScan:     reqData.FullScan,
 	}
 	if err := synccron.AddNewSyncTask(taskObj); err != nil {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "添加刮削任务失败: " + err.Error(), Data: nil})
@@ -989,6 +1128,397 @@ func ReScrape(c *gin.Context) {
 	}
 }
 
+// GetScrapeReviewRecords 获取待确认的刮削记录
+// @Summary 获取待确认的刮削记录
+// @Description 分页获取识别置信度低于阈值的电影记录，包含候选影片
+// @Tags 刮削管理
+// @Accept json
+// @Produce json
+// @Param page query integer false "页码"
+// @Param pageSize query integer false "每页数量"
+// @Param name query string false "名称"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /scrape/review [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetScrapeReviewRecords(c *gin.Context) {
+	page := helpers.StringToInt(c.Query("page"))
+	if page == 0 {
+		page = 1
+	}
+	pageSize := helpers.StringToInt(c.Query("pageSize"))
+	if pageSize == 0 {
+		pageSize = 100
+	}
+	total, scrapeRecords := models.GetScrapeMediaFiles(page, pageSize, string(models.MediaTypeMovie), string(models.ScrapeMediaStatusNeedsReview), c.Query("name"))
+	type reviewResp struct {
+		ID           uint                        `json:"id"`
+		Path         string                      `json:"path"`
+		FileName     string                      `json:"file_name"`
+		Name         string                      `json:"name"`
+		Year         int                         `json:"year"`
+		TmdbID       int64                       `json:"tmdb_id"`
+		Confidence   int                         `json:"confidence"`    // 当前识别结果的置信度
+		Duration     int64                       `json:"duration"`      // 视频时长，单位：分钟
+		FailedReason string                      `json:"failed_reason"` // 待确认原因
+		ScrapedAt    int64                       `json:"scraped_at"`    // 刮削时间
+		Candidates   []*models.IdentifyCandidate `json:"candidates"`    // 候选影片，按置信度倒序
+	}
+	list := make([]*reviewResp, 0, len(scrapeRecords))
+	for _, scrapeMedia := range scrapeRecords {
+		item := &reviewResp{
+			ID:           scrapeMedia.ID,
+			Path:         scrapeMedia.Path,
+			FileName:     scrapeMedia.VideoFilename,
+			Name:         scrapeMedia.Name,
+			Year:         scrapeMedia.Year,
+			TmdbID:       scrapeMedia.TmdbId,
+			Confidence:   scrapeMedia.Confidence,
+			FailedReason: scrapeMedia.FailedReason,
+			ScrapedAt:    scrapeMedia.ScrapeTime,
+			Candidates:   scrapeMedia.GetCandidates(),
+		}
+		if scrapeMedia.VideoCodec != nil {
+			item.Duration = scrapeMedia.VideoCodec.DurationInMinutes
+		}
+		list = append(list, item)
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "获取待确认记录成功", Data: map[string]any{"total": total, "list": list}})
+}
+
+// ConfirmScrapeReview 确认待确认记录的识别结果
+// @Summary 确认识别结果
+// @Description 选择一个候选影片（或者输入tmdb id），记录改为待刮削，下次刮削时使用该影片
+// @Tags 刮削管理
+// @Accept json
+// @Produce json
+// @Param id body integer true "记录ID"
+// @Param tmdb_id body integer false "选择的TMDB ID，为0时确认当前识别结果"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /scrape/review/confirm [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func ConfirmScrapeReview(c *gin.Context) {
+	type confirmReq struct {
+		ID     uint  `json:"id"`
+		TmdbId int64 `json:"tmdb_id"`
+	}
+	var req confirmReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "请求参数错误: " + err.Error(), Data: nil})
+		return
+	}
+	scrapeMedia := models.GetScrapeMediaFileById(req.ID)
+	if scrapeMedia == nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "没有找到要确认的记录", Data: nil})
+		return
+	}
+	if err := scrapeMedia.ConfirmReview(req.TmdbId); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "确认识别结果失败: " + err.Error(), Data: nil})
+		return
+	}
+	data := make(map[string]any)
+	data["name"] = scrapeMedia.Name
+	data["year"] = scrapeMedia.Year
+	data["tmdb_id"] = scrapeMedia.TmdbId
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "操作成功，下次扫描时会使用确认的影片进行刮削", Data: data})
+}
+
+// RegenerateNfo 批量重新生成nfo和图片
+// @Summary 批量重新生成nfo和图片
+// @Description 使用数据库中已刮削的元数据重新生成已整理记录的nfo和图片，不请求TMDB，本地目录直接覆盖，网盘加入上传队列覆盖上传
+// @Tags 刮削管理
+// @Accept json
+// @Produce json
+// @Param scrape_path_id body integer false "刮削目录ID，不传或者0表示所有刮削目录"
+// @Param media_type body string false "媒体类型：movie、tvshow，不传表示所有类型"
+// @Param start_time body integer false "整理时间范围开始（秒级时间戳）"
+// @Param end_time body integer false "整理时间范围结束（秒级时间戳）"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /scrape/regenerate-nfo [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func RegenerateNfo(c *gin.Context) {
+	var req scrape.RegenerateOptions
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "请求参数错误: " + err.Error(), Data: nil})
+		return
+	}
+	if req.EndTime > 0 && req.StartTime > req.EndTime {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "开始时间不能晚于结束时间", Data: nil})
+		return
+	}
+	if err := scrape.StartRegenerate(req); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "已开始重新生成nfo和图片，网盘目录的文件会加入上传队列", Data: nil})
+}
+
+// GetRegenerateNfoStatus 获取重新生成nfo和图片的进度
+// @Summary 获取重新生成nfo和图片的进度
+// @Description 返回当前或最近一次重新生成任务的筛选条件和处理数量
+// @Tags 刮削管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /scrape/regenerate-nfo [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetRegenerateNfoStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "", Data: scrape.GetRegenerateStatus()})
+}
+
+// GetRenameJournalRuns 获取整理批次列表
+// @Summary 获取整理批次列表
+// @Description 每次刮削的整理操作（移动、改名、复制、链接、创建目录、删除）按批次记录，返回批次的操作数和撤销情况，最新的在前面
+// @Tags 刮削管理
+// @Accept json
+// @Produce json
+// @Param scrape_path_id query integer false "刮削目录ID，不传表示所有刮削目录"
+// @Param page query integer false "页码，默认1"
+// @Param pageSize query integer false "每页数量，默认20"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /scrape/rename-journal/runs [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetRenameJournalRuns(c *gin.Context) {
+	page := helpers.StringToInt(c.Query("page"))
+	if page == 0 {
+		page = 1
+	}
+	pageSize := helpers.StringToInt(c.Query("pageSize"))
+	if pageSize == 0 {
+		pageSize = 20
+	}
+	scrapePathId := helpers.StringToInt(c.Query("scrape_path_id"))
+	runs, total := models.GetRenameJournalRuns(uint(scrapePathId), page, pageSize)
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "获取整理批次成功", Data: map[string]any{"total": total, "list": runs}})
+}
+
+// GetRenameJournals 获取整理批次的操作日志
+// @Summary 获取整理批次的操作日志
+// @Description 按执行顺序返回批次内的所有整理操作和撤销状态
+// @Tags 刮削管理
+// @Accept json
+// @Produce json
+// @Param run_no query string true "批次号"
+// @Param scrape_path_id query integer false "刮削目录ID，不传表示批次内所有刮削目录"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /scrape/rename-journal [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetRenameJournals(c *gin.Context) {
+	runNo := c.Query("run_no")
+	if runNo == "" {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "批次号不能为空", Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "获取整理操作日志成功", Data: models.GetRenameJournalsByRunNo(runNo, uint(helpers.StringToInt(c.Query("scrape_path_id"))))})
+}
+
+// RevertRenameJournal 撤销整理批次
+// @Summary 撤销整理批次
+// @Description 按执行顺序的逆序撤销整个批次或者指定刮削记录的整理操作，在后台执行；整理后的文件已被移走、替换或者原位置已有同名文件时标记为冲突并跳过，删除操作无法撤销
+// @Tags 刮削管理
+// @Accept json
+// @Produce json
+// @Param run_no body string true "批次号"
+// @Param scrape_path_id body integer false "刮削目录ID，不传表示撤销批次内所有刮削目录的操作"
+// @Param scrape_media_file_ids body []integer false "只撤销这些刮削记录的操作，不传表示撤销整个批次"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /scrape/rename-journal/revert [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func RevertRenameJournal(c *gin.Context) {
+	type revertReq struct {
+		RunNo              string `json:"run_no"`
+		ScrapePathId       uint   `json:"scrape_path_id"`
+		ScrapeMediaFileIds []uint `json:"scrape_media_file_ids"`
+	}
+	var req revertReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "请求参数错误: " + err.Error(), Data: nil})
+		return
+	}
+	if req.RunNo == "" {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "批次号不能为空", Data: nil})
+		return
+	}
+	if err := scrape.StartRevertRenameJournal(req.RunNo, req.ScrapePathId, req.ScrapeMediaFileIds); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "已开始撤销，可以在操作日志中查看每条操作的撤销结果", Data: nil})
+}
+
+// GetScrapeLinks 获取镜像整理的链接列表
+// @Summary 获取镜像整理的链接列表
+// @Description 本地来源使用硬链接或软链接整理时，返回整理目录中的链接和来源文件的对应关系
+// @Tags 刮削管理
+// @Accept json
+// @Produce json
+// @Param scrape_path_id query integer true "刮削目录ID"
+// @Param page query integer false "页码，默认1"
+// @Param pageSize query integer false "每页数量，默认20"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /scrape/links [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetScrapeLinks(c *gin.Context) {
+	scrapePathId := helpers.StringToInt(c.Query("scrape_path_id"))
+	if scrapePathId == 0 {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "刮削目录ID不能为空", Data: nil})
+		return
+	}
+	page := helpers.StringToInt(c.Query("page"))
+	if page == 0 {
+		page = 1
+	}
+	pageSize := helpers.StringToInt(c.Query("pageSize"))
+	if pageSize == 0 {
+		pageSize = 20
+	}
+	links, total := models.GetScrapeLinks(uint(scrapePathId), page, pageSize)
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "获取链接列表成功", Data: map[string]any{"total": total, "list": links}})
+}
+
+// CheckScrapeLinks 检查镜像整理的链接
+// @Summary 检查镜像整理的链接
+// @Description 来源文件已删除时清理链接，来源文件被替换（例如洗版）或者链接丢失时重新链接；每次刮削开始时也会自动检查
+// @Tags 刮削管理
+// @Accept json
+// @Produce json
+// @Param scrape_path_id body integer true "刮削目录ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /scrape/links/check [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func CheckScrapeLinks(c *gin.Context) {
+	type checkReq struct {
+		ScrapePathId uint `json:"scrape_path_id"`
+	}
+	var req checkReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "请求参数错误: " + err.Error(), Data: nil})
+		return
+	}
+	scrapePath := models.GetScrapePathByID(req.ScrapePathId)
+	if scrapePath == nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "刮削目录不存在", Data: nil})
+		return
+	}
+	if scrapePath.IsScraping {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "刮削目录正在刮削，刮削开始时已经检查过链接", Data: nil})
+		return
+	}
+	removed, relinked, err := scrapePath.CheckLinks()
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "检查链接完成", Data: map[string]any{"removed": removed, "relinked": relinked}})
+}
+
+// GetMissingEpisodes 获取缺集报告
+// @Summary 获取缺集报告
+// @Description 按TMDB的集列表统计每部剧集已播出但没有入库的集、不完整的季和特别篇情况，报告在后台生成（每天自动生成一次），返回最近一次生成的结果
+// @Tags 刮削管理
+// @Accept json
+// @Produce json
+// @Param air_status query string false "播出状态：continuing-连载中 ended-已完结，不传表示全部"
+// @Param only_incomplete query integer false "只返回有缺集的剧集：1-是 0-否"
+// @Param include_specials query integer false "特别篇缺集也算不完整：1-是 0-否"
+// @Param keyword query string false "按名称过滤"
+// @Param page query integer false "页码，默认1"
+// @Param pageSize query integer false "每页数量，默认20"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /scrape/missing-episodes [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetMissingEpisodes(c *gin.Context) {
+	page := helpers.StringToInt(c.Query("page"))
+	if page == 0 {
+		page = 1
+	}
+	pageSize := helpers.StringToInt(c.Query("pageSize"))
+	if pageSize == 0 {
+		pageSize = 20
+	}
+	filter := &models.EpisodeReportFilter{
+		AirStatus:       models.TvShowAirStatus(c.Query("air_status")),
+		OnlyIncomplete:  c.Query("only_incomplete") == "1",
+		IncludeSpecials: c.Query("include_specials") == "1",
+		Keyword:         c.Query("keyword"),
+	}
+	items, total, status := models.GetEpisodeReport(filter, page, pageSize)
+	status["total"] = total
+	status["list"] = items
+	status["notify_days"] = models.GlobalScrapeSettings.MissingNotifyDays
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "获取缺集报告成功", Data: status})
+}
+
+// RefreshMissingEpisodes 重新生成缺集报告
+// @Summary 重新生成缺集报告
+// @Description 在后台重新生成缺集报告，每部剧集都要查询TMDB（有缓存时使用缓存），剧集较多时需要一段时间
+// @Tags 刮削管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /scrape/missing-episodes/refresh [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func RefreshMissingEpisodes(c *gin.Context) {
+	if err := models.StartBuildEpisodeReport(); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "已开始生成缺集报告", Data: nil})
+}
+
+// SaveMissingEpisodeSettings 保存缺集通知设置
+// @Summary 保存缺集通知设置
+// @Description 新播出的集超过设置的天数仍然没有入库时发送通知，每一集只通知一次，0表示不通知
+// @Tags 刮削管理
+// @Accept json
+// @Produce json
+// @Param notify_days body integer true "天数，0表示不通知"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /scrape/missing-episodes/settings [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func SaveMissingEpisodeSettings(c *gin.Context) {
+	type settingsReq struct {
+		NotifyDays int `json:"notify_days"`
+	}
+	var req settingsReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "请求参数错误: " + err.Error(), Data: nil})
+		return
+	}
+	if req.NotifyDays < 0 {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "天数不能小于0", Data: nil})
+		return
+	}
+	if err := models.GlobalScrapeSettings.SaveMissingNotifyDays(req.NotifyDays); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "保存缺集通知设置成功", Data: nil})
+}
+
 // 清除所有刮削失败的记录
 func ClearFailedScrapeRecords(c *gin.Context) {
 	err := models.ClearFailedScrapeRecords([]uint{})
@@ -1356,3 +1886,71 @@ func TmdbSearch(c *gin.Context) {
 	}
 
 }
+
+// GetNamingPresets 获取命名预设
+// @Summary 获取命名预设
+// @Description 获取内置的 Emby、Jellyfin、Plex、Kodi 命名预设，刮削目录选择预设后保存时使用预设的文件夹和文件名模板
+// @Tags 刮削管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} object
+// @Router /scrape/naming-presets [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetNamingPresets(c *gin.Context) {
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "获取命名预设成功", Data: models.NamingPresets})
+}
+
+// CheckNameTemplate 检查命名模板
+// @Summary 检查命名模板
+// @Description 使用内置示例或者刮削目录的实际记录渲染文件夹或文件名模板，报告语法错误、生成的名称为空以及各系统不允许的字符
+// @Tags 刮削管理
+// @Accept json
+// @Produce json
+// @Param template body string true "模板"
+// @Param media_type body string false "媒体类型，不传时使用刮削目录的类型，默认电影"
+// @Param scrape_path_id body integer false "刮削目录ID，传入时使用该目录最近的已识别记录"
+// @Param record_ids body []integer false "刮削记录ID，传入时使用这些记录"
+// @Param limit body integer false "使用的最近记录数量，默认5"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /scrape/template/check [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func CheckNameTemplate(c *gin.Context) {
+	type checkReq struct {
+		Template     string           `json:"template"`
+		MediaType    models.MediaType `json:"media_type"`
+		ScrapePathId uint             `json:"scrape_path_id"`
+		RecordIds    []uint           `json:"record_ids"`
+		Limit        int              `json:"limit"`
+	}
+	var req checkReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "请求参数错误: " + err.Error(), Data: nil})
+		return
+	}
+	if req.Limit <= 0 {
+		req.Limit = 5
+	}
+	var samples []*models.ScrapeMediaFile
+	if len(req.RecordIds) > 0 {
+		samples = models.GetScrapeMediaFilesByIds(req.RecordIds)
+	} else if req.ScrapePathId > 0 {
+		scrapePath := models.GetScrapePathByID(req.ScrapePathId)
+		if scrapePath == nil {
+			c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "刮削目录不存在", Data: nil})
+			return
+		}
+		if req.MediaType == "" {
+			req.MediaType = scrapePath.MediaType
+		}
+		samples = models.GetTemplateSampleRecords(req.ScrapePathId, req.Limit)
+	}
+	// 没有实际记录时使用内置示例
+	if len(samples) == 0 {
+		samples = models.TemplateSampleMediaFiles(req.MediaType)
+	}
+	result := models.CheckNameTemplate(req.Template, samples)
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "检查模板完成", Data: result})
+}
@@ -3,6 +3,7 @@ package controllers
 import (
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/notification"
 	"Q115-STRM/internal/notificationmanager"
 	"Q115-STRM/internal/synccron"
 	"context"
@@ -251,7 +252,10 @@ func SyncStrmInc(args []string) helpers.CommandResponse {
 		return helpers.CommandResponse{Text: errMsg}
 	}
 	_, taskID := checkAndExtractSingleParam(args)
-	return helpers.CommandResponse{Text: runStrmTask(taskID, false)}
+	return helpers.CommandResponse{
+		Text:   runStrmTask(taskID, false),
+		OnSent: trackTaskProgress("🔄 增量STRM同步进度", newTaskProgresses(synccron.SyncTaskTypeStrm, taskID)),
+	}
 }
 
 // SyncStrnFull 执行全量STRM同步并在完成后发送通知
@@ -261,7 +265,10 @@ func SyncStrnFull(args []string) helpers.CommandResponse {
 		return helpers.CommandResponse{Text: errMsg}
 	}
 	_, taskID := checkAndExtractSingleParam(args)
-	return helpers.CommandResponse{Text: runStrmTask(taskID, true)}
+	return helpers.CommandResponse{
+		Text:   runStrmTask(taskID, true),
+		OnSent: trackTaskProgress("🚀 全量STRM同步进度", newTaskProgresses(synccron.SyncTaskTypeStrm, taskID)),
+	}
 }
 
 // Scrape 执行刮削任务并在完成后发送通知
@@ -271,7 +278,10 @@ func Scrape(args []string) helpers.CommandResponse {
 		return helpers.CommandResponse{Text: errMsg}
 	}
 	_, taskID := checkAndExtractSingleParam(args)
-	return helpers.CommandResponse{Text: runScrapeTask(taskID)}
+	return helpers.CommandResponse{
+		Text:   runScrapeTask(taskID),
+		OnSent: trackTaskProgress("🎬 刮削进度", newTaskProgresses(synccron.SyncTaskTypeScrape, taskID)),
+	}
 }
 
 // waitForTasksCompletion 等待指定任务完成
@@ -363,7 +373,7 @@ func runStrmThenScrape(extractedIDs []uint) string {
 			var hasNewScrapeFiles bool
 
 			// 检查是否有新文件
-			if len(extractedIDs) == 0 || extractedIDs[1] == 0 {
+			if len(extractedIDs) < 2 || extractedIDs[1] == 0 {
 				// 检查所有刮削目录是否有新文件
 				allScrapePaths := models.GetScrapePathes("")
 				for _, scrapePath := range allScrapePaths {
@@ -443,36 +453,54 @@ func runStrmThenScrape(extractedIDs []uint) string {
 	return "🔄 开始执行任务序列"
 }
 
+// 取任务序列中第index个目录ID，没有传入时为0，表示所有目录
+func sequenceParam(extractedIDs []uint, index int) uint {
+	if len(extractedIDs) > index {
+		return extractedIDs[index]
+	}
+	return 0
+}
+
 // ScrapeThenStrm 先执行刮削任务，完成后再执行同步任务
 // args: 参数格式为 #数字 #数字，分别代表刮削目录ID和同步目录ID
 // 如果参数为0，则执行所有目录的操作
-func ScrapeThenStrm(args []string) string {
+func ScrapeThenStrm(args []string) helpers.CommandResponse {
 	// 检查参数格式
 	if errMsg, _ := checkAndExtractMoreParam(args); errMsg != "" {
-		return errMsg
+		return helpers.CommandResponse{Text: errMsg}
 	}
 
 	// 解析参数
 	_, extractedIDs := checkAndExtractMoreParam(args)
+	tasks := append(newTaskProgresses(synccron.SyncTaskTypeScrape, sequenceParam(extractedIDs, 0)),
+		newTaskProgresses(synccron.SyncTaskTypeStrm, sequenceParam(extractedIDs, 1))...)
 
 	// 调用 runScrapeThenStrm 执行任务序列
-	return runScrapeThenStrm(extractedIDs)
+	return helpers.CommandResponse{
+		Text:   runScrapeThenStrm(extractedIDs),
+		OnSent: trackTaskProgress("🎬🔄 先刮削后同步进度", tasks),
+	}
 }
 
 // StrmThenScrape 先执行同步任务，完成后再执行刮削任务
 // args: 参数格式为 #数字 #数字，分别代表同步目录ID和刮削目录ID
 // 如果参数为0，则执行所有目录的操作
-func StrmThenScrape(args []string) string {
+func StrmThenScrape(args []string) helpers.CommandResponse {
 	// 检查参数格式
 	if errMsg, _ := checkAndExtractMoreParam(args); errMsg != "" {
-		return errMsg
+		return helpers.CommandResponse{Text: errMsg}
 	}
 
 	// 解析参数
 	_, extractedIDs := checkAndExtractMoreParam(args)
+	tasks := append(newTaskProgresses(synccron.SyncTaskTypeStrm, sequenceParam(extractedIDs, 0)),
+		newTaskProgresses(synccron.SyncTaskTypeScrape, sequenceParam(extractedIDs, 1))...)
 
 	// 调用 runStrmThenScrape 执行任务序列
-	return runStrmThenScrape(extractedIDs)
+	return helpers.CommandResponse{
+		Text:   runStrmThenScrape(extractedIDs),
+		OnSent: trackTaskProgress("🔄🎬 先同步后刮削进度", tasks),
+	}
 }
 
 // ParseStrmPathArgs 解析get_strm_path命令的参数
@@ -611,6 +639,65 @@ func getScrapePath(args []string) helpers.CommandResponse {
 	}
 }
 
+// 按钮上显示的目录名称，太长时只保留结尾部分
+func shortPathName(path string) string {
+	runes := []rune(path)
+	if len(runes) <= 20 {
+		return path
+	}
+	return "…" + string(runes[len(runes)-19:])
+}
+
+// strmMenu 选择同步目录执行STRM同步的菜单
+func strmMenu(args []string) helpers.CommandResponse {
+	syncPaths, _ := models.GetSyncPathList(1, 10000000, false, "")
+	if len(syncPaths) == 0 {
+		return helpers.CommandResponse{Text: "📂 还没有添加同步目录"}
+	}
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔄 全部增量同步", "strm_inc"),
+			tgbotapi.NewInlineKeyboardButtonData("🚀 全部全量同步", "strm_sync"),
+		),
+	}
+	for _, sp := range syncPaths {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🔄 #%d %s", sp.ID, shortPathName(sp.RemotePath)), fmt.Sprintf("strm_inc #%d", sp.ID)),
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🚀 #%d 全量", sp.ID), fmt.Sprintf("strm_sync #%d", sp.ID)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🔄🎬 全部先同步后刮削", "strm_scrape"),
+	))
+	return helpers.CommandResponse{
+		Text:        fmt.Sprintf("📂 <b>选择要同步的目录</b>\n共 %d 个同步目录，🔄为增量同步，🚀为全量同步", len(syncPaths)),
+		ReplyMarkup: tgbotapi.NewInlineKeyboardMarkup(rows...),
+	}
+}
+
+// scrapeMenu 选择刮削目录执行刮削的菜单
+func scrapeMenu(args []string) helpers.CommandResponse {
+	scrapePaths := models.GetScrapePathes("")
+	if len(scrapePaths) == 0 {
+		return helpers.CommandResponse{Text: "🗂 还没有添加刮削目录"}
+	}
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("🎬 全部刮削", "scrape")),
+	}
+	for _, sp := range scrapePaths {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🎬 #%d %s", sp.ID, shortPathName(sp.SourcePath)), fmt.Sprintf("scrape #%d", sp.ID)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🎬🔄 全部先刮削后同步", "scrape_strm"),
+	))
+	return helpers.CommandResponse{
+		Text:        fmt.Sprintf("🗂 <b>选择要刮削的目录</b>\n共 %d 个刮削目录", len(scrapePaths)),
+		ReplyMarkup: tgbotapi.NewInlineKeyboardMarkup(rows...),
+	}
+}
+
 func StartListenTelegramBot() {
 	mgr := notificationmanager.GlobalEnhancedNotificationManager
 
@@ -620,10 +707,43 @@ func StartListenTelegramBot() {
 		"scrape":          Scrape,
 		"get_strm_path":   getStrmPath,
 		"get_scrape_path": getScrapePath,
-		// "scrape_strm": ScrapeThenStrm,
-		// "strm_scrape": StrmThenScrape,
+		"scrape_strm":     ScrapeThenStrm,
+		"strm_scrape":     StrmThenScrape,
+		"strm_menu":       strmMenu,
+		"scrape_menu":     scrapeMenu,
+		"queue":           queueStatus,
+		"pause_upload":    pauseUploadQueue,
+		"resume_upload":   resumeUploadQueue,
+		"pause_download":  pauseDownloadQueue,
+		"resume_download": resumeDownloadQueue,
+		"throttle":        throttleStatus,
+		"review":          reviewList,
+		"review_ok":       confirmReview,
+		"backup":          startBackup,
+	}
+
+	// 命令需要的角色：只读用户只能查询，操作员可以执行任务，管理员可以修改设置
+	roles := map[string]notification.TelegramRole{
+		"get_strm_path":   notification.TelegramRoleViewer,
+		"get_scrape_path": notification.TelegramRoleViewer,
+		"strm_menu":       notification.TelegramRoleViewer,
+		"scrape_menu":     notification.TelegramRoleViewer,
+		"queue":           notification.TelegramRoleViewer,
+		"throttle":        notification.TelegramRoleViewer,
+		"review":          notification.TelegramRoleViewer,
+		"strm_inc":        notification.TelegramRoleOperator,
+		"strm_sync":       notification.TelegramRoleOperator,
+		"scrape":          notification.TelegramRoleOperator,
+		"scrape_strm":     notification.TelegramRoleOperator,
+		"strm_scrape":     notification.TelegramRoleOperator,
+		"review_ok":       notification.TelegramRoleOperator,
+		"backup":          notification.TelegramRoleOperator,
+		"pause_upload":    notification.TelegramRoleOperator,
+		"resume_upload":   notification.TelegramRoleOperator,
+		"pause_download":  notification.TelegramRoleOperator,
+		"resume_download": notification.TelegramRoleOperator,
 	}
 
-	mgr.RegisterTelegramCommands(myCommands)
+	mgr.RegisterTelegramCommands(myCommands, roles)
 	mgr.StartAll()
 }
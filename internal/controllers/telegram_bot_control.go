@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/backup"
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/v115open"
+	"fmt"
+	"html"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Telegram机器人一次最多显示的待确认记录数量
+const telegramReviewPageSize = 5
+
+func queueRunningText(running bool) string {
+	if running {
+		return "▶️ 运行中"
+	}
+	return "⏸️ 已暂停"
+}
+
+// queueStatus 查看上传下载队列的状态，通过按钮暂停或恢复
+func queueStatus(args []string) helpers.CommandResponse {
+	var downloads, uploads int64
+	db.Db.Model(&models.DbDownloadTask{}).Where("status IN ?", []models.DownloadStatus{models.DownloadStatusPending, models.DownloadStatusDownloading}).Count(&downloads)
+	db.Db.Model(&models.DbUploadTask{}).Where("status IN ?", []models.UploadStatus{models.UploadStatusPending, models.UploadStatusUploading}).Count(&uploads)
+	uploadRunning := models.GlobalUploadQueue.IsRunning()
+	downloadRunning := models.GlobalDownloadQueue.IsRunning()
+
+	text := "📦 <b>队列状态</b>\n"
+	text += fmt.Sprintf("⬆️ 上传队列: %s，待处理 %d 个\n", queueRunningText(uploadRunning), uploads)
+	text += fmt.Sprintf("⬇️ 下载队列: %s，待处理 %d 个\n", queueRunningText(downloadRunning), downloads)
+
+	uploadButton := tgbotapi.NewInlineKeyboardButtonData("⏸️ 暂停上传", "pause_upload")
+	if !uploadRunning {
+		uploadButton = tgbotapi.NewInlineKeyboardButtonData("▶️ 恢复上传", "resume_upload")
+	}
+	downloadButton := tgbotapi.NewInlineKeyboardButtonData("⏸️ 暂停下载", "pause_download")
+	if !downloadRunning {
+		downloadButton = tgbotapi.NewInlineKeyboardButtonData("▶️ 恢复下载", "resume_download")
+	}
+	return helpers.CommandResponse{
+		Text: text,
+		ReplyMarkup: tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(uploadButton, downloadButton),
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("🔃 刷新", "queue")),
+		),
+	}
+}
+
+// pauseUploadQueue 暂停上传队列
+func pauseUploadQueue(args []string) helpers.CommandResponse {
+	models.GlobalUploadQueue.Stop()
+	helpers.AppLogger.Infof("通过Telegram机器人暂停了上传队列")
+	return queueStatus(args)
+}
+
+// resumeUploadQueue 恢复上传队列
+func resumeUploadQueue(args []string) helpers.CommandResponse {
+	models.GlobalUploadQueue.Restart()
+	helpers.AppLogger.Infof("通过Telegram机器人恢复了上传队列")
+	return queueStatus(args)
+}
+
+// pauseDownloadQueue 暂停下载队列
+func pauseDownloadQueue(args []string) helpers.CommandResponse {
+	models.GlobalDownloadQueue.Stop()
+	helpers.AppLogger.Infof("通过Telegram机器人暂停了下载队列")
+	return queueStatus(args)
+}
+
+// resumeDownloadQueue 恢复下载队列
+func resumeDownloadQueue(args []string) helpers.CommandResponse {
+	models.GlobalDownloadQueue.Restart()
+	helpers.AppLogger.Infof("通过Telegram机器人恢复了下载队列")
+	return queueStatus(args)
+}
+
+// throttleStatus 查看115 OpenAPI的限流状态和最近一小时的请求统计
+func throttleStatus(args []string) helpers.CommandResponse {
+	executor := v115open.GetGlobalExecutor()
+	status := executor.GetThrottleStatus()
+	stats := executor.GetStats(time.Hour)
+
+	text := "🚦 <b>115 限流状态</b>\n"
+	if status.IsThrottled {
+		text += fmt.Sprintf("状态: 🔴 限流中，已限流 %s，预计 %s 后恢复\n", formatDuration(status.ElapsedTime), formatDuration(status.RemainingTime))
+	} else {
+		text += "状态: 🟢 正常\n"
+	}
+	text += fmt.Sprintf("最近1分钟请求: %d\n", stats.QPMCount)
+	text += fmt.Sprintf("最近1小时请求: %d\n", stats.QPHCount)
+	text += fmt.Sprintf("平均响应时间: %d 毫秒\n", stats.AvgResponseTime)
+	text += fmt.Sprintf("累计限流次数: %d\n", stats.ThrottledCount)
+	if stats.LastThrottleTime != nil {
+		text += fmt.Sprintf("最后一次限流: %s\n", stats.LastThrottleTime.Format("2006-01-02 15:04:05"))
+	}
+	return helpers.CommandResponse{
+		Text: text,
+		ReplyMarkup: tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("🔃 刷新", "throttle")),
+		),
+	}
+}
+
+// reviewList 列出待确认的识别结果，每条记录可以确认当前结果或者选择置信度最高的候选影片
+func reviewList(args []string) helpers.CommandResponse {
+	total, records := models.GetScrapeMediaFiles(1, telegramReviewPageSize, string(models.MediaTypeMovie), string(models.ScrapeMediaStatusNeedsReview), "")
+	if total == 0 {
+		return helpers.CommandResponse{Text: "🔍 没有待确认的识别结果"}
+	}
+	text := fmt.Sprintf("🔍 <b>待确认的识别结果</b>\n共 %d 条，显示前 %d 条\n\n", total, len(records))
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, record := range records {
+		text += fmt.Sprintf("#%d %s\n", record.ID, html.EscapeString(record.VideoFilename))
+		text += fmt.Sprintf("  当前: %s (%d) 置信度 %d\n", html.EscapeString(record.Name), record.Year, record.Confidence)
+		row := tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("✅ #%d 确认当前", record.ID), fmt.Sprintf("review_ok #%d", record.ID)),
+		)
+		// 第一个和当前结果不同的候选影片
+		for _, candidate := range record.GetCandidates() {
+			if candidate.IsCurrent || candidate.TmdbId == record.TmdbId {
+				continue
+			}
+			text += fmt.Sprintf("  候选: %s (%d) 置信度 %d\n", html.EscapeString(candidate.Name), candidate.Year, candidate.Confidence)
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("🔁 #%d 改为候选", record.ID),
+				fmt.Sprintf("review_ok #%d #%d", record.ID, candidate.TmdbId),
+			))
+			break
+		}
+		text += "\n"
+		rows = append(rows, row)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("🔃 刷新", "review")))
+	return helpers.CommandResponse{
+		Text:        text,
+		ReplyMarkup: tgbotapi.NewInlineKeyboardMarkup(rows...),
+	}
+}
+
+// confirmReview 确认识别结果
+// args: #记录ID [#TMDB ID]，不传TMDB ID时确认当前识别结果
+func confirmReview(args []string) helpers.CommandResponse {
+	errMsg, ids := checkAndExtractMoreParam(args)
+	if errMsg != "" {
+		return helpers.CommandResponse{Text: errMsg}
+	}
+	if len(ids) == 0 {
+		return helpers.CommandResponse{Text: "❌ 请指定要确认的记录，格式: /review_ok #记录ID [#TMDB ID]"}
+	}
+	scrapeMedia := models.GetScrapeMediaFileById(ids[0])
+	if scrapeMedia == nil {
+		return helpers.CommandResponse{Text: "❌ 没有找到要确认的记录"}
+	}
+	var tmdbId int64
+	if len(ids) > 1 {
+		tmdbId = int64(ids[1])
+	}
+	if err := scrapeMedia.ConfirmReview(tmdbId); err != nil {
+		return helpers.CommandResponse{Text: "❌ 确认识别结果失败: " + html.EscapeString(err.Error())}
+	}
+	return helpers.CommandResponse{
+		Text: fmt.Sprintf("✅ 已确认 #%d 为 TMDB %d，下次刮削时会使用确认的影片", scrapeMedia.ID, scrapeMedia.TmdbId),
+		ReplyMarkup: tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("🔍 继续确认", "review")),
+		),
+	}
+}
+
+// startBackup 立即备份数据，备份结束后修改回复显示结果
+func startBackup(args []string) helpers.CommandResponse {
+	if backup.IsRunning() {
+		return helpers.CommandResponse{Text: "⏳ 备份任务正在运行中"}
+	}
+	result := make(chan error, 1)
+	go func() {
+		err := backup.Backup(models.BackupTypeManual, "Telegram机器人备份")
+		if err != nil {
+			helpers.AppLogger.Errorf("Telegram机器人触发的备份失败: %v", err)
+		}
+		result <- err
+	}()
+	return helpers.CommandResponse{
+		Text: "💾 已开始备份数据，请稍候",
+		OnSent: func(edit helpers.MessageEditor) {
+			text := "✅ 数据备份完成"
+			if err := <-result; err != nil {
+				text = "❌ 数据备份失败: " + html.EscapeString(err.Error())
+			}
+			if err := edit(text, nil); err != nil {
+				helpers.AppLogger.Warnf("更新Telegram备份结果失败: %v", err)
+			}
+		},
+	}
+}
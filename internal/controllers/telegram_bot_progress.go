@@ -0,0 +1,231 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/synccron"
+	ws "Q115-STRM/internal/websocket"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+)
+
+const (
+	// 进度消息最短的修改间隔，避免触发Telegram的频率限制
+	progressEditInterval = 5 * time.Second
+	// 超过这个时间不再更新进度消息
+	progressTimeout = 12 * time.Hour
+)
+
+// 单个同步/刮削任务的进度
+type taskProgress struct {
+	id        uint
+	taskType  synccron.SyncTaskType
+	name      string
+	seen      bool // 是否已经在队列中看到过这个任务
+	startedAt time.Time
+	doneAt    time.Time
+	done      bool
+	success   bool
+	errMsg    string
+	items     int // 已处理的刮削文件数量
+	failed    int // 刮削失败的文件数量
+}
+
+// 根据目录ID获取要跟踪进度的任务，ID为0时和执行任务时一样取所有目录
+func newTaskProgresses(taskType synccron.SyncTaskType, id uint) []*taskProgress {
+	var tasks []*taskProgress
+	switch taskType {
+	case synccron.SyncTaskTypeStrm:
+		if id > 0 {
+			if syncPath := models.GetSyncPathById(id); syncPath != nil {
+				tasks = append(tasks, &taskProgress{id: syncPath.ID, taskType: taskType, name: syncPath.RemotePath})
+			}
+			return tasks
+		}
+		syncPaths, _ := models.GetSyncPathList(1, 10000000, false, "")
+		for _, syncPath := range syncPaths {
+			tasks = append(tasks, &taskProgress{id: syncPath.ID, taskType: taskType, name: syncPath.RemotePath})
+		}
+	case synccron.SyncTaskTypeScrape:
+		if id > 0 {
+			if scrapePath := models.GetScrapePathByID(id); scrapePath != nil {
+				tasks = append(tasks, &taskProgress{id: scrapePath.ID, taskType: taskType, name: scrapePath.SourcePath})
+			}
+			return tasks
+		}
+		for _, scrapePath := range models.GetScrapePathes("") {
+			tasks = append(tasks, &taskProgress{id: scrapePath.ID, taskType: taskType, name: scrapePath.SourcePath})
+		}
+	}
+	return tasks
+}
+
+// 根据事件更新任务进度
+func applyProgressEvent(tasks []*taskProgress, event ws.WSEvent) {
+	var taskType synccron.SyncTaskType
+	var id uint
+	var success bool
+	var errMsg string
+	switch data := event.Data.(type) {
+	case ws.StrmSyncTaskData:
+		taskType, id, success, errMsg = synccron.SyncTaskTypeStrm, data.TaskID, data.Success, data.Error
+	case ws.ScrapeTaskData:
+		taskType, id, success = synccron.SyncTaskTypeScrape, data.TaskID, data.Success
+	case ws.ScrapeItemData:
+		taskType, id, success = synccron.SyncTaskTypeScrape, data.ScrapePathID, data.Success
+	default:
+		return
+	}
+	for _, task := range tasks {
+		if task.taskType != taskType || task.id != id {
+			continue
+		}
+		switch event.EventType {
+		case ws.EventStrmSyncTaskStart, ws.EventScraperTaskStart:
+			// 同一个目录可能被执行多次，重新开始计数
+			*task = taskProgress{id: task.id, taskType: task.taskType, name: task.name, seen: true, startedAt: event.Timestamp}
+		case ws.EventStrmSyncTaskComplete, ws.EventScraperTaskComplete:
+			if !task.seen {
+				continue
+			}
+			if task.startedAt.IsZero() {
+				task.startedAt = event.Timestamp
+			}
+			task.done = true
+			task.doneAt = event.Timestamp
+			task.success = success
+			task.errMsg = errMsg
+		case ws.EventScraperItemComplete:
+			if !task.seen || task.done {
+				continue
+			}
+			task.items++
+			if !success {
+				task.failed++
+			}
+		}
+	}
+}
+
+// 根据队列状态补充进度，错过了完成事件的任务也能结束
+func pollTaskProgress(tasks []*taskProgress, now time.Time) {
+	for _, task := range tasks {
+		if task.done {
+			continue
+		}
+		switch synccron.CheckNewTaskStatus(task.id, task.taskType) {
+		case synccron.TaskStatusWaiting, synccron.TaskStatusRunning:
+			task.seen = true
+		default:
+			// 在队列中出现过，现在已经不在队列中，说明任务已经结束
+			if task.seen {
+				task.done = true
+				task.success = true
+				task.doneAt = now
+				if task.startedAt.IsZero() {
+					task.startedAt = now
+				}
+			}
+		}
+	}
+}
+
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	if d >= time.Hour {
+		return fmt.Sprintf("%d小时%d分", int(d.Hours()), int(d.Minutes())%60)
+	}
+	if d >= time.Minute {
+		return fmt.Sprintf("%d分%d秒", int(d.Minutes()), int(d.Seconds())%60)
+	}
+	return fmt.Sprintf("%d秒", int(d.Seconds()))
+}
+
+// 生成进度消息的内容，返回内容和是否全部完成
+func formatTaskProgress(title string, tasks []*taskProgress, now time.Time) (string, bool) {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("<b>%s</b>\n", html.EscapeString(title)))
+	allDone := true
+	var lastType synccron.SyncTaskType
+	for _, task := range tasks {
+		if task.taskType != lastType {
+			lastType = task.taskType
+			if task.taskType == synccron.SyncTaskTypeScrape {
+				sb.WriteString("\n🎬 刮削\n")
+			} else {
+				sb.WriteString("\n🔄 STRM同步\n")
+			}
+		}
+		var status string
+		switch {
+		case task.done && task.success:
+			status = fmt.Sprintf("✅ 完成，用时 %s", formatDuration(task.doneAt.Sub(task.startedAt)))
+		case task.done:
+			status = "❌ 失败"
+			if task.errMsg != "" {
+				status += "：" + html.EscapeString(task.errMsg)
+			}
+		case !task.startedAt.IsZero():
+			status = fmt.Sprintf("🔄 运行中，已用时 %s", formatDuration(now.Sub(task.startedAt)))
+		default:
+			status = "⏳ 等待中"
+		}
+		if task.taskType == synccron.SyncTaskTypeScrape && task.items > 0 {
+			status += fmt.Sprintf("，已处理 %d 个文件", task.items)
+			if task.failed > 0 {
+				status += fmt.Sprintf("（失败 %d）", task.failed)
+			}
+		}
+		if !task.done {
+			allDone = false
+		}
+		sb.WriteString(fmt.Sprintf("#%d %s\n  %s\n", task.id, html.EscapeString(task.name), status))
+	}
+	if allDone {
+		sb.WriteString("\n🏁 全部任务已结束")
+	}
+	return sb.String(), allDone
+}
+
+// trackTaskProgress 返回回复发送后执行的函数，根据任务事件实时修改回复内容显示进度
+func trackTaskProgress(title string, tasks []*taskProgress) func(edit helpers.MessageEditor) {
+	return func(edit helpers.MessageEditor) {
+		if len(tasks) == 0 {
+			return
+		}
+		events, unsubscribe := ws.Subscribe(256)
+		defer unsubscribe()
+		ticker := time.NewTicker(progressEditInterval)
+		defer ticker.Stop()
+		timeout := time.After(progressTimeout)
+		lastText := ""
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					// 处理事件太慢被取消订阅，之后只靠定时查询任务状态
+					events = nil
+					continue
+				}
+				applyProgressEvent(tasks, event)
+			case <-timeout:
+				helpers.AppLogger.Infof("Telegram任务进度 %s 超过 %s 没有结束，停止更新", title, progressTimeout)
+				return
+			case now := <-ticker.C:
+				pollTaskProgress(tasks, now)
+				text, allDone := formatTaskProgress(title, tasks, now)
+				if text != lastText {
+					if err := edit(text, nil); err != nil {
+						helpers.AppLogger.Warnf("更新Telegram任务进度失败: %v", err)
+					}
+					lastText = text
+				}
+				if allDone {
+					return
+				}
+			}
+		}
+	}
+}
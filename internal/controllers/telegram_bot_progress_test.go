@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"Q115-STRM/internal/synccron"
+	ws "Q115-STRM/internal/websocket"
+)
+
+func TestTaskProgress(t *testing.T) {
+	start := time.Date(2026, 1, 1, 9, 0, 0, 0, time.Local)
+	tasks := []*taskProgress{
+		{id: 1, taskType: synccron.SyncTaskTypeStrm, name: "/电影"},
+		{id: 2, taskType: synccron.SyncTaskTypeScrape, name: "/电影<刮削>"},
+	}
+	events := []ws.WSEvent{
+		// 开始前的完成事件属于之前的任务，忽略
+		{EventType: ws.EventStrmSyncTaskComplete, Timestamp: start, Data: ws.StrmSyncTaskData{TaskID: 1, Success: true}},
+		{EventType: ws.EventStrmSyncTaskStart, Timestamp: start, Data: ws.StrmSyncTaskData{TaskID: 1}},
+		{EventType: ws.EventStrmSyncTaskComplete, Timestamp: start.Add(90 * time.Second), Data: ws.StrmSyncTaskData{TaskID: 1, Success: true}},
+		{EventType: ws.EventScraperTaskStart, Timestamp: start.Add(2 * time.Minute), Data: ws.ScrapeTaskData{TaskID: 2}},
+		{EventType: ws.EventScraperItemComplete, Timestamp: start.Add(3 * time.Minute), Data: ws.ScrapeItemData{ScrapePathID: 2, Success: true}},
+		{EventType: ws.EventScraperItemComplete, Timestamp: start.Add(3 * time.Minute), Data: ws.ScrapeItemData{ScrapePathID: 2}},
+		{EventType: ws.EventScraperItemComplete, Timestamp: start.Add(3 * time.Minute), Data: ws.ScrapeItemData{ScrapePathID: 3, Success: true}},
+	}
+	for _, event := range events {
+		applyProgressEvent(tasks, event)
+	}
+
+	text, allDone := formatTaskProgress("进度", tasks, start.Add(5*time.Minute))
+	if allDone {
+		t.Error("刮削还在运行，不应全部完成")
+	}
+	for _, expected := range []string{
+		"#1 /电影\n  ✅ 完成，用时 1分30秒\n",
+		"#2 /电影&lt;刮削&gt;\n  🔄 运行中，已用时 3分0秒，已处理 2 个文件（失败 1）\n",
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("进度内容缺少 %q:\n%s", expected, text)
+		}
+	}
+
+	applyProgressEvent(tasks, ws.WSEvent{EventType: ws.EventScraperTaskComplete, Timestamp: start.Add(6 * time.Minute), Data: ws.ScrapeTaskData{TaskID: 2}})
+	text, allDone = formatTaskProgress("进度", tasks, start.Add(6*time.Minute))
+	if !allDone || !strings.Contains(text, "❌ 失败，已处理 2 个文件（失败 1）") {
+		t.Errorf("刮削失败后应全部结束:\n%s", text)
+	}
+}
@@ -0,0 +1,307 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/webhook"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookSubscriptionRequest 添加或修改Webhook订阅请求
+type WebhookSubscriptionRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	URL         string   `json:"url" binding:"required"`
+	Secret      string   `json:"secret"` // 为空时自动生成，修改时为空表示不修改
+	Events      []string `json:"events" binding:"required"`
+	Enabled     bool     `json:"enabled"`
+	Description string   `json:"description"`
+}
+
+// 检查请求参数，返回逗号分隔的事件
+func (r *WebhookSubscriptionRequest) validate() (string, error) {
+	u, err := url.Parse(r.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("地址必须是 http 或 https 开头的完整URL")
+	}
+	events := make([]string, 0, len(r.Events))
+	for _, event := range r.Events {
+		event = strings.TrimSpace(event)
+		if event != "*" && !slices.Contains(webhook.Events, event) {
+			return "", fmt.Errorf("不支持的事件: %s", event)
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+	if len(events) == 0 {
+		return "", fmt.Errorf("至少需要订阅一个事件")
+	}
+	return strings.Join(events, ","), nil
+}
+
+// GetWebhookSubscriptions 获取Webhook订阅列表
+// @Summary 获取Webhook订阅列表
+// @Description 获取所有自动化Webhook订阅和可以订阅的事件
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /webhooks [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetWebhookSubscriptions(c *gin.Context) {
+	subs := make([]models.WebhookSubscription, 0)
+	if err := db.Db.Order("id ASC").Find(&subs).Error; err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "查询Webhook订阅失败", Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "查询成功", Data: gin.H{
+		"list":   subs,
+		"events": webhook.Events,
+	}})
+}
+
+// CreateWebhookSubscription 添加Webhook订阅
+// @Summary 添加Webhook订阅
+// @Description 添加自动化Webhook订阅。事件发生时向地址POST JSON，请求头 X-QMS-Signature 为 sha256=HMAC-SHA256(密钥, X-QMS-Timestamp + "." + 请求体)
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Param name body string true "名称"
+// @Param url body string true "接收地址"
+// @Param secret body string false "签名密钥，为空时自动生成"
+// @Param events body []string true "订阅的事件，* 表示所有事件"
+// @Param enabled body boolean false "是否启用"
+// @Param description body string false "备注"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /webhooks [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func CreateWebhookSubscription(c *gin.Context) {
+	var req WebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("参数错误：%v", err), Data: nil})
+		return
+	}
+	events, err := req.validate()
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
+	if req.Secret == "" {
+		if req.Secret, err = webhook.GenerateSecret(); err != nil {
+			c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("生成签名密钥失败：%v", err), Data: nil})
+			return
+		}
+	}
+	sub := &models.WebhookSubscription{
+		Name:        req.Name,
+		URL:         req.URL,
+		Secret:      req.Secret,
+		Events:      events,
+		Enabled:     req.Enabled,
+		Description: req.Description,
+	}
+	if err := db.Db.Create(sub).Error; err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("添加Webhook订阅失败：%v", err), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "添加成功", Data: sub})
+}
+
+// 按路径参数查询订阅，不存在时返回错误响应
+func getWebhookSubscriptionByParam(c *gin.Context) *models.WebhookSubscription {
+	var sub models.WebhookSubscription
+	if err := db.Db.First(&sub, helpers.StringToInt(c.Param("id"))).Error; err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "Webhook订阅不存在", Data: nil})
+		return nil
+	}
+	return &sub
+}
+
+// UpdateWebhookSubscription 修改Webhook订阅
+// @Summary 修改Webhook订阅
+// @Description 修改自动化Webhook订阅，secret为空时不修改密钥
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Param id path integer true "订阅ID"
+// @Param name body string true "名称"
+// @Param url body string true "接收地址"
+// @Param secret body string false "签名密钥"
+// @Param events body []string true "订阅的事件，* 表示所有事件"
+// @Param enabled body boolean false "是否启用"
+// @Param description body string false "备注"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /webhooks/{id} [put]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func UpdateWebhookSubscription(c *gin.Context) {
+	var req WebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("参数错误：%v", err), Data: nil})
+		return
+	}
+	events, err := req.validate()
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
+	sub := getWebhookSubscriptionByParam(c)
+	if sub == nil {
+		return
+	}
+	sub.Name = req.Name
+	sub.URL = req.URL
+	sub.Events = events
+	sub.Enabled = req.Enabled
+	sub.Description = req.Description
+	if req.Secret != "" {
+		sub.Secret = req.Secret
+	}
+	if err := db.Db.Save(sub).Error; err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("修改Webhook订阅失败：%v", err), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "修改成功", Data: sub})
+}
+
+// DeleteWebhookSubscription 删除Webhook订阅
+// @Summary 删除Webhook订阅
+// @Description 删除自动化Webhook订阅和它的投递记录
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Param id path integer true "订阅ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /webhooks/{id} [delete]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func DeleteWebhookSubscription(c *gin.Context) {
+	sub := getWebhookSubscriptionByParam(c)
+	if sub == nil {
+		return
+	}
+	if err := db.Db.Where("subscription_id = ?", sub.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("删除投递记录失败：%v", err), Data: nil})
+		return
+	}
+	if err := db.Db.Delete(sub).Error; err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("删除Webhook订阅失败：%v", err), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "删除成功", Data: nil})
+}
+
+// PingWebhookSubscription 测试Webhook订阅
+// @Summary 测试Webhook订阅
+// @Description 立即向订阅地址发送一条 ping 事件，返回投递结果，订阅禁用时也会发送
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Param id path integer true "订阅ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /webhooks/{id}/ping [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func PingWebhookSubscription(c *gin.Context) {
+	if webhook.GlobalDispatcher == nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "Webhook发送队列未启动", Data: nil})
+		return
+	}
+	sub := getWebhookSubscriptionByParam(c)
+	if sub == nil {
+		return
+	}
+	delivery, err := webhook.GlobalDispatcher.Ping(sub)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("发送失败：%v", err), Data: nil})
+		return
+	}
+	if delivery.Status != webhook.DeliverySuccess {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("发送失败：%s", delivery.LastError), Data: delivery})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "发送成功", Data: delivery})
+}
+
+// GetWebhookDeliveries 获取Webhook投递记录
+// @Summary 获取Webhook投递记录
+// @Description 分页获取Webhook投递记录，包括请求体、响应状态码和失败原因
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Param subscription_id query integer false "订阅ID"
+// @Param event query string false "事件"
+// @Param status query string false "状态：pending/success/failed"
+// @Param page query integer false "页码"
+// @Param page_size query integer false "每页数量"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /webhooks/deliveries [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetWebhookDeliveries(c *gin.Context) {
+	page := helpers.StringToInt(c.Query("page"))
+	if page <= 0 {
+		page = 1
+	}
+	pageSize := helpers.StringToInt(c.Query("page_size"))
+	if pageSize <= 0 {
+		pageSize = 20
+	}
+	query := db.Db.Model(&models.WebhookDelivery{})
+	if subscriptionID := c.Query("subscription_id"); subscriptionID != "" {
+		query = query.Where("subscription_id = ?", subscriptionID)
+	}
+	if event := c.Query("event"); event != "" {
+		query = query.Where("event = ?", event)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var total int64
+	query.Count(&total)
+	deliveries := make([]models.WebhookDelivery, 0)
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&deliveries).Error; err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "查询投递记录失败", Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "查询成功", Data: gin.H{"total": total, "list": deliveries}})
+}
+
+// RedeliverWebhook 重新发送Webhook投递
+// @Summary 重新发送Webhook投递
+// @Description 使用原来的投递ID和请求体重新发送，失败后按原来的规则重试
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Param id path integer true "投递记录ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /webhooks/deliveries/{id}/redeliver [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func RedeliverWebhook(c *gin.Context) {
+	if webhook.GlobalDispatcher == nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "Webhook发送队列未启动", Data: nil})
+		return
+	}
+	if err := webhook.GlobalDispatcher.Redeliver(uint(helpers.StringToInt(c.Param("id")))); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "已加入发送队列", Data: nil})
+}
@@ -4,6 +4,7 @@ import (
 	embyclientrestgo "Q115-STRM/internal/embyclient-rest-go"
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/models"
+	ws "Q115-STRM/internal/websocket"
 	"encoding/json"
 	"errors"
 	"net/url"
@@ -35,7 +36,7 @@ type embySyncTask struct {
 }
 
 // 同步Emby媒体库到本地数据库
-func PerformEmbySync() (int, error) {
+func PerformEmbySync() (total int, err error) {
 	// 检查是否已有任务在运行，避免并发执行
 	if IsEmbySyncRunning() {
 		helpers.AppLogger.Warnf("Emby同步任务已在运行，跳过本次定时执行")
@@ -52,6 +53,14 @@ func PerformEmbySync() (int, error) {
 		return 0, errors.New("Emby同步任务已在运行")
 	}
 	defer atomic.StoreInt32(&embySyncRunning, 0)
+	ws.BroadcastEvent(ws.EventEmbySyncStart, ws.EmbySyncData{})
+	defer func() {
+		data := ws.EmbySyncData{Processed: total, Success: err == nil}
+		if err != nil {
+			data.Error = err.Error()
+		}
+		ws.BroadcastEvent(ws.EventEmbySyncComplete, data)
+	}()
 
 	client := embyclientrestgo.NewClient(config.EmbyUrl, config.EmbyApiKey)
 	users, err := client.GetUsersWithAllLibrariesAccess()
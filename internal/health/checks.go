@@ -0,0 +1,242 @@
+package health
+
+import (
+	"Q115-STRM/internal/db"
+	embyclientrestgo "Q115-STRM/internal/embyclient-rest-go"
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/notificationmanager"
+	ws "Q115-STRM/internal/websocket"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 根据当前配置生成要检查的组件，没有配置的组件不检查
+func defaultChecks() []check {
+	checks := []check{databaseCheck()}
+	if accounts, err := models.GetAllAccount(); err == nil {
+		for _, account := range accounts {
+			if c := accountCheck(account); c != nil {
+				checks = append(checks, *c)
+			}
+		}
+	}
+	if models.SettingsGlobal.HttpProxy != "" {
+		checks = append(checks, proxyCheck(models.SettingsGlobal.HttpProxy))
+	}
+	if settings := models.GlobalScrapeSettings; settings != nil {
+		if !settings.TmdbOffline {
+			checks = append(checks, tmdbCheck(settings))
+		}
+		if settings.EnableAi != "" && settings.EnableAi != models.AiActionOff {
+			checks = append(checks, aiCheck(settings))
+		}
+	}
+	if config := models.GlobalEmbyConfig; config != nil && config.EmbyUrl != "" && config.EmbyApiKey != "" {
+		checks = append(checks, embyCheck(config.EmbyUrl, config.EmbyApiKey))
+	}
+	return checks
+}
+
+func databaseCheck() check {
+	name := "数据库(SQLite)"
+	if db.IsPostgres() {
+		name = "数据库(PostgreSQL)"
+	}
+	return check{
+		key:  KindDatabase,
+		kind: KindDatabase,
+		name: name,
+		run: func(ctx context.Context) (string, error) {
+			sqlDB, err := db.Db.DB()
+			if err != nil {
+				return "", fmt.Errorf("获取数据库连接失败: %v", err)
+			}
+			if err := sqlDB.PingContext(ctx); err != nil {
+				return "", fmt.Errorf("数据库连接失败: %v", err)
+			}
+			stats := sqlDB.Stats()
+			return fmt.Sprintf("连接数 %d，使用中 %d", stats.OpenConnections, stats.InUse), nil
+		},
+	}
+}
+
+// 网盘账号，检查访问凭证是否有效，不支持的账号类型返回nil
+func accountCheck(account models.Account) *check {
+	name := account.Name
+	if name == "" {
+		name = account.Username
+	}
+	c := &check{
+		key:  fmt.Sprintf("%s:%d", KindAccount, account.ID),
+		kind: KindAccount,
+		name: fmt.Sprintf("%s账号 %s", account.SourceType.String(), name),
+	}
+	switch account.SourceType {
+	case models.SourceType115:
+		c.run = func(ctx context.Context) (string, error) {
+			if account.Token == "" {
+				return "", tokenInvalidError(account)
+			}
+			info, err := account.Get115Client().UserInfo()
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("用户 %s", info.UserName), nil
+		}
+	case models.SourceTypeBaiduPan:
+		c.run = func(ctx context.Context) (string, error) {
+			if account.Token == "" {
+				return "", tokenInvalidError(account)
+			}
+			info, err := account.GetBaiDuPanClient().GetUserInfo(ctx)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("用户 %s", info.GetBaiduName()), nil
+		}
+	case models.SourceTypeOpenList:
+		c.run = func(ctx context.Context) (string, error) {
+			client := account.GetOpenListClient()
+			if account.Token != "" {
+				if info, err := client.GetUserInfo(account.Token); err == nil {
+					return fmt.Sprintf("用户 %s", info.Username), nil
+				}
+			}
+			// 凭证过期后重新登录，登录失败才认为不可用
+			if _, err := client.GetToken(); err != nil {
+				return "", fmt.Errorf("登录失败: %v", err)
+			}
+			return "访问凭证已过期，重新登录成功", nil
+		}
+	default:
+		return nil
+	}
+	c.dedupKey = func(err error) string {
+		var hard hardError
+		if errors.As(err, &hard) {
+			// 和刷新凭证失败时的通知使用同一个去重键，避免重复通知
+			return fmt.Sprintf("token_invalid:%d", account.ID)
+		}
+		return ""
+	}
+	return c
+}
+
+func tokenInvalidError(account models.Account) error {
+	reason := account.TokenFailedReason
+	if reason == "" {
+		reason = "未授权"
+	}
+	return hardError{fmt.Errorf("访问凭证已失效，请重新授权: %s", reason)}
+}
+
+func proxyCheck(proxyURL string) check {
+	return check{
+		key:  KindProxy,
+		kind: KindProxy,
+		name: "HTTP代理",
+		run: func(ctx context.Context) (string, error) {
+			result, err := helpers.TestHttpProxyAdvanced(proxyURL)
+			if err != nil {
+				return "", err
+			}
+			if !result.Success {
+				return "", errors.New(result.ErrorMessage)
+			}
+			return fmt.Sprintf("测试地址 %d/%d 可以访问", result.SuccessCount, result.TotalCount), nil
+		},
+	}
+}
+
+func tmdbCheck(settings *models.ScrapeSettings) check {
+	return check{
+		key:  KindTmdb,
+		kind: KindTmdb,
+		name: "TMDB",
+		run: func(ctx context.Context) (string, error) {
+			if !settings.TestTmdb() {
+				return "", errors.New("TMDB接口无法访问或者API Key无效")
+			}
+			return "", nil
+		},
+	}
+}
+
+func aiCheck(settings *models.ScrapeSettings) check {
+	return check{
+		key:  KindAi,
+		kind: KindAi,
+		name: "AI识别接口",
+		run: func(ctx context.Context) (string, error) {
+			// 只请求模型列表，不消耗token
+			if err := settings.GetAiClient().Ping(); err != nil {
+				return "", err
+			}
+			return settings.GetAiModelName(), nil
+		},
+	}
+}
+
+func embyCheck(embyURL, apiKey string) check {
+	return check{
+		key:  KindEmby,
+		kind: KindEmby,
+		name: "Emby",
+		run: func(ctx context.Context) (string, error) {
+			folders, err := embyclientrestgo.NewClient(embyURL, apiKey).GetLibraryVirtualFolders()
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("媒体库 %d 个", len(folders)), nil
+		},
+	}
+}
+
+// 发送状态变化的系统告警
+func sendAlert(c *Component, dedupKey string) {
+	if notificationmanager.GlobalEnhancedNotificationManager == nil {
+		return
+	}
+	if dedupKey == "" {
+		dedupKey = fmt.Sprintf("health:%s:%s", c.Key, c.Status)
+	}
+	notif := &models.Notification{
+		Type:      models.SystemAlert,
+		Timestamp: time.Now(),
+		DedupKey:  dedupKey,
+	}
+	if c.Status == StatusDown {
+		notif.Title = fmt.Sprintf("🚨 %s 不可用", c.Name)
+		notif.Content = fmt.Sprintf("原因：%s\n连续失败：%d 次\n⏰ 时间: %s", c.Message, c.Failures, time.Unix(c.ChangedAt, 0).Format("2006-01-02 15:04:05"))
+		notif.Priority = models.HighPriority
+	} else {
+		notif.Title = fmt.Sprintf("✅ %s 已恢复", c.Name)
+		notif.Content = fmt.Sprintf("上次失败原因：%s\n⏰ 时间: %s", c.LastFailure, time.Unix(c.ChangedAt, 0).Format("2006-01-02 15:04:05"))
+		notif.Priority = models.NormalPriority
+	}
+	send := notificationmanager.GlobalEnhancedNotificationManager.SendNotification
+	if c.Kind == KindDatabase {
+		// 发送队列保存在数据库中，数据库的告警直接发送到各个渠道
+		send = notificationmanager.GlobalEnhancedNotificationManager.SendNotificationDirect
+	}
+	if err := send(context.Background(), notif); err != nil {
+		helpers.AppLogger.Errorf("发送健康检查告警失败: %v", err)
+	}
+}
+
+// 账号凭证失效时立即重新检查，不用等下一轮
+func (m *Monitor) watchEvents() {
+	for {
+		events, unsubscribe := ws.Subscribe(64)
+		for event := range events {
+			if event.EventType == ws.EventAccountTokenExpired {
+				m.Trigger()
+			}
+		}
+		// 处理不过来时通道会被关闭，重新订阅
+		unsubscribe()
+	}
+}
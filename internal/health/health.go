@@ -0,0 +1,273 @@
+package health
+
+import (
+	"Q115-STRM/internal/helpers"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ============ 健康检查 ============
+// 定时检查账号、代理、TMDB、AI接口、Emby和数据库，状态变化时发送系统告警，持续失败不会重复告警
+
+const (
+	CheckInterval    = 5 * time.Minute  // 检查间隔
+	checkTimeout     = 60 * time.Second // 单个组件的检查超时时间
+	startDelay       = 30 * time.Second // 程序启动后等待一段时间再检查，避免和启动任务抢资源
+	FailureThreshold = 2                // 连续失败多少次才认为不可用，避免网络抖动时告警
+)
+
+// Status 组件状态
+type Status string
+
+const (
+	StatusUnknown Status = "unknown" // 还没有检查过，或者失败次数没有达到阈值
+	StatusUp      Status = "up"
+	StatusDown    Status = "down"
+)
+
+// 组件类型
+const (
+	KindDatabase = "database"
+	KindAccount  = "account"
+	KindProxy    = "proxy"
+	KindTmdb     = "tmdb"
+	KindAi       = "ai"
+	KindEmby     = "emby"
+)
+
+// Component 组件的健康状态
+type Component struct {
+	Key         string `json:"key"`  // 唯一标识，比如 account:1
+	Kind        string `json:"kind"` // 组件类型
+	Name        string `json:"name"`
+	Status      Status `json:"status"`
+	Message     string `json:"message"`      // 最后一次检查的结果，失败时是失败原因
+	LatencyMs   int64  `json:"latency_ms"`   // 最后一次检查耗时
+	Failures    int    `json:"failures"`     // 连续失败次数
+	CheckedAt   int64  `json:"checked_at"`   // 最后一次检查时间
+	ChangedAt   int64  `json:"changed_at"`   // 状态变化时间
+	LastFailure string `json:"last_failure"` // 最近一次失败原因，恢复后保留
+}
+
+// Report /api/health 返回的数据
+type Report struct {
+	Status     Status       `json:"status"` // 所有组件都可用时为up，有不可用的组件时为down
+	CheckedAt  int64        `json:"checked_at"`
+	Components []*Component `json:"components"`
+}
+
+// 一次检查的结果
+type result struct {
+	err       error
+	message   string
+	latencyMs int64
+}
+
+// 确定不可用的错误，比如账号凭证已经清空，不需要等连续失败
+type hardError struct {
+	error
+}
+
+// 需要检查的组件，每轮检查时根据当前配置生成
+type check struct {
+	key  string
+	kind string
+	name string
+	run  func(ctx context.Context) (string, error) // 返回成功时的说明
+	// 告警使用的去重键，为空时使用组件和状态，账号凭证失效时和原有的失效通知使用同一个键
+	dedupKey func(err error) string
+}
+
+// Monitor 健康检查
+type Monitor struct {
+	mu         sync.RWMutex
+	components map[string]*Component
+	checkedAt  int64
+	running    sync.Mutex // 同一时间只进行一轮检查
+	checks     func() []check
+	notify     func(c *Component, dedupKey string)
+	once       sync.Once
+	trigger    chan struct{}
+}
+
+// GlobalMonitor 全局健康检查
+var GlobalMonitor = NewMonitor(defaultChecks, sendAlert)
+
+// NewMonitor 创建健康检查
+func NewMonitor(checks func() []check, notify func(c *Component, dedupKey string)) *Monitor {
+	return &Monitor{
+		components: make(map[string]*Component),
+		checks:     checks,
+		notify:     notify,
+		trigger:    make(chan struct{}, 1),
+	}
+}
+
+// Start 启动定时检查
+func Start() {
+	GlobalMonitor.Start()
+}
+
+// Start 启动定时检查
+func (m *Monitor) Start() {
+	m.once.Do(func() {
+		go m.run()
+		go m.watchEvents()
+		helpers.AppLogger.Infof("健康检查已启动，检查间隔 %s", CheckInterval)
+	})
+}
+
+func (m *Monitor) run() {
+	time.Sleep(startDelay)
+	ticker := time.NewTicker(CheckInterval)
+	defer ticker.Stop()
+	for {
+		m.CheckAll()
+		select {
+		case <-ticker.C:
+		case <-m.trigger:
+		}
+	}
+}
+
+// Trigger 在后台立即检查一次
+func (m *Monitor) Trigger() {
+	select {
+	case m.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// CheckAll 检查所有组件，并发执行，等待全部完成
+func (m *Monitor) CheckAll() {
+	m.running.Lock()
+	defer m.running.Unlock()
+	checks := m.checks()
+	results := make([]result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			results[i] = runCheck(c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	now := time.Now().Unix()
+	keys := make(map[string]bool, len(checks))
+	for i, c := range checks {
+		keys[c.key] = true
+		m.apply(c, results[i], now)
+	}
+	m.mu.Lock()
+	// 删除已经不存在或者没有配置的组件
+	for key := range m.components {
+		if !keys[key] {
+			delete(m.components, key)
+		}
+	}
+	m.checkedAt = now
+	m.mu.Unlock()
+}
+
+// 执行检查，超时后直接返回失败，检查函数在后台继续执行
+func runCheck(c check) result {
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	start := time.Now()
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- result{err: fmt.Errorf("检查时发生错误: %v", r)}
+			}
+		}()
+		message, err := c.run(ctx)
+		done <- result{err: err, message: message}
+	}()
+	var res result
+	select {
+	case res = <-done:
+	case <-ctx.Done():
+		res = result{err: context.DeadlineExceeded}
+	}
+	res.latencyMs = time.Since(start).Milliseconds()
+	return res
+}
+
+// 更新组件状态，状态变化时告警
+func (m *Monitor) apply(c check, res result, now int64) {
+	m.mu.Lock()
+	comp, ok := m.components[c.key]
+	if !ok {
+		comp = &Component{Key: c.key, Kind: c.kind, Status: StatusUnknown, ChangedAt: now}
+		m.components[c.key] = comp
+	}
+	comp.Name = c.name
+	comp.CheckedAt = now
+	comp.LatencyMs = res.latencyMs
+	prev := comp.Status
+	next := prev
+	if res.err == nil {
+		comp.Failures = 0
+		comp.Message = res.message
+		next = StatusUp
+	} else {
+		comp.Failures++
+		comp.Message = res.err.Error()
+		comp.LastFailure = comp.Message
+		var hard hardError
+		if comp.Failures >= FailureThreshold || errors.As(res.err, &hard) {
+			next = StatusDown
+		}
+	}
+	changed := next != prev
+	if changed {
+		comp.Status = next
+		comp.ChangedAt = now
+	}
+	snapshot := *comp
+	m.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	helpers.AppLogger.Infof("健康检查: %s 状态从 %s 变为 %s: %s", snapshot.Name, prev, next, snapshot.Message)
+	// 第一次检查成功不需要通知
+	if prev == StatusUnknown && next == StatusUp {
+		return
+	}
+	dedupKey := ""
+	if c.dedupKey != nil {
+		dedupKey = c.dedupKey(res.err)
+	}
+	if m.notify != nil {
+		m.notify(&snapshot, dedupKey)
+	}
+}
+
+// Report 当前所有组件的状态
+func (m *Monitor) Report() *Report {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	report := &Report{Status: StatusUp, CheckedAt: m.checkedAt, Components: make([]*Component, 0, len(m.components))}
+	if m.checkedAt == 0 {
+		report.Status = StatusUnknown
+	}
+	for _, comp := range m.components {
+		c := *comp
+		report.Components = append(report.Components, &c)
+		if c.Status == StatusDown {
+			report.Status = StatusDown
+		}
+	}
+	sort.Slice(report.Components, func(i, j int) bool {
+		return report.Components[i].Key < report.Components[j].Key
+	})
+	return report
+}
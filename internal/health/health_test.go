@@ -0,0 +1,71 @@
+package health
+
+import (
+	"Q115-STRM/internal/helpers"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+)
+
+func TestMonitorAlertsOnTransitions(t *testing.T) {
+	helpers.AppLogger = &helpers.QLogger{Logger: log.New(io.Discard, "", 0)}
+	var fail error
+	alerts := make([]Status, 0)
+	m := NewMonitor(func() []check {
+		return []check{{key: "tmdb", kind: KindTmdb, name: "TMDB", run: func(ctx context.Context) (string, error) {
+			return "", fail
+		}}}
+	}, func(c *Component, dedupKey string) {
+		alerts = append(alerts, c.Status)
+	})
+
+	// 第一次检查成功不告警
+	m.CheckAll()
+	if len(alerts) != 0 || m.Report().Status != StatusUp {
+		t.Fatalf("第一次检查成功不应该告警: %v %+v", alerts, m.Report())
+	}
+	// 失败次数没有达到阈值时保持原来的状态
+	fail = errors.New("timeout")
+	m.CheckAll()
+	if len(alerts) != 0 || m.Report().Components[0].Status != StatusUp {
+		t.Fatalf("失败一次不应该告警: %v", alerts)
+	}
+	// 连续失败只告警一次
+	m.CheckAll()
+	m.CheckAll()
+	if len(alerts) != 1 || alerts[0] != StatusDown || m.Report().Status != StatusDown {
+		t.Fatalf("连续失败应该只告警一次: %v", alerts)
+	}
+	// 恢复时告警
+	fail = nil
+	m.CheckAll()
+	if len(alerts) != 2 || alerts[1] != StatusUp {
+		t.Fatalf("恢复时应该告警: %v", alerts)
+	}
+	if comp := m.Report().Components[0]; comp.LastFailure != "timeout" || comp.Failures != 0 {
+		t.Errorf("恢复后应该保留最近一次失败原因: %+v", comp)
+	}
+}
+
+func TestMonitorHardErrorAndRemoval(t *testing.T) {
+	helpers.AppLogger = &helpers.QLogger{Logger: log.New(io.Discard, "", 0)}
+	checks := []check{{key: "account:1", kind: KindAccount, name: "115账号", run: func(ctx context.Context) (string, error) {
+		return "", hardError{errors.New("访问凭证已失效")}
+	}}}
+	alerts := 0
+	m := NewMonitor(func() []check { return checks }, func(c *Component, dedupKey string) { alerts++ })
+
+	// 确定不可用的错误不需要等连续失败
+	m.CheckAll()
+	if alerts != 1 || m.Report().Components[0].Status != StatusDown {
+		t.Fatalf("凭证失效应该立即告警: %d %+v", alerts, m.Report())
+	}
+	// 删除账号后不再显示
+	checks = nil
+	m.CheckAll()
+	if len(m.Report().Components) != 0 || m.Report().Status != StatusUp {
+		t.Errorf("删除的组件不应该再显示: %+v", m.Report())
+	}
+}
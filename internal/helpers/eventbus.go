@@ -21,6 +21,12 @@ const (
 	StrmSyncCompleteEvent EventType = "strm_sync_complete"
 )
 
+// strm同步完成事件的数据
+type StrmSyncCompleteData struct {
+	ScrapePathIds []uint // 需要扫描来源目录的刮削目录ID
+	SkipScanIds   []uint // 新增文件已经在同步时入库，跳过扫描的刮削目录ID
+}
+
 // 事件数据
 type Event struct {
 	Type EventType `json:"type"`
@@ -0,0 +1,42 @@
+package helpers
+
+import "time"
+
+// ============ 数据库发送队列 ============
+// 通知和Webhook的发送队列都是先写入数据库，再由后台协程轮询发送，失败后按指数退避重试
+
+// 清理过期记录的间隔
+const queueCleanupInterval = time.Hour
+
+// RetryBackoff 第attempts次失败后的重试等待时间，从base开始每次翻倍，最长不超过max
+func RetryBackoff(attempts int, base, max time.Duration) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := base
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= max {
+			return max
+		}
+	}
+	return delay
+}
+
+// RunQueueLoop 每隔interval或者收到wake信号时处理一次队列，每小时清理一次过期记录，不会返回
+func RunQueueLoop(interval time.Duration, wake <-chan struct{}, process func(), cleanup func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	lastCleanup := time.Time{}
+	for {
+		process()
+		if time.Since(lastCleanup) > queueCleanupInterval {
+			cleanup()
+			lastCleanup = time.Now()
+		}
+		select {
+		case <-ticker.C:
+		case <-wake:
+		}
+	}
+}
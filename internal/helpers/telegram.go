@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,8 +14,20 @@ import (
 // TelegramBot 结构体用于处理Telegram机器人操作
 type TelegramBot struct {
 	Token  string
-	ChatID string
+	ChatID string // 多个聊天用逗号分隔
 	Client *tgbotapi.BotAPI
+	// Authorize 检查命令发送者是否有权限执行命令，返回错误时拒绝执行，为 nil 时不检查
+	Authorize func(source CommandSource, cmd string) error
+	// Audit 记录执行或者被拒绝的命令，为 nil 时不记录
+	Audit func(source CommandSource, cmd string, args []string, allowed bool, result string)
+}
+
+// CommandSource 命令的发送者和所在的聊天
+type CommandSource struct {
+	ChatID   int64
+	ChatType string // private、group、supergroup
+	UserID   int64
+	Username string
 }
 
 // TelegramResponse Telegram API响应结构
@@ -36,6 +49,36 @@ type TelegramMessage struct {
 type CommandResponse struct {
 	Text        string
 	ReplyMarkup interface{}
+	// OnSent 回复发送成功后在协程中调用，edit 用于修改这条回复，比如实时更新任务进度
+	OnSent func(edit MessageEditor)
+}
+
+// MessageEditor 修改已发送的消息，markup 为 nil 时去掉内联键盘
+type MessageEditor func(text string, markup interface{}) error
+
+// ParseChatIDs 解析逗号分隔的聊天ID，忽略无效的ID
+func ParseChatIDs(chatID string) []int64 {
+	var ids []int64
+	for _, part := range strings.Split(chatID, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if id, err := strconv.ParseInt(part, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// 是否为配置中的聊天
+func (bot *TelegramBot) isAllowedChat(chatID int64) bool {
+	for _, id := range ParseChatIDs(bot.ChatID) {
+		if id == chatID {
+			return true
+		}
+	}
+	return false
 }
 
 // maskToken 掩码token用于日志输出
@@ -111,13 +154,16 @@ func (bot *TelegramBot) SendMessage(text string) error {
 		return fmt.Errorf("telegram chat ID不能为空")
 	}
 
-	msg := tgbotapi.NewMessage(StringToInt64(bot.ChatID), text)
-	msg.ParseMode = "HTML"
-	_, err := bot.Client.Send(msg)
-	if err != nil {
-		return fmt.Errorf("发送消息失败: %v", err)
+	// 发送到所有聊天，部分失败时返回最后一个错误
+	var lastErr error
+	for _, chatID := range ParseChatIDs(bot.ChatID) {
+		msg := tgbotapi.NewMessage(chatID, text)
+		msg.ParseMode = "HTML"
+		if _, err := bot.Client.Send(msg); err != nil {
+			lastErr = fmt.Errorf("发送消息到 %d 失败: %v", chatID, err)
+		}
 	}
-	return nil
+	return lastErr
 
 	// url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", bot.Token)
 
@@ -185,23 +231,25 @@ func (bot *TelegramBot) SendPhoto(image string, caption string) error {
 		file = tgbotapi.FilePath(image)
 	}
 
-	msg := tgbotapi.NewPhoto(StringToInt64(bot.ChatID), file)
-	if caption != "" {
-		// Telegram 照片caption上限约为1024字符，这里做简单截断
-		if len([]rune(caption)) > 1024 {
-			// 保留前1024个字符
-			runes := []rune(caption)
-			caption = string(runes[:1024])
-		}
-		msg.Caption = caption
-		msg.ParseMode = "HTML"
+	// Telegram 照片caption上限约为1024字符，这里做简单截断
+	if len([]rune(caption)) > 1024 {
+		// 保留前1024个字符
+		runes := []rune(caption)
+		caption = string(runes[:1024])
 	}
 
-	_, err := bot.Client.Send(msg)
-	if err != nil {
-		return fmt.Errorf("发送图片失败: %v", err)
+	var lastErr error
+	for _, chatID := range ParseChatIDs(bot.ChatID) {
+		msg := tgbotapi.NewPhoto(chatID, file)
+		if caption != "" {
+			msg.Caption = caption
+			msg.ParseMode = "HTML"
+		}
+		if _, err := bot.Client.Send(msg); err != nil {
+			lastErr = fmt.Errorf("发送图片到 %d 失败: %v", chatID, err)
+		}
 	}
-	return nil
+	return lastErr
 }
 
 // SendMessageWithRetry 带重试机制的发送消息
@@ -332,12 +380,25 @@ func (bot *TelegramBot) StartListening(ctx context.Context, handleCommand map[st
 		var cmd string
 		var args []string
 		var chatID int64
+		var source CommandSource
 
 		if update.Message != nil && update.Message.IsCommand() {
-			// 处理文字命令 /xxxx
+			// 处理文字命令 /xxxx，群组中 /xxxx@其他机器人 的命令不处理
+			if at := strings.SplitN(update.Message.CommandWithAt(), "@", 2); len(at) == 2 && !strings.EqualFold(at[1], bot.Client.Self.UserName) {
+				continue
+			}
 			cmd = update.Message.Command()
 			args = strings.Fields(update.Message.CommandArguments())
 			chatID = update.Message.Chat.ID
+		} else if update.Message != nil && bot.isMention(update.Message.Text) {
+			// 群组中 @机器人 命令 参数
+			parts := strings.Fields(update.Message.Text)[1:]
+			if len(parts) == 0 {
+				continue
+			}
+			cmd = strings.TrimPrefix(parts[0], "/")
+			args = parts[1:]
+			chatID = update.Message.Chat.ID
 		} else if update.CallbackQuery != nil {
 			// 处理按钮点击
 			bot.Client.Request(tgbotapi.NewCallback(update.CallbackQuery.ID, ""))
@@ -352,22 +413,47 @@ func (bot *TelegramBot) StartListening(ctx context.Context, handleCommand map[st
 				args = []string{}
 			}
 			chatID = update.CallbackQuery.Message.Chat.ID
+			source.ChatType = update.CallbackQuery.Message.Chat.Type
+			if from := update.CallbackQuery.From; from != nil {
+				source.UserID = from.ID
+				source.Username = from.UserName
+			}
 		} else {
 			continue
 		}
+		source.ChatID = chatID
+		if update.Message != nil {
+			source.ChatType = update.Message.Chat.Type
+			if from := update.Message.From; from != nil {
+				source.UserID = from.ID
+				source.Username = from.UserName
+			}
+		}
 
 		// --- 权限检查 ---
 		// 重点：只响应你在配置中指定的 ChatID，防止其他人控制你的程序
-		if bot.ChatID != "" && fmt.Sprintf("%d", chatID) != bot.ChatID {
+		// 没有配置 ChatID 时由 Authorize 按用户列表检查，没有用户时拒绝
+		if bot.ChatID != "" && !bot.isAllowedChat(chatID) {
 			continue
 		}
 
 		// --- 处理命令 ---
 		var response CommandResponse
-		if logic, ok := handleCommand[cmd]; ok {
+		allowed := true
+		if cmd != "whoami" && bot.Authorize != nil {
+			if err := bot.Authorize(source, cmd); err != nil {
+				allowed = false
+				response.Text = "⛔ " + err.Error()
+			}
+		}
+		if !allowed {
+			// 没有权限，不执行命令
+		} else if logic, ok := handleCommand[cmd]; ok {
 			response = logic(args)
 		} else {
 			switch cmd {
+			case "whoami":
+				response.Text = fmt.Sprintf("🪪 你的用户ID: <code>%d</code>\n当前聊天ID: <code>%d</code>", source.UserID, chatID)
 			case "start", "help":
 				response.Text = `👋 <b>欢迎使用 QMediaSync Bot</b>  
 
@@ -376,8 +462,18 @@ func (bot *TelegramBot) StartListening(ctx context.Context, handleCommand map[st
 							🚀/strm_sync - <b>执行全量 STRM 同步</b>  
 							🔄/strm_inc - <b>执行增量 STRM 同步</b>  
 							🎬/scrape - <b>执行刮削任务</b>  
+							📂/strm_menu - <b>选择目录执行 STRM 同步</b>  
+							🗂/scrape_menu - <b>选择目录执行刮削</b>  
+							🔄🎬/strm_scrape - <b>先同步后刮削</b>  
+							🎬🔄/scrape_strm - <b>先刮削后同步</b>  
 							📋/get_strm_path - <b>查看 STRM 同步路径</b>  
 							🧹/get_scrape_path - <b>查看刮削路径</b>  
+							📦/queue - <b>查看和暂停/恢复上传下载队列</b>  
+							🚦/throttle - <b>查看 115 限流状态</b>  
+							🔍/review - <b>确认待确认的识别结果</b>  
+							💾/backup - <b>立即备份数据</b>  
+							🪪/whoami - <b>查看自己的用户ID</b>  
+							👥/users - <b>查看和管理机器人用户（管理员）</b>  
 							   							
 							⚡ <b>同步模式说明：</b>  
 							• <b>全量模式：</b> "全量同步"操作会删除所有缓存数据（不会删除本地文件），然后执行同步，可以处理所有网盘文件变更  
@@ -386,6 +482,13 @@ func (bot *TelegramBot) StartListening(ctx context.Context, handleCommand map[st
 							⚡ <b>同步/刮削命令：</b>  
 							• 不加任何参数执行默认对所有同步/刮削路径执行
 							• 可在命令后增加序号指定执行目录, 序号见同步/刮削目录设置。格式: /scrape #序号
+							• 先同步后刮削: /strm_scrape #同步目录序号 #刮削目录序号，先刮削后同步参数顺序相反
+							• 执行后回复的消息会实时更新任务进度
+							
+							👥 <b>用户权限：</b>  
+							• 只读用户可以查询状态，操作员可以执行同步、刮削等任务，管理员可以修改设置和管理用户
+							• 添加用户: /user_set 用户ID 角色(viewer/operator/admin) [备注]，删除用户: /user_del 用户ID
+							• 群组中可以使用 /命令@机器人 或者 @机器人 命令
 							`
 				// 构建内联键盘
 				keyboard := tgbotapi.NewInlineKeyboardMarkup(
@@ -399,6 +502,16 @@ func (bot *TelegramBot) StartListening(ctx context.Context, handleCommand map[st
 						tgbotapi.NewInlineKeyboardButtonData("🧹 刮削路径", "get_scrape_path"),
 						tgbotapi.NewInlineKeyboardButtonData("📊📊 系统状态", "status"),
 					),
+					tgbotapi.NewInlineKeyboardRow(
+						tgbotapi.NewInlineKeyboardButtonData("📂 选择同步目录", "strm_menu"),
+						tgbotapi.NewInlineKeyboardButtonData("🗂 选择刮削目录", "scrape_menu"),
+					),
+					tgbotapi.NewInlineKeyboardRow(
+						tgbotapi.NewInlineKeyboardButtonData("📦 队列", "queue"),
+						tgbotapi.NewInlineKeyboardButtonData("🚦 限流", "throttle"),
+						tgbotapi.NewInlineKeyboardButtonData("🔍 待确认", "review"),
+						tgbotapi.NewInlineKeyboardButtonData("💾 备份", "backup"),
+					),
 				)
 				response.ReplyMarkup = keyboard
 			case "status":
@@ -408,6 +521,9 @@ func (bot *TelegramBot) StartListening(ctx context.Context, handleCommand map[st
 
 			}
 		}
+		if bot.Audit != nil {
+			bot.Audit(source, cmd, args, allowed, response.Text)
+		}
 
 		// 回复结果
 		if response.Text != "" {
@@ -419,12 +535,42 @@ func (bot *TelegramBot) StartListening(ctx context.Context, handleCommand map[st
 				reply.ReplyMarkup = response.ReplyMarkup
 			}
 
-			bot.Client.Send(reply)
+			sent, err := bot.Client.Send(reply)
+			if err != nil {
+				AppLogger.Warnf("回复Telegram命令 %s 失败: %v", cmd, err)
+			} else if response.OnSent != nil {
+				go response.OnSent(bot.messageEditor(chatID, sent.MessageID))
+			}
 		}
 
 	}
 }
 
+// 消息是否以 @机器人 开头
+func (bot *TelegramBot) isMention(text string) bool {
+	name := bot.Client.Self.UserName
+	if name == "" {
+		return false
+	}
+	fields := strings.Fields(text)
+	return len(fields) > 0 && strings.EqualFold(fields[0], "@"+name)
+}
+
+// messageEditor 返回修改指定消息的函数
+func (bot *TelegramBot) messageEditor(chatID int64, messageID int) MessageEditor {
+	return func(text string, markup interface{}) error {
+		var edit tgbotapi.EditMessageTextConfig
+		if keyboard, ok := markup.(tgbotapi.InlineKeyboardMarkup); ok {
+			edit = tgbotapi.NewEditMessageTextAndMarkup(chatID, messageID, text, keyboard)
+		} else {
+			edit = tgbotapi.NewEditMessageText(chatID, messageID, text)
+		}
+		edit.ParseMode = "HTML"
+		_, err := bot.Client.Request(edit)
+		return err
+	}
+}
+
 func (bot *TelegramBot) SetMenuContent() {
 	type menuItem struct {
 		Command     string
@@ -438,7 +584,16 @@ func (bot *TelegramBot) SetMenuContent() {
 		{"scrape", "🎬 执行刮削任务"},
 		{"get_strm_path", "📋 查看 STRM 同步路径"},
 		{"get_scrape_path", "🧹 查看刮削路径"},
-		// {"strm_scrape", "🔄🎬 先同步后刮削"},
+		{"strm_menu", "📂 选择目录执行 STRM 同步"},
+		{"scrape_menu", "🗂 选择目录执行刮削"},
+		{"strm_scrape", "🔄🎬 先同步后刮削"},
+		{"scrape_strm", "🎬🔄 先刮削后同步"},
+		{"queue", "📦 查看和控制上传下载队列"},
+		{"throttle", "🚦 查看 115 限流状态"},
+		{"review", "🔍 确认待确认的识别结果"},
+		{"backup", "💾 立即备份数据"},
+		{"whoami", "🪪 查看自己的用户ID"},
+		{"users", "👥 查看机器人用户"},
 		{"help", "📋 显示功能操作指南"},
 		{"status", "📊 查看系统运行状态"},
 	}
@@ -450,10 +605,13 @@ func (bot *TelegramBot) SetMenuContent() {
 			Description: item.Description,
 		})
 	}
-	scope := tgbotapi.NewBotCommandScopeAllPrivateChats()
-	cfg := tgbotapi.NewSetMyCommandsWithScope(scope, tgCommands...)
-	_, err := bot.Client.Request(cfg)
-	if err != nil {
-		AppLogger.Errorf("设置Bot菜单失败: %v", err)
+	for _, scope := range []tgbotapi.BotCommandScope{
+		tgbotapi.NewBotCommandScopeAllPrivateChats(),
+		tgbotapi.NewBotCommandScopeAllGroupChats(),
+	} {
+		cfg := tgbotapi.NewSetMyCommandsWithScope(scope, tgCommands...)
+		if _, err := bot.Client.Request(cfg); err != nil {
+			AppLogger.Errorf("设置Bot菜单失败: %v", err)
+		}
 	}
 }
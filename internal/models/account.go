@@ -7,6 +7,7 @@ import (
 	"Q115-STRM/internal/notificationmanager"
 	"Q115-STRM/internal/openlist"
 	"Q115-STRM/internal/v115open"
+	ws "Q115-STRM/internal/websocket"
 	"context"
 	"fmt"
 	"time"
@@ -108,6 +109,12 @@ func (account *Account) ClearToken(reason string) {
 		helpers.AppLogger.Errorf("清空开放平台访问凭证失败: %v", err)
 		return
 	}
+	ws.BroadcastEvent(ws.EventAccountTokenExpired, ws.AccountTokenData{
+		AccountID:  account.ID,
+		Name:       account.Name,
+		SourceType: string(account.SourceType),
+		Reason:     reason,
+	})
 }
 
 func (account *Account) UpdateOpenList(baseUrl string, username string, password string, token string) error {
@@ -358,6 +365,7 @@ func HandleV115TokenInvalid(event helpers.Event) helpers.EventResult {
 		Content:   fmt.Sprintf("账号ID：%d\n用户名：%s\n请重新授权\n⏰ 时间: %s", int(account.ID), account.Username, time.Now().Format("2006-01-02 15:04:05")),
 		Timestamp: time.Now(),
 		Priority:  HighPriority,
+		DedupKey:  fmt.Sprintf("token_invalid:%d", account.ID),
 	}
 	if notificationmanager.GlobalEnhancedNotificationManager != nil {
 		if err := notificationmanager.GlobalEnhancedNotificationManager.SendNotification(ctx, notif); err != nil {
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"time"
 )
 
@@ -27,6 +28,8 @@ type UploadSource string
 const (
 	UploadSourceStrm   UploadSource = "strm同步"
 	UploadSourceScrape UploadSource = "刮削整理"
+	// 跨存储整理：来源和目标不在同一个网盘，视频和字幕通过上传队列转移到目标
+	UploadSourceTransfer UploadSource = "跨存储整理"
 )
 
 type DbUploadTask struct {
@@ -46,9 +49,20 @@ type DbUploadTask struct {
 	StartTime            int64            `json:"start_time"`                                       // 开始时间
 	EndTime              int64            `json:"end_time"`                                         // 结束时间
 	IsSeasonOrTvshowFile bool             `json:"is_season_or_tvshow_file"`                         // 是否是剧集或电视剧文件
+	Overwrite            bool             `json:"overwrite"`                                        // 是否覆盖网盘中已存在的文件，重新生成元数据时使用
+	TransferAccountId    uint             `json:"transfer_account_id"`                              // 跨存储整理时来源文件所在的账号ID
+	TransferSourceType   SourceType       `json:"transfer_source_type"`                             // 跨存储整理时来源文件的类型
+	TransferFileId       string           `json:"transfer_file_id"`                                 // 来源文件ID，115是文件ID，其他是完整路径
+	TransferPickCode     string           `json:"transfer_pick_code"`                               // 来源文件的提取码，115是pickcode，百度网盘是fs_id，用来获取下载链接
+	TransferPathId       string           `json:"transfer_path_id"`                                 // 来源文件所在目录ID
+	DeleteSource         bool             `json:"delete_source"`                                    // 上传并校验完成后删除来源文件（移动模式）
+	IsVideo              bool             `json:"is_video"`                                         // 是否是视频文件，完成后更新媒体的视频文件ID
+	ScrapePathId         uint             `json:"scrape_path_id"`                                   // 刮削目录ID
+	RunNo                string           `json:"run_no"`                                           // 刮削批次号，上传完成后记录到整理操作日志，用来撤销
 	SyncFile             *SyncFile        `json:"-" gorm:"-"`                                       // 同步文件
 	ScrapeMediaFile      *ScrapeMediaFile `json:"-" gorm:"-"`                                       // 刮削文件
 	Account              *Account         `json:"-" gorm:"-"`                                       // 账户
+	uploadedFileId       string           // 本次实际上传的文件ID，网盘中已存在跳过上传时为空
 }
 
 // String 返回状态的字符串表示
@@ -125,6 +139,11 @@ func (task *DbUploadTask) GetAccount() *Account {
 
 // 执行上传
 func (task *DbUploadTask) Upload() {
+	if task.Source == UploadSourceTransfer {
+		// 跨存储整理，来源文件可能在网盘里，单独处理
+		task.Transfer()
+		return
+	}
 	if !helpers.PathExists(task.LocalFullPath) {
 		task.Fail(fmt.Errorf("本地文件 %s 不存在", task.LocalFullPath))
 		return
@@ -154,6 +173,7 @@ func (task *DbUploadTask) Upload() {
 	task.Complete()
 	// 如果是刮削类型,需要进行后续通知
 	if task.Source == UploadSourceScrape {
+		task.recordJournal()
 		// 通知刮削整理完成
 		scrapeMediaFile := GetScrapeMediaFileById(task.ScrapeMediaFileId)
 		if scrapeMediaFile == nil {
@@ -189,8 +209,12 @@ func (task *DbUploadTask) Upload115File() bool {
 	// 检查远程文件是否存在
 	detail, existsErr := client.GetFsDetailByPath(context.Background(), task.RemoteFileId)
 
-	if existsErr == nil && detail.FileId != "" {
-		if task.Source == UploadSourceStrm {
+	oldFileId := ""
+	if existsErr == nil && detail.FileId != "" && task.Overwrite {
+		// 覆盖上传，新文件上传成功后再删除旧文件，上传失败时保留旧文件
+		oldFileId = detail.FileId
+	} else if existsErr == nil && detail.FileId != "" {
+		if task.Source == UploadSourceStrm || task.Source == UploadSourceTransfer {
 			return true
 		}
 		if task.Source == UploadSourceScrape {
@@ -218,7 +242,19 @@ func (task *DbUploadTask) Upload115File() bool {
 	}
 	helpers.AppLogger.Infof("准备将文件 %s 上传到115目录 %s", task.LocalFullPath, task.RemotePathId)
 	// 上传文件
-	fileId, err := client.Upload(context.Background(), task.LocalFullPath, task.RemotePathId, "", "")
+	uploadPath := task.LocalFullPath
+	fileName := filepath.Base(task.LocalFullPath)
+	tmpStamp := time.Now().UnixNano()
+	if oldFileId != "" {
+		// 115使用本地文件名作为网盘文件名，覆盖时先用临时文件名上传，避免和旧文件同名
+		uploadPath = filepath.Join(filepath.Dir(task.LocalFullPath), fmt.Sprintf(".%d.%s", tmpStamp, fileName))
+		if err := helpers.CopyFile(task.LocalFullPath, uploadPath); err != nil {
+			task.Fail(fmt.Errorf("复制临时上传文件 %s 失败: %v", uploadPath, err))
+			return false
+		}
+		defer os.Remove(uploadPath)
+	}
+	fileId, err := client.Upload(context.Background(), uploadPath, task.RemotePathId, "", "")
 	if err != nil {
 		task.Fail(fmt.Errorf("调用115上传API失败: %v", err))
 		return false
@@ -227,6 +263,30 @@ func (task *DbUploadTask) Upload115File() bool {
 		task.Fail(fmt.Errorf("115上传文件 %s 失败: 返回空文件ID", task.FileName))
 		return false
 	}
+	if oldFileId != "" {
+		// 先把旧文件改成临时文件名，再把新文件改回原来的文件名，最后删除旧文件
+		// 任何一步失败都恢复旧文件并删除新文件，不会丢失旧文件，也不会留下隐藏的新文件
+		oldTmpName := fmt.Sprintf(".%d.old.%s", tmpStamp, fileName)
+		if _, renErr := client.ReName(context.Background(), oldFileId, oldTmpName); renErr != nil {
+			client.Del(context.Background(), []string{fileId}, task.RemotePathId)
+			task.Fail(fmt.Errorf("115重命名旧文件 %s 失败: %v", task.RemoteFileId, renErr))
+			return false
+		}
+		if _, renErr := client.ReName(context.Background(), fileId, fileName); renErr != nil {
+			if _, restoreErr := client.ReName(context.Background(), oldFileId, fileName); restoreErr != nil {
+				helpers.AppLogger.Errorf("115恢复旧文件 %s 的文件名失败，旧文件现在是 %s: %v", task.RemoteFileId, oldTmpName, restoreErr)
+			}
+			client.Del(context.Background(), []string{fileId}, task.RemotePathId)
+			task.Fail(fmt.Errorf("115重命名上传的文件 %s 失败: %v", task.RemoteFileId, renErr))
+			return false
+		}
+		if _, delErr := client.Del(context.Background(), []string{oldFileId}, task.RemotePathId); delErr != nil {
+			// 新文件已经替换到位，只留下改名后的旧文件
+			helpers.AppLogger.Warnf("115删除旧文件 %s 失败，请手动删除: %v", oldTmpName, delErr)
+		}
+		helpers.AppLogger.Infof("覆盖上传，已替换115旧文件 %s", task.RemoteFileId)
+	}
+	task.uploadedFileId = fileId
 	helpers.AppLogger.Infof("115上传文件 %s 成功, 新的文件ID: %s", task.LocalFullPath, fileId)
 	if task.Source == UploadSourceStrm {
 		// 查询文件详情，然后更新本地文件的修改时间
@@ -266,11 +326,12 @@ func (task *DbUploadTask) UploadBaiduPanFile() bool {
 	}
 	task.Uploading()
 	// 调用上传方法
-	resp, err := client.Upload(context.Background(), task.LocalFullPath, task.RemoteFileId)
+	resp, err := client.Upload(context.Background(), task.LocalFullPath, task.RemoteFileId, task.Overwrite)
 	if err != nil {
 		task.Fail(fmt.Errorf("百度网盘上传文件 %s 失败: %v", task.FileName, err))
 		return false
 	}
+	task.uploadedFileId = task.RemoteFileId
 	if task.Source == UploadSourceStrm {
 		t := time.Unix(int64(*resp.Mtime), 0)
 		// 更新本地文件的修改时间
@@ -297,11 +358,12 @@ func (task *DbUploadTask) UploadOpenListFile() bool {
 		return false
 	}
 	task.Uploading()
-	_, err := client.Upload(task.LocalFullPath, task.RemoteFileId)
+	_, err := client.Upload(task.LocalFullPath, task.RemoteFileId, task.Overwrite)
 	if err != nil {
 		task.Fail(fmt.Errorf("OpenList上传文件 %s 失败: %v", task.FileName, err))
 		return false
 	}
+	task.uploadedFileId = task.RemoteFileId
 	if task.Source == UploadSourceStrm {
 		// 查询文件详情
 		detail, err := client.FileDetail(task.RemoteFileId)
@@ -327,11 +389,15 @@ func (task *DbUploadTask) UploadOpenListFile() bool {
 
 func (task *DbUploadTask) UploadLocalFile() bool {
 	task.Uploading()
+	exists := helpers.PathExists(task.RemoteFileId)
 	err := helpers.CopyFile(task.LocalFullPath, task.RemoteFileId)
 	if err != nil {
 		task.Fail(fmt.Errorf("本地文件 %s 复制到 %s 失败: %v", task.LocalFullPath, task.RemoteFileId, err))
 		return false
 	}
+	if !exists {
+		task.uploadedFileId = task.RemoteFileId
+	}
 	return true
 }
 
@@ -401,8 +467,40 @@ func AddUploadTaskFromSyncFile(file *SyncFile) error {
 	return nil
 }
 
+// 刮削上传的元数据记录到整理操作日志，撤销批次时删除
+// 覆盖上传的文件原来就存在，跳过上传的文件不是本次创建的，都不记录
+func (task *DbUploadTask) recordJournal() {
+	if task.RunNo == "" || task.Overwrite || task.uploadedFileId == "" {
+		return
+	}
+	j := &RenameJournal{
+		RunNo:             task.RunNo,
+		ScrapePathId:      task.ScrapePathId,
+		ScrapeMediaFileId: task.ScrapeMediaFileId,
+		SourceType:        task.SourceType,
+		Op:                RenameJournalOpUpload,
+		DestPath:          filepath.Dir(task.RemoteFileId),
+		DestPathId:        filepath.Dir(task.RemoteFileId),
+		DestName:          filepath.Base(task.RemoteFileId),
+		FileId:            task.uploadedFileId,
+	}
+	if task.SourceType == SourceType115 {
+		j.DestPathId = task.RemotePathId
+	}
+	AddRenameJournal(j)
+}
+
 // 添加刮削整理产生的上传任务
 func AddUploadTaskFromMediaFile(mediaFile *ScrapeMediaFile, scrapePath *ScrapePath, fileName, localFullPath, remoteFileId, remotePathId string, isSeasonOrTvshowFile bool) error {
+	return addScrapeUploadTask(mediaFile, scrapePath, fileName, localFullPath, remoteFileId, remotePathId, isSeasonOrTvshowFile, false)
+}
+
+// 添加重新生成元数据产生的上传任务，覆盖网盘中已存在的文件
+func AddOverwriteUploadTaskFromMediaFile(mediaFile *ScrapeMediaFile, scrapePath *ScrapePath, fileName, localFullPath, remoteFileId, remotePathId string, isSeasonOrTvshowFile bool) error {
+	return addScrapeUploadTask(mediaFile, scrapePath, fileName, localFullPath, remoteFileId, remotePathId, isSeasonOrTvshowFile, true)
+}
+
+func addScrapeUploadTask(mediaFile *ScrapeMediaFile, scrapePath *ScrapePath, fileName, localFullPath, remoteFileId, remotePathId string, isSeasonOrTvshowFile bool, overwrite bool) error {
 	stat, err := os.Stat(localFullPath)
 	if err != nil {
 		helpers.AppLogger.Errorf("要上传的文件 %s 无法获取到文件信息，错误：%vs", localFullPath, err.Error())
@@ -420,9 +518,9 @@ func AddUploadTaskFromMediaFile(mediaFile *ScrapeMediaFile, scrapePath *ScrapePa
 	}
 	// 插入新纪录
 	task := &DbUploadTask{
-		AccountId:            scrapePath.AccountId,
+		AccountId:            scrapePath.GetDestAccountId(),
 		ScrapeMediaFileId:    mediaFile.ID,
-		SourceType:           scrapePath.SourceType,
+		SourceType:           scrapePath.GetDestSourceType(),
 		RemoteFileId:         remoteFileId,
 		FileName:             fileName,
 		RemotePathId:         remotePathId,
@@ -431,6 +529,12 @@ func AddUploadTaskFromMediaFile(mediaFile *ScrapeMediaFile, scrapePath *ScrapePa
 		Status:               UploadStatusPending,
 		FileSize:             size,
 		IsSeasonOrTvshowFile: isSeasonOrTvshowFile,
+		Overwrite:            overwrite,
+		ScrapePathId:         scrapePath.ID,
+	}
+	// 跨存储整理时目标存储的操作不能用来源存储撤销，不记录
+	if !scrapePath.IsCrossStorage() {
+		task.RunNo = scrapePath.RunNo
 	}
 	derr := db.Db.Save(task).Error
 	return derr
@@ -0,0 +1,381 @@
+package models
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/v115open"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 添加跨存储整理产生的转移任务
+// source是来源文件，FileId和PickCode和扫描时一致（115是文件ID和pickcode，百度网盘是路径和fs_id，其他都是完整路径）
+// 来源是本地文件时直接从来源上传，来源在网盘时上传前先下载到临时目录
+func AddTransferUploadTask(mediaFile *ScrapeMediaFile, scrapePath *ScrapePath, source *MediaMetaFiles, sourcePathId, destPath, destPathId, destFileName string, isVideo bool) error {
+	remoteFileId := filepath.Join(destPath, destFileName)
+	if scrapePath.GetDestSourceType() != SourceTypeLocal {
+		remoteFileId = filepath.ToSlash(remoteFileId)
+	}
+	// 先检查是否存在
+	if task := CheckUploadTaskExist(UploadSourceTransfer, remoteFileId); task != nil {
+		if task.Status == UploadStatusPending {
+			return errors.New("任务已存在，状态为待上传")
+		}
+		if task.Status == UploadStatusUploading {
+			return errors.New("任务已存在，状态为上传中")
+		}
+	}
+	localFullPath := source.FileId
+	if scrapePath.SourceType != SourceTypeLocal {
+		localFullPath = filepath.Join(helpers.ConfigDir, "tmp", "跨存储整理", fmt.Sprintf("%d", mediaFile.ID), destFileName)
+	}
+	task := &DbUploadTask{
+		AccountId:          scrapePath.GetDestAccountId(),
+		ScrapeMediaFileId:  mediaFile.ID,
+		SourceType:         scrapePath.GetDestSourceType(),
+		RemoteFileId:       remoteFileId,
+		FileName:           destFileName,
+		RemotePathId:       destPathId,
+		LocalFullPath:      localFullPath,
+		Source:             UploadSourceTransfer,
+		Status:             UploadStatusPending,
+		TransferAccountId:  scrapePath.AccountId,
+		TransferSourceType: scrapePath.SourceType,
+		TransferFileId:     source.FileId,
+		TransferPickCode:   source.PickCode,
+		TransferPathId:     sourcePathId,
+		DeleteSource:       mediaFile.RenameType == RenameTypeMove,
+		IsVideo:            isVideo,
+	}
+	if err := db.Db.Save(task).Error; err != nil {
+		helpers.AppLogger.Errorf("添加转移任务 %s => %s 失败: %v", source.FileId, remoteFileId, err)
+		return err
+	}
+	helpers.AppLogger.Infof("添加转移任务 %s => %s 成功", source.FileId, remoteFileId)
+	return nil
+}
+
+// 执行跨存储整理的转移任务
+// 1. 目标已存在相同大小的文件时跳过上传
+// 2. 来源和目标都是115时先尝试秒传，不用下载
+// 3. 来源在网盘时先下载到临时目录，再按目标类型上传
+// 4. 校验目标文件大小和来源一致后，更新媒体的文件ID，移动模式再删除来源文件
+func (task *DbUploadTask) Transfer() {
+	task.Uploading()
+	sourceSize, sourceSha1, err := task.transferSourceInfo()
+	if err != nil {
+		task.Fail(err)
+		return
+	}
+	task.FileSize = sourceSize
+	fileId, pickCode, size := task.transferDestInfo()
+	if fileId == "" {
+		uploaded := false
+		if task.SourceType == SourceType115 && task.TransferSourceType == SourceType115 && sourceSha1 != "" {
+			uploaded, err = task.rapidUpload115(sourceSize, sourceSha1)
+			if err != nil {
+				helpers.AppLogger.Warnf("[转移] 115秒传 %s 失败，改为下载后上传: %v", task.FileName, err)
+			}
+		}
+		if !uploaded {
+			if err := task.downloadTransferSource(sourceSize); err != nil {
+				task.Fail(err)
+				return
+			}
+			if !task.uploadTransferFile() {
+				return
+			}
+		}
+		fileId, pickCode, size = task.transferDestInfo()
+	} else {
+		helpers.AppLogger.Infof("[转移] 目标文件 %s 已存在，跳过上传", task.RemoteFileId)
+	}
+	// 校验
+	if fileId == "" {
+		task.Fail(fmt.Errorf("上传完成后目标文件 %s 不存在", task.RemoteFileId))
+		return
+	}
+	if size != sourceSize {
+		task.Fail(fmt.Errorf("目标文件 %s 大小 %d 和来源文件大小 %d 不一致", task.RemoteFileId, size, sourceSize))
+		return
+	}
+	task.updateTransferredMedia(fileId, pickCode)
+	task.removeTransferTmpFile()
+	if task.DeleteSource {
+		if err := task.deleteTransferSource(); err != nil {
+			// 目标文件已经校验通过，重试时会跳过上传直接删除来源
+			task.Fail(fmt.Errorf("文件已转移到 %s，但删除来源文件 %s 失败: %v", task.RemoteFileId, task.TransferFileId, err))
+			return
+		}
+		helpers.AppLogger.Infof("[转移] 已删除来源文件 %s", task.TransferFileId)
+	}
+	task.Complete()
+	helpers.AppLogger.Infof("[转移] 文件 %s 已转移到 %s", task.TransferFileId, task.RemoteFileId)
+}
+
+func (task *DbUploadTask) getTransferAccount() (*Account, error) {
+	if task.TransferSourceType == SourceTypeLocal {
+		return nil, nil
+	}
+	return GetAccountById(task.TransferAccountId)
+}
+
+// 查询来源文件的大小，来源是115时同时返回sha1
+func (task *DbUploadTask) transferSourceInfo() (int64, string, error) {
+	if task.TransferSourceType == SourceTypeLocal {
+		stat, err := os.Stat(task.TransferFileId)
+		if err != nil {
+			return 0, "", fmt.Errorf("来源文件 %s 不存在: %v", task.TransferFileId, err)
+		}
+		return stat.Size(), "", nil
+	}
+	account, err := task.getTransferAccount()
+	if err != nil {
+		return 0, "", fmt.Errorf("来源账号 %d 不存在: %v", task.TransferAccountId, err)
+	}
+	switch task.TransferSourceType {
+	case SourceType115:
+		detail, err := account.Get115Client().GetFsDetailByCid(context.Background(), task.TransferFileId)
+		if err != nil || detail.FileId == "" {
+			return 0, "", fmt.Errorf("来源文件 %s 不存在: %v", task.TransferFileId, err)
+		}
+		return detail.FileSizeByte, detail.Sha1, nil
+	case SourceTypeOpenList:
+		detail, err := account.GetOpenListClient().FileDetail(task.TransferFileId)
+		if err != nil {
+			return 0, "", fmt.Errorf("来源文件 %s 不存在: %v", task.TransferFileId, err)
+		}
+		return detail.Size, "", nil
+	case SourceTypeBaiduPan:
+		info, err := account.GetBaiDuPanClient().FileExists(context.Background(), task.TransferFileId)
+		if err != nil || info == nil {
+			return 0, "", fmt.Errorf("来源文件 %s 不存在: %v", task.TransferFileId, err)
+		}
+		return int64(info.Size), "", nil
+	}
+	return 0, "", fmt.Errorf("不支持的来源类型 %s", task.TransferSourceType)
+}
+
+// 查询目标文件，不存在时返回空的文件ID
+// 返回的文件ID和提取码和扫描时的规则一致
+func (task *DbUploadTask) transferDestInfo() (string, string, int64) {
+	if task.SourceType == SourceTypeLocal {
+		stat, err := os.Stat(task.RemoteFileId)
+		if err != nil {
+			return "", "", 0
+		}
+		return task.RemoteFileId, task.RemoteFileId, stat.Size()
+	}
+	account := task.GetAccount()
+	if account == nil {
+		return "", "", 0
+	}
+	switch task.SourceType {
+	case SourceType115:
+		detail, err := account.Get115Client().GetFsDetailByPath(context.Background(), task.RemoteFileId)
+		if err != nil || detail.FileId == "" {
+			return "", "", 0
+		}
+		return detail.FileId, detail.PickCode, detail.FileSizeByte
+	case SourceTypeOpenList:
+		detail, err := account.GetOpenListClient().FileDetail(task.RemoteFileId)
+		if err != nil {
+			return "", "", 0
+		}
+		return task.RemoteFileId, task.RemoteFileId, detail.Size
+	case SourceTypeBaiduPan:
+		info, err := account.GetBaiDuPanClient().FileExists(context.Background(), task.RemoteFileId)
+		if err != nil || info == nil || info.ServerFilename == "" {
+			return "", "", 0
+		}
+		return task.RemoteFileId, helpers.Int64ToString(int64(info.FsId)), int64(info.Size)
+	}
+	return "", "", 0
+}
+
+// 115到115用sha1秒传，pre_id和二次认证需要的片段从来源的下载链接按区间读取
+// 返回是否秒传成功
+func (task *DbUploadTask) rapidUpload115(size int64, sha1 string) (bool, error) {
+	account := task.GetAccount()
+	if account == nil {
+		return false, fmt.Errorf("目标账号 %d 不存在", task.AccountId)
+	}
+	sourceAccount, err := task.getTransferAccount()
+	if err != nil {
+		return false, err
+	}
+	url := sourceAccount.Get115Client().GetDownloadUrl(context.Background(), task.TransferPickCode, v115open.DEFAULTUA, false)
+	if url == "" {
+		return false, fmt.Errorf("获取来源文件 %s 的下载链接失败", task.TransferPickCode)
+	}
+	readRange := func(start, end int64) ([]byte, error) {
+		return readUrlRange(url, v115open.DEFAULTUA, start, end)
+	}
+	fileId, err := account.Get115Client().UploadResume(context.Background(), task.FileName, size, task.RemotePathId, sha1, readRange)
+	if errors.Is(err, v115open.ErrUploadNeedData) {
+		helpers.AppLogger.Infof("[转移] 115没有相同的文件 %s，不能秒传", task.FileName)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	helpers.AppLogger.Infof("[转移] 115秒传 %s 成功, 文件ID: %s", task.FileName, fileId)
+	return true, nil
+}
+
+// 读取下载链接中[start, end]区间的数据
+func readUrlRange(url, userAgent string, start, end int64) ([]byte, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	client := &http.Client{Timeout: 60 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent && resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("读取区间 %d-%d 失败，HTTP状态码: %d", start, end, resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, end-start+1))
+}
+
+// 把网盘中的来源文件下载到临时目录，已下载完整的不再重复下载
+func (task *DbUploadTask) downloadTransferSource(size int64) error {
+	if task.TransferSourceType == SourceTypeLocal {
+		return nil
+	}
+	if stat, err := os.Stat(task.LocalFullPath); err == nil && stat.Size() == size {
+		helpers.AppLogger.Infof("[转移] 临时文件 %s 已下载，跳过下载", task.LocalFullPath)
+		return nil
+	}
+	account, err := task.getTransferAccount()
+	if err != nil {
+		return fmt.Errorf("来源账号 %d 不存在: %v", task.TransferAccountId, err)
+	}
+	url := ""
+	ua := v115open.DEFAULTUA
+	switch task.TransferSourceType {
+	case SourceType115:
+		url = account.Get115Client().GetDownloadUrl(context.Background(), task.TransferPickCode, ua, false)
+	case SourceTypeOpenList:
+		url = account.GetOpenListClient().GetRawUrl(task.TransferFileId)
+	case SourceTypeBaiduPan:
+		detail, err := account.GetBaiDuPanClient().GetFileDetail(context.Background(), task.TransferPickCode, 1)
+		if err != nil {
+			return fmt.Errorf("获取百度网盘文件 %s 详情失败: %v", task.TransferFileId, err)
+		}
+		url = fmt.Sprintf("%s&access_token=%s", detail.Dlink, account.Token)
+		ua = "pan.baidu.com"
+	}
+	if url == "" {
+		return fmt.Errorf("获取来源文件 %s 的下载链接失败", task.TransferFileId)
+	}
+	helpers.AppLogger.Infof("[转移] 开始下载来源文件 %s 到临时文件 %s", task.TransferFileId, task.LocalFullPath)
+	if err := helpers.DownloadFileWithProgress(context.Background(), "", url, task.LocalFullPath, ua, nil); err != nil {
+		return fmt.Errorf("下载来源文件 %s 失败: %v", task.TransferFileId, err)
+	}
+	return nil
+}
+
+// 按目标类型上传
+func (task *DbUploadTask) uploadTransferFile() bool {
+	switch task.SourceType {
+	case SourceType115:
+		if !task.Upload115File() {
+			return false
+		}
+		// 115上传使用本地文件名，来源是本地文件时需要改成整理后的名字
+		localName := filepath.Base(task.LocalFullPath)
+		if localName == task.FileName {
+			return true
+		}
+		client := task.GetAccount().Get115Client()
+		detail, err := client.GetFsDetailByPath(context.Background(), filepath.ToSlash(filepath.Join(filepath.Dir(task.RemoteFileId), localName)))
+		if err != nil || detail.FileId == "" {
+			task.Fail(fmt.Errorf("查询上传后的文件 %s 失败: %v", localName, err))
+			return false
+		}
+		if _, err := client.ReName(context.Background(), detail.FileId, task.FileName); err != nil {
+			task.Fail(fmt.Errorf("上传后的文件 %s 改名为 %s 失败: %v", localName, task.FileName, err))
+			return false
+		}
+		return true
+	case SourceTypeOpenList:
+		return task.UploadOpenListFile()
+	case SourceTypeBaiduPan:
+		return task.UploadBaiduPanFile()
+	case SourceTypeLocal:
+		return task.UploadLocalFile()
+	}
+	task.Fail(fmt.Errorf("未知的上传目标类型 %s", task.SourceType))
+	return false
+}
+
+// 转移完成后更新媒体记录中的视频或字幕文件ID
+func (task *DbUploadTask) updateTransferredMedia(fileId, pickCode string) {
+	mediaFile := GetScrapeMediaFileById(task.ScrapeMediaFileId)
+	if mediaFile == nil {
+		return
+	}
+	if task.IsVideo {
+		if mediaFile.MediaType == MediaTypeTvShow && mediaFile.MediaEpisode != nil {
+			mediaFile.MediaEpisode.VideoFileId = fileId
+			mediaFile.MediaEpisode.VideoPickCode = pickCode
+			mediaFile.MediaEpisode.Save()
+		} else if mediaFile.Media != nil {
+			mediaFile.Media.VideoFileId = fileId
+			mediaFile.Media.VideoPickCode = pickCode
+			mediaFile.Media.Save()
+		}
+		return
+	}
+	if mediaFile.MediaType != MediaTypeTvShow && mediaFile.Media != nil {
+		for _, sub := range mediaFile.Media.SubtitleFiles {
+			if sub.FileName == task.FileName {
+				sub.FileId = fileId
+				sub.PickCode = pickCode
+			}
+		}
+		mediaFile.Media.Save()
+	}
+}
+
+// 删除下载到临时目录的来源文件
+func (task *DbUploadTask) removeTransferTmpFile() {
+	if task.TransferSourceType == SourceTypeLocal {
+		return
+	}
+	os.Remove(task.LocalFullPath)
+	// 目录为空时删除
+	os.Remove(filepath.Dir(task.LocalFullPath))
+}
+
+// 删除来源文件（移动模式）
+func (task *DbUploadTask) deleteTransferSource() error {
+	if task.TransferSourceType == SourceTypeLocal {
+		return os.Remove(task.TransferFileId)
+	}
+	account, err := task.getTransferAccount()
+	if err != nil {
+		return err
+	}
+	switch task.TransferSourceType {
+	case SourceType115:
+		_, err = account.Get115Client().Del(context.Background(), []string{task.TransferFileId}, task.TransferPathId)
+	case SourceTypeOpenList:
+		err = account.GetOpenListClient().Del(filepath.ToSlash(filepath.Dir(task.TransferFileId)), []string{filepath.Base(task.TransferFileId)})
+	case SourceTypeBaiduPan:
+		err = account.GetBaiDuPanClient().Del(context.Background(), []string{task.TransferFileId})
+	}
+	return err
+}
@@ -3,6 +3,7 @@ package models
 import (
 	"Q115-STRM/internal/db"
 	"Q115-STRM/internal/helpers"
+	ws "Q115-STRM/internal/websocket"
 	"context"
 	"sync"
 	"time"
@@ -77,6 +78,7 @@ func (dq *DQ) Start() {
 	}
 	dq.running = true
 	dq.mutex.Unlock()
+	ws.BroadcastEvent(ws.EventQueueStateChange, ws.QueueStateData{Queue: ws.QueueDownload, Running: true})
 
 	// 启动工作协程
 	for i := 0; i < dq.numWorkers; i++ {
@@ -97,6 +99,7 @@ func (dq *DQ) Stop() {
 	}
 	dq.running = false
 	dq.mutex.Unlock()
+	ws.BroadcastEvent(ws.EventQueueStateChange, ws.QueueStateData{Queue: ws.QueueDownload, Running: false})
 
 	// 关闭tasks通道
 	close(dq.tasks)
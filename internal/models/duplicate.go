@@ -0,0 +1,546 @@
+package models
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+type DuplicateMatchType string
+
+const (
+	DuplicateMatchHash DuplicateMatchType = "hash" // 文件SHA1相同，内容完全一样
+	DuplicateMatchTmdb DuplicateMatchType = "tmdb" // TMDB ID（剧集还包括季和集）相同，可能是不同版本
+)
+
+type DuplicateGroupStatus string
+
+const (
+	DuplicateGroupStatusPending  DuplicateGroupStatus = "pending"  // 待确认
+	DuplicateGroupStatusDeleting DuplicateGroupStatus = "deleting" // 已确认，正在删除
+	DuplicateGroupStatusDone     DuplicateGroupStatus = "done"     // 已删除
+	DuplicateGroupStatusFailed   DuplicateGroupStatus = "failed"   // 有文件删除失败
+	DuplicateGroupStatusIgnored  DuplicateGroupStatus = "ignored"  // 已忽略，重新查找时不再出现
+)
+
+type DuplicateFileStatus string
+
+const (
+	DuplicateFileStatusNormal  DuplicateFileStatus = ""        // 未处理
+	DuplicateFileStatusDeleted DuplicateFileStatus = "deleted" // 已删除
+	DuplicateFileStatusFailed  DuplicateFileStatus = "failed"  // 删除失败
+)
+
+// 自动选择保留文件的策略
+type DuplicatePolicy string
+
+const (
+	DuplicatePolicyHighestResolution DuplicatePolicy = "highest_resolution" // 保留分辨率最高的
+	DuplicatePolicyPreferredAccount  DuplicatePolicy = "preferred_account"  // 保留首选账号中的
+)
+
+// 重复文件组
+type DuplicateGroup struct {
+	BaseModel
+	MatchType     DuplicateMatchType   `json:"match_type" gorm:"index"` // 匹配方式
+	MatchKey      string               `json:"match_key" gorm:"index"`  // 匹配键：SHA1或者 类型:TMDB ID[:季:集]
+	Name          string               `json:"name"`                    // 显示名称
+	MediaType     MediaType            `json:"media_type"`              // 媒体类型，按SHA1匹配时为空
+	TmdbId        int64                `json:"tmdb_id"`                 // TMDB ID
+	SeasonNumber  int                  `json:"season_number"`           // 季编号
+	EpisodeNumber int                  `json:"episode_number"`          // 集编号
+	FileCount     int                  `json:"file_count"`              // 文件数
+	WastedSize    int64                `json:"wasted_size"`             // 不保留的文件大小合计，单位：字节
+	Status        DuplicateGroupStatus `json:"status" gorm:"index"`     // 状态
+	Files         []*DuplicateFile     `json:"files" gorm:"-"`          // 组内文件
+}
+
+func (*DuplicateGroup) TableName() string {
+	return "duplicate_groups"
+}
+
+// 重复文件组中的文件
+// 115的FileId是网盘文件ID，ParentId是父目录ID，其他来源都是完整路径
+type DuplicateFile struct {
+	BaseModel
+	GroupId           uint                `json:"group_id" gorm:"index"` // 重复文件组ID
+	SyncFileId        uint                `json:"sync_file_id"`          // 同步文件ID，没有同步记录时为0
+	ScrapeMediaFileId uint                `json:"scrape_media_file_id"`  // 刮削记录ID，按SHA1匹配时为0
+	SourceType        SourceType          `json:"source_type"`           // 来源类型
+	AccountId         uint                `json:"account_id"`            // 账号ID，本地为0
+	FileId            string              `json:"file_id"`               // 文件ID
+	ParentId          string              `json:"parent_id"`             // 父目录ID
+	PickCode          string              `json:"pick_code"`             // 115 pickcode 或者 百度网盘 fsid
+	FileName          string              `json:"file_name"`             // 文件名
+	Path              string              `json:"path"`                  // 所在目录
+	FileSize          int64               `json:"file_size"`             // 文件大小，未知时为0
+	Resolution        int                 `json:"resolution"`            // 分辨率高度，未知时为0
+	IsHDR             bool                `json:"is_hdr"`                // 是否HDR
+	Bitrate           int64               `json:"bitrate"`               // 视频码率
+	Keep              bool                `json:"keep"`                  // 是否保留
+	Status            DuplicateFileStatus `json:"status"`                // 状态
+	Error             string              `json:"error"`                 // 删除失败的原因
+}
+
+func (*DuplicateFile) TableName() string {
+	return "duplicate_files"
+}
+
+// 账号的重复文件占用
+type DuplicateAccountSummary struct {
+	AccountId   uint       `json:"account_id"`
+	AccountName string     `json:"account_name"`
+	SourceType  SourceType `json:"source_type"`
+	FileCount   int64      `json:"file_count"`  // 不保留的文件数
+	WastedSize  int64      `json:"wasted_size"` // 不保留的文件大小合计，单位：字节
+}
+
+// 同一个文件只算一次（多个同步目录可能包含同一个文件）
+func (f *DuplicateFile) identity() string {
+	return fmt.Sprintf("%s:%d:%s", f.SourceType, f.AccountId, f.FileId)
+}
+
+// 比较两个文件哪个更值得保留，a更好返回1，b更好返回-1
+// 首选账号策略先比较账号，然后依次比较分辨率、HDR、码率、文件大小，都相同时先入库的更好
+func CompareDuplicateFile(a, b *DuplicateFile, policy DuplicatePolicy, preferredAccountId uint) int {
+	if policy == DuplicatePolicyPreferredAccount && preferredAccountId > 0 {
+		if ap, bp := a.AccountId == preferredAccountId, b.AccountId == preferredAccountId; ap != bp {
+			if ap {
+				return 1
+			}
+			return -1
+		}
+	}
+	if a.Resolution != b.Resolution {
+		if a.Resolution > b.Resolution {
+			return 1
+		}
+		return -1
+	}
+	if a.IsHDR != b.IsHDR {
+		if a.IsHDR {
+			return 1
+		}
+		return -1
+	}
+	if a.Bitrate != b.Bitrate {
+		if a.Bitrate > b.Bitrate {
+			return 1
+		}
+		return -1
+	}
+	if a.FileSize != b.FileSize {
+		if a.FileSize > b.FileSize {
+			return 1
+		}
+		return -1
+	}
+	// 分辨率策略在质量相同时也优先保留首选账号中的
+	if preferredAccountId > 0 {
+		if ap, bp := a.AccountId == preferredAccountId, b.AccountId == preferredAccountId; ap != bp {
+			if ap {
+				return 1
+			}
+			return -1
+		}
+	}
+	if a.SyncFileId != b.SyncFileId {
+		if a.SyncFileId < b.SyncFileId {
+			return 1
+		}
+		return -1
+	}
+	return 0
+}
+
+// 按策略标记组内保留的文件，只保留一个，返回不保留的文件大小合计
+func ApplyDuplicatePolicy(files []*DuplicateFile, policy DuplicatePolicy, preferredAccountId uint) int64 {
+	var best *DuplicateFile
+	for _, f := range files {
+		if best == nil || CompareDuplicateFile(f, best, policy, preferredAccountId) > 0 {
+			best = f
+		}
+	}
+	var wasted int64
+	for _, f := range files {
+		f.Keep = f == best
+		if !f.Keep {
+			wasted += f.FileSize
+		}
+	}
+	return wasted
+}
+
+// 查找重复文件，重新生成待确认的重复文件组
+// 已忽略、正在删除和删除失败的组保留，再次出现时不重复加入
+func FindDuplicates(policy DuplicatePolicy, preferredAccountId uint) (int, error) {
+	ignored := make(map[string]bool)
+	var keys []string
+	db.Db.Model(&DuplicateGroup{}).Where("status IN ?", []DuplicateGroupStatus{DuplicateGroupStatusIgnored, DuplicateGroupStatusDeleting, DuplicateGroupStatusFailed}).Pluck("match_key", &keys)
+	for _, key := range keys {
+		ignored[key] = true
+	}
+	groups := make([]*DuplicateGroup, 0)
+	groups = append(groups, findHashDuplicates(ignored)...)
+	groups = append(groups, findTmdbDuplicates(ignored)...)
+	// 删除上一次的待确认组
+	var pendingIds []uint
+	db.Db.Model(&DuplicateGroup{}).Where("status = ?", DuplicateGroupStatusPending).Pluck("id", &pendingIds)
+	if len(pendingIds) > 0 {
+		if err := db.Db.Where("group_id IN ?", pendingIds).Delete(&DuplicateFile{}).Error; err != nil {
+			return 0, err
+		}
+		if err := db.Db.Where("id IN ?", pendingIds).Delete(&DuplicateGroup{}).Error; err != nil {
+			return 0, err
+		}
+	}
+	for _, group := range groups {
+		group.Status = DuplicateGroupStatusPending
+		group.FileCount = len(group.Files)
+		group.WastedSize = ApplyDuplicatePolicy(group.Files, policy, preferredAccountId)
+		if err := db.Db.Create(group).Error; err != nil {
+			helpers.AppLogger.Errorf("保存重复文件组 %s 失败: %v", group.MatchKey, err)
+			continue
+		}
+		for _, f := range group.Files {
+			f.GroupId = group.ID
+		}
+		if err := db.Db.Create(group.Files).Error; err != nil {
+			helpers.AppLogger.Errorf("保存重复文件组 %s 的文件失败: %v", group.MatchKey, err)
+		}
+	}
+	return len(groups), nil
+}
+
+// 按SHA1分组，只有115等会返回SHA1的来源才能匹配
+func findHashDuplicates(ignored map[string]bool) []*DuplicateGroup {
+	var hashes []string
+	if err := db.Db.Model(&SyncFile{}).Where("is_video = ? AND sha1 <> ''", true).Group("sha1").Having("COUNT(*) > 1").Pluck("sha1", &hashes).Error; err != nil {
+		helpers.AppLogger.Errorf("按SHA1查询重复文件失败: %v", err)
+		return nil
+	}
+	groups := make([]*DuplicateGroup, 0, len(hashes))
+	for _, hash := range hashes {
+		if ignored[hash] {
+			continue
+		}
+		var syncFiles []*SyncFile
+		if err := db.Db.Where("sha1 = ? AND is_video = ?", hash, true).Order("id ASC").Find(&syncFiles).Error; err != nil {
+			helpers.AppLogger.Errorf("查询SHA1为 %s 的文件失败: %v", hash, err)
+			continue
+		}
+		files := make([]*DuplicateFile, 0, len(syncFiles))
+		seen := make(map[string]bool)
+		for _, sf := range syncFiles {
+			f := &DuplicateFile{
+				SyncFileId: sf.ID,
+				SourceType: sf.SourceType,
+				AccountId:  sf.AccountId,
+				FileId:     sf.FileId,
+				ParentId:   sf.ParentId,
+				PickCode:   sf.PickCode,
+				FileName:   sf.FileName,
+				Path:       sf.Path,
+				FileSize:   sf.FileSize,
+			}
+			if seen[f.identity()] {
+				continue
+			}
+			seen[f.identity()] = true
+			files = append(files, f)
+		}
+		if len(files) < 2 {
+			continue
+		}
+		groups = append(groups, &DuplicateGroup{
+			MatchType: DuplicateMatchHash,
+			MatchKey:  hash,
+			Name:      files[0].FileName,
+			Files:     files,
+		})
+	}
+	return groups
+}
+
+type tmdbDuplicateKey struct {
+	MediaType     MediaType
+	TmdbId        int64
+	SeasonNumber  int
+	EpisodeNumber int
+}
+
+func (k tmdbDuplicateKey) String() string {
+	if k.MediaType == MediaTypeTvShow {
+		return fmt.Sprintf("%s:%d:%d:%d", k.MediaType, k.TmdbId, k.SeasonNumber, k.EpisodeNumber)
+	}
+	return fmt.Sprintf("%s:%d", k.MediaType, k.TmdbId)
+}
+
+// 按TMDB ID分组，剧集还要季和集相同，来自所有刮削目录
+// 已刮削或者已整理的记录才参与，同一刮削目录内的电影多版本不参与
+func findTmdbDuplicates(ignored map[string]bool) []*DuplicateGroup {
+	var keys []tmdbDuplicateKey
+	err := db.Db.Model(&ScrapeMediaFile{}).
+		Select("media_type, tmdb_id, season_number, episode_number").
+		Where("tmdb_id > 0 AND media_type IN ? AND status IN ?", []MediaType{MediaTypeMovie, MediaTypeTvShow}, []ScrapeMediaStatus{ScrapeMediaStatusScraped, ScrapeMediaStatusRenamed}).
+		Group("media_type, tmdb_id, season_number, episode_number").
+		Having("COUNT(*) > 1").
+		Scan(&keys).Error
+	if err != nil {
+		helpers.AppLogger.Errorf("按TMDB ID查询重复文件失败: %v", err)
+		return nil
+	}
+	scrapePaths := make(map[uint]*ScrapePath)
+	groups := make([]*DuplicateGroup, 0, len(keys))
+	for _, key := range keys {
+		matchKey := key.String()
+		if ignored[matchKey] {
+			continue
+		}
+		var scrapeMediaFiles []*ScrapeMediaFile
+		err := db.Db.Where("media_type = ? AND tmdb_id = ? AND season_number = ? AND episode_number = ? AND status IN ?", key.MediaType, key.TmdbId, key.SeasonNumber, key.EpisodeNumber, []ScrapeMediaStatus{ScrapeMediaStatusScraped, ScrapeMediaStatusRenamed}).Order("id ASC").Find(&scrapeMediaFiles).Error
+		if err != nil {
+			helpers.AppLogger.Errorf("查询TMDB ID为 %d 的刮削记录失败: %v", key.TmdbId, err)
+			continue
+		}
+		files := make([]*DuplicateFile, 0, len(scrapeMediaFiles))
+		seen := make(map[string]bool)
+		for _, sm := range scrapeMediaFiles {
+			// 按多版本规则整理的电影版本是有意保留的，不算重复
+			if sm.MediaType == MediaTypeMovie && (sm.VersionLabel != "" || sm.IsVersionHeld) {
+				continue
+			}
+			sm.DecodeJson()
+			sp, ok := scrapePaths[sm.ScrapePathId]
+			if !ok {
+				sp = GetScrapePathByID(sm.ScrapePathId)
+				scrapePaths[sm.ScrapePathId] = sp
+			}
+			if sp == nil {
+				continue
+			}
+			f := newDuplicateFileFromScrapeMedia(sm, sp)
+			if seen[f.identity()] {
+				continue
+			}
+			seen[f.identity()] = true
+			files = append(files, f)
+		}
+		if len(files) < 2 {
+			continue
+		}
+		name := fmt.Sprintf("%s (%d)", scrapeMediaFiles[0].Name, scrapeMediaFiles[0].Year)
+		if key.MediaType == MediaTypeTvShow {
+			name = fmt.Sprintf("%s S%02dE%02d", name, key.SeasonNumber, key.EpisodeNumber)
+		}
+		groups = append(groups, &DuplicateGroup{
+			MatchType:     DuplicateMatchTmdb,
+			MatchKey:      matchKey,
+			Name:          name,
+			MediaType:     key.MediaType,
+			TmdbId:        key.TmdbId,
+			SeasonNumber:  key.SeasonNumber,
+			EpisodeNumber: key.EpisodeNumber,
+			Files:         files,
+		})
+	}
+	return groups
+}
+
+// 刮削记录当前的视频文件，已整理的在新目录中
+// 有同步记录时使用同步记录中的文件大小
+func newDuplicateFileFromScrapeMedia(sm *ScrapeMediaFile, sp *ScrapePath) *DuplicateFile {
+	f := &DuplicateFile{
+		ScrapeMediaFileId: sm.ID,
+		SourceType:        sm.SourceType,
+		AccountId:         sp.AccountId,
+		FileId:            sm.VideoFileId,
+		ParentId:          sm.PathId,
+		PickCode:          sm.VideoPickCode,
+		FileName:          sm.VideoFilename,
+		Path:              filepath.Join(sm.SourcePath, sm.Path),
+		Resolution:        sm.GetResolutionHeight(),
+		IsHDR:             sm.IsHDR,
+	}
+	if sm.VideoCodec != nil {
+		f.Bitrate = sm.VideoCodec.Bitrate
+	}
+	if sm.SourceType == SourceTypeLocal {
+		f.AccountId = 0
+	}
+	if sm.Status == ScrapeMediaStatusRenamed && sm.NewPathId != "" {
+		f.SourceType = sp.GetDestSourceType()
+		f.AccountId = sp.GetDestAccountId()
+		f.FileName = sm.NewVideoBaseName + sm.VideoExt
+		f.ParentId = sm.NewPathId
+		f.Path = sm.NewPathId
+		if f.SourceType != SourceType115 {
+			// 其他来源的ID是完整路径
+			f.FileId = filepath.Join(sm.NewPathId, f.FileName)
+			if f.SourceType != SourceTypeLocal {
+				f.FileId = filepath.ToSlash(f.FileId)
+			}
+		}
+	}
+	var sf SyncFile
+	if err := db.Db.Where("source_type = ? AND file_id = ?", f.SourceType, f.FileId).First(&sf).Error; err == nil {
+		f.SyncFileId = sf.ID
+		f.FileSize = sf.FileSize
+		f.PickCode = sf.PickCode
+		f.Path = sf.Path
+	}
+	return f
+}
+
+// 分页查询重复文件组，包含组内文件
+func GetDuplicateGroups(status DuplicateGroupStatus, matchType DuplicateMatchType, page, pageSize int) ([]*DuplicateGroup, int64) {
+	groups := make([]*DuplicateGroup, 0)
+	var total int64
+	tx := db.Db.Model(&DuplicateGroup{})
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+	if matchType != "" {
+		tx = tx.Where("match_type = ?", matchType)
+	}
+	tx.Count(&total)
+	if err := tx.Order("wasted_size DESC, id ASC").Limit(pageSize).Offset((page - 1) * pageSize).Find(&groups).Error; err != nil {
+		helpers.AppLogger.Errorf("查询重复文件组失败: %v", err)
+		return groups, total
+	}
+	for _, group := range groups {
+		group.Files = GetDuplicateFilesByGroupId(group.ID)
+	}
+	return groups, total
+}
+
+func GetDuplicateGroupById(id uint) *DuplicateGroup {
+	var group DuplicateGroup
+	if err := db.Db.Where("id = ?", id).First(&group).Error; err != nil {
+		return nil
+	}
+	group.Files = GetDuplicateFilesByGroupId(group.ID)
+	return &group
+}
+
+func GetDuplicateFilesByGroupId(groupId uint) []*DuplicateFile {
+	var files []*DuplicateFile
+	if err := db.Db.Where("group_id = ?", groupId).Order("id ASC").Find(&files).Error; err != nil {
+		helpers.AppLogger.Errorf("查询重复文件组 %d 的文件失败: %v", groupId, err)
+	}
+	return files
+}
+
+// 按账号统计待确认重复文件中不保留的文件占用的空间
+func GetDuplicateAccountSummary() []*DuplicateAccountSummary {
+	summary := make([]*DuplicateAccountSummary, 0)
+	err := db.Db.Model(&DuplicateFile{}).
+		Select("duplicate_files.account_id, duplicate_files.source_type, COUNT(*) AS file_count, SUM(duplicate_files.file_size) AS wasted_size").
+		Joins("JOIN duplicate_groups ON duplicate_groups.id = duplicate_files.group_id").
+		Where("duplicate_groups.status IN ? AND duplicate_files.keep = ? AND duplicate_files.status <> ?", []DuplicateGroupStatus{DuplicateGroupStatusPending, DuplicateGroupStatusFailed}, false, DuplicateFileStatusDeleted).
+		Group("duplicate_files.account_id, duplicate_files.source_type").
+		Order("wasted_size DESC").
+		Scan(&summary).Error
+	if err != nil {
+		helpers.AppLogger.Errorf("统计重复文件占用失败: %v", err)
+	}
+	for _, s := range summary {
+		if s.AccountId == 0 {
+			s.AccountName = "本地"
+			continue
+		}
+		if account, err := GetAccountById(s.AccountId); err == nil {
+			s.AccountName = account.Name
+		}
+	}
+	return summary
+}
+
+// 手动选择组内保留的文件，至少保留一个
+func (g *DuplicateGroup) SetKeepFiles(fileIds []uint) error {
+	if g.Status != DuplicateGroupStatusPending && g.Status != DuplicateGroupStatusFailed {
+		return fmt.Errorf("重复文件组 %s 不是待确认状态", g.Name)
+	}
+	if len(fileIds) == 0 {
+		return errors.New("至少要保留一个文件")
+	}
+	// 提交的文件必须属于这个组，并且至少保留一个未删除的文件
+	hasKeep := false
+	for _, id := range fileIds {
+		idx := slices.IndexFunc(g.Files, func(f *DuplicateFile) bool { return f.ID == id })
+		if idx < 0 {
+			return fmt.Errorf("文件 %d 不属于重复文件组 %s", id, g.Name)
+		}
+		if g.Files[idx].Status != DuplicateFileStatusDeleted {
+			hasKeep = true
+		}
+	}
+	if !hasKeep {
+		return errors.New("至少要保留一个文件")
+	}
+	var wasted int64
+	for _, f := range g.Files {
+		if f.Status == DuplicateFileStatusDeleted {
+			continue
+		}
+		f.Keep = slices.Contains(fileIds, f.ID)
+		if !f.Keep {
+			wasted += f.FileSize
+		}
+		if err := db.Db.Model(&DuplicateFile{}).Where("id = ?", f.ID).Update("keep", f.Keep).Error; err != nil {
+			return err
+		}
+	}
+	g.WastedSize = wasted
+	return db.Db.Model(&DuplicateGroup{}).Where("id = ?", g.ID).Update("wasted_size", wasted).Error
+}
+
+func (g *DuplicateGroup) UpdateStatus(status DuplicateGroupStatus) {
+	g.Status = status
+	if err := db.Db.Model(&DuplicateGroup{}).Where("id = ?", g.ID).Update("status", status).Error; err != nil {
+		helpers.AppLogger.Errorf("更新重复文件组 %d 状态失败: %v", g.ID, err)
+	}
+}
+
+func (f *DuplicateFile) UpdateStatus(status DuplicateFileStatus, errMsg string) {
+	f.Status = status
+	f.Error = errMsg
+	if err := db.Db.Model(&DuplicateFile{}).Where("id = ?", f.ID).Updates(map[string]interface{}{"status": status, "error": errMsg}).Error; err != nil {
+		helpers.AppLogger.Errorf("更新重复文件 %d 状态失败: %v", f.ID, err)
+	}
+}
+
+// 保留的文件是否已经在其他组中被删除
+// 同一个文件可能同时出现在按SHA1和按TMDB ID匹配的组中，避免两个组互相删除对方保留的文件
+func (f *DuplicateFile) IsDeletedElsewhere() bool {
+	var count int64
+	db.Db.Model(&DuplicateFile{}).Where("source_type = ? AND account_id = ? AND file_id = ? AND status = ?", f.SourceType, f.AccountId, f.FileId, DuplicateFileStatusDeleted).Count(&count)
+	return count > 0
+}
+
+// 删除文件后清理同步记录、STRM文件和刮削记录
+func (f *DuplicateFile) CleanupRecords() {
+	if sf := GetSyncFileById(f.SyncFileId); sf != nil {
+		if strings.HasSuffix(sf.LocalFilePath, ".strm") && helpers.PathExists(sf.LocalFilePath) {
+			if err := os.Remove(sf.LocalFilePath); err != nil {
+				helpers.AppLogger.Errorf("删除STRM文件 %s 失败: %v", sf.LocalFilePath, err)
+			}
+		}
+		if err := DeleteEmbyMediaSyncFilesBySyncFileID(sf.ID); err != nil {
+			helpers.AppLogger.Errorf("删除同步文件 %d 的Emby关联失败: %v", sf.ID, err)
+		}
+		if err := db.Db.Delete(&SyncFile{}, sf.ID).Error; err != nil {
+			helpers.AppLogger.Errorf("删除同步文件记录 %d 失败: %v", sf.ID, err)
+		}
+	}
+	if f.ScrapeMediaFileId > 0 {
+		if err := db.Db.Delete(&ScrapeMediaFile{}, f.ScrapeMediaFileId).Error; err != nil {
+			helpers.AppLogger.Errorf("删除刮削记录 %d 失败: %v", f.ScrapeMediaFileId, err)
+		}
+	}
+}
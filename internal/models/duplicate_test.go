@@ -0,0 +1,51 @@
+package models
+
+import "testing"
+
+func TestApplyDuplicatePolicy(t *testing.T) {
+	newFiles := func() []*DuplicateFile {
+		return []*DuplicateFile{
+			{SyncFileId: 1, AccountId: 1, Resolution: 1080, FileSize: 10},
+			{SyncFileId: 2, AccountId: 2, Resolution: 2160, FileSize: 40},
+			{SyncFileId: 3, AccountId: 3, Resolution: 2160, FileSize: 40},
+		}
+	}
+	tests := []struct {
+		name      string
+		policy    DuplicatePolicy
+		preferred uint
+		keep      uint
+		wasted    int64
+	}{
+		{name: "保留分辨率最高的", policy: DuplicatePolicyHighestResolution, keep: 2, wasted: 50},
+		{name: "分辨率相同时优先首选账号", policy: DuplicatePolicyHighestResolution, preferred: 3, keep: 3, wasted: 50},
+		{name: "保留首选账号中的", policy: DuplicatePolicyPreferredAccount, preferred: 1, keep: 1, wasted: 80},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := newFiles()
+			wasted := ApplyDuplicatePolicy(files, tt.policy, tt.preferred)
+			if wasted != tt.wasted {
+				t.Errorf("浪费空间期望: %d, 实际: %d", tt.wasted, wasted)
+			}
+			for _, f := range files {
+				if f.Keep != (f.SyncFileId == tt.keep) {
+					t.Errorf("文件 %d 保留状态错误: %v", f.SyncFileId, f.Keep)
+				}
+			}
+		})
+	}
+}
+
+func TestSetKeepFilesValidate(t *testing.T) {
+	g := &DuplicateGroup{Name: "沙丘 (2021)", Status: DuplicateGroupStatusPending, Files: []*DuplicateFile{
+		{BaseModel: BaseModel{ID: 1}},
+		{BaseModel: BaseModel{ID: 2}, Status: DuplicateFileStatusDeleted},
+	}}
+	if err := g.SetKeepFiles([]uint{3}); err == nil {
+		t.Errorf("不属于这个组的文件应该返回错误")
+	}
+	if err := g.SetKeepFiles([]uint{2}); err == nil {
+		t.Errorf("只保留已删除的文件应该返回错误")
+	}
+}
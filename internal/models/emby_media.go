@@ -266,6 +266,19 @@ func GetEmbyLibraryIdsBySyncPathId(syncPathId uint) map[string]string {
 	return libraryIds
 }
 
+// 查询Emby媒体项所在的媒体库名称，没有同步过的媒体项返回空字符串
+func GetEmbyLibraryNameByItemId(itemId string) string {
+	var item EmbyMediaItem
+	if err := db.Db.Where("item_id = ?", itemId).First(&item).Error; err != nil {
+		return ""
+	}
+	var library EmbyLibrary
+	if err := db.Db.Where("library_id = ?", item.LibraryId).First(&library).Error; err != nil {
+		return ""
+	}
+	return library.Name
+}
+
 // 刷新Emby媒体库通过SyncPathId
 func RefreshEmbyLibraryBySyncPathId(syncPathId uint) error {
 	if GlobalEmbyConfig == nil || GlobalEmbyConfig.EmbyUrl == "" || GlobalEmbyConfig.EmbyApiKey == "" || GlobalEmbyConfig.EnableRefreshLibrary == 0 {
@@ -0,0 +1,372 @@
+package models
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/notificationmanager"
+	"Q115-STRM/internal/tmdb"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 剧集播出状态
+type TvShowAirStatus string
+
+const (
+	TvShowAirStatusContinuing TvShowAirStatus = "continuing" // 连载中
+	TvShowAirStatusEnded      TvShowAirStatus = "ended"      // 已完结（包括被砍）
+)
+
+// 新播出的剧集只在这个天数内通知，避免开启通知时把以前缺失的集全部发出来
+const missingNotifyWindowDays = 30
+
+// 缺失的集
+type MissingEpisode struct {
+	SeasonNumber  int    `json:"season_number"`  // 季编号
+	EpisodeNumber int    `json:"episode_number"` // 集编号
+	Name          string `json:"name"`           // 集名称
+	AirDate       string `json:"air_date"`       // 播出日期，未知时为空
+}
+
+// 季的完整度
+type SeasonCompleteness struct {
+	SeasonNumber int               `json:"season_number"` // 季编号，0为特别篇
+	Name         string            `json:"name"`          // 季名称
+	EpisodeCount int               `json:"episode_count"` // TMDB上的集数（包括未播出的）
+	Aired        int               `json:"aired"`         // 已播出的集数
+	Owned        int               `json:"owned"`         // 已有的集数（只统计已播出的）
+	Complete     bool              `json:"complete"`      // 已播出的集是否都有了
+	Missing      []*MissingEpisode `json:"missing"`       // 缺失的集
+}
+
+// 剧集的完整度
+type TvShowCompleteness struct {
+	TmdbId            int64                 `json:"tmdb_id"`            // TMDB ID
+	Name              string                `json:"name"`               // 名称
+	Year              int                   `json:"year"`               // 年份
+	PosterPath        string                `json:"poster_path"`        // 海报
+	TmdbStatus        string                `json:"tmdb_status"`        // TMDB上的状态，例如：Returning Series、Ended
+	AirStatus         TvShowAirStatus       `json:"air_status"`         // 连载中或者已完结
+	NextAirDate       string                `json:"next_air_date"`      // 下一集播出日期
+	Aired             int                   `json:"aired"`              // 已播出的集数，不含特别篇
+	Owned             int                   `json:"owned"`              // 已有的集数，不含特别篇
+	Missing           int                   `json:"missing"`            // 缺失的集数，不含特别篇
+	IncompleteSeasons int                   `json:"incomplete_seasons"` // 不完整的季数，不含特别篇
+	Seasons           []*SeasonCompleteness `json:"seasons"`            // 各季完整度，不含特别篇
+	Specials          *SeasonCompleteness   `json:"specials"`           // 特别篇完整度，TMDB上没有特别篇时为nil
+	Error             string                `json:"error"`              // 获取TMDB信息失败的原因
+}
+
+// 缺集报告的查询条件
+type EpisodeReportFilter struct {
+	AirStatus       TvShowAirStatus // 为空表示全部
+	OnlyIncomplete  bool            // 只返回有缺集的剧集
+	IncludeSpecials bool            // 特别篇缺集也算不完整
+	Keyword         string          // 按名称过滤
+}
+
+// 已发送过的缺集通知，每一集只通知一次
+type MissingEpisodeNotice struct {
+	BaseModel
+	TmdbId        int64 `json:"tmdb_id" gorm:"uniqueIndex:idx_missing_episode"`
+	SeasonNumber  int   `json:"season_number" gorm:"uniqueIndex:idx_missing_episode"`
+	EpisodeNumber int   `json:"episode_number" gorm:"uniqueIndex:idx_missing_episode"`
+}
+
+func (*MissingEpisodeNotice) TableName() string {
+	return "missing_episode_notices"
+}
+
+// TMDB的剧集状态转换为连载中或者已完结
+func GetTvShowAirStatus(tmdbStatus string) TvShowAirStatus {
+	switch tmdbStatus {
+	case "Ended", "Canceled":
+		return TvShowAirStatusEnded
+	}
+	return TvShowAirStatusContinuing
+}
+
+// 计算剧集的完整度
+// seasons中没有集列表时（例如旧的缓存），使用季的集数和最近播出的一集推算已播出的集
+// owned: 季编号 => 集编号 => 是否已有
+func ComputeTvShowCompleteness(detail *tmdb.TvDetail, seasons map[int]*tmdb.SeasonDetail, owned map[int]map[int]bool, today string) *TvShowCompleteness {
+	report := &TvShowCompleteness{
+		TmdbId:     detail.ID,
+		Name:       detail.Name,
+		PosterPath: detail.PosterPath,
+		TmdbStatus: detail.Status,
+		AirStatus:  GetTvShowAirStatus(detail.Status),
+		Seasons:    make([]*SeasonCompleteness, 0, len(detail.Seasons)),
+	}
+	if len(detail.FirstAirDate) >= 4 {
+		report.Year = helpers.StringToInt(detail.FirstAirDate[:4])
+	}
+	if detail.NextEpisodeToAir != nil {
+		report.NextAirDate = detail.NextEpisodeToAir.AirDate
+	}
+	for _, season := range detail.Seasons {
+		sc := &SeasonCompleteness{
+			SeasonNumber: season.SeasonNumber,
+			Name:         season.Name,
+			EpisodeCount: season.EpisodeCount,
+			Missing:      make([]*MissingEpisode, 0),
+		}
+		for _, ep := range airedEpisodes(detail, season, seasons[season.SeasonNumber], today) {
+			sc.Aired++
+			if owned[season.SeasonNumber][ep.EpisodeNumber] {
+				sc.Owned++
+				continue
+			}
+			sc.Missing = append(sc.Missing, &MissingEpisode{
+				SeasonNumber:  season.SeasonNumber,
+				EpisodeNumber: ep.EpisodeNumber,
+				Name:          ep.Name,
+				AirDate:       ep.AirDate,
+			})
+		}
+		sc.Complete = sc.Aired > 0 && len(sc.Missing) == 0
+		if season.SeasonNumber == 0 {
+			report.Specials = sc
+			continue
+		}
+		report.Seasons = append(report.Seasons, sc)
+		report.Aired += sc.Aired
+		report.Owned += sc.Owned
+		report.Missing += len(sc.Missing)
+		if len(sc.Missing) > 0 {
+			report.IncompleteSeasons++
+		}
+	}
+	return report
+}
+
+// 季中已播出的集
+func airedEpisodes(detail *tmdb.TvDetail, season tmdb.Season, seasonDetail *tmdb.SeasonDetail, today string) []tmdb.SeasonEpisode {
+	episodes := make([]tmdb.SeasonEpisode, 0)
+	if seasonDetail != nil && len(seasonDetail.Episodes) > 0 {
+		for _, ep := range seasonDetail.Episodes {
+			if ep.AirDate != "" && ep.AirDate <= today {
+				episodes = append(episodes, ep)
+			}
+		}
+		return episodes
+	}
+	// 没有集列表，按最近播出的一集推算
+	count := season.EpisodeCount
+	if last := detail.LastEpisodeToAir; last != nil && season.SeasonNumber > 0 {
+		if season.SeasonNumber > last.SeasonNumber {
+			count = 0
+		} else if season.SeasonNumber == last.SeasonNumber {
+			count = min(count, last.EpisodeNumber)
+		}
+	} else if season.AirDate == "" || season.AirDate > today {
+		count = 0
+	}
+	for i := 1; i <= count; i++ {
+		episodes = append(episodes, tmdb.SeasonEpisode{SeasonNumber: season.SeasonNumber, EpisodeNumber: i})
+	}
+	return episodes
+}
+
+// 报告是否符合查询条件
+func (r *TvShowCompleteness) Match(filter *EpisodeReportFilter) bool {
+	if filter.AirStatus != "" && r.AirStatus != filter.AirStatus {
+		return false
+	}
+	if filter.Keyword != "" && !strings.Contains(strings.ToLower(r.Name), strings.ToLower(filter.Keyword)) {
+		return false
+	}
+	if filter.OnlyIncomplete {
+		missing := r.Missing
+		if filter.IncludeSpecials && r.Specials != nil {
+			missing += len(r.Specials.Missing)
+		}
+		if missing == 0 && r.Error == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// 已有剧集的集，来自已刮削或已整理的刮削记录
+type ownedEpisode struct {
+	TmdbId        int64
+	Name          string
+	Year          int
+	SeasonNumber  int
+	EpisodeNumber int
+}
+
+// 生成所有剧集的缺集报告
+func BuildEpisodeReport(ctx context.Context) ([]*TvShowCompleteness, error) {
+	var rows []*ownedEpisode
+	err := db.Db.Model(&ScrapeMediaFile{}).
+		Select("tmdb_id, name, year, season_number, episode_number").
+		Where("media_type = ? AND tmdb_id > 0 AND status IN ?", MediaTypeTvShow, []ScrapeMediaStatus{ScrapeMediaStatusScraped, ScrapeMediaStatusRenamed}).
+		Order("tmdb_id ASC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	shows := make([]*ownedEpisode, 0)
+	owned := make(map[int64]map[int]map[int]bool)
+	for _, row := range rows {
+		if _, ok := owned[row.TmdbId]; !ok {
+			owned[row.TmdbId] = make(map[int]map[int]bool)
+			shows = append(shows, row)
+		}
+		if _, ok := owned[row.TmdbId][row.SeasonNumber]; !ok {
+			owned[row.TmdbId][row.SeasonNumber] = make(map[int]bool)
+		}
+		owned[row.TmdbId][row.SeasonNumber][row.EpisodeNumber] = true
+	}
+	client := GlobalScrapeSettings.GetTmdbClient()
+	language := GlobalScrapeSettings.GetTmdbLanguage()
+	today := time.Now().Format("2006-01-02")
+	reports := make([]*TvShowCompleteness, 0, len(shows))
+	for _, show := range shows {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		detail, err := client.GetTvDetail(show.TmdbId, language)
+		if err != nil {
+			reports = append(reports, &TvShowCompleteness{TmdbId: show.TmdbId, Name: show.Name, Year: show.Year, Error: err.Error()})
+			continue
+		}
+		seasons := make(map[int]*tmdb.SeasonDetail)
+		for _, season := range detail.Seasons {
+			seasonDetail, err := client.GetTvSeasonDetail(show.TmdbId, season.SeasonNumber, language)
+			if err != nil {
+				helpers.AppLogger.Warnf("获取剧集 %s 第 %d 季的集列表失败，按集数推算: %v", show.Name, season.SeasonNumber, err)
+				continue
+			}
+			seasons[season.SeasonNumber] = seasonDetail
+		}
+		report := ComputeTvShowCompleteness(detail, seasons, owned[show.TmdbId], today)
+		if report.PosterPath != "" {
+			report.PosterPath = fmt.Sprintf("%s/t/p/original%s", GlobalScrapeSettings.GetTmdbImageUrl(), report.PosterPath)
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// 缺集报告快照，生成比较慢（每部剧都要查询TMDB），在后台生成后缓存
+type episodeReportSnapshot struct {
+	mutex     sync.Mutex
+	building  bool
+	buildTime int64
+	items     []*TvShowCompleteness
+	err       string
+}
+
+var episodeReport = &episodeReportSnapshot{}
+
+// 在后台重新生成缺集报告，完成后检查是否需要发送缺集通知
+func StartBuildEpisodeReport() error {
+	episodeReport.mutex.Lock()
+	if episodeReport.building {
+		episodeReport.mutex.Unlock()
+		return errors.New("缺集报告正在生成")
+	}
+	episodeReport.building = true
+	episodeReport.mutex.Unlock()
+	go func() {
+		items, err := BuildEpisodeReport(context.Background())
+		episodeReport.mutex.Lock()
+		episodeReport.building = false
+		if err != nil {
+			episodeReport.err = err.Error()
+			episodeReport.mutex.Unlock()
+			helpers.AppLogger.Errorf("生成缺集报告失败: %v", err)
+			return
+		}
+		episodeReport.items = items
+		episodeReport.err = ""
+		episodeReport.buildTime = time.Now().Unix()
+		episodeReport.mutex.Unlock()
+		helpers.AppLogger.Infof("缺集报告生成完成，共 %d 部剧集", len(items))
+		NotifyMissingEpisodes(items)
+	}()
+	return nil
+}
+
+// 查询缺集报告，返回符合条件的剧集和报告状态
+func GetEpisodeReport(filter *EpisodeReportFilter, page, pageSize int) ([]*TvShowCompleteness, int, map[string]any) {
+	episodeReport.mutex.Lock()
+	defer episodeReport.mutex.Unlock()
+	matched := make([]*TvShowCompleteness, 0)
+	for _, item := range episodeReport.items {
+		if item.Match(filter) {
+			matched = append(matched, item)
+		}
+	}
+	status := map[string]any{
+		"building":   episodeReport.building,
+		"build_time": episodeReport.buildTime,
+		"error":      episodeReport.err,
+	}
+	total := len(matched)
+	start := min((page-1)*pageSize, total)
+	end := min(start+pageSize, total)
+	return matched[start:end], total, status
+}
+
+// 新播出的集超过设置的天数仍然缺失时发送通知，每部剧一条通知，每一集只通知一次
+func NotifyMissingEpisodes(items []*TvShowCompleteness) {
+	days := GlobalScrapeSettings.MissingNotifyDays
+	manager := notificationmanager.GlobalEnhancedNotificationManager
+	if days <= 0 || manager == nil {
+		return
+	}
+	now := time.Now()
+	latest := now.AddDate(0, 0, -days).Format("2006-01-02")
+	earliest := now.AddDate(0, 0, -(days + missingNotifyWindowDays)).Format("2006-01-02")
+	for _, item := range items {
+		seasons := item.Seasons
+		if item.Specials != nil {
+			seasons = append(slices.Clone(seasons), item.Specials)
+		}
+		episodes := make([]string, 0)
+		notices := make([]*MissingEpisodeNotice, 0)
+		for _, season := range seasons {
+			for _, ep := range season.Missing {
+				if ep.AirDate == "" || ep.AirDate > latest || ep.AirDate < earliest {
+					continue
+				}
+				notice := &MissingEpisodeNotice{TmdbId: item.TmdbId, SeasonNumber: ep.SeasonNumber, EpisodeNumber: ep.EpisodeNumber}
+				var count int64
+				db.Db.Model(&MissingEpisodeNotice{}).Where("tmdb_id = ? AND season_number = ? AND episode_number = ?", notice.TmdbId, notice.SeasonNumber, notice.EpisodeNumber).Count(&count)
+				if count > 0 {
+					continue
+				}
+				notices = append(notices, notice)
+				episodes = append(episodes, fmt.Sprintf("S%02dE%02d %s（%s播出）", ep.SeasonNumber, ep.EpisodeNumber, ep.Name, ep.AirDate))
+			}
+		}
+		if len(episodes) == 0 {
+			continue
+		}
+		notif := &Notification{
+			Type:      EpisodeMissing,
+			Title:     fmt.Sprintf("📺 %s 有 %d 集播出超过 %d 天仍未入库", item.Name, len(episodes), days),
+			Content:   strings.Join(episodes, "\n"),
+			Image:     item.PosterPath,
+			Timestamp: now,
+			Priority:  NormalPriority,
+		}
+		if err := manager.SendNotification(context.Background(), notif); err != nil {
+			// 发送失败不记录，下次生成报告时再通知
+			helpers.AppLogger.Errorf("发送缺集通知失败: %v", err)
+			continue
+		}
+		if err := db.Db.Create(&notices).Error; err != nil {
+			helpers.AppLogger.Errorf("记录缺集通知失败: %v", err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"Q115-STRM/internal/tmdb"
+	"testing"
+)
+
+func TestComputeTvShowCompleteness(t *testing.T) {
+	detail := &tmdb.TvDetail{
+		SearchTv: tmdb.SearchTv{ID: 1399, Name: "权力的游戏", FirstAirDate: "2011-04-17"},
+		Status:   "Returning Series",
+		Seasons: []tmdb.Season{
+			{SeasonNumber: 0, EpisodeCount: 2, AirDate: "2010-12-05"},
+			{SeasonNumber: 1, EpisodeCount: 3, AirDate: "2011-04-17"},
+			{SeasonNumber: 2, EpisodeCount: 4, AirDate: "2012-04-01"},
+		},
+		LastEpisodeToAir: &tmdb.SeasonEpisode{SeasonNumber: 2, EpisodeNumber: 2},
+	}
+	seasons := map[int]*tmdb.SeasonDetail{
+		1: {Episodes: []tmdb.SeasonEpisode{
+			{EpisodeNumber: 1, AirDate: "2011-04-17"},
+			{EpisodeNumber: 2, AirDate: "2011-04-24"},
+			{EpisodeNumber: 3, AirDate: "2011-05-01"},
+		}},
+		// 第2季没有集列表，按最近播出的一集推算
+	}
+	owned := map[int]map[int]bool{
+		1: {1: true, 3: true},
+		2: {1: true, 2: true},
+	}
+	report := ComputeTvShowCompleteness(detail, seasons, owned, "2012-04-10")
+	if report.Year != 2011 || report.AirStatus != TvShowAirStatusContinuing {
+		t.Errorf("年份或播出状态错误: %d %s", report.Year, report.AirStatus)
+	}
+	if report.Aired != 5 || report.Owned != 4 || report.Missing != 1 || report.IncompleteSeasons != 1 {
+		t.Errorf("统计错误: 已播出 %d 已有 %d 缺失 %d 不完整的季 %d", report.Aired, report.Owned, report.Missing, report.IncompleteSeasons)
+	}
+	if len(report.Seasons) != 2 || report.Seasons[0].Complete || !report.Seasons[1].Complete {
+		t.Fatalf("季完整度错误")
+	}
+	if missing := report.Seasons[0].Missing; len(missing) != 1 || missing[0].EpisodeNumber != 2 || missing[0].AirDate != "2011-04-24" {
+		t.Errorf("第1季应该缺第2集")
+	}
+	if report.Specials == nil || report.Specials.Aired != 2 || len(report.Specials.Missing) != 2 {
+		t.Errorf("特别篇统计错误")
+	}
+
+	if !report.Match(&EpisodeReportFilter{AirStatus: TvShowAirStatusContinuing, OnlyIncomplete: true}) {
+		t.Errorf("连载中且有缺集的剧集应该匹配")
+	}
+	if report.Match(&EpisodeReportFilter{AirStatus: TvShowAirStatusEnded}) {
+		t.Errorf("连载中的剧集不应该匹配已完结")
+	}
+}
@@ -54,6 +54,8 @@ type Media struct {
 	NumberOfSeasons     int                `json:"number_of_seasons"`                        // 季数
 	Num                 string             `json:"num"`                                      // 番号
 	MpaaRating          string             `json:"mpaa_rating"`                              // MPAA分级
+	CollectionId        int64              `json:"collection_id"`                            // TMDB合集ID，只有电影有
+	CollectionName      string             `json:"collection_name"`                          // TMDB合集名称
 	Path                string             `json:"path"`                                     // 刮削整理后的电影或者电视剧的路径
 	PathId              string             `json:"path_id"`                                  // 刮削整理后的电影或者电视剧的路径ID
 	VideoFileName       string             `json:"video_file_name"`                          // 刮削整理后的电影或者电视剧的视频文件名
@@ -225,6 +227,10 @@ func (m *Media) FillInfoByTmdbInfo(tmdbInfo *TmdbInfo) {
 		m.VoteCount = tmdbInfo.MovieDetail.VoteCount
 		m.OriginalLanguage = tmdbInfo.MovieDetail.OriginalLanguage
 		m.ImdbId = tmdbInfo.MovieDetail.ImdbID
+		if tmdbInfo.MovieDetail.BelongsToCollection != nil {
+			m.CollectionId = tmdbInfo.MovieDetail.BelongsToCollection.ID
+			m.CollectionName = tmdbInfo.MovieDetail.BelongsToCollection.Name
+		}
 		// 提取分级信息
 		for _, releaseDate := range tmdbInfo.ReleasesDate {
 			if releaseDate.ISO_3166_1 == "US" {
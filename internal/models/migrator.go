@@ -18,7 +18,7 @@ type Migrator struct {
 	VersionCode int `json:"version_code"` // 版本号
 }
 
-var MaxVersionCode = 38
+var MaxVersionCode = 61
 var AllTables = []any{
 	BackupConfig{}, BackupRecord{},
 	ApiKey{}, Settings{}, Sync{}, User{}, Account{},
@@ -28,6 +28,10 @@ var AllTables = []any{
 	RequestStat{}, EmbyConfig{}, EmbyMediaItem{}, EmbyMediaSyncFile{}, EmbyLibrary{}, EmbyLibrarySyncPath{},
 	DbDownloadTask{}, DbUploadTask{}, NotificationChannel{}, TelegramChannelConfig{}, MeoWChannelConfig{}, BarkChannelConfig{},
 	ServerChanChannelConfig{}, CustomWebhookChannelConfig{}, NotificationRule{},
+	WeComChannelConfig{}, DingTalkChannelConfig{}, FeishuChannelConfig{}, GotifyChannelConfig{}, NtfyChannelConfig{}, DiscordChannelConfig{}, EmailChannelConfig{},
+	TmdbCache{}, RenameJournal{}, DuplicateGroup{}, DuplicateFile{}, MissingEpisodeNotice{}, ScrapeExtraFile{}, ScrapeLink{}, ScrapeScanDir{}, NotificationLog{}, NotificationDigestItem{},
+	TelegramUser{}, TelegramCommandLog{},
+	WebhookSubscription{}, WebhookDelivery{},
 }
 
 func (*Migrator) TableName() string {
@@ -462,6 +466,153 @@ func Migrate() {
 		helpers.AppLogger.Info("已添加enable_playback_overview和enable_playback_progress字段到emby_config表")
 		migrator.UpdateVersionCode(db.Db)
 	}
+	if migrator.VersionCode == 38 {
+		// 添加多版本处理策略字段到刮削目录表，添加多版本标签字段到刮削文件表
+		db.Db.AutoMigrate(ScrapePath{}, ScrapeMediaFile{})
+		helpers.AppLogger.Info("已添加multi_version_policy、multi_version_hold_path、version_label、is_version_held字段")
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 39 {
+		// 添加字幕设置字段到刮削设置表，添加下载字幕开关到刮削目录表
+		db.Db.AutoMigrate(ScrapeSettings{}, ScrapePath{})
+		helpers.AppLogger.Info("已添加字幕设置字段到scrape_settings表和enable_subtitle字段到scrape_path表")
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 40 {
+		// 添加识别置信度字段到刮削记录表，添加待确认阈值到刮削目录表
+		db.Db.AutoMigrate(ScrapeMediaFile{}, ScrapePath{})
+		helpers.AppLogger.Info("已添加confidence、candidates_json字段到scrape_media_file表和review_confidence字段到scrape_path表")
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 41 {
+		// 创建TMDB缓存表，添加离线模式字段到刮削设置表
+		db.Db.AutoMigrate(TmdbCache{}, ScrapeSettings{})
+		helpers.AppLogger.Info("已创建tmdb_cache表，已添加tmdb_offline字段到scrape_settings表")
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 42 {
+		// 添加覆盖上传字段到上传任务表
+		db.Db.AutoMigrate(DbUploadTask{})
+		helpers.AppLogger.Info("已添加overwrite字段到db_upload_tasks表")
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 43 {
+		// 创建整理操作日志表
+		db.Db.AutoMigrate(RenameJournal{})
+		helpers.AppLogger.Info("已创建rename_journals表")
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 44 {
+		// 添加目标账号和目标类型字段到刮削目录表，添加跨存储整理字段到上传任务表
+		db.Db.AutoMigrate(ScrapePath{}, DbUploadTask{})
+		helpers.AppLogger.Info("已添加dest_account_id、dest_source_type字段到scrape_path表和transfer_*字段到db_upload_tasks表")
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 45 {
+		// 创建重复文件表
+		db.Db.AutoMigrate(DuplicateGroup{}, DuplicateFile{})
+		helpers.AppLogger.Info("已创建duplicate_groups、duplicate_files表")
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 46 {
+		// 添加缺集通知天数字段到刮削设置表，创建缺集通知记录表，为已有渠道添加缺集通知规则
+		db.Db.AutoMigrate(ScrapeSettings{}, MissingEpisodeNotice{})
+		addNotificationRulesForExistingChannels(db.Db, notification.EpisodeMissing)
+		helpers.AppLogger.Info("已添加missing_notify_days字段到scrape_settings表，已创建missing_episode_notices表")
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 47 {
+		// 创建附加内容表
+		db.Db.AutoMigrate(ScrapeExtraFile{})
+		helpers.AppLogger.Info("已创建scrape_extra_files表")
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 48 {
+		// 添加原盘字段到刮削记录表
+		db.Db.AutoMigrate(ScrapeMediaFile{})
+		helpers.AppLogger.Info("已添加disc_type、disc_path、disc_path_id、disc_dir_id字段到scrape_media_files表")
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 49 {
+		// 创建镜像整理链接表
+		db.Db.AutoMigrate(ScrapeLink{})
+		helpers.AppLogger.Info("已创建scrape_links表")
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 50 {
+		// 增量扫描
+		db.Db.AutoMigrate(ScrapePath{}, ScrapeScanDir{})
+		helpers.AppLogger.Info("已添加incremental_scan字段到scrape_paths表，已创建scrape_scan_dirs表")
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 51 {
+		// 命名预设和TMDB合集
+		db.Db.AutoMigrate(ScrapePath{}, Media{})
+		helpers.AppLogger.Info("已添加naming_preset字段到scrape_paths表，已添加collection_id、collection_name字段到media表")
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 52 {
+		// 通知发送队列
+		db.Db.AutoMigrate(NotificationLog{})
+		helpers.AppLogger.Info("已创建notification_log表")
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 53 {
+		// 增加企业微信、钉钉、飞书、Gotify、ntfy、Discord、邮件通知渠道表
+		db.Db.AutoMigrate(
+			&WeComChannelConfig{},
+			&DingTalkChannelConfig{},
+			&FeishuChannelConfig{},
+			&GotifyChannelConfig{},
+			&NtfyChannelConfig{},
+			&DiscordChannelConfig{},
+			&EmailChannelConfig{},
+		)
+		helpers.AppLogger.Info("已创建企业微信、钉钉、飞书、Gotify、ntfy、Discord、邮件通知渠道表")
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 54 {
+		// 通知规则增加过滤条件和标题、内容模板
+		db.Db.AutoMigrate(&NotificationRule{})
+		helpers.AppLogger.Info("通知规则已增加过滤条件和模板字段")
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 55 {
+		// 通知规则增加汇总模式，增加待汇总通知表
+		db.Db.AutoMigrate(&NotificationRule{}, &NotificationDigestItem{})
+		helpers.AppLogger.Info("已增加通知汇总")
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 56 {
+		// Telegram机器人增加用户权限和命令记录
+		db.Db.AutoMigrate(&TelegramUser{}, &TelegramCommandLog{})
+		helpers.AppLogger.Info("已增加Telegram机器人用户和命令记录表")
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 57 {
+		// 增加自动化Webhook订阅和投递记录
+		db.Db.AutoMigrate(&WebhookSubscription{}, &WebhookDelivery{})
+		helpers.AppLogger.Info("已增加Webhook订阅和投递记录表")
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 58 {
+		// 上传任务增加刮削批次号，刮削上传的元数据记录到整理操作日志
+		db.Db.AutoMigrate(DbUploadTask{})
+		helpers.AppLogger.Info("上传任务已增加刮削批次号")
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 59 {
+		// 通知发送记录增加临时图片，所有渠道发送完成后删除
+		db.Db.AutoMigrate(NotificationLog{})
+		helpers.AppLogger.Info("通知发送记录已增加临时图片字段")
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 60 {
+		// 通知规则增加刮削分类过滤，和Emby媒体库过滤分开
+		db.Db.AutoMigrate(&NotificationRule{})
+		helpers.AppLogger.Info("通知规则已增加刮削分类过滤字段")
+		migrator.UpdateVersionCode(db.Db)
+	}
 	helpers.AppLogger.Infof("当前数据库版本 %d", migrator.VersionCode)
 }
 
@@ -822,12 +973,11 @@ func migrateExistingNotificationSettings(dbConn *gorm.DB) {
 // addPlaybackNotificationRulesForExistingChannels 为已有渠道添加新的播放通知类型规则
 func addPlaybackNotificationRulesForExistingChannels(dbConn *gorm.DB) {
 	// 新增的播放通知类型
-	newPlaybackTypes := []notification.NotificationType{
-		notification.PlaybackStart,
-		notification.PlaybackPause,
-		notification.PlaybackStop,
-	}
+	addNotificationRulesForExistingChannels(dbConn, notification.PlaybackStart, notification.PlaybackPause, notification.PlaybackStop)
+}
 
+// 为已有渠道添加新的通知类型规则
+func addNotificationRulesForExistingChannels(dbConn *gorm.DB, newTypes ...notification.NotificationType) {
 	// 获取所有已有的通知渠道
 	var channels []NotificationChannel
 	if err := dbConn.Find(&channels).Error; err != nil {
@@ -837,7 +987,7 @@ func addPlaybackNotificationRulesForExistingChannels(dbConn *gorm.DB) {
 
 	addedCount := 0
 	for _, channel := range channels {
-		for _, eventType := range newPlaybackTypes {
+		for _, eventType := range newTypes {
 			// 检查规则是否已存在
 			var existingRule NotificationRule
 			err := dbConn.Where("channel_id = ? AND event_type = ?", channel.ID, string(eventType)).
@@ -851,16 +1001,16 @@ func addPlaybackNotificationRulesForExistingChannels(dbConn *gorm.DB) {
 					IsEnabled: true,
 				}
 				if err := dbConn.Create(&newRule).Error; err != nil {
-					helpers.AppLogger.Errorf("为渠道 %d 添加播放通知规则失败：%v", channel.ID, err)
+					helpers.AppLogger.Errorf("为渠道 %d 添加通知规则失败：%v", channel.ID, err)
 				} else {
 					addedCount++
-					helpers.AppLogger.Infof("为渠道 %d（%s）添加播放通知规则：%s", channel.ID, channel.ChannelName, eventType)
+					helpers.AppLogger.Infof("为渠道 %d（%s）添加通知规则：%s", channel.ID, channel.ChannelName, eventType)
 				}
 			}
 		}
 	}
 
-	helpers.AppLogger.Infof("数据库迁移完成：已为 %d 个渠道添加新的播放通知类型规则", addedCount)
+	helpers.AppLogger.Infof("数据库迁移完成：已为 %d 个渠道添加新的通知类型规则", addedCount)
 }
 
 func fillSyncPathIdInEmbyMediaSyncFile(dbConn *gorm.DB) {
@@ -35,6 +35,7 @@ const (
 	PlaybackStart  NotificationType = notification.PlaybackStart
 	PlaybackPause  NotificationType = notification.PlaybackPause
 	PlaybackStop   NotificationType = notification.PlaybackStop
+	EpisodeMissing NotificationType = notification.EpisodeMissing
 )
 
 // NotificationPriority 通知优先级 - 从 internal/notification 导入
@@ -49,5 +50,41 @@ const (
 // Notification 统一通知对象 - 从 internal/notification 导入
 type Notification = notification.Notification
 
+// DigestData 汇总通知需要的数据 - 从 internal/notification 导入
+type DigestData = notification.DigestData
+
 // CustomWebhookChannelConfig 自定义Webhook渠道配置 - 别名供models包使用
 type CustomWebhookChannelConfig = notification.CustomWebhookChannelConfig
+
+// NotificationLog 通知发送记录 - 别名供models包使用
+type NotificationLog = notification.NotificationLog
+
+// NotificationDigestItem 等待汇总发送的通知 - 别名供models包使用
+type NotificationDigestItem = notification.NotificationDigestItem
+
+// WeComChannelConfig 企业微信渠道配置 - 别名供models包使用
+type WeComChannelConfig = notification.WeComChannelConfig
+
+// DingTalkChannelConfig 钉钉渠道配置 - 别名供models包使用
+type DingTalkChannelConfig = notification.DingTalkChannelConfig
+
+// FeishuChannelConfig 飞书渠道配置 - 别名供models包使用
+type FeishuChannelConfig = notification.FeishuChannelConfig
+
+// GotifyChannelConfig Gotify渠道配置 - 别名供models包使用
+type GotifyChannelConfig = notification.GotifyChannelConfig
+
+// NtfyChannelConfig ntfy渠道配置 - 别名供models包使用
+type NtfyChannelConfig = notification.NtfyChannelConfig
+
+// DiscordChannelConfig Discord渠道配置 - 别名供models包使用
+type DiscordChannelConfig = notification.DiscordChannelConfig
+
+// EmailChannelConfig 邮件渠道配置 - 别名供models包使用
+type EmailChannelConfig = notification.EmailChannelConfig
+
+// TelegramUser 允许控制Telegram机器人的用户 - 别名供models包使用
+type TelegramUser = notification.TelegramUser
+
+// TelegramCommandLog Telegram机器人命令记录 - 别名供models包使用
+type TelegramCommandLog = notification.TelegramCommandLog
@@ -0,0 +1,180 @@
+package models
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"fmt"
+	"time"
+)
+
+type RenameJournalOp string
+
+const (
+	RenameJournalOpMove     RenameJournalOp = "move"     // 移动（可能同时改名）
+	RenameJournalOpRename   RenameJournalOp = "rename"   // 改名
+	RenameJournalOpCopy     RenameJournalOp = "copy"     // 复制
+	RenameJournalOpHardLink RenameJournalOp = "hardlink" // 硬链接
+	RenameJournalOpSymlink  RenameJournalOp = "symlink"  // 软链接
+	RenameJournalOpMkdir    RenameJournalOp = "mkdir"    // 创建目录
+	RenameJournalOpDelete   RenameJournalOp = "delete"   // 删除
+	RenameJournalOpUpload   RenameJournalOp = "upload"   // 通过上传队列上传的nfo、图片等元数据
+)
+
+type RenameJournalStatus string
+
+const (
+	RenameJournalStatusDone         RenameJournalStatus = "done"         // 已执行
+	RenameJournalStatusReverted     RenameJournalStatus = "reverted"     // 已撤销
+	RenameJournalStatusConflict     RenameJournalStatus = "conflict"     // 目标已变化，无法撤销
+	RenameJournalStatusFailed       RenameJournalStatus = "failed"       // 撤销失败
+	RenameJournalStatusIrreversible RenameJournalStatus = "irreversible" // 删除操作无法撤销
+)
+
+// 整理操作日志，记录rename.*执行的每一次文件操作，按刮削批次分组，用来批量撤销
+// 115的PathId和FileId是网盘ID，其他来源都是完整路径
+type RenameJournal struct {
+	BaseModel
+	RunNo             string              `json:"run_no" gorm:"index"`               // 刮削批次号，每次启动刮削生成一个
+	ScrapePathId      uint                `json:"scrape_path_id" gorm:"index"`       // 刮削目录ID
+	ScrapeMediaFileId uint                `json:"scrape_media_file_id" gorm:"index"` // 刮削记录ID，和媒体文件无关的操作（如创建目录）为0
+	SourceType        SourceType          `json:"source_type"`                       // 来源类型
+	Op                RenameJournalOp     `json:"op"`                                // 操作类型
+	SourcePath        string              `json:"source_path"`                       // 操作前所在目录
+	SourcePathId      string              `json:"source_path_id"`                    // 操作前所在目录ID
+	SourceName        string              `json:"source_name"`                       // 操作前的文件名
+	DestPath          string              `json:"dest_path"`                         // 操作后所在目录，创建目录时为父目录
+	DestPathId        string              `json:"dest_path_id"`                      // 操作后所在目录ID
+	DestName          string              `json:"dest_name"`                         // 操作后的文件名，创建目录时为目录名
+	FileId            string              `json:"file_id"`                           // 操作后的文件ID，115用来判断文件是否被替换
+	Status            RenameJournalStatus `json:"status" gorm:"index"`               // 状态
+	Error             string              `json:"error"`                             // 撤销失败或冲突的原因
+	RevertedAt        int64               `json:"reverted_at"`                       // 撤销时间
+}
+
+func (*RenameJournal) TableName() string {
+	return "rename_journals"
+}
+
+// 整理批次的汇总信息
+type RenameJournalRun struct {
+	RunNo        string `json:"run_no"`
+	ScrapePathId uint   `json:"scrape_path_id"`
+	Total        int64  `json:"total"`    // 操作数
+	Done         int64  `json:"done"`     // 未撤销的操作数
+	Reverted     int64  `json:"reverted"` // 已撤销的操作数
+	Conflict     int64  `json:"conflict"` // 冲突的操作数
+	Items        int64  `json:"items"`    // 涉及的刮削记录数
+	StartTime    int64  `json:"start_time"`
+	EndTime      int64  `json:"end_time"`
+}
+
+// 生成刮削批次号：纳秒级时间 + 刮削目录ID，多个刮削目录同时启动也不会重复，按时间排序
+func NewRenameJournalRunNo(scrapePathId uint) string {
+	return fmt.Sprintf("%s-%d", time.Now().Format("20060102150405.000000000"), scrapePathId)
+}
+
+func AddRenameJournal(j *RenameJournal) error {
+	if j.Status == "" {
+		j.Status = RenameJournalStatusDone
+		if j.Op == RenameJournalOpDelete {
+			j.Status = RenameJournalStatusIrreversible
+		}
+	}
+	if err := db.Db.Create(j).Error; err != nil {
+		helpers.AppLogger.Errorf("写入整理操作日志失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// 分页查询整理批次，最新的在前面
+func GetRenameJournalRuns(scrapePathId uint, page, pageSize int) ([]*RenameJournalRun, int64) {
+	runs := make([]*RenameJournalRun, 0)
+	var total int64
+	tx := db.Db.Model(&RenameJournal{})
+	if scrapePathId > 0 {
+		tx = tx.Where("scrape_path_id = ?", scrapePathId)
+	}
+	// 旧版本的批次号只精确到秒，同一批次号可能包含多个刮削目录，按批次号和刮削目录分组
+	db.Db.Table("(?) AS runs", tx.Select("run_no, scrape_path_id").Group("run_no, scrape_path_id")).Count(&total)
+	tx = db.Db.Model(&RenameJournal{})
+	if scrapePathId > 0 {
+		tx = tx.Where("scrape_path_id = ?", scrapePathId)
+	}
+	err := tx.Select("run_no, scrape_path_id, COUNT(*) AS total, "+
+		"SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS done, "+
+		"SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS reverted, "+
+		"SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS conflict, "+
+		"COUNT(DISTINCT NULLIF(scrape_media_file_id, 0)) AS items, "+
+		"MIN(created_at) AS start_time, MAX(created_at) AS end_time",
+		RenameJournalStatusDone, RenameJournalStatusReverted, RenameJournalStatusConflict).
+		Group("run_no, scrape_path_id").
+		Order("run_no DESC, scrape_path_id ASC").
+		Limit(pageSize).
+		Offset((page - 1) * pageSize).
+		Scan(&runs).Error
+	if err != nil {
+		helpers.AppLogger.Errorf("查询整理批次失败: %v", err)
+	}
+	return runs, total
+}
+
+// 查询批次下的操作日志，按执行顺序排列，scrapePathId为0时不限刮削目录
+func GetRenameJournalsByRunNo(runNo string, scrapePathId uint) []*RenameJournal {
+	var journals []*RenameJournal
+	tx := db.Db.Where("run_no = ?", runNo)
+	if scrapePathId > 0 {
+		tx = tx.Where("scrape_path_id = ?", scrapePathId)
+	}
+	if err := tx.Order("id ASC").Find(&journals).Error; err != nil {
+		helpers.AppLogger.Errorf("查询批次 %s 的整理操作日志失败: %v", runNo, err)
+	}
+	return journals
+}
+
+func (j *RenameJournal) UpdateStatus(status RenameJournalStatus, errMsg string) {
+	j.Status = status
+	j.Error = errMsg
+	updateData := map[string]interface{}{
+		"status": status,
+		"error":  errMsg,
+	}
+	if status == RenameJournalStatusReverted {
+		j.RevertedAt = time.Now().Unix()
+		updateData["reverted_at"] = j.RevertedAt
+	}
+	if err := db.Db.Model(&RenameJournal{}).Where("id = ?", j.ID).Updates(updateData).Error; err != nil {
+		helpers.AppLogger.Errorf("更新整理操作日志 %d 状态失败: %v", j.ID, err)
+	}
+}
+
+// 删除撤销完成的刮削记录和刮削时创建的媒体信息，下次扫描时重新识别
+// 电视剧的剧集信息只属于这个刮削记录，季和剧集信息没有其他刮削记录使用时一起删除
+func DeleteRevertedScrapeMediaFile(id uint) error {
+	sm := GetScrapeMediaFileById(id)
+	if sm == nil {
+		return nil
+	}
+	if err := db.Db.Delete(&ScrapeMediaFile{}, id).Error; err != nil {
+		return err
+	}
+	if sm.MediaEpisodeId > 0 {
+		db.Db.Delete(&MediaEpisode{}, sm.MediaEpisodeId)
+	}
+	var count int64
+	if sm.MediaSeasonId > 0 {
+		db.Db.Model(&ScrapeMediaFile{}).Where("media_season_id = ?", sm.MediaSeasonId).Count(&count)
+		if count == 0 {
+			db.Db.Delete(&MediaSeason{}, sm.MediaSeasonId)
+		}
+	}
+	if sm.MediaId > 0 {
+		db.Db.Model(&ScrapeMediaFile{}).Where("media_id = ?", sm.MediaId).Count(&count)
+		if count == 0 {
+			db.Db.Delete(&Media{}, sm.MediaId)
+			db.Db.Where("media_id = ?", sm.MediaId).Delete(&MediaSeason{})
+			db.Db.Where("media_id = ?", sm.MediaId).Delete(&MediaEpisode{})
+		}
+	}
+	return nil
+}
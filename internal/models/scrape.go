@@ -4,9 +4,11 @@ import (
 	"Q115-STRM/internal/db"
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/openai"
+	"Q115-STRM/internal/subtitle"
 	"Q115-STRM/internal/tmdb"
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 type AiAction string
@@ -27,12 +29,18 @@ type ScrapeSettings struct {
 	TmdbLanguage      string   `json:"tmdb_language" form:"tmdb_language"`             // TMDB 语言，默认值为"zh-CN"
 	TmdbImageLanguage string   `json:"tmdb_image_language" form:"tmdb_image_language"` // TMDB 图片语言，默认值为"en-US"
 	TmdbEnableProxy   bool     `json:"tmdb_enable_proxy" form:"tmdb_enable_proxy"`     // 是否启用TMDB代理
+	TmdbOffline       bool     `json:"tmdb_offline" form:"tmdb_offline"`               // TMDB离线模式，只使用本地缓存的数据，不请求TMDB，也不下载图片
 	EnableAi          AiAction `json:"enable_ai" form:"enable_ai"`                     // 是否启用AI识别
 	AiBaseUrl         string   `json:"ai_base_url" form:"ai_base_url"`                 // AI识别基础URL
 	AiApiKey          string   `json:"ai_api_key" form:"ai_api_key"`                   // AI识别API KEY
 	AiModelName       string   `json:"ai_model_name" form:"ai_model_name"`             // AI识别模型名称
 	AiPrompt          string   `json:"ai_prompt" form:"ai_prompt"`                     // AI识别提示词，如果留空则使用默认值
 	AiTimeout         int      `json:"ai_timeout" form:"ai_timeout"`                   // AI识别超时时间，单位秒，默认值为:120
+	SubtitleLanguages string   `json:"subtitle_languages" form:"subtitle_languages"`   // 字幕语言偏好，逗号分隔，按优先级排序，默认值为"zh-CN,zh-TW,en"
+	OpenSubtitlesKey  string   `json:"opensubtitles_key" form:"opensubtitles_key"`     // OpenSubtitles API KEY，为空则不使用OpenSubtitles
+	AssrtToken        string   `json:"assrt_token" form:"assrt_token"`                 // 射手网(伪) API Token，为空则不使用射手网
+	AssrtUrl          string   `json:"assrt_url" form:"assrt_url"`                     // 射手网(伪) API地址，可以使用兼容的服务，为空则使用默认值
+	MissingNotifyDays int      `json:"missing_notify_days" form:"missing_notify_days"` // 新播出的剧集超过多少天仍然缺失时发送通知，0表示不通知
 }
 
 const (
@@ -115,7 +123,23 @@ func (s *ScrapeSettings) GetTmdbProxyUrl() string {
 }
 
 func (s *ScrapeSettings) GetTmdbClient() *tmdb.Client {
-	return tmdb.NewClient(s.GetTmdbApiKey(), s.GetTmdbAccessToken(), s.GetTmdbApiUrl(), s.GetTmdbLanguage(), s.GetTmdbProxyUrl())
+	client := tmdb.NewClient(s.GetTmdbApiKey(), s.GetTmdbAccessToken(), s.GetTmdbApiUrl(), s.GetTmdbLanguage(), s.GetTmdbProxyUrl())
+	client.SetCache(TmdbDbCache{})
+	client.SetOffline(s.TmdbOffline)
+	return client
+}
+
+// 保存TMDB离线模式
+func (s *ScrapeSettings) SaveTmdbOffline(offline bool) error {
+	s.TmdbOffline = offline
+	if err := db.Db.Model(s).Where("id = ?", s.ID).Update("tmdb_offline", offline).Error; err != nil {
+		helpers.AppLogger.Errorf("更新TMDB离线模式失败: %v", err)
+		return err
+	}
+	// 立即生效，不用等下次创建客户端
+	s.GetTmdbClient()
+	helpers.AppLogger.Infof("TMDB离线模式已更新为 %v", offline)
+	return nil
 }
 
 // 保存tmdb设置
@@ -217,6 +241,62 @@ func (s *ScrapeSettings) SaveAi(apiKey string, baseUrl string, modelName string,
 	return nil
 }
 
+// 字幕语言偏好
+func (s *ScrapeSettings) GetSubtitleLanguages() []string {
+	languages := make([]string, 0)
+	for _, l := range strings.Split(s.SubtitleLanguages, ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			languages = append(languages, l)
+		}
+	}
+	if len(languages) == 0 {
+		return subtitle.DefaultLanguages
+	}
+	return languages
+}
+
+// 返回已配置的字幕提供者，按OpenSubtitles、射手网的顺序查询
+func (s *ScrapeSettings) GetSubtitleProviders() []subtitle.Provider {
+	providers := make([]subtitle.Provider, 0)
+	if s.OpenSubtitlesKey != "" {
+		providers = append(providers, subtitle.NewOpenSubtitles(s.OpenSubtitlesKey, "", SettingsGlobal.HttpProxy))
+	}
+	if s.AssrtToken != "" {
+		providers = append(providers, subtitle.NewAssrt(s.AssrtToken, s.AssrtUrl, SettingsGlobal.HttpProxy))
+	}
+	return providers
+}
+
+// 保存字幕设置
+func (s *ScrapeSettings) SaveSubtitle(languages string, openSubtitlesKey string, assrtToken string, assrtUrl string) error {
+	s.SubtitleLanguages = languages
+	s.OpenSubtitlesKey = openSubtitlesKey
+	s.AssrtToken = assrtToken
+	s.AssrtUrl = assrtUrl
+	updateData := make(map[string]interface{})
+	updateData["subtitle_languages"] = languages
+	updateData["open_subtitles_key"] = openSubtitlesKey
+	updateData["assrt_token"] = assrtToken
+	updateData["assrt_url"] = assrtUrl
+	err := db.Db.Model(s).Where("id = ?", s.ID).Updates(updateData).Error
+	if err != nil {
+		helpers.AppLogger.Errorf("更新字幕设置失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// 保存缺集通知设置
+func (s *ScrapeSettings) SaveMissingNotifyDays(days int) error {
+	s.MissingNotifyDays = days
+	err := db.Db.Model(s).Where("id = ?", s.ID).Update("missing_notify_days", days).Error
+	if err != nil {
+		helpers.AppLogger.Errorf("更新缺集通知设置失败: %v", err)
+		return err
+	}
+	return nil
+}
+
 // 测试TMDB是否配置正确
 func (s *ScrapeSettings) TestTmdb() bool {
 	client := s.GetTmdbClient()
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// 原盘类型
+type DiscType string
+
+const (
+	DiscTypeBluray DiscType = "bdmv" // 蓝光原盘目录，xxx/BDMV/STREAM/*.m2ts
+	DiscTypeIso    DiscType = "iso"  // ISO镜像文件
+)
+
+// 路径是否是蓝光原盘的视频流目录 xxx/BDMV/STREAM
+func IsBlurayStreamPath(path string) bool {
+	parts := strings.Split(strings.TrimSuffix(filepath.ToSlash(path), "/"), "/")
+	n := len(parts)
+	return n >= 2 && strings.EqualFold(parts[n-1], "STREAM") && strings.EqualFold(parts[n-2], "BDMV")
+}
+
+// 蓝光原盘视频流目录对应的原盘根目录，也就是BDMV的上一级目录
+func BlurayDiscRoot(streamPath string) string {
+	return filepath.Dir(filepath.Dir(streamPath))
+}
+
+// 是否是ISO镜像文件
+func IsIsoFile(fileName string) bool {
+	return strings.EqualFold(filepath.Ext(fileName), ".iso")
+}
+
+// 识别使用的文件名，原盘的视频流或者镜像文件名一般没有意义，使用原盘所在目录名识别
+func (sm *ScrapeMediaFile) IdentifyFileName() string {
+	if sm.DiscPath != "" {
+		return filepath.Base(sm.DiscPath)
+	}
+	return sm.VideoFilename
+}
+
+// 识别使用的文件夹名
+func (sm *ScrapeMediaFile) IdentifyFolderName() string {
+	if sm.DiscPath != "" {
+		return filepath.Base(sm.DiscPath)
+	}
+	return filepath.Base(sm.Path)
+}
+
+// 附加内容和原盘一起存放在原盘所在目录中
+func (sm *ScrapeMediaFile) ExtrasPath() string {
+	if sm.DiscType == DiscTypeBluray {
+		return sm.DiscPath
+	}
+	return sm.Path
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"Q115-STRM/internal/helpers"
+	"encoding/binary"
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 一张原盘只读取文件最大的这些播放列表，防盗版的原盘会有上百个迷惑用的播放列表
+const MaxBlurayPlaylists = 30
+
+// 蓝光原盘BDMV/PLAYLIST中的播放列表（mpls文件）
+type BlurayPlaylist struct {
+	Name     string
+	Duration time.Duration
+	Clips    map[string]time.Duration // 引用的视频流文件名（例如00800.m2ts）=> 在列表中的播放时长
+}
+
+// 播放列表文件，Id是读取文件时使用的ID（115为提取码，百度网盘为fs_id，其他为路径）
+type BlurayPlaylistFile struct {
+	Id   string
+	Name string
+	Size int64
+}
+
+// 原盘中的播放列表目录，也就是视频流目录同级的PLAYLIST目录
+func BlurayPlaylistPath(streamPath string) string {
+	return filepath.Join(filepath.Dir(streamPath), "PLAYLIST")
+}
+
+func IsBlurayPlaylistFile(fileName string) bool {
+	return strings.EqualFold(filepath.Ext(fileName), ".mpls")
+}
+
+// 解析mpls文件，只读取主路径的播放项
+// 时间单位是45kHz，多角度播放项只取第一个角度的视频流
+func ParseBlurayPlaylist(name string, data []byte) (*BlurayPlaylist, error) {
+	if len(data) < 20 || string(data[0:4]) != "MPLS" {
+		return nil, errors.New("不是有效的mpls文件")
+	}
+	start := int(binary.BigEndian.Uint32(data[8:12]))
+	if start+10 > len(data) {
+		return nil, errors.New("mpls文件不完整")
+	}
+	count := int(binary.BigEndian.Uint16(data[start+6 : start+8]))
+	playlist := &BlurayPlaylist{Name: name, Clips: make(map[string]time.Duration)}
+	offset := start + 10
+	for i := 0; i < count; i++ {
+		if offset+22 > len(data) {
+			return nil, errors.New("mpls文件不完整")
+		}
+		length := int(binary.BigEndian.Uint16(data[offset : offset+2]))
+		item := data[offset+2:]
+		clipName := string(item[0:5]) + ".m2ts"
+		inTime := binary.BigEndian.Uint32(item[12:16])
+		outTime := binary.BigEndian.Uint32(item[16:20])
+		if outTime > inTime {
+			duration := time.Duration(outTime-inTime) * time.Second / 45000
+			playlist.Clips[clipName] += duration
+			playlist.Duration += duration
+		}
+		offset += 2 + length
+	}
+	return playlist, nil
+}
+
+// 按文件大小取最多MaxBlurayPlaylists个播放列表读取并解析，读取或者解析失败的跳过
+func ReadBlurayPlaylists(files []*BlurayPlaylistFile, read func(file *BlurayPlaylistFile) ([]byte, error)) []*BlurayPlaylist {
+	sort.Slice(files, func(i, j int) bool { return files[i].Size > files[j].Size })
+	if len(files) > MaxBlurayPlaylists {
+		files = files[:MaxBlurayPlaylists]
+	}
+	playlists := make([]*BlurayPlaylist, 0, len(files))
+	for _, file := range files {
+		data, err := read(file)
+		if err != nil {
+			helpers.AppLogger.Warnf("读取蓝光原盘播放列表 %s 失败: %v", file.Name, err)
+			continue
+		}
+		playlist, err := ParseBlurayPlaylist(file.Name, data)
+		if err != nil {
+			helpers.AppLogger.Warnf("解析蓝光原盘播放列表 %s 失败: %v", file.Name, err)
+			continue
+		}
+		playlists = append(playlists, playlist)
+	}
+	return playlists
+}
+
+// 主影片流：最长的播放列表中播放时长最长的视频流，没有可用的播放列表时返回空
+func MainBlurayClip(playlists []*BlurayPlaylist) string {
+	var longest *BlurayPlaylist
+	for _, playlist := range playlists {
+		if longest == nil || playlist.Duration > longest.Duration {
+			longest = playlist
+		}
+	}
+	if longest == nil || longest.Duration == 0 {
+		return ""
+	}
+	clips := make([]string, 0, len(longest.Clips))
+	for clip := range longest.Clips {
+		clips = append(clips, clip)
+	}
+	// 时长相同时按文件名排序，保证结果稳定
+	sort.Slice(clips, func(i, j int) bool {
+		if longest.Clips[clips[i]] != longest.Clips[clips[j]] {
+			return longest.Clips[clips[i]] > longest.Clips[clips[j]]
+		}
+		return clips[i] < clips[j]
+	})
+	return clips[0]
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestIsBlurayStreamPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		expected bool
+	}{
+		{name: "网盘路径", path: "电影/阿凡达 (2009)/BDMV/STREAM", expected: true},
+		{name: "小写目录名", path: "/media/Avatar.2009/bdmv/stream/", expected: true},
+		{name: "原盘根目录", path: "电影/阿凡达 (2009)/BDMV", expected: false},
+		{name: "普通STREAM目录", path: "电影/STREAM", expected: false},
+		{name: "只有STREAM", path: "STREAM", expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := IsBlurayStreamPath(tt.path); actual != tt.expected {
+				t.Errorf("期望: %v, 实际: %v", tt.expected, actual)
+			}
+		})
+	}
+	if root := BlurayDiscRoot("电影/阿凡达 (2009)/BDMV/STREAM"); root != "电影/阿凡达 (2009)" {
+		t.Errorf("期望: 电影/阿凡达 (2009), 实际: %s", root)
+	}
+}
+
+func TestScrapeMediaFileIdentifyName(t *testing.T) {
+	disc := &ScrapeMediaFile{
+		Path:          "电影/阿凡达 (2009)/BDMV/STREAM",
+		VideoFilename: "00800.m2ts",
+		DiscType:      DiscTypeBluray,
+		DiscPath:      "电影/阿凡达 (2009)",
+	}
+	if disc.IdentifyFileName() != "阿凡达 (2009)" || disc.IdentifyFolderName() != "阿凡达 (2009)" || disc.ExtrasPath() != "电影/阿凡达 (2009)" {
+		t.Errorf("原盘识别名称错误: %s %s %s", disc.IdentifyFileName(), disc.IdentifyFolderName(), disc.ExtrasPath())
+	}
+	// 在来源根目录的ISO没有原盘目录，使用文件名识别
+	iso := &ScrapeMediaFile{Path: "电影", VideoFilename: "阿凡达.2009.iso", DiscType: DiscTypeIso}
+	if iso.IdentifyFileName() != "阿凡达.2009.iso" || iso.IdentifyFolderName() != "电影" || iso.ExtrasPath() != "电影" {
+		t.Errorf("ISO识别名称错误: %s %s %s", iso.IdentifyFileName(), iso.IdentifyFolderName(), iso.ExtrasPath())
+	}
+}
+
+// 构造一个mpls文件，每个播放项为 视频流编号 和 时长（秒）
+func makeTestMpls(items ...any) []byte {
+	data := []byte("MPLS0200")
+	data = binary.BigEndian.AppendUint32(data, 20)
+	data = append(data, make([]byte, 8)...)
+	// PlayList：长度、保留、播放项数量、子路径数量
+	data = binary.BigEndian.AppendUint32(data, 0)
+	data = append(data, 0, 0)
+	data = binary.BigEndian.AppendUint16(data, uint16(len(items)/2))
+	data = binary.BigEndian.AppendUint16(data, 0)
+	for i := 0; i < len(items); i += 2 {
+		data = binary.BigEndian.AppendUint16(data, 20)
+		data = append(data, []byte(items[i].(string))...)
+		data = append(data, []byte("M2TS")...)
+		data = append(data, 0, 1, 0)
+		data = binary.BigEndian.AppendUint32(data, 45000)
+		data = binary.BigEndian.AppendUint32(data, uint32(45000+items[i+1].(int)*45000))
+	}
+	return data
+}
+
+func TestMainBlurayClip(t *testing.T) {
+	main, err := ParseBlurayPlaylist("00800.mpls", makeTestMpls("00055", 30, "00056", 7000, "00057", 60))
+	if err != nil {
+		t.Fatalf("解析mpls失败: %v", err)
+	}
+	if main.Duration != 7090*time.Second || main.Clips["00056.m2ts"] != 7000*time.Second {
+		t.Errorf("播放列表时长错误: %v %v", main.Duration, main.Clips)
+	}
+	// 花絮列表只有一个视频流，文件比主影片的视频流片段大也不应该被选中
+	extra, _ := ParseBlurayPlaylist("00001.mpls", makeTestMpls("00001", 1800))
+	if clip := MainBlurayClip([]*BlurayPlaylist{extra, main}); clip != "00056.m2ts" {
+		t.Errorf("期望: 00056.m2ts, 实际: %s", clip)
+	}
+	if clip := MainBlurayClip(nil); clip != "" {
+		t.Errorf("没有播放列表时期望为空, 实际: %s", clip)
+	}
+	if _, err := ParseBlurayPlaylist("bad.mpls", []byte("MPLS")); err == nil {
+		t.Errorf("不完整的mpls文件应该返回错误")
+	}
+}
@@ -0,0 +1,276 @@
+package models
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 附加内容类型，和Emby/Jellyfin的文件名后缀一致
+type ExtraType string
+
+const (
+	ExtraTypeTrailer         ExtraType = "trailer"         // 预告片
+	ExtraTypeSample          ExtraType = "sample"          // 样片
+	ExtraTypeFeaturette      ExtraType = "featurette"      // 花絮
+	ExtraTypeBehindTheScenes ExtraType = "behindthescenes" // 幕后
+	ExtraTypeDeletedScene    ExtraType = "deleted"         // 删减片段
+	ExtraTypeInterview       ExtraType = "interview"       // 访谈
+	ExtraTypeScene           ExtraType = "scene"           // 片段
+	ExtraTypeShort           ExtraType = "short"           // 短片
+	ExtraTypeExtra           ExtraType = "extra"           // 其他附加内容
+)
+
+// 整理后存放的子目录，Emby和Jellyfin都能识别
+var extraTypeFolders = map[ExtraType]string{
+	ExtraTypeTrailer:         "trailers",
+	ExtraTypeSample:          "samples",
+	ExtraTypeFeaturette:      "featurettes",
+	ExtraTypeBehindTheScenes: "behind the scenes",
+	ExtraTypeDeletedScene:    "deleted scenes",
+	ExtraTypeInterview:       "interviews",
+	ExtraTypeScene:           "scenes",
+	ExtraTypeShort:           "shorts",
+	ExtraTypeExtra:           "extras",
+}
+
+// 来源目录名（小写）对应的附加内容类型
+var extraDirTypes = map[string]ExtraType{
+	"trailers":          ExtraTypeTrailer,
+	"trailer":           ExtraTypeTrailer,
+	"预告片":               ExtraTypeTrailer,
+	"samples":           ExtraTypeSample,
+	"sample":            ExtraTypeSample,
+	"featurettes":       ExtraTypeFeaturette,
+	"featurette":        ExtraTypeFeaturette,
+	"花絮":                ExtraTypeFeaturette,
+	"behind the scenes": ExtraTypeBehindTheScenes,
+	"behindthescenes":   ExtraTypeBehindTheScenes,
+	"幕后":                ExtraTypeBehindTheScenes,
+	"deleted scenes":    ExtraTypeDeletedScene,
+	"deletedscenes":     ExtraTypeDeletedScene,
+	"删减片段":              ExtraTypeDeletedScene,
+	"interviews":        ExtraTypeInterview,
+	"scenes":            ExtraTypeScene,
+	"shorts":            ExtraTypeShort,
+	"extras":            ExtraTypeExtra,
+	"extra":             ExtraTypeExtra,
+	"other":             ExtraTypeExtra,
+	"others":            ExtraTypeExtra,
+	"特典":                ExtraTypeExtra,
+}
+
+// 文件名后缀对应的附加内容类型，顺序决定匹配优先级
+var extraSuffixTypes = []struct {
+	suffix    string
+	extraType ExtraType
+	anySep    bool // 是否允许 . _ 空格作为分隔符以及文件名就是类型名，其他只允许 -
+}{
+	{"behindthescenes", ExtraTypeBehindTheScenes, false},
+	{"deletedscene", ExtraTypeDeletedScene, false},
+	{"deleted", ExtraTypeDeletedScene, false},
+	{"featurette", ExtraTypeFeaturette, false},
+	{"interview", ExtraTypeInterview, false},
+	{"trailer", ExtraTypeTrailer, true},
+	{"sample", ExtraTypeSample, true},
+	{"scene", ExtraTypeScene, false},
+	{"short", ExtraTypeShort, false},
+	{"other", ExtraTypeExtra, false},
+	{"extra", ExtraTypeExtra, false},
+}
+
+func (t ExtraType) FolderName() string {
+	if folder, ok := extraTypeFolders[t]; ok {
+		return folder
+	}
+	return extraTypeFolders[ExtraTypeExtra]
+}
+
+// 目录名是否是附加内容目录
+func extraDirType(dirName string) (ExtraType, bool) {
+	extraType, ok := extraDirTypes[strings.ToLower(strings.TrimSpace(dirName))]
+	return extraType, ok
+}
+
+// 按所在目录名和文件名判断视频是否是预告片、花絮等附加内容
+// 返回附加内容类型和所属视频的文件名（不含扩展名），按目录归类或者文件名就是类型名时所属视频为空
+func ClassifyExtra(dirName, fileName string) (ExtraType, string) {
+	if extraType, ok := extraDirType(dirName); ok {
+		return extraType, ""
+	}
+	baseName := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	lowerName := strings.ToLower(baseName)
+	for _, item := range extraSuffixTypes {
+		if item.anySep && lowerName == item.suffix {
+			return item.extraType, ""
+		}
+		seps := []string{"-"}
+		if item.anySep {
+			seps = append(seps, ".", "_", " ")
+		}
+		for _, sep := range seps {
+			if strings.HasSuffix(lowerName, sep+item.suffix) {
+				mediaBaseName := strings.TrimSpace(baseName[:len(baseName)-len(sep+item.suffix)])
+				return item.extraType, mediaBaseName
+			}
+		}
+	}
+	return "", ""
+}
+
+type ScrapeExtraStatus string
+
+const (
+	ScrapeExtraStatusPending ScrapeExtraStatus = "pending" // 等待所属视频整理
+	ScrapeExtraStatusRenamed ScrapeExtraStatus = "renamed" // 已整理到附加内容目录
+	ScrapeExtraStatusFailed  ScrapeExtraStatus = "failed"  // 整理失败
+)
+
+// 刮削目录中的预告片、花絮等附加内容，不单独刮削，跟随所属视频整理
+type ScrapeExtraFile struct {
+	BaseModel
+	ScrapePathId  uint              `json:"scrape_path_id" gorm:"index"`
+	ExtraType     ExtraType         `json:"extra_type"`
+	FileName      string            `json:"file_name"`
+	FileId        string            `json:"file_id" gorm:"index"`
+	PickCode      string            `json:"pick_code"`
+	Path          string            `json:"path"`                          // 所在目录
+	PathId        string            `json:"path_id"`                       // 所在目录ID
+	MediaPath     string            `json:"media_path" gorm:"index"`       // 所属视频所在的目录，按目录归类的是上一级目录
+	MediaBaseName string            `json:"media_base_name"`               // 所属视频的文件名（不含扩展名），为空时属于目录中的视频
+	MediaFileId   uint              `json:"media_file_id"`                 // 整理时所属的刮削记录ID
+	NewPath       string            `json:"new_path"`                      // 整理后的目录
+	Status        ScrapeExtraStatus `json:"status" gorm:"default:pending"` // 状态
+	Error         string            `json:"error"`                         // 整理失败的原因
+	RenameTime    int64             `json:"rename_time"`                   // 整理时间
+}
+
+func (*ScrapeExtraFile) TableName() string {
+	return "scrape_extra_files"
+}
+
+// 如果视频是附加内容，生成附加内容记录，否则返回nil
+// 其他类型不区分附加内容
+func (sp *ScrapePath) MakeScrapeExtraFile(path, pathId, fileName, fileId, pickCode string) *ScrapeExtraFile {
+	if sp.MediaType == MediaTypeOther {
+		return nil
+	}
+	extraType, mediaBaseName := ClassifyExtra(filepath.Base(path), fileName)
+	if extraType == "" {
+		return nil
+	}
+	mediaPath := path
+	if _, ok := extraDirType(filepath.Base(path)); ok {
+		// 按目录归类的附加内容属于上一级目录中的视频
+		mediaPath = filepath.Dir(path)
+		if sp.SourceType != SourceTypeLocal {
+			mediaPath = filepath.ToSlash(mediaPath)
+		}
+	}
+	return &ScrapeExtraFile{
+		ScrapePathId:  sp.ID,
+		ExtraType:     extraType,
+		FileName:      fileName,
+		FileId:        fileId,
+		PickCode:      pickCode,
+		Path:          path,
+		PathId:        pathId,
+		MediaPath:     mediaPath,
+		MediaBaseName: mediaBaseName,
+		Status:        ScrapeExtraStatusPending,
+	}
+}
+
+// 检查附加内容是否已经入库
+func CheckExistsExtraFile(fileId string, scrapePathId uint) bool {
+	var total int64
+	if err := db.Db.Model(&ScrapeExtraFile{}).Where("file_id = ? AND scrape_path_id = ?", fileId, scrapePathId).Count(&total).Error; err != nil {
+		helpers.AppLogger.Errorf("检查附加内容是否存在失败: %v", err)
+		return false
+	}
+	return total > 0
+}
+
+// 保存新发现的附加内容，已入库的跳过，返回是否保存
+func SaveScrapeExtraFile(extra *ScrapeExtraFile) bool {
+	if CheckExistsExtraFile(extra.FileId, extra.ScrapePathId) {
+		return false
+	}
+	if err := db.Db.Create(extra).Error; err != nil {
+		helpers.AppLogger.Errorf("保存附加内容 %s 失败: %v", extra.FileName, err)
+		return false
+	}
+	helpers.AppLogger.Infof("文件 %s 是附加内容（%s），跟随 %s 中的视频整理，不单独刮削", extra.FileName, extra.ExtraType, extra.MediaPath)
+	return true
+}
+
+// 查询目录中等待整理的附加内容
+func GetPendingExtraFiles(scrapePathId uint, mediaPath string) []*ScrapeExtraFile {
+	var extras []*ScrapeExtraFile
+	if err := db.Db.Where("scrape_path_id = ? AND media_path = ? AND status = ?", scrapePathId, mediaPath, ScrapeExtraStatusPending).Find(&extras).Error; err != nil {
+		helpers.AppLogger.Errorf("查询附加内容失败: %v", err)
+		return nil
+	}
+	return extras
+}
+
+// 查询有等待整理的附加内容的目录
+func GetPendingExtraMediaPaths(scrapePathId uint) []string {
+	var paths []string
+	if err := db.Db.Model(&ScrapeExtraFile{}).Where("scrape_path_id = ? AND status = ?", scrapePathId, ScrapeExtraStatusPending).Distinct().Pluck("media_path", &paths).Error; err != nil {
+		helpers.AppLogger.Errorf("查询附加内容目录失败: %v", err)
+		return nil
+	}
+	return paths
+}
+
+// 查询来源目录在mediaPath的已整理记录，用来整理视频整理之后才发现的附加内容
+// 电影和集按附加内容目录（蓝光原盘为原盘目录）匹配，showLevel为true时按电视剧目录匹配
+func GetRenamedScrapeMediaFilesByExtrasPath(scrapePathId uint, mediaPath string, showLevel bool) []*ScrapeMediaFile {
+	var scrapeMediaFiles []*ScrapeMediaFile
+	tx := db.Db.Where("scrape_path_id = ? AND status = ? AND is_version_held = ?", scrapePathId, ScrapeMediaStatusRenamed, false)
+	if showLevel {
+		tx = tx.Where("tvshow_path = ?", mediaPath)
+	} else {
+		tx = tx.Where("path = ? OR disc_path = ?", mediaPath, mediaPath)
+	}
+	if err := tx.Order("id asc").Find(&scrapeMediaFiles).Error; err != nil {
+		helpers.AppLogger.Errorf("查询已整理的刮削记录失败: %v", err)
+		return nil
+	}
+	if showLevel {
+		return DecodeScrapeMediaFile(scrapeMediaFiles)
+	}
+	matched := make([]*ScrapeMediaFile, 0, len(scrapeMediaFiles))
+	for _, sm := range scrapeMediaFiles {
+		if sm.ExtrasPath() == mediaPath {
+			matched = append(matched, sm)
+		}
+	}
+	return DecodeScrapeMediaFile(matched)
+}
+
+// 统计目录中的刮削记录数量，用来判断按目录归类的附加内容属于哪个视频
+func CountScrapeMediaFilesInPath(scrapePathId uint, path string) int64 {
+	var total int64
+	db.Db.Model(&ScrapeMediaFile{}).Where("scrape_path_id = ? AND path = ?", scrapePathId, path).Count(&total)
+	return total
+}
+
+func (e *ScrapeExtraFile) Renamed(mediaFileId uint, newPath string) {
+	e.MediaFileId = mediaFileId
+	e.NewPath = newPath
+	e.Status = ScrapeExtraStatusRenamed
+	e.Error = ""
+	e.RenameTime = time.Now().Unix()
+	db.Db.Save(e)
+}
+
+func (e *ScrapeExtraFile) Failed(mediaFileId uint, reason string) {
+	e.MediaFileId = mediaFileId
+	e.Status = ScrapeExtraStatusFailed
+	e.Error = reason
+	db.Db.Save(e)
+}
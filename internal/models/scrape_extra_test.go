@@ -0,0 +1,34 @@
+package models
+
+import "testing"
+
+func TestClassifyExtra(t *testing.T) {
+	tests := []struct {
+		name      string
+		dirName   string
+		fileName  string
+		extraType ExtraType
+		mediaName string
+	}{
+		{name: "正片", dirName: "阿凡达 (2009)", fileName: "阿凡达 (2009).mkv"},
+		{name: "预告片后缀", dirName: "阿凡达 (2009)", fileName: "阿凡达 (2009)-trailer.mp4", extraType: ExtraTypeTrailer, mediaName: "阿凡达 (2009)"},
+		{name: "样片点分隔", dirName: "Avatar.2009", fileName: "Avatar.2009.1080p.Sample.mkv", extraType: ExtraTypeSample, mediaName: "Avatar.2009.1080p"},
+		{name: "文件名就是类型", dirName: "Avatar.2009", fileName: "sample.mkv", extraType: ExtraTypeSample},
+		{name: "删减片段", dirName: "Avatar.2009", fileName: "Avatar-deletedscene.mkv", extraType: ExtraTypeDeletedScene, mediaName: "Avatar"},
+		{name: "按目录归类", dirName: "Behind The Scenes", fileName: "制作特辑.mkv", extraType: ExtraTypeBehindTheScenes},
+		{name: "中文花絮目录", dirName: "花絮", fileName: "01.mp4", extraType: ExtraTypeFeaturette},
+		{name: "片名以单词结尾不算", dirName: "The Big Short (2015)", fileName: "The Big Short (2015).mkv"},
+		{name: "只有-分隔的后缀", dirName: "The Other", fileName: "The Other.mkv"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extraType, mediaName := ClassifyExtra(tt.dirName, tt.fileName)
+			if extraType != tt.extraType || mediaName != tt.mediaName {
+				t.Errorf("期望: %q %q, 实际: %q %q", tt.extraType, tt.mediaName, extraType, mediaName)
+			}
+		})
+	}
+	if ExtraTypeDeletedScene.FolderName() != "deleted scenes" || ExtraType("unknown").FolderName() != "extras" {
+		t.Errorf("附加内容目录名错误")
+	}
+}
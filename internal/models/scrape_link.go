@@ -0,0 +1,160 @@
+package models
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 是否是链接整理，链接整理不改动来源文件
+func (t RenameType) IsLink() bool {
+	return t == RenameTypeHardSymlink || t == RenameTypeSoftSymlink
+}
+
+// 镜像整理：本地来源使用硬链接或软链接在目标目录中生成整理好的媒体库，来源目录保持原样（例如正在做种的下载目录）
+// 整理时记录每一对链接，来源文件删除时清理链接，来源文件被替换（例如洗版）时重新链接
+func (sp *ScrapePath) IsMirror() bool {
+	return sp.SourceType == SourceTypeLocal && sp.ScrapeType != ScrapeTypeOnly && sp.RenameType.IsLink() && !sp.IsCrossStorage()
+}
+
+type ScrapeLinkCheckResult string
+
+const (
+	ScrapeLinkOk       ScrapeLinkCheckResult = "ok"       // 链接正常
+	ScrapeLinkRemoved  ScrapeLinkCheckResult = "removed"  // 来源文件已删除，链接已清理
+	ScrapeLinkRelinked ScrapeLinkCheckResult = "relinked" // 来源文件已替换，已重新链接
+)
+
+// 镜像整理创建的链接
+type ScrapeLink struct {
+	BaseModel
+	ScrapePathId      uint   `json:"scrape_path_id" gorm:"index"`
+	ScrapeMediaFileId uint   `json:"scrape_media_file_id" gorm:"index"` // 刮削记录ID
+	IsHard            bool   `json:"is_hard"`                           // 是否是硬链接
+	SourceFile        string `json:"source_file" gorm:"index"`          // 来源文件完整路径
+	LinkFile          string `json:"link_file" gorm:"uniqueIndex"`      // 链接完整路径
+	CheckTime         int64  `json:"check_time"`                        // 最后检查时间
+}
+
+func (*ScrapeLink) TableName() string {
+	return "scrape_links"
+}
+
+// 创建硬链接或软链接
+func CreateLink(sourceFile, linkFile string, isHard bool) error {
+	if isHard {
+		return os.Link(sourceFile, linkFile)
+	}
+	return os.Symlink(sourceFile, linkFile)
+}
+
+// 记录链接，同一个链接路径只保留一条记录
+func SaveScrapeLink(link *ScrapeLink) {
+	existing := &ScrapeLink{}
+	if err := db.Db.Where("link_file = ?", link.LinkFile).First(existing).Error; err == nil {
+		link.ID = existing.ID
+		link.CreatedAt = existing.CreatedAt
+	}
+	if err := db.Db.Save(link).Error; err != nil {
+		helpers.AppLogger.Errorf("保存链接记录 %s => %s 失败: %v", link.SourceFile, link.LinkFile, err)
+	}
+}
+
+// 查询刮削目录的链接记录
+func GetScrapeLinks(scrapePathId uint, page, pageSize int) ([]*ScrapeLink, int64) {
+	var total int64
+	links := make([]*ScrapeLink, 0)
+	tx := db.Db.Model(&ScrapeLink{}).Where("scrape_path_id = ?", scrapePathId)
+	tx.Count(&total)
+	if page > 0 && pageSize > 0 {
+		tx = tx.Offset((page - 1) * pageSize).Limit(pageSize)
+	}
+	if err := tx.Order("id ASC").Find(&links).Error; err != nil {
+		helpers.AppLogger.Errorf("查询链接记录失败: %v", err)
+	}
+	return links, total
+}
+
+// 删除刮削记录的所有链接记录，不删除链接文件
+func DeleteScrapeLinksByMediaFile(scrapeMediaFileId uint) {
+	db.Db.Where("scrape_media_file_id = ?", scrapeMediaFileId).Delete(&ScrapeLink{})
+}
+
+// 删除链接记录，不删除链接文件
+func DeleteScrapeLinkByLinkFile(linkFile string) {
+	db.Db.Where("link_file = ?", linkFile).Delete(&ScrapeLink{})
+}
+
+// 检查链接：来源文件不存在时删除链接，来源文件被替换或者链接丢失时重新链接
+// 链接所在目录被删除说明用户移除了整理结果，只删除记录
+func (l *ScrapeLink) Check() (ScrapeLinkCheckResult, error) {
+	sourceInfo, err := os.Stat(l.SourceFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return ScrapeLinkOk, err
+		}
+		if rerr := os.Remove(l.LinkFile); rerr != nil && !os.IsNotExist(rerr) {
+			return ScrapeLinkOk, rerr
+		}
+		helpers.AppLogger.Infof("来源文件 %s 已删除，清理链接 %s", l.SourceFile, l.LinkFile)
+		db.Db.Delete(l)
+		return ScrapeLinkRemoved, nil
+	}
+	if !helpers.PathExists(filepath.Dir(l.LinkFile)) {
+		helpers.AppLogger.Infof("链接 %s 所在目录已删除，删除链接记录", l.LinkFile)
+		db.Db.Delete(l)
+		return ScrapeLinkRemoved, nil
+	}
+	if l.linkIsValid(sourceInfo) {
+		l.CheckTime = time.Now().Unix()
+		db.Db.Model(l).Update("check_time", l.CheckTime)
+		return ScrapeLinkOk, nil
+	}
+	if rerr := os.Remove(l.LinkFile); rerr != nil && !os.IsNotExist(rerr) {
+		return ScrapeLinkOk, rerr
+	}
+	if err := CreateLink(l.SourceFile, l.LinkFile, l.IsHard); err != nil {
+		return ScrapeLinkOk, err
+	}
+	helpers.AppLogger.Infof("来源文件 %s 已替换，重新链接到 %s", l.SourceFile, l.LinkFile)
+	l.CheckTime = time.Now().Unix()
+	db.Db.Save(l)
+	return ScrapeLinkRelinked, nil
+}
+
+// 硬链接必须和来源是同一个文件，软链接必须指向来源路径（来源替换后自动指向新文件）
+func (l *ScrapeLink) linkIsValid(sourceInfo os.FileInfo) bool {
+	if l.IsHard {
+		linkInfo, err := os.Stat(l.LinkFile)
+		return err == nil && os.SameFile(sourceInfo, linkInfo)
+	}
+	target, err := os.Readlink(l.LinkFile)
+	return err == nil && target == l.SourceFile
+}
+
+// 检查刮削目录所有的链接，返回清理和重新链接的数量
+func (sp *ScrapePath) CheckLinks() (int, int, error) {
+	if !sp.IsMirror() {
+		return 0, 0, errors.New("只有本地来源使用硬链接或软链接整理的刮削目录才需要检查链接")
+	}
+	removed, relinked := 0, 0
+	links, _ := GetScrapeLinks(sp.ID, 0, 0)
+	for _, link := range links {
+		result, err := link.Check()
+		if err != nil {
+			helpers.AppLogger.Errorf("检查链接 %s => %s 失败: %v", link.SourceFile, link.LinkFile, err)
+			continue
+		}
+		switch result {
+		case ScrapeLinkRemoved:
+			removed++
+		case ScrapeLinkRelinked:
+			relinked++
+		}
+	}
+	helpers.AppLogger.Infof("刮削目录 %s 共检查 %d 个链接，清理 %d 个，重新链接 %d 个", sp.SourcePath, len(links), removed, relinked)
+	return removed, relinked, nil
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScrapePathIsMirror(t *testing.T) {
+	tests := []struct {
+		name     string
+		sp       *ScrapePath
+		expected bool
+	}{
+		{name: "本地硬链接", sp: &ScrapePath{SourceType: SourceTypeLocal, ScrapeType: ScrapeTypeScrapeAndRename, RenameType: RenameTypeHardSymlink}, expected: true},
+		{name: "本地软链接仅整理", sp: &ScrapePath{SourceType: SourceTypeLocal, ScrapeType: ScrapeTypeOnlyRename, RenameType: RenameTypeSoftSymlink}, expected: true},
+		{name: "本地移动", sp: &ScrapePath{SourceType: SourceTypeLocal, ScrapeType: ScrapeTypeScrapeAndRename, RenameType: RenameTypeMove}, expected: false},
+		{name: "仅刮削", sp: &ScrapePath{SourceType: SourceTypeLocal, ScrapeType: ScrapeTypeOnly, RenameType: RenameTypeHardSymlink}, expected: false},
+		{name: "115", sp: &ScrapePath{SourceType: SourceType115, ScrapeType: ScrapeTypeScrapeAndRename, RenameType: RenameTypeHardSymlink}, expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := tt.sp.IsMirror(); actual != tt.expected {
+				t.Errorf("期望: %v, 实际: %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestScrapeLinkIsValid(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source.mkv")
+	if err := os.WriteFile(source, []byte("1080p"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	hard := &ScrapeLink{IsHard: true, SourceFile: source, LinkFile: filepath.Join(dir, "hard.mkv")}
+	soft := &ScrapeLink{SourceFile: source, LinkFile: filepath.Join(dir, "soft.mkv")}
+	for _, link := range []*ScrapeLink{hard, soft} {
+		if err := CreateLink(link.SourceFile, link.LinkFile, link.IsHard); err != nil {
+			t.Skipf("当前文件系统不支持链接: %v", err)
+		}
+	}
+	sourceInfo, _ := os.Stat(source)
+	if !hard.linkIsValid(sourceInfo) || !soft.linkIsValid(sourceInfo) {
+		t.Fatalf("新建的链接应该有效")
+	}
+	// 洗版替换来源文件后，硬链接还指向旧文件，软链接自动指向新文件
+	os.Remove(source)
+	if err := os.WriteFile(source, []byte("2160p"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	sourceInfo, _ = os.Stat(source)
+	if hard.linkIsValid(sourceInfo) {
+		t.Errorf("来源文件替换后硬链接应该失效")
+	}
+	if !soft.linkIsValid(sourceInfo) {
+		t.Errorf("来源文件替换后软链接应该仍然有效")
+	}
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+
+	"gorm.io/gorm"
+)
+
+// 增量扫描记录的目录状态
+// 目录的修改时间、包含的文件总数和文件夹总数都和上次扫描时一致，说明目录中没有新增或删除文件，扫描时跳过整个目录
+type ScrapeScanDir struct {
+	BaseModel
+	ScrapePathId uint   `json:"scrape_path_id" gorm:"uniqueIndex:idx_scrape_scan_dir"`
+	PathId       string `json:"path_id" gorm:"uniqueIndex:idx_scrape_scan_dir"` // 目录ID
+	MTime        int64  `json:"mtime"`                                          // 目录修改时间
+	FileCount    int64  `json:"file_count"`                                     // 包含的文件总数
+	FolderCount  int64  `json:"folder_count"`                                   // 包含的文件夹总数
+	ScanTime     int64  `json:"scan_time"`                                      // 最后扫描时间
+}
+
+func (*ScrapeScanDir) TableName() string {
+	return "scrape_scan_dirs"
+}
+
+// 目录状态是否和上次扫描时一致
+func (d *ScrapeScanDir) Unchanged(last *ScrapeScanDir) bool {
+	if last == nil {
+		return false
+	}
+	return d.MTime == last.MTime && d.FileCount == last.FileCount && d.FolderCount == last.FolderCount
+}
+
+// 查询刮削目录上次扫描的所有目录状态，key为目录ID
+func GetScrapeScanDirs(scrapePathId uint) map[string]*ScrapeScanDir {
+	dirs := make([]*ScrapeScanDir, 0)
+	if err := db.Db.Where("scrape_path_id = ?", scrapePathId).Find(&dirs).Error; err != nil {
+		helpers.AppLogger.Errorf("查询刮削目录 %d 的扫描记录失败: %v", scrapePathId, err)
+	}
+	result := make(map[string]*ScrapeScanDir, len(dirs))
+	for _, dir := range dirs {
+		result[dir.PathId] = dir
+	}
+	return result
+}
+
+// 保存本次扫描的目录状态，已有记录的更新，没有的新增
+func SaveScrapeScanDirs(scrapePathId uint, dirs []*ScrapeScanDir) {
+	if len(dirs) == 0 {
+		return
+	}
+	last := GetScrapeScanDirs(scrapePathId)
+	for _, dir := range dirs {
+		dir.ScrapePathId = scrapePathId
+		if old, ok := last[dir.PathId]; ok {
+			dir.ID = old.ID
+			dir.CreatedAt = old.CreatedAt
+		}
+	}
+	err := db.Db.Transaction(func(tx *gorm.DB) error {
+		for _, dir := range dirs {
+			if err := tx.Save(dir).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		helpers.AppLogger.Errorf("保存刮削目录 %d 的扫描记录失败: %v", scrapePathId, err)
+		return
+	}
+	helpers.AppLogger.Infof("已保存刮削目录 %d 的 %d 个目录扫描记录", scrapePathId, len(dirs))
+}
+
+// 清空刮削目录的扫描记录，下次扫描时扫描全部目录
+func ClearScrapeScanDirs(scrapePathId uint) {
+	if err := db.Db.Where("scrape_path_id = ?", scrapePathId).Delete(&ScrapeScanDir{}).Error; err != nil {
+		helpers.AppLogger.Errorf("清空刮削目录 %d 的扫描记录失败: %v", scrapePathId, err)
+	}
+}
@@ -0,0 +1,27 @@
+package models
+
+import "testing"
+
+func TestScrapeScanDirUnchanged(t *testing.T) {
+	last := &ScrapeScanDir{PathId: "1", MTime: 1700000000, FileCount: 12, FolderCount: 3}
+	tests := []struct {
+		name     string
+		dir      *ScrapeScanDir
+		last     *ScrapeScanDir
+		expected bool
+	}{
+		{name: "没有变化", dir: &ScrapeScanDir{PathId: "1", MTime: 1700000000, FileCount: 12, FolderCount: 3}, last: last, expected: true},
+		{name: "没有上次记录", dir: &ScrapeScanDir{PathId: "1", MTime: 1700000000, FileCount: 12, FolderCount: 3}, last: nil, expected: false},
+		{name: "上次没有记录文件总数", dir: &ScrapeScanDir{PathId: "1", MTime: 1700000000, FileCount: 12, FolderCount: 3}, last: &ScrapeScanDir{PathId: "1", MTime: 1700000000, FileCount: -1, FolderCount: -1}, expected: false},
+		{name: "修改时间变化", dir: &ScrapeScanDir{PathId: "1", MTime: 1700000100, FileCount: 12, FolderCount: 3}, last: last, expected: false},
+		{name: "子目录新增文件", dir: &ScrapeScanDir{PathId: "1", MTime: 1700000000, FileCount: 13, FolderCount: 3}, last: last, expected: false},
+		{name: "子目录新增文件夹", dir: &ScrapeScanDir{PathId: "1", MTime: 1700000000, FileCount: 12, FolderCount: 4}, last: last, expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := tt.dir.Unchanged(tt.last); actual != tt.expected {
+				t.Errorf("期望: %v, 实际: %v", tt.expected, actual)
+			}
+		})
+	}
+}
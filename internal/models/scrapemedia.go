@@ -30,6 +30,7 @@ const (
 	ScrapeMediaStatusIgnore       ScrapeMediaStatus = "ignore"        // 忽略
 	ScrapeMediaStatusScrapeFailed ScrapeMediaStatus = "scrape_failed" // 刮削失败
 	ScrapeMediaStatusRollbacking  ScrapeMediaStatus = "rollbacking"   // 回滚中
+	ScrapeMediaStatusNeedsReview  ScrapeMediaStatus = "needs_review"  // 识别置信度低，待确认
 )
 
 type TmdbGender int
@@ -136,6 +137,15 @@ type MoveNewFileToSourceFile struct {
 	FileId       string `json:"file_id"`        // 文件ID
 	PathId       string `json:"path_id"`        // 路径ID
 	FileFullPath string `json:"file_full_path"` // 文件完整路径
+	// 文件移动前的位置，记录整理日志使用，为空时网盘需要再查询一次
+	Source *FileSource `json:"source,omitempty"`
+}
+
+// FileSource 文件整理前所在的目录和文件名
+type FileSource struct {
+	Path   string `json:"path"`
+	PathId string `json:"path_id"`
+	Name   string `json:"name"`
 }
 
 // 待刮削的视频文件列表
@@ -199,6 +209,14 @@ type ScrapeMediaFile struct {
 	BatchNo              string            `json:"batch_no" gorm:"index:idx_batch_no_tvshow"`       // 批次号(每次扫描都是同一个批次)
 	TvIsRename           bool              `json:"tv_is_rename"`                                    // 是否重命名剧集
 	SeasonIsRename       bool              `json:"season_is_rename"`                                // 是否重命名季
+	VersionLabel         string            `json:"version_label"`                                   // 多版本标签，例如：2160p，同一TMDB ID存在多个文件时使用
+	IsVersionHeld        bool              `json:"is_version_held"`                                 // 是否为非最佳版本，已移动到多版本待定目录
+	Confidence           int               `json:"confidence"`                                      // 识别置信度，0-100，0表示未计算
+	CandidatesJson       string            `json:"-"`                                               // 待确认时的候选影片json字符串
+	DiscType             DiscType          `json:"disc_type"`                                       // 原盘类型，为空表示普通视频文件
+	DiscPath             string            `json:"disc_path"`                                       // 原盘所在目录，识别时使用该目录名
+	DiscPathId           string            `json:"disc_path_id"`                                    // 原盘所在目录ID
+	DiscDirId            string            `json:"disc_dir_id"`                                     // 蓝光原盘BDMV目录ID，整理时整个目录移动
 	Media                *Media            `json:"-" gorm:"-"`                                      // 影视剧信息
 	MediaSeason          *MediaSeason      `json:"-" gorm:"-"`                                      // 季信息
 	MediaEpisode         *MediaEpisode     `json:"-" gorm:"-"`                                      // 集信息
@@ -633,7 +651,11 @@ func (sm *ScrapeMediaFile) buildTemplateContext() pongo2.Context {
 		if sm.Media.Num != "" {
 			ctx["num"] = sm.Media.Num
 		}
+		ctx["collection"] = sm.Media.CollectionName
 	}
+	ctx["hdr_type"] = sm.HdrType()
+	ctx["audio_channels"] = sm.AudioChannels()
+	ctx["release_group"] = sm.ReleaseGroup()
 
 	if sm.MediaType == MediaTypeTvShow {
 		ctx["season"] = sm.SeasonNumber
@@ -657,19 +679,17 @@ func (sm *ScrapeMediaFile) buildTemplateContext() pongo2.Context {
 	return ctx
 }
 
-func (sm *ScrapeMediaFile) renderNewTemplate(template string) string {
+func (sm *ScrapeMediaFile) renderNewTemplate(template string) (string, error) {
 	ctx := sm.buildTemplateContext()
 	tpl, err := pongo2.FromString(template)
 	if err != nil {
-		helpers.AppLogger.Errorf("新模板解析失败: %v", err)
-		return ""
+		return "", fmt.Errorf("新模板解析失败: %v", err)
 	}
 	out, err := tpl.Execute(ctx)
 	if err != nil {
-		helpers.AppLogger.Errorf("新模板渲染失败: %v", err)
-		return ""
+		return "", fmt.Errorf("新模板渲染失败: %v", err)
 	}
-	return out
+	return out, nil
 }
 
 func (sm *ScrapeMediaFile) GenerateNameByTemplate(template string) string {
@@ -678,7 +698,11 @@ func (sm *ScrapeMediaFile) GenerateNameByTemplate(template string) string {
 	}
 
 	if sm.isNewTemplateSyntax(template) {
-		return sm.renderNewTemplate(template)
+		out, err := sm.renderNewTemplate(template)
+		if err != nil {
+			helpers.AppLogger.Errorf("%v", err)
+		}
+		return out
 	}
 
 	newName := strings.ReplaceAll(template, "{title}", sm.Name)
@@ -741,6 +765,14 @@ func (sm *ScrapeMediaFile) GenerateNameByTemplate(template string) string {
 	} else {
 		newName = strings.ReplaceAll(newName, "{num}", "")
 	}
+	if sm.Media != nil && sm.Media.CollectionName != "" {
+		newName = strings.ReplaceAll(newName, "{collection}", sm.Media.CollectionName)
+	} else {
+		newName = strings.ReplaceAll(newName, "{collection}", "")
+	}
+	newName = strings.ReplaceAll(newName, "{hdr_type}", sm.HdrType())
+	newName = strings.ReplaceAll(newName, "{audio_channels}", sm.AudioChannels())
+	newName = strings.ReplaceAll(newName, "{release_group}", sm.ReleaseGroup())
 	if sm.MediaType == MediaTypeTvShow {
 		if sm.SeasonNumber >= 0 {
 			// 季
@@ -850,6 +882,8 @@ func (sm *ScrapeMediaFile) ReScrape(name string, year int, tmdbId int64, season
 			updateData["media_episode_id"] = 0
 			updateData["failed_reason"] = ""
 			updateData["media_id"] = 0
+			// 重新识别后原来的候选影片不再有效
+			updateData["candidates_json"] = ""
 			db.Db.Where("id = ?", mediaId).Delete(&Media{})
 			db.Db.Where("media_id = ?", mediaId).Delete(&MediaSeason{})
 			db.Db.Where("media_id = ?", mediaId).Delete(&MediaEpisode{})
@@ -861,6 +895,7 @@ func (sm *ScrapeMediaFile) ReScrape(name string, year int, tmdbId int64, season
 			} else {
 				helpers.AppLogger.Infof("重新刮削时更新电视剧内所有剧集成功, 影响 %d 行 %+v", edb.RowsAffected, updateData)
 			}
+			updateData["confidence"] = sm.Confidence
 			if err := db.Db.Model(ScrapeMediaFile{}).Where("id = ?", sm.ID).Updates(updateData).Error; err != nil {
 				helpers.AppLogger.Errorf("重新刮削时更新剧集失败: %v", err)
 				return err
@@ -873,6 +908,7 @@ func (sm *ScrapeMediaFile) ReScrape(name string, year int, tmdbId int64, season
 				sm.MediaSeasonId = 0
 				sm.MediaEpisodeId = 0
 				sm.FailedReason = ""
+				sm.CandidatesJson = ""
 			}
 			hasEdit := false
 			// 检查输入的季是否存在
@@ -908,6 +944,7 @@ func (sm *ScrapeMediaFile) ReScrape(name string, year int, tmdbId int64, season
 			sm.ReScrapeTime = time.Now().Unix()
 			sm.Status = ScrapeMediaStatusScanned
 			sm.FailedReason = ""
+			sm.CandidatesJson = ""
 			sm.MediaId = 0
 			db.Db.Where("id = ?", mediaId).Delete(&Media{})
 			sm.Save()
@@ -1297,6 +1334,27 @@ func GetScrapeMediaFileIdBySeasonId(seasonMediaId uint) []uint {
 	return episodeIds
 }
 
+// 查询已整理的记录，重新生成nfo和图片使用，按ID升序分页，lastId为上一页最后一条记录的ID
+// mediaType为空不限类型，startTime、endTime为0不限整理时间
+func GetRenamedScrapeMediaFiles(scrapePathId uint, mediaType MediaType, startTime, endTime int64, lastId uint, limit int) []*ScrapeMediaFile {
+	var scrapeMediaFiles []*ScrapeMediaFile
+	tx := db.Db.Where("scrape_path_id = ? AND status = ? AND media_id > 0 AND id > ?", scrapePathId, ScrapeMediaStatusRenamed, lastId)
+	if mediaType != "" {
+		tx = tx.Where("media_type = ?", mediaType)
+	}
+	if startTime > 0 {
+		tx = tx.Where("rename_time >= ?", startTime)
+	}
+	if endTime > 0 {
+		tx = tx.Where("rename_time <= ?", endTime)
+	}
+	if err := tx.Order("id asc").Limit(limit).Find(&scrapeMediaFiles).Error; err != nil {
+		helpers.AppLogger.Errorf("查询已整理文件失败: %v", err)
+		return nil
+	}
+	return DecodeScrapeMediaFile(scrapeMediaFiles)
+}
+
 func GetScrapeMediaFilesByIds(ids []uint) []*ScrapeMediaFile {
 	var scrapeMediaFiles []*ScrapeMediaFile
 	if err := db.Db.Where("id IN ?", ids).Order("id desc").Find(&scrapeMediaFiles).Error; err != nil {
@@ -1434,9 +1492,13 @@ func ClearFailedScrapeRecords(ids []uint) error {
 	}
 	idArray := make([]uint, 0)
 	mediaIdArray := make([]uint, 0)
+	scrapePathIds := make([]uint, 0)
 	// 找出所有保存下来的fileid
 	for _, sm := range failedScrapeMediaFiles {
 		idArray = append(idArray, sm.ID)
+		if !slices.Contains(scrapePathIds, sm.ScrapePathId) {
+			scrapePathIds = append(scrapePathIds, sm.ScrapePathId)
+		}
 		if !slices.Contains(mediaIdArray, sm.MediaId) {
 			mediaIdArray = append(mediaIdArray, sm.MediaId)
 		}
@@ -1446,6 +1508,10 @@ func ClearFailedScrapeRecords(ids []uint) error {
 		helpers.AppLogger.Errorf("删除刮削记录失败: %v", err)
 		return err
 	}
+	// 删除记录后文件需要重新扫描入库，清空增量扫描的目录状态，否则所在目录没有变化会被跳过
+	for _, scrapePathId := range scrapePathIds {
+		ClearScrapeScanDirs(scrapePathId)
+	}
 	// 删除所有Media
 	if len(mediaIdArray) > 0 {
 		if err := db.Db.Where("id IN ?", mediaIdArray).Delete(&Media{}).Error; err != nil {
@@ -1507,7 +1573,7 @@ func GetAllEpisodeByTvshow(tmdbId int64, batchNo string) []*ScrapeMediaFile {
 }
 
 // TruncateAllScrapeRecords 清空所有刮削记录
-// 使用DELETE命令清空ScrapeMediaFile、Media、MediaSeason、MediaEpisode四张表
+// 使用DELETE命令清空ScrapeMediaFile、Media、MediaSeason、MediaEpisode四张表，同时清空增量扫描的目录状态
 func TruncateAllScrapeRecords() error {
 	// 按顺序删除表数据，注意外键依赖关系
 	// 先清空子表（MediaEpisode, MediaSeason），再清空父表（Media），最后清空ScrapeMediaFile
@@ -1516,6 +1582,7 @@ func TruncateAllScrapeRecords() error {
 		"media_seasons",
 		"media",
 		"scrape_media_files",
+		"scrape_scan_dirs",
 	}
 
 	for _, tableName := range tables {
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// 识别候选影片，待确认时供用户选择
+type IdentifyCandidate struct {
+	TmdbId       int64   `json:"tmdb_id"`       // TMDB ID
+	Name         string  `json:"name"`          // 标题
+	OriginalName string  `json:"original_name"` // 原始标题
+	Year         int     `json:"year"`          // 上映年份
+	Overview     string  `json:"overview"`      // 简介
+	PosterPath   string  `json:"poster_path"`   // 封面图片
+	Popularity   float64 `json:"popularity"`    // 流行度
+	Runtime      int64   `json:"runtime"`       // 片长，单位：分钟，搜索结果中没有，只有当前识别结果才有
+	Confidence   int     `json:"confidence"`    // 置信度，0-100
+	IsCurrent    bool    `json:"is_current"`    // 是否为当前识别结果
+}
+
+// 计算置信度时使用的文件信息
+type IdentifyQuery struct {
+	Name            string // 从文件名或文件夹中提取的名称
+	Year            int    // 从文件名或文件夹中提取的年份，0表示未知
+	DurationMinutes int64  // ffprobe提取的视频时长，0表示未知
+}
+
+// 置信度各项的权重，合计100
+const (
+	confidenceTitleWeight      = 40
+	confidenceYearWeight       = 25
+	confidenceRuntimeWeight    = 20
+	confidencePopularityWeight = 15
+)
+
+// 计算候选影片的置信度
+// 标题相似度、年份差、片长和视频时长的差、流行度（相对候选中最高的流行度）
+// 年份或片长未知时给一半的分，不因为缺少信息直接判为低置信度
+func ScoreIdentifyCandidate(q *IdentifyQuery, c *IdentifyCandidate, maxPopularity float64) int {
+	similarity := max(TitleSimilarity(q.Name, c.Name), TitleSimilarity(q.Name, c.OriginalName))
+	score := similarity * confidenceTitleWeight
+	if q.Year == 0 || c.Year == 0 {
+		score += confidenceYearWeight / 2
+	} else {
+		switch delta := math.Abs(float64(q.Year - c.Year)); {
+		case delta == 0:
+			score += confidenceYearWeight
+		case delta == 1:
+			score += confidenceYearWeight * 0.6
+		case delta == 2:
+			score += confidenceYearWeight * 0.2
+		}
+	}
+	if q.DurationMinutes == 0 || c.Runtime == 0 {
+		score += confidenceRuntimeWeight / 2
+	} else {
+		// 加长版、剪辑版会有差异，15分钟以内都算匹配
+		switch delta := math.Abs(float64(q.DurationMinutes - c.Runtime)); {
+		case delta <= 5:
+			score += confidenceRuntimeWeight
+		case delta <= 15:
+			score += confidenceRuntimeWeight * 0.6
+		case delta <= 30:
+			score += confidenceRuntimeWeight * 0.25
+		}
+	}
+	if maxPopularity > 0 {
+		score += c.Popularity / maxPopularity * confidencePopularityWeight
+	} else {
+		score += confidencePopularityWeight / 2
+	}
+	return int(math.Round(score))
+}
+
+// 标题相似度，0-1，忽略大小写、空格和标点
+func TitleSimilarity(a, b string) float64 {
+	ra := normalizeTitle(a)
+	rb := normalizeTitle(b)
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+	maxLen := max(len(ra), len(rb))
+	return 1 - float64(levenshtein(ra, rb))/float64(maxLen)
+}
+
+func normalizeTitle(title string) []rune {
+	runes := make([]rune, 0, len(title))
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			runes = append(runes, r)
+		}
+	}
+	return runes
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// 待确认时的候选影片
+func (sm *ScrapeMediaFile) GetCandidates() []*IdentifyCandidate {
+	if sm.CandidatesJson == "" {
+		return nil
+	}
+	candidates, err := helpers.StringJson[[]*IdentifyCandidate](sm.CandidatesJson)
+	if err != nil {
+		helpers.AppLogger.Errorf("解码候选影片失败: %v", err)
+	}
+	return candidates
+}
+
+// 置信度低于阈值，改为待确认，不继续整理
+func (sm *ScrapeMediaFile) NeedsReview(confidence int, threshold int, candidates []*IdentifyCandidate) {
+	sm.Status = ScrapeMediaStatusNeedsReview
+	sm.Confidence = confidence
+	sm.CandidatesJson = helpers.JsonString(candidates)
+	sm.FailedReason = fmt.Sprintf("识别置信度 %d 低于 %d，需要确认识别结果", confidence, threshold)
+	sm.ScrapeTime = time.Now().Unix()
+	updateData := make(map[string]interface{})
+	updateData["status"] = sm.Status
+	updateData["confidence"] = sm.Confidence
+	updateData["candidates_json"] = sm.CandidatesJson
+	updateData["failed_reason"] = sm.FailedReason
+	updateData["scrape_time"] = sm.ScrapeTime
+	if err := db.Db.Model(&ScrapeMediaFile{}).Where("id = ?", sm.ID).Updates(updateData).Error; err != nil {
+		helpers.AppLogger.Errorf("更新刮削媒体为待确认失败: id=%d %v", sm.ID, err)
+	}
+}
+
+// 确认识别结果，使用选择的TMDB ID重新刮削
+// 改为待刮削后走重新识别的流程，下次刮削时不再计算置信度
+func (sm *ScrapeMediaFile) ConfirmReview(tmdbId int64) error {
+	if sm.Status != ScrapeMediaStatusNeedsReview {
+		return fmt.Errorf("记录不是待确认状态")
+	}
+	if tmdbId == 0 {
+		tmdbId = sm.TmdbId
+	}
+	sm.Status = ScrapeMediaStatusScanned
+	sm.Confidence = 100
+	sm.CandidatesJson = ""
+	if err := sm.ReScrape("", 0, tmdbId, 0, 0); err != nil {
+		sm.Status = ScrapeMediaStatusNeedsReview
+		return err
+	}
+	return nil
+}
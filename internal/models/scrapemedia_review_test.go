@@ -0,0 +1,46 @@
+package models
+
+import "testing"
+
+func TestTitleSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		min  float64
+		max  float64
+	}{
+		{name: "完全相同", a: "星际穿越", b: "星际穿越", min: 1, max: 1},
+		{name: "忽略大小写和标点", a: "Spider-Man: Homecoming", b: "spider man homecoming", min: 1, max: 1},
+		{name: "部分相同", a: "沙丘2", b: "沙丘", min: 0.6, max: 0.7},
+		{name: "完全不同", a: "星际穿越", b: "Inception", min: 0, max: 0},
+		{name: "空标题", a: "", b: "Inception", min: 0, max: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := TitleSimilarity(tt.a, tt.b)
+			if result < tt.min || result > tt.max {
+				t.Errorf("期望: %.2f-%.2f, 实际: %.2f", tt.min, tt.max, result)
+			}
+		})
+	}
+}
+
+func TestScoreIdentifyCandidate(t *testing.T) {
+	q := &IdentifyQuery{Name: "Dune", Year: 2021, DurationMinutes: 155}
+	right := &IdentifyCandidate{Name: "沙丘", OriginalName: "Dune", Year: 2021, Runtime: 156, Popularity: 80}
+	old := &IdentifyCandidate{Name: "沙丘", OriginalName: "Dune", Year: 1984, Runtime: 137, Popularity: 20}
+	rightScore := ScoreIdentifyCandidate(q, right, 80)
+	oldScore := ScoreIdentifyCandidate(q, old, 80)
+	if rightScore != 100 {
+		t.Errorf("完全匹配的置信度应该是100, 实际: %d", rightScore)
+	}
+	if oldScore >= 60 {
+		t.Errorf("年份和片长都不匹配的置信度应该低于60, 实际: %d", oldScore)
+	}
+	// 缺少年份和时长时不应该直接判为低置信度
+	unknown := ScoreIdentifyCandidate(&IdentifyQuery{Name: "Dune"}, &IdentifyCandidate{OriginalName: "Dune"}, 0)
+	if unknown < 60 {
+		t.Errorf("标题匹配但缺少其他信息时置信度应该不低于60, 实际: %d", unknown)
+	}
+}
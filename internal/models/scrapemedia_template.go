@@ -0,0 +1,336 @@
+package models
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/flosch/pongo2/v5"
+)
+
+// 命名预设，选择预设后刮削目录的文件夹和文件名模板使用预设的模板
+type NamingPreset struct {
+	Key          string `json:"key"`
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	MovieFolder  string `json:"movie_folder"`  // 电影文件夹模板
+	MovieFile    string `json:"movie_file"`    // 电影文件名模板
+	TvShowFolder string `json:"tvshow_folder"` // 电视剧文件夹模板
+	EpisodeFile  string `json:"episode_file"`  // 集文件名模板
+}
+
+var NamingPresets = []*NamingPreset{
+	{
+		Key:          "emby",
+		Name:         "Emby",
+		Description:  "文件夹名带 [tmdbid=xxx]，Emby 可以直接按 TMDB ID 匹配",
+		MovieFolder:  "{{ title }} ({{ year }}) [tmdbid={{ tmdbid }}]",
+		MovieFile:    "{{ title }} ({{ year }}){% if videoFormat %} - {{ videoFormat }}{% endif %}",
+		TvShowFolder: "{{ title }} ({{ year }}) [tmdbid={{ tmdbid }}]",
+		EpisodeFile:  "{{ title }} - {{ season_episode }}{% if episode_title %} - {{ episode_title }}{% endif %}",
+	},
+	{
+		Key:          "jellyfin",
+		Name:         "Jellyfin",
+		Description:  "文件夹名带 [tmdbid-xxx]，Jellyfin 可以直接按 TMDB ID 匹配",
+		MovieFolder:  "{{ title }} ({{ year }}) [tmdbid-{{ tmdbid }}]",
+		MovieFile:    "{{ title }} ({{ year }}){% if videoFormat %} - {{ videoFormat }}{% endif %}",
+		TvShowFolder: "{{ title }} ({{ year }}) [tmdbid-{{ tmdbid }}]",
+		EpisodeFile:  "{{ title }} {{ season_episode }}{% if episode_title %} - {{ episode_title }}{% endif %}",
+	},
+	{
+		Key:          "plex",
+		Name:         "Plex",
+		Description:  "文件夹名带 {tmdb-xxx}，版本信息放在 {edition-xxx} 中",
+		MovieFolder:  "{{ title }} ({{ year }}) {tmdb-{{ tmdbid }}}",
+		MovieFile:    "{{ title }} ({{ year }}){% if videoFormat %} {edition-{{ videoFormat }}}{% endif %}",
+		TvShowFolder: "{{ title }} ({{ year }}) {tmdb-{{ tmdbid }}}",
+		EpisodeFile:  "{{ title }} ({{ year }}) - {{ season_episode|lower }}{% if episode_title %} - {{ episode_title }}{% endif %}",
+	},
+	{
+		Key:          "kodi",
+		Name:         "Kodi",
+		Description:  "Kodi 按名称和年份匹配，依赖同目录的 nfo 文件",
+		MovieFolder:  "{{ title }} ({{ year }})",
+		MovieFile:    "{{ title }} ({{ year }})",
+		TvShowFolder: "{{ title }} ({{ year }})",
+		EpisodeFile:  "{{ title }} {{ season_episode }}",
+	},
+}
+
+// 根据key查询命名预设
+func GetNamingPreset(key string) *NamingPreset {
+	for _, preset := range NamingPresets {
+		if preset.Key == key {
+			return preset
+		}
+	}
+	return nil
+}
+
+// 预设中指定媒体类型的文件夹和文件名模板
+func (p *NamingPreset) Templates(mediaType MediaType) (string, string) {
+	if mediaType == MediaTypeTvShow {
+		return p.TvShowFolder, p.EpisodeFile
+	}
+	return p.MovieFolder, p.MovieFile
+}
+
+// 使用命名预设的模板替换刮削目录的模板，没有选择预设时保持自定义模板
+func (sp *ScrapePath) ApplyNamingPreset() error {
+	if sp.NamingPreset == "" {
+		return nil
+	}
+	preset := GetNamingPreset(sp.NamingPreset)
+	if preset == nil {
+		return fmt.Errorf("命名预设 %s 不存在", sp.NamingPreset)
+	}
+	sp.FolderNameTemplate, sp.FileNameTemplate = preset.Templates(sp.MediaType)
+	return nil
+}
+
+// HDR类型，文件名中有更具体的类型时优先使用文件名，否则根据ffprobe的像素格式判断
+func (sm *ScrapeMediaFile) HdrType() string {
+	tokens := strings.FieldsFunc(strings.ToUpper(sm.IdentifyFileName()), func(r rune) bool {
+		return strings.ContainsRune(" ._-[]()", r)
+	})
+	switch {
+	case slices.Contains(tokens, "DV") || slices.Contains(tokens, "DOVI") || strings.Contains(strings.Join(tokens, " "), "DOLBY VISION"):
+		return "DV"
+	case slices.Contains(tokens, "HDR10+") || slices.Contains(tokens, "HDR10PLUS"):
+		return "HDR10+"
+	case slices.Contains(tokens, "HDR10"):
+		return "HDR10"
+	case slices.Contains(tokens, "HLG"):
+		return "HLG"
+	case slices.Contains(tokens, "HDR") || sm.IsHDR:
+		return "HDR"
+	}
+	return ""
+}
+
+// 第一条音轨的声道布局，例如：5.1、7.1
+func (sm *ScrapeMediaFile) AudioChannels() string {
+	if len(sm.AudioCodec) == 0 || sm.AudioCodec[0] == nil || sm.AudioCodec[0].Channels <= 0 {
+		return ""
+	}
+	channels := sm.AudioCodec[0].Channels
+	layouts := map[int64]string{1: "1.0", 2: "2.0", 3: "2.1", 6: "5.1", 7: "6.1", 8: "7.1"}
+	if layout, ok := layouts[channels]; ok {
+		return layout
+	}
+	return fmt.Sprintf("%dch", channels)
+}
+
+var (
+	releaseGroupSuffixRegex = regexp.MustCompile(`-([A-Za-z0-9@]+)$`)
+	releaseGroupPrefixRegex = regexp.MustCompile(`^\[([^\[\]]+)\]`)
+)
+
+// 发布组，取文件名末尾的 -GROUP 或者开头的 [GROUP]
+func (sm *ScrapeMediaFile) ReleaseGroup() string {
+	name := sm.IdentifyFileName()
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	if match := releaseGroupSuffixRegex.FindStringSubmatch(name); match != nil {
+		group := match[1]
+		// 纯数字一般是集数或者年份
+		if len(group) <= 20 && strings.Trim(group, "0123456789") != "" {
+			return group
+		}
+	}
+	if match := releaseGroupPrefixRegex.FindStringSubmatch(name); match != nil {
+		return strings.TrimSpace(match[1])
+	}
+	return ""
+}
+
+// 旧模板支持的变量
+var legacyTemplateVars = []string{
+	"title", "year", "resolution", "resolution_level", "bitrate", "tmdb_id", "original_title", "original_name", "actors", "num",
+	"season_number", "episode_number", "season_episode", "episode_name", "collection", "hdr_type", "audio_channels", "release_group",
+}
+
+var legacyTemplateVarRegex = regexp.MustCompile(`\{([A-Za-z_]+)\}`)
+
+// 名称中各系统不允许的字符
+var illegalNameChars = []struct {
+	os    string
+	chars string
+}{
+	{os: "Windows", chars: `<>:"/\|?*`},
+	{os: "Linux", chars: "/"},
+	{os: "macOS", chars: "/:"},
+}
+
+var windowsReservedNames = []string{"CON", "PRN", "AUX", "NUL", "COM1", "COM2", "COM3", "COM4", "COM5", "COM6", "COM7", "COM8", "COM9", "LPT1", "LPT2", "LPT3", "LPT4", "LPT5", "LPT6", "LPT7", "LPT8", "LPT9"}
+
+// 检查生成的文件或文件夹名称在各系统下是否合法
+func CheckNameForOS(name string) []string {
+	issues := make([]string, 0)
+	if strings.TrimSpace(name) == "" {
+		return append(issues, "生成的名称为空")
+	}
+	for _, rule := range illegalNameChars {
+		found := make([]string, 0)
+		for _, c := range rule.chars {
+			if strings.ContainsRune(name, c) {
+				found = append(found, string(c))
+			}
+		}
+		if len(found) > 0 {
+			issues = append(issues, fmt.Sprintf("包含 %s 不允许的字符 %s", rule.os, strings.Join(found, " ")))
+		}
+	}
+	if strings.ContainsFunc(name, func(r rune) bool { return r < 32 }) {
+		issues = append(issues, "包含控制字符")
+	}
+	if strings.HasSuffix(name, ".") || strings.HasSuffix(name, " ") {
+		issues = append(issues, "以点或空格结尾，Windows 会自动去掉")
+	}
+	base := strings.ToUpper(strings.TrimSpace(name))
+	if idx := strings.Index(base, "."); idx >= 0 {
+		base = base[:idx]
+	}
+	if slices.Contains(windowsReservedNames, base) {
+		issues = append(issues, fmt.Sprintf("%s 是 Windows 保留名称", name))
+	}
+	if len(name) > 255 {
+		issues = append(issues, fmt.Sprintf("长度 %d 字节超过 255 字节的限制", len(name)))
+	}
+	return issues
+}
+
+// 模板在一条记录上的渲染结果
+type TemplateSampleResult struct {
+	ScrapeMediaFileId uint     `json:"scrape_media_file_id"` // 实际记录的ID，内置示例为0
+	Source            string   `json:"source"`               // 记录的原始文件名
+	Output            string   `json:"output"`               // 生成的名称
+	Errors            []string `json:"errors"`
+}
+
+// 模板检查结果
+type TemplateCheckResult struct {
+	Template  string                  `json:"template"`
+	NewSyntax bool                    `json:"new_syntax"` // 是否是新语法（pongo2）
+	Valid     bool                    `json:"valid"`
+	Errors    []string                `json:"errors"`   // 模板本身的错误，例如语法错误
+	Warnings  []string                `json:"warnings"` // 不影响生成的问题，例如未知变量
+	Samples   []*TemplateSampleResult `json:"samples"`
+}
+
+// 使用示例或实际记录渲染模板，检查语法错误、空名称和各系统不允许的字符
+func CheckNameTemplate(template string, samples []*ScrapeMediaFile) *TemplateCheckResult {
+	result := &TemplateCheckResult{
+		Template: template,
+		Valid:    true,
+		Errors:   make([]string, 0),
+		Warnings: make([]string, 0),
+		Samples:  make([]*TemplateSampleResult, 0),
+	}
+	if strings.TrimSpace(template) == "" {
+		result.Warnings = append(result.Warnings, "模板为空，将使用默认命名")
+		return result
+	}
+	result.NewSyntax = (&ScrapeMediaFile{}).isNewTemplateSyntax(template)
+	if result.NewSyntax {
+		if _, err := pongo2.FromString(template); err != nil {
+			result.Valid = false
+			result.Errors = append(result.Errors, fmt.Sprintf("模板语法错误: %v", err))
+			return result
+		}
+	} else {
+		for _, match := range legacyTemplateVarRegex.FindAllStringSubmatch(template, -1) {
+			if !slices.Contains(legacyTemplateVars, match[1]) {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("未知变量 {%s}，会原样保留在名称中", match[1]))
+			}
+		}
+	}
+	for _, sm := range samples {
+		sample := &TemplateSampleResult{ScrapeMediaFileId: sm.ID, Source: sm.VideoFilename, Errors: make([]string, 0)}
+		if result.NewSyntax {
+			out, err := sm.renderNewTemplate(template)
+			if err != nil {
+				sample.Errors = append(sample.Errors, err.Error())
+			}
+			sample.Output = out
+		} else {
+			sample.Output = sm.GenerateNameByTemplate(template)
+		}
+		if len(sample.Errors) == 0 {
+			sample.Errors = append(sample.Errors, CheckNameForOS(sample.Output)...)
+		}
+		if len(sample.Errors) > 0 {
+			result.Valid = false
+		}
+		result.Samples = append(result.Samples, sample)
+	}
+	return result
+}
+
+// 内置的模板示例记录
+func TemplateSampleMediaFiles(mediaType MediaType) []*ScrapeMediaFile {
+	if mediaType == MediaTypeTvShow {
+		return []*ScrapeMediaFile{
+			{
+				MediaType:       MediaTypeTvShow,
+				Name:            "绝命毒师",
+				Year:            2008,
+				TmdbId:          1396,
+				SeasonNumber:    1,
+				EpisodeNumber:   1,
+				Resolution:      "1080p",
+				ResolutionLevel: "FHD",
+				VideoExt:        ".mkv",
+				VideoFilename:   "Breaking.Bad.S01E01.1080p.BluRay.x265.10bit.DTS-HD.MA.5.1-NTb.mkv",
+				VideoCodec:      &VideoCodec{Codec: "hevc", Bitrate: 6000000},
+				AudioCodec:      []*AudioCodec{{Codec: "dts", Channels: 6}},
+				Media:           &Media{Name: "绝命毒师", OriginalName: "Breaking Bad", OriginalLanguage: "en", ImdbId: "tt0903747"},
+				MediaSeason:     &MediaSeason{Year: 2008},
+				MediaEpisode:    &MediaEpisode{EpisodeName: "试播集", Year: 2008},
+			},
+		}
+	}
+	return []*ScrapeMediaFile{
+		{
+			MediaType:       MediaTypeMovie,
+			Name:            "星际穿越",
+			Year:            2014,
+			TmdbId:          157336,
+			Resolution:      "2160p",
+			ResolutionLevel: "UHD",
+			VideoExt:        ".mkv",
+			VideoFilename:   "Interstellar.2014.2160p.UHD.BluRay.x265.10bit.HDR10.TrueHD.7.1.Atmos-FGT.mkv",
+			IsHDR:           true,
+			VideoCodec:      &VideoCodec{Codec: "hevc", Bitrate: 60000000},
+			AudioCodec:      []*AudioCodec{{Codec: "truehd", Channels: 8}},
+			Media:           &Media{Name: "星际穿越", OriginalName: "Interstellar", OriginalLanguage: "en", ImdbId: "tt0816692", Runtime: 169},
+		},
+		{
+			MediaType:       MediaTypeMovie,
+			Name:            "钢铁侠",
+			Year:            2008,
+			TmdbId:          1726,
+			Resolution:      "1080p",
+			ResolutionLevel: "FHD",
+			VideoExt:        ".mp4",
+			VideoFilename:   "Iron.Man.2008.1080p.BluRay.x264-SPARKS.mp4",
+			VideoCodec:      &VideoCodec{Codec: "h264", Bitrate: 10000000},
+			AudioCodec:      []*AudioCodec{{Codec: "ac3", Channels: 6}},
+			Media:           &Media{Name: "钢铁侠", OriginalName: "Iron Man", OriginalLanguage: "en", ImdbId: "tt0371746", CollectionId: 131292, CollectionName: "钢铁侠（系列）"},
+		},
+	}
+}
+
+// 刮削目录最近的已识别记录，用来检查模板
+func GetTemplateSampleRecords(scrapePathId uint, limit int) []*ScrapeMediaFile {
+	var scrapeMediaFiles []*ScrapeMediaFile
+	if err := db.Db.Where("scrape_path_id = ? AND media_id > 0", scrapePathId).Order("id desc").Limit(limit).Find(&scrapeMediaFiles).Error; err != nil {
+		helpers.AppLogger.Errorf("查询刮削记录失败: %v", err)
+		return nil
+	}
+	return DecodeScrapeMediaFile(scrapeMediaFiles)
+}
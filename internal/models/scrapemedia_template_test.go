@@ -0,0 +1,86 @@
+package models
+
+import "testing"
+
+func TestNamingPresetsAreValid(t *testing.T) {
+	for _, preset := range NamingPresets {
+		for _, mediaType := range []MediaType{MediaTypeMovie, MediaTypeTvShow} {
+			folder, file := preset.Templates(mediaType)
+			for _, template := range []string{folder, file} {
+				result := CheckNameTemplate(template, TemplateSampleMediaFiles(mediaType))
+				if !result.Valid {
+					t.Errorf("预设 %s 模板 %s 检查失败: %+v", preset.Key, template, result)
+				}
+			}
+		}
+	}
+	sm := TemplateSampleMediaFiles(MediaTypeMovie)[0]
+	if name := sm.GenerateNameByTemplate(GetNamingPreset("plex").MovieFolder); name != "星际穿越 (2014) {tmdb-157336}" {
+		t.Errorf("Plex预设生成的文件夹名称错误: %s", name)
+	}
+}
+
+func TestCheckNameTemplate(t *testing.T) {
+	samples := TemplateSampleMediaFiles(MediaTypeMovie)
+	tests := []struct {
+		name     string
+		template string
+		valid    bool
+		warnings int
+	}{
+		{name: "新语法", template: "{{ title }} ({{ year }}){% if hdr_type %} {{ hdr_type }}{% endif %}", valid: true},
+		{name: "结尾空格", template: "{{ title }} ({{ year }}) {{ hdr_type }}", valid: false},
+		{name: "旧语法", template: "{title} ({year}) - {release_group}", valid: true},
+		{name: "旧语法未知变量", template: "{title} {unknown}", valid: true, warnings: 1},
+		{name: "语法错误", template: "{% if title %}{{ title }}", valid: false},
+		{name: "渲染为空", template: "{% if num %}{{ num }}{% endif %}", valid: false},
+		{name: "非法字符", template: "{{ title }}: {{ original_title }}", valid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := CheckNameTemplate(tt.template, samples)
+			if result.Valid != tt.valid || len(result.Warnings) != tt.warnings {
+				t.Errorf("期望: %v %d, 实际: %+v", tt.valid, tt.warnings, result)
+			}
+		})
+	}
+}
+
+func TestTemplateExtraVars(t *testing.T) {
+	tests := []struct {
+		name          string
+		sm            *ScrapeMediaFile
+		hdrType       string
+		audioChannels string
+		releaseGroup  string
+	}{
+		{
+			name:          "HDR10和发布组",
+			sm:            &ScrapeMediaFile{VideoFilename: "Interstellar.2014.2160p.BluRay.x265.HDR10.TrueHD.7.1-FGT.mkv", AudioCodec: []*AudioCodec{{Channels: 8}}},
+			hdrType:       "HDR10",
+			audioChannels: "7.1",
+			releaseGroup:  "FGT",
+		},
+		{
+			name:          "杜比视界",
+			sm:            &ScrapeMediaFile{VideoFilename: "Dune.2021.2160p.WEB-DL.DV.HDR10+.DDP5.1.mkv", AudioCodec: []*AudioCodec{{Channels: 6}}},
+			hdrType:       "DV",
+			audioChannels: "5.1",
+			releaseGroup:  "",
+		},
+		{
+			name:          "像素格式HDR和开头的发布组",
+			sm:            &ScrapeMediaFile{VideoFilename: "[Nekomoe kissaten] Frieren - 01.mkv", IsHDR: true},
+			hdrType:       "HDR",
+			audioChannels: "",
+			releaseGroup:  "Nekomoe kissaten",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.sm.HdrType() != tt.hdrType || tt.sm.AudioChannels() != tt.audioChannels || tt.sm.ReleaseGroup() != tt.releaseGroup {
+				t.Errorf("期望: %s %s %s, 实际: %s %s %s", tt.hdrType, tt.audioChannels, tt.releaseGroup, tt.sm.HdrType(), tt.sm.AudioChannels(), tt.sm.ReleaseGroup())
+			}
+		})
+	}
+}
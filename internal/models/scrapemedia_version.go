@@ -0,0 +1,107 @@
+package models
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 从文件名中提取分辨率，例如：2160p、1080i、4K
+var versionResolutionRegexp = regexp.MustCompile(`(?i)(480|576|720|1080|1440|2160|4320)[pi]`)
+var version4KRegexp = regexp.MustCompile(`(?i)(^|[^a-z0-9])(4k|uhd)([^a-z0-9]|$)`)
+
+// 查询同一刮削目录下相同TMDB ID的其他电影文件（多版本）
+// 忽略、刮削失败和待确认的记录不参与多版本处理
+func GetSameTmdbMovieFiles(sm *ScrapeMediaFile) []*ScrapeMediaFile {
+	if sm.TmdbId == 0 {
+		return nil
+	}
+	var scrapeMediaFiles []*ScrapeMediaFile
+	if err := db.Db.Where("scrape_path_id = ? AND media_type = ? AND tmdb_id = ? AND id <> ? AND status NOT IN ?", sm.ScrapePathId, MediaTypeMovie, sm.TmdbId, sm.ID, []ScrapeMediaStatus{ScrapeMediaStatusIgnore, ScrapeMediaStatusScrapeFailed, ScrapeMediaStatusNeedsReview}).Order("id asc").Find(&scrapeMediaFiles).Error; err != nil {
+		helpers.AppLogger.Errorf("查询相同TMDB ID的电影文件失败: tmdb_id=%d %v", sm.TmdbId, err)
+		return nil
+	}
+	return DecodeScrapeMediaFile(scrapeMediaFiles)
+}
+
+// 返回分辨率名称，例如：2160p
+// 优先使用ffprobe提取的分辨率，没有则从文件名中提取
+func (sm *ScrapeMediaFile) GetResolutionName() string {
+	if sm.Resolution != "" {
+		return sm.Resolution
+	}
+	if match := versionResolutionRegexp.FindStringSubmatch(sm.VideoFilename); len(match) > 1 {
+		return match[1] + "p"
+	}
+	if version4KRegexp.MatchString(sm.VideoFilename) {
+		return "2160p"
+	}
+	return ""
+}
+
+// 返回分辨率高度，用于比较版本，无法识别返回0
+func (sm *ScrapeMediaFile) GetResolutionHeight() int {
+	height, err := strconv.Atoi(strings.TrimSuffix(sm.GetResolutionName(), "p"))
+	if err != nil {
+		return 0
+	}
+	return height
+}
+
+// 生成多版本标签，例如：2160p、1080p HDR
+// 和其他版本标签重复时追加文件ID，保证同一文件夹内文件名不冲突
+func (sm *ScrapeMediaFile) MakeVersionLabel(others []*ScrapeMediaFile) string {
+	label := sm.GetResolutionName()
+	if label == "" {
+		label = "Version"
+	}
+	if sm.IsHDR {
+		label += " HDR"
+	}
+	for _, other := range others {
+		if other.VersionLabel == label {
+			return fmt.Sprintf("%s %d", label, sm.ID)
+		}
+	}
+	return label
+}
+
+// 比较两个版本的优劣，a更好返回1，b更好返回-1
+// 依次比较分辨率、HDR、视频码率，都相同时ID小的（先入库的）更好
+func CompareMovieVersion(a, b *ScrapeMediaFile) int {
+	if ah, bh := a.GetResolutionHeight(), b.GetResolutionHeight(); ah != bh {
+		if ah > bh {
+			return 1
+		}
+		return -1
+	}
+	if a.IsHDR != b.IsHDR {
+		if a.IsHDR {
+			return 1
+		}
+		return -1
+	}
+	var ab, bb int64
+	if a.VideoCodec != nil {
+		ab = a.VideoCodec.Bitrate
+	}
+	if b.VideoCodec != nil {
+		bb = b.VideoCodec.Bitrate
+	}
+	if ab != bb {
+		if ab > bb {
+			return 1
+		}
+		return -1
+	}
+	if a.ID < b.ID {
+		return 1
+	}
+	if a.ID > b.ID {
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,70 @@
+package models
+
+import "testing"
+
+func TestGetResolutionName(t *testing.T) {
+	tests := []struct {
+		name     string
+		sm       *ScrapeMediaFile
+		expected string
+	}{
+		{
+			name:     "ffprobe分辨率",
+			sm:       &ScrapeMediaFile{Resolution: "2160p", VideoFilename: "Interstellar.2014.1080p.mkv"},
+			expected: "2160p",
+		},
+		{
+			name:     "文件名分辨率",
+			sm:       &ScrapeMediaFile{VideoFilename: "Interstellar.2014.1080p.BluRay.x264.mkv"},
+			expected: "1080p",
+		},
+		{
+			name:     "文件名4K",
+			sm:       &ScrapeMediaFile{VideoFilename: "星际穿越.4K.mkv"},
+			expected: "2160p",
+		},
+		{
+			name:     "无法识别",
+			sm:       &ScrapeMediaFile{VideoFilename: "星际穿越.mkv"},
+			expected: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.sm.GetResolutionName(); result != tt.expected {
+				t.Errorf("期望: %s, 实际: %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestMakeVersionLabel(t *testing.T) {
+	sm := &ScrapeMediaFile{BaseModel: BaseModel{ID: 12}, Resolution: "2160p", IsHDR: true}
+	if label := sm.MakeVersionLabel(nil); label != "2160p HDR" {
+		t.Errorf("期望: 2160p HDR, 实际: %s", label)
+	}
+	others := []*ScrapeMediaFile{{VersionLabel: "2160p HDR"}}
+	if label := sm.MakeVersionLabel(others); label != "2160p HDR 12" {
+		t.Errorf("期望: 2160p HDR 12, 实际: %s", label)
+	}
+}
+
+func TestCompareMovieVersion(t *testing.T) {
+	uhd := &ScrapeMediaFile{BaseModel: BaseModel{ID: 2}, Resolution: "2160p"}
+	fhd := &ScrapeMediaFile{BaseModel: BaseModel{ID: 1}, Resolution: "1080p", IsHDR: true}
+	if CompareMovieVersion(uhd, fhd) != 1 {
+		t.Errorf("分辨率高的版本应该更好")
+	}
+	hdr := &ScrapeMediaFile{BaseModel: BaseModel{ID: 3}, Resolution: "2160p", IsHDR: true}
+	if CompareMovieVersion(uhd, hdr) != -1 {
+		t.Errorf("分辨率相同时HDR版本应该更好")
+	}
+	high := &ScrapeMediaFile{BaseModel: BaseModel{ID: 4}, Resolution: "2160p", VideoCodec: &VideoCodec{Bitrate: 40000000}}
+	if CompareMovieVersion(high, uhd) != 1 {
+		t.Errorf("分辨率和HDR相同时码率高的版本应该更好")
+	}
+	same := &ScrapeMediaFile{BaseModel: BaseModel{ID: 5}, Resolution: "2160p"}
+	if CompareMovieVersion(uhd, same) != 1 {
+		t.Errorf("完全相同时先入库的版本应该更好")
+	}
+}
@@ -55,6 +55,15 @@ const (
 	ScrapeTypeOnlyRename      ScrapeType = "only_rename"       // 仅整理
 )
 
+type MultiVersionPolicy string
+
+const (
+	MultiVersionPolicyKeepAll  MultiVersionPolicy = "keep_all"  // 保留所有版本，按多版本规则命名
+	MultiVersionPolicyKeepBest MultiVersionPolicy = "keep_best" // 只保留最佳版本，其余版本移动到待定目录
+)
+
+const DEFAULT_MULTI_VERSION_HOLD_PATH = "多版本待定"
+
 var SubtitleExtArr = []string{".ass", ".srt", ".ssa", ".vtt", ".sup", ".idx", ".sub"}
 var ImageExtArr = []string{".jpg", ".png", ".jpeg", ".gif"}
 var AllowdExtArr = append(SubtitleExtArr, append(ImageExtArr, []string{".nfo", ".mp3", ".flac", ".aas"}...)...)
@@ -77,6 +86,8 @@ type ScrapePath struct {
 	SourcePathId          string                       `json:"source_path_id" form:"source_path_id"`                     // 源路径ID，如果是115则是FileId，如果是Local则为空字符串，如果是openlist则是远程路径ID
 	DestPath              string                       `json:"dest_path" form:"dest_path"`                               // 目标路径，绝对路径
 	DestPathId            string                       `json:"dest_path_id" form:"dest_path_id"`                         // 目标路径ID，如果是115则是FileId，如果是Local则为空字符串，如果是openlist则是远程路径ID
+	DestAccountId         uint                         `json:"dest_account_id" form:"dest_account_id"`                   // 目标路径的账号ID，为0时和来源相同
+	DestSourceType        SourceType                   `json:"dest_source_type" form:"dest_source_type"`                 // 目标路径类型，为空时和来源相同，和来源不同时整理阶段通过上传队列转移文件
 	ScrapeType            ScrapeType                   `json:"scrape_type" form:"scrape_type"`                           // 刮削类型
 	RenameType            RenameType                   `json:"rename_type" form:"rename_type"`                           // 重命名类型，非本地仅支持移动重命名
 	FolderNameTemplate    string                       `json:"folder_name_template" form:"folder_name_template"`         // 文件夹名称模板，支持{{title}}、{{year}}、{{season}}、{{episode}}
@@ -98,13 +109,23 @@ type ScrapePath struct {
 	NextCronRun           string                       `json:"next_cron_run" form:"next_cron_run"`                       // 下次执行时间
 	CronEnabled           int                          `json:"cron_enabled" form:"cron_enabled"`                         // 定时任务启用状态（0/1）
 	EnableFanartTv        bool                         `json:"enable_fanart_tv" form:"enable_fanart_tv"`                 // 是否启用 fanart.tv，开启时会从 fanart.tv 下载高清图
+	EnableSubtitle        bool                         `json:"enable_subtitle" form:"enable_subtitle"`                   // 是否下载字幕，开启时会在没有中文字幕的情况下从字幕提供者下载
 	IsScraping            bool                         `json:"is_scraping" form:"is_scraping"`                           // 是否正在刮削
 	MaxThreads            int                          `json:"max_threads" form:"max_threads"`                           // 刮削最大线程数，默认值为5
+	MultiVersionPolicy    MultiVersionPolicy           `json:"multi_version_policy" form:"multi_version_policy"`         // 同一电影存在多个版本时的处理策略，默认保留所有版本
+	MultiVersionHoldPath  string                       `json:"multi_version_hold_path" form:"multi_version_hold_path"`   // 只保留最佳版本时，其余版本存放的目录，相对于目标路径，默认：多版本待定
+	ReviewConfidence      int                          `json:"review_confidence" form:"review_confidence"`               // 电影识别置信度低于该值时进入待确认状态，不整理，0表示不启用
+	NamingPreset          string                       `json:"naming_preset" form:"naming_preset"`                       // 命名预设，emby、jellyfin、plex、kodi，为空时使用自定义模板
+	IncrementalScan       bool                         `json:"incremental_scan" form:"incremental_scan"`                 // 是否增量扫描，开启时跳过上次扫描后没有变化的目录，目前只支持115
 	V115Client            *v115open.OpenClient         `json:"-" gorm:"-"`                                               // 115客户端
 	BaiduPanClient        *baidupan.Client             `json:"-" gorm:"-"`                                               // 百度网盘客户端
 	OpenListClient        *openlist.Client             `json:"-" gorm:"-"`                                               // openlist客户端
+	DestV115Client        *v115open.OpenClient         `json:"-" gorm:"-"`                                               // 目标路径的115客户端，和来源相同时就是V115Client
+	DestBaiduPanClient    *baidupan.Client             `json:"-" gorm:"-"`                                               // 目标路径的百度网盘客户端
+	DestOpenListClient    *openlist.Client             `json:"-" gorm:"-"`                                               // 目标路径的openlist客户端
 	ExistsFiles           map[string]bool              `json:"-" gorm:"-"`                                               // 已存在的文件，key为文件路径，value为是否存在
 	ScrapeRootPath        string                       `json:"-" gorm:"-"`                                               // 刮削根路径
+	RunNo                 string                       `json:"-" gorm:"-"`                                               // 本次刮削的批次号，整理操作日志按批次号分组
 	Category              ScrapePathCategoryCollection `json:"-" gorm:"-"`
 	CategoryMap           map[uint]string              `json:"-" gorm:"-"`
 	// 完成的电视剧缓存，每次启动整理时清除，防止多次操作电视剧完成
@@ -172,6 +193,23 @@ func (m *ScrapePath) Save() error {
 		m.DeletedKeyword = ""
 	}
 
+	// 选择了命名预设时使用预设的模板
+	if err := m.ApplyNamingPreset(); err != nil {
+		return err
+	}
+	// 增量扫描依赖115目录详情中的文件和文件夹总数，其他来源不支持
+	if m.IncrementalScan {
+		sourceType := m.SourceType
+		if m.ID > 0 {
+			if old := GetScrapePathByID(m.ID); old != nil {
+				sourceType = old.SourceType
+			}
+		}
+		if sourceType != SourceType115 {
+			return fmt.Errorf("增量扫描目前只支持115网盘")
+		}
+	}
+
 	// 处理 cron 相关字段
 	if m.CronExpression != "" {
 		// 解析 cron 表达式为描述
@@ -203,7 +241,7 @@ func (m *ScrapePath) Save() error {
 		isUpdateCategory := false
 		oldScrapePath := GetScrapePathByID(m.ID)
 		if oldScrapePath != nil {
-			if oldScrapePath.DestPathId != m.DestPathId {
+			if oldScrapePath.DestPathId != m.DestPathId || oldScrapePath.GetDestAccountId() != m.GetDestAccountId() {
 				isUpdateCategory = true
 			}
 		}
@@ -226,6 +264,8 @@ func (m *ScrapePath) Save() error {
 			"source_path_id":           m.SourcePathId,
 			"dest_path":                m.DestPath,
 			"dest_path_id":             m.DestPathId,
+			"dest_account_id":          m.DestAccountId,
+			"dest_source_type":         m.DestSourceType,
 			"file_name_template":       m.FileNameTemplate,
 			"folder_name_template":     m.FolderNameTemplate,
 			"deleted_keyword":          m.DeletedKeyword,
@@ -237,11 +277,17 @@ func (m *ScrapePath) Save() error {
 			"exclude_no_image_actor":   m.ExcludeNoImageActor,
 			"force_delete_source_path": m.ForceDeleteSourcePath,
 			"enable_fanart_tv":         m.EnableFanartTv,
+			"enable_subtitle":          m.EnableSubtitle,
 			"max_threads":              m.MaxThreads,
 			"enable_cron":              m.EnableCron,
 			"cron_expression":          m.CronExpression,
 			"cron_description":         m.CronDescription,
 			"cron_enabled":             m.CronEnabled,
+			"multi_version_policy":     m.MultiVersionPolicy,
+			"multi_version_hold_path":  m.MultiVersionHoldPath,
+			"review_confidence":        m.ReviewConfidence,
+			"incremental_scan":         m.IncrementalScan,
+			"naming_preset":            m.NamingPreset,
 		}
 
 		// 如果提供了 cron 表达式，则更新 next_cron_run
@@ -260,6 +306,10 @@ func (m *ScrapePath) Save() error {
 			helpers.AppLogger.Errorf("更新刮削目录失败: %v", err)
 			return err
 		}
+		// 改变了来源目录，上次扫描的目录状态失效
+		if oldScrapePath != nil && oldScrapePath.SourcePathId != m.SourcePathId {
+			ClearScrapeScanDirs(m.ID)
+		}
 		// 如果改变了目标目录，则需要重建Category
 		if isUpdateCategory {
 			// 将ScrapePathCategory表相关的FileID字段清空
@@ -273,6 +323,41 @@ func (sp *ScrapePath) GetAccount() (*Account, error) {
 	return GetAccountById(sp.AccountId)
 }
 
+// 目标路径的类型，没有单独设置时和来源相同
+func (sp *ScrapePath) GetDestSourceType() SourceType {
+	if sp.DestSourceType == "" {
+		return sp.SourceType
+	}
+	return sp.DestSourceType
+}
+
+// 目标路径的账号ID，没有单独设置时和来源相同
+func (sp *ScrapePath) GetDestAccountId() uint {
+	if sp.GetDestSourceType() == SourceTypeLocal {
+		return 0
+	}
+	if sp.DestAccountId == 0 {
+		return sp.AccountId
+	}
+	return sp.DestAccountId
+}
+
+func (sp *ScrapePath) GetDestAccount() (*Account, error) {
+	return GetAccountById(sp.GetDestAccountId())
+}
+
+// 是否跨存储整理：目标路径和来源不在同一个网盘账号（或者一个是本地一个是网盘）
+// 仅刮削时元数据写回来源目录，不算跨存储
+func (sp *ScrapePath) IsCrossStorage() bool {
+	if sp.ScrapeType == ScrapeTypeOnly {
+		return false
+	}
+	if sp.GetDestSourceType() != sp.SourceType {
+		return true
+	}
+	return sp.SourceType != SourceTypeLocal && sp.GetDestAccountId() != sp.AccountId
+}
+
 type ScrapeMediaResult struct {
 	TaskID int
 	Result []*ScrapeFile
@@ -323,6 +408,14 @@ func (sp *ScrapePath) GetMaxThreads() int {
 	return sp.MaxThreads
 }
 
+// 多版本待定目录，相对于目标路径
+func (sp *ScrapePath) GetMultiVersionHoldPath() string {
+	if sp.MultiVersionHoldPath == "" {
+		return DEFAULT_MULTI_VERSION_HOLD_PATH
+	}
+	return sp.MultiVersionHoldPath
+}
+
 func (sp *ScrapePath) IsVideoFile(filename string) bool {
 	ext := strings.ToLower(filepath.Ext(filename))
 	for _, videoExt := range sp.VideoExtList {
@@ -374,7 +467,7 @@ func (sp *ScrapePath) MakeScrapeMediaFile(path, pathId, fileName, fileId, pickCo
 
 // 将本地临时文件移动到本地目标路径
 func (sp *ScrapePath) MoveLocalTempFileToDest(files []map[string]string) (bool, error) {
-	if sp.SourceType != SourceTypeLocal {
+	if sp.GetDestSourceType() != SourceTypeLocal {
 		return true, fmt.Errorf("非本地文件刮削，无法移动到目标位置")
 	}
 	for _, file := range files {
@@ -517,17 +610,17 @@ func (sp *ScrapePath) GenerateCategory() {
 		fileId := ""
 		var err error
 		// 创建目录
-		// 根据SourceType不同,调用各自接口创建目录
-		switch sp.SourceType {
+		// 根据目标路径的类型不同,调用各自接口创建目录
+		switch sp.GetDestSourceType() {
 		case SourceType115:
 			// 先查询是否存在
 			categoryPath := filepath.Join(sp.DestPath, category.Name)
-			detail, detailErr := sp.V115Client.GetFsDetailByPath(context.Background(), categoryPath)
+			detail, detailErr := sp.DestV115Client.GetFsDetailByPath(context.Background(), categoryPath)
 			if detail != nil && detailErr == nil && detail.FileId != "" {
 				helpers.AppLogger.Infof("目录 %s 已存在, 目录ID=%s, 返回值:%+v", categoryPath, detail.FileId, detail)
 				fileId = detail.FileId
 			} else {
-				fileId, err = sp.V115Client.MkDir(context.Background(), sp.DestPathId, category.Name)
+				fileId, err = sp.DestV115Client.MkDir(context.Background(), sp.DestPathId, category.Name)
 				if err != nil {
 					helpers.AppLogger.Errorf("创建115目录失败: %v", err)
 					continue
@@ -535,7 +628,7 @@ func (sp *ScrapePath) GenerateCategory() {
 			}
 		case SourceTypeOpenList:
 			fileId = sp.DestPathId + "/" + category.Name
-			err = sp.OpenListClient.Mkdir(fileId)
+			err = sp.DestOpenListClient.Mkdir(fileId)
 			if err != nil {
 				helpers.AppLogger.Errorf("创建OpenList目录失败: %v", err)
 				continue
@@ -547,9 +640,9 @@ func (sp *ScrapePath) GenerateCategory() {
 		case SourceTypeBaiduPan:
 			fileId = sp.DestPathId + "/" + category.Name
 			// 先查询是否存在
-			exists, _ := sp.BaiduPanClient.PathExists(context.Background(), fileId)
+			exists, _ := sp.DestBaiduPanClient.PathExists(context.Background(), fileId)
 			if !exists {
-				err = sp.BaiduPanClient.Mkdir(context.Background(), fileId)
+				err = sp.DestBaiduPanClient.Mkdir(context.Background(), fileId)
 				if err != nil {
 					helpers.AppLogger.Errorf("创建百度网盘目录失败: %v", err)
 					continue
@@ -921,6 +1014,115 @@ func (sp *ScrapePath) GetSyncPathByPath(path string) *SyncPath {
 	return nil
 }
 
+// 是否可以直接使用STRM同步发现的新文件作为待刮削记录，不需要再扫描一遍来源目录
+// 只有115和本地目录的文件ID和刮削扫描时一致；其他类型仅整理需要同目录的nfo和图片，仍然走完整扫描
+func (sp *ScrapePath) CanEnqueueFromSync(syncPath *SyncPath) bool {
+	if syncPath == nil || sp.SourceType != syncPath.SourceType || sp.AccountId != syncPath.AccountId {
+		return false
+	}
+	if sp.SourceType != SourceType115 && sp.SourceType != SourceTypeLocal {
+		return false
+	}
+	if sp.MediaType == MediaTypeOther && sp.ScrapeType == ScrapeTypeOnlyRename {
+		return false
+	}
+	return true
+}
+
+// 将STRM同步新增的视频文件直接作为待刮削记录入库，返回入库的数量以及是否还需要完整扫描
+// 只处理在刮削来源目录下的文件，字幕从同步记录中同目录的文件匹配
+// 蓝光原盘需要按目录扫描才能确定主影片流，有新增的原盘时需要完整扫描
+func (sp *ScrapePath) EnqueueSyncFiles(files []*SyncFile) (int, bool) {
+	batchNo := time.Now().Format("20060102150405000")
+	waitSaveFiles := make([]*ScrapeMediaFile, 0)
+	total := 0
+	needScan := false
+	for _, file := range files {
+		if !file.IsVideo || !sp.IsVideoFile(file.FileName) {
+			continue
+		}
+		if !IsSubPath(sp.SourcePath, file.Path) {
+			continue
+		}
+		if IsBlurayStreamPath(file.Path) {
+			needScan = true
+			continue
+		}
+		if !sp.CheckFileIsAllowed(file.FileName, file.FileSize) {
+			continue
+		}
+		if CheckExistsFileIdAndName(file.FileId, sp.ID) {
+			continue
+		}
+		if extra := sp.MakeScrapeExtraFile(file.Path, file.ParentId, file.FileName, file.FileId, file.PickCode); extra != nil {
+			SaveScrapeExtraFile(extra)
+			continue
+		}
+		mediaFile := sp.MakeScrapeMediaFile(file.Path, file.ParentId, file.FileName, file.FileId, file.PickCode)
+		if IsIsoFile(file.FileName) && sp.MediaType == MediaTypeMovie {
+			mediaFile.DiscType = DiscTypeIso
+			if file.ParentId != sp.SourcePathId {
+				mediaFile.DiscPath = file.Path
+				mediaFile.DiscPathId = file.ParentId
+			}
+		}
+		if subFiles := getSyncSubtitleFiles(file); len(subFiles) > 0 {
+			mediaFile.SubtitleFileJson = helpers.JsonString(subFiles)
+		}
+		if sp.MediaType == MediaTypeTvShow {
+			mediaFile.BatchNo = batchNo
+			if err := mediaFile.ExtractSeasonEpisode(sp); err != nil {
+				helpers.AppLogger.Errorf("提取季和集序号失败, 文件名: %s, 失败原因: %v", mediaFile.VideoFilename, err)
+				continue
+			}
+		}
+		mediaFile.Status = ScrapeMediaStatusScanned
+		mediaFile.ScanTime = time.Now().Unix()
+		waitSaveFiles = append(waitSaveFiles, mediaFile)
+		if len(waitSaveFiles) > 100 {
+			db.Db.Save(waitSaveFiles)
+			total += len(waitSaveFiles)
+			waitSaveFiles = []*ScrapeMediaFile{}
+		}
+	}
+	if len(waitSaveFiles) > 0 {
+		db.Db.Save(waitSaveFiles)
+		total += len(waitSaveFiles)
+	}
+	return total, needScan
+}
+
+// 从同步记录中查找和视频文件同目录、同名开头的字幕文件
+func getSyncSubtitleFiles(videoFile *SyncFile) []*MediaMetaFiles {
+	var files []*SyncFile
+	if err := db.Db.Where("sync_path_id = ? AND parent_id = ? AND is_video = ?", videoFile.SyncPathId, videoFile.ParentId, false).Find(&files).Error; err != nil {
+		return nil
+	}
+	baseName := strings.TrimSuffix(videoFile.FileName, filepath.Ext(videoFile.FileName))
+	subFiles := make([]*MediaMetaFiles, 0)
+	for _, file := range files {
+		if !slices.Contains(SubtitleExtArr, filepath.Ext(file.FileName)) || !strings.HasPrefix(file.FileName, baseName) {
+			continue
+		}
+		subFiles = append(subFiles, &MediaMetaFiles{
+			FileName: file.FileName,
+			FileId:   file.FileId,
+			PickCode: file.PickCode,
+		})
+	}
+	return subFiles
+}
+
+// 判断路径是否在目录下（包括目录本身），忽略开头和结尾的/
+func IsSubPath(dir, path string) bool {
+	dir = strings.Trim(filepath.ToSlash(dir), "/")
+	path = strings.Trim(filepath.ToSlash(path), "/")
+	if dir == "" {
+		return true
+	}
+	return path == dir || strings.HasPrefix(path, dir+"/")
+}
+
 func (sp *ScrapePath) Decode() error {
 	// 解码json字符串
 	if sp.VideoExt != "" {
@@ -1036,6 +1238,18 @@ func DeleteScrapePath(id uint) error {
 		helpers.AppLogger.Errorf("删除刮削目录文件失败: %v", err)
 		return err
 	}
+	// 删除未整理的附加内容记录
+	if err := db.Db.Where("scrape_path_id = ? AND status <> ?", id, ScrapeExtraStatusRenamed).Delete(&ScrapeExtraFile{}).Error; err != nil {
+		helpers.AppLogger.Errorf("删除附加内容记录失败: %v", err)
+		return err
+	}
+	// 删除增量扫描的目录状态
+	ClearScrapeScanDirs(id)
+	// 删除镜像整理的链接记录，已创建的链接保留
+	if err := db.Db.Where("scrape_path_id = ?", id).Delete(&ScrapeLink{}).Error; err != nil {
+		helpers.AppLogger.Errorf("删除链接记录失败: %v", err)
+		return err
+	}
 	// 删除所有media / mediaSeason / mediaEpisode
 	if err := db.Db.Where("scrape_path_id = ?", id).Delete(&Media{}).Error; err != nil {
 		helpers.AppLogger.Errorf("删除Media失败: %v", err)
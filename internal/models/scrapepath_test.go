@@ -0,0 +1,76 @@
+package models
+
+import "testing"
+
+func TestScrapePathIsCrossStorage(t *testing.T) {
+	tests := []struct {
+		name     string
+		sp       *ScrapePath
+		expected bool
+		destId   uint
+	}{
+		{
+			name:     "目标和来源相同",
+			sp:       &ScrapePath{AccountId: 1, SourceType: SourceType115, ScrapeType: ScrapeTypeScrapeAndRename},
+			expected: false,
+			destId:   1,
+		},
+		{
+			name:     "同类型不同账号",
+			sp:       &ScrapePath{AccountId: 1, SourceType: SourceType115, DestAccountId: 2, ScrapeType: ScrapeTypeScrapeAndRename},
+			expected: true,
+			destId:   2,
+		},
+		{
+			name:     "本地到115",
+			sp:       &ScrapePath{SourceType: SourceTypeLocal, DestAccountId: 2, DestSourceType: SourceType115, ScrapeType: ScrapeTypeOnlyRename},
+			expected: true,
+			destId:   2,
+		},
+		{
+			name:     "百度网盘到本地",
+			sp:       &ScrapePath{AccountId: 3, SourceType: SourceTypeBaiduPan, DestSourceType: SourceTypeLocal, ScrapeType: ScrapeTypeScrapeAndRename},
+			expected: true,
+			destId:   0,
+		},
+		{
+			name:     "仅刮削不算跨存储",
+			sp:       &ScrapePath{AccountId: 1, SourceType: SourceType115, DestAccountId: 2, ScrapeType: ScrapeTypeOnly},
+			expected: false,
+			destId:   2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.sp.IsCrossStorage(); result != tt.expected {
+				t.Errorf("跨存储期望: %v, 实际: %v", tt.expected, result)
+			}
+			if id := tt.sp.GetDestAccountId(); id != tt.destId {
+				t.Errorf("目标账号期望: %d, 实际: %d", tt.destId, id)
+			}
+		})
+	}
+}
+
+func TestScrapePathCanEnqueueFromSync(t *testing.T) {
+	syncPath := &SyncPath{SourceType: SourceType115, AccountId: 1}
+	tests := []struct {
+		name     string
+		sp       *ScrapePath
+		expected bool
+	}{
+		{name: "同账号的115电影", sp: &ScrapePath{AccountId: 1, SourceType: SourceType115, MediaType: MediaTypeMovie}, expected: true},
+		{name: "不同账号", sp: &ScrapePath{AccountId: 2, SourceType: SourceType115, MediaType: MediaTypeMovie}, expected: false},
+		{name: "其他类型仅整理需要扫描nfo", sp: &ScrapePath{AccountId: 1, SourceType: SourceType115, MediaType: MediaTypeOther, ScrapeType: ScrapeTypeOnlyRename}, expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.sp.CanEnqueueFromSync(syncPath); result != tt.expected {
+				t.Errorf("期望: %v, 实际: %v", tt.expected, result)
+			}
+		})
+	}
+	if !IsSubPath("电影/新片", "/电影/新片/阿凡达 (2009)") || IsSubPath("电影/新片", "电影/新片2") {
+		t.Errorf("子路径判断错误")
+	}
+}
@@ -277,7 +277,15 @@ func InitNotificationManager() {
 	if err := enhancedManager.LoadChannels(); err != nil {
 		helpers.AppLogger.Warnf("加载通知渠道失败: %v", err)
 	}
+	// 汇总通知中显示下载、上传队列的积压数量
+	enhancedManager.SetBacklogProvider(func() map[string]int64 {
+		var downloads, uploads int64
+		db.Db.Model(&DbDownloadTask{}).Where("status IN ?", []DownloadStatus{DownloadStatusPending, DownloadStatusDownloading}).Count(&downloads)
+		db.Db.Model(&DbUploadTask{}).Where("status IN ?", []UploadStatus{UploadStatusPending, UploadStatusUploading}).Count(&uploads)
+		return map[string]int64{"下载": downloads, "上传": uploads}
+	})
 	notificationmanager.GlobalEnhancedNotificationManager = enhancedManager
+	enhancedManager.StartQueue()
 }
 
 // GetFileListPageSize 获取115文件列表每页查询数量
@@ -89,6 +89,8 @@ func (s *Sync) Complete(sourceType SourceType) bool {
 			Content:   fmt.Sprintf("📊 耗时: %s, 生成STRM: %s, 下载: %s, 上传: %s\n⏰ 时间: %s", s.GetDuration(), helpers.IntToString(s.NewStrm), helpers.IntToString(s.NewMeta), helpers.IntToString(s.NewUpload), time.Now().Format("2006-01-02 15:04:05")),
 			Timestamp: time.Now(),
 			Priority:  NormalPriority,
+			SyncPath:  s.RemotePath,
+			Digest:    &DigestData{NewStrm: s.NewStrm, NewMeta: s.NewMeta, NewUpload: s.NewUpload},
 		}
 		if notificationmanager.GlobalEnhancedNotificationManager != nil {
 			if err := notificationmanager.GlobalEnhancedNotificationManager.SendNotification(ctx, notif); err != nil {
@@ -113,6 +115,8 @@ func (s *Sync) Failed(reason string) {
 		Content:   fmt.Sprintf("🔍 错误: %s\n⏰ 时间: %s", reason, time.Now().Format("2006-01-02 15:04:05")),
 		Timestamp: time.Now(),
 		Priority:  HighPriority,
+		DedupKey:  fmt.Sprintf("sync_error:%d:%s", s.SyncPathId, reason), // 同一个同步目录同样的错误短时间内只通知一次
+		SyncPath:  s.RemotePath,
 	}
 	if notificationmanager.GlobalEnhancedNotificationManager != nil {
 		if err := notificationmanager.GlobalEnhancedNotificationManager.SendNotification(ctx, notif); err != nil {
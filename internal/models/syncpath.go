@@ -493,3 +493,10 @@ func GetAllSyncPathByAccountId(accountId uint) []SyncPath {
 	db.Db.Where("account_id = ?", accountId).Find(&syncPaths)
 	return syncPaths
 }
+
+// 获取所有同步路径
+func GetAllSyncPaths() []SyncPath {
+	var syncPaths []SyncPath
+	db.Db.Order("id ASC").Find(&syncPaths)
+	return syncPaths
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"time"
+)
+
+// TMDB接口响应缓存，重新刮削和离线模式使用
+type TmdbCache struct {
+	BaseModel
+	CacheKey string `json:"cache_key" gorm:"type:varchar(512);uniqueIndex"` // 接口地址 + 查询参数（包含语言）
+	Data     string `json:"-" gorm:"type:text"`                             // 响应json字符串
+}
+
+func (*TmdbCache) TableName() string {
+	return "tmdb_cache"
+}
+
+// 实现tmdb.Cache接口，数据保存到数据库
+type TmdbDbCache struct{}
+
+func (TmdbDbCache) Get(key string) ([]byte, int64, bool) {
+	var cache TmdbCache
+	if err := db.Db.Where("cache_key = ?", key).Limit(1).Find(&cache).Error; err != nil {
+		helpers.TMDBLog.Errorf("查询TMDB缓存失败 %s: %v", key, err)
+		return nil, 0, false
+	}
+	if cache.ID == 0 {
+		return nil, 0, false
+	}
+	return []byte(cache.Data), cache.UpdatedAt, true
+}
+
+func (TmdbDbCache) Set(key string, data []byte) {
+	var cache TmdbCache
+	db.Db.Where("cache_key = ?", key).Limit(1).Find(&cache)
+	if cache.ID > 0 {
+		updateData := make(map[string]interface{})
+		updateData["data"] = string(data)
+		updateData["updated_at"] = time.Now().Unix()
+		if err := db.Db.Model(&TmdbCache{}).Where("id = ?", cache.ID).Updates(updateData).Error; err != nil {
+			helpers.TMDBLog.Errorf("更新TMDB缓存失败 %s: %v", key, err)
+		}
+		return
+	}
+	cache.CacheKey = key
+	cache.Data = string(data)
+	if err := db.Db.Create(&cache).Error; err != nil {
+		// 多个刮削线程同时写入同一个键时会失败，不影响使用
+		helpers.TMDBLog.Warnf("写入TMDB缓存失败 %s: %v", key, err)
+	}
+}
+
+// 缓存的接口数量
+func GetTmdbCacheCount() int64 {
+	var total int64
+	if err := db.Db.Model(&TmdbCache{}).Count(&total).Error; err != nil {
+		helpers.AppLogger.Errorf("查询TMDB缓存数量失败: %v", err)
+	}
+	return total
+}
+
+// 清空TMDB缓存
+func ClearTmdbCache() error {
+	if err := db.Db.Exec("DELETE FROM tmdb_cache").Error; err != nil {
+		helpers.AppLogger.Errorf("清空TMDB缓存失败: %v", err)
+		return err
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"Q115-STRM/internal/helpers"
+	ws "Q115-STRM/internal/websocket"
 	"sync"
 	"time"
 )
@@ -49,6 +50,7 @@ func (uq *UQ) Start() {
 	// 重新创建tasks通道和results通道
 	uq.tasks = make(chan *DbUploadTask, uq.numWorkers)
 	uq.mutex.Unlock()
+	ws.BroadcastEvent(ws.EventQueueStateChange, ws.QueueStateData{Queue: ws.QueueUpload, Running: true})
 	// 启动工作协程
 	for i := 0; i < uq.numWorkers; i++ {
 		go uq.worker()
@@ -170,6 +172,7 @@ func (uq *UQ) Stop() {
 	}
 	uq.running = false
 	uq.mutex.Unlock()
+	ws.BroadcastEvent(ws.EventQueueStateChange, ws.QueueStateData{Queue: ws.QueueUpload, Running: false})
 
 	// 关闭tasks通道
 	close(uq.tasks)
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/webhook"
+)
+
+// WebhookSubscription 自动化Webhook订阅 - 别名供models包使用
+type WebhookSubscription = webhook.Subscription
+
+// WebhookDelivery 自动化Webhook投递记录 - 别名供models包使用
+type WebhookDelivery = webhook.Delivery
+
+// InitWebhook 初始化并启动Webhook发送队列
+func InitWebhook() {
+	dispatcher := webhook.NewDispatcher(db.Db)
+	webhook.GlobalDispatcher = dispatcher
+	dispatcher.Start()
+}
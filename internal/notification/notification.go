@@ -1,6 +1,10 @@
 package notification
 
-import "time"
+import (
+	"slices"
+	"strings"
+	"time"
+)
 
 // NotificationChannel 通知渠道基础配置
 type NotificationChannel struct {
@@ -18,7 +22,7 @@ type TelegramChannelConfig struct {
 	ID        uint   `json:"id" gorm:"primaryKey"`
 	ChannelID uint   `json:"channel_id" gorm:"uniqueIndex:idx_telegram_channel"`
 	BotToken  string `json:"bot_token"`
-	ChatID    string `json:"chat_id"`
+	ChatID    string `json:"chat_id"` // 接收通知和命令的聊天，可以是私聊或者群组，多个用逗号分隔
 	ProxyURL  string `json:"proxy_url"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
@@ -62,8 +66,101 @@ type NotificationRule struct {
 	ChannelID uint   `json:"channel_id" gorm:"index"`
 	EventType string `json:"event_type" gorm:"index"`
 	IsEnabled bool   `json:"is_enabled" gorm:"default:true"`
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	// 过滤条件，为空表示不过滤，多个值用英文逗号分隔，满足其中一个即可
+	SyncPaths   string               `json:"sync_paths"`   // 只通知这些同步路径（包括子路径）
+	Libraries   string               `json:"libraries"`    // 只通知这些Emby媒体库
+	Categories  string               `json:"categories"`   // 只通知这些刮削分类
+	Users       string               `json:"users"`        // 只通知这些用户的播放
+	MinPriority NotificationPriority `json:"min_priority"` // 只通知不低于该优先级的通知
+	// 标题和内容模板，为空时使用原标题和内容，可以使用 {{title}} {{content}} {{sync_path}} {{library}} {{category}} {{user}} 以及 Metadata 中的键
+	TitleTemplate string `json:"title_template"`
+	BodyTemplate  string `json:"body_template"`
+	// 汇总模式，为空时立即发送，否则在汇总时间把这段时间的通知合并成一条发送
+	DigestMode DigestMode `json:"digest_mode"`
+	DigestCron string     `json:"digest_cron"` // 汇总发送时间，cron表达式，为空时使用汇总模式的默认时间
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// DigestMode 通知汇总模式
+type DigestMode string
+
+const (
+	DigestNone   DigestMode = ""
+	DigestHourly DigestMode = "hourly" // 每小时整点
+	DigestDaily  DigestMode = "daily"  // 每天 9:00
+	DigestWeekly DigestMode = "weekly" // 每周一 9:00
+)
+
+// DigestSchedule 汇总发送时间的cron表达式，不是汇总模式时返回空
+func (r *NotificationRule) DigestSchedule() string {
+	if r.DigestMode == DigestNone {
+		return ""
+	}
+	if r.DigestCron != "" {
+		return r.DigestCron
+	}
+	switch r.DigestMode {
+	case DigestHourly:
+		return "0 * * * *"
+	case DigestWeekly:
+		return "0 9 * * 1"
+	default:
+		return "0 9 * * *"
+	}
+}
+
+// 优先级的大小，未知的优先级按普通处理
+func (p NotificationPriority) Level() int {
+	switch p {
+	case HighPriority:
+		return 3
+	case LowPriority:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Match 通知是否满足规则的过滤条件
+// 设置了过滤条件但通知没有对应的属性时不通知
+func (r *NotificationRule) Match(n *Notification) bool {
+	if r.MinPriority != "" && n.Priority.Level() < r.MinPriority.Level() {
+		return false
+	}
+	if paths := splitRuleValues(r.SyncPaths); len(paths) > 0 {
+		matched := false
+		for _, p := range paths {
+			p = strings.TrimRight(p, "/")
+			if n.SyncPath == p || strings.HasPrefix(n.SyncPath, p+"/") {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if libs := splitRuleValues(r.Libraries); len(libs) > 0 && !slices.Contains(libs, n.Library) {
+		return false
+	}
+	if categories := splitRuleValues(r.Categories); len(categories) > 0 && !slices.Contains(categories, n.Category) {
+		return false
+	}
+	if users := splitRuleValues(r.Users); len(users) > 0 && !slices.Contains(users, n.User) {
+		return false
+	}
+	return true
+}
+
+func splitRuleValues(s string) []string {
+	result := make([]string, 0)
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
 }
 
 // NotificationType 通知类型枚举
@@ -76,9 +173,11 @@ const (
 	SystemAlert    NotificationType = "system_alert"
 	MediaAdded     NotificationType = "media_added"
 	MediaRemoved   NotificationType = "media_removed"
-	PlaybackStart  NotificationType = "playback_start" // 播放开始
-	PlaybackPause  NotificationType = "playback_pause" // 播放暂停
-	PlaybackStop   NotificationType = "playback_stop"  // 播放停止
+	PlaybackStart  NotificationType = "playback_start"  // 播放开始
+	PlaybackPause  NotificationType = "playback_pause"  // 播放暂停
+	PlaybackStop   NotificationType = "playback_stop"   // 播放停止
+	EpisodeMissing NotificationType = "episode_missing" // 新播出的剧集缺失
+	DigestSummary  NotificationType = "digest"          // 汇总通知，由汇总模式的规则生成，不能配置规则
 )
 
 // AllNotificationTypes 所有通知类型，用于创建渠道时的默认规则
@@ -92,6 +191,7 @@ var AllNotificationTypes = []NotificationType{
 	PlaybackStart,
 	PlaybackPause,
 	PlaybackStop,
+	EpisodeMissing,
 }
 
 // NotificationPriority 通知优先级
@@ -113,6 +213,133 @@ type Notification struct {
 	Timestamp time.Time              `json:"timestamp"`
 	Priority  NotificationPriority   `json:"priority"`
 	Image     string                 `json:"image"`
+	// 图片是临时下载的文件，所有渠道发送完成或者失败后删除
+	RemoveImage bool   `json:"remove_image,omitempty"`
+	DedupKey    string `json:"dedup_key"` // 去重键，相同去重键的通知在去重时间内只发送一次，为空时使用类型+标题+内容
+	// 用于规则过滤和模板的属性，不在消息中显示
+	SyncPath string `json:"sync_path,omitempty"` // 相关的同步路径
+	Library  string `json:"library,omitempty"`   // 相关的Emby媒体库
+	Category string `json:"category,omitempty"`  // 刮削整理使用的分类
+	User     string `json:"user,omitempty"`      // 播放用户
+	// 汇总通知使用的数据，不在消息中显示
+	Digest *DigestData `json:"digest,omitempty"`
+}
+
+// DigestData 汇总通知需要的结构化数据
+type DigestData struct {
+	MediaType string   `json:"media_type,omitempty"` // movie | tvshow
+	MediaName string   `json:"media_name,omitempty"` // 电影或电视剧名称
+	Poster    string   `json:"poster,omitempty"`     // 海报的网络地址，汇总通知中每部影片显示自己的海报
+	Episodes  []string `json:"episodes,omitempty"`   // 入库的季集，如 S01E01-03
+	NewStrm   int      `json:"new_strm,omitempty"`   // 同步生成的STRM数量
+	NewMeta   int      `json:"new_meta,omitempty"`   // 同步下载的元数据数量
+	NewUpload int      `json:"new_upload,omitempty"` // 同步上传的文件数量
+}
+
+// NotificationLogStatus 通知发送状态
+type NotificationLogStatus string
+
+const (
+	NotificationLogPending    NotificationLogStatus = "pending"    // 等待发送或等待重试
+	NotificationLogSent       NotificationLogStatus = "sent"       // 已发送
+	NotificationLogFailed     NotificationLogStatus = "failed"     // 重试次数用完仍然失败
+	NotificationLogSuppressed NotificationLogStatus = "suppressed" // 去重时间内已发送过相同通知，不再发送
+)
+
+// NotificationLog 通知发送队列和发送记录，每个渠道一条
+type NotificationLog struct {
+	ID          uint                  `json:"id" gorm:"primaryKey"`
+	ChannelID   uint                  `json:"channel_id" gorm:"index"`
+	ChannelType string                `json:"channel_type"`
+	EventType   string                `json:"event_type" gorm:"index"`
+	Title       string                `json:"title"`
+	Content     string                `json:"content" gorm:"type:text"`
+	Payload     string                `json:"-" gorm:"type:text"`          // 通知的JSON，重试时使用
+	DedupKey    string                `json:"dedup_key" gorm:"index"`      // 去重键
+	Status      NotificationLogStatus `json:"status" gorm:"index"`         // 发送状态
+	Attempts    int                   `json:"attempts"`                    // 已尝试次数
+	NextRetryAt int64                 `json:"next_retry_at" gorm:"index"`  // 下次发送时间
+	LastError   string                `json:"last_error" gorm:"type:text"` // 最后一次失败原因
+	SentAt      int64                 `json:"sent_at"`                     // 发送成功时间
+	TempImage   string                `json:"-" gorm:"index"`              // 发送完成后要删除的临时图片
+	CreatedAt   time.Time             `json:"created_at" gorm:"index"`
+	UpdatedAt   time.Time             `json:"updated_at"`
+}
+
+func (*NotificationLog) TableName() string {
+	return "notification_log"
+}
+
+// NotificationDigestItem 等待汇总发送的通知
+type NotificationDigestItem struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ChannelID uint      `json:"channel_id" gorm:"index"`
+	EventType string    `json:"event_type" gorm:"index"`
+	Payload   string    `json:"-" gorm:"type:text"` // 通知的JSON
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (*NotificationDigestItem) TableName() string {
+	return "notification_digest_items"
+}
+
+// TelegramRole Telegram机器人用户的角色
+type TelegramRole string
+
+const (
+	TelegramRoleViewer   TelegramRole = "viewer"   // 只读，只能查询状态
+	TelegramRoleOperator TelegramRole = "operator" // 操作员，可以执行同步、刮削等任务
+	TelegramRoleAdmin    TelegramRole = "admin"    // 管理员，可以修改设置和管理用户
+)
+
+// 角色的大小，未知的角色没有任何权限
+func (r TelegramRole) Level() int {
+	switch r {
+	case TelegramRoleAdmin:
+		return 3
+	case TelegramRoleOperator:
+		return 2
+	case TelegramRoleViewer:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// TelegramUser 允许控制Telegram机器人的用户
+// 渠道没有添加任何用户时，ChatID中的聊天里所有人都是管理员
+type TelegramUser struct {
+	ID        uint         `json:"id" gorm:"primaryKey"`
+	ChannelID uint         `json:"channel_id" gorm:"uniqueIndex:idx_telegram_user"`
+	UserID    int64        `json:"user_id" gorm:"uniqueIndex:idx_telegram_user"` // Telegram用户ID
+	Username  string       `json:"username"`                                     // Telegram用户名，仅用于显示
+	Role      TelegramRole `json:"role"`
+	Remark    string       `json:"remark"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+func (*TelegramUser) TableName() string {
+	return "telegram_users"
+}
+
+// TelegramCommandLog 通过Telegram机器人执行的命令记录
+type TelegramCommandLog struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ChannelID uint      `json:"channel_id" gorm:"index"`
+	ChatID    int64     `json:"chat_id"`
+	ChatType  string    `json:"chat_type"` // private、group、supergroup
+	UserID    int64     `json:"user_id" gorm:"index"`
+	Username  string    `json:"username"`
+	Command   string    `json:"command" gorm:"index"`
+	Args      string    `json:"args"`
+	Allowed   bool      `json:"allowed"`                 // 是否有权限执行
+	Result    string    `json:"result" gorm:"type:text"` // 回复内容或者拒绝原因
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+func (*TelegramCommandLog) TableName() string {
+	return "telegram_command_logs"
 }
 
 // CustomWebhookChannelConfig 自定义 Webhook 渠道配置
@@ -135,3 +362,80 @@ type CustomWebhookChannelConfig struct {
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 }
+
+// WeComChannelConfig 企业微信群机器人渠道配置
+type WeComChannelConfig struct {
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	ChannelID  uint   `json:"channel_id" gorm:"uniqueIndex:idx_wecom_channel"`
+	WebhookURL string `json:"webhook_url"` // 机器人 Webhook 地址，https://qyapi.weixin.qq.com/cgi-bin/webhook/send?key=xxx
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// DingTalkChannelConfig 钉钉群机器人渠道配置
+type DingTalkChannelConfig struct {
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	ChannelID  uint   `json:"channel_id" gorm:"uniqueIndex:idx_dingtalk_channel"`
+	WebhookURL string `json:"webhook_url"` // 机器人 Webhook 地址，https://oapi.dingtalk.com/robot/send?access_token=xxx
+	Secret     string `json:"secret"`      // 加签密钥，安全设置选择加签时填写
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// FeishuChannelConfig 飞书群机器人渠道配置
+type FeishuChannelConfig struct {
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	ChannelID  uint   `json:"channel_id" gorm:"uniqueIndex:idx_feishu_channel"`
+	WebhookURL string `json:"webhook_url"` // 机器人 Webhook 地址，https://open.feishu.cn/open-apis/bot/v2/hook/xxx
+	Secret     string `json:"secret"`      // 签名校验密钥，安全设置选择签名校验时填写
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// GotifyChannelConfig Gotify渠道配置
+type GotifyChannelConfig struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	ChannelID uint   `json:"channel_id" gorm:"uniqueIndex:idx_gotify_channel"`
+	ServerURL string `json:"server_url"` // Gotify 服务地址
+	AppToken  string `json:"app_token"`  // 应用Token
+	Priority  int    `json:"priority" gorm:"default:5"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// NtfyChannelConfig ntfy渠道配置
+type NtfyChannelConfig struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	ChannelID uint   `json:"channel_id" gorm:"uniqueIndex:idx_ntfy_channel"`
+	ServerURL string `json:"server_url" gorm:"default:https://ntfy.sh"`
+	Topic     string `json:"topic"`
+	Token     string `json:"token"` // 访问令牌，主题需要鉴权时填写
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// DiscordChannelConfig Discord渠道配置
+type DiscordChannelConfig struct {
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	ChannelID  uint   `json:"channel_id" gorm:"uniqueIndex:idx_discord_channel"`
+	WebhookURL string `json:"webhook_url"` // 频道 Webhook 地址
+	Username   string `json:"username"`    // 覆盖 Webhook 的显示名称
+	AvatarURL  string `json:"avatar_url"`  // 覆盖 Webhook 的头像
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// EmailChannelConfig SMTP邮件渠道配置
+type EmailChannelConfig struct {
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	ChannelID  uint   `json:"channel_id" gorm:"uniqueIndex:idx_email_channel"`
+	SMTPHost   string `json:"smtp_host"`
+	SMTPPort   int    `json:"smtp_port"`
+	Encryption string `json:"encryption" gorm:"default:starttls"` // none | starttls | ssl
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	From       string `json:"from"` // 发件人，为空时使用用户名
+	To         string `json:"to"`   // 收件人，多个用英文逗号分隔
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
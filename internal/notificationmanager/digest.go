@@ -0,0 +1,289 @@
+package notificationmanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/notification"
+
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+// ============ 通知汇总 ============
+// 汇总模式的规则匹配到的通知先存入 notification_digest_items 表，不立即发送
+// 到汇总时间后，同一渠道、相同汇总时间的所有事件类型合并成一条通知放入发送队列
+
+// 同一汇总时间的规则
+type digestGroup struct {
+	info       *channelInfo
+	schedule   string
+	eventTypes []string
+}
+
+// SetBacklogProvider 设置获取其他队列积压数量的回调，key为队列名称，在汇总通知中显示
+func (m *EnhancedNotificationManager) SetBacklogProvider(provider func() map[string]int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.backlogProvider = provider
+}
+
+// 把通知存入待汇总表
+func (m *EnhancedNotificationManager) addDigestItem(channelID uint, n *notification.Notification) error {
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("序列化通知失败: %v", err)
+	}
+	return m.db.Create(&notification.NotificationDigestItem{
+		ChannelID: channelID,
+		EventType: string(n.Type),
+		Payload:   string(payload),
+	}).Error
+}
+
+// 检查到了汇总时间的渠道，生成汇总通知
+// 只在发送队列的协程中调用，digestNext 不需要加锁
+func (m *EnhancedNotificationManager) processDigests(now time.Time) {
+	m.mu.RLock()
+	groups := make(map[string]*digestGroup)
+	for eventType, rules := range m.rules {
+		for _, rule := range rules {
+			schedule := rule.DigestSchedule()
+			if schedule == "" {
+				continue
+			}
+			info, ok := m.handlers[rule.ChannelID]
+			if !ok {
+				continue
+			}
+			key := fmt.Sprintf("%d|%s", rule.ChannelID, schedule)
+			if groups[key] == nil {
+				groups[key] = &digestGroup{info: info, schedule: schedule}
+			}
+			groups[key].eventTypes = append(groups[key].eventTypes, eventType)
+		}
+	}
+	m.mu.RUnlock()
+
+	for key, group := range groups {
+		next, ok := m.digestNext[key]
+		if ok && now.Before(next) {
+			continue
+		}
+		schedule, err := cron.ParseStandard(group.schedule)
+		if err != nil {
+			helpers.AppLogger.Errorf("渠道 [%s] 的汇总时间 %s 格式错误: %v", group.info.config.ChannelType, group.schedule, err)
+			m.digestNext[key] = now.Add(time.Hour)
+			continue
+		}
+		m.digestNext[key] = schedule.Next(now)
+		// 第一次只计算下次汇总时间，程序重启前留下的通知在下次汇总时间一起发送
+		if !ok {
+			continue
+		}
+		if err := m.flushDigest(group, now); err != nil {
+			helpers.AppLogger.Errorf("渠道 [%s] 生成汇总通知失败: %v", group.info.config.ChannelType, err)
+		}
+	}
+}
+
+// 把渠道待汇总的通知合并成一条放入发送队列
+func (m *EnhancedNotificationManager) flushDigest(group *digestGroup, now time.Time) error {
+	channelID := group.info.config.ID
+	var items []*notification.NotificationDigestItem
+	if err := m.db.Where("channel_id = ? AND event_type IN ?", channelID, group.eventTypes).Order("id ASC").Find(&items).Error; err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	notifs := make([]*notification.Notification, 0, len(items))
+	ids := make([]uint, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+		var n notification.Notification
+		if err := json.Unmarshal([]byte(item.Payload), &n); err != nil {
+			helpers.AppLogger.Warnf("解析待汇总通知 %d 失败: %v", item.ID, err)
+			continue
+		}
+		notifs = append(notifs, &n)
+	}
+	summary := buildDigest(notifs, items[0].CreatedAt, now, m.digestBacklog())
+	payload, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("序列化汇总通知失败: %v", err)
+	}
+	err = m.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&notification.NotificationLog{
+			ChannelID:   channelID,
+			ChannelType: group.info.config.ChannelType,
+			EventType:   string(notification.DigestSummary),
+			Title:       summary.Title,
+			Content:     summary.Content,
+			Payload:     string(payload),
+			DedupKey:    fmt.Sprintf("digest:%d:%d", channelID, now.Unix()),
+			Status:      notification.NotificationLogPending,
+			NextRetryAt: now.Unix(),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", ids).Delete(&notification.NotificationDigestItem{}).Error
+	})
+	if err != nil {
+		return err
+	}
+	helpers.AppLogger.Infof("渠道 [%s] 已生成汇总通知，合并了 %d 条通知", group.info.config.ChannelType, len(items))
+	return nil
+}
+
+// 各个队列的积压数量
+func (m *EnhancedNotificationManager) digestBacklog() map[string]int64 {
+	backlog := make(map[string]int64)
+	var pending int64
+	m.db.Model(&notification.NotificationLog{}).Where("status = ?", notification.NotificationLogPending).Count(&pending)
+	backlog["通知发送"] = pending
+	m.mu.RLock()
+	provider := m.backlogProvider
+	m.mu.RUnlock()
+	if provider != nil {
+		for name, count := range provider() {
+			backlog[name] = count
+		}
+	}
+	return backlog
+}
+
+// 把一段时间内的通知合并成一条汇总通知
+func buildDigest(notifs []*notification.Notification, from, to time.Time, backlog map[string]int64) *notification.Notification {
+	var movies []string
+	var seriesNames []string
+	seriesEpisodes := make(map[string][]string)
+	// 每部影片的海报，Emby入库通知的图片带有api_key不能使用，只使用刮削时的网络海报
+	posters := make(map[string]string)
+	seen := make(map[string]bool)
+	var syncCount, newStrm, newMeta, newUpload int
+	var failures []string
+	others := make(map[notification.NotificationType]int)
+	image := ""
+
+	for _, n := range notifs {
+		d := n.Digest
+		switch {
+		case n.Type == notification.SyncFinished && d != nil:
+			syncCount++
+			newStrm += d.NewStrm
+			newMeta += d.NewMeta
+			newUpload += d.NewUpload
+		case n.Type == notification.SyncError || n.Priority == notification.HighPriority:
+			failures = append(failures, n.Title)
+		case d != nil && d.MediaType == "movie":
+			// 刮削完成和Emby入库可能是同一部电影
+			if !seen["movie:"+d.MediaName] {
+				seen["movie:"+d.MediaName] = true
+				movies = append(movies, d.MediaName)
+			}
+			if posters["movie:"+d.MediaName] == "" && strings.HasPrefix(d.Poster, "http") {
+				posters["movie:"+d.MediaName] = d.Poster
+			}
+			if image == "" && strings.HasPrefix(n.Image, "http") {
+				image = n.Image
+			}
+		case d != nil && d.MediaType == "tvshow":
+			if _, ok := seriesEpisodes[d.MediaName]; !ok {
+				seriesNames = append(seriesNames, d.MediaName)
+				seriesEpisodes[d.MediaName] = []string{}
+			}
+			if posters["tvshow:"+d.MediaName] == "" && strings.HasPrefix(d.Poster, "http") {
+				posters["tvshow:"+d.MediaName] = d.Poster
+			}
+			for _, ep := range d.Episodes {
+				if !seen["tvshow:"+d.MediaName+ep] {
+					seen["tvshow:"+d.MediaName+ep] = true
+					seriesEpisodes[d.MediaName] = append(seriesEpisodes[d.MediaName], ep)
+				}
+			}
+			if image == "" && strings.HasPrefix(n.Image, "http") {
+				image = n.Image
+			}
+		default:
+			others[n.Type]++
+		}
+	}
+
+	var sb strings.Builder
+	if len(movies) > 0 {
+		sb.WriteString(fmt.Sprintf("🎬 新增电影 (%d)\n", len(movies)))
+		for _, name := range movies {
+			sb.WriteString(fmt.Sprintf("  • %s\n", name))
+			if poster := posters["movie:"+name]; poster != "" {
+				sb.WriteString(fmt.Sprintf("    🖼 %s\n", poster))
+			}
+		}
+	}
+	if len(seriesNames) > 0 {
+		sb.WriteString(fmt.Sprintf("📺 新增剧集 (%d)\n", len(seriesNames)))
+		for _, name := range seriesNames {
+			if eps := seriesEpisodes[name]; len(eps) > 0 {
+				sb.WriteString(fmt.Sprintf("  • %s %s\n", name, strings.Join(eps, ", ")))
+			} else {
+				sb.WriteString(fmt.Sprintf("  • %s\n", name))
+			}
+			if poster := posters["tvshow:"+name]; poster != "" {
+				sb.WriteString(fmt.Sprintf("    🖼 %s\n", poster))
+			}
+		}
+	}
+	if syncCount > 0 {
+		sb.WriteString(fmt.Sprintf("🔄 同步完成 %d 次，生成STRM: %d, 下载: %d, 上传: %d\n", syncCount, newStrm, newMeta, newUpload))
+	}
+	if len(failures) > 0 {
+		sb.WriteString(fmt.Sprintf("❌ 失败和告警 (%d)\n", len(failures)))
+		for _, title := range failures {
+			sb.WriteString(fmt.Sprintf("  • %s\n", title))
+		}
+	}
+	if len(others) > 0 {
+		types := make([]string, 0, len(others))
+		for t := range others {
+			types = append(types, string(t))
+		}
+		sort.Strings(types)
+		parts := make([]string, 0, len(types))
+		for _, t := range types {
+			parts = append(parts, fmt.Sprintf("%s %d 条", t, others[notification.NotificationType(t)]))
+		}
+		sb.WriteString(fmt.Sprintf("📋 其他通知: %s\n", strings.Join(parts, ", ")))
+	}
+	names := make([]string, 0, len(backlog))
+	for name, count := range backlog {
+		if count > 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) > 0 {
+		sort.Strings(names)
+		parts := make([]string, 0, len(names))
+		for _, name := range names {
+			parts = append(parts, fmt.Sprintf("%s %d", name, backlog[name]))
+		}
+		sb.WriteString(fmt.Sprintf("📦 队列积压: %s\n", strings.Join(parts, ", ")))
+	}
+	sb.WriteString(fmt.Sprintf("⏰ 时间: %s - %s", from.Format("2006-01-02 15:04"), to.Format("2006-01-02 15:04")))
+
+	priority := notification.NormalPriority
+	if len(failures) > 0 {
+		priority = notification.HighPriority
+	}
+	return &notification.Notification{
+		Type:      notification.DigestSummary,
+		Title:     fmt.Sprintf("📰 通知汇总：共 %d 条", len(notifs)),
+		Content:   sb.String(),
+		Image:     image,
+		Timestamp: to,
+		Priority:  priority,
+	}
+}
@@ -0,0 +1,62 @@
+package notificationmanager
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"Q115-STRM/internal/notification"
+)
+
+func TestDigestSchedule(t *testing.T) {
+	tests := []struct {
+		rule     notification.NotificationRule
+		expected string
+	}{
+		{notification.NotificationRule{}, ""},
+		{notification.NotificationRule{DigestMode: notification.DigestHourly}, "0 * * * *"},
+		{notification.NotificationRule{DigestMode: notification.DigestDaily}, "0 9 * * *"},
+		{notification.NotificationRule{DigestMode: notification.DigestWeekly}, "0 9 * * 1"},
+		{notification.NotificationRule{DigestMode: notification.DigestDaily, DigestCron: "30 21 * * *"}, "30 21 * * *"},
+	}
+	for _, tt := range tests {
+		if actual := tt.rule.DigestSchedule(); actual != tt.expected {
+			t.Errorf("汇总模式 %q, 期望: %q, 实际: %q", tt.rule.DigestMode, tt.expected, actual)
+		}
+	}
+}
+
+func TestBuildDigest(t *testing.T) {
+	notifs := []*notification.Notification{
+		{Type: notification.MediaAdded, Image: "/tmp/a.jpg", Digest: &notification.DigestData{MediaType: "movie", MediaName: "电影A"}},
+		{Type: notification.ScrapeFinished, Image: "https://image.tmdb.org/a.jpg", Digest: &notification.DigestData{MediaType: "movie", MediaName: "电影A", Poster: "https://image.tmdb.org/a.jpg"}},
+		{Type: notification.ScrapeFinished, Image: "https://image.tmdb.org/c.jpg", Digest: &notification.DigestData{MediaType: "movie", MediaName: "电影C", Poster: "https://image.tmdb.org/c.jpg"}},
+		{Type: notification.ScrapeFinished, Digest: &notification.DigestData{MediaType: "tvshow", MediaName: "剧集B", Episodes: []string{"S01E01"}}},
+		{Type: notification.ScrapeFinished, Digest: &notification.DigestData{MediaType: "tvshow", MediaName: "剧集B", Episodes: []string{"S01E02", "S01E01"}}},
+		{Type: notification.SyncFinished, Digest: &notification.DigestData{NewStrm: 3, NewMeta: 2}},
+		{Type: notification.SyncFinished, Digest: &notification.DigestData{NewStrm: 1, NewUpload: 4}},
+		{Type: notification.SyncError, Title: "❌ 同步错误", Priority: notification.HighPriority},
+		{Type: notification.PlaybackStart},
+	}
+	from := time.Date(2026, 1, 1, 9, 0, 0, 0, time.Local)
+	summary := buildDigest(notifs, from, from.Add(24*time.Hour), map[string]int64{"通知发送": 0, "下载": 5})
+
+	if summary.Image != "https://image.tmdb.org/a.jpg" {
+		t.Errorf("应使用第一张网络海报, 实际: %s", summary.Image)
+	}
+	if summary.Priority != notification.HighPriority {
+		t.Error("有失败时应为高优先级")
+	}
+	for _, expected := range []string{
+		"🎬 新增电影 (2)\n  • 电影A\n    🖼 https://image.tmdb.org/a.jpg\n  • 电影C\n    🖼 https://image.tmdb.org/c.jpg\n",
+		"  • 剧集B S01E01, S01E02\n",
+		"同步完成 2 次，生成STRM: 4, 下载: 2, 上传: 4",
+		"❌ 失败和告警 (1)\n  • ❌ 同步错误\n",
+		"📋 其他通知: playback_start 1 条",
+		"📦 队列积压: 下载 5\n",
+	} {
+		if !strings.Contains(summary.Content, expected) {
+			t.Errorf("汇总内容缺少 %q:\n%s", expected, summary.Content)
+		}
+	}
+}
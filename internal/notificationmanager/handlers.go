@@ -14,6 +14,8 @@ import (
 
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/notification"
+
+	"gorm.io/gorm"
 )
 
 // ChannelHandler 通知渠道处理器接口
@@ -37,6 +39,9 @@ type TelegramChannelHandler struct {
 	initOnce       sync.Once
 	stopChan       chan struct{}                                     // 用于停止信号
 	customCommands map[string]func([]string) helpers.CommandResponse // 保存从外部注入的命令
+	commandRoles   map[string]notification.TelegramRole              // 外部注入的命令需要的角色
+	db             *gorm.DB                                          // 查询机器人用户和记录命令
+	cancel         context.CancelFunc                                // 停止监听
 }
 
 func NewTelegramChannelHandler(config *notification.TelegramChannelConfig) *TelegramChannelHandler {
@@ -142,12 +147,21 @@ func (h *TelegramChannelHandler) initBot() error {
 	if h.bot == nil {
 		return fmt.Errorf("创建Telegram机器人失败")
 	}
+	h.bot.Authorize = h.authorize
+	h.bot.Audit = h.audit
 	h.bot.SetMenuContent()
 	return err
 }
 
-func (h *TelegramChannelHandler) SetCommands(cmds map[string]func([]string) helpers.CommandResponse) {
+// SetCommands 设置外部注入的命令和命令需要的角色
+func (h *TelegramChannelHandler) SetCommands(cmds map[string]func([]string) helpers.CommandResponse, roles map[string]notification.TelegramRole) {
 	h.customCommands = cmds
+	h.commandRoles = roles
+}
+
+// SetDB 设置数据库，用于机器人用户权限和命令记录，不设置时不检查权限
+func (h *TelegramChannelHandler) SetDB(db *gorm.DB) {
+	h.db = db
 }
 
 // Start 实现 BackgroundHandler 接口
@@ -157,13 +171,24 @@ func (h *TelegramChannelHandler) Start(ctx context.Context) {
 		return
 	}
 
+	commands := make(map[string]func([]string) helpers.CommandResponse, len(h.customCommands)+3)
+	for cmd, logic := range h.customCommands {
+		commands[cmd] = logic
+	}
+	if h.db != nil {
+		for cmd, logic := range h.userCommands() {
+			commands[cmd] = logic
+		}
+	}
+	ctx, h.cancel = context.WithCancel(ctx)
+
 	// 在协程中运行监听，避免阻塞主进程
 	go func() {
 		helpers.AppLogger.Infof("Telegram Bot 监听协程启动...")
 
 		// 调用你现有的监听逻辑，并把自定义命令传进去
 		// 注意：我们需要对 StartListening 做一点小改动，让它能感知 ctx
-		h.bot.StartListening(ctx, h.customCommands)
+		h.bot.StartListening(ctx, commands)
 
 		helpers.AppLogger.Infof("Telegram Bot 监听协程已安全退出")
 	}()
@@ -173,6 +198,10 @@ func (h *TelegramChannelHandler) Stop() {
 	if h.stopChan != nil {
 		close(h.stopChan)
 	}
+	// 停止监听，重新加载渠道时不会有两个协程同时获取更新
+	if h.cancel != nil {
+		h.cancel()
+	}
 }
 
 // MeoWChannelHandler MeoW渠道处理器
@@ -0,0 +1,173 @@
+package notificationmanager
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"Q115-STRM/internal/notification"
+)
+
+// EmailChannelHandler SMTP邮件渠道处理器
+type EmailChannelHandler struct {
+	config *notification.EmailChannelConfig
+}
+
+func NewEmailChannelHandler(config *notification.EmailChannelConfig) *EmailChannelHandler {
+	return &EmailChannelHandler{config: config}
+}
+
+func (h *EmailChannelHandler) GetChannelType() string {
+	return "email"
+}
+
+func (h *EmailChannelHandler) IsHealthy() bool {
+	return h.config.SMTPHost != "" && len(h.recipients()) > 0 && h.sender() != ""
+}
+
+// 收件人列表
+func (h *EmailChannelHandler) recipients() []string {
+	result := make([]string, 0)
+	for _, addr := range strings.Split(h.config.To, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			result = append(result, addr)
+		}
+	}
+	return result
+}
+
+// 发件人，没有配置时使用登录用户名
+func (h *EmailChannelHandler) sender() string {
+	if h.config.From != "" {
+		return h.config.From
+	}
+	return h.config.Username
+}
+
+// 端口，没有配置时按加密方式使用默认端口
+func (h *EmailChannelHandler) port() int {
+	if h.config.SMTPPort > 0 {
+		return h.config.SMTPPort
+	}
+	switch h.config.Encryption {
+	case "ssl":
+		return 465
+	case "none":
+		return 25
+	default:
+		return 587
+	}
+}
+
+// 生成HTML格式的邮件正文
+func formatEmailHTML(n *notification.Notification) string {
+	var sb strings.Builder
+	sb.WriteString("<html><body>")
+	sb.WriteString(fmt.Sprintf("<h3>%s</h3>", html.EscapeString(n.Title)))
+	sb.WriteString(fmt.Sprintf("<p>%s</p>", strings.ReplaceAll(html.EscapeString(n.Content), "\n", "<br>")))
+	if len(n.Metadata) > 0 {
+		sb.WriteString("<ul>")
+		for key, value := range n.Metadata {
+			sb.WriteString(fmt.Sprintf("<li><b>%s:</b> %s</li>", html.EscapeString(key), html.EscapeString(fmt.Sprintf("%v", value))))
+		}
+		sb.WriteString("</ul>")
+	}
+	if n.Image != "" {
+		sb.WriteString(fmt.Sprintf(`<p><img src="%s" style="max-width:100%%"></p>`, html.EscapeString(n.Image)))
+	}
+	sb.WriteString("</body></html>")
+	return sb.String()
+}
+
+// 生成完整的邮件内容
+func (h *EmailChannelHandler) buildMessage(n *notification.Notification, now time.Time) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("From: %s\r\n", h.sender()))
+	buf.WriteString(fmt.Sprintf("To: %s\r\n", strings.Join(h.recipients(), ", ")))
+	buf.WriteString(fmt.Sprintf("Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", n.Title)))
+	buf.WriteString(fmt.Sprintf("Date: %s\r\n", now.Format(time.RFC1123Z)))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+	encoded := base64.StdEncoding.EncodeToString([]byte(formatEmailHTML(n)))
+	// base64正文每行不超过76个字符
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+	return buf.Bytes()
+}
+
+// Send 通过SMTP发送HTML邮件，支持 SSL、STARTTLS 和不加密三种方式
+func (h *EmailChannelHandler) Send(ctx context.Context, n *notification.Notification) error {
+	to := h.recipients()
+	if len(to) == 0 {
+		return fmt.Errorf("邮件 没有配置收件人")
+	}
+	addr := net.JoinHostPort(h.config.SMTPHost, fmt.Sprintf("%d", h.port()))
+	dialer := &net.Dialer{Timeout: 15 * time.Second}
+	if deadline, ok := ctx.Deadline(); ok {
+		dialer.Deadline = deadline
+	}
+	tlsConfig := &tls.Config{ServerName: h.config.SMTPHost}
+
+	var conn net.Conn
+	var err error
+	if h.config.Encryption == "ssl" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("邮件 连接SMTP服务器失败: %v", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, h.config.SMTPHost)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("邮件 SMTP握手失败: %v", err)
+	}
+	defer client.Close()
+
+	if h.config.Encryption == "starttls" || h.config.Encryption == "" {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("邮件 STARTTLS失败: %v", err)
+		}
+	}
+	if h.config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", h.config.Username, h.config.Password, h.config.SMTPHost)); err != nil {
+			return fmt.Errorf("邮件 SMTP认证失败: %v", err)
+		}
+	}
+	if err := client.Mail(h.sender()); err != nil {
+		return fmt.Errorf("邮件 设置发件人失败: %v", err)
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("邮件 设置收件人 %s 失败: %v", rcpt, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("邮件 发送内容失败: %v", err)
+	}
+	if _, err := w.Write(h.buildMessage(n, time.Now())); err != nil {
+		w.Close()
+		return fmt.Errorf("邮件 发送内容失败: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("邮件 发送内容失败: %v", err)
+	}
+	return client.Quit()
+}
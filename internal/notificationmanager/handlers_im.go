@@ -0,0 +1,484 @@
+package notificationmanager
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"Q115-STRM/internal/notification"
+
+	"gorm.io/gorm"
+)
+
+// LoadChannelHandler 为企业微信、钉钉、飞书、Gotify、ntfy、Discord、邮件渠道读取配置并创建处理器
+func LoadChannelHandler(db *gorm.DB, channel *notification.NotificationChannel) (ChannelHandler, error) {
+	switch channel.ChannelType {
+	case "wecom":
+		var config notification.WeComChannelConfig
+		if err := db.Where("channel_id = ?", channel.ID).First(&config).Error; err != nil {
+			return nil, fmt.Errorf("企业微信配置不存在: %v", err)
+		}
+		return NewWeComChannelHandler(&config), nil
+	case "dingtalk":
+		var config notification.DingTalkChannelConfig
+		if err := db.Where("channel_id = ?", channel.ID).First(&config).Error; err != nil {
+			return nil, fmt.Errorf("钉钉配置不存在: %v", err)
+		}
+		return NewDingTalkChannelHandler(&config), nil
+	case "feishu":
+		var config notification.FeishuChannelConfig
+		if err := db.Where("channel_id = ?", channel.ID).First(&config).Error; err != nil {
+			return nil, fmt.Errorf("飞书配置不存在: %v", err)
+		}
+		return NewFeishuChannelHandler(&config), nil
+	case "gotify":
+		var config notification.GotifyChannelConfig
+		if err := db.Where("channel_id = ?", channel.ID).First(&config).Error; err != nil {
+			return nil, fmt.Errorf("Gotify配置不存在: %v", err)
+		}
+		return NewGotifyChannelHandler(&config), nil
+	case "ntfy":
+		var config notification.NtfyChannelConfig
+		if err := db.Where("channel_id = ?", channel.ID).First(&config).Error; err != nil {
+			return nil, fmt.Errorf("ntfy配置不存在: %v", err)
+		}
+		return NewNtfyChannelHandler(&config), nil
+	case "discord":
+		var config notification.DiscordChannelConfig
+		if err := db.Where("channel_id = ?", channel.ID).First(&config).Error; err != nil {
+			return nil, fmt.Errorf("Discord配置不存在: %v", err)
+		}
+		return NewDiscordChannelHandler(&config), nil
+	case "email":
+		var config notification.EmailChannelConfig
+		if err := db.Where("channel_id = ?", channel.ID).First(&config).Error; err != nil {
+			return nil, fmt.Errorf("邮件配置不存在: %v", err)
+		}
+		return NewEmailChannelHandler(&config), nil
+	default:
+		return nil, fmt.Errorf("未知的渠道类型: %s", channel.ChannelType)
+	}
+}
+
+// 生成markdown格式的消息，withImage 为 true 时在末尾插入图片
+func formatMarkdown(n *notification.Notification, withImage bool) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("**%s**\n\n", n.Title))
+	sb.WriteString(strings.ReplaceAll(n.Content, "\n", "\n\n"))
+	for key, value := range n.Metadata {
+		sb.WriteString(fmt.Sprintf("\n\n**%s:** %v", key, value))
+	}
+	if withImage && n.Image != "" {
+		sb.WriteString(fmt.Sprintf("\n\n![](%s)", n.Image))
+	}
+	return sb.String()
+}
+
+// 发送JSON请求，非2xx状态码返回错误
+func postJSON(ctx context.Context, name string, endpoint string, payload any, headers map[string]string) ([]byte, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%s 消息编码失败: %v", name, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("%s 创建请求失败: %v", name, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	client := &http.Client{
+		Timeout: 15 * time.Second,
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s 发送请求失败: %v", name, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s 返回错误: status=%d, body=%s", name, resp.StatusCode, string(body))
+	}
+	return body, nil
+}
+
+// 检查企业微信、钉钉风格的响应 {"errcode":0,"errmsg":"ok"}
+func checkErrCode(name string, body []byte) error {
+	var result struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("%s 响应解析失败: %v, body=%s", name, err, string(body))
+	}
+	if result.ErrCode != 0 {
+		return fmt.Errorf("%s 响应错误: errcode=%d, errmsg=%s", name, result.ErrCode, result.ErrMsg)
+	}
+	return nil
+}
+
+// HMAC-SHA256签名后base64编码
+func hmacSha256Base64(key, message string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(message))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ============ 企业微信 ============
+
+// WeComChannelHandler 企业微信群机器人渠道处理器
+type WeComChannelHandler struct {
+	config *notification.WeComChannelConfig
+}
+
+func NewWeComChannelHandler(config *notification.WeComChannelConfig) *WeComChannelHandler {
+	return &WeComChannelHandler{config: config}
+}
+
+func (h *WeComChannelHandler) GetChannelType() string {
+	return "wecom"
+}
+
+func (h *WeComChannelHandler) IsHealthy() bool {
+	return h.config.WebhookURL != ""
+}
+
+// Send 没有图片时发送markdown消息，有图片时发送图文卡片（企业微信的markdown不支持图片）
+func (h *WeComChannelHandler) Send(ctx context.Context, n *notification.Notification) error {
+	var payload map[string]any
+	if n.Image != "" {
+		payload = map[string]any{
+			"msgtype": "news",
+			"news": map[string]any{
+				"articles": []map[string]string{
+					{"title": n.Title, "description": n.Content, "url": n.Image, "picurl": n.Image},
+				},
+			},
+		}
+	} else {
+		payload = map[string]any{
+			"msgtype":  "markdown",
+			"markdown": map[string]string{"content": formatMarkdown(n, false)},
+		}
+	}
+	body, err := postJSON(ctx, "企业微信", h.config.WebhookURL, payload, nil)
+	if err != nil {
+		return err
+	}
+	return checkErrCode("企业微信", body)
+}
+
+// ============ 钉钉 ============
+
+// DingTalkChannelHandler 钉钉群机器人渠道处理器
+type DingTalkChannelHandler struct {
+	config *notification.DingTalkChannelConfig
+}
+
+func NewDingTalkChannelHandler(config *notification.DingTalkChannelConfig) *DingTalkChannelHandler {
+	return &DingTalkChannelHandler{config: config}
+}
+
+func (h *DingTalkChannelHandler) GetChannelType() string {
+	return "dingtalk"
+}
+
+func (h *DingTalkChannelHandler) IsHealthy() bool {
+	return h.config.WebhookURL != ""
+}
+
+// 开启加签时在地址上附加 timestamp 和 sign 参数
+func (h *DingTalkChannelHandler) endpoint(now time.Time) (string, error) {
+	if h.config.Secret == "" {
+		return h.config.WebhookURL, nil
+	}
+	u, err := url.Parse(h.config.WebhookURL)
+	if err != nil {
+		return "", fmt.Errorf("钉钉 Webhook 地址错误: %v", err)
+	}
+	timestamp := now.UnixMilli()
+	query := u.Query()
+	query.Set("timestamp", fmt.Sprintf("%d", timestamp))
+	query.Set("sign", hmacSha256Base64(h.config.Secret, fmt.Sprintf("%d\n%s", timestamp, h.config.Secret)))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+func (h *DingTalkChannelHandler) Send(ctx context.Context, n *notification.Notification) error {
+	endpoint, err := h.endpoint(time.Now())
+	if err != nil {
+		return err
+	}
+	payload := map[string]any{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"title": n.Title,
+			"text":  formatMarkdown(n, true),
+		},
+	}
+	body, err := postJSON(ctx, "钉钉", endpoint, payload, nil)
+	if err != nil {
+		return err
+	}
+	return checkErrCode("钉钉", body)
+}
+
+// ============ 飞书 ============
+
+// FeishuChannelHandler 飞书群机器人渠道处理器
+type FeishuChannelHandler struct {
+	config *notification.FeishuChannelConfig
+}
+
+func NewFeishuChannelHandler(config *notification.FeishuChannelConfig) *FeishuChannelHandler {
+	return &FeishuChannelHandler{config: config}
+}
+
+func (h *FeishuChannelHandler) GetChannelType() string {
+	return "feishu"
+}
+
+func (h *FeishuChannelHandler) IsHealthy() bool {
+	return h.config.WebhookURL != ""
+}
+
+// 卡片标题颜色
+func feishuCardTemplate(priority notification.NotificationPriority) string {
+	switch priority {
+	case notification.HighPriority:
+		return "red"
+	case notification.LowPriority:
+		return "grey"
+	default:
+		return "blue"
+	}
+}
+
+// Send 发送消息卡片，飞书卡片中的图片需要先上传，这里用按钮链接到图片
+func (h *FeishuChannelHandler) Send(ctx context.Context, n *notification.Notification) error {
+	content := n.Content
+	for key, value := range n.Metadata {
+		content += fmt.Sprintf("\n**%s:** %v", key, value)
+	}
+	elements := []map[string]any{
+		{"tag": "div", "text": map[string]string{"tag": "lark_md", "content": content}},
+	}
+	if n.Image != "" {
+		elements = append(elements, map[string]any{
+			"tag": "action",
+			"actions": []map[string]any{
+				{"tag": "button", "text": map[string]string{"tag": "plain_text", "content": "查看图片"}, "url": n.Image, "type": "default"},
+			},
+		})
+	}
+	payload := map[string]any{
+		"msg_type": "interactive",
+		"card": map[string]any{
+			"header": map[string]any{
+				"title":    map[string]string{"tag": "plain_text", "content": n.Title},
+				"template": feishuCardTemplate(n.Priority),
+			},
+			"elements": elements,
+		},
+	}
+	if h.config.Secret != "" {
+		timestamp := time.Now().Unix()
+		// 飞书的签名使用 timestamp+"\n"+secret 作为密钥对空字符串签名
+		payload["timestamp"] = fmt.Sprintf("%d", timestamp)
+		payload["sign"] = hmacSha256Base64(fmt.Sprintf("%d\n%s", timestamp, h.config.Secret), "")
+	}
+	body, err := postJSON(ctx, "飞书", h.config.WebhookURL, payload, nil)
+	if err != nil {
+		return err
+	}
+	var result struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("飞书 响应解析失败: %v, body=%s", err, string(body))
+	}
+	if result.Code != 0 {
+		return fmt.Errorf("飞书 响应错误: code=%d, msg=%s", result.Code, result.Msg)
+	}
+	return nil
+}
+
+// ============ Gotify ============
+
+// GotifyChannelHandler Gotify渠道处理器
+type GotifyChannelHandler struct {
+	config *notification.GotifyChannelConfig
+}
+
+func NewGotifyChannelHandler(config *notification.GotifyChannelConfig) *GotifyChannelHandler {
+	return &GotifyChannelHandler{config: config}
+}
+
+func (h *GotifyChannelHandler) GetChannelType() string {
+	return "gotify"
+}
+
+func (h *GotifyChannelHandler) IsHealthy() bool {
+	return h.config.ServerURL != "" && h.config.AppToken != ""
+}
+
+func (h *GotifyChannelHandler) Send(ctx context.Context, n *notification.Notification) error {
+	priority := h.config.Priority
+	if priority <= 0 {
+		priority = 5
+	}
+	if n.Priority == notification.HighPriority && priority < 8 {
+		priority = 8
+	}
+	extras := map[string]any{
+		"client::display": map[string]string{"contentType": "text/markdown"},
+	}
+	if n.Image != "" {
+		extras["client::notification"] = map[string]string{"bigImageUrl": n.Image}
+	}
+	payload := map[string]any{
+		"title":    n.Title,
+		"message":  strings.TrimPrefix(formatMarkdown(n, true), fmt.Sprintf("**%s**\n\n", n.Title)),
+		"priority": priority,
+		"extras":   extras,
+	}
+	endpoint := strings.TrimRight(h.config.ServerURL, "/") + "/message"
+	_, err := postJSON(ctx, "Gotify", endpoint, payload, map[string]string{"X-Gotify-Key": h.config.AppToken})
+	return err
+}
+
+// ============ ntfy ============
+
+// NtfyChannelHandler ntfy渠道处理器
+type NtfyChannelHandler struct {
+	config *notification.NtfyChannelConfig
+}
+
+func NewNtfyChannelHandler(config *notification.NtfyChannelConfig) *NtfyChannelHandler {
+	return &NtfyChannelHandler{config: config}
+}
+
+func (h *NtfyChannelHandler) GetChannelType() string {
+	return "ntfy"
+}
+
+func (h *NtfyChannelHandler) IsHealthy() bool {
+	return h.config.Topic != ""
+}
+
+// ntfy的优先级 1-5
+func ntfyPriority(priority notification.NotificationPriority) int {
+	switch priority {
+	case notification.HighPriority:
+		return 4
+	case notification.LowPriority:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// Send 使用JSON方式发布到服务根地址，图片作为附件URL
+func (h *NtfyChannelHandler) Send(ctx context.Context, n *notification.Notification) error {
+	serverURL := h.config.ServerURL
+	if serverURL == "" {
+		serverURL = "https://ntfy.sh"
+	}
+	payload := map[string]any{
+		"topic":    h.config.Topic,
+		"title":    n.Title,
+		"message":  strings.TrimPrefix(formatMarkdown(n, false), fmt.Sprintf("**%s**\n\n", n.Title)),
+		"markdown": true,
+		"priority": ntfyPriority(n.Priority),
+		"tags":     []string{string(n.Type)},
+	}
+	if n.Image != "" {
+		payload["attach"] = n.Image
+	}
+	headers := map[string]string{}
+	if h.config.Token != "" {
+		headers["Authorization"] = "Bearer " + h.config.Token
+	}
+	_, err := postJSON(ctx, "ntfy", strings.TrimRight(serverURL, "/"), payload, headers)
+	return err
+}
+
+// ============ Discord ============
+
+// DiscordChannelHandler Discord渠道处理器
+type DiscordChannelHandler struct {
+	config *notification.DiscordChannelConfig
+}
+
+func NewDiscordChannelHandler(config *notification.DiscordChannelConfig) *DiscordChannelHandler {
+	return &DiscordChannelHandler{config: config}
+}
+
+func (h *DiscordChannelHandler) GetChannelType() string {
+	return "discord"
+}
+
+func (h *DiscordChannelHandler) IsHealthy() bool {
+	return h.config.WebhookURL != ""
+}
+
+// embed侧边颜色
+func discordColor(priority notification.NotificationPriority) int {
+	switch priority {
+	case notification.HighPriority:
+		return 0xE74C3C
+	case notification.LowPriority:
+		return 0x95A5A6
+	default:
+		return 0x3498DB
+	}
+}
+
+// Send 发送embed卡片，图片显示在卡片中
+func (h *DiscordChannelHandler) Send(ctx context.Context, n *notification.Notification) error {
+	description := n.Content
+	// embed描述最多4096个字符
+	if runes := []rune(description); len(runes) > 4000 {
+		description = string(runes[:4000]) + "..."
+	}
+	embed := map[string]any{
+		"title":       n.Title,
+		"description": description,
+		"color":       discordColor(n.Priority),
+	}
+	if !n.Timestamp.IsZero() {
+		embed["timestamp"] = n.Timestamp.Format(time.RFC3339)
+	}
+	if n.Image != "" {
+		embed["image"] = map[string]string{"url": n.Image}
+	}
+	fields := make([]map[string]any, 0)
+	for key, value := range n.Metadata {
+		fields = append(fields, map[string]any{"name": key, "value": fmt.Sprintf("%v", value), "inline": true})
+	}
+	if len(fields) > 0 {
+		embed["fields"] = fields
+	}
+	payload := map[string]any{
+		"embeds": []map[string]any{embed},
+	}
+	if h.config.Username != "" {
+		payload["username"] = h.config.Username
+	}
+	if h.config.AvatarURL != "" {
+		payload["avatar_url"] = h.config.AvatarURL
+	}
+	_, err := postJSON(ctx, "Discord", h.config.WebhookURL, payload, nil)
+	return err
+}
@@ -0,0 +1,115 @@
+package notificationmanager
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"Q115-STRM/internal/notification"
+)
+
+func TestDingTalkEndpointSign(t *testing.T) {
+	h := NewDingTalkChannelHandler(&notification.DingTalkChannelConfig{
+		WebhookURL: "https://oapi.dingtalk.com/robot/send?access_token=abc",
+		Secret:     "SECxxx",
+	})
+	now := time.UnixMilli(1700000000000)
+	endpoint, err := h.endpoint(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(endpoint)
+	query := u.Query()
+	if query.Get("access_token") != "abc" || query.Get("timestamp") != "1700000000000" {
+		t.Fatalf("签名地址参数错误: %s", endpoint)
+	}
+	if expected := hmacSha256Base64("SECxxx", "1700000000000\nSECxxx"); query.Get("sign") != expected {
+		t.Fatalf("签名错误，期望: %s, 实际: %s", expected, query.Get("sign"))
+	}
+
+	// 没有密钥时不加签
+	h.config.Secret = ""
+	if endpoint, _ := h.endpoint(now); endpoint != h.config.WebhookURL {
+		t.Fatalf("未加签时地址不应变化: %s", endpoint)
+	}
+}
+
+// 启动一个测试服务器，记录收到的请求体和请求头
+func newRecordServer(t *testing.T, response string) (*httptest.Server, *map[string]any, *http.Header) {
+	body := map[string]any{}
+	header := http.Header{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("请求体不是JSON: %s", string(data))
+		}
+		header = r.Header.Clone()
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return server, &body, &header
+}
+
+func TestWeComSendImageAsNews(t *testing.T) {
+	server, body, _ := newRecordServer(t, `{"errcode":0,"errmsg":"ok"}`)
+	h := NewWeComChannelHandler(&notification.WeComChannelConfig{WebhookURL: server.URL})
+	err := h.Send(context.Background(), &notification.Notification{Title: "标题", Content: "内容", Image: "https://example.com/a.jpg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (*body)["msgtype"] != "news" {
+		t.Fatalf("有图片时应发送图文消息: %v", *body)
+	}
+}
+
+func TestWeComSendErrCode(t *testing.T) {
+	server, _, _ := newRecordServer(t, `{"errcode":93000,"errmsg":"invalid webhook url"}`)
+	h := NewWeComChannelHandler(&notification.WeComChannelConfig{WebhookURL: server.URL})
+	err := h.Send(context.Background(), &notification.Notification{Title: "标题", Content: "内容"})
+	if err == nil || !strings.Contains(err.Error(), "93000") {
+		t.Fatalf("应返回企业微信错误码, 实际: %v", err)
+	}
+}
+
+func TestGotifySend(t *testing.T) {
+	server, body, header := newRecordServer(t, `{}`)
+	h := NewGotifyChannelHandler(&notification.GotifyChannelConfig{ServerURL: server.URL + "/", AppToken: "token", Priority: 5})
+	err := h.Send(context.Background(), &notification.Notification{Title: "标题", Content: "内容", Priority: notification.HighPriority, Image: "https://example.com/a.jpg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Get("X-Gotify-Key") != "token" {
+		t.Fatalf("缺少 X-Gotify-Key: %v", *header)
+	}
+	if (*body)["priority"] != float64(8) {
+		t.Fatalf("高优先级通知应提升到8, 实际: %v", (*body)["priority"])
+	}
+	extras, _ := (*body)["extras"].(map[string]any)
+	if _, ok := extras["client::notification"]; !ok {
+		t.Fatalf("有图片时应设置 bigImageUrl: %v", extras)
+	}
+}
+
+func TestEmailBuildMessage(t *testing.T) {
+	h := NewEmailChannelHandler(&notification.EmailChannelConfig{
+		SMTPHost: "smtp.example.com",
+		Username: "bot@example.com",
+		To:       "a@example.com, b@example.com,",
+	})
+	if len(h.recipients()) != 2 || h.sender() != "bot@example.com" || h.port() != 587 {
+		t.Fatalf("收件人/发件人/端口错误: %v %s %d", h.recipients(), h.sender(), h.port())
+	}
+	msg := string(h.buildMessage(&notification.Notification{Title: "入库通知", Content: "<b>电影</b>"}, time.Now()))
+	if !strings.Contains(msg, "Subject: =?UTF-8?b?") || !strings.Contains(msg, "To: a@example.com, b@example.com") {
+		t.Fatalf("邮件头错误: %s", msg)
+	}
+	if strings.Contains(formatEmailHTML(&notification.Notification{Content: "<b>"}), "<b><") {
+		t.Fatal("正文中的HTML应该转义")
+	}
+}
@@ -16,11 +16,22 @@ import (
 
 // EnhancedNotificationManager 增强的通知管理器
 type EnhancedNotificationManager struct {
-	handlers    map[uint]*channelInfo // key: ChannelID, value: handler + config
-	rules       map[string][]uint     // key: EventType, value: ChannelIDs
+	handlers    map[uint]*channelInfo                       // key: ChannelID, value: handler + config
+	rules       map[string][]*notification.NotificationRule // key: EventType, value: 启用的规则
 	mu          sync.RWMutex
 	db          *gorm.DB
 	getProxyURL func() string // 获取代理URL的回调函数
+	// 发送队列
+	enqueueMu sync.Mutex          // 保证去重检查和写入队列是原子的
+	wake      chan struct{}       // 有新通知时唤醒队列
+	limiter   *channelRateLimiter // 每个渠道的发送频率限制
+	queueOnce sync.Once
+	// 通知汇总
+	digestNext      map[string]time.Time    // key: 渠道ID|汇总时间, value: 下次汇总时间
+	backlogProvider func() map[string]int64 // 获取其他队列积压数量的回调
+	// Telegram机器人命令，重新加载渠道时重新注入
+	telegramCommands     map[string]func([]string) helpers.CommandResponse
+	telegramCommandRoles map[string]notification.TelegramRole
 }
 
 type channelInfo struct {
@@ -36,9 +47,12 @@ var GlobalEnhancedNotificationManager *EnhancedNotificationManager
 func NewEnhancedNotificationManager(db *gorm.DB, getProxyURL func() string) *EnhancedNotificationManager {
 	return &EnhancedNotificationManager{
 		handlers:    make(map[uint]*channelInfo),
-		rules:       make(map[string][]uint),
+		rules:       make(map[string][]*notification.NotificationRule),
 		db:          db,
 		getProxyURL: getProxyURL,
+		wake:        make(chan struct{}, 1),
+		limiter:     newChannelRateLimiter(channelRateLimit, channelRateWindow),
+		digestNext:  make(map[string]time.Time),
 	}
 }
 
@@ -48,7 +62,7 @@ func (m *EnhancedNotificationManager) LoadChannels() error {
 	defer m.mu.Unlock()
 
 	m.handlers = make(map[uint]*channelInfo)
-	m.rules = make(map[string][]uint)
+	m.rules = make(map[string][]*notification.NotificationRule)
 
 	// 加载所有启用的通知渠道
 	var channels []notification.NotificationChannel
@@ -76,8 +90,8 @@ func (m *EnhancedNotificationManager) LoadChannels() error {
 	if err := m.db.Where("is_enabled = ?", true).Find(&rules).Error; err != nil {
 		helpers.AppLogger.Warnf("加载通知规则失败: %v", err)
 	} else {
-		for _, rule := range rules {
-			m.rules[rule.EventType] = append(m.rules[rule.EventType], rule.ChannelID)
+		for i := range rules {
+			m.rules[rules[i].EventType] = append(m.rules[rules[i].EventType], &rules[i])
 		}
 	}
 
@@ -117,10 +131,15 @@ func (m *EnhancedNotificationManager) createChannelHandler(channel *notification
 			proxyURL = m.getProxyURL()
 		}
 		helpers.AppLogger.Infof("为Telegram渠道使用代理: %s", proxyURL)
+		var handler *TelegramChannelHandler
 		if proxyURL != "" {
-			return NewTelegramChannelHandlerWithProxy(&config, proxyURL), nil
+			handler = NewTelegramChannelHandlerWithProxy(&config, proxyURL)
+		} else {
+			handler = NewTelegramChannelHandler(&config)
 		}
-		return NewTelegramChannelHandler(&config), nil
+		handler.SetDB(m.db)
+		handler.SetCommands(m.telegramCommands, m.telegramCommandRoles)
+		return handler, nil
 
 	case "meow":
 		var config notification.MeoWChannelConfig
@@ -151,45 +170,8 @@ func (m *EnhancedNotificationManager) createChannelHandler(channel *notification
 		return NewCustomWebhookChannelHandler(&config), nil
 
 	default:
-		return nil, fmt.Errorf("未知的渠道类型: %s", channel.ChannelType)
-	}
-}
-
-// SendNotification 发送通知到所有相关渠道
-func (m *EnhancedNotificationManager) SendNotification(ctx context.Context, notification *notification.Notification) error {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	// 获取此事件类型启用的渠道
-	channelIDs, exists := m.rules[string(notification.Type)]
-	if !exists {
-		helpers.AppLogger.Warnf("未找到事件类型 %s 的通知规则", notification.Type)
-		return nil
+		return LoadChannelHandler(m.db, channel)
 	}
-
-	var errs []error
-	for _, channelID := range channelIDs {
-		info, ok := m.handlers[channelID]
-		if !ok {
-			continue
-		}
-
-		// 为每个通知发送创建子context，超时15秒
-		sendCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
-		defer cancel()
-
-		if err := info.handler.Send(sendCtx, notification); err != nil {
-			helpers.AppLogger.Errorf("渠道 [%s] 发送失败: %v", info.config.ChannelType, err)
-			errs = append(errs, err)
-		} else {
-			helpers.AppLogger.Debugf("渠道 [%s] 发送成功", info.config.ChannelType)
-		}
-	}
-
-	if len(errs) > 0 {
-		return fmt.Errorf("部分渠道发送失败: %v", errs)
-	}
-	return nil
 }
 
 // ReloadChannel 重新加载单个渠道
@@ -245,14 +227,17 @@ func (m *EnhancedNotificationManager) GetChannels() []notification.NotificationC
 }
 
 // RegisterTelegramCommands 将自定义命令逻辑注入到所有 Telegram 渠道中
-func (m *EnhancedNotificationManager) RegisterTelegramCommands(cmds map[string]func([]string) helpers.CommandResponse) {
+// roles: 每个命令需要的角色，没有指定的命令只有管理员可以执行
+func (m *EnhancedNotificationManager) RegisterTelegramCommands(cmds map[string]func([]string) helpers.CommandResponse, roles map[string]notification.TelegramRole) {
 	m.mu.Lock() // 修改内部状态，加写锁
 	defer m.mu.Unlock()
 
+	m.telegramCommands = cmds
+	m.telegramCommandRoles = roles
 	for _, info := range m.handlers {
 		// 类型断言：检查这个 handler 是不是 TelegramChannelHandler
 		if tg, ok := info.handler.(*TelegramChannelHandler); ok {
-			tg.SetCommands(cmds)
+			tg.SetCommands(cmds, roles)
 		}
 	}
 }
@@ -0,0 +1,371 @@
+package notificationmanager
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/notification"
+)
+
+// ============ 通知发送队列 ============
+// 通知先写入 notification_log 表，再由队列按渠道发送
+// 发送失败按指数退避重试，程序重启后未发送完的通知继续发送
+
+const (
+	queuePollInterval = 5 * time.Second  // 队列轮询间隔
+	queueBatchSize    = 100              // 每次最多取出的待发送通知数量
+	sendTimeout       = 15 * time.Second // 单次发送超时
+	maxSendAttempts   = 5                // 最多尝试次数，超过后标记为失败
+	retryBaseDelay    = 30 * time.Second // 第一次重试的等待时间，之后每次翻倍
+	retryMaxDelay     = 30 * time.Minute // 重试最长等待时间
+	DedupWindow       = 10 * time.Minute // 去重时间，相同去重键的通知在该时间内只发送一次
+	channelRateLimit  = 20               // 每个渠道每分钟最多发送的通知数量，超过的延后发送
+	channelRateWindow = time.Minute
+	logRetention      = 30 * 24 * time.Hour // 发送记录保留时间
+)
+
+// 每个渠道的发送频率限制
+type channelRateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	sent   map[uint][]time.Time // 渠道在时间窗口内的发送时间
+}
+
+func newChannelRateLimiter(limit int, window time.Duration) *channelRateLimiter {
+	return &channelRateLimiter{limit: limit, window: window, sent: make(map[uint][]time.Time)}
+}
+
+// Allow 检查渠道是否可以发送，可以发送时记录本次发送，否则返回最早可以发送的时间
+func (l *channelRateLimiter) Allow(channelID uint, now time.Time) (bool, time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	times := l.sent[channelID]
+	// 去掉时间窗口之外的记录
+	start := 0
+	for start < len(times) && now.Sub(times[start]) >= l.window {
+		start++
+	}
+	times = times[start:]
+	if len(times) >= l.limit {
+		l.sent[channelID] = times
+		return false, times[0].Add(l.window)
+	}
+	l.sent[channelID] = append(times, now)
+	return true, now
+}
+
+// 第attempts次失败后的重试等待时间
+func retryDelay(attempts int) time.Duration {
+	return helpers.RetryBackoff(attempts, retryBaseDelay, retryMaxDelay)
+}
+
+// 通知的去重键，没有指定时使用类型+标题+内容
+func notificationDedupKey(n *notification.Notification) string {
+	if n.DedupKey != "" {
+		return n.DedupKey
+	}
+	return fmt.Sprintf("%s:%x", n.Type, sha1.Sum([]byte(n.Title+"\n"+n.Content)))
+}
+
+// SendNotification 把通知放入所有相关渠道的发送队列
+// 只有写入队列失败时才返回错误，发送结果可以在通知发送记录中查看
+func (m *EnhancedNotificationManager) SendNotification(ctx context.Context, n *notification.Notification) error {
+	if n.Timestamp.IsZero() {
+		n.Timestamp = time.Now()
+	}
+	if n.RemoveImage && n.Image != "" {
+		// 没有写入发送队列或者都被去重时直接删除，否则等发送完成再删除
+		defer m.releaseTempImage(n.Image)
+	}
+	m.mu.RLock()
+	rules, exists := m.rules[string(n.Type)]
+	type target struct {
+		info   *channelInfo
+		notif  *notification.Notification
+		digest bool // 汇总模式，等汇总时间再发送
+	}
+	targets := make([]target, 0, len(rules))
+	for _, rule := range rules {
+		info, ok := m.handlers[rule.ChannelID]
+		if !ok {
+			continue
+		}
+		if !rule.Match(n) {
+			helpers.AppLogger.Debugf("渠道 [%s] 的规则过滤了通知: %s", info.config.ChannelType, n.Title)
+			continue
+		}
+		if rule.DigestSchedule() != "" {
+			targets = append(targets, target{info: info, notif: n, digest: true})
+			continue
+		}
+		targets = append(targets, target{info: info, notif: renderRuleNotification(rule, n)})
+	}
+	m.mu.RUnlock()
+	if !exists {
+		helpers.AppLogger.Warnf("未找到事件类型 %s 的通知规则", n.Type)
+		return nil
+	}
+	// 去重键使用原始通知计算，不受规则模板影响
+	dedupKey := notificationDedupKey(n)
+
+	// 去重检查和写入需要原子执行，否则同时发送的相同通知都会通过检查
+	m.enqueueMu.Lock()
+	defer m.enqueueMu.Unlock()
+	var errs []error
+	for _, t := range targets {
+		info := t.info
+		if t.digest {
+			if err := m.addDigestItem(info.config.ID, n); err != nil {
+				helpers.AppLogger.Errorf("渠道 [%s] 通知写入汇总失败: %v", info.config.ChannelType, err)
+				errs = append(errs, err)
+			}
+			continue
+		}
+		payload, err := json.Marshal(t.notif)
+		if err != nil {
+			return fmt.Errorf("序列化通知失败: %v", err)
+		}
+		log := &notification.NotificationLog{
+			ChannelID:   info.config.ID,
+			ChannelType: info.config.ChannelType,
+			EventType:   string(n.Type),
+			Title:       t.notif.Title,
+			Content:     t.notif.Content,
+			Payload:     string(payload),
+			DedupKey:    dedupKey,
+			Status:      notification.NotificationLogPending,
+			NextRetryAt: time.Now().Unix(),
+		}
+		if n.RemoveImage {
+			log.TempImage = n.Image
+		}
+		if m.isDuplicate(info.config.ID, dedupKey) {
+			log.Status = notification.NotificationLogSuppressed
+			helpers.AppLogger.Infof("渠道 [%s] %s 内已发送过相同通知，不再发送: %s", info.config.ChannelType, DedupWindow, n.Title)
+		}
+		if err := m.db.Create(log).Error; err != nil {
+			helpers.AppLogger.Errorf("渠道 [%s] 通知写入发送队列失败: %v", info.config.ChannelType, err)
+			errs = append(errs, err)
+		}
+	}
+	m.wakeQueue()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("部分渠道写入发送队列失败: %v", errs)
+	}
+	return nil
+}
+
+// SendNotificationDirect 不经过发送队列，直接发送到所有相关渠道
+// 用于数据库不可用时的告警，不写发送记录，不去重，不汇总，失败也不重试
+func (m *EnhancedNotificationManager) SendNotificationDirect(ctx context.Context, n *notification.Notification) error {
+	if n.Timestamp.IsZero() {
+		n.Timestamp = time.Now()
+	}
+	type target struct {
+		info  *channelInfo
+		notif *notification.Notification
+	}
+	m.mu.RLock()
+	rules := m.rules[string(n.Type)]
+	targets := make([]target, 0, len(rules))
+	for _, rule := range rules {
+		info, ok := m.handlers[rule.ChannelID]
+		if !ok || !rule.Match(n) {
+			continue
+		}
+		targets = append(targets, target{info: info, notif: renderRuleNotification(rule, n)})
+	}
+	m.mu.RUnlock()
+
+	var mu sync.Mutex
+	var errs []error
+	var wg sync.WaitGroup
+	for _, t := range targets {
+		wg.Add(1)
+		go func(t target) {
+			defer wg.Done()
+			sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+			defer cancel()
+			if err := t.info.handler.Send(sendCtx, t.notif); err != nil {
+				helpers.AppLogger.Errorf("渠道 [%s] 直接发送通知失败: %v", t.info.config.ChannelType, err)
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}(t)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return fmt.Errorf("部分渠道发送失败: %v", errs)
+	}
+	return nil
+}
+
+// 去重时间内渠道是否已经有相同去重键的通知（待发送或已发送）
+func (m *EnhancedNotificationManager) isDuplicate(channelID uint, dedupKey string) bool {
+	var count int64
+	m.db.Model(&notification.NotificationLog{}).
+		Where("channel_id = ? AND dedup_key = ? AND status IN ? AND created_at > ?", channelID, dedupKey,
+			[]notification.NotificationLogStatus{notification.NotificationLogPending, notification.NotificationLogSent},
+			time.Now().Add(-DedupWindow)).
+		Count(&count)
+	return count > 0
+}
+
+// StartQueue 启动通知发送队列
+func (m *EnhancedNotificationManager) StartQueue() {
+	m.queueOnce.Do(func() {
+		go m.runQueue()
+		helpers.AppLogger.Info("通知发送队列已启动")
+	})
+}
+
+func (m *EnhancedNotificationManager) wakeQueue() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (m *EnhancedNotificationManager) runQueue() {
+	helpers.RunQueueLoop(queuePollInterval, m.wake, func() {
+		m.processDigests(time.Now())
+		m.processQueue()
+	}, m.cleanupLogs)
+}
+
+// 取出到期的通知，每个渠道一个协程按顺序发送，某个渠道超时不影响其他渠道
+func (m *EnhancedNotificationManager) processQueue() {
+	var logs []*notification.NotificationLog
+	if err := m.db.Where("status = ? AND next_retry_at <= ?", notification.NotificationLogPending, time.Now().Unix()).
+		Order("id ASC").Limit(queueBatchSize).Find(&logs).Error; err != nil {
+		helpers.AppLogger.Errorf("查询待发送通知失败: %v", err)
+		return
+	}
+	if len(logs) == 0 {
+		return
+	}
+	byChannel := make(map[uint][]*notification.NotificationLog)
+	for _, log := range logs {
+		byChannel[log.ChannelID] = append(byChannel[log.ChannelID], log)
+	}
+	var wg sync.WaitGroup
+	for _, channelLogs := range byChannel {
+		wg.Add(1)
+		go func(channelLogs []*notification.NotificationLog) {
+			defer wg.Done()
+			for _, log := range channelLogs {
+				m.deliver(log)
+			}
+		}(channelLogs)
+	}
+	wg.Wait()
+}
+
+// 发送一条通知并更新发送记录
+func (m *EnhancedNotificationManager) deliver(log *notification.NotificationLog) {
+	defer func() {
+		if log.Status != notification.NotificationLogPending && log.TempImage != "" {
+			m.releaseTempImage(log.TempImage)
+		}
+	}()
+	m.mu.RLock()
+	info, ok := m.handlers[log.ChannelID]
+	m.mu.RUnlock()
+	if !ok {
+		log.Status = notification.NotificationLogFailed
+		log.LastError = "渠道不存在或已禁用"
+		m.db.Save(log)
+		return
+	}
+	now := time.Now()
+	if allowed, next := m.limiter.Allow(log.ChannelID, now); !allowed {
+		// 超过频率限制，延后发送，不计入尝试次数
+		m.db.Model(log).Update("next_retry_at", next.Unix())
+		return
+	}
+	var n notification.Notification
+	if err := json.Unmarshal([]byte(log.Payload), &n); err != nil {
+		log.Status = notification.NotificationLogFailed
+		log.LastError = fmt.Sprintf("解析通知失败: %v", err)
+		m.db.Save(log)
+		return
+	}
+
+	sendCtx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	err := info.handler.Send(sendCtx, &n)
+	cancel()
+
+	log.Attempts++
+	if err == nil {
+		log.Status = notification.NotificationLogSent
+		log.SentAt = time.Now().Unix()
+		log.LastError = ""
+		helpers.AppLogger.Debugf("渠道 [%s] 发送成功", info.config.ChannelType)
+	} else {
+		log.LastError = err.Error()
+		if log.Attempts >= maxSendAttempts {
+			log.Status = notification.NotificationLogFailed
+			helpers.AppLogger.Errorf("渠道 [%s] 发送失败，已尝试 %d 次，不再重试: %v", info.config.ChannelType, log.Attempts, err)
+		} else {
+			delay := retryDelay(log.Attempts)
+			log.NextRetryAt = time.Now().Add(delay).Unix()
+			helpers.AppLogger.Warnf("渠道 [%s] 第 %d 次发送失败，%s 后重试: %v", info.config.ChannelType, log.Attempts, delay, err)
+		}
+	}
+	if err := m.db.Save(log).Error; err != nil {
+		helpers.AppLogger.Errorf("更新通知发送记录失败: %v", err)
+	}
+}
+
+// 没有待发送的通知使用临时图片时删除图片
+func (m *EnhancedNotificationManager) releaseTempImage(imagePath string) {
+	var count int64
+	if err := m.db.Model(&notification.NotificationLog{}).
+		Where("temp_image = ? AND status = ?", imagePath, notification.NotificationLogPending).
+		Count(&count).Error; err != nil || count > 0 {
+		return
+	}
+	if err := os.Remove(imagePath); err != nil && !os.IsNotExist(err) {
+		helpers.AppLogger.Warnf("删除通知临时图片 %s 失败: %v", imagePath, err)
+	}
+}
+
+// RetryLog 重新发送失败的通知
+func (m *EnhancedNotificationManager) RetryLog(logID uint) error {
+	var log notification.NotificationLog
+	if err := m.db.Where("id = ?", logID).First(&log).Error; err != nil {
+		return fmt.Errorf("发送记录不存在: %v", err)
+	}
+	if log.Status != notification.NotificationLogFailed && log.Status != notification.NotificationLogSuppressed {
+		return fmt.Errorf("只有发送失败或被去重的通知可以重新发送")
+	}
+	log.Status = notification.NotificationLogPending
+	log.Attempts = 0
+	log.NextRetryAt = time.Now().Unix()
+	if err := m.db.Save(&log).Error; err != nil {
+		return err
+	}
+	m.wakeQueue()
+	return nil
+}
+
+// 删除过期的发送记录，待发送的保留
+func (m *EnhancedNotificationManager) cleanupLogs() {
+	result := m.db.Where("status <> ? AND created_at < ?", notification.NotificationLogPending, time.Now().Add(-logRetention)).Delete(&notification.NotificationLog{})
+	if result.Error != nil {
+		helpers.AppLogger.Errorf("清理通知发送记录失败: %v", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		helpers.AppLogger.Infof("已清理 %d 条过期的通知发送记录", result.RowsAffected)
+	}
+}
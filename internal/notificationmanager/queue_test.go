@@ -0,0 +1,97 @@
+package notificationmanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"Q115-STRM/internal/notification"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{attempts: 1, expected: 30 * time.Second},
+		{attempts: 2, expected: time.Minute},
+		{attempts: 4, expected: 4 * time.Minute},
+		{attempts: 10, expected: retryMaxDelay},
+	}
+	for _, tt := range tests {
+		if actual := retryDelay(tt.attempts); actual != tt.expected {
+			t.Errorf("第 %d 次失败，期望: %s, 实际: %s", tt.attempts, tt.expected, actual)
+		}
+	}
+}
+
+func TestChannelRateLimiter(t *testing.T) {
+	limiter := newChannelRateLimiter(2, time.Minute)
+	now := time.Now()
+	if ok, _ := limiter.Allow(1, now); !ok {
+		t.Fatal("第1条应该允许发送")
+	}
+	if ok, _ := limiter.Allow(1, now.Add(time.Second)); !ok {
+		t.Fatal("第2条应该允许发送")
+	}
+	ok, next := limiter.Allow(1, now.Add(2*time.Second))
+	if ok || !next.Equal(now.Add(time.Minute)) {
+		t.Fatalf("第3条应该延后到 %s, 实际: %v %s", now.Add(time.Minute), ok, next)
+	}
+	// 其他渠道不受影响
+	if ok, _ := limiter.Allow(2, now.Add(2*time.Second)); !ok {
+		t.Fatal("其他渠道应该允许发送")
+	}
+	// 时间窗口过后可以继续发送
+	if ok, _ := limiter.Allow(1, now.Add(time.Minute)); !ok {
+		t.Fatal("时间窗口过后应该允许发送")
+	}
+}
+
+func TestNotificationDedupKey(t *testing.T) {
+	a := &notification.Notification{Type: notification.SyncError, Title: "同步错误", Content: "错误A"}
+	b := &notification.Notification{Type: notification.SyncError, Title: "同步错误", Content: "错误A"}
+	c := &notification.Notification{Type: notification.SyncError, Title: "同步错误", Content: "错误B"}
+	if notificationDedupKey(a) != notificationDedupKey(b) || notificationDedupKey(a) == notificationDedupKey(c) {
+		t.Errorf("默认去重键错误")
+	}
+	c.DedupKey = "sync_error:A"
+	if notificationDedupKey(c) != "sync_error:A" {
+		t.Errorf("指定的去重键没有生效: %s", notificationDedupKey(c))
+	}
+}
+
+type recordingHandler struct {
+	sent []*notification.Notification
+}
+
+func (h *recordingHandler) Send(ctx context.Context, n *notification.Notification) error {
+	h.sent = append(h.sent, n)
+	return nil
+}
+
+func (h *recordingHandler) GetChannelType() string { return "test" }
+
+func (h *recordingHandler) IsHealthy() bool { return true }
+
+func TestSendNotificationDirect(t *testing.T) {
+	// 没有数据库也可以发送
+	m := NewEnhancedNotificationManager(nil, nil)
+	alert, filtered := &recordingHandler{}, &recordingHandler{}
+	m.handlers[1] = &channelInfo{handler: alert, config: &notification.NotificationChannel{ID: 1, ChannelType: "test"}}
+	m.handlers[2] = &channelInfo{handler: filtered, config: &notification.NotificationChannel{ID: 2, ChannelType: "test"}}
+	m.rules[string(notification.SystemAlert)] = []*notification.NotificationRule{
+		{ChannelID: 1, TitleTemplate: "[告警] {{title}}"},
+		{ChannelID: 2, MinPriority: notification.HighPriority},
+	}
+	n := &notification.Notification{Type: notification.SystemAlert, Title: "数据库不可用", Priority: notification.NormalPriority}
+	if err := m.SendNotificationDirect(context.Background(), n); err != nil {
+		t.Fatalf("直接发送失败: %v", err)
+	}
+	if len(alert.sent) != 1 || alert.sent[0].Title != "[告警] 数据库不可用" {
+		t.Errorf("应该使用规则模板发送: %+v", alert.sent)
+	}
+	if len(filtered.sent) != 0 {
+		t.Errorf("被规则过滤的渠道不应该发送")
+	}
+}
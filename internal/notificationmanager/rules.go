@@ -0,0 +1,57 @@
+package notificationmanager
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"Q115-STRM/internal/notification"
+)
+
+// 模板中没有对应值的变量
+var unknownTemplateVarRegex = regexp.MustCompile(`\{\{[^{}]*\}\}`)
+
+// 规则模板可以使用的变量，Metadata 中的键也可以直接使用
+func ruleTemplateVars(n *notification.Notification) map[string]string {
+	vars := make(map[string]string, len(n.Metadata)+10)
+	for key, value := range n.Metadata {
+		vars[key] = fmt.Sprintf("%v", value)
+	}
+	vars["title"] = n.Title
+	vars["content"] = n.Content
+	vars["timestamp"] = n.Timestamp.Format("2006-01-02 15:04:05")
+	vars["image"] = n.Image
+	vars["type"] = string(n.Type)
+	vars["priority"] = string(n.Priority)
+	vars["sync_path"] = n.SyncPath
+	vars["library"] = n.Library
+	vars["category"] = n.Category
+	vars["user"] = n.User
+	return vars
+}
+
+// 替换模板中的 {{变量}}，没有值的变量替换为空
+func renderRuleTemplate(tpl string, vars map[string]string) string {
+	for key, value := range vars {
+		tpl = strings.ReplaceAll(tpl, "{{"+key+"}}", value)
+	}
+	return strings.TrimSpace(unknownTemplateVarRegex.ReplaceAllString(tpl, ""))
+}
+
+// 使用规则的标题、内容模板生成发送到该渠道的通知，规则没有模板时返回原通知
+func renderRuleNotification(rule *notification.NotificationRule, n *notification.Notification) *notification.Notification {
+	if rule.TitleTemplate == "" && rule.BodyTemplate == "" {
+		return n
+	}
+	vars := ruleTemplateVars(n)
+	rendered := *n
+	if rule.TitleTemplate != "" {
+		rendered.Title = renderRuleTemplate(rule.TitleTemplate, vars)
+	}
+	if rule.BodyTemplate != "" {
+		rendered.Content = renderRuleTemplate(rule.BodyTemplate, vars)
+		// 内容完全由模板决定，渠道不再附加 Metadata
+		rendered.Metadata = nil
+	}
+	return &rendered
+}
@@ -0,0 +1,60 @@
+package notificationmanager
+
+import (
+	"testing"
+	"time"
+
+	"Q115-STRM/internal/notification"
+)
+
+func TestNotificationRuleMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     notification.NotificationRule
+		notif    notification.Notification
+		expected bool
+	}{
+		{"没有过滤条件", notification.NotificationRule{}, notification.Notification{}, true},
+		{"同步路径子目录", notification.NotificationRule{SyncPaths: "/电影/, /剧集"}, notification.Notification{SyncPath: "/电影/华语"}, true},
+		{"同步路径前缀不同", notification.NotificationRule{SyncPaths: "/电影"}, notification.Notification{SyncPath: "/电影2"}, false},
+		{"没有同步路径", notification.NotificationRule{SyncPaths: "/电影"}, notification.Notification{}, false},
+		{"优先级不够", notification.NotificationRule{MinPriority: notification.HighPriority}, notification.Notification{Priority: notification.NormalPriority}, false},
+		{"优先级满足", notification.NotificationRule{MinPriority: notification.NormalPriority}, notification.Notification{Priority: notification.HighPriority}, true},
+		{"媒体库", notification.NotificationRule{Libraries: "电影,动画"}, notification.Notification{Library: "动画"}, true},
+		{"刮削分类不算媒体库", notification.NotificationRule{Libraries: "电影"}, notification.Notification{Category: "电影"}, false},
+		{"刮削分类", notification.NotificationRule{Categories: "华语电影"}, notification.Notification{Category: "华语电影"}, true},
+		{"用户不匹配", notification.NotificationRule{Users: "爸爸"}, notification.Notification{User: "孩子"}, false},
+	}
+	for _, tt := range tests {
+		if actual := tt.rule.Match(&tt.notif); actual != tt.expected {
+			t.Errorf("%s: 期望 %v, 实际 %v", tt.name, tt.expected, actual)
+		}
+	}
+}
+
+func TestRenderRuleNotification(t *testing.T) {
+	n := &notification.Notification{
+		Title:     "▶️ 开始播放 电影",
+		Content:   "原内容",
+		User:      "孩子",
+		Metadata:  map[string]interface{}{"观看时长": "1小时"},
+		Timestamp: time.Now(),
+	}
+	// 没有模板时使用原通知
+	if renderRuleNotification(&notification.NotificationRule{}, n) != n {
+		t.Fatal("没有模板时应返回原通知")
+	}
+	rendered := renderRuleNotification(&notification.NotificationRule{
+		TitleTemplate: "{{user}} {{title}}",
+		BodyTemplate:  "时长: {{观看时长}} {{missing}}",
+	}, n)
+	if rendered.Title != "孩子 ▶️ 开始播放 电影" {
+		t.Errorf("标题渲染错误: %s", rendered.Title)
+	}
+	if rendered.Content != "时长: 1小时" {
+		t.Errorf("内容渲染错误: %q", rendered.Content)
+	}
+	if rendered.Metadata != nil || n.Content != "原内容" {
+		t.Error("使用内容模板时不应修改原通知，也不再附加 Metadata")
+	}
+}
@@ -0,0 +1,238 @@
+package notificationmanager
+
+import (
+	"fmt"
+	"html"
+	"slices"
+	"strconv"
+	"strings"
+
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/notification"
+
+	"gorm.io/gorm"
+)
+
+// ============ Telegram机器人用户权限 ============
+// 渠道没有添加用户时，只有配置的私聊可以执行命令，并且不能管理用户，第一个管理员只能在网页中添加
+// 群组中的所有成员都能发送命令，所以没有添加用户时群组中不能执行命令
+// 添加用户后只有列表中的用户可以执行命令，并且按角色限制可以执行的命令
+
+// 命令记录中回复内容的最大长度
+const telegramAuditResultLimit = 500
+
+// 机器人内置命令需要的角色，未列出的内置命令所有人都可以执行
+var builtinTelegramCommandRoles = map[string]notification.TelegramRole{
+	"users":    notification.TelegramRoleAdmin,
+	"user_set": notification.TelegramRoleAdmin,
+	"user_del": notification.TelegramRoleAdmin,
+}
+
+// 命令需要的角色，注册时没有指定角色的命令只有管理员可以执行
+func (h *TelegramChannelHandler) requiredRole(cmd string) notification.TelegramRole {
+	if role, ok := builtinTelegramCommandRoles[cmd]; ok {
+		return role
+	}
+	if role, ok := h.commandRoles[cmd]; ok {
+		return role
+	}
+	if _, ok := h.customCommands[cmd]; ok {
+		return notification.TelegramRoleAdmin
+	}
+	// help、status 和未知命令
+	return notification.TelegramRoleViewer
+}
+
+// authorize 检查命令发送者的角色
+func (h *TelegramChannelHandler) authorize(source helpers.CommandSource, cmd string) error {
+	var users []notification.TelegramUser
+	if h.db == nil {
+		// 无法查询用户时按没有用户处理
+		return h.checkUserRole(users, source, cmd)
+	}
+	if err := h.db.Where("channel_id = ?", h.config.ChannelID).Find(&users).Error; err != nil {
+		return fmt.Errorf("查询机器人用户失败")
+	}
+	return h.checkUserRole(users, source, cmd)
+}
+
+// 按渠道的用户列表检查命令发送者的角色
+func (h *TelegramChannelHandler) checkUserRole(users []notification.TelegramUser, source helpers.CommandSource, cmd string) error {
+	if len(users) == 0 {
+		if _, ok := builtinTelegramCommandRoles[cmd]; ok {
+			return fmt.Errorf("还没有添加用户，请先在网页的通知设置中添加第一个管理员")
+		}
+		if source.ChatType != "private" {
+			return fmt.Errorf("还没有添加用户，群组中不能执行命令，请先在网页的通知设置中添加用户")
+		}
+		// 没有配置ChatID时任何人都能私聊机器人，必须是配置中的聊天才能执行
+		if h.config == nil || !slices.Contains(helpers.ParseChatIDs(h.config.ChatID), source.ChatID) {
+			return fmt.Errorf("还没有添加用户，只有配置的聊天可以执行命令，请先在网页的通知设置中添加用户")
+		}
+		return nil
+	}
+	for _, user := range users {
+		if user.UserID != source.UserID {
+			continue
+		}
+		if user.Role.Level() < h.requiredRole(cmd).Level() {
+			return fmt.Errorf("权限不足，/%s 需要 %s 角色", cmd, h.requiredRole(cmd))
+		}
+		return nil
+	}
+	return fmt.Errorf("你没有使用机器人的权限，请联系管理员添加用户ID %d", source.UserID)
+}
+
+// audit 记录通过机器人执行的命令
+func (h *TelegramChannelHandler) audit(source helpers.CommandSource, cmd string, args []string, allowed bool, result string) {
+	if h.db == nil {
+		return
+	}
+	if runes := []rune(result); len(runes) > telegramAuditResultLimit {
+		result = string(runes[:telegramAuditResultLimit])
+	}
+	log := &notification.TelegramCommandLog{
+		ChannelID: h.config.ChannelID,
+		ChatID:    source.ChatID,
+		ChatType:  source.ChatType,
+		UserID:    source.UserID,
+		Username:  source.Username,
+		Command:   cmd,
+		Args:      strings.Join(args, " "),
+		Allowed:   allowed,
+		Result:    result,
+	}
+	if err := h.db.Create(log).Error; err != nil {
+		helpers.AppLogger.Warnf("记录Telegram命令 %s 失败: %v", cmd, err)
+	}
+	if !allowed {
+		helpers.AppLogger.Warnf("Telegram用户 %d(%s) 没有权限执行命令 %s", source.UserID, source.Username, cmd)
+	}
+}
+
+// 管理机器人用户的内置命令，需要知道渠道ID，所以不由外部注入
+func (h *TelegramChannelHandler) userCommands() map[string]func([]string) helpers.CommandResponse {
+	return map[string]func([]string) helpers.CommandResponse{
+		"users":    h.listUsers,
+		"user_set": h.setUser,
+		"user_del": h.deleteUser,
+	}
+}
+
+func (h *TelegramChannelHandler) listUsers(args []string) helpers.CommandResponse {
+	var users []notification.TelegramUser
+	h.db.Where("channel_id = ?", h.config.ChannelID).Order("id ASC").Find(&users)
+	if len(users) == 0 {
+		return helpers.CommandResponse{Text: "👥 还没有添加用户，请先在网页的通知设置中添加第一个管理员"}
+	}
+	text := fmt.Sprintf("👥 <b>机器人用户</b>\n共 %d 个\n\n", len(users))
+	for _, user := range users {
+		text += fmt.Sprintf("<code>%d</code> %s", user.UserID, user.Role)
+		if user.Username != "" {
+			text += " @" + html.EscapeString(user.Username)
+		}
+		if user.Remark != "" {
+			text += " " + html.EscapeString(user.Remark)
+		}
+		text += "\n"
+	}
+	return helpers.CommandResponse{Text: text}
+}
+
+// 参数: 用户ID 角色 [备注]
+func (h *TelegramChannelHandler) setUser(args []string) helpers.CommandResponse {
+	if len(args) < 2 {
+		return helpers.CommandResponse{Text: "❌ 格式: /user_set 用户ID 角色(viewer/operator/admin) [备注]"}
+	}
+	userID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return helpers.CommandResponse{Text: "❌ 用户ID必须是数字，可以让对方发送 /whoami 查看"}
+	}
+	role := notification.TelegramRole(args[1])
+	if role.Level() == 0 {
+		return helpers.CommandResponse{Text: "❌ 角色只能是 viewer、operator、admin"}
+	}
+	if err := SaveTelegramUser(h.db, &notification.TelegramUser{
+		ChannelID: h.config.ChannelID,
+		UserID:    userID,
+		Role:      role,
+		Remark:    strings.Join(args[2:], " "),
+	}); err != nil {
+		return helpers.CommandResponse{Text: "❌ " + html.EscapeString(err.Error())}
+	}
+	return helpers.CommandResponse{Text: fmt.Sprintf("✅ 已设置用户 %d 的角色为 %s", userID, role)}
+}
+
+// 参数: 用户ID
+func (h *TelegramChannelHandler) deleteUser(args []string) helpers.CommandResponse {
+	if len(args) < 1 {
+		return helpers.CommandResponse{Text: "❌ 格式: /user_del 用户ID"}
+	}
+	userID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return helpers.CommandResponse{Text: "❌ 用户ID必须是数字"}
+	}
+	if err := DeleteTelegramUser(h.db, h.config.ChannelID, userID); err != nil {
+		return helpers.CommandResponse{Text: "❌ " + html.EscapeString(err.Error())}
+	}
+	return helpers.CommandResponse{Text: fmt.Sprintf("✅ 已删除用户 %d", userID)}
+}
+
+// SaveTelegramUser 添加或者修改机器人用户
+// 第一个用户必须是管理员，否则添加后就没有人可以管理用户了
+func SaveTelegramUser(db *gorm.DB, user *notification.TelegramUser) error {
+	var existing notification.TelegramUser
+	err := db.Where("channel_id = ? AND user_id = ?", user.ChannelID, user.UserID).First(&existing).Error
+	if err == nil {
+		if existing.Role == notification.TelegramRoleAdmin && user.Role != notification.TelegramRoleAdmin {
+			if err := checkOtherAdmin(db, user.ChannelID, user.UserID); err != nil {
+				return err
+			}
+		}
+		updates := map[string]interface{}{"role": user.Role, "remark": user.Remark}
+		if user.Username != "" {
+			updates["username"] = user.Username
+		}
+		return db.Model(&existing).Updates(updates).Error
+	}
+	if err != gorm.ErrRecordNotFound {
+		return err
+	}
+	if user.Role != notification.TelegramRoleAdmin {
+		var count int64
+		db.Model(&notification.TelegramUser{}).Where("channel_id = ?", user.ChannelID).Count(&count)
+		if count == 0 {
+			return fmt.Errorf("第一个用户必须是管理员")
+		}
+	}
+	return db.Create(user).Error
+}
+
+// DeleteTelegramUser 删除机器人用户，不能删除最后一个管理员
+func DeleteTelegramUser(db *gorm.DB, channelID uint, userID int64) error {
+	var existing notification.TelegramUser
+	if err := db.Where("channel_id = ? AND user_id = ?", channelID, userID).First(&existing).Error; err != nil {
+		return fmt.Errorf("用户不存在")
+	}
+	if existing.Role == notification.TelegramRoleAdmin {
+		if err := checkOtherAdmin(db, channelID, userID); err != nil {
+			// 只剩一个用户时可以删除，删除后只有私聊可以执行命令
+			var count int64
+			db.Model(&notification.TelegramUser{}).Where("channel_id = ?", channelID).Count(&count)
+			if count > 1 {
+				return err
+			}
+		}
+	}
+	return db.Delete(&existing).Error
+}
+
+// 除了指定用户外是否还有其他管理员
+func checkOtherAdmin(db *gorm.DB, channelID uint, userID int64) error {
+	var count int64
+	db.Model(&notification.TelegramUser{}).Where("channel_id = ? AND user_id <> ? AND role = ?", channelID, userID, notification.TelegramRoleAdmin).Count(&count)
+	if count == 0 {
+		return fmt.Errorf("至少需要保留一个管理员")
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package notificationmanager
+
+import (
+	"testing"
+
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/notification"
+)
+
+func TestTelegramRequiredRole(t *testing.T) {
+	h := &TelegramChannelHandler{}
+	h.SetCommands(map[string]func([]string) helpers.CommandResponse{
+		"scrape": nil,
+		"queue":  nil,
+		"secret": nil,
+	}, map[string]notification.TelegramRole{
+		"scrape": notification.TelegramRoleOperator,
+		"queue":  notification.TelegramRoleViewer,
+	})
+	tests := map[string]notification.TelegramRole{
+		"scrape":   notification.TelegramRoleOperator,
+		"queue":    notification.TelegramRoleViewer,
+		"secret":   notification.TelegramRoleAdmin, // 注册时没有指定角色
+		"user_set": notification.TelegramRoleAdmin,
+		"help":     notification.TelegramRoleViewer,
+	}
+	for cmd, expected := range tests {
+		if actual := h.requiredRole(cmd); actual != expected {
+			t.Errorf("命令 %s 期望角色 %s, 实际 %s", cmd, expected, actual)
+		}
+	}
+	if notification.TelegramRole("guest").Level() != 0 {
+		t.Error("未知角色不应有权限")
+	}
+}
+
+func TestTelegramCheckUserRole(t *testing.T) {
+	h := &TelegramChannelHandler{config: &notification.TelegramChannelConfig{ChatID: "1"}}
+	private := helpers.CommandSource{ChatType: "private", ChatID: 1, UserID: 1}
+	group := helpers.CommandSource{ChatType: "supergroup", ChatID: -100, UserID: 1}
+	if err := h.checkUserRole(nil, private, "status"); err != nil {
+		t.Errorf("没有用户时配置的私聊应可以执行普通命令: %v", err)
+	}
+	if err := h.checkUserRole(nil, helpers.CommandSource{ChatType: "private", ChatID: 2, UserID: 2}, "status"); err == nil {
+		t.Error("没有用户时其他私聊不能执行命令")
+	}
+	if err := (&TelegramChannelHandler{config: &notification.TelegramChannelConfig{}}).checkUserRole(nil, private, "status"); err == nil {
+		t.Error("没有用户并且没有配置ChatID时不能执行命令")
+	}
+	if err := h.checkUserRole(nil, private, "user_set"); err == nil {
+		t.Error("没有用户时不能通过机器人添加用户")
+	}
+	if err := h.checkUserRole(nil, group, "status"); err == nil {
+		t.Error("没有用户时群组中不能执行命令")
+	}
+	users := []notification.TelegramUser{{UserID: 1, Role: notification.TelegramRoleViewer}}
+	if err := h.checkUserRole(users, group, "status"); err != nil {
+		t.Errorf("列表中的用户可以在群组中执行命令: %v", err)
+	}
+	if err := h.checkUserRole(users, private, "user_set"); err == nil {
+		t.Error("viewer 不能管理用户")
+	}
+	if err := h.checkUserRole(users, helpers.CommandSource{ChatType: "private", UserID: 2}, "status"); err == nil {
+		t.Error("不在列表中的用户不能执行命令")
+	}
+}
+
+func TestParseChatIDs(t *testing.T) {
+	ids := helpers.ParseChatIDs(" 123, -100456 ,,abc")
+	if len(ids) != 2 || ids[0] != 123 || ids[1] != -100456 {
+		t.Errorf("解析聊天ID错误: %v", ids)
+	}
+}
@@ -191,6 +191,24 @@ func (c *Client) TakeMoiveName(filename string, prompt string) (*MediaInfoAI, er
 	return &mediaInfo, nil
 }
 
+// Ping checks that the endpoint and API key are usable by listing models, without consuming tokens
+func (c *Client) Ping() error {
+	url := fmt.Sprintf("%s/v1/models", c.baseURL)
+	r := c.resty.R().SetHeader("Authorization", fmt.Sprintf("Bearer %s", c.apiKey)).SetMethod("GET")
+	response, err := c.doRequest(url, r, &RequestConfig{Timeout: 15 * time.Second})
+	if err != nil {
+		return err
+	}
+	if !response.IsSuccess() {
+		var openAIError OpenAIError
+		if err := json.Unmarshal(response.Bytes(), &openAIError); err == nil && openAIError.Message != "" {
+			return fmt.Errorf("%s", openAIError.Message)
+		}
+		return fmt.Errorf("HTTP %d", response.StatusCode())
+	}
+	return nil
+}
+
 // CreateChatCompletion creates a chat completion
 func (c *Client) CreateChatCompletion(message []Message, options *RequestConfig) (*ChatCompletionResponse, error) {
 	url := fmt.Sprintf("%s/v1/chat/completions", c.baseURL)
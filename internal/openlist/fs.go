@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -174,7 +175,8 @@ func (c *Client) FileDetail(path string) (*FileDetail, error) {
 }
 
 // 上传
-func (c *Client) Upload(localFile string, remotePath string) (*UploadResult, error) {
+// overwrite 为true时覆盖已存在的同名文件
+func (c *Client) Upload(localFile string, remotePath string, overwrite bool) (*UploadResult, error) {
 	remotePath = strings.ReplaceAll(remotePath, "\\", "/")
 	if !strings.HasPrefix(remotePath, "/") {
 		remotePath = "/" + remotePath
@@ -188,7 +190,7 @@ func (c *Client) Upload(localFile string, remotePath string) (*UploadResult, err
 	encodedPath := helpers.UrlEncode(remotePath)
 	req.Header.Add("File-Path", encodedPath)
 	req.Header.Add("As-Task", "true")
-	req.Header.Add("overwrite", "false")
+	req.Header.Add("overwrite", strconv.FormatBool(overwrite))
 	req.Header.Add("Content-Type", "multipart/form-data")
 	req.SetMethod(http.MethodPut).SetResult(&result)
 	_, err := c.doRequest("/api/fs/form", req, MakeRequestConfig(0, 1, 300))
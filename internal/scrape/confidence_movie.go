@@ -0,0 +1,116 @@
+package scrape
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"errors"
+	"path/filepath"
+	"slices"
+	"sort"
+)
+
+// 待确认时最多保留的候选影片数量
+const maxReviewCandidates = 5
+
+// 置信度低于阈值，已改为待确认，不是刮削失败
+var errNeedsReview = errors.New("识别置信度低，等待确认")
+
+// 计算识别结果的置信度，低于阈值时改为待确认，返回true表示不继续刮削整理
+// 文件名中带有tmdbid、重新识别（手工确认）的不计算
+func (m *movieScrapeImpl) CheckConfidence(mediaFile *models.ScrapeMediaFile) bool {
+	threshold := m.scrapePath.ReviewConfidence
+	if threshold <= 0 || mediaFile.MediaType == models.MediaTypeOther || mediaFile.IsReScrape || mediaFile.Media == nil {
+		return false
+	}
+	q := m.getIdentifyQuery(mediaFile)
+	if q == nil {
+		return false
+	}
+	current := &models.IdentifyCandidate{
+		TmdbId:       mediaFile.TmdbId,
+		Name:         mediaFile.Media.Name,
+		OriginalName: mediaFile.Media.OriginalName,
+		Year:         mediaFile.Media.Year,
+		Overview:     mediaFile.Media.Overview,
+		PosterPath:   mediaFile.Media.PosterPath,
+		Runtime:      mediaFile.Media.Runtime,
+		IsCurrent:    true,
+	}
+	candidates := []*models.IdentifyCandidate{current}
+	// 不带年份搜索，同名的其他影片作为候选
+	searchResult, err := m.tmdbClient.SearchMovie(q.Name, 0, models.GlobalScrapeSettings.GetTmdbLanguage(), true, false)
+	if err != nil {
+		helpers.AppLogger.Errorf("计算置信度时搜索tmdb电影失败, 名称 %s, 错误: %v", q.Name, err)
+	} else {
+		for _, result := range searchResult.Results {
+			if result.ID == current.TmdbId {
+				current.Popularity = result.Popularity
+				continue
+			}
+			candidates = append(candidates, &models.IdentifyCandidate{
+				TmdbId:       result.ID,
+				Name:         result.Title,
+				OriginalName: result.OriginalTitle,
+				Year:         helpers.ParseYearFromDate(result.ReleaseDate),
+				Overview:     result.Overview,
+				PosterPath:   result.PosterPath,
+				Popularity:   result.Popularity,
+			})
+		}
+	}
+	maxPopularity := 0.0
+	for _, c := range candidates {
+		maxPopularity = max(maxPopularity, c.Popularity)
+	}
+	for _, c := range candidates {
+		c.Confidence = models.ScoreIdentifyCandidate(q, c, maxPopularity)
+	}
+	mediaFile.Confidence = current.Confidence
+	if current.Confidence >= threshold {
+		helpers.AppLogger.Infof("文件 %s 识别置信度 %d，识别结果 %s (%d) tmdbid=%d", mediaFile.VideoFilename, current.Confidence, current.Name, current.Year, current.TmdbId)
+		mediaFile.Save()
+		return false
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Confidence > candidates[j].Confidence
+	})
+	if len(candidates) > maxReviewCandidates {
+		candidates = candidates[:maxReviewCandidates]
+		// 当前识别结果一定要保留
+		if !slices.ContainsFunc(candidates, func(c *models.IdentifyCandidate) bool { return c.IsCurrent }) {
+			candidates[maxReviewCandidates-1] = current
+		}
+	}
+	helpers.AppLogger.Warnf("文件 %s 识别置信度 %d 低于 %d，识别结果 %s (%d) tmdbid=%d，改为待确认", mediaFile.VideoFilename, current.Confidence, threshold, current.Name, current.Year, current.TmdbId)
+	mediaFile.NeedsReview(current.Confidence, threshold, candidates)
+	return true
+}
+
+// 从文件名和文件夹名中重新提取名称、年份，和识别时使用的规则一致
+// 文件名或文件夹名中带有tmdbid的返回nil，不需要计算置信度
+func (m *movieScrapeImpl) getIdentifyQuery(mediaFile *models.ScrapeMediaFile) *models.IdentifyQuery {
+	info := helpers.ExtractMediaInfoRe(filepath.Base(mediaFile.VideoFilename), true, false, m.scrapePath.VideoExtList, m.scrapePath.DeleteKeyword...)
+	folderInfo := helpers.ExtractMediaInfoRe(filepath.Base(mediaFile.Path), true, false, m.scrapePath.VideoExtList, m.scrapePath.DeleteKeyword...)
+	if info.TmdbId != 0 || folderInfo.TmdbId != 0 {
+		return nil
+	}
+	if info.Name == "" || info.Year == 0 {
+		if folderInfo.Name != "" {
+			info.Name = folderInfo.Name
+		}
+		if folderInfo.Year != 0 {
+			info.Year = folderInfo.Year
+		}
+	}
+	if info.Name == "" {
+		return nil
+	}
+	q := &models.IdentifyQuery{
+		Name: info.Name,
+		Year: info.Year,
+	}
+	if mediaFile.VideoCodec != nil {
+		q.DurationMinutes = mediaFile.VideoCodec.DurationInMinutes
+	}
+	return q
+}
@@ -0,0 +1,95 @@
+package scrape
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// 同一个目录的附加内容可能被多个集的整理协程同时处理，整理时加锁
+var extrasMutex sync.Mutex
+
+// 把视频所在目录中的预告片、花絮等附加内容移动到目标目录下Emby/Jellyfin识别的子目录中，例如trailers、extras
+// 只在同一个存储中移动整理时处理，仅刮削、复制模式和跨存储整理时附加内容保留在原位置
+func organizeExtras(renamer renameImpl, scrapePath *models.ScrapePath, mediaFile *models.ScrapeMediaFile, mediaPath, destPath, destPathId string) {
+	if mediaPath == "" || destPathId == "" {
+		return
+	}
+	extrasMutex.Lock()
+	defer extrasMutex.Unlock()
+	extras := models.GetPendingExtraFiles(mediaFile.ScrapePathId, mediaPath)
+	if len(extras) == 0 {
+		return
+	}
+	if mediaFile.ScrapeType == models.ScrapeTypeOnly || mediaFile.RenameType != models.RenameTypeMove || scrapePath.IsCrossStorage() {
+		helpers.AppLogger.Infof("视频 %s 不是在同一个存储中移动整理，目录 %s 中的 %d 个附加内容保留在原位置", mediaFile.VideoFilename, mediaPath, len(extras))
+		return
+	}
+	videoBaseName := strings.TrimSuffix(mediaFile.VideoFilename, filepath.Ext(mediaFile.VideoFilename))
+	for _, extra := range extras {
+		if extra.MediaBaseName != "" && extra.MediaBaseName != videoBaseName {
+			continue
+		}
+		// 电影目录中有多个视频时，无法确定按目录归类的附加内容属于哪一个
+		if extra.MediaBaseName == "" && mediaFile.MediaType == models.MediaTypeMovie && models.CountScrapeMediaFilesInPath(mediaFile.ScrapePathId, mediaPath) > 1 {
+			helpers.AppLogger.Warnf("目录 %s 中有多个视频，无法确定附加内容 %s 属于哪个视频，保留在原位置", mediaPath, extra.FileName)
+			continue
+		}
+		extraPath := filepath.Join(destPath, extra.ExtraType.FolderName())
+		extraPathId, err := renamer.CheckAndMkDir(extraPath, destPath, destPathId)
+		if err != nil {
+			helpers.AppLogger.Errorf("创建附加内容目录 %s 失败: %v", extraPath, err)
+			extra.Failed(mediaFile.ID, err.Error())
+			continue
+		}
+		if err := renamer.MoveFiles(models.MoveNewFileToSourceFile{
+			FileId:       extra.FileId,
+			PathId:       extraPathId,
+			FileFullPath: filepath.Join(extraPath, extra.FileName),
+			Source:       &models.FileSource{Path: extra.Path, PathId: extra.PathId, Name: extra.FileName},
+		}); err != nil {
+			helpers.AppLogger.Errorf("移动附加内容 %s 到 %s 失败: %v", extra.FileName, extraPath, err)
+			extra.Failed(mediaFile.ID, err.Error())
+			continue
+		}
+		helpers.AppLogger.Infof("附加内容 %s 已整理到 %s", extra.FileName, extraPath)
+		extra.Renamed(mediaFile.ID, extraPath)
+	}
+}
+
+// 整理视频已经整理完成之后才发现的附加内容，每次刮削结束时执行
+// 附加内容所在目录的视频都已整理时，跟随已整理的视频移动到目标目录
+func (s *Scrape) organizePendingExtras() {
+	var renamer renameImpl
+	if s.scrapePath.MediaType == models.MediaTypeTvShow {
+		renamer = NewRenameTvShowImpl(s.scrapePath, s.ctx, s.V115Client, s.OpenlistClient, s.BaiduPanClient)
+	} else {
+		renamer = NewRenameMovieImpl(s.scrapePath, s.ctx, s.V115Client, s.OpenlistClient, s.BaiduPanClient)
+	}
+	for _, mediaPath := range models.GetPendingExtraMediaPaths(s.scrapePath.ID) {
+		select {
+		case <-s.ctx.Done():
+			return
+		default:
+		}
+		if s.scrapePath.MediaType == models.MediaTypeTvShow {
+			// 电视剧目录中的附加内容属于整部剧，季目录中的属于这一季
+			if mediaFiles := models.GetRenamedScrapeMediaFilesByExtrasPath(s.scrapePath.ID, mediaPath, true); len(mediaFiles) > 0 {
+				for _, mediaFile := range mediaFiles {
+					organizeExtras(renamer, s.scrapePath, mediaFile, mediaPath, mediaFile.GetDestFullTvshowPath(), mediaFile.NewPathId)
+				}
+				continue
+			}
+			for _, mediaFile := range models.GetRenamedScrapeMediaFilesByExtrasPath(s.scrapePath.ID, mediaPath, false) {
+				organizeExtras(renamer, s.scrapePath, mediaFile, mediaPath, mediaFile.GetDestFullSeasonPath(), mediaFile.NewSeasonPathId)
+			}
+			continue
+		}
+		for _, mediaFile := range models.GetRenamedScrapeMediaFilesByExtrasPath(s.scrapePath.ID, mediaPath, false) {
+			destPath, destPathId := mediaFile.GetMovieOrTvshowDestPath()
+			organizeExtras(renamer, s.scrapePath, mediaFile, mediaPath, destPath, destPathId)
+		}
+	}
+}
@@ -105,7 +105,7 @@ func (i *IdMovieImpl) extractInfo(mediaFile *models.ScrapeMediaFile) (*helpers.M
 // AI提取
 func (i *IdMovieImpl) extractInfoByAI(mediaFile *models.ScrapeMediaFile) (*helpers.MediaInfo, error) {
 	client := models.GlobalScrapeSettings.GetAiClient()
-	info, err := client.TakeMoiveName(mediaFile.VideoFilename, i.scrapePath.GetAiPrompt())
+	info, err := client.TakeMoiveName(mediaFile.IdentifyFileName(), i.scrapePath.GetAiPrompt())
 	if err != nil {
 		helpers.AppLogger.Errorf("强制使用AI从文件名中提取媒体信息失败: %v", err)
 		return nil, err
@@ -126,7 +126,7 @@ func (i *IdMovieImpl) extractInfoByAI(mediaFile *models.ScrapeMediaFile) (*helpe
 			}, nil
 		}
 	}
-	folderName := filepath.Base(mediaFile.Path)
+	folderName := mediaFile.IdentifyFolderName()
 	// 从文件夹中提取信息
 	helpers.AppLogger.Warnf("AI从文件名中提取媒体信息不全，继续从文件夹中补齐信息，文件名 %s， 提取结果 %+v", mediaFile.VideoFilename, info)
 	folderInfo, err := client.TakeMoiveName(folderName, i.scrapePath.GetAiPrompt())
@@ -161,8 +161,8 @@ func (i *IdMovieImpl) extractInfoByAI(mediaFile *models.ScrapeMediaFile) (*helpe
 
 // 正则提取
 func (i *IdMovieImpl) extractInfoByRE(mediaFile *models.ScrapeMediaFile) (*helpers.MediaInfo, error) {
-	folderName := filepath.Base(mediaFile.Path)
-	filename := filepath.Base(mediaFile.VideoFilename)
+	folderName := mediaFile.IdentifyFolderName()
+	filename := filepath.Base(mediaFile.IdentifyFileName())
 	// 从文件名中获取媒体信息
 	info := helpers.ExtractMediaInfoRe(filename, true, false, i.scrapePath.VideoExtList, i.scrapePath.DeleteKeyword...)
 	if info.TmdbId != 0 {
@@ -0,0 +1,237 @@
+package scrape
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/v115open"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 重新生成nfo和图片的筛选条件
+type RegenerateOptions struct {
+	ScrapePathId uint             `json:"scrape_path_id"` // 刮削目录ID，0表示所有刮削目录
+	MediaType    models.MediaType `json:"media_type"`     // 媒体类型，空表示所有类型
+	StartTime    int64            `json:"start_time"`     // 整理时间范围开始（秒级时间戳），0表示不限
+	EndTime      int64            `json:"end_time"`       // 整理时间范围结束（秒级时间戳），0表示不限
+}
+
+// 重新生成任务的进度
+type RegenerateStatus struct {
+	Running   bool              `json:"running"`
+	Options   RegenerateOptions `json:"options"`
+	Total     int               `json:"total"`   // 已处理的记录数
+	Success   int               `json:"success"` // 成功数
+	Failed    int               `json:"failed"`  // 失败数
+	Skipped   int               `json:"skipped"` // 跳过数（仅整理的记录没有nfo）
+	StartTime int64             `json:"start_time"`
+	EndTime   int64             `json:"end_time"`
+}
+
+// 每次从数据库查询的记录数
+const regeneratePageSize = 100
+
+var regenerateStatus = &RegenerateStatus{}
+var regenerateMutex sync.RWMutex
+
+// 返回重新生成任务的进度
+func GetRegenerateStatus() RegenerateStatus {
+	regenerateMutex.RLock()
+	defer regenerateMutex.RUnlock()
+	return *regenerateStatus
+}
+
+// 启动重新生成任务，在后台执行，同时只能运行一个
+// 使用数据库中已刮削的元数据重新生成nfo和图片，不请求TMDB
+// 本地目录直接覆盖目标位置的文件，网盘加入上传队列覆盖上传
+func StartRegenerate(opt RegenerateOptions) error {
+	regenerateMutex.Lock()
+	defer regenerateMutex.Unlock()
+	if regenerateStatus.Running {
+		return errors.New("已有重新生成任务正在运行")
+	}
+	var scrapePathes []*models.ScrapePath
+	if opt.ScrapePathId > 0 {
+		sp := models.GetScrapePathByID(opt.ScrapePathId)
+		if sp == nil {
+			return fmt.Errorf("刮削目录 %d 不存在", opt.ScrapePathId)
+		}
+		scrapePathes = append(scrapePathes, sp)
+	} else {
+		scrapePathes = models.GetScrapePathes("")
+	}
+	regenerateStatus = &RegenerateStatus{
+		Running:   true,
+		Options:   opt,
+		StartTime: time.Now().Unix(),
+	}
+	go func() {
+		for _, sp := range scrapePathes {
+			regenerateScrapePath(sp, opt)
+		}
+		regenerateMutex.Lock()
+		regenerateStatus.Running = false
+		regenerateStatus.EndTime = time.Now().Unix()
+		helpers.AppLogger.Infof("重新生成nfo和图片完成，共 %d 条，成功 %d 条，失败 %d 条，跳过 %d 条", regenerateStatus.Total, regenerateStatus.Success, regenerateStatus.Failed, regenerateStatus.Skipped)
+		regenerateMutex.Unlock()
+	}()
+	return nil
+}
+
+func updateRegenerateStatus(err error, skipped bool) {
+	regenerateMutex.Lock()
+	defer regenerateMutex.Unlock()
+	regenerateStatus.Total++
+	switch {
+	case skipped:
+		regenerateStatus.Skipped++
+	case err != nil:
+		regenerateStatus.Failed++
+	default:
+		regenerateStatus.Success++
+	}
+}
+
+func regenerateScrapePath(sp *models.ScrapePath, opt RegenerateOptions) {
+	if sp.ScrapeType == models.ScrapeTypeOnlyRename {
+		helpers.AppLogger.Infof("刮削目录 %s 是仅整理模式，没有nfo和图片，跳过重新生成", sp.SourcePath)
+		return
+	}
+	if sp.IsScraping {
+		helpers.AppLogger.Warnf("刮削目录 %s 正在刮削，跳过重新生成", sp.SourcePath)
+		return
+	}
+	s := NewScrape(sp)
+	defer s.ctxCancel()
+	if err := s.initOpenClient(); err != nil {
+		helpers.AppLogger.Errorf("重新生成nfo和图片失败，刮削目录 %s 初始化客户端失败: %v", sp.SourcePath, err)
+		return
+	}
+	var movieImpl *movieScrapeImpl
+	var tvshowImpl *tvShowScrapeImpl
+	if sp.MediaType == models.MediaTypeTvShow {
+		tvshowImpl = NewTvShowScrapeImpl(sp, s.ctx, s.V115Client, s.OpenlistClient, s.BaiduPanClient).(*tvShowScrapeImpl)
+	} else {
+		movieImpl = NewMovieScrapeImpl(sp, s.ctx, s.V115Client, s.OpenlistClient, s.BaiduPanClient).(*movieScrapeImpl)
+	}
+	// 电视剧和季的文件只需要生成一次
+	doneTvshows := make(map[uint]bool)
+	doneSeasons := make(map[uint]bool)
+	var lastId uint
+	for {
+		mediaFiles := models.GetRenamedScrapeMediaFiles(sp.ID, opt.MediaType, opt.StartTime, opt.EndTime, lastId, regeneratePageSize)
+		if len(mediaFiles) == 0 {
+			break
+		}
+		for _, mediaFile := range mediaFiles {
+			lastId = mediaFile.ID
+			if mediaFile.ScrapeType == models.ScrapeTypeOnlyRename || mediaFile.MediaType == models.MediaTypeOther {
+				updateRegenerateStatus(nil, true)
+				continue
+			}
+			var err error
+			if tvshowImpl != nil {
+				err = tvshowImpl.RegenerateEpisode(mediaFile, doneTvshows, doneSeasons)
+			} else {
+				err = movieImpl.Regenerate(mediaFile)
+			}
+			if err != nil {
+				helpers.AppLogger.Errorf("重新生成 %s 的nfo和图片失败: %v", mediaFile.VideoFilename, err)
+			}
+			updateRegenerateStatus(err, false)
+		}
+	}
+	helpers.AppLogger.Infof("刮削目录 %s 的nfo和图片已重新生成", sp.SourcePath)
+}
+
+// 重新生成的文件覆盖目标位置已有的文件：本地目录直接移动，网盘加入上传队列覆盖上传
+func (s *ScrapeBase) uploadRegeneratedFiles(mediaFile *models.ScrapeMediaFile, files []uploadFile, isSeasonOrTvshowFile bool) error {
+	ok, err := s.MoveLocalTempFileToDest(mediaFile, files)
+	if err == nil {
+		return nil
+	}
+	if !ok {
+		return err
+	}
+	for _, file := range files {
+		if !helpers.PathExists(file.SourcePath) {
+			continue
+		}
+		err := models.AddOverwriteUploadTaskFromMediaFile(mediaFile, s.scrapePath, file.FileName, file.SourcePath, filepath.Join(file.DestPath, file.FileName), file.DestPathId, isSeasonOrTvshowFile)
+		if err != nil {
+			helpers.AppLogger.Errorf("添加上传任务 %s 失败, 失败原因: %v", file.FileName, err)
+		}
+	}
+	return nil
+}
+
+// 使用数据库中的元数据重新生成电影的nfo和图片
+func (m *movieScrapeImpl) Regenerate(mediaFile *models.ScrapeMediaFile) error {
+	if mediaFile.Media == nil {
+		return errors.New("没有关联的媒体信息")
+	}
+	if mediaFile.NewPathId == "" {
+		return fmt.Errorf("父文件夹不存在，无法上传文件元数据 %s", mediaFile.NewPathName)
+	}
+	mediaFile.ScrapeRootPath = filepath.Join(helpers.ConfigDir, "tmp", "刮削临时文件", fmt.Sprintf("%d", mediaFile.ScrapePathId), "电影或其他")
+	localTempPath := mediaFile.GetTmpFullMoviePath()
+	if err := os.MkdirAll(localTempPath, 0777); err != nil {
+		return err
+	}
+	nfoName := m.GetMovieRealName(mediaFile, "", "nfo")
+	if err := m.GenerateMovieNfo(mediaFile, localTempPath, nfoName, m.scrapePath.ExcludeNoImageActor); err != nil {
+		return err
+	}
+	m.DownloadMovieImages(mediaFile, localTempPath)
+	files := m.GetMovieUploadFiles(mediaFile)
+	m.SyncFilesToSTRMPath(mediaFile, files)
+	return m.uploadRegeneratedFiles(mediaFile, files, false)
+}
+
+// 使用数据库中的元数据重新生成集的nfo和图片，所属的电视剧和季没有生成过时一起生成
+func (t *tvShowScrapeImpl) RegenerateEpisode(mediaFile *models.ScrapeMediaFile, doneTvshows, doneSeasons map[uint]bool) error {
+	if mediaFile.Media == nil || mediaFile.MediaSeason == nil || mediaFile.MediaEpisode == nil {
+		return errors.New("没有关联的媒体信息")
+	}
+	mediaFile.ScrapeRootPath = filepath.Join(helpers.ConfigDir, "tmp", "刮削临时文件", fmt.Sprintf("%d", mediaFile.ScrapePathId), "电视剧")
+	tvshowPath := mediaFile.GetTmpFullTvshowPath()
+	seasonPath := mediaFile.GetTmpFullSeasonPath()
+	if err := os.MkdirAll(seasonPath, 0777); err != nil {
+		return err
+	}
+	if !doneTvshows[mediaFile.MediaId] {
+		doneTvshows[mediaFile.MediaId] = true
+		if err := t.GenerateTvShowNfo(mediaFile, tvshowPath, t.scrapePath.ExcludeNoImageActor); err != nil {
+			return err
+		}
+		t.DownloadTvshowImages(mediaFile, tvshowPath)
+		if err := t.uploadRegeneratedFiles(mediaFile, t.GetTvshowUploadFiles(mediaFile), true); err != nil {
+			return err
+		}
+	}
+	if !doneSeasons[mediaFile.MediaSeasonId] {
+		doneSeasons[mediaFile.MediaSeasonId] = true
+		if err := t.GenerateSeasonNfo(mediaFile); err != nil {
+			return err
+		}
+		seasonImageList := make(map[string]string)
+		seasonImageList[fmt.Sprintf("season%02d-poster.jpg", mediaFile.SeasonNumber)] = mediaFile.MediaSeason.PosterPath
+		t.DownloadImages(tvshowPath, v115open.DEFAULTUA, seasonImageList)
+		if err := t.uploadRegeneratedFiles(mediaFile, t.GetSeasonUploadFiles(mediaFile), true); err != nil {
+			return err
+		}
+	}
+	if err := t.GenerateEpisodeNfo(mediaFile); err != nil {
+		return err
+	}
+	episodeImageList := make(map[string]string)
+	episodeImageList[mediaFile.GetEpisodePosterName()] = mediaFile.MediaEpisode.PosterPath
+	t.DownloadImages(seasonPath, v115open.DEFAULTUA, episodeImageList)
+	files := t.GetEpisodeUploadFiles(mediaFile)
+	t.SyncFilesToSTRMPath(mediaFile, files)
+	return t.uploadRegeneratedFiles(mediaFile, files, false)
+}
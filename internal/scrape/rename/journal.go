@@ -0,0 +1,137 @@
+package rename
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+// 撤销时发现目标已经变化，不能撤销
+var ErrRevertConflict = errors.New("目标已变化")
+
+// 撤销整理操作需要的文件操作，各来源分别实现
+type journalFs interface {
+	// 查询目录下的文件，返回文件ID，不存在返回空字符串
+	findFile(path, pathId, name string) (string, error)
+	// 把文件从整理后的位置移回原位置，pathId是原目录ID
+	moveBack(j *models.RenameJournal, fileId, pathId string) error
+	// 删除整理后的文件或目录
+	removeFile(j *models.RenameJournal, fileId string) error
+	// 目录是否为空
+	isEmptyDir(j *models.RenameJournal, fileId string) (bool, error)
+	// 检查原目录是否存在，不存在则创建，返回目录ID
+	ensureDir(path, pathId string) (string, error)
+}
+
+// 是否需要记录整理操作，没有批次号时（回滚、撤销等刮削任务之外的操作）不记录
+func (r *RenameBase) journalEnabled() bool {
+	return !r.noJournal && r.scrapePath != nil && r.scrapePath.RunNo != ""
+}
+
+// 记录一次整理操作
+func (r *RenameBase) record(mediaFile *models.ScrapeMediaFile, j *models.RenameJournal) {
+	if !r.journalEnabled() {
+		return
+	}
+	j.RunNo = r.scrapePath.RunNo
+	j.ScrapePathId = r.scrapePath.ID
+	j.SourceType = r.scrapePath.SourceType
+	if mediaFile != nil {
+		j.ScrapeMediaFileId = mediaFile.ID
+	}
+	models.AddRenameJournal(j)
+}
+
+// 记录来源为路径的整理操作（本地、OpenList、百度网盘），文件ID就是完整路径
+// 创建目录时sourceFullPath为空，删除时destFullPath为空
+func (r *RenameBase) recordPath(mediaFile *models.ScrapeMediaFile, op models.RenameJournalOp, sourceFullPath, destFullPath string) {
+	j := &models.RenameJournal{Op: op}
+	if sourceFullPath != "" {
+		j.SourcePath = filepath.Dir(sourceFullPath)
+		j.SourcePathId = j.SourcePath
+		j.SourceName = filepath.Base(sourceFullPath)
+		j.FileId = sourceFullPath
+	}
+	if destFullPath != "" {
+		j.DestPath = filepath.Dir(destFullPath)
+		j.DestPathId = j.DestPath
+		j.DestName = filepath.Base(destFullPath)
+		j.FileId = destFullPath
+	}
+	r.record(mediaFile, j)
+}
+
+// 视频文件所在的原目录
+func mediaSourceDir(mediaFile *models.ScrapeMediaFile) (string, string) {
+	if mediaFile.PathId == "" && mediaFile.MediaType == models.MediaTypeTvShow {
+		return mediaFile.TvshowPath, mediaFile.TvshowPathId
+	}
+	return mediaFile.Path, mediaFile.PathId
+}
+
+// 按整理时的逆操作撤销一条日志
+// 移动和改名：整理后的文件还在并且原位置没有同名文件时移回原位置
+// 复制、链接和上传：删除整理后的文件
+// 创建目录：目录为空时删除
+// 删除：无法撤销
+func revertJournal(fs journalFs, j *models.RenameJournal) error {
+	switch j.Op {
+	case models.RenameJournalOpMove, models.RenameJournalOpRename:
+		fileId, err := fs.findFile(j.DestPath, j.DestPathId, j.DestName)
+		if err != nil {
+			return err
+		}
+		if fileId == "" {
+			return fmt.Errorf("%w: 整理后的文件 %s 已不存在", ErrRevertConflict, filepath.Join(j.DestPath, j.DestName))
+		}
+		if j.FileId != "" && j.SourceType == models.SourceType115 && fileId != j.FileId {
+			return fmt.Errorf("%w: 整理后的文件 %s 已被替换", ErrRevertConflict, filepath.Join(j.DestPath, j.DestName))
+		}
+		pathId, err := fs.ensureDir(j.SourcePath, j.SourcePathId)
+		if err != nil {
+			return err
+		}
+		existsId, err := fs.findFile(j.SourcePath, pathId, j.SourceName)
+		if err != nil {
+			return err
+		}
+		if existsId != "" {
+			return fmt.Errorf("%w: 原位置已存在同名文件 %s", ErrRevertConflict, filepath.Join(j.SourcePath, j.SourceName))
+		}
+		return fs.moveBack(j, fileId, pathId)
+	case models.RenameJournalOpCopy, models.RenameJournalOpHardLink, models.RenameJournalOpSymlink, models.RenameJournalOpUpload:
+		fileId, err := fs.findFile(j.DestPath, j.DestPathId, j.DestName)
+		if err != nil {
+			return err
+		}
+		if fileId == "" {
+			helpers.AppLogger.Infof("整理后的文件 %s 已不存在，无需删除", filepath.Join(j.DestPath, j.DestName))
+			return nil
+		}
+		if j.FileId != "" && j.SourceType == models.SourceType115 && fileId != j.FileId {
+			return fmt.Errorf("%w: 整理后的文件 %s 已被替换", ErrRevertConflict, filepath.Join(j.DestPath, j.DestName))
+		}
+		return fs.removeFile(j, fileId)
+	case models.RenameJournalOpMkdir:
+		fileId, err := fs.findFile(j.DestPath, j.DestPathId, j.DestName)
+		if err != nil {
+			return err
+		}
+		if fileId == "" {
+			return nil
+		}
+		empty, err := fs.isEmptyDir(j, fileId)
+		if err != nil {
+			return err
+		}
+		if !empty {
+			return fmt.Errorf("%w: 目录 %s 不为空", ErrRevertConflict, filepath.Join(j.DestPath, j.DestName))
+		}
+		return fs.removeFile(j, fileId)
+	case models.RenameJournalOpDelete:
+		return fmt.Errorf("%w: 删除操作无法撤销", ErrRevertConflict)
+	}
+	return fmt.Errorf("未知的操作类型 %s", j.Op)
+}
@@ -0,0 +1,75 @@
+package rename
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRevertLocalJournal(t *testing.T) {
+	helpers.AppLogger = &helpers.QLogger{Logger: log.New(io.Discard, "", 0)}
+	root := t.TempDir()
+	sourceDir := filepath.Join(root, "source", "Dune.2021")
+	destDir := filepath.Join(root, "dest", "沙丘 (2021)")
+	os.MkdirAll(sourceDir, 0777)
+	os.MkdirAll(destDir, 0777)
+	os.WriteFile(filepath.Join(destDir, "沙丘 (2021).mkv"), []byte("video"), 0666)
+	os.WriteFile(filepath.Join(destDir, "沙丘 (2021).srt"), []byte("sub"), 0666)
+	r := NewRenameLocal(context.Background(), &models.ScrapePath{SourcePath: filepath.Join(root, "source")})
+
+	move := &models.RenameJournal{
+		SourceType: models.SourceTypeLocal, Op: models.RenameJournalOpMove,
+		SourcePath: sourceDir, SourcePathId: sourceDir, SourceName: "Dune.2021.mkv",
+		DestPath: destDir, DestPathId: destDir, DestName: "沙丘 (2021).mkv",
+	}
+	if err := r.Revert(move); err != nil {
+		t.Fatalf("撤销移动失败: %v", err)
+	}
+	if !helpers.PathExists(filepath.Join(sourceDir, "Dune.2021.mkv")) || helpers.PathExists(filepath.Join(destDir, "沙丘 (2021).mkv")) {
+		t.Errorf("视频文件没有移回原位置")
+	}
+	// 再次撤销时整理后的文件已不存在
+	if err := r.Revert(move); !errors.Is(err, ErrRevertConflict) {
+		t.Errorf("整理后的文件不存在时应该是冲突, 实际: %v", err)
+	}
+
+	// 原位置已有同名文件
+	os.WriteFile(filepath.Join(sourceDir, "Dune.2021.srt"), []byte("other"), 0666)
+	sub := &models.RenameJournal{
+		SourceType: models.SourceTypeLocal, Op: models.RenameJournalOpMove,
+		SourcePath: sourceDir, SourcePathId: sourceDir, SourceName: "Dune.2021.srt",
+		DestPath: destDir, DestPathId: destDir, DestName: "沙丘 (2021).srt",
+	}
+	if err := r.Revert(sub); !errors.Is(err, ErrRevertConflict) {
+		t.Errorf("原位置有同名文件时应该是冲突, 实际: %v", err)
+	}
+
+	// 上传的元数据直接删除
+	os.WriteFile(filepath.Join(destDir, "沙丘 (2021).nfo"), []byte("nfo"), 0666)
+	upload := &models.RenameJournal{
+		SourceType: models.SourceTypeLocal, Op: models.RenameJournalOpUpload,
+		DestPath: destDir, DestPathId: destDir, DestName: "沙丘 (2021).nfo",
+	}
+	if err := r.Revert(upload); err != nil || helpers.PathExists(filepath.Join(destDir, "沙丘 (2021).nfo")) {
+		t.Errorf("上传的元数据应该被删除: %v", err)
+	}
+
+	// 目录不为空时不删除
+	mkdir := &models.RenameJournal{
+		SourceType: models.SourceTypeLocal, Op: models.RenameJournalOpMkdir,
+		DestPath: filepath.Dir(destDir), DestPathId: filepath.Dir(destDir), DestName: filepath.Base(destDir),
+	}
+	if err := r.Revert(mkdir); !errors.Is(err, ErrRevertConflict) {
+		t.Errorf("目录不为空时应该是冲突, 实际: %v", err)
+	}
+	os.Remove(filepath.Join(destDir, "沙丘 (2021).srt"))
+	if err := r.Revert(mkdir); err != nil || helpers.PathExists(destDir) {
+		t.Errorf("空目录应该被删除: %v", err)
+	}
+}
@@ -8,4 +8,28 @@ import (
 type RenameBase struct {
 	scrapePath *models.ScrapePath
 	ctx        context.Context
+	noJournal  bool // 不记录整理操作日志，跨存储整理时目标存储的操作不能用来源存储撤销
+}
+
+// 各来源的整理实现
+type Renamer interface {
+	RenameAndMove(mediaFile *models.ScrapeMediaFile, destPath, destPathId, newName string) error
+	CheckAndMkDir(destFullPath, rootPath, rootPathId string) (string, error)
+	RemoveMediaSourcePath(mediaFile *models.ScrapeMediaFile, sp *models.ScrapePath) error
+	ReadFileContent(fileId string) ([]byte, error)
+	CheckAndDeleteFiles(mediaFile *models.ScrapeMediaFile, files []models.WillDeleteFile) error
+	MoveFiles(f models.MoveNewFileToSourceFile) error
+	DeleteDir(path, pathId string) error
+	Rename(fileId, newName string) error
+	// 重命名位置已知的文件，不需要再查询文件原来的位置
+	RenameAt(source models.FileSource, fileId, newName string) error
+	ExistsAndRename(fileId, newName string) (string, error)
+	// 查询目录下的文件，返回文件ID，不存在返回空字符串
+	FindFile(path, pathId, name string) (string, error)
+	// 重命名目录下的文件，文件不存在时跳过
+	RenameFile(path, pathId, name, newName string) error
+}
+
+func (r *RenameBase) disableJournal() {
+	r.noJournal = true
 }
@@ -6,6 +6,7 @@ import (
 	"Q115-STRM/internal/v115open"
 	"context"
 	"errors"
+	"fmt"
 	"path/filepath"
 	"strings"
 )
@@ -45,6 +46,7 @@ func (r *Rename115) RenameAndMove(mediaFile *models.ScrapeMediaFile, destPath, d
 func (r *Rename115) move(mediaFile *models.ScrapeMediaFile, destPathId, destPath, newName string) error {
 	// helpers.AppLogger.Infof("115整理文件：%s 到 %s", mediaFile.Path+"/"+mediaFile.VideoFilename, destPath+"/"+newName)
 	// 先检查是否已存在，如果已存在，就不移动了
+	sourcePath, sourcePathId := mediaSourceDir(mediaFile)
 	detail, detailErr := r.client.GetFsDetailByPath(r.ctx, filepath.Join(destPath, newName))
 	if detail == nil || detailErr != nil || detail.FileId == "" {
 		_, err := r.client.Move(r.ctx, []string{mediaFile.VideoFileId}, destPathId)
@@ -53,6 +55,7 @@ func (r *Rename115) move(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 			return err
 		} else {
 			helpers.AppLogger.Infof("文件 %s 成功移动到 %s", mediaFile.Path+"/"+mediaFile.VideoFilename, destPath+"/"+newName)
+			r.recordFile(mediaFile, models.RenameJournalOpMove, mediaFile.VideoFileId, sourcePath, sourcePathId, mediaFile.VideoFilename, destPath, destPathId, mediaFile.VideoFilename)
 		}
 		if mediaFile.VideoFilename != newName {
 			// 改名
@@ -62,6 +65,7 @@ func (r *Rename115) move(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 				return err
 			} else {
 				helpers.AppLogger.Infof("文件 %s 成功重命名为 %s", mediaFile.VideoFilename, newName)
+				r.recordFile(mediaFile, models.RenameJournalOpRename, mediaFile.VideoFileId, destPath, destPathId, mediaFile.VideoFilename, destPath, destPathId, newName)
 			}
 		}
 		if mediaFile.MediaType != models.MediaTypeTvShow {
@@ -107,6 +111,7 @@ func (r *Rename115) move(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 				helpers.AppLogger.Errorf("115移动字幕文件 %s 失败: %v", sub.FileName, err)
 				continue
 			}
+			r.recordFile(mediaFile, models.RenameJournalOpMove, sub.FileId, sourcePath, sourcePathId, sub.FileName, destPath, destPathId, sub.FileName)
 			// 检查是否需要改名
 			if mediaFile.VideoFilename != newName {
 				// 改名
@@ -119,6 +124,7 @@ func (r *Rename115) move(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 						continue
 					} else {
 						helpers.AppLogger.Infof("字幕文件 %s 成功重命名为 %s", sub.FileName, newSubName)
+						r.recordFile(mediaFile, models.RenameJournalOpRename, sub.FileId, destPath, destPathId, sub.FileName, destPath, destPathId, newSubName)
 					}
 					newSub.FileName = newSubName
 				}
@@ -143,6 +149,7 @@ func (r *Rename115) move(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 					helpers.AppLogger.Errorf("115移动图片文件 %s 失败: %v", imageFile.FileName, err)
 					continue
 				}
+				r.recordFile(mediaFile, models.RenameJournalOpMove, imageFile.FileId, sourcePath, sourcePathId, imageFile.FileName, destPath, destPathId, imageFile.FileName)
 				newSubName := strings.Replace(imageFile.FileName, oldBaseName, mediaFile.NewVideoBaseName, 1)
 				// 检查是否需要改名
 				if newSubName != imageFile.FileName {
@@ -153,6 +160,7 @@ func (r *Rename115) move(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 						continue
 					} else {
 						helpers.AppLogger.Infof("图片文件 %s 成功重命名为 %s", imageFile.FileName, newSubName)
+						r.recordFile(mediaFile, models.RenameJournalOpRename, imageFile.FileId, destPath, destPathId, imageFile.FileName, destPath, destPathId, newSubName)
 					}
 				}
 			}
@@ -164,6 +172,8 @@ func (r *Rename115) move(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 			_, err := r.client.Move(r.ctx, []string{mediaFile.NfoFileId}, destPathId)
 			if err != nil {
 				helpers.AppLogger.Errorf("115移动nfo文件 %s 失败: %v", mediaFile.NfoFileName, err)
+			} else {
+				r.recordFile(mediaFile, models.RenameJournalOpMove, mediaFile.NfoFileId, sourcePath, sourcePathId, mediaFile.NfoFileName, destPath, destPathId, mediaFile.NfoFileName)
 			}
 			// 检查是否需要改名
 			if newNfoName != mediaFile.NfoFileName {
@@ -173,6 +183,7 @@ func (r *Rename115) move(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 					helpers.AppLogger.Errorf("115改名nfo文件 %s 失败: %v", mediaFile.NfoFileName, err)
 				} else {
 					helpers.AppLogger.Infof("nfo文件 %s 成功重命名为 %s", mediaFile.NfoFileName, newNfoName)
+					r.recordFile(mediaFile, models.RenameJournalOpRename, mediaFile.NfoFileId, destPath, destPathId, mediaFile.NfoFileName, destPath, destPathId, newNfoName)
 				}
 
 			}
@@ -187,6 +198,7 @@ func (r *Rename115) copy(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 	var videoFileId string = mediaFile.VideoFileId
 	var pickcode string = mediaFile.VideoPickCode
 	// 先检查是否已存在，如果已存在，就不移动了
+	sourcePath, sourcePathId := mediaSourceDir(mediaFile)
 	detail, detailErr := r.client.GetFsDetailByPath(r.ctx, filepath.Join(destPath, newName))
 	if detail == nil || detailErr != nil || detail.FileId == "" {
 		_, err = r.client.Copy(r.ctx, []string{mediaFile.VideoFileId}, destPathId, false)
@@ -204,6 +216,7 @@ func (r *Rename115) copy(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 			helpers.AppLogger.Infof("复制文件 %s 到 %s 后，新文件ID为 %s", mediaFile.VideoFilename, filepath.Join(destPath, mediaFile.VideoFilename), newDetail.FileId)
 			videoFileId = newDetail.FileId
 			pickcode = newDetail.PickCode
+			r.recordFile(mediaFile, models.RenameJournalOpCopy, videoFileId, sourcePath, sourcePathId, mediaFile.VideoFilename, destPath, destPathId, mediaFile.VideoFilename)
 		}
 		if mediaFile.VideoFilename != newName {
 			// 改名
@@ -213,6 +226,7 @@ func (r *Rename115) copy(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 				return err
 			} else {
 				helpers.AppLogger.Infof("文件 %s 成功重命名为 %s", filepath.Join(mediaFile.Path, mediaFile.VideoFilename), filepath.Join(destPath, newName))
+				r.recordFile(mediaFile, models.RenameJournalOpRename, videoFileId, destPath, destPathId, mediaFile.VideoFilename, destPath, destPathId, newName)
 			}
 		}
 		if mediaFile.MediaType != models.MediaTypeTvShow {
@@ -276,6 +290,7 @@ func (r *Rename115) copy(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 			}
 			newSub.FileId = newSubDetail.FileId
 			newSub.PickCode = newSubDetail.PickCode
+			r.recordFile(mediaFile, models.RenameJournalOpCopy, newSub.FileId, sourcePath, sourcePathId, sub.FileName, destPath, destPathId, sub.FileName)
 			mediaFile.Media.SubtitleFiles = append(mediaFile.Media.SubtitleFiles, newSub)
 			// 检查是否需要改名
 			if newSubName != sub.FileName {
@@ -286,6 +301,7 @@ func (r *Rename115) copy(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 					continue
 				} else {
 					helpers.AppLogger.Infof("字幕文件 %s 成功重命名为 %s", sub.FileName, newSubName)
+					r.recordFile(mediaFile, models.RenameJournalOpRename, newSub.FileId, destPath, destPathId, sub.FileName, destPath, destPathId, newSubName)
 				}
 			}
 		}
@@ -314,6 +330,7 @@ func (r *Rename115) copy(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 					continue
 				}
 				imageFile.FileId = newImageDetail.FileId
+				r.recordFile(mediaFile, models.RenameJournalOpCopy, imageFile.FileId, sourcePath, sourcePathId, imageFile.FileName, destPath, destPathId, imageFile.FileName)
 				newSubName := strings.Replace(imageFile.FileName, oldBaseName, mediaFile.NewVideoBaseName, 1)
 				// 检查是否需要改名
 				if newSubName != imageFile.FileName {
@@ -324,6 +341,7 @@ func (r *Rename115) copy(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 						continue
 					} else {
 						helpers.AppLogger.Infof("图片文件 %s 成功重命名为 %s", imageFile.FileName, newSubName)
+						r.recordFile(mediaFile, models.RenameJournalOpRename, imageFile.FileId, destPath, destPathId, imageFile.FileName, destPath, destPathId, newSubName)
 					}
 				}
 			}
@@ -389,6 +407,7 @@ func (r *Rename115) CheckAndMkDir(destFullPath string, rootPath, rootPathId stri
 			return "", mErr
 		} else {
 			helpers.AppLogger.Infof("父文件夹创建成功，路径：%s，目录ID：%s", currentCheckPath, cpId)
+			r.recordFile(nil, models.RenameJournalOpMkdir, cpId, "", "", "", currentParentPath, currentParentId, p)
 		}
 		currentParentPath = currentCheckPath
 		currentParentId = cpId
@@ -440,6 +459,7 @@ func (r *Rename115) RemoveMediaSourcePath(mediaFile *models.ScrapeMediaFile, sp
 			return err
 		}
 		helpers.AppLogger.Infof("刮削完成，尝试删除115中的文件夹成功, 路径：%s 文件夹ID=%s", sourcePath, sourcePathId)
+		r.recordFile(mediaFile, models.RenameJournalOpDelete, sourcePathId, parentPath, parentId, filepath.Base(sourcePath), "", "", "")
 	}
 	// 再删除电视剧文件夹
 	if mediaFile.PathId != "" {
@@ -479,6 +499,7 @@ func (r *Rename115) RemoveMediaSourcePath(mediaFile *models.ScrapeMediaFile, sp
 				return err
 			}
 			helpers.AppLogger.Infof("刮削完成，删除115中的电视剧文件夹成功, 路径：%s 文件夹ID=%s", mediaFile.TvshowPath, mediaFile.TvshowPathId)
+			r.recordFile(mediaFile, models.RenameJournalOpDelete, mediaFile.TvshowPathId, filepath.Dir(mediaFile.TvshowPath), tvshowParentId, filepath.Base(mediaFile.TvshowPath), "", "", "")
 		}
 	}
 	return nil
@@ -521,6 +542,7 @@ func (r *Rename115) CheckAndDeleteFiles(mediaFile *models.ScrapeMediaFile, files
 			continue
 		}
 		helpers.AppLogger.Infof("删除115文件成功, 路径：%s", f.FullFilePath)
+		r.recordFile(mediaFile, models.RenameJournalOpDelete, fsDetail.FileId, parentPath, parentDetail.FileId, filepath.Base(f.FullFilePath), "", "", "")
 	}
 	return nil
 }
@@ -532,6 +554,8 @@ func (r *Rename115) MoveFiles(f models.MoveNewFileToSourceFile) error {
 		helpers.AppLogger.Infof("115文件存在，无需移动: 路径：%s", f.FileFullPath)
 		return nil
 	}
+	// 需要记录操作日志时，使用调用方提供的原来位置，没有提供时才查询
+	source := r.fileSource(f.FileId, f.Source)
 	// 移动文件
 	_, err = r.client.Move(r.ctx, []string{f.FileId}, f.PathId)
 	if err != nil {
@@ -539,6 +563,9 @@ func (r *Rename115) MoveFiles(f models.MoveNewFileToSourceFile) error {
 		return err
 	}
 	helpers.AppLogger.Infof("移动115文件成功, %s => %s", f.FileId, f.FileFullPath)
+	if source != nil {
+		r.recordFile(nil, models.RenameJournalOpMove, f.FileId, source.Path, source.PathId, source.Name, filepath.Dir(f.FileFullPath), f.PathId, source.Name)
+	}
 	return nil
 }
 
@@ -556,10 +583,20 @@ func (r *Rename115) DeleteDir(path, pathId string) error {
 		return err
 	}
 	helpers.AppLogger.Infof("删除115目录成功, 路径：%s", pathId)
+	r.recordFile(nil, models.RenameJournalOpDelete, pathId, parentPath, parentDetail.FileId, filepath.Base(path), "", "", "")
 	return nil
 }
 
 func (r *Rename115) Rename(fileId, newName string) error {
+	return r.rename(fileId, newName, nil)
+}
+
+func (r *Rename115) RenameAt(source models.FileSource, fileId, newName string) error {
+	return r.rename(fileId, newName, &source)
+}
+
+func (r *Rename115) rename(fileId, newName string, known *models.FileSource) error {
+	source := r.fileSource(fileId, known)
 	// 重命名文件
 	_, err := r.client.ReName(r.ctx, fileId, newName)
 	if err != nil {
@@ -567,6 +604,9 @@ func (r *Rename115) Rename(fileId, newName string) error {
 		return err
 	}
 	helpers.AppLogger.Infof("重命名115文件成功, %s => %s", fileId, newName)
+	if source != nil {
+		r.recordFile(nil, models.RenameJournalOpRename, fileId, source.Path, source.PathId, source.Name, source.Path, source.PathId, newName)
+	}
 	return nil
 }
 
@@ -590,5 +630,103 @@ func (r *Rename115) ExistsAndRename(fileId, newName string) (string, error) {
 		return "", err
 	}
 	helpers.AppLogger.Infof("重命名115文件成功, %s => %s", fileId, newName)
+	if len(fsDetail.Paths) > 0 {
+		parentPath, parentId := fsDetail.GetFullPath(), fsDetail.Paths[len(fsDetail.Paths)-1].FileId
+		r.recordFile(nil, models.RenameJournalOpRename, fileId, parentPath, parentId, fsDetail.FileName, parentPath, parentId, newName)
+	}
 	return fileId, nil
 }
+
+// 记录115的整理操作，目录和文件都用ID定位，撤销时用路径检查是否已变化
+func (r *Rename115) recordFile(mediaFile *models.ScrapeMediaFile, op models.RenameJournalOp, fileId, sourcePath, sourcePathId, sourceName, destPath, destPathId, destName string) {
+	r.record(mediaFile, &models.RenameJournal{
+		Op:           op,
+		FileId:       fileId,
+		SourcePath:   sourcePath,
+		SourcePathId: sourcePathId,
+		SourceName:   sourceName,
+		DestPath:     destPath,
+		DestPathId:   destPathId,
+		DestName:     destName,
+	})
+}
+
+// 需要记录操作日志时返回文件原来的位置，调用方已经知道时直接使用，否则查询一次
+// 不需要记录或者查询失败返回nil
+func (r *Rename115) fileSource(fileId string, known *models.FileSource) *models.FileSource {
+	if !r.journalEnabled() {
+		return nil
+	}
+	if known != nil && known.PathId != "" && known.Name != "" {
+		return known
+	}
+	detail, err := r.client.GetFsDetailByCid(r.ctx, fileId)
+	if err != nil || detail == nil || detail.FileId == "" || len(detail.Paths) == 0 {
+		return nil
+	}
+	return &models.FileSource{Path: detail.GetFullPath(), PathId: detail.Paths[len(detail.Paths)-1].FileId, Name: detail.FileName}
+}
+
+func (r *Rename115) FindFile(path, pathId, name string) (string, error) {
+	return r.findFile(path, pathId, name)
+}
+
+func (r *Rename115) RenameFile(path, pathId, name, newName string) error {
+	fileId, err := r.findFile(path, pathId, name)
+	if err != nil || fileId == "" || name == newName {
+		return err
+	}
+	return r.RenameAt(models.FileSource{Path: path, PathId: pathId, Name: name}, fileId, newName)
+}
+
+func (r *Rename115) Revert(j *models.RenameJournal) error {
+	return revertJournal(r, j)
+}
+
+func (r *Rename115) findFile(path, pathId, name string) (string, error) {
+	detail, err := r.client.GetFsDetailByPath(r.ctx, filepath.ToSlash(filepath.Join(path, name)))
+	if err != nil || detail == nil || detail.FileId == "" {
+		return "", nil
+	}
+	return detail.FileId, nil
+}
+
+func (r *Rename115) moveBack(j *models.RenameJournal, fileId, pathId string) error {
+	if j.DestPathId != pathId {
+		if _, err := r.client.Move(r.ctx, []string{fileId}, pathId); err != nil {
+			return err
+		}
+	}
+	if j.DestName != j.SourceName {
+		if _, err := r.client.ReName(r.ctx, fileId, j.SourceName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Rename115) removeFile(j *models.RenameJournal, fileId string) error {
+	_, err := r.client.Del(r.ctx, []string{fileId}, j.DestPathId)
+	return err
+}
+
+func (r *Rename115) isEmptyDir(j *models.RenameJournal, fileId string) (bool, error) {
+	fsList, err := r.client.GetFsList(r.ctx, fileId, true, false, true, 0, 1)
+	if err != nil {
+		return false, err
+	}
+	return fsList.Count == 0, nil
+}
+
+// 原目录已被删除时在来源路径下重新创建
+func (r *Rename115) ensureDir(path, pathId string) (string, error) {
+	detail, err := r.client.GetFsDetailByPath(r.ctx, filepath.ToSlash(path))
+	if err == nil && detail != nil && detail.FileId != "" {
+		return detail.FileId, nil
+	}
+	rel, err := filepath.Rel(r.scrapePath.SourcePath, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("%w: 原目录 %s 已不存在", ErrRevertConflict, path)
+	}
+	return r.CheckAndMkDir(path, r.scrapePath.SourcePath, r.scrapePath.SourcePathId)
+}
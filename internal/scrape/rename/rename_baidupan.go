@@ -137,6 +137,9 @@ func (r *RenameBaiduPan) move(mediaFile *models.ScrapeMediaFile, newName, newPat
 		helpers.AppLogger.Errorf("百度网盘移动文件失败: %v", err)
 		return err
 	}
+	for _, item := range fileList {
+		r.recordPath(mediaFile, models.RenameJournalOpMove, item.Path, filepath.ToSlash(filepath.Join(item.Dest, item.NewName)))
+	}
 	return nil
 }
 
@@ -205,6 +208,9 @@ func (r *RenameBaiduPan) copy(mediaFile *models.ScrapeMediaFile, newName, newPat
 		helpers.AppLogger.Errorf("百度网盘 复制文件失败: %v", err)
 		return err
 	}
+	for _, item := range fileList {
+		r.recordPath(mediaFile, models.RenameJournalOpCopy, item.Path, filepath.ToSlash(filepath.Join(item.Dest, item.NewName)))
+	}
 	start := 0
 	for {
 		// 查询新的fsid
@@ -265,6 +271,7 @@ func (r *RenameBaiduPan) CheckAndMkDir(destFullPath string, rootPath, rootPathId
 			helpers.AppLogger.Errorf("11百度网盘 创建文件夹失败: %s 错误：%v", destFullPath, err)
 			return destFullPath, err
 		}
+		r.recordPath(nil, models.RenameJournalOpMkdir, "", destFullPath)
 	}
 	return destFullPath, nil
 }
@@ -299,6 +306,7 @@ func (r *RenameBaiduPan) RemoveMediaSourcePath(mediaFile *models.ScrapeMediaFile
 			return err
 		}
 		helpers.AppLogger.Infof("刮削完成，尝试删除百度网盘文件夹成功, 路径：%s", sourcePath)
+		r.recordPath(mediaFile, models.RenameJournalOpDelete, sourcePath, "")
 	}
 	// 再删除电视剧文件夹
 	if mediaFile.PathId != "" {
@@ -321,6 +329,7 @@ func (r *RenameBaiduPan) RemoveMediaSourcePath(mediaFile *models.ScrapeMediaFile
 				return err
 			}
 			helpers.AppLogger.Infof("刮削完成，尝试删除百度网盘电视剧文件夹成功, 路径：%s", mediaFile.TvshowPathId)
+			r.recordPath(mediaFile, models.RenameJournalOpDelete, mediaFile.TvshowPath, "")
 		}
 
 	}
@@ -350,12 +359,13 @@ func (r *RenameBaiduPan) CheckAndDeleteFiles(mediaFile *models.ScrapeMediaFile,
 			helpers.AppLogger.Infof("百度网盘 文件不存在，无需删除: 路径：%s", f.FullFilePath)
 			continue
 		}
-		err = r.client.Del(r.ctx, []string{filepath.Dir(f.FullFilePath)})
+		err = r.client.Del(r.ctx, []string{f.FullFilePath})
 		if err != nil {
 			helpers.AppLogger.Errorf("删除百度网盘文件失败: 路径：%s %v", f.FullFilePath, err)
 			continue
 		}
 		helpers.AppLogger.Infof("删除百度网盘文件成功, 路径：%s", f.FullFilePath)
+		r.recordPath(mediaFile, models.RenameJournalOpDelete, f.FullFilePath, "")
 	}
 	return nil
 }
@@ -375,15 +385,29 @@ func (r *RenameBaiduPan) MoveFiles(f models.MoveNewFileToSourceFile) error {
 		return err
 	}
 	helpers.AppLogger.Infof("移动百度网盘文件成功: %s => %s", f.FileId, newFileId)
+	r.recordPath(nil, models.RenameJournalOpMove, f.FileId, newFileId)
 	return nil
 }
 
 func (r *RenameBaiduPan) DeleteDir(path, pathId string) error {
-	return r.client.Del(r.ctx, []string{pathId})
+	if err := r.client.Del(r.ctx, []string{pathId}); err != nil {
+		return err
+	}
+	r.recordPath(nil, models.RenameJournalOpDelete, pathId, "")
+	return nil
 }
 
 func (r *RenameBaiduPan) Rename(fileId, newName string) error {
-	return r.client.Rename(r.ctx, filepath.Dir(fileId), newName)
+	if err := r.client.Rename(r.ctx, fileId, newName); err != nil {
+		return err
+	}
+	r.recordPath(nil, models.RenameJournalOpRename, fileId, filepath.ToSlash(filepath.Join(filepath.Dir(fileId), newName)))
+	return nil
+}
+
+// 文件ID就是路径，不需要查询原来的位置
+func (r *RenameBaiduPan) RenameAt(source models.FileSource, fileId, newName string) error {
+	return r.Rename(fileId, newName)
 }
 
 // 检查是否存在，存在就改名字，然后返回新的fileId
@@ -408,3 +432,53 @@ func (r *RenameBaiduPan) ExistsAndRename(fileId, newName string) (string, error)
 	helpers.AppLogger.Infof("重命名百度网盘文件成功, %s => %s", fileId, newName)
 	return filepath.ToSlash(filepath.Join(filepath.Dir(fileId), newName)), nil
 }
+
+func (r *RenameBaiduPan) FindFile(path, pathId, name string) (string, error) {
+	return r.findFile(path, pathId, name)
+}
+
+func (r *RenameBaiduPan) RenameFile(path, pathId, name, newName string) error {
+	fileId, err := r.findFile(path, pathId, name)
+	if err != nil || fileId == "" || name == newName {
+		return err
+	}
+	return r.Rename(fileId, newName)
+}
+
+func (r *RenameBaiduPan) Revert(j *models.RenameJournal) error {
+	return revertJournal(r, j)
+}
+
+func (r *RenameBaiduPan) findFile(path, pathId, name string) (string, error) {
+	fullPath := filepath.ToSlash(filepath.Join(pathId, name))
+	detail, _ := r.client.FileExists(r.ctx, fullPath)
+	if detail == nil || detail.ServerFilename == "" {
+		return "", nil
+	}
+	return fullPath, nil
+}
+
+// 移动时指定新名字，一次完成移回原目录和改回原名
+func (r *RenameBaiduPan) moveBack(j *models.RenameJournal, fileId, pathId string) error {
+	return r.client.MoveBatch(r.ctx, []baidupan.MoveOrCopyItem{{
+		Path:    fileId,
+		Dest:    filepath.ToSlash(pathId),
+		NewName: j.SourceName,
+	}})
+}
+
+func (r *RenameBaiduPan) removeFile(j *models.RenameJournal, fileId string) error {
+	return r.client.Del(r.ctx, []string{fileId})
+}
+
+func (r *RenameBaiduPan) isEmptyDir(j *models.RenameJournal, fileId string) (bool, error) {
+	fsList, err := r.client.GetFileList(r.ctx, fileId, 0, 1, 0, 1)
+	if err != nil {
+		return false, err
+	}
+	return len(fsList) == 0, nil
+}
+
+func (r *RenameBaiduPan) ensureDir(path, pathId string) (string, error) {
+	return r.CheckAndMkDir(filepath.ToSlash(pathId), "", "")
+}
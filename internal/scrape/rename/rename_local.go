@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 type RenameLocal struct {
@@ -33,9 +34,9 @@ func (r *RenameLocal) RenameAndMove(mediaFile *models.ScrapeMediaFile, destPath,
 	case models.RenameTypeMove:
 		r.move(mediaFile, destPathId, newName)
 	case models.RenameTypeHardSymlink:
-		r.symlink(mediaFile, destPathId, newName, true)
+		return r.symlink(mediaFile, destPathId, newName, true)
 	case models.RenameTypeSoftSymlink:
-		r.symlink(mediaFile, destPathId, newName, false)
+		return r.symlink(mediaFile, destPathId, newName, false)
 	}
 	return nil
 }
@@ -57,6 +58,7 @@ func (r *RenameLocal) move(mediaFile *models.ScrapeMediaFile, destPathId, newNam
 			return err
 		} else {
 			helpers.AppLogger.Infof("文件 %s 成功移动到 %s", sourcePath+"/"+mediaFile.VideoFilename, destPathId+"/"+newName)
+			r.recordPath(mediaFile, models.RenameJournalOpMove, sourceFullPath, destFullPath)
 		}
 	}
 	if mediaFile.MediaType != models.MediaTypeTvShow {
@@ -83,6 +85,7 @@ func (r *RenameLocal) move(mediaFile *models.ScrapeMediaFile, destPathId, newNam
 				helpers.AppLogger.Errorf("移动字幕文件 %s 到 %s 失败: %v", sub.FileName, destPathId+"/"+newSubName, err)
 			} else {
 				helpers.AppLogger.Infof("字幕文件 %s 成功移动到 %s", sub.FileId, destPathId+"/"+newSubName)
+				r.recordPath(mediaFile, models.RenameJournalOpMove, sub.FileId, newSubFullPath)
 				newSub := &models.MediaMetaFiles{
 					FileName: newSubName,
 					FileId:   newSubFullPath,
@@ -114,6 +117,7 @@ func (r *RenameLocal) move(mediaFile *models.ScrapeMediaFile, destPathId, newNam
 					helpers.AppLogger.Errorf("移动图片文件 %s 到 %s 失败: %v", imageFile.FileName, destPathId+"/"+newImageName, err)
 				} else {
 					helpers.AppLogger.Infof("图片文件 %s 成功移动到 %s", imageFile.FileId, destPathId+"/"+newImageName)
+					r.recordPath(mediaFile, models.RenameJournalOpMove, imageFile.FileId, filepath.Join(destPathId, newImageName))
 				}
 			}
 		}
@@ -126,6 +130,7 @@ func (r *RenameLocal) move(mediaFile *models.ScrapeMediaFile, destPathId, newNam
 				helpers.AppLogger.Errorf("移动nfo文件 %s 到 %s 失败: %v", mediaFile.NfoFileName, destPathId+"/"+newNfoName, err)
 			} else {
 				helpers.AppLogger.Infof("nfo文件 %s 成功移动到 %s", mediaFile.NfoFileId, destPathId+"/"+newNfoName)
+				r.recordPath(mediaFile, models.RenameJournalOpMove, mediaFile.NfoFileId, filepath.Join(destPathId, newNfoName))
 			}
 		}
 	}
@@ -148,6 +153,7 @@ func (r *RenameLocal) copy(mediaFile *models.ScrapeMediaFile, destPathId, newNam
 			return err
 		} else {
 			helpers.AppLogger.Infof("文件 %s 成功移动到 %s", sourcePath+"/"+mediaFile.VideoFilename, destPathId+"/"+newName)
+			r.recordPath(mediaFile, models.RenameJournalOpCopy, sourceFullPath, destFullPath)
 		}
 	}
 	if mediaFile.MediaType != models.MediaTypeTvShow {
@@ -174,6 +180,7 @@ func (r *RenameLocal) copy(mediaFile *models.ScrapeMediaFile, destPathId, newNam
 				helpers.AppLogger.Errorf("移动字幕文件 %s 到 %s 失败: %v", sub.FileName, destPathId+"/"+newSubName, err)
 			} else {
 				helpers.AppLogger.Infof("字幕文件 %s 成功移动到 %s", sub.FileId, destPathId+"/"+newSubName)
+				r.recordPath(mediaFile, models.RenameJournalOpCopy, sub.FileId, newSubFullPath)
 				newSub := &models.MediaMetaFiles{
 					FileName: newSubName,
 					FileId:   newSubFullPath,
@@ -206,6 +213,7 @@ func (r *RenameLocal) copy(mediaFile *models.ScrapeMediaFile, destPathId, newNam
 					helpers.AppLogger.Errorf("移动图片文件 %s 到 %s 失败: %v", imageFile.FileName, destPathId+"/"+newImageName, err)
 				} else {
 					helpers.AppLogger.Infof("图片文件 %s 成功移动到 %s", imageFile.FileId, destPathId+"/"+newImageName)
+					r.recordPath(mediaFile, models.RenameJournalOpCopy, imageFile.FileId, filepath.Join(destPathId, newImageName))
 				}
 			}
 		}
@@ -218,6 +226,7 @@ func (r *RenameLocal) copy(mediaFile *models.ScrapeMediaFile, destPathId, newNam
 				helpers.AppLogger.Errorf("移动nfo文件 %s 到 %s 失败: %v", mediaFile.NfoFileName, destPathId+"/"+newNfoName, err)
 			} else {
 				helpers.AppLogger.Infof("nfo文件 %s 成功移动到 %s", mediaFile.NfoFileId, destPathId+"/"+newNfoName)
+				r.recordPath(mediaFile, models.RenameJournalOpCopy, mediaFile.NfoFileId, filepath.Join(destPathId, newNfoName))
 			}
 		}
 	}
@@ -230,9 +239,14 @@ func (r *RenameLocal) symlink(mediaFile *models.ScrapeMediaFile, destPathId, new
 		sourcePath = mediaFile.TvshowPathId
 	}
 	sourceFullPath := filepath.Join(sourcePath, mediaFile.VideoFilename)
+	op := models.RenameJournalOpSymlink
+	if isHard {
+		op = models.RenameJournalOpHardLink
+	}
 	destFullPath := filepath.Join(destPathId, newName)
 	if helpers.PathExists(destFullPath) {
 		helpers.AppLogger.Infof("文件 %s 已存在，无需硬链接", destFullPath)
+		r.recordLink(mediaFile, sourceFullPath, destFullPath, isHard)
 	} else {
 		var err error
 		if isHard {
@@ -245,6 +259,8 @@ func (r *RenameLocal) symlink(mediaFile *models.ScrapeMediaFile, destPathId, new
 			return err
 		} else {
 			helpers.AppLogger.Infof("文件 %s 成功链接到 %s", sourceFullPath, destFullPath)
+			r.recordPath(mediaFile, op, sourceFullPath, destFullPath)
+			r.recordLink(mediaFile, sourceFullPath, destFullPath, isHard)
 		}
 	}
 	if mediaFile.MediaType != models.MediaTypeTvShow {
@@ -275,6 +291,8 @@ func (r *RenameLocal) symlink(mediaFile *models.ScrapeMediaFile, destPathId, new
 				helpers.AppLogger.Errorf("创建硬链接字幕文件 %s 到 %s 失败: %v", sub.FileName, destPathId+"/"+newSubName, err)
 			} else {
 				helpers.AppLogger.Infof("字幕文件 %s 成功链接到 %s", sub.FileId, destPathId+"/"+newSubName)
+				r.recordPath(mediaFile, op, sub.FileId, filepath.Join(destPathId, newSubName))
+				r.recordLink(mediaFile, sub.FileId, filepath.Join(destPathId, newSubName), isHard)
 				newSub := &models.MediaMetaFiles{
 					FileName: newSubName,
 					FileId:   filepath.Join(destPathId, newSubName),
@@ -311,6 +329,8 @@ func (r *RenameLocal) symlink(mediaFile *models.ScrapeMediaFile, destPathId, new
 					helpers.AppLogger.Errorf("创建硬链接图片文件 %s 到 %s 失败: %v", imageFile.FileName, destPathId+"/"+newImageName, err)
 				} else {
 					helpers.AppLogger.Infof("图片文件 %s 成功硬链接到 %s", imageFile.FileId, destPathId+"/"+newImageName)
+					r.recordPath(mediaFile, op, imageFile.FileId, filepath.Join(destPathId, newImageName))
+					r.recordLink(mediaFile, imageFile.FileId, filepath.Join(destPathId, newImageName), isHard)
 				}
 			}
 		}
@@ -328,19 +348,41 @@ func (r *RenameLocal) symlink(mediaFile *models.ScrapeMediaFile, destPathId, new
 				helpers.AppLogger.Errorf("创建硬链接nfo文件 %s 到 %s 失败: %v", mediaFile.NfoFileName, destPathId+"/"+newNfoName, err)
 			} else {
 				helpers.AppLogger.Infof("nfo文件 %s 成功硬链接到 %s", mediaFile.NfoFileId, destPathId+"/"+newNfoName)
+				r.recordPath(mediaFile, op, mediaFile.NfoFileId, filepath.Join(destPathId, newNfoName))
+				r.recordLink(mediaFile, mediaFile.NfoFileId, filepath.Join(destPathId, newNfoName), isHard)
 			}
 		}
 	}
 	return nil
 }
 
+// 记录镜像整理的链接，用来在来源文件删除或替换时清理、重新链接
+func (r *RenameLocal) recordLink(mediaFile *models.ScrapeMediaFile, sourceFullPath, destFullPath string, isHard bool) {
+	models.SaveScrapeLink(&models.ScrapeLink{
+		ScrapePathId:      mediaFile.ScrapePathId,
+		ScrapeMediaFileId: mediaFile.ID,
+		IsHard:            isHard,
+		SourceFile:        sourceFullPath,
+		LinkFile:          destFullPath,
+		CheckTime:         time.Now().Unix(),
+	})
+}
+
 func (r *RenameLocal) CheckAndMkDir(destFullPath string, rootPath, rootPathId string) (string, error) {
 	if !helpers.PathExists(destFullPath) {
+		// 记录每一级新建的目录，撤销时从最深的一级开始删除
+		missing := make([]string, 0)
+		for p := destFullPath; !helpers.PathExists(p) && filepath.Dir(p) != p; p = filepath.Dir(p) {
+			missing = append(missing, p)
+		}
 		err := os.MkdirAll(destFullPath, 0777)
 		if err != nil {
 			helpers.AppLogger.Errorf("创建父文件夹失败: %v", err)
 			return "", err
 		}
+		for i := len(missing) - 1; i >= 0; i-- {
+			r.recordPath(nil, models.RenameJournalOpMkdir, "", missing[i])
+		}
 	}
 	return destFullPath, nil
 }
@@ -373,6 +415,7 @@ func (r *RenameLocal) RemoveMediaSourcePath(mediaFile *models.ScrapeMediaFile, s
 			return err
 		}
 		helpers.AppLogger.Infof("刮削完成，尝试删除本地目录成功, 路径：%s", sourcePath)
+		r.recordPath(mediaFile, models.RenameJournalOpDelete, sourcePath, "")
 	}
 	// 如果有电视剧文件夹，则删除
 	if mediaFile.PathId != "" {
@@ -391,6 +434,7 @@ func (r *RenameLocal) RemoveMediaSourcePath(mediaFile *models.ScrapeMediaFile, s
 				return err
 			}
 			helpers.AppLogger.Infof("刮削完成，尝试删除本地电视剧文件夹成功, 路径：%s", mediaFile.TvshowPathId)
+			r.recordPath(mediaFile, models.RenameJournalOpDelete, mediaFile.TvshowPathId, "")
 		}
 	}
 	return nil
@@ -419,6 +463,7 @@ func (r *RenameLocal) CheckAndDeleteFiles(mediaFile *models.ScrapeMediaFile, fil
 			continue
 		}
 		helpers.AppLogger.Infof("删除本地文件成功, 路径：%s", f.FullFilePath)
+		r.recordPath(mediaFile, models.RenameJournalOpDelete, f.FullFilePath, "")
 	}
 	return nil
 }
@@ -436,15 +481,30 @@ func (r *RenameLocal) MoveFiles(f models.MoveNewFileToSourceFile) error {
 		return err
 	}
 	helpers.AppLogger.Infof("移动本地文件成功: %s => %s", f.FileId, newFileId)
+	r.recordPath(nil, models.RenameJournalOpMove, f.FileId, newFileId)
 	return nil
 }
 
 func (r *RenameLocal) DeleteDir(path, pathId string) error {
-	return os.RemoveAll(pathId)
+	if err := os.RemoveAll(pathId); err != nil {
+		return err
+	}
+	r.recordPath(nil, models.RenameJournalOpDelete, pathId, "")
+	return nil
 }
 
 func (r *RenameLocal) Rename(fileId, newName string) error {
-	return helpers.MoveFile(fileId, filepath.Join(filepath.Dir(fileId), newName), false)
+	newFileId := filepath.Join(filepath.Dir(fileId), newName)
+	if err := helpers.MoveFile(fileId, newFileId, false); err != nil {
+		return err
+	}
+	r.recordPath(nil, models.RenameJournalOpRename, fileId, newFileId)
+	return nil
+}
+
+// 文件ID就是路径，不需要查询原来的位置
+func (r *RenameLocal) RenameAt(source models.FileSource, fileId, newName string) error {
+	return r.Rename(fileId, newName)
 }
 
 // 检查是否存在，存在就改名字，然后返回新的fileId
@@ -468,3 +528,61 @@ func (r *RenameLocal) ExistsAndRename(fileId, newName string) (string, error) {
 	helpers.AppLogger.Infof("重命名本地文件成功, %s => %s", fileId, newName)
 	return filepath.Join(filepath.Dir(fileId), newName), nil
 }
+
+func (r *RenameLocal) FindFile(path, pathId, name string) (string, error) {
+	return r.findFile(path, pathId, name)
+}
+
+func (r *RenameLocal) RenameFile(path, pathId, name, newName string) error {
+	fileId, err := r.findFile(path, pathId, name)
+	if err != nil || fileId == "" || name == newName {
+		return err
+	}
+	return r.Rename(fileId, newName)
+}
+
+func (r *RenameLocal) Revert(j *models.RenameJournal) error {
+	return revertJournal(r, j)
+}
+
+func (r *RenameLocal) findFile(path, pathId, name string) (string, error) {
+	fullPath := filepath.Join(pathId, name)
+	// 软链接指向的文件不存在时也要能找到链接本身
+	if _, err := os.Lstat(fullPath); err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return fullPath, nil
+}
+
+func (r *RenameLocal) moveBack(j *models.RenameJournal, fileId, pathId string) error {
+	return helpers.MoveFile(fileId, filepath.Join(pathId, j.SourceName), false)
+}
+
+func (r *RenameLocal) removeFile(j *models.RenameJournal, fileId string) error {
+	if err := os.Remove(fileId); err != nil {
+		return err
+	}
+	// 撤销的链接不再跟随来源文件重新链接
+	if j.Op == models.RenameJournalOpHardLink || j.Op == models.RenameJournalOpSymlink {
+		models.DeleteScrapeLinkByLinkFile(fileId)
+	}
+	return nil
+}
+
+func (r *RenameLocal) isEmptyDir(j *models.RenameJournal, fileId string) (bool, error) {
+	entries, err := os.ReadDir(fileId)
+	if err != nil {
+		return false, err
+	}
+	return len(entries) == 0, nil
+}
+
+func (r *RenameLocal) ensureDir(path, pathId string) (string, error) {
+	if err := os.MkdirAll(pathId, 0777); err != nil {
+		return "", err
+	}
+	return pathId, nil
+}
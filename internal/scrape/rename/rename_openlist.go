@@ -45,6 +45,7 @@ func (r *RenameOpenList) RenameAndMove(mediaFile *models.ScrapeMediaFile, destPa
 			return err
 		} else {
 			helpers.AppLogger.Infof("文件 %s 重命名成功：%s", oldPath+"/"+mediaFile.VideoFilename, newName)
+			r.recordPath(mediaFile, models.RenameJournalOpRename, filepath.Join(oldPath, mediaFile.VideoFilename), filepath.Join(oldPath, newName))
 		}
 	}
 	// 先改名，后移动或复制
@@ -77,6 +78,7 @@ func (r *RenameOpenList) move(mediaFile *models.ScrapeMediaFile, newName, newPat
 			return err
 		} else {
 			helpers.AppLogger.Infof("OpenList 文件 %s 成功从 %s 移动到新文件夹 %s", newName, oldPath, newPathId)
+			r.recordPath(mediaFile, models.RenameJournalOpMove, filepath.Join(oldPath, newName), destFullPath)
 		}
 	}
 	// 查询一下详情
@@ -120,6 +122,10 @@ func (r *RenameOpenList) move(mediaFile *models.ScrapeMediaFile, newName, newPat
 		err := r.client.Move(oldPath, newPathId, files)
 		if err != nil {
 			helpers.AppLogger.Errorf("OpenList移动字幕文件失败: %v", err)
+		} else {
+			for _, name := range files {
+				r.recordPath(mediaFile, models.RenameJournalOpMove, filepath.Join(oldPath, name), filepath.Join(newPathId, name))
+			}
 		}
 		// 改名
 		for _, sub := range mediaFile.SubtitleFiles {
@@ -130,6 +136,7 @@ func (r *RenameOpenList) move(mediaFile *models.ScrapeMediaFile, newName, newPat
 					helpers.AppLogger.Errorf("OpenList改名字幕文件失败: %v", err)
 				} else {
 					helpers.AppLogger.Infof("字幕文件 %s 重命名成功：%s", newPathId+"/"+sub.FileName, newSubName)
+					r.recordPath(mediaFile, models.RenameJournalOpRename, filepath.Join(newPathId, sub.FileName), filepath.Join(newPathId, newSubName))
 				}
 			}
 		}
@@ -155,6 +162,9 @@ func (r *RenameOpenList) move(mediaFile *models.ScrapeMediaFile, newName, newPat
 				helpers.AppLogger.Errorf("OpenList移动图片文件失败: %v", err)
 				return err
 			}
+			for _, name := range files {
+				r.recordPath(mediaFile, models.RenameJournalOpMove, filepath.Join(oldPath, name), filepath.Join(newPathId, name))
+			}
 			// 改名
 			for _, imageFile := range mediaFile.ImageFiles {
 				// 改名
@@ -167,6 +177,7 @@ func (r *RenameOpenList) move(mediaFile *models.ScrapeMediaFile, newName, newPat
 						return err
 					} else {
 						helpers.AppLogger.Infof("图片文件 %s 重命名成功：%s", newPathId+"/"+imageFile.FileName, newImageName)
+						r.recordPath(mediaFile, models.RenameJournalOpRename, filepath.Join(newPathId, imageFile.FileName), filepath.Join(newPathId, newImageName))
 					}
 				}
 			}
@@ -178,6 +189,8 @@ func (r *RenameOpenList) move(mediaFile *models.ScrapeMediaFile, newName, newPat
 			err := r.client.Move(oldPath, newPathId, []string{mediaFile.NfoFileName})
 			if err != nil {
 				helpers.AppLogger.Errorf("OpenList移动nfo文件 %s 失败: %v", mediaFile.NfoFileName, err)
+			} else {
+				r.recordPath(mediaFile, models.RenameJournalOpMove, filepath.Join(oldPath, mediaFile.NfoFileName), filepath.Join(newPathId, mediaFile.NfoFileName))
 			}
 			// 检查是否需要改名
 			if newNfoName != mediaFile.NfoFileName {
@@ -187,6 +200,7 @@ func (r *RenameOpenList) move(mediaFile *models.ScrapeMediaFile, newName, newPat
 					helpers.AppLogger.Errorf("OpenList改名nfo文件 %s 失败: %v", mediaFile.NfoFileName, err)
 				} else {
 					helpers.AppLogger.Infof("nfo文件 %s 成功重命名为 %s", mediaFile.NfoFileName, newNfoName)
+					r.recordPath(mediaFile, models.RenameJournalOpRename, filepath.Join(newPathId, mediaFile.NfoFileName), filepath.Join(newPathId, newNfoName))
 				}
 			}
 		}
@@ -201,6 +215,7 @@ func (r *RenameOpenList) copy(mediaFile *models.ScrapeMediaFile, newName, newPat
 		return err
 	} else {
 		helpers.AppLogger.Infof("Openlist 文件 %s 成功复制到 %s", oldPath+"/"+newName, newPathId+"/"+newName)
+		r.recordPath(mediaFile, models.RenameJournalOpCopy, filepath.Join(oldPath, newName), filepath.Join(newPathId, newName))
 	}
 	destFullPath := filepath.ToSlash(filepath.Join(newPathId, newName))
 	// 查询一下详情
@@ -247,6 +262,10 @@ func (r *RenameOpenList) copy(mediaFile *models.ScrapeMediaFile, newName, newPat
 		err := r.client.Copy(oldPath, newPathId, files)
 		if err != nil {
 			helpers.AppLogger.Errorf("OpenList复制字幕文件失败: %v", err)
+		} else {
+			for _, name := range files {
+				r.recordPath(mediaFile, models.RenameJournalOpCopy, filepath.Join(oldPath, name), filepath.Join(newPathId, name))
+			}
 		}
 		// 改名
 		for _, sub := range mediaFile.SubtitleFiles {
@@ -259,6 +278,7 @@ func (r *RenameOpenList) copy(mediaFile *models.ScrapeMediaFile, newName, newPat
 					helpers.AppLogger.Errorf("OpenList改名字幕文件失败: %v", err)
 				} else {
 					helpers.AppLogger.Infof("字幕文件 %s 重命名成功：%s", newPathId+"/"+sub.FileName, newSubName)
+					r.recordPath(mediaFile, models.RenameJournalOpRename, filepath.Join(newPathId, sub.FileName), filepath.Join(newPathId, newSubName))
 				}
 			}
 		}
@@ -283,6 +303,9 @@ func (r *RenameOpenList) copy(mediaFile *models.ScrapeMediaFile, newName, newPat
 				helpers.AppLogger.Errorf("OpenList复制图片文件失败: %v", err)
 				return err
 			}
+			for _, name := range files {
+				r.recordPath(mediaFile, models.RenameJournalOpCopy, filepath.Join(oldPath, name), filepath.Join(newPathId, name))
+			}
 			// 改名
 			for _, imageFile := range mediaFile.ImageFiles {
 				// 改名
@@ -295,6 +318,7 @@ func (r *RenameOpenList) copy(mediaFile *models.ScrapeMediaFile, newName, newPat
 						return err
 					} else {
 						helpers.AppLogger.Infof("图片文件 %s 重命名成功：%s", newPathId+"/"+imageFile.FileName, newImageName)
+						r.recordPath(mediaFile, models.RenameJournalOpRename, filepath.Join(newPathId, imageFile.FileName), filepath.Join(newPathId, newImageName))
 					}
 				}
 			}
@@ -306,6 +330,8 @@ func (r *RenameOpenList) copy(mediaFile *models.ScrapeMediaFile, newName, newPat
 			err := r.client.Copy(oldPath, newPathId, []string{mediaFile.NfoFileName})
 			if err != nil {
 				helpers.AppLogger.Errorf("OpenList复制nfo文件 %s 失败: %v", mediaFile.NfoFileName, err)
+			} else {
+				r.recordPath(mediaFile, models.RenameJournalOpCopy, filepath.Join(oldPath, mediaFile.NfoFileName), filepath.Join(newPathId, mediaFile.NfoFileName))
 			}
 			// 检查是否需要改名
 			if newNfoName != mediaFile.NfoFileName {
@@ -315,6 +341,7 @@ func (r *RenameOpenList) copy(mediaFile *models.ScrapeMediaFile, newName, newPat
 					helpers.AppLogger.Errorf("OpenList改名nfo文件 %s 失败: %v", mediaFile.NfoFileName, err)
 				} else {
 					helpers.AppLogger.Infof("nfo文件 %s 成功重命名为 %s", mediaFile.NfoFileName, newNfoName)
+					r.recordPath(mediaFile, models.RenameJournalOpRename, filepath.Join(newPathId, mediaFile.NfoFileName), filepath.Join(newPathId, newNfoName))
 				}
 			}
 		}
@@ -331,6 +358,7 @@ func (r *RenameOpenList) CheckAndMkDir(destFullPath string, rootPath, rootPathId
 			helpers.AppLogger.Errorf("创建文件夹失败: %s 错误：%v", destFullPath, err)
 			return destFullPath, err
 		}
+		r.recordPath(nil, models.RenameJournalOpMkdir, "", destFullPath)
 	}
 	return destFullPath, nil
 }
@@ -365,6 +393,7 @@ func (r *RenameOpenList) RemoveMediaSourcePath(mediaFile *models.ScrapeMediaFile
 			return err
 		}
 		helpers.AppLogger.Infof("刮削完成，尝试删除Openlist文件夹成功, 路径：%s", sourcePath)
+		r.recordPath(mediaFile, models.RenameJournalOpDelete, sourcePath, "")
 	}
 	// 再删除电视剧文件夹
 	if mediaFile.PathId != "" {
@@ -387,6 +416,7 @@ func (r *RenameOpenList) RemoveMediaSourcePath(mediaFile *models.ScrapeMediaFile
 				return err
 			}
 			helpers.AppLogger.Infof("刮削完成，尝试删除Openlist中的电视剧文件夹成功, 路径：%s", mediaFile.TvshowPathId)
+			r.recordPath(mediaFile, models.RenameJournalOpDelete, mediaFile.TvshowPathId, "")
 		}
 
 	}
@@ -422,6 +452,7 @@ func (r *RenameOpenList) CheckAndDeleteFiles(mediaFile *models.ScrapeMediaFile,
 			continue
 		}
 		helpers.AppLogger.Infof("删除OpenList文件成功, 路径：%s", f.FullFilePath)
+		r.recordPath(mediaFile, models.RenameJournalOpDelete, f.FullFilePath, "")
 	}
 	return nil
 }
@@ -441,15 +472,29 @@ func (r *RenameOpenList) MoveFiles(f models.MoveNewFileToSourceFile) error {
 		return err
 	}
 	helpers.AppLogger.Infof("移动OpenList文件成功: %s => %s", f.FileId, newFileId)
+	r.recordPath(nil, models.RenameJournalOpMove, f.FileId, newFileId)
 	return nil
 }
 
 func (r *RenameOpenList) DeleteDir(path, pathId string) error {
-	return r.client.Del(filepath.Dir(pathId), []string{filepath.Base(pathId)})
+	if err := r.client.Del(filepath.Dir(pathId), []string{filepath.Base(pathId)}); err != nil {
+		return err
+	}
+	r.recordPath(nil, models.RenameJournalOpDelete, pathId, "")
+	return nil
 }
 
 func (r *RenameOpenList) Rename(fileId, newName string) error {
-	return r.client.Rename(filepath.Dir(fileId), filepath.Base(fileId), newName)
+	if err := r.client.Rename(filepath.Dir(fileId), filepath.Base(fileId), newName); err != nil {
+		return err
+	}
+	r.recordPath(nil, models.RenameJournalOpRename, fileId, filepath.Join(filepath.Dir(fileId), newName))
+	return nil
+}
+
+// 文件ID就是路径，不需要查询原来的位置
+func (r *RenameOpenList) RenameAt(source models.FileSource, fileId, newName string) error {
+	return r.Rename(fileId, newName)
 }
 
 // 检查是否存在，存在就改名字，然后返回新的fileId
@@ -474,3 +519,57 @@ func (r *RenameOpenList) ExistsAndRename(fileId, newName string) (string, error)
 	helpers.AppLogger.Infof("重命名OpenList文件成功, %s => %s", fileId, newName)
 	return filepath.Join(filepath.Dir(fileId), newName), nil
 }
+
+func (r *RenameOpenList) FindFile(path, pathId, name string) (string, error) {
+	return r.findFile(path, pathId, name)
+}
+
+func (r *RenameOpenList) RenameFile(path, pathId, name, newName string) error {
+	fileId, err := r.findFile(path, pathId, name)
+	if err != nil || fileId == "" || name == newName {
+		return err
+	}
+	return r.Rename(fileId, newName)
+}
+
+func (r *RenameOpenList) Revert(j *models.RenameJournal) error {
+	return revertJournal(r, j)
+}
+
+func (r *RenameOpenList) findFile(path, pathId, name string) (string, error) {
+	fullPath := filepath.ToSlash(filepath.Join(pathId, name))
+	detail, _ := r.client.FileDetail(fullPath)
+	if detail == nil || detail.Name == "" {
+		return "", nil
+	}
+	return fullPath, nil
+}
+
+// 先在整理后的目录改回原名，再移回原目录
+func (r *RenameOpenList) moveBack(j *models.RenameJournal, fileId, pathId string) error {
+	if j.DestName != j.SourceName {
+		if err := r.client.Rename(j.DestPathId, j.DestName, j.SourceName); err != nil {
+			return err
+		}
+	}
+	if j.DestPathId != pathId {
+		return r.client.Move(j.DestPathId, pathId, []string{j.SourceName})
+	}
+	return nil
+}
+
+func (r *RenameOpenList) removeFile(j *models.RenameJournal, fileId string) error {
+	return r.client.Del(filepath.ToSlash(filepath.Dir(fileId)), []string{filepath.Base(fileId)})
+}
+
+func (r *RenameOpenList) isEmptyDir(j *models.RenameJournal, fileId string) (bool, error) {
+	fsList, err := r.client.FileList(r.ctx, fileId, 1, 1)
+	if err != nil {
+		return false, err
+	}
+	return fsList.Total == 0, nil
+}
+
+func (r *RenameOpenList) ensureDir(path, pathId string) (string, error) {
+	return r.CheckAndMkDir(pathId, "", "")
+}
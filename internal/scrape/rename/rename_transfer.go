@@ -0,0 +1,186 @@
+package rename
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+var ErrTransferMoveBack = errors.New("跨存储整理不支持把文件移回来源目录")
+
+// 跨存储整理：来源和目标不在同一个网盘账号（或者一个是本地一个是网盘）
+// 目标目录下的操作（创建目录、删除元数据等）由目标存储的实现完成，来源目录下的操作由来源存储的实现完成
+// 视频、字幕通过上传队列转移到目标目录，上传并校验完成后才删除来源文件（移动模式）
+// 转移不记录整理操作日志，目标存储的操作也不记录，撤销只能还原来源存储的操作
+type RenameTransfer struct {
+	RenameBase
+	source Renamer
+	dest   Renamer
+}
+
+func NewRenameTransfer(ctx context.Context, scrapePath *models.ScrapePath, source, dest Renamer) *RenameTransfer {
+	if d, ok := dest.(interface{ disableJournal() }); ok {
+		d.disableJournal()
+	}
+	return &RenameTransfer{
+		RenameBase: RenameBase{
+			scrapePath: scrapePath,
+			ctx:        ctx,
+		},
+		source: source,
+		dest:   dest,
+	}
+}
+
+// 是否是目标路径下的目录
+func (r *RenameTransfer) isDestPath(path string) bool {
+	destPath := strings.TrimSuffix(filepath.ToSlash(r.scrapePath.DestPath), "/")
+	path = filepath.ToSlash(path)
+	return path == destPath || strings.HasPrefix(path, destPath+"/")
+}
+
+// 来源文件的ID，115使用文件ID，其他来源是完整路径
+func (r *RenameTransfer) sourceFileId(pathId, fileId, fileName string) string {
+	switch r.scrapePath.SourceType {
+	case models.SourceType115:
+		return fileId
+	case models.SourceTypeLocal:
+		return filepath.Join(pathId, fileName)
+	}
+	return filepath.ToSlash(filepath.Join(pathId, fileName))
+}
+
+// 把视频和字幕加入转移队列，其他类型仅整理时图片和nfo也一起转移
+// 字幕的文件ID先记录为目标路径，转移完成后更新为实际的文件ID
+func (r *RenameTransfer) RenameAndMove(mediaFile *models.ScrapeMediaFile, destPath, destPathId, newName string) error {
+	_, sourcePathId := mediaSourceDir(mediaFile)
+	video := &models.MediaMetaFiles{
+		FileName: mediaFile.VideoFilename,
+		FileId:   r.sourceFileId(sourcePathId, mediaFile.VideoFileId, mediaFile.VideoFilename),
+		PickCode: mediaFile.VideoPickCode,
+	}
+	if err := models.AddTransferUploadTask(mediaFile, r.scrapePath, video, sourcePathId, destPath, destPathId, newName, true); err != nil {
+		helpers.AppLogger.Errorf("添加视频 %s 的转移任务失败: %v", mediaFile.VideoFilename, err)
+		return err
+	}
+	oldBaseName := strings.TrimSuffix(mediaFile.VideoFilename, mediaFile.VideoExt)
+	if mediaFile.SubtitleFileJson != "" {
+		subtitleFiles := make([]*models.MediaMetaFiles, 0)
+		for _, sub := range mediaFile.SubtitleFiles {
+			newSubName := strings.Replace(sub.FileName, oldBaseName, mediaFile.NewVideoBaseName, 1)
+			source := &models.MediaMetaFiles{
+				FileName: sub.FileName,
+				FileId:   r.sourceFileId(sourcePathId, sub.FileId, sub.FileName),
+				PickCode: sub.PickCode,
+			}
+			if err := models.AddTransferUploadTask(mediaFile, r.scrapePath, source, sourcePathId, destPath, destPathId, newSubName, false); err != nil {
+				helpers.AppLogger.Errorf("添加字幕 %s 的转移任务失败: %v", sub.FileName, err)
+				continue
+			}
+			destFullPath := filepath.Join(destPath, newSubName)
+			subtitleFiles = append(subtitleFiles, &models.MediaMetaFiles{
+				FileName: newSubName,
+				FileId:   destFullPath,
+				PickCode: destFullPath,
+			})
+		}
+		if mediaFile.MediaType != models.MediaTypeTvShow {
+			mediaFile.Media.SubtitleFiles = subtitleFiles
+			mediaFile.Media.Save()
+		} else {
+			mediaFile.MediaEpisode.SubtitleFiles = subtitleFiles
+			mediaFile.MediaEpisode.Save()
+		}
+	}
+	if mediaFile.ScrapeType == models.ScrapeTypeOnlyRename && mediaFile.MediaType == models.MediaTypeOther {
+		// 其他类型仅整理要把图片和nfo也转移过去
+		files := make([]*models.MediaMetaFiles, 0, len(mediaFile.ImageFiles)+1)
+		files = append(files, mediaFile.ImageFiles...)
+		if mediaFile.NfoFileId != "" {
+			files = append(files, &models.MediaMetaFiles{FileName: mediaFile.NfoFileName, FileId: mediaFile.NfoFileId, PickCode: mediaFile.NfoPickCode})
+		}
+		for _, file := range files {
+			newFileName := strings.Replace(file.FileName, oldBaseName, mediaFile.NewVideoBaseName, 1)
+			source := &models.MediaMetaFiles{
+				FileName: file.FileName,
+				FileId:   r.sourceFileId(sourcePathId, file.FileId, file.FileName),
+				PickCode: file.PickCode,
+			}
+			if err := models.AddTransferUploadTask(mediaFile, r.scrapePath, source, sourcePathId, destPath, destPathId, newFileName, false); err != nil {
+				helpers.AppLogger.Errorf("添加文件 %s 的转移任务失败: %v", file.FileName, err)
+			}
+		}
+	}
+	helpers.AppLogger.Infof("视频 %s 已加入转移队列，目标：%s", mediaFile.VideoFilename, filepath.Join(destPath, newName))
+	return nil
+}
+
+// 整理时在目标路径下创建目录，回滚时在来源路径下创建目录
+func (r *RenameTransfer) CheckAndMkDir(destFullPath, rootPath, rootPathId string) (string, error) {
+	if r.isDestPath(rootPath) {
+		return r.dest.CheckAndMkDir(destFullPath, rootPath, rootPathId)
+	}
+	return r.source.CheckAndMkDir(destFullPath, rootPath, rootPathId)
+}
+
+// 来源文件由转移任务在校验完成后逐个删除，这里不删除来源目录
+func (r *RenameTransfer) RemoveMediaSourcePath(mediaFile *models.ScrapeMediaFile, sp *models.ScrapePath) error {
+	helpers.AppLogger.Infof("跨存储整理，视频 %s 的来源文件在转移完成后删除，跳过删除来源目录 %s", mediaFile.Name, mediaFile.Path)
+	return nil
+}
+
+// 读取来源目录中的文件，例如其他类型的nfo
+func (r *RenameTransfer) ReadFileContent(fileId string) ([]byte, error) {
+	return r.source.ReadFileContent(fileId)
+}
+
+// 删除目标目录中的元数据
+func (r *RenameTransfer) CheckAndDeleteFiles(mediaFile *models.ScrapeMediaFile, files []models.WillDeleteFile) error {
+	return r.dest.CheckAndDeleteFiles(mediaFile, files)
+}
+
+// 目标目录内的移动（例如多版本待定）由目标存储完成
+// 回滚时把目标目录中的文件移回来源目录，跨存储时不支持
+func (r *RenameTransfer) MoveFiles(f models.MoveNewFileToSourceFile) error {
+	if f.FileFullPath != "" && r.isDestPath(f.FileFullPath) {
+		return r.dest.MoveFiles(f)
+	}
+	return ErrTransferMoveBack
+}
+
+// 删除目标目录
+func (r *RenameTransfer) DeleteDir(path, pathId string) error {
+	return r.dest.DeleteDir(path, pathId)
+}
+
+// 回滚时改名的都是来源目录中的文件
+func (r *RenameTransfer) Rename(fileId, newName string) error {
+	return r.source.Rename(fileId, newName)
+}
+
+func (r *RenameTransfer) RenameAt(source models.FileSource, fileId, newName string) error {
+	return r.source.RenameAt(source, fileId, newName)
+}
+
+func (r *RenameTransfer) ExistsAndRename(fileId, newName string) (string, error) {
+	return r.source.ExistsAndRename(fileId, newName)
+}
+
+// 按目录所在的存储查询文件
+func (r *RenameTransfer) FindFile(path, pathId, name string) (string, error) {
+	if r.isDestPath(path) {
+		return r.dest.FindFile(path, pathId, name)
+	}
+	return r.source.FindFile(path, pathId, name)
+}
+
+// 按目录所在的存储重命名文件，例如多版本补充版本标签
+func (r *RenameTransfer) RenameFile(path, pathId, name, newName string) error {
+	if r.isDestPath(path) {
+		return r.dest.RenameFile(path, pathId, name, newName)
+	}
+	return r.source.RenameFile(path, pathId, name, newName)
+}
@@ -0,0 +1,133 @@
+package scrape
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/scrape/rename"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// 按日志撤销整理操作，各来源的rename实现
+type journalReverter interface {
+	Revert(j *models.RenameJournal) error
+}
+
+// 正在撤销的批次
+var revertingRuns = make(map[string]bool)
+var revertingMutex sync.Mutex
+
+// 撤销整理批次，在后台执行
+// scrapePathId为0时撤销批次内所有刮削目录的操作，每个刮削目录使用自己的来源撤销
+// mediaFileIds为空时撤销整个批次（包括创建的目录），否则只撤销这些刮削记录的操作
+// 按执行顺序的逆序撤销，整理后的文件已被移走、替换或者原位置已有同名文件时标记为冲突并跳过
+func StartRevertRenameJournal(runNo string, scrapePathId uint, mediaFileIds []uint) error {
+	journals := models.GetRenameJournalsByRunNo(runNo, scrapePathId)
+	if len(journals) == 0 {
+		return fmt.Errorf("批次 %s 没有整理操作日志", runNo)
+	}
+	// 按刮削目录分组，旧版本的批次号可能包含多个刮削目录
+	scrapePaths := make([]*models.ScrapePath, 0)
+	groups := make(map[uint][]*models.RenameJournal)
+	for _, j := range journals {
+		if _, ok := groups[j.ScrapePathId]; !ok {
+			sp := models.GetScrapePathByID(j.ScrapePathId)
+			if sp == nil {
+				return fmt.Errorf("刮削目录 %d 不存在", j.ScrapePathId)
+			}
+			if sp.IsScraping {
+				return fmt.Errorf("刮削目录 %s 正在刮削，请等待完成后再撤销", sp.SourcePath)
+			}
+			scrapePaths = append(scrapePaths, sp)
+		}
+		groups[j.ScrapePathId] = append(groups[j.ScrapePathId], j)
+	}
+	revertingMutex.Lock()
+	defer revertingMutex.Unlock()
+	keys := make([]string, 0, len(scrapePaths))
+	for _, sp := range scrapePaths {
+		key := fmt.Sprintf("%s:%d", runNo, sp.ID)
+		if revertingRuns[key] {
+			return errors.New("该批次正在撤销")
+		}
+		keys = append(keys, key)
+	}
+	for _, key := range keys {
+		revertingRuns[key] = true
+	}
+	go func() {
+		defer func() {
+			revertingMutex.Lock()
+			for _, key := range keys {
+				delete(revertingRuns, key)
+			}
+			revertingMutex.Unlock()
+		}()
+		for _, sp := range scrapePaths {
+			revertRenameJournals(sp, groups[sp.ID], mediaFileIds)
+		}
+	}()
+	return nil
+}
+
+func revertRenameJournals(sp *models.ScrapePath, journals []*models.RenameJournal, mediaFileIds []uint) {
+	s := NewScrape(sp)
+	defer s.ctxCancel()
+	if err := s.initOpenClient(); err != nil {
+		helpers.AppLogger.Errorf("撤销整理失败，刮削目录 %s 初始化客户端失败: %v", sp.SourcePath, err)
+		return
+	}
+	var reverter journalReverter
+	switch sp.SourceType {
+	case models.SourceType115:
+		reverter = rename.NewRename115(s.ctx, sp, s.V115Client)
+	case models.SourceTypeOpenList:
+		reverter = rename.NewRenameOpenList(s.ctx, sp, s.OpenlistClient)
+	case models.SourceTypeBaiduPan:
+		reverter = rename.NewRenameBaiduPan(s.ctx, sp, s.BaiduPanClient)
+	default:
+		reverter = rename.NewRenameLocal(s.ctx, sp)
+	}
+	// 有操作没能撤销的刮削记录，不删除
+	unfinished := make(map[uint]bool)
+	reverted := make(map[uint]bool)
+	for i := len(journals) - 1; i >= 0; i-- {
+		j := journals[i]
+		if len(mediaFileIds) > 0 && !slices.Contains(mediaFileIds, j.ScrapeMediaFileId) {
+			continue
+		}
+		// 删除的一般是整理后已经为空的来源目录，移回时会重新创建，不影响还原
+		if j.Status == models.RenameJournalStatusIrreversible || j.Status == models.RenameJournalStatusReverted {
+			continue
+		}
+		err := reverter.Revert(j)
+		switch {
+		case err == nil:
+			j.UpdateStatus(models.RenameJournalStatusReverted, "")
+			reverted[j.ScrapeMediaFileId] = true
+		case errors.Is(err, rename.ErrRevertConflict):
+			helpers.AppLogger.Warnf("撤销整理操作 #%d 冲突: %v", j.ID, err)
+			j.UpdateStatus(models.RenameJournalStatusConflict, err.Error())
+			unfinished[j.ScrapeMediaFileId] = true
+		default:
+			helpers.AppLogger.Errorf("撤销整理操作 #%d 失败: %v", j.ID, err)
+			j.UpdateStatus(models.RenameJournalStatusFailed, err.Error())
+			unfinished[j.ScrapeMediaFileId] = true
+		}
+	}
+	// 文件都已经回到原位置的刮削记录删除掉，和回滚一样，下次扫描时重新识别
+	success := 0
+	for id := range reverted {
+		if id == 0 || unfinished[id] {
+			continue
+		}
+		if err := models.DeleteRevertedScrapeMediaFile(id); err != nil {
+			helpers.AppLogger.Errorf("删除已撤销的刮削记录 %d 失败: %v", id, err)
+		}
+		success++
+	}
+	delete(unfinished, 0)
+	helpers.AppLogger.Infof("刮削目录 %s 的整理批次 %s 撤销完成，%d 条刮削记录已还原，%d 条刮削记录有未撤销的操作", sp.SourcePath, journals[0].RunNo, success, len(unfinished))
+}
@@ -10,6 +10,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"path/filepath"
 )
 
 // 重命名电影文件
@@ -20,22 +21,35 @@ type renameMovieImpl struct {
 }
 
 func NewRenameMovieImpl(scrapePath *models.ScrapePath, ctx context.Context, v115Client *v115open.OpenClient, openlistClient *openlist.Client, baiduPanClient *baidupan.Client) renameImpl {
-	var ri renameImpl
-	switch scrapePath.SourceType {
+	return &renameMovieImpl{
+		scrapePath: scrapePath,
+		ctx:        ctx,
+		renameImpl: newScrapePathRenamer(scrapePath, ctx, v115Client, openlistClient, baiduPanClient),
+	}
+}
+
+// 按存储类型创建整理实现
+func newRenamer(ctx context.Context, scrapePath *models.ScrapePath, sourceType models.SourceType, v115Client *v115open.OpenClient, openlistClient *openlist.Client, baiduPanClient *baidupan.Client) rename.Renamer {
+	switch sourceType {
 	case models.SourceType115:
-		ri = rename.NewRename115(ctx, scrapePath, v115Client)
+		return rename.NewRename115(ctx, scrapePath, v115Client)
 	case models.SourceTypeOpenList:
-		ri = rename.NewRenameOpenList(ctx, scrapePath, openlistClient)
+		return rename.NewRenameOpenList(ctx, scrapePath, openlistClient)
 	case models.SourceTypeBaiduPan:
-		ri = rename.NewRenameBaiduPan(ctx, scrapePath, baiduPanClient)
+		return rename.NewRenameBaiduPan(ctx, scrapePath, baiduPanClient)
 	default:
-		ri = rename.NewRenameLocal(ctx, scrapePath)
+		return rename.NewRenameLocal(ctx, scrapePath)
 	}
-	return &renameMovieImpl{
-		scrapePath: scrapePath,
-		ctx:        ctx,
-		renameImpl: ri,
+}
+
+// 刮削目录的整理实现，目标和来源不在同一个存储时使用跨存储整理
+func newScrapePathRenamer(scrapePath *models.ScrapePath, ctx context.Context, v115Client *v115open.OpenClient, openlistClient *openlist.Client, baiduPanClient *baidupan.Client) rename.Renamer {
+	source := newRenamer(ctx, scrapePath, scrapePath.SourceType, v115Client, openlistClient, baiduPanClient)
+	if !scrapePath.IsCrossStorage() {
+		return source
 	}
+	dest := newRenamer(ctx, scrapePath, scrapePath.GetDestSourceType(), scrapePath.DestV115Client, scrapePath.DestOpenListClient, scrapePath.DestBaiduPanClient)
+	return rename.NewRenameTransfer(ctx, scrapePath, source, dest)
 }
 
 func (r *renameMovieImpl) RenameAndMove(mediaFile *models.ScrapeMediaFile, destPath, destPathId, newName string) error {
@@ -52,6 +66,9 @@ func (r *renameMovieImpl) RenameAndMove(mediaFile *models.ScrapeMediaFile, destP
 	if newName == "" {
 		newName = fmt.Sprintf("%s%s", mediaFile.NewVideoBaseName, mediaFile.VideoExt)
 	}
+	if r.movesWholeDisc(mediaFile) {
+		return r.moveBlurayDisc(mediaFile, destPath, destPathId)
+	}
 	helpers.AppLogger.Infof("开始根据整理规则重命名或移动文件：%s", newName)
 	if err := r.renameImpl.RenameAndMove(mediaFile, destPath, destPathId, newName); err != nil {
 		return err
@@ -68,7 +85,49 @@ func (r *renameMovieImpl) CheckAndMkDir(destFullPath, rootPath, rootPathId strin
 	return newPathId, nil
 }
 
+// 蓝光原盘在同一个存储中移动整理时整个BDMV目录移动，复制模式和跨存储整理只处理主影片流
+func (r *renameMovieImpl) movesWholeDisc(mediaFile *models.ScrapeMediaFile) bool {
+	return mediaFile.DiscType == models.DiscTypeBluray && mediaFile.DiscDirId != "" && mediaFile.RenameType == models.RenameTypeMove && !r.scrapePath.IsCrossStorage()
+}
+
+// 把蓝光原盘的BDMV目录移动到电影目录中，视频流跟随目录移动，不改名
+func (r *renameMovieImpl) moveBlurayDisc(mediaFile *models.ScrapeMediaFile, destPath, destPathId string) error {
+	helpers.AppLogger.Infof("开始移动蓝光原盘 %s 到 %s", mediaFile.DiscPath, destPath)
+	if err := r.renameImpl.MoveFiles(models.MoveNewFileToSourceFile{
+		FileId:       mediaFile.DiscDirId,
+		PathId:       destPathId,
+		FileFullPath: filepath.Join(destPath, "BDMV"),
+		Source:       &models.FileSource{Path: mediaFile.DiscPath, PathId: mediaFile.DiscPathId, Name: "BDMV"},
+	}); err != nil {
+		return err
+	}
+	// 网盘文件ID移动后不变，其他存储的文件ID是路径
+	videoFileId := filepath.Join(destPathId, "BDMV", "STREAM", mediaFile.VideoFilename)
+	if mediaFile.SourceType != models.SourceTypeLocal {
+		videoFileId = filepath.ToSlash(videoFileId)
+	}
+	switch mediaFile.SourceType {
+	case models.SourceType115:
+		mediaFile.Media.VideoFileId = mediaFile.VideoFileId
+		mediaFile.Media.VideoPickCode = mediaFile.VideoPickCode
+	case models.SourceTypeBaiduPan:
+		mediaFile.Media.VideoFileId = videoFileId
+		mediaFile.Media.VideoPickCode = mediaFile.VideoPickCode
+	default:
+		mediaFile.Media.VideoFileId = videoFileId
+		mediaFile.Media.VideoPickCode = videoFileId
+	}
+	return nil
+}
+
 func (r *renameMovieImpl) RemoveMediaSourcePath(mediaFile *models.ScrapeMediaFile, sp *models.ScrapePath) error {
+	if r.movesWholeDisc(mediaFile) && mediaFile.DiscPathId != "" {
+		// 视频流目录已经跟随BDMV目录移走，删除原盘所在目录
+		discFile := *mediaFile
+		discFile.Path = mediaFile.DiscPath
+		discFile.PathId = mediaFile.DiscPathId
+		return r.renameImpl.RemoveMediaSourcePath(&discFile, sp)
+	}
 	return r.renameImpl.RemoveMediaSourcePath(mediaFile, sp)
 }
 
@@ -92,6 +151,18 @@ func (r *renameMovieImpl) Rename(fileId, newName string) error {
 	return r.renameImpl.Rename(fileId, newName)
 }
 
+func (r *renameMovieImpl) RenameAt(source models.FileSource, fileId, newName string) error {
+	return r.renameImpl.RenameAt(source, fileId, newName)
+}
+
 func (r *renameMovieImpl) ExistsAndRename(fileId, newName string) (string, error) {
 	return r.renameImpl.ExistsAndRename(fileId, newName)
 }
+
+func (r *renameMovieImpl) FindFile(path, pathId, name string) (string, error) {
+	return r.renameImpl.FindFile(path, pathId, name)
+}
+
+func (r *renameMovieImpl) RenameFile(path, pathId, name, newName string) error {
+	return r.renameImpl.RenameFile(path, pathId, name, newName)
+}
@@ -5,7 +5,6 @@ import (
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/models"
 	"Q115-STRM/internal/openlist"
-	"Q115-STRM/internal/scrape/rename"
 	"Q115-STRM/internal/v115open"
 	"context"
 	"errors"
@@ -20,21 +19,10 @@ type renameTvShowImpl struct {
 }
 
 func NewRenameTvShowImpl(scrapePath *models.ScrapePath, ctx context.Context, v115Client *v115open.OpenClient, openlistClient *openlist.Client, baiduPanClient *baidupan.Client) renameImpl {
-	var ri renameImpl
-	switch scrapePath.SourceType {
-	case models.SourceType115:
-		ri = rename.NewRename115(ctx, scrapePath, v115Client)
-	case models.SourceTypeOpenList:
-		ri = rename.NewRenameOpenList(ctx, scrapePath, openlistClient)
-	case models.SourceTypeBaiduPan:
-		ri = rename.NewRenameBaiduPan(ctx, scrapePath, baiduPanClient)
-	default:
-		ri = rename.NewRenameLocal(ctx, scrapePath)
-	}
 	return &renameTvShowImpl{
 		scrapePath: scrapePath,
 		ctx:        ctx,
-		renameImpl: ri,
+		renameImpl: newScrapePathRenamer(scrapePath, ctx, v115Client, openlistClient, baiduPanClient),
 	}
 }
 
@@ -81,6 +69,18 @@ func (r *renameTvShowImpl) Rename(fileId, newName string) error {
 	return r.renameImpl.Rename(fileId, newName)
 }
 
+func (r *renameTvShowImpl) RenameAt(source models.FileSource, fileId, newName string) error {
+	return r.renameImpl.RenameAt(source, fileId, newName)
+}
+
 func (r *renameTvShowImpl) ExistsAndRename(fileId, newName string) (string, error) {
 	return r.renameImpl.ExistsAndRename(fileId, newName)
 }
+
+func (r *renameTvShowImpl) FindFile(path, pathId, name string) (string, error) {
+	return r.renameImpl.FindFile(path, pathId, name)
+}
+
+func (r *renameTvShowImpl) RenameFile(path, pathId, name, newName string) error {
+	return r.renameImpl.RenameFile(path, pathId, name, newName)
+}
@@ -21,7 +21,9 @@ type Scan115Impl struct {
 }
 
 func New115ScanImpl(scrapePath *models.ScrapePath, client *v115open.OpenClient, ctx context.Context) *Scan115Impl {
-	return &Scan115Impl{scanBaseImpl: scanBaseImpl{ctx: ctx, scrapePath: scrapePath}, client: client}
+	s := &Scan115Impl{scanBaseImpl: scanBaseImpl{ctx: ctx, scrapePath: scrapePath}, client: client}
+	s.playlistReader = s
+	return s
 }
 
 // 检查来源目录和目标目录是否存在
@@ -32,7 +34,8 @@ func (s *Scan115Impl) CheckPathExists() error {
 		ferr := fmt.Errorf("刮削来源目录 %s => %s 疑似不存在，请检查或编辑重新选择来源目录: %v", s.scrapePath.SourcePathId, s.scrapePath.SourcePath, err)
 		return ferr
 	}
-	if s.scrapePath.ScrapeType != models.ScrapeTypeOnly {
+	// 跨存储整理时目标目录由目标存储检查
+	if s.scrapePath.ScrapeType != models.ScrapeTypeOnly && !s.scrapePath.IsCrossStorage() {
 		// 检查targetId是否存在
 		targetDetail, err := s.client.GetFsDetailByCid(s.ctx, s.scrapePath.DestPathId)
 		if err != nil || targetDetail.FileId == "" {
@@ -59,6 +62,7 @@ func (s *Scan115Impl) GetNetFileFiles() error {
 		ferr := fmt.Errorf("刮削目录 %s => %s 疑似不存在，请检查或编辑重新选择来源目录: %v", s.scrapePath.SourcePathId, s.scrapePath.SourcePath, err)
 		return ferr
 	}
+	s.initScanDirs()
 	// 初始化路径队列，容量为接口线程数
 	s.pathTasks = make(chan string, models.SettingsGlobal.FileDetailThreads)
 	// 启动一个控制buffer的context
@@ -67,7 +71,13 @@ func (s *Scan115Impl) GetNetFileFiles() error {
 	go s.bufferMonitor(bufferCtx)
 	// 加入根目录
 	s.wg = sync.WaitGroup{}
-	s.addPathToTasks(s.scrapePath.SourcePathId)
+	s.addRootPathTasks(func(folder string) (string, error) {
+		detail, err := s.client.GetFsDetailByPath(s.ctx, folder)
+		if err != nil {
+			return "", err
+		}
+		return detail.FileId, nil
+	})
 	// 启动一个协程处理目录
 	helpers.AppLogger.Infof("开始处理目录 %s, 开启 %d 个任务", s.scrapePath.SourcePath, models.SettingsGlobal.FileDetailThreads)
 	for i := 0; i < models.SettingsGlobal.FileDetailThreads; i++ {
@@ -83,9 +93,41 @@ func (s *Scan115Impl) GetNetFileFiles() error {
 	s.wg.Wait()        // 等待最后一个目录处理完
 	close(s.pathTasks) // 关闭pathTasks，释放资源
 	cancelBuffer()     // 取消bufferMonitor上下文，释放资源
+	s.saveScanDirs()
 	return nil
 }
 
+// 增量扫描时检查子目录是否可以跳过
+// 目录的修改时间只反映直接子项的变化，还要对比目录详情中包含的文件和文件夹总数，判断更深层的目录是否有变化
+func (s *Scan115Impl) skipUnchangedDir(file v115open.File) bool {
+	if !s.scrapePath.IncrementalScan {
+		return false
+	}
+	// 上次没有扫描过的目录一定要扫描，不查询目录详情，文件总数记为未知，下次扫描时再查询记录
+	if !s.fullScan && s.lastScanDirs[file.FileId] == nil {
+		s.recordScanDir(&models.ScrapeScanDir{PathId: file.FileId, MTime: file.Utime, FileCount: -1, FolderCount: -1})
+		return false
+	}
+	detail, err := s.client.GetFsDetailByCid(s.ctx, file.FileId)
+	if err != nil || detail.FileId == "" {
+		helpers.AppLogger.Warnf("查询目录 %s 详情失败，不跳过: %v", file.FileName, err)
+		return false
+	}
+	fileCount, _ := detail.Count.Int64()
+	folderCount, _ := detail.FolderCount.Int64()
+	dir := &models.ScrapeScanDir{
+		PathId:      file.FileId,
+		MTime:       file.Utime,
+		FileCount:   fileCount,
+		FolderCount: folderCount,
+	}
+	if !s.recordScanDir(dir) {
+		return false
+	}
+	helpers.AppLogger.Infof("目录 %s 上次扫描后没有变化，跳过", file.FileName)
+	return true
+}
+
 func (s *Scan115Impl) startPathWorkWithLimiter(workerID int) {
 	// 从channel获取路径任务
 	for {
@@ -122,6 +164,10 @@ func (s *Scan115Impl) startPathWorkWithLimiter(workerID int) {
 					continue pageloop
 				}
 				parentPath = fsList.PathStr
+				if models.IsBlurayStreamPath(parentPath) && len(fsList.Path) >= 3 {
+					n := len(fsList.Path)
+					s.setDiscDir(pathId, fsList.Path[n-2].FileId.String(), fsList.Path[n-3].FileId.String())
+				}
 				// 取完就跳出
 				if len(fsList.Data) == 0 {
 					break pageloop
@@ -133,7 +179,10 @@ func (s *Scan115Impl) startPathWorkWithLimiter(workerID int) {
 						return
 					}
 					if file.FileCategory == v115open.TypeDir {
-						// 是目录，加入队列
+						// 是目录，没有变化的跳过，否则加入队列
+						if s.skipUnchangedDir(file) {
+							continue fileloop
+						}
 						s.addPathToTasks(file.FileId)
 						continue fileloop
 					}
@@ -206,6 +255,7 @@ func (s *Scan115Impl) startPathWorkWithLimiter(workerID int) {
 			// 处理视频文件
 			verr := s.processVideoFile(parentPath, pathId, videoFiles, picFiles, nfoFiles, subFiles)
 			if verr != nil {
+				s.scanFailed.Store(true)
 				s.wg.Done()
 				return
 			}
@@ -214,3 +264,41 @@ func (s *Scan115Impl) startPathWorkWithLimiter(workerID int) {
 		}
 	}
 }
+
+// 读取蓝光原盘的播放列表，115的目录ID不是路径，从BDMV目录中找到PLAYLIST目录
+func (s *Scan115Impl) readBlurayPlaylists(playlistPath, bdmvId string) []*models.BlurayPlaylist {
+	limit := models.GetFileListPageSize()
+	bdmvList, err := s.client.GetFsList(s.ctx, bdmvId, true, true, true, 0, limit)
+	if err != nil {
+		helpers.AppLogger.Warnf("查询蓝光原盘目录 %s 失败: %v", filepath.Dir(playlistPath), err)
+		return nil
+	}
+	playlistId := ""
+	for _, dir := range bdmvList.Data {
+		if dir.FileCategory == v115open.TypeDir && strings.EqualFold(dir.FileName, filepath.Base(playlistPath)) {
+			playlistId = dir.FileId
+			break
+		}
+	}
+	if playlistId == "" {
+		return nil
+	}
+	fsList, err := s.client.GetFsList(s.ctx, playlistId, true, false, false, 0, limit)
+	if err != nil {
+		helpers.AppLogger.Warnf("查询蓝光原盘播放列表目录 %s 失败: %v", playlistPath, err)
+		return nil
+	}
+	files := make([]*models.BlurayPlaylistFile, 0)
+	for _, file := range fsList.Data {
+		if file.FileCategory != v115open.TypeDir && models.IsBlurayPlaylistFile(file.FileName) {
+			files = append(files, &models.BlurayPlaylistFile{Id: file.PickCode, Name: file.FileName, Size: file.FileSize})
+		}
+	}
+	return models.ReadBlurayPlaylists(files, func(file *models.BlurayPlaylistFile) ([]byte, error) {
+		url := s.client.GetDownloadUrl(s.ctx, file.Id, v115open.DEFAULTUA, false)
+		if url == "" {
+			return nil, errors.New("获取115文件下载链接失败, url为空")
+		}
+		return helpers.ReadFromUrl(url, v115open.DEFAULTUA)
+	})
+}
@@ -21,7 +21,9 @@ type ScanBaiduPanImpl struct {
 }
 
 func NewBaiduPanScanImpl(scrapePath *models.ScrapePath, client *baidupan.Client, ctx context.Context) *ScanBaiduPanImpl {
-	return &ScanBaiduPanImpl{scanBaseImpl: scanBaseImpl{ctx: ctx, scrapePath: scrapePath}, client: client}
+	s := &ScanBaiduPanImpl{scanBaseImpl: scanBaseImpl{ctx: ctx, scrapePath: scrapePath}, client: client}
+	s.playlistReader = s
+	return s
 }
 
 // 检查来源目录和目标目录是否存在
@@ -32,7 +34,8 @@ func (s *ScanBaiduPanImpl) CheckPathExists() error {
 		ferr := fmt.Errorf("刮削来源目录 %s 疑似不存在，请检查或编辑重新选择来源目录: %v", s.scrapePath.SourcePathId, err)
 		return ferr
 	}
-	if s.scrapePath.ScrapeType != models.ScrapeTypeOnly {
+	// 跨存储整理时目标目录由目标存储检查
+	if s.scrapePath.ScrapeType != models.ScrapeTypeOnly && !s.scrapePath.IsCrossStorage() {
 		// 检查targetId是否存在
 		_, err = s.client.PathExists(s.ctx, s.scrapePath.DestPathId)
 		if err != nil {
@@ -66,7 +69,9 @@ func (s *ScanBaiduPanImpl) GetNetFileFiles() error {
 	go s.bufferMonitor(bufferCtx)
 	// 加入根目录
 	s.wg = sync.WaitGroup{}
-	s.addPathToTasks(s.scrapePath.SourcePathId)
+	s.addRootPathTasks(func(folder string) (string, error) {
+		return folder, nil
+	})
 	// 启动一个协程处理目录
 	helpers.AppLogger.Infof("开始处理目录 %s, 开启 %d 个线程", s.scrapePath.SourcePath, models.SettingsGlobal.FileDetailThreads)
 	for i := 0; i < models.SettingsGlobal.FileDetailThreads; i++ {
@@ -218,3 +223,26 @@ func (s *ScanBaiduPanImpl) startPathWorkWithLimiter(workerID int) {
 
 	}
 }
+
+// 读取蓝光原盘的播放列表
+func (s *ScanBaiduPanImpl) readBlurayPlaylists(playlistPath, bdmvId string) []*models.BlurayPlaylist {
+	playlistPath = filepath.ToSlash(playlistPath)
+	fsList, err := s.client.GetFileList(s.ctx, playlistPath, 0, 1, 0, 1000)
+	if err != nil {
+		return nil
+	}
+	files := make([]*models.BlurayPlaylistFile, 0)
+	for _, file := range fsList {
+		if file.IsDir == uint32(1) || !models.IsBlurayPlaylistFile(file.ServerFilename) {
+			continue
+		}
+		files = append(files, &models.BlurayPlaylistFile{Id: helpers.Int64ToString(int64(file.FsId)), Name: file.ServerFilename, Size: int64(file.Size)})
+	}
+	return models.ReadBlurayPlaylists(files, func(file *models.BlurayPlaylistFile) ([]byte, error) {
+		detail, err := s.client.GetFileDetail(s.ctx, file.Id, 1)
+		if err != nil || detail == nil || detail.Dlink == "" {
+			return nil, errors.New("获取百度网盘文件下载链接失败, url为空")
+		}
+		return helpers.ReadFromUrl(detail.Dlink, "pan.baidu.com")
+	})
+}
@@ -11,6 +11,7 @@ import (
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -29,6 +30,16 @@ type scanBaseImpl struct {
 	mu         sync.RWMutex // 保护缓冲区的锁
 	wg         sync.WaitGroup
 	pathTasks  chan string
+	discDirs   sync.Map // 蓝光原盘视频流目录ID => BDMV目录和原盘目录ID，目录ID不是路径时由扫描器记录
+	// 读取蓝光原盘播放列表，为空时按文件大小选择主影片流
+	playlistReader blurayPlaylistReader
+	// 增量扫描
+	fullScan     bool                             // 强制全量扫描
+	lastScanDirs map[string]*models.ScrapeScanDir // 上次扫描记录的目录状态
+	scanDirs     sync.Map                         // 本次扫描的目录状态
+	scanFailed   atomic.Bool                      // 有目录处理失败，不保存本次扫描的目录状态
+	// 只扫描这些子目录，为空时扫描整个来源目录
+	scanFolders []string
 }
 
 func (s *scanBaseImpl) CheckIsRunning() bool {
@@ -40,6 +51,39 @@ func (s *scanBaseImpl) CheckIsRunning() bool {
 	}
 }
 
+// 只扫描来源目录下的部分子目录，入站Webhook触发的子目录同步完成后使用
+func (s *scanBaseImpl) SetScanFolders(folders []string) {
+	s.scanFolders = folders
+}
+
+// 把要扫描的根目录加入队列，resolve 把子目录路径转换成目录ID
+// 子目录不在来源目录下或者转换失败时扫描整个来源目录
+func (s *scanBaseImpl) addRootPathTasks(resolve func(folder string) (string, error)) {
+	ids := make([]string, 0, len(s.scanFolders))
+	for _, folder := range s.scanFolders {
+		if !models.IsSubPath(s.scrapePath.SourcePath, folder) || models.IsSubPath(folder, s.scrapePath.SourcePath) {
+			helpers.AppLogger.Infof("目录 %s 不是刮削目录 %s 的子目录，扫描整个刮削目录", folder, s.scrapePath.SourcePath)
+			ids = ids[:0]
+			break
+		}
+		id, err := resolve(folder)
+		if err != nil || id == "" {
+			helpers.AppLogger.Warnf("查询子目录 %s 失败，扫描整个刮削目录: %v", folder, err)
+			ids = ids[:0]
+			break
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		s.addPathToTasks(s.scrapePath.SourcePathId)
+		return
+	}
+	helpers.AppLogger.Infof("刮削目录 %s 只扫描子目录: %s", s.scrapePath.SourcePath, strings.Join(s.scanFolders, ", "))
+	for _, id := range ids {
+		s.addPathToTasks(id)
+	}
+}
+
 func (s *scanBaseImpl) addPathToTasks(path string) {
 	select {
 	case <-s.ctx.Done():
@@ -117,6 +161,10 @@ func (s *scanBaseImpl) processVideoFile(parentPath, pathId string, videoFiles, p
 	// 记录下图片、nfo、字幕文件
 	// 如果发现了视频文件，则寻找有没有视频文件对应的图片、nfo、字幕文件
 	// 如果没发现视频文件，则清空
+	// 蓝光原盘的视频流目录，整个原盘作为一个视频处理
+	if models.IsBlurayStreamPath(parentPath) {
+		return s.processBlurayDisc(parentPath, pathId, videoFiles)
+	}
 videoloop:
 	// 处理videofiles以及对应的字幕等
 	for _, videoFile := range videoFiles {
@@ -124,6 +172,11 @@ videoloop:
 		if !s.CheckIsRunning() {
 			return errors.New("任务被停止")
 		}
+		// 预告片、花絮等附加内容不单独刮削，记录下来跟随所属视频整理
+		if extra := s.scrapePath.MakeScrapeExtraFile(parentPath, pathId, videoFile.Name, videoFile.Id, videoFile.PickCode); extra != nil {
+			models.SaveScrapeExtraFile(extra)
+			continue videoloop
+		}
 		ext := filepath.Ext(videoFile.Name)
 		baseName := strings.TrimSuffix(videoFile.Name, ext)
 		imageList := make([]*models.MediaMetaFiles, 0)
@@ -209,6 +262,14 @@ videoloop:
 			continue videoloop
 		}
 		mediaFile := s.scrapePath.MakeScrapeMediaFile(parentPath, pathId, videoFile.Name, videoFile.Id, videoFile.PickCode)
+		// ISO镜像使用所在目录名识别，在来源根目录时只能使用文件名
+		if models.IsIsoFile(videoFile.Name) && s.scrapePath.MediaType == models.MediaTypeMovie {
+			mediaFile.DiscType = models.DiscTypeIso
+			if pathId != s.scrapePath.SourcePathId {
+				mediaFile.DiscPath = parentPath
+				mediaFile.DiscPathId = pathId
+			}
+		}
 		if nfoMetaFile != nil {
 			mediaFile.NfoFileId = nfoMetaFile.FileId
 			mediaFile.NfoPickCode = nfoMetaFile.PickCode
@@ -0,0 +1,101 @@
+package scan
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 读取蓝光原盘的播放列表，各来源分别实现
+type blurayPlaylistReader interface {
+	readBlurayPlaylists(playlistPath, bdmvId string) []*models.BlurayPlaylist
+}
+
+type discDir struct {
+	bdmvId string // BDMV目录ID
+	rootId string // 原盘所在目录ID
+}
+
+// 记录蓝光原盘视频流目录对应的BDMV目录和原盘目录ID
+// 115的目录ID不是路径，无法从视频流目录ID推算，扫描时从目录列表的路径中取出
+func (s *scanBaseImpl) setDiscDir(streamPathId, bdmvId, rootId string) {
+	s.discDirs.Store(streamPathId, discDir{bdmvId: bdmvId, rootId: rootId})
+}
+
+func (s *scanBaseImpl) getDiscDir(streamPathId string) discDir {
+	if dir, ok := s.discDirs.Load(streamPathId); ok {
+		return dir.(discDir)
+	}
+	// 目录ID就是路径
+	bdmvId := filepath.Dir(streamPathId)
+	rootId := filepath.Dir(bdmvId)
+	if s.scrapePath.SourceType != models.SourceTypeLocal {
+		bdmvId = filepath.ToSlash(bdmvId)
+		rootId = filepath.ToSlash(rootId)
+	}
+	return discDir{bdmvId: bdmvId, rootId: rootId}
+}
+
+// 蓝光原盘的主影片流，取最长的播放列表中的主要视频流
+// 没有可读的播放列表或者视频流不在列表中时，取最大的m2ts文件
+func mainBlurayStream(videoFiles []*localFile, playlists []*models.BlurayPlaylist) *localFile {
+	if clip := models.MainBlurayClip(playlists); clip != "" {
+		for _, videoFile := range videoFiles {
+			if strings.EqualFold(videoFile.Name, clip) {
+				return videoFile
+			}
+		}
+	}
+	var main *localFile
+	for _, videoFile := range videoFiles {
+		if !strings.EqualFold(filepath.Ext(videoFile.Name), ".m2ts") {
+			continue
+		}
+		if main == nil || videoFile.Size > main.Size {
+			main = videoFile
+		}
+	}
+	return main
+}
+
+// 处理蓝光原盘，视频流目录中的所有m2ts只生成一条刮削记录，使用原盘所在目录名识别
+func (s *scanBaseImpl) processBlurayDisc(parentPath, pathId string, videoFiles []*localFile) error {
+	if s.scrapePath.MediaType != models.MediaTypeMovie {
+		helpers.AppLogger.Warnf("目录 %s 是蓝光原盘，只有电影支持按原盘刮削整理，跳过", parentPath)
+		return nil
+	}
+	// 已入库的视频流不会出现在列表中，原盘已经有记录就不再处理
+	if models.CountScrapeMediaFilesInPath(s.scrapePath.ID, parentPath) > 0 {
+		helpers.AppLogger.Infof("蓝光原盘 %s 已在数据库中，跳过", parentPath)
+		return nil
+	}
+	dir := s.getDiscDir(pathId)
+	var playlists []*models.BlurayPlaylist
+	if s.playlistReader != nil {
+		playlists = s.playlistReader.readBlurayPlaylists(models.BlurayPlaylistPath(parentPath), dir.bdmvId)
+	}
+	mainStream := mainBlurayStream(videoFiles, playlists)
+	if mainStream == nil {
+		return nil
+	}
+	discPath := models.BlurayDiscRoot(parentPath)
+	if s.scrapePath.SourceType != models.SourceTypeLocal {
+		discPath = filepath.ToSlash(discPath)
+	}
+	mediaFile := s.scrapePath.MakeScrapeMediaFile(parentPath, pathId, mainStream.Name, mainStream.Id, mainStream.PickCode)
+	mediaFile.DiscType = models.DiscTypeBluray
+	mediaFile.DiscPath = discPath
+	mediaFile.DiscPathId = dir.rootId
+	mediaFile.DiscDirId = dir.bdmvId
+	mediaFile.Status = models.ScrapeMediaStatusScanned
+	mediaFile.ScanTime = time.Now().Unix()
+	if err := db.Db.Save(mediaFile).Error; err != nil {
+		helpers.AppLogger.Errorf("保存蓝光原盘 %s 失败: %v", discPath, err)
+		return nil
+	}
+	helpers.AppLogger.Infof("目录 %s 是蓝光原盘，共 %d 个视频流，使用主影片流 %s", discPath, len(videoFiles), mainStream.Name)
+	return nil
+}
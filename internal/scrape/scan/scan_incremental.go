@@ -0,0 +1,50 @@
+package scan
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"time"
+)
+
+// 强制全量扫描，不跳过没有变化的目录，扫描后重新记录所有目录的状态
+func (s *scanBaseImpl) SetFullScan(fullScan bool) {
+	s.fullScan = fullScan
+}
+
+// 读取上次扫描记录的目录状态
+func (s *scanBaseImpl) initScanDirs() {
+	if !s.scrapePath.IncrementalScan {
+		return
+	}
+	if s.fullScan {
+		helpers.AppLogger.Infof("刮削目录 %s 强制全量扫描", s.scrapePath.SourcePath)
+		s.lastScanDirs = make(map[string]*models.ScrapeScanDir)
+		return
+	}
+	s.lastScanDirs = models.GetScrapeScanDirs(s.scrapePath.ID)
+	helpers.AppLogger.Infof("刮削目录 %s 增量扫描，上次扫描记录了 %d 个目录", s.scrapePath.SourcePath, len(s.lastScanDirs))
+}
+
+// 记录目录本次扫描时的状态，返回目录是否和上次扫描时一致可以跳过
+func (s *scanBaseImpl) recordScanDir(dir *models.ScrapeScanDir) bool {
+	dir.ScanTime = time.Now().Unix()
+	s.scanDirs.Store(dir.PathId, dir)
+	if s.fullScan {
+		return false
+	}
+	return dir.Unchanged(s.lastScanDirs[dir.PathId])
+}
+
+// 扫描完成后保存目录状态
+// 任务中途停止或者有目录处理失败时不保存，否则没扫描完的目录下次会被跳过
+func (s *scanBaseImpl) saveScanDirs() {
+	if !s.scrapePath.IncrementalScan || !s.CheckIsRunning() || s.scanFailed.Load() {
+		return
+	}
+	dirs := make([]*models.ScrapeScanDir, 0)
+	s.scanDirs.Range(func(key, value any) bool {
+		dirs = append(dirs, value.(*models.ScrapeScanDir))
+		return true
+	})
+	models.SaveScrapeScanDirs(s.scrapePath.ID, dirs)
+}
@@ -20,7 +20,9 @@ type ScanLocalImpl struct {
 }
 
 func NewLocalScanImpl(scrapePath *models.ScrapePath, ctx context.Context) *ScanLocalImpl {
-	return &ScanLocalImpl{scanBaseImpl: scanBaseImpl{ctx: ctx, scrapePath: scrapePath}}
+	s := &ScanLocalImpl{scanBaseImpl: scanBaseImpl{ctx: ctx, scrapePath: scrapePath}}
+	s.playlistReader = s
+	return s
 }
 
 // 检查来源目录和目标目录是否存在
@@ -31,7 +33,8 @@ func (s *ScanLocalImpl) CheckPathExists() error {
 		ferr := fmt.Errorf("刮削来源目录 %s 疑似不存在，请检查或编辑重新选择来源目录", s.scrapePath.SourcePathId)
 		return ferr
 	}
-	if s.scrapePath.ScrapeType != models.ScrapeTypeOnly {
+	// 跨存储整理时目标目录由目标存储检查
+	if s.scrapePath.ScrapeType != models.ScrapeTypeOnly && !s.scrapePath.IsCrossStorage() {
 		// 检查targetId是否存在
 		exists = helpers.PathExists(s.scrapePath.DestPathId)
 		if !exists {
@@ -64,7 +67,12 @@ func (s *ScanLocalImpl) GetNetFileFiles() error {
 	go s.bufferMonitor(bufferCtx)
 	// 加入根目录
 	s.wg = sync.WaitGroup{}
-	s.addPathToTasks(s.scrapePath.SourcePathId)
+	s.addRootPathTasks(func(folder string) (string, error) {
+		if !helpers.PathExists(folder) {
+			return "", fmt.Errorf("目录不存在")
+		}
+		return filepath.FromSlash(folder), nil
+	})
 	// 启动一个协程处理目录
 	threads := models.SettingsGlobal.FileDetailThreads
 	if threads == 0 {
@@ -177,3 +185,26 @@ func (s *ScanLocalImpl) startPathWorkWithLimiter(workerID int) {
 		}
 	}
 }
+
+// 读取蓝光原盘的播放列表
+func (s *ScanLocalImpl) readBlurayPlaylists(playlistPath, bdmvId string) []*models.BlurayPlaylist {
+	entries, err := os.ReadDir(playlistPath)
+	if err != nil {
+		return nil
+	}
+	files := make([]*models.BlurayPlaylistFile, 0)
+	for _, entry := range entries {
+		if entry.IsDir() || !models.IsBlurayPlaylistFile(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		fullPath := filepath.Join(playlistPath, entry.Name())
+		files = append(files, &models.BlurayPlaylistFile{Id: fullPath, Name: entry.Name(), Size: info.Size()})
+	}
+	return models.ReadBlurayPlaylists(files, func(file *models.BlurayPlaylistFile) ([]byte, error) {
+		return os.ReadFile(file.Id)
+	})
+}
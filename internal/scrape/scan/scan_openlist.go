@@ -4,6 +4,7 @@ import (
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/models"
 	"Q115-STRM/internal/openlist"
+	"Q115-STRM/internal/v115open"
 	"context"
 	"errors"
 	"fmt"
@@ -22,7 +23,9 @@ type ScanOpenlistImpl struct {
 }
 
 func NewOpenlistScanImpl(scrapePath *models.ScrapePath, client *openlist.Client, ctx context.Context) *ScanOpenlistImpl {
-	return &ScanOpenlistImpl{scanBaseImpl: scanBaseImpl{ctx: ctx, scrapePath: scrapePath}, client: client}
+	s := &ScanOpenlistImpl{scanBaseImpl: scanBaseImpl{ctx: ctx, scrapePath: scrapePath}, client: client}
+	s.playlistReader = s
+	return s
 }
 
 // 检查来源目录和目标目录是否存在
@@ -33,7 +36,8 @@ func (s *ScanOpenlistImpl) CheckPathExists() error {
 		ferr := fmt.Errorf("刮削来源目录 %s 疑似不存在，请检查或编辑重新选择来源目录: %v", s.scrapePath.SourcePathId, err)
 		return ferr
 	}
-	if s.scrapePath.ScrapeType != models.ScrapeTypeOnly {
+	// 跨存储整理时目标目录由目标存储检查
+	if s.scrapePath.ScrapeType != models.ScrapeTypeOnly && !s.scrapePath.IsCrossStorage() {
 		// 检查targetId是否存在
 		_, err = s.client.FileDetail(s.scrapePath.DestPathId)
 		if err != nil {
@@ -67,7 +71,9 @@ func (s *ScanOpenlistImpl) GetNetFileFiles() error {
 	go s.bufferMonitor(bufferCtx)
 	// 加入根目录
 	s.wg = sync.WaitGroup{}
-	s.addPathToTasks(s.scrapePath.SourcePathId)
+	s.addRootPathTasks(func(folder string) (string, error) {
+		return folder, nil
+	})
 	// 启动一个协程处理目录
 	helpers.AppLogger.Infof("开始处理目录 %s, 开启 %d 个任务", s.scrapePath.SourcePath, models.SettingsGlobal.FileDetailThreads)
 	for i := 0; i < models.SettingsGlobal.FileDetailThreads; i++ {
@@ -215,3 +221,27 @@ func (s *ScanOpenlistImpl) startPathWorkWithLimiter(workerID int) {
 
 	}
 }
+
+// 读取蓝光原盘的播放列表
+func (s *ScanOpenlistImpl) readBlurayPlaylists(playlistPath, bdmvId string) []*models.BlurayPlaylist {
+	playlistPath = filepath.ToSlash(playlistPath)
+	fsList, err := s.client.FileList(s.ctx, playlistPath, 1, 1000)
+	if err != nil {
+		return nil
+	}
+	files := make([]*models.BlurayPlaylistFile, 0)
+	for _, file := range fsList.Content {
+		if file.IsDir || !models.IsBlurayPlaylistFile(file.Name) {
+			continue
+		}
+		fullPath := filepath.ToSlash(filepath.Join(playlistPath, file.Name))
+		files = append(files, &models.BlurayPlaylistFile{Id: fullPath, Name: file.Name, Size: file.Size})
+	}
+	return models.ReadBlurayPlaylists(files, func(file *models.BlurayPlaylistFile) ([]byte, error) {
+		url := s.client.GetRawUrl(file.Id)
+		if url == "" {
+			return nil, errors.New("获取openlist文件下载链接失败, url为空")
+		}
+		return helpers.ReadFromUrl(url, v115open.DEFAULTUA)
+	})
+}
@@ -30,6 +30,8 @@ import (
 type scanImpl interface {
 	GetNetFileFiles() error
 	CheckPathExists() error
+	SetFullScan(fullScan bool)
+	SetScanFolders(folders []string)
 }
 
 type IdentifyImpl interface {
@@ -55,7 +57,10 @@ type renameImpl interface {
 	MoveFiles(f models.MoveNewFileToSourceFile) error
 	DeleteDir(path, pathId string) error
 	Rename(fileId, newName string) error
+	RenameAt(source models.FileSource, fileId, newName string) error
 	ExistsAndRename(fileId, newName string) (string, error)
+	FindFile(path, pathId, name string) (string, error)
+	RenameFile(path, pathId, name, newName string) error
 }
 
 // 刮削
@@ -76,6 +81,9 @@ type Scrape struct {
 	V115Client     *v115open.OpenClient
 	OpenlistClient *openlist.Client
 	BaiduPanClient *baidupan.Client
+	SkipScan       bool     // 跳过扫描来源目录，直接刮削已入库的待刮削记录
+	FullScan       bool     // 强制全量扫描，开启增量扫描时也扫描所有目录
+	ScanFolders    []string // 只扫描这些子目录，为空时扫描整个来源目录
 }
 
 // scrapePath 要刮削的目录
@@ -90,21 +98,73 @@ func NewScrape(scrapePath *models.ScrapePath) *Scrape {
 }
 
 func (s *Scrape) initOpenClient() error {
-	if s.scrapePath.SourceType == models.SourceTypeLocal {
+	if s.scrapePath.SourceType != models.SourceTypeLocal {
+		account, err := s.scrapePath.GetAccount()
+		if err != nil {
+			helpers.AppLogger.Errorf("获取刮削目录 %s 账号失败: %v", s.scrapePath.SourcePath, err)
+			return err
+		}
+		switch s.scrapePath.SourceType {
+		case models.SourceType115:
+			s.V115Client = account.Get115Client()
+		case models.SourceTypeOpenList:
+			s.OpenlistClient = account.GetOpenListClient()
+		case models.SourceTypeBaiduPan:
+			s.BaiduPanClient = account.GetBaiDuPanClient()
+		}
+	}
+	// 目标路径的客户端，和来源在同一个存储时直接使用来源的客户端
+	s.scrapePath.DestV115Client = s.V115Client
+	s.scrapePath.DestOpenListClient = s.OpenlistClient
+	s.scrapePath.DestBaiduPanClient = s.BaiduPanClient
+	if !s.scrapePath.IsCrossStorage() || s.scrapePath.GetDestSourceType() == models.SourceTypeLocal {
 		return nil
 	}
-	account, err := s.scrapePath.GetAccount()
+	destAccount, err := s.scrapePath.GetDestAccount()
 	if err != nil {
-		helpers.AppLogger.Errorf("获取刮削目录 %s 账号失败: %v", s.scrapePath.SourcePath, err)
+		helpers.AppLogger.Errorf("获取刮削目录 %s 的目标账号失败: %v", s.scrapePath.SourcePath, err)
 		return err
 	}
-	switch s.scrapePath.SourceType {
+	switch s.scrapePath.GetDestSourceType() {
+	case models.SourceType115:
+		s.scrapePath.DestV115Client = destAccount.Get115Client()
+		if s.scrapePath.DestV115Client == nil {
+			return fmt.Errorf("目标账号 %s 的115客户端不存在", destAccount.Name)
+		}
+	case models.SourceTypeOpenList:
+		s.scrapePath.DestOpenListClient = destAccount.GetOpenListClient()
+		if s.scrapePath.DestOpenListClient == nil {
+			return fmt.Errorf("目标账号 %s 的OpenList客户端不存在", destAccount.Name)
+		}
+	case models.SourceTypeBaiduPan:
+		s.scrapePath.DestBaiduPanClient = destAccount.GetBaiDuPanClient()
+		if s.scrapePath.DestBaiduPanClient == nil {
+			return fmt.Errorf("目标账号 %s 的百度网盘客户端不存在", destAccount.Name)
+		}
+	}
+	return nil
+}
+
+// 跨存储整理时用目标存储的客户端检查目标目录是否存在
+func (s *Scrape) checkDestPathExists() error {
+	sp := s.scrapePath
+	var err error
+	exists := false
+	switch sp.GetDestSourceType() {
+	case models.SourceTypeLocal:
+		exists = helpers.PathExists(sp.DestPathId)
 	case models.SourceType115:
-		s.V115Client = account.Get115Client()
+		var detail *v115open.FileDetail
+		detail, err = sp.DestV115Client.GetFsDetailByCid(s.ctx, sp.DestPathId)
+		exists = err == nil && detail.FileId != ""
 	case models.SourceTypeOpenList:
-		s.OpenlistClient = account.GetOpenListClient()
+		_, err = sp.DestOpenListClient.FileDetail(sp.DestPathId)
+		exists = err == nil
 	case models.SourceTypeBaiduPan:
-		s.BaiduPanClient = account.GetBaiDuPanClient()
+		exists, err = sp.DestBaiduPanClient.PathExists(s.ctx, sp.DestPathId)
+	}
+	if !exists {
+		return fmt.Errorf("刮削目标目录 %s => %s 疑似不存在，请检查或编辑重新选择目标目录: %v", sp.DestPathId, sp.DestPath, err)
 	}
 	return nil
 }
@@ -155,6 +215,8 @@ func (s *Scrape) Start() bool {
 	// 启动一个协程定时监控是否需要退出
 	helpers.AppLogger.Infof("开始刮削目录 %s", s.scrapePath.SourcePath)
 	s.scrapePath.SetRunning()
+	// 本次刮削的整理操作都记录到同一个批次，用来撤销
+	s.scrapePath.RunNo = models.NewRenameJournalRunNo(s.scrapePath.ID)
 	defer func() {
 		s.scrapePath.SetNotRunning()
 		select {
@@ -173,23 +235,42 @@ func (s *Scrape) Start() bool {
 		helpers.AppLogger.Errorf("检查来源目录 %s 或者目标目录 %s 是否异常: %v", s.scrapePath.SourcePathId, s.scrapePath.DestPathId, err)
 		return false
 	}
+	if s.scrapePath.IsCrossStorage() {
+		if err := s.checkDestPathExists(); err != nil {
+			helpers.AppLogger.Errorf("检查目标目录 %s 是否异常: %v", s.scrapePath.DestPathId, err)
+			return false
+		}
+	}
+	// 镜像整理先检查已创建的链接，来源文件删除时清理链接，来源文件被替换时重新链接
+	if s.scrapePath.IsMirror() {
+		s.scrapePath.CheckLinks()
+	}
 	// 先生成所有二级分类
 	s.scrapePath.V115Client = s.V115Client
 	s.scrapePath.OpenListClient = s.OpenlistClient
 	s.scrapePath.BaiduPanClient = s.BaiduPanClient
 	s.scrapePath.GenerateCategory()
-	// 获取视频文件列表并从文件名中提取媒体信息用来刮削
-	eerr := s.scanImpl.GetNetFileFiles()
-	if eerr != nil {
-		helpers.AppLogger.Errorf("获取目录 %s 视频文件列表失败: %v", s.scrapePath.SourcePath, eerr)
-		return false
+	if s.SkipScan {
+		// STRM同步时已经将新增的视频文件入库，不需要再扫描一遍来源目录
+		helpers.AppLogger.Infof("刮削目录 %s 的待刮削文件已在STRM同步时入库，跳过扫描", s.scrapePath.SourcePath)
+	} else {
+		// 获取视频文件列表并从文件名中提取媒体信息用来刮削
+		s.scanImpl.SetFullScan(s.FullScan)
+		s.scanImpl.SetScanFolders(s.ScanFolders)
+		eerr := s.scanImpl.GetNetFileFiles()
+		if eerr != nil {
+			helpers.AppLogger.Errorf("获取目录 %s 视频文件列表失败: %v", s.scrapePath.SourcePath, eerr)
+			return false
+		}
+		helpers.AppLogger.Infof("获取目录 %s 视频文件列表成功", s.scrapePath.SourcePath)
 	}
-	helpers.AppLogger.Infof("获取目录 %s 视频文件列表成功", s.scrapePath.SourcePath)
 	err = s.scrapeImpl.Start()
 	if err != nil {
 		helpers.AppLogger.Errorf("启动刮削 %s 失败: %v", s.scrapePath.SourcePath, err)
 		return false
 	}
+	// 视频已经整理过、这次才扫描到的附加内容，跟随已整理的视频移动
+	s.organizePendingExtras()
 	helpers.AppLogger.Infof("刮削整理 #%d %s 成功", s.scrapePath.ID, s.scrapePath.SourcePath)
 	// s.ctxCancel() // 通知其他相关协程都退出
 	return true
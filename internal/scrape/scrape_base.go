@@ -36,6 +36,10 @@ type ScrapeBase struct {
 
 // 下载图片到指定文件
 func (s *ScrapeBase) DownloadImages(parentPath, ua string, fileList map[string]string) {
+	if models.GlobalScrapeSettings.TmdbOffline {
+		helpers.AppLogger.Infof("TMDB离线模式，跳过下载图片到 %s", parentPath)
+		return
+	}
 	for fileName, url := range fileList {
 		filePath := filepath.Join(parentPath, fileName)
 		// helpers.AppLogger.Infof("下载图片 %s 到 %s", url, filePath)
@@ -186,7 +190,7 @@ func (s *ScrapeBase) GetDownloadUrl(mediaFile *models.ScrapeMediaFile) string {
 
 // 将本地临时文件移动到本地目标路径
 func (m *ScrapeBase) MoveLocalTempFileToDest(mediaFile *models.ScrapeMediaFile, files []uploadFile) (bool, error) {
-	if mediaFile.SourceType != models.SourceTypeLocal {
+	if m.scrapePath.GetDestSourceType() != models.SourceTypeLocal {
 		helpers.AppLogger.Warnf("非本地文件刮削，无法移动到目标位置")
 		return true, fmt.Errorf("非本地文件刮削，无法移动到目标位置")
 	}
@@ -48,11 +48,12 @@ mainloop:
 				helpers.AppLogger.Infof("集刮削整理任务队列 %d 处理电视剧 %s 季 %d 集 %d 成功", taskIndex, mediaFile.Name, mediaFile.SeasonNumber, mediaFile.EpisodeNumber)
 			}
 			// 触发单个刮削项完成事件
-			ws.BroadcastEvent(ws.EventScraperItemComplete, map[string]any{
-				"item_id": mediaFile.ID,
-				"name":    mediaFile.VideoFilename,
-				"status":  string(mediaFile.Status),
-				"success": err == nil,
+			ws.BroadcastEvent(ws.EventScraperItemComplete, ws.ScrapeItemData{
+				ItemID:       mediaFile.ID,
+				ScrapePathID: mediaFile.ScrapePathId,
+				Name:         mediaFile.VideoFilename,
+				Status:       string(mediaFile.Status),
+				Success:      err == nil,
 			})
 			wg.Done() // 处理完成后，计数-1
 		}
@@ -106,6 +107,8 @@ func (t *tvShowScrapeImpl) Scrape(mediaFile *models.ScrapeMediaFile) error {
 		episodeImageList := make(map[string]string)
 		episodeImageList[mediaFile.GetEpisodePosterName()] = mediaFile.MediaEpisode.PosterPath
 		t.DownloadImages(episodePath, v115open.DEFAULTUA, episodeImageList)
+		// 下载字幕
+		t.DownloadSubtitles(mediaFile, episodePath)
 		helpers.AppLogger.Infof("电视剧 %s 季 %d 集 %d 生成nfo和下载图片成功，路径：%s", mediaFile.Name, mediaFile.SeasonNumber, mediaFile.EpisodeNumber, episodePath)
 	}
 	mediaFile.ScrapeFinish()
@@ -137,6 +140,12 @@ func (t *tvShowScrapeImpl) Process(mediaFile *models.ScrapeMediaFile) error {
 		}
 		mediaFile.MediaEpisode.Status = models.MediaStatusRenamed
 		mediaFile.MediaEpisode.Save()
+		// 季目录和电视剧目录中的预告片、花絮等附加内容分别移动到新的季目录和电视剧目录的子目录中
+		// 没有季目录时集直接在电视剧目录中，附加内容属于整部剧
+		if mediaFile.Path != mediaFile.TvshowPath {
+			organizeExtras(t.renameImpl, t.scrapePath, mediaFile, mediaFile.Path, mediaFile.GetDestFullSeasonPath(), mediaFile.NewSeasonPathId)
+		}
+		organizeExtras(t.renameImpl, t.scrapePath, mediaFile, mediaFile.TvshowPath, mediaFile.GetDestFullTvshowPath(), mediaFile.NewPathId)
 	}
 	// 上传所有刮削好的元数据
 	if mediaFile.ScrapeType != models.ScrapeTypeOnlyRename {
@@ -219,6 +228,8 @@ func (t *tvShowScrapeImpl) FinishEpisode(mediaFile *models.ScrapeMediaFile) {
 			Image:     mediaFile.Media.PosterPath,
 			Timestamp: time.Now(),
 			Priority:  models.NormalPriority,
+			Category:  mediaFile.CategoryName,
+			Digest:    &models.DigestData{MediaType: "tvshow", MediaName: mediaFile.Name, Episodes: seasonStrArray, Poster: mediaFile.Media.PosterPath},
 		}
 		if notificationmanager.GlobalEnhancedNotificationManager != nil {
 			if err := notificationmanager.GlobalEnhancedNotificationManager.SendNotification(ctx, notif); err != nil {
@@ -356,6 +367,16 @@ func (t *tvShowScrapeImpl) GetEpisodeUploadFiles(mediaFile *models.ScrapeMediaFi
 		SourcePath: filepath.Join(sourcePath, jpgName),
 	}
 	fileList = append(fileList, file)
+	// 下载的字幕
+	for _, subName := range getSubtitleFileNames(sourcePath, mediaFile.NewVideoBaseName) {
+		fileList = append(fileList, uploadFile{
+			ID:         fmt.Sprintf("%d", mediaFile.ID),
+			DestPathId: destPathId,
+			DestPath:   destPath,
+			FileName:   subName,
+			SourcePath: filepath.Join(sourcePath, subName),
+		})
+	}
 	return fileList
 }
 
@@ -102,11 +102,12 @@ mainloop:
 				helpers.AppLogger.Errorf("任务队列 %d 刮削文件 %s 失败: %v", taskIndex, mediaFile.VideoFilename, err)
 			}
 			// 触发单个刮削项完成事件
-			ws.BroadcastEvent(ws.EventScraperItemComplete, map[string]any{
-				"item_id": mediaFile.ID,
-				"name":    mediaFile.VideoFilename,
-				"status":  string(mediaFile.Status),
-				"success": err == nil,
+			ws.BroadcastEvent(ws.EventScraperItemComplete, ws.ScrapeItemData{
+				ItemID:       mediaFile.ID,
+				ScrapePathID: mediaFile.ScrapePathId,
+				Name:         mediaFile.VideoFilename,
+				Status:       string(mediaFile.Status),
+				Success:      err == nil,
 			})
 			continue mainloop
 		case <-time.After(5 * time.Minute):
@@ -122,15 +123,29 @@ func (m *movieScrapeImpl) Process(mediaFile *models.ScrapeMediaFile) error {
 		helpers.AppLogger.Errorf("创建临时目录失败: %v", err)
 		return err
 	}
+	// 刮削时确定新文件名前加锁，整理完成后释放
+	defer unlockMovieVersions(mediaFile)
 	// 先从文件名或文件夹名字中提取影片名字+年份或tmdbid
 	if mediaFile.Status == models.ScrapeMediaStatusScanned {
 		// 待刮削，启动刮削流程
 		err := m.Scrape(mediaFile)
+		if errors.Is(err, errNeedsReview) {
+			return nil
+		}
 		if err != nil {
 			mediaFile.Failed(err.Error())
 			return err
 		}
 	}
+	lockMovieVersions(mediaFile)
+	// 只保留最佳版本时，非最佳版本直接移动到待定目录
+	if held, err := m.HoldWorseVersions(mediaFile); err != nil {
+		mediaFile.RenameFailed(err.Error())
+		return err
+	} else if held {
+		m.FinishMovie(mediaFile)
+		return nil
+	}
 	// 改为整理中
 	mediaFile.Renaming()
 	m.MakeParentPath(mediaFile, m.scrapePath.CategoryMap)
@@ -143,6 +158,9 @@ func (m *movieScrapeImpl) Process(mediaFile *models.ScrapeMediaFile) error {
 		}
 		mediaFile.Media.Status = models.MediaStatusRenamed
 		mediaFile.Media.Save()
+		// 预告片、花絮等附加内容移动到电影目录的子目录中
+		destPath, destPathId := mediaFile.GetMovieOrTvshowDestPath()
+		organizeExtras(m.renameImpl, m.scrapePath, mediaFile, mediaFile.ExtrasPath(), destPath, destPathId)
 	}
 	// 上传所有刮削好的元数据
 	if mediaFile.ScrapeType != models.ScrapeTypeOnlyRename {
@@ -174,10 +192,16 @@ func (m *movieScrapeImpl) Scrape(mediaFile *models.ScrapeMediaFile) error {
 	if err := m.FFprobe(mediaFile); err != nil {
 		helpers.AppLogger.Errorf("提取视频信息失败, 文件名: %s, 错误: %v", mediaFile.VideoFilename, err)
 	}
+	// 识别置信度过低时等待确认，不继续整理
+	if m.CheckConfidence(mediaFile) {
+		return errNeedsReview
+	}
 	// 确定二级分类
 	if cerr := m.GenrateCategory(mediaFile); cerr != nil {
 		return cerr
 	}
+	// 同一电影的多个版本串行确定文件名和整理
+	lockMovieVersions(mediaFile)
 	m.GenerateNewName(mediaFile)
 	if mediaFile.ScrapeType != models.ScrapeTypeOnlyRename {
 		// 下载图片，生成nfo文件
@@ -193,26 +217,33 @@ func (m *movieScrapeImpl) Scrape(mediaFile *models.ScrapeMediaFile) error {
 		nfoName := m.GetMovieRealName(mediaFile, "", "nfo")
 		// 生成nfo
 		m.GenerateMovieNfo(mediaFile, localTempPath, nfoName, m.scrapePath.ExcludeNoImageActor)
-		fileList := map[string]string{}
-		posterExt := filepath.Ext(mediaFile.Media.PosterPath)
-		fileList[m.GetMovieRealName(mediaFile, fmt.Sprintf("poster%s", posterExt), "image")] = mediaFile.Media.PosterPath
-		logoExt := filepath.Ext(mediaFile.Media.LogoPath)
-		fileList[m.GetMovieRealName(mediaFile, fmt.Sprintf("clearlogo%s", logoExt), "image")] = mediaFile.Media.LogoPath
-		fanartExt := filepath.Ext(mediaFile.Media.BackdropPath)
-		fileList[m.GetMovieRealName(mediaFile, fmt.Sprintf("fanart%s", fanartExt), "image")] = mediaFile.Media.BackdropPath
-		m.DownloadImages(localTempPath, v115open.DEFAULTUA, fileList)
-		// 从fanart.tv查询图片并下载
-		if m.scrapePath.EnableFanartTv {
-			fileList = m.DownloadMovieImagesFromFanart(mediaFile)
-			if fileList != nil {
-				m.DownloadImages(localTempPath, v115open.DEFAULTUA, fileList)
-			}
-		}
+		m.DownloadMovieImages(mediaFile, localTempPath)
+		// 下载字幕
+		m.DownloadSubtitles(mediaFile, localTempPath)
 	}
 	mediaFile.ScrapeFinish()
 	return nil
 }
 
+// 下载电影的海报、logo、背景图，开启了fanart.tv时同时下载fanart.tv的图片
+func (m *movieScrapeImpl) DownloadMovieImages(mediaFile *models.ScrapeMediaFile, localTempPath string) {
+	fileList := map[string]string{}
+	posterExt := filepath.Ext(mediaFile.Media.PosterPath)
+	fileList[m.GetMovieRealName(mediaFile, fmt.Sprintf("poster%s", posterExt), "image")] = mediaFile.Media.PosterPath
+	logoExt := filepath.Ext(mediaFile.Media.LogoPath)
+	fileList[m.GetMovieRealName(mediaFile, fmt.Sprintf("clearlogo%s", logoExt), "image")] = mediaFile.Media.LogoPath
+	fanartExt := filepath.Ext(mediaFile.Media.BackdropPath)
+	fileList[m.GetMovieRealName(mediaFile, fmt.Sprintf("fanart%s", fanartExt), "image")] = mediaFile.Media.BackdropPath
+	m.DownloadImages(localTempPath, v115open.DEFAULTUA, fileList)
+	// 从fanart.tv查询图片并下载
+	if m.scrapePath.EnableFanartTv && !models.GlobalScrapeSettings.TmdbOffline {
+		fileList = m.DownloadMovieImagesFromFanart(mediaFile)
+		if fileList != nil {
+			m.DownloadImages(localTempPath, v115open.DEFAULTUA, fileList)
+		}
+	}
+}
+
 // 从tmdb刮削元数据和图片信息（不下载，不创建目录）
 func (m *movieScrapeImpl) ScrapeMovieMedia(mediaFile *models.ScrapeMediaFile) error {
 	// 如果是其他类型，需要读取nfo文件
@@ -314,6 +345,8 @@ func (m *movieScrapeImpl) GenerateNewName(mediaFile *models.ScrapeMediaFile) {
 	} else {
 		mediaFile.NewVideoBaseName = mediaFile.GenerateNameByTemplate(m.scrapePath.FileNameTemplate) // 不含扩展名
 	}
+	// 同一电影存在多个版本时，按多版本规则命名
+	m.ApplyMultiVersionName(mediaFile)
 	mediaFile.Media.Path = filepath.Join(mediaFile.DestPath, mediaFile.CategoryName, mediaFile.NewPathName)
 	mediaFile.Media.VideoFileName = mediaFile.NewVideoBaseName + mediaFile.VideoExt
 	// 保存
@@ -455,7 +488,7 @@ func (m *movieScrapeImpl) GetMovieUploadFiles(mediaFile *models.ScrapeMediaFile)
 
 // 将本地临时文件移动到本地目标路径
 func (m *movieScrapeImpl) MoveLocalTempFileToDest(mediaFile *models.ScrapeMediaFile, files []uploadFile) (bool, error) {
-	if mediaFile.SourceType != models.SourceTypeLocal {
+	if m.scrapePath.GetDestSourceType() != models.SourceTypeLocal {
 		return true, fmt.Errorf("非本地文件刮削，无法移动到目标位置")
 	}
 	for _, file := range files {
@@ -507,7 +540,7 @@ func (m *movieScrapeImpl) MakeParentPath(mediaFile *models.ScrapeMediaFile, cate
 // 删除来源路径
 func (m *movieScrapeImpl) FinishMovie(mediaFile *models.ScrapeMediaFile) {
 	mediaFile.StatusFinish()
-	if mediaFile.SourceType == models.SourceTypeLocal {
+	if m.scrapePath.GetDestSourceType() == models.SourceTypeLocal {
 		mediaFile.RemoveTmpFiles(nil)
 	}
 	// 发送通知
@@ -520,6 +553,8 @@ func (m *movieScrapeImpl) FinishMovie(mediaFile *models.ScrapeMediaFile) {
 			Image:     mediaFile.Media.PosterPath,
 			Timestamp: time.Now(),
 			Priority:  models.NormalPriority,
+			Category:  mediaFile.CategoryName,
+			Digest:    &models.DigestData{MediaType: "movie", MediaName: mediaFile.Name, Poster: mediaFile.Media.PosterPath},
 		}
 		if notificationmanager.GlobalEnhancedNotificationManager != nil {
 			if err := notificationmanager.GlobalEnhancedNotificationManager.SendNotification(ctx, notif); err != nil {
@@ -762,6 +797,9 @@ func (m *movieScrapeImpl) MakeMediaFromTMDB(mediaFile *models.ScrapeMediaFile, t
 	mediaFile.Save()
 }
 
+// 电影目录中可能上传的图片
+var movieImageNames = []string{"poster.jpg", "clearlogo.jpg", "clearart.jpg", "square.jpg", "logo.jpg", "fanart.jpg", "backdrop.jpg", "background.jpg", "4kbackground.jpg", "thumb.jpg", "banner.jpg", "disc.jpg"}
+
 func (m *movieScrapeImpl) GetMovieRealName(sm *models.ScrapeMediaFile, name string, filetype string) string {
 	if filetype == "nfo" {
 		return fmt.Sprintf("%s.nfo", sm.NewVideoBaseName)
@@ -777,7 +815,7 @@ func (m *movieScrapeImpl) GetMovieRealName(sm *models.ScrapeMediaFile, name stri
 // 刮削和整理的重新刮削逻辑：
 //   - 移动：将文件移动回源目录，如果源目录已删除，则新建同名目录并修改path、pathid等
 //   - 复制：检查源目录和源视频文件是否依然存在，如果存在则删除目录目录，如果不存在则将目标文件移动回源目录（源目录不存在则新建），并修改videofileid, videofilename, videopickcode,pathid, pathname等值
-//   - 软链接、硬链接：同复制
+//   - 软链接、硬链接：来源目录没有改动，直接删除目标目录
 //
 // 其他类型不支持重新刮削
 func (m *movieScrapeImpl) Rollback(mediaFile *models.ScrapeMediaFile) error {
@@ -792,8 +830,7 @@ func (m *movieScrapeImpl) Rollback(mediaFile *models.ScrapeMediaFile) error {
 		destPath := mediaFile.GetDestFullMoviePath()
 		nfoName := m.GetMovieRealName(mediaFile, "", "nfo")
 		files = append(files, models.WillDeleteFile{FullFilePath: filepath.Join(destPath, nfoName)})
-		imageList := []string{"poster.jpg", "clearlogo.jpg", "clearart.jpg", "square.jpg", "logo.jpg", "fanart.jpg", "backdrop.jpg", "background.jpg", "4kbackground.jpg", "thumb.jpg", "banner.jpg", "disc.jpg"}
-		for _, im := range imageList {
+		for _, im := range movieImageNames {
 			imageName := m.GetMovieRealName(mediaFile, im, "image")
 			files = append(files, models.WillDeleteFile{FullFilePath: filepath.Join(destPath, imageName)})
 		}
@@ -807,16 +844,24 @@ func (m *movieScrapeImpl) Rollback(mediaFile *models.ScrapeMediaFile) error {
 		// 字幕改名
 		if mediaFile.Media.SubtitleFiles != nil {
 			for _, sub := range mediaFile.Media.SubtitleFiles {
-				m.renameImpl.Rename(sub.FileId, newBaseName+filepath.Ext(sub.FileName))
+				m.renameImpl.RenameAt(models.FileSource{Path: mediaFile.Path, PathId: mediaFile.PathId, Name: sub.FileName}, sub.FileId, newBaseName+filepath.Ext(sub.FileName))
 			}
 		}
 		// 视频文件改名
-		m.renameImpl.Rename(mediaFile.Media.VideoFileId, newBaseName+mediaFile.VideoExt)
+		m.renameImpl.RenameAt(models.FileSource{Path: mediaFile.Path, PathId: mediaFile.PathId, Name: mediaFile.Media.VideoFileName}, mediaFile.Media.VideoFileId, newBaseName+mediaFile.VideoExt)
 		// 文件夹改名
 		m.renameImpl.Rename(mediaFile.PathId, newBaseName)
 	}
-	// 如果是移动则使用现在的处理方式
-	if mediaFile.ScrapeType == models.ScrapeTypeScrapeAndRename || mediaFile.ScrapeType == models.ScrapeTypeOnlyRename {
+	isRenamed := mediaFile.ScrapeType == models.ScrapeTypeScrapeAndRename || mediaFile.ScrapeType == models.ScrapeTypeOnlyRename
+	if isRenamed && mediaFile.RenameType.IsLink() {
+		// 链接整理没有改动来源目录，删除整理目录中的链接和元数据即可
+		if derr := m.renameImpl.DeleteDir(mediaFile.Media.Path, mediaFile.Media.PathId); derr != nil {
+			helpers.AppLogger.Errorf("删除目标目录失败: %v", derr)
+			return derr
+		}
+		models.DeleteScrapeLinksByMediaFile(mediaFile.ID)
+	} else if isRenamed {
+		// 如果是移动则使用现在的处理方式
 		// 检查目录是否存在，如果存在则改名字，如果不存在则创建
 		parentPath := filepath.Dir(mediaFile.Path)
 		var newPath string
@@ -874,13 +919,14 @@ func (m *movieScrapeImpl) Rollback(mediaFile *models.ScrapeMediaFile) error {
 					FileId:       sub.FileId,
 					FileFullPath: filepath.Join(newPath, newBaseName, filepath.Ext(sub.FileName)),
 					PathId:       pathId,
+					Source:       &models.FileSource{Path: mediaFile.Media.Path, PathId: mediaFile.Media.PathId, Name: sub.FileName},
 				}
 				merr := m.renameImpl.MoveFiles(moveFile)
 				if merr != nil {
 					continue
 				}
 				// 改名
-				m.renameImpl.Rename(moveFile.FileId, newBaseName+filepath.Ext(sub.FileName))
+				m.renameImpl.RenameAt(models.FileSource{Path: newPath, PathId: pathId, Name: sub.FileName}, moveFile.FileId, newBaseName+filepath.Ext(sub.FileName))
 			}
 		}
 		exists := false
@@ -900,6 +946,7 @@ func (m *movieScrapeImpl) Rollback(mediaFile *models.ScrapeMediaFile) error {
 			moveFile := models.MoveNewFileToSourceFile{
 				FileId: mediaFile.Media.VideoFileId,
 				PathId: pathId,
+				Source: &models.FileSource{Path: mediaFile.Media.Path, PathId: mediaFile.Media.PathId, Name: mediaFile.Media.VideoFileName},
 			}
 			merr := m.renameImpl.MoveFiles(moveFile)
 			if merr != nil {
@@ -910,7 +957,7 @@ func (m *movieScrapeImpl) Rollback(mediaFile *models.ScrapeMediaFile) error {
 				moveFile.FileId = strings.Replace(moveFile.FileId, mediaFile.Media.PathId, pathId, 1)
 			}
 			// 改名
-			m.renameImpl.Rename(moveFile.FileId, newBaseName+mediaFile.VideoExt)
+			m.renameImpl.RenameAt(models.FileSource{Path: newPath, PathId: pathId, Name: mediaFile.Media.VideoFileName}, moveFile.FileId, newBaseName+mediaFile.VideoExt)
 		}
 		// 删除目标目录
 		derr := m.renameImpl.DeleteDir(mediaFile.Media.Path, mediaFile.Media.PathId)
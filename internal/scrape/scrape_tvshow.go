@@ -293,15 +293,20 @@ func (t *tvShowScrapeImpl) ScrapeTvshow(mediaFile *models.ScrapeMediaFile) error
 		}
 		// 生成nfo
 		t.GenerateTvShowNfo(mediaFile, localTempPath, t.scrapePath.ExcludeNoImageActor)
-		fileList := map[string]string{}
-		fileList[t.GetTvshowRealName(mediaFile, "poster.jpg", "image")] = mediaFile.Media.PosterPath
-		fileList[t.GetTvshowRealName(mediaFile, "clearlogo.jpg", "image")] = mediaFile.Media.LogoPath
-		fileList[t.GetTvshowRealName(mediaFile, "fanart.jpg", "image")] = mediaFile.Media.BackdropPath
-		t.DownloadImages(localTempPath, v115open.DEFAULTUA, fileList)
+		t.DownloadTvshowImages(mediaFile, localTempPath)
 	}
 	return nil
 }
 
+// 下载电视剧的海报、logo、背景图
+func (t *tvShowScrapeImpl) DownloadTvshowImages(mediaFile *models.ScrapeMediaFile, localTempPath string) {
+	fileList := map[string]string{}
+	fileList[t.GetTvshowRealName(mediaFile, "poster.jpg", "image")] = mediaFile.Media.PosterPath
+	fileList[t.GetTvshowRealName(mediaFile, "clearlogo.jpg", "image")] = mediaFile.Media.LogoPath
+	fileList[t.GetTvshowRealName(mediaFile, "fanart.jpg", "image")] = mediaFile.Media.BackdropPath
+	t.DownloadImages(localTempPath, v115open.DEFAULTUA, fileList)
+}
+
 func (t *tvShowScrapeImpl) ScrapeTvshowMedia(mediaFile *models.ScrapeMediaFile) error {
 	helpers.AppLogger.Infof("刮削电视剧, 名字=%s，年份=%d, tmdbid=%d", mediaFile.Name, mediaFile.Year, mediaFile.TmdbId)
 	tmdbInfo := &models.TmdbInfo{}
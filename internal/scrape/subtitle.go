@@ -0,0 +1,109 @@
+package scrape
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/subtitle"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// 内封字幕流的语言标签，ffprobe返回的通常是ISO 639-2代码
+var embeddedSubtitleLanguages = map[string][]string{
+	"zh": {"zh", "chi", "zho", "chs", "cht", "chinese"},
+	"en": {"en", "eng", "english"},
+	"ja": {"ja", "jpn", "japanese"},
+	"ko": {"ko", "kor", "korean"},
+}
+
+// 下载字幕到临时目录，和nfo、图片一起上传
+// 已有外挂字幕，或者内封字幕包含首选语言时跳过，TMDB离线模式下也跳过
+func (s *ScrapeBase) DownloadSubtitles(mediaFile *models.ScrapeMediaFile, localTempPath string) {
+	if !s.scrapePath.EnableSubtitle || models.GlobalScrapeSettings.TmdbOffline || mediaFile.MediaType == models.MediaTypeOther || mediaFile.Media == nil {
+		return
+	}
+	if len(mediaFile.SubtitleFiles) > 0 {
+		helpers.AppLogger.Infof("视频 %s 已有外挂字幕，跳过下载字幕", mediaFile.VideoFilename)
+		return
+	}
+	languages := models.GlobalScrapeSettings.GetSubtitleLanguages()
+	if hasEmbeddedSubtitle(mediaFile, languages[0]) {
+		helpers.AppLogger.Infof("视频 %s 已有 %s 内封字幕，跳过下载字幕", mediaFile.VideoFilename, languages[0])
+		return
+	}
+	providers := models.GlobalScrapeSettings.GetSubtitleProviders()
+	if len(providers) == 0 {
+		helpers.AppLogger.Warnf("没有配置任何字幕提供者，跳过下载字幕")
+		return
+	}
+	q := &subtitle.Query{
+		TmdbId:    mediaFile.TmdbId,
+		ImdbId:    mediaFile.Media.ImdbId,
+		Title:     mediaFile.Media.Name,
+		Year:      mediaFile.Year,
+		FileName:  mediaFile.VideoFilename,
+		Languages: languages,
+	}
+	if mediaFile.MediaType == models.MediaTypeTvShow {
+		q.Season = mediaFile.SeasonNumber
+		q.Episode = mediaFile.EpisodeNumber
+	}
+	if mediaFile.SourceType == models.SourceTypeLocal {
+		// 只有本地文件才计算哈希，网盘文件需要下载头尾数据，代价太大
+		hash, size, err := subtitle.ComputeHash(mediaFile.VideoPickCode)
+		if err != nil {
+			helpers.AppLogger.Warnf("计算视频 %s 的哈希失败: %v", mediaFile.VideoFilename, err)
+		}
+		q.FileHash = hash
+		q.FileSize = size
+	}
+	sub, content, err := subtitle.Fetch(s.ctx, providers, q)
+	if err != nil {
+		helpers.AppLogger.Errorf("下载视频 %s 的字幕失败: %v", mediaFile.VideoFilename, err)
+		return
+	}
+	if sub == nil {
+		helpers.AppLogger.Infof("没有找到视频 %s 的字幕，语言偏好: %s", mediaFile.VideoFilename, strings.Join(languages, ","))
+		return
+	}
+	subFile := filepath.Join(localTempPath, subtitle.MakeFileName(mediaFile.NewVideoBaseName, sub))
+	if err := os.WriteFile(subFile, content, 0777); err != nil {
+		helpers.AppLogger.Errorf("保存字幕文件 %s 失败: %v", subFile, err)
+		return
+	}
+	helpers.AppLogger.Infof("从 %s 下载字幕成功: %s", sub.Provider, subFile)
+}
+
+// 返回临时目录中属于该视频的字幕文件名，例如：xxx.zh-CN.srt
+func getSubtitleFileNames(localTempPath string, videoBaseName string) []string {
+	files, err := os.ReadDir(localTempPath)
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0)
+	for _, file := range files {
+		if file.IsDir() || !strings.HasPrefix(file.Name(), videoBaseName+".") {
+			continue
+		}
+		if subtitle.FormatFromName(file.Name()) != "" {
+			names = append(names, file.Name())
+		}
+	}
+	return names
+}
+
+func hasEmbeddedSubtitle(mediaFile *models.ScrapeMediaFile, language string) bool {
+	primary := strings.ToLower(strings.Split(language, "-")[0])
+	tags, ok := embeddedSubtitleLanguages[primary]
+	if !ok {
+		tags = []string{primary}
+	}
+	for _, sub := range mediaFile.SubtitleCodec {
+		if slices.Contains(tags, strings.ToLower(sub.Language)) {
+			return true
+		}
+	}
+	return false
+}
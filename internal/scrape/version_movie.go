@@ -0,0 +1,240 @@
+package scrape
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// 同一电影的多个版本串行分组和整理，key为刮削目录ID和TMDB ID
+// 并行处理时各自查询到的其他版本不完整，会出现都不带版本标签或者标签重复
+var movieVersionLocks sync.Map
+
+// 正在持有版本锁的刮削记录，key为刮削记录ID
+var heldMovieVersionLocks sync.Map
+
+// 从确定新文件名开始，到整理完成为止持有版本锁，已经持有时直接返回
+func lockMovieVersions(mediaFile *models.ScrapeMediaFile) {
+	if mediaFile.MediaType != models.MediaTypeMovie || mediaFile.ScrapeType == models.ScrapeTypeOnly || mediaFile.TmdbId == 0 {
+		return
+	}
+	if _, ok := heldMovieVersionLocks.Load(mediaFile.ID); ok {
+		return
+	}
+	key := fmt.Sprintf("%d:%d", mediaFile.ScrapePathId, mediaFile.TmdbId)
+	lock, _ := movieVersionLocks.LoadOrStore(key, &sync.Mutex{})
+	mu := lock.(*sync.Mutex)
+	mu.Lock()
+	heldMovieVersionLocks.Store(mediaFile.ID, mu)
+}
+
+// 释放版本锁，没有持有时不处理
+func unlockMovieVersions(mediaFile *models.ScrapeMediaFile) {
+	if lock, ok := heldMovieVersionLocks.LoadAndDelete(mediaFile.ID); ok {
+		lock.(*sync.Mutex).Unlock()
+	}
+}
+
+// 同一电影存在多个版本时，按Emby/Jellyfin多版本规则命名
+// 所有版本放在同一个文件夹内，文件名为：文件夹名 - 版本标签，例如：星际穿越 (2014) - 2160p.mkv
+// 第一个版本整理时还没有其他版本，不带标签，出现第二个版本时给第一个版本补上标签
+func (m *movieScrapeImpl) ApplyMultiVersionName(mediaFile *models.ScrapeMediaFile) {
+	if mediaFile.MediaType != models.MediaTypeMovie || mediaFile.ScrapeType == models.ScrapeTypeOnly {
+		return
+	}
+	others := models.GetSameTmdbMovieFiles(mediaFile)
+	if len(others) == 0 {
+		return
+	}
+	// 沿用其他版本已生成的文件夹名，保证所有版本在同一个文件夹内
+	for _, other := range others {
+		if other.NewPathName != "" && other.CategoryName == mediaFile.CategoryName {
+			mediaFile.NewPathName = other.NewPathName
+			break
+		}
+	}
+	for _, other := range others {
+		if other.VersionLabel != "" || other.IsVersionHeld || other.Status != models.ScrapeMediaStatusRenamed || other.NewPathName != mediaFile.NewPathName || other.CategoryName != mediaFile.CategoryName {
+			continue
+		}
+		if err := m.labelRenamedVersion(other, others); err != nil {
+			helpers.AppLogger.Errorf("给已整理的版本 %s 补充版本标签失败: %v", other.VideoFilename, err)
+		}
+	}
+	mediaFile.VersionLabel = mediaFile.MakeVersionLabel(others)
+	mediaFile.NewVideoBaseName = fmt.Sprintf("%s - %s", mediaFile.NewPathName, mediaFile.VersionLabel)
+	helpers.AppLogger.Infof("电影 %s 存在 %d 个其他版本，按多版本命名: %s", mediaFile.Name, len(others), mediaFile.NewVideoBaseName)
+}
+
+// 给已整理完成、没有版本标签的版本补上标签，视频、nfo和字幕一起改名
+// 图片是文件夹共用的，不需要改名
+func (m *movieScrapeImpl) labelRenamedVersion(other *models.ScrapeMediaFile, others []*models.ScrapeMediaFile) error {
+	if other.Media == nil || other.Media.VideoFileId == "" {
+		return fmt.Errorf("没有找到已整理的视频文件")
+	}
+	label := other.MakeVersionLabel(others)
+	oldBaseName := other.NewVideoBaseName
+	newBaseName := fmt.Sprintf("%s - %s", other.NewPathName, label)
+	path, pathId := other.Media.Path, other.Media.PathId
+	videoName := newBaseName + other.VideoExt
+	if err := m.renameImpl.RenameFile(path, pathId, other.Media.VideoFileName, videoName); err != nil {
+		return err
+	}
+	if err := m.renameImpl.RenameFile(path, pathId, oldBaseName+".nfo", newBaseName+".nfo"); err != nil {
+		helpers.AppLogger.Errorf("重命名nfo文件 %s 失败: %v", oldBaseName+".nfo", err)
+	}
+	for _, sub := range other.Media.SubtitleFiles {
+		subName := strings.Replace(sub.FileName, oldBaseName, newBaseName, 1)
+		if err := m.renameImpl.RenameFile(path, pathId, sub.FileName, subName); err != nil {
+			helpers.AppLogger.Errorf("重命名字幕文件 %s 失败: %v", sub.FileName, err)
+			continue
+		}
+		sub.FileId = m.movedFileId(sub.FileId, pathId, subName)
+		sub.FileName = subName
+	}
+	other.Media.VideoFileName = videoName
+	other.Media.VideoFileId = m.movedFileId(other.Media.VideoFileId, pathId, videoName)
+	if m.scrapePath.GetDestSourceType() == models.SourceTypeLocal {
+		other.Media.VideoPickCode = other.Media.VideoFileId
+	}
+	other.Media.Save()
+	other.VersionLabel = label
+	other.NewVideoBaseName = newBaseName
+	other.Save()
+	helpers.AppLogger.Infof("已整理的版本 %s 补充版本标签: %s", oldBaseName, newBaseName)
+	return nil
+}
+
+// 文件移动或改名后的文件ID，115的文件ID不变，其他类型的文件ID是路径
+func (m *movieScrapeImpl) movedFileId(fileId, pathId, fileName string) string {
+	switch m.scrapePath.GetDestSourceType() {
+	case models.SourceType115:
+		return fileId
+	case models.SourceTypeLocal:
+		return filepath.Join(pathId, fileName)
+	}
+	return filepath.ToSlash(filepath.Join(pathId, fileName))
+}
+
+// 只保留最佳版本
+// 当前文件不是最佳版本时，移动到待定目录并返回true
+// 当前文件是最佳版本时，将已整理到影片目录的其他版本移动到待定目录，返回false继续正常整理
+func (m *movieScrapeImpl) HoldWorseVersions(mediaFile *models.ScrapeMediaFile) (bool, error) {
+	if m.scrapePath.MultiVersionPolicy != models.MultiVersionPolicyKeepBest || mediaFile.MediaType != models.MediaTypeMovie || mediaFile.ScrapeType == models.ScrapeTypeOnly {
+		return false, nil
+	}
+	others := models.GetSameTmdbMovieFiles(mediaFile)
+	if len(others) == 0 {
+		return false, nil
+	}
+	for _, other := range others {
+		if models.CompareMovieVersion(other, mediaFile) > 0 {
+			helpers.AppLogger.Infof("电影 %s 存在更好的版本 %s，当前版本 %s 移动到待定目录", mediaFile.Name, other.VideoFilename, mediaFile.VideoFilename)
+			return true, m.holdCurrentVersion(mediaFile)
+		}
+	}
+	for _, other := range others {
+		if other.Status != models.ScrapeMediaStatusRenamed || other.IsVersionHeld {
+			continue
+		}
+		if err := m.holdRenamedVersion(mediaFile, other); err != nil {
+			helpers.AppLogger.Errorf("将较差版本 %s 移动到待定目录失败: %v", other.VideoFilename, err)
+		}
+	}
+	return false, nil
+}
+
+// 返回多版本待定目录的完整路径，目录结构和影片目录保持一致
+func (m *movieScrapeImpl) getHoldFullPath(mediaFile *models.ScrapeMediaFile) string {
+	return filepath.Join(mediaFile.DestPath, m.scrapePath.GetMultiVersionHoldPath(), mediaFile.NewPathName)
+}
+
+// 将当前版本整理到待定目录，不上传元数据
+func (m *movieScrapeImpl) holdCurrentVersion(mediaFile *models.ScrapeMediaFile) error {
+	mediaFile.Renaming()
+	holdFullPath := m.getHoldFullPath(mediaFile)
+	holdPathId, err := m.renameImpl.CheckAndMkDir(holdFullPath, mediaFile.DestPath, mediaFile.DestPathId)
+	if err != nil {
+		return err
+	}
+	mediaFile.NewPathId = holdPathId
+	if err := m.renameImpl.RenameAndMove(mediaFile, holdFullPath, holdPathId, ""); err != nil {
+		return err
+	}
+	mediaFile.IsVersionHeld = true
+	mediaFile.Save()
+	mediaFile.Media.Path = holdFullPath
+	mediaFile.Media.PathId = holdPathId
+	mediaFile.Media.VideoFileName = mediaFile.NewVideoBaseName + mediaFile.VideoExt
+	mediaFile.Media.Status = models.MediaStatusRenamed
+	mediaFile.Media.Save()
+	return nil
+}
+
+// 将已整理完成的版本从影片目录移动到待定目录，nfo、字幕和图片一起移动
+// 图片是文件夹共用的，当前版本随后会重新上传，仅整理时不上传图片，留在影片目录
+func (m *movieScrapeImpl) holdRenamedVersion(mediaFile, other *models.ScrapeMediaFile) error {
+	if other.Media == nil || other.Media.VideoFileId == "" {
+		return fmt.Errorf("没有找到已整理的视频文件")
+	}
+	holdFullPath := m.getHoldFullPath(other)
+	holdPathId, err := m.renameImpl.CheckAndMkDir(holdFullPath, other.DestPath, other.DestPathId)
+	if err != nil {
+		return err
+	}
+	path, pathId := other.Media.Path, other.Media.PathId
+	fileName := other.Media.VideoFileName
+	if err := m.renameImpl.MoveFiles(models.MoveNewFileToSourceFile{
+		FileId:       other.Media.VideoFileId,
+		PathId:       holdPathId,
+		FileFullPath: filepath.Join(holdFullPath, fileName),
+		Source:       &models.FileSource{Path: path, PathId: pathId, Name: fileName},
+	}); err != nil {
+		return err
+	}
+	sidecars := []string{other.NewVideoBaseName + ".nfo"}
+	if mediaFile.ScrapeType != models.ScrapeTypeOnlyRename {
+		sidecars = append(sidecars, movieImageNames...)
+	}
+	for _, name := range sidecars {
+		m.holdSidecar(path, pathId, name, holdFullPath, holdPathId)
+	}
+	for _, sub := range other.Media.SubtitleFiles {
+		if m.holdSidecar(path, pathId, sub.FileName, holdFullPath, holdPathId) {
+			sub.FileId = m.movedFileId(sub.FileId, holdPathId, sub.FileName)
+		}
+	}
+	other.Media.VideoFileId = m.movedFileId(other.Media.VideoFileId, holdPathId, fileName)
+	if m.scrapePath.GetDestSourceType() == models.SourceTypeLocal {
+		other.Media.VideoPickCode = other.Media.VideoFileId
+	}
+	other.Media.Path = holdFullPath
+	other.Media.PathId = holdPathId
+	other.Media.Save()
+	other.NewPathId = holdPathId
+	other.IsVersionHeld = true
+	other.Save()
+	helpers.AppLogger.Infof("较差版本 %s 已移动到待定目录 %s", fileName, holdFullPath)
+	return nil
+}
+
+// 把影片目录中的一个附属文件移动到待定目录，文件不存在或移动失败返回false
+func (m *movieScrapeImpl) holdSidecar(path, pathId, name, holdFullPath, holdPathId string) bool {
+	fileId, err := m.renameImpl.FindFile(path, pathId, name)
+	if err != nil || fileId == "" {
+		return false
+	}
+	if err := m.renameImpl.MoveFiles(models.MoveNewFileToSourceFile{
+		FileId:       fileId,
+		PathId:       holdPathId,
+		FileFullPath: filepath.Join(holdFullPath, name),
+		Source:       &models.FileSource{Path: path, PathId: pathId, Name: name},
+	}); err != nil {
+		helpers.AppLogger.Errorf("移动文件 %s 到待定目录失败: %v", name, err)
+		return false
+	}
+	return true
+}
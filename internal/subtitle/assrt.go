@@ -0,0 +1,163 @@
+package subtitle
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"resty.dev/v3"
+)
+
+const (
+	ASSRT_API_URL = "https://api.assrt.net/v1/"
+)
+
+// 射手网(伪) API，按关键词搜索，兼容该接口的服务都可以使用
+type Assrt struct {
+	token       string
+	restyClient *resty.Client
+}
+
+type assrtSub struct {
+	Id         int64  `json:"id"`
+	NativeName string `json:"native_name"`
+	VideoName  string `json:"videoname"`
+	SubType    string `json:"subtype"`
+	DownCount  int64  `json:"down_count"`
+	FileName   string `json:"filename"`
+	Url        string `json:"url"`
+	Lang       struct {
+		Desc     string          `json:"desc"`
+		LangList map[string]bool `json:"langlist"`
+	} `json:"lang"`
+	FileList []struct {
+		Url  string `json:"url"`
+		Name string `json:"f"`
+		Size string `json:"s"`
+	} `json:"filelist"`
+}
+
+type assrtResponse struct {
+	Status int `json:"status"`
+	Sub    struct {
+		Subs []assrtSub `json:"subs"`
+	} `json:"sub"`
+	ErrMsg string `json:"errmsg"`
+}
+
+func NewAssrt(token, baseUrl, proxyUrl string) *Assrt {
+	if baseUrl == "" {
+		baseUrl = ASSRT_API_URL
+	}
+	client := resty.New()
+	client.SetTimeout(30 * time.Second)
+	client.SetBaseURL(baseUrl)
+	client.SetHeader("User-Agent", "q115-strm-go/1.0")
+	if proxyUrl != "" {
+		client.SetProxy(proxyUrl)
+	}
+	return &Assrt{token: token, restyClient: client}
+}
+
+func (a *Assrt) Name() string {
+	return "assrt"
+}
+
+func (a *Assrt) request(ctx context.Context, url string, params map[string]string) (*assrtResponse, error) {
+	resp, err := a.restyClient.R().SetContext(ctx).
+		SetQueryParam("token", a.token).
+		SetQueryParams(params).
+		SetResult(&assrtResponse{}).
+		SetForceResponseContentType("application/json"). // 部分兼容服务不返回json的Content-Type
+		Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode() >= 400 {
+		return nil, fmt.Errorf("HTTP error %d: %s", resp.StatusCode(), resp.String())
+	}
+	result := resp.Result().(*assrtResponse)
+	if result.Status != 0 {
+		return nil, fmt.Errorf("接口返回错误 %d: %s", result.Status, result.ErrMsg)
+	}
+	return result, nil
+}
+
+func (a *Assrt) Search(ctx context.Context, q *Query) ([]*Subtitle, error) {
+	keyword := q.Title
+	if q.Season > 0 {
+		keyword = fmt.Sprintf("%s S%02dE%02d", q.Title, q.Season, q.Episode)
+	} else if q.Year > 0 {
+		keyword = fmt.Sprintf("%s %d", q.Title, q.Year)
+	}
+	if keyword == "" {
+		return nil, nil
+	}
+	result, err := a.request(ctx, "sub/search", map[string]string{"q": keyword, "cnt": "15"})
+	if err != nil {
+		return nil, err
+	}
+	subs := make([]*Subtitle, 0)
+	for _, item := range result.Sub.Subs {
+		format := strings.ToLower(item.SubType)
+		for _, lang := range assrtLanguages(item.Lang.LangList) {
+			subs = append(subs, &Subtitle{
+				Provider:  a.Name(),
+				Id:        fmt.Sprintf("%d", item.Id),
+				Language:  lang,
+				Format:    format,
+				Name:      item.NativeName,
+				Downloads: item.DownCount,
+			})
+		}
+	}
+	return subs, nil
+}
+
+// 查询详情拿到压缩包内的文件列表，选择和格式匹配的文件下载
+func (a *Assrt) Download(ctx context.Context, sub *Subtitle) ([]byte, error) {
+	result, err := a.request(ctx, "sub/detail", map[string]string{"id": sub.Id})
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Sub.Subs) == 0 {
+		return nil, fmt.Errorf("字幕 %s 不存在", sub.Id)
+	}
+	detail := result.Sub.Subs[0]
+	for _, file := range detail.FileList {
+		if FormatFromName(file.Name) == sub.Format {
+			sub.DownloadUrl = file.Url
+			break
+		}
+	}
+	if sub.DownloadUrl == "" && FormatFromName(detail.FileName) == sub.Format {
+		sub.DownloadUrl = detail.Url
+	}
+	if sub.DownloadUrl == "" {
+		return nil, fmt.Errorf("字幕 %s 没有可以直接使用的%s文件", sub.Id, sub.Format)
+	}
+	resp, err := a.restyClient.R().SetContext(ctx).Get(sub.DownloadUrl)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode() >= 400 {
+		return nil, fmt.Errorf("HTTP error %d", resp.StatusCode())
+	}
+	return resp.Bytes(), nil
+}
+
+// 射手网的语言列表：langchs 简体，langcht 繁体，langeng 英文，langdou 双语（按简体处理）
+func assrtLanguages(langList map[string]bool) []string {
+	langs := make([]string, 0)
+	if langList["langchs"] || langList["langdou"] {
+		langs = append(langs, "zh-CN")
+	}
+	if langList["langcht"] {
+		langs = append(langs, "zh-TW")
+	}
+	if langList["langeng"] {
+		langs = append(langs, "en")
+	}
+	return langs
+}
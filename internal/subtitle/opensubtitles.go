@@ -0,0 +1,161 @@
+package subtitle
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"resty.dev/v3"
+)
+
+const (
+	OPENSUBTITLES_API_URL = "https://api.opensubtitles.com/api/v1/"
+)
+
+// OpenSubtitles REST API
+type OpenSubtitles struct {
+	apiKey      string
+	restyClient *resty.Client
+}
+
+type openSubtitlesSearchResponse struct {
+	Data []struct {
+		Id         string `json:"id"`
+		Attributes struct {
+			Language       string `json:"language"`
+			DownloadCount  int64  `json:"download_count"`
+			MoviehashMatch bool   `json:"moviehash_match"`
+			Release        string `json:"release"`
+			Files          []struct {
+				FileId   int64  `json:"file_id"`
+				FileName string `json:"file_name"`
+			} `json:"files"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+type openSubtitlesDownloadResponse struct {
+	Link     string `json:"link"`
+	FileName string `json:"file_name"`
+	Message  string `json:"message"`
+}
+
+func NewOpenSubtitles(apiKey, baseUrl, proxyUrl string) *OpenSubtitles {
+	if baseUrl == "" {
+		baseUrl = OPENSUBTITLES_API_URL
+	}
+	client := resty.New()
+	client.SetTimeout(30 * time.Second)
+	client.SetBaseURL(baseUrl)
+	client.SetHeader("Accept", "application/json")
+	client.SetHeader("Content-Type", "application/json")
+	client.SetHeader("User-Agent", "q115-strm-go v1.0")
+	client.SetHeader("Api-Key", apiKey)
+	if proxyUrl != "" {
+		client.SetProxy(proxyUrl)
+	}
+	return &OpenSubtitles{apiKey: apiKey, restyClient: client}
+}
+
+func (o *OpenSubtitles) Name() string {
+	return "opensubtitles"
+}
+
+func (o *OpenSubtitles) Search(ctx context.Context, q *Query) ([]*Subtitle, error) {
+	request := o.restyClient.R().SetContext(ctx).SetResult(&openSubtitlesSearchResponse{})
+	langs := make([]string, 0, len(q.Languages))
+	for _, l := range q.Languages {
+		langs = append(langs, strings.ToLower(l))
+	}
+	request.SetQueryParam("languages", strings.Join(langs, ","))
+	if q.Season > 0 {
+		// 剧集使用电视剧的TMDB ID + 季集编号
+		if q.TmdbId > 0 {
+			request.SetQueryParam("parent_tmdb_id", fmt.Sprintf("%d", q.TmdbId))
+		}
+		request.SetQueryParam("season_number", fmt.Sprintf("%d", q.Season))
+		request.SetQueryParam("episode_number", fmt.Sprintf("%d", q.Episode))
+	} else {
+		if q.TmdbId > 0 {
+			request.SetQueryParam("tmdb_id", fmt.Sprintf("%d", q.TmdbId))
+		}
+		if q.ImdbId != "" {
+			request.SetQueryParam("imdb_id", strings.TrimPrefix(q.ImdbId, "tt"))
+		}
+	}
+	if q.FileHash != "" {
+		request.SetQueryParam("moviehash", q.FileHash)
+	}
+	resp, err := request.Get("subtitles")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode() >= 400 {
+		return nil, fmt.Errorf("HTTP error %d: %s", resp.StatusCode(), resp.String())
+	}
+	result := resp.Result().(*openSubtitlesSearchResponse)
+	subs := make([]*Subtitle, 0)
+	for _, item := range result.Data {
+		for _, file := range item.Attributes.Files {
+			format := FormatFromName(file.FileName)
+			if format == "" {
+				// OpenSubtitles默认提供srt格式
+				format = "srt"
+			}
+			subs = append(subs, &Subtitle{
+				Provider:    o.Name(),
+				Id:          fmt.Sprintf("%d", file.FileId),
+				Language:    normalizeOpenSubtitlesLanguage(item.Attributes.Language),
+				Format:      format,
+				Name:        item.Attributes.Release,
+				Downloads:   item.Attributes.DownloadCount,
+				HashMatched: item.Attributes.MoviehashMatch,
+			})
+		}
+	}
+	return subs, nil
+}
+
+func (o *OpenSubtitles) Download(ctx context.Context, sub *Subtitle) ([]byte, error) {
+	// file_id 必须是数字，字符串会返回400
+	fileId, err := strconv.ParseInt(sub.Id, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("字幕文件ID %s 不是数字: %w", sub.Id, err)
+	}
+	resp, err := o.restyClient.R().SetContext(ctx).
+		SetBody(map[string]any{"file_id": fileId, "sub_format": sub.Format}).
+		SetResult(&openSubtitlesDownloadResponse{}).
+		Post("download")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode() >= 400 {
+		return nil, fmt.Errorf("HTTP error %d: %s", resp.StatusCode(), resp.String())
+	}
+	result := resp.Result().(*openSubtitlesDownloadResponse)
+	if result.Link == "" {
+		return nil, fmt.Errorf("没有返回下载地址: %s", result.Message)
+	}
+	sub.DownloadUrl = result.Link
+	fileResp, err := o.restyClient.R().SetContext(ctx).SetHeader("Accept", "*/*").Get(result.Link)
+	if err != nil {
+		return nil, err
+	}
+	if fileResp.StatusCode() >= 400 {
+		return nil, fmt.Errorf("HTTP error %d", fileResp.StatusCode())
+	}
+	return fileResp.Bytes(), nil
+}
+
+// OpenSubtitles的语言代码为小写，例如：zh-cn、en
+func normalizeOpenSubtitlesLanguage(lang string) string {
+	switch strings.ToLower(lang) {
+	case "zh-cn", "zh", "chi", "zho":
+		return "zh-CN"
+	case "zh-tw":
+		return "zh-TW"
+	}
+	return strings.ToLower(lang)
+}
@@ -0,0 +1,157 @@
+package subtitle
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// 字幕格式，只下载可以直接挂载的文本字幕
+var AllowedFormats = []string{"srt", "ass", "ssa"}
+
+// 默认语言偏好
+var DefaultLanguages = []string{"zh-CN", "zh-TW", "en"}
+
+// 查询条件，TMDB ID、IMDb ID、文件哈希至少提供一个，标题用于按关键词搜索的提供者
+type Query struct {
+	TmdbId    int64    // 电影或电视剧的TMDB ID
+	ImdbId    string   // IMDb ID，例如：tt0816692
+	Title     string   // 标题
+	Year      int      // 年份
+	Season    int      // 季编号，电影为0
+	Episode   int      // 集编号，电影为0
+	FileName  string   // 视频文件名，用于匹配发布组
+	FileHash  string   // OpenSubtitles哈希，只有本地文件才能计算
+	FileSize  int64    // 视频文件大小
+	Languages []string // 语言偏好，按优先级排序，例如：zh-CN、en
+}
+
+// 查询到的字幕
+type Subtitle struct {
+	Provider    string // 提供者名称
+	Id          string // 提供者的字幕ID或者文件ID
+	Language    string // 语言，统一为 zh-CN、zh-TW、en 格式
+	Format      string // 字幕格式：srt、ass、ssa
+	Name        string // 字幕名称
+	DownloadUrl string // 下载地址，有些提供者需要调用Download接口才能拿到
+	Downloads   int64  // 下载次数，用于排序
+	HashMatched bool   // 是否和视频文件哈希匹配
+}
+
+// 字幕提供者
+type Provider interface {
+	Name() string
+	Search(ctx context.Context, q *Query) ([]*Subtitle, error)
+	Download(ctx context.Context, sub *Subtitle) ([]byte, error)
+}
+
+// 按语言偏好选择最佳字幕
+// 语言优先级最高，同语言内哈希匹配优先，然后是文本字幕格式优先ass，最后按下载次数
+func ChooseBest(subs []*Subtitle, languages []string) *Subtitle {
+	var best *Subtitle
+	bestRank := -1
+	for _, sub := range subs {
+		if !slices.Contains(AllowedFormats, sub.Format) {
+			continue
+		}
+		langIndex := slices.IndexFunc(languages, func(l string) bool {
+			return strings.EqualFold(l, sub.Language)
+		})
+		if langIndex < 0 {
+			continue
+		}
+		if best == nil || langIndex < bestRank || (langIndex == bestRank && better(sub, best)) {
+			best = sub
+			bestRank = langIndex
+		}
+	}
+	return best
+}
+
+func better(a, b *Subtitle) bool {
+	if a.HashMatched != b.HashMatched {
+		return a.HashMatched
+	}
+	if a.Format != b.Format {
+		return a.Format == "ass"
+	}
+	return a.Downloads > b.Downloads
+}
+
+// 依次查询所有提供者，返回第一个找到的最佳字幕和内容
+func Fetch(ctx context.Context, providers []Provider, q *Query) (*Subtitle, []byte, error) {
+	if len(q.Languages) == 0 {
+		q.Languages = DefaultLanguages
+	}
+	var lastErr error
+	for _, p := range providers {
+		subs, err := p.Search(ctx, q)
+		if err != nil {
+			lastErr = fmt.Errorf("%s 查询字幕失败: %w", p.Name(), err)
+			continue
+		}
+		best := ChooseBest(subs, q.Languages)
+		if best == nil {
+			continue
+		}
+		content, err := p.Download(ctx, best)
+		if err != nil {
+			lastErr = fmt.Errorf("%s 下载字幕失败: %w", p.Name(), err)
+			continue
+		}
+		return best, content, nil
+	}
+	if lastErr != nil {
+		return nil, nil, lastErr
+	}
+	return nil, nil, nil
+}
+
+// 生成字幕文件名，例如：星际穿越 (2014).zh-CN.srt
+func MakeFileName(videoBaseName string, sub *Subtitle) string {
+	return fmt.Sprintf("%s.%s.%s", videoBaseName, sub.Language, sub.Format)
+}
+
+// 根据文件扩展名返回字幕格式，不支持的格式返回空字符串
+func FormatFromName(name string) string {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(name)), ".")
+	if slices.Contains(AllowedFormats, ext) {
+		return ext
+	}
+	return ""
+}
+
+const hashChunkSize = 64 * 1024
+
+// 计算OpenSubtitles哈希：文件大小 + 头尾各64KB按uint64小端累加
+func ComputeHash(filePath string) (string, int64, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return "", 0, err
+	}
+	size := stat.Size()
+	if size < hashChunkSize*2 {
+		return "", size, fmt.Errorf("文件太小，无法计算哈希")
+	}
+	hash := uint64(size)
+	buf := make([]byte, hashChunkSize)
+	for _, offset := range []int64{0, size - hashChunkSize} {
+		if _, err := f.ReadAt(buf, offset); err != nil && err != io.EOF {
+			return "", size, err
+		}
+		for i := 0; i < hashChunkSize; i += 8 {
+			hash += binary.LittleEndian.Uint64(buf[i : i+8])
+		}
+	}
+	return fmt.Sprintf("%016x", hash), size, nil
+}
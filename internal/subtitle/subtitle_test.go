@@ -0,0 +1,127 @@
+package subtitle
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 兼容射手网接口的本地服务
+func newAssrtStandIn(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	var server *httptest.Server
+	mux.HandleFunc("/v1/sub/search", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("token") != "test-token" {
+			w.Write([]byte(`{"status":101,"errmsg":"token错误"}`))
+			return
+		}
+		w.Write([]byte(`{"status":0,"sub":{"subs":[
+			{"id":1,"native_name":"英文字幕","subtype":"SRT","down_count":900,"lang":{"desc":"英","langlist":{"langeng":true}}},
+			{"id":2,"native_name":"简体SRT","subtype":"SRT","down_count":500,"lang":{"desc":"简","langlist":{"langchs":true}}},
+			{"id":3,"native_name":"简英ASS","subtype":"ASS","down_count":100,"lang":{"desc":"简英","langlist":{"langchs":true,"langeng":true}}}
+		]}}`))
+	})
+	mux.HandleFunc("/v1/sub/detail", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":0,"sub":{"subs":[{"id":3,"filename":"sub.rar","url":"` + server.URL + `/files/sub.rar","filelist":[
+			{"f":"readme.txt","url":"` + server.URL + `/files/readme.txt"},
+			{"f":"movie.chs.ass","url":"` + server.URL + `/files/movie.chs.ass"}
+		]}]}}`))
+	})
+	mux.HandleFunc("/files/movie.chs.ass", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[Script Info]"))
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAssrtFetch(t *testing.T) {
+	server := newAssrtStandIn(t)
+	provider := NewAssrt("test-token", server.URL+"/v1/", "")
+	sub, content, err := Fetch(context.Background(), []Provider{provider}, &Query{Title: "星际穿越", Year: 2014, Languages: []string{"zh-CN", "en"}})
+	if err != nil {
+		t.Fatalf("查询字幕失败: %v", err)
+	}
+	if sub == nil || sub.Id != "3" || sub.Language != "zh-CN" || sub.Format != "ass" {
+		t.Fatalf("选择的字幕不正确: %+v", sub)
+	}
+	if string(content) != "[Script Info]" {
+		t.Errorf("字幕内容不正确: %s", string(content))
+	}
+	if name := MakeFileName("星际穿越 (2014)", sub); name != "星际穿越 (2014).zh-CN.ass" {
+		t.Errorf("字幕文件名不正确: %s", name)
+	}
+}
+
+func TestAssrtInvalidToken(t *testing.T) {
+	server := newAssrtStandIn(t)
+	provider := NewAssrt("wrong", server.URL+"/v1/", "")
+	if _, _, err := Fetch(context.Background(), []Provider{provider}, &Query{Title: "星际穿越"}); err == nil {
+		t.Errorf("token错误时应该返回错误")
+	}
+}
+
+func TestOpenSubtitlesDownloadFileIdIsNumber(t *testing.T) {
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/download", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("解析请求失败: %v", err)
+		}
+		if id, ok := body["file_id"].(float64); !ok || id != 123 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message":"file_id must be integer"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"link":"` + server.URL + `/files/sub.srt"}`))
+	})
+	mux.HandleFunc("/files/sub.srt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("1"))
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	provider := NewOpenSubtitles("key", server.URL+"/", "")
+	content, err := provider.Download(context.Background(), &Subtitle{Id: "123", Format: "srt"})
+	if err != nil || string(content) != "1" {
+		t.Fatalf("下载字幕失败: %v", err)
+	}
+}
+
+func TestChooseBest(t *testing.T) {
+	subs := []*Subtitle{
+		{Id: "1", Language: "en", Format: "srt", Downloads: 1000},
+		{Id: "2", Language: "zh-CN", Format: "srt", Downloads: 10},
+		{Id: "3", Language: "zh-CN", Format: "srt", Downloads: 5, HashMatched: true},
+		{Id: "4", Language: "zh-CN", Format: "sup", Downloads: 9999},
+	}
+	if best := ChooseBest(subs, []string{"zh-CN", "en"}); best == nil || best.Id != "3" {
+		t.Errorf("应该选择哈希匹配的中文字幕: %+v", best)
+	}
+	if best := ChooseBest(subs, []string{"en"}); best == nil || best.Id != "1" {
+		t.Errorf("应该选择英文字幕: %+v", best)
+	}
+	if best := ChooseBest(subs, []string{"ja"}); best != nil {
+		t.Errorf("没有匹配的语言时应该返回nil: %+v", best)
+	}
+}
+
+func TestComputeHash(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "video.mkv")
+	if err := os.WriteFile(file, make([]byte, hashChunkSize*2), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hash, size, err := ComputeHash(file)
+	if err != nil {
+		t.Fatalf("计算哈希失败: %v", err)
+	}
+	// 内容全是0时哈希等于文件大小
+	if size != hashChunkSize*2 || hash != "0000000000020000" {
+		t.Errorf("哈希不正确: %s %d", hash, size)
+	}
+}
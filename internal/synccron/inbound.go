@@ -0,0 +1,333 @@
+package synccron
+
+import (
+	"Q115-STRM/internal/models"
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============ 入站Webhook ============
+// 下载器、CD2、rclone等程序通知路径变化后，找到覆盖该路径的同步目录，只同步变化的子目录
+// 同一个目录短时间内多次通知只同步一次，等通知停止一段时间后再加入队列
+
+// InboundDebounceDelay 最后一次通知之后等待多久再加入同步队列
+const InboundDebounceDelay = 30 * time.Second
+
+// InboundChange 外部程序通知的路径变化
+type InboundChange struct {
+	Path  string `json:"path"`
+	IsDir *bool  `json:"is_dir"` // 为空时根据扩展名判断，视频和元数据扩展名当作文件
+}
+
+// InboundOptions 入站通知的选项
+type InboundOptions struct {
+	Scrape      bool   `json:"scrape"`       // 同步完成后刮削关联的刮削目录
+	StripPrefix string `json:"strip_prefix"` // 去掉路径开头的部分，比如下载器或者rclone的挂载目录
+	AddPrefix   string `json:"add_prefix"`   // 去掉前缀后再加上的部分，对应同步目录的网盘路径
+}
+
+// InboundResult 每个路径的处理结果
+type InboundResult struct {
+	Path       string `json:"path"`                   // 通知的路径
+	Matched    bool   `json:"matched"`                // 是否找到覆盖该路径的同步目录
+	SyncPathID uint   `json:"sync_path_id,omitempty"` // 同步目录ID
+	Folder     string `json:"folder,omitempty"`       // 要同步的子目录
+	TargetPath string `json:"target_path,omitempty"`  // 子目录对应的本地目录
+	ScrapeIds  []uint `json:"scrape_ids,omitempty"`   // 同步完成后要刮削的目录
+	Error      string `json:"error,omitempty"`        // 路径不合法的原因
+}
+
+// 等待加入队列的子目录同步
+type inboundPending struct {
+	syncPathID uint
+	folder     string
+	task       *NewSyncTask
+	timer      *time.Timer
+}
+
+type inboundDebouncer struct {
+	mu      sync.Mutex
+	delay   time.Duration
+	pending map[string]*inboundPending
+	enqueue func(task *NewSyncTask) error
+}
+
+var globalInboundDebouncer = &inboundDebouncer{
+	delay:   InboundDebounceDelay,
+	pending: make(map[string]*inboundPending),
+	enqueue: AddNewSyncTask,
+}
+
+// HandleInboundChanges 处理外部程序通知的路径变化，返回每个路径的匹配结果
+func HandleInboundChanges(changes []InboundChange, opts InboundOptions) []InboundResult {
+	syncPaths := models.GetAllSyncPaths()
+	results := make([]InboundResult, 0, len(changes))
+	for _, change := range changes {
+		result, syncPath := resolveInbound(syncPaths, change, opts)
+		if syncPath == nil && result.Error != "" {
+			logError("入站Webhook: 路径 %s 不合法，忽略: %s", change.Path, result.Error)
+			results = append(results, result)
+			continue
+		}
+		if syncPath == nil {
+			logInfo("入站Webhook: 路径 %s 不在任何同步目录中，忽略", result.Path)
+			results = append(results, result)
+			continue
+		}
+		if opts.Scrape {
+			result.ScrapeIds = syncPath.GetScrapePathIds()
+		}
+		globalInboundDebouncer.add(syncPath, &result)
+		results = append(results, result)
+	}
+	return results
+}
+
+// 找到覆盖路径的同步目录，有多个时使用路径最长的那个
+func resolveInbound(syncPaths []models.SyncPath, change InboundChange, opts InboundOptions) (InboundResult, *models.SyncPath) {
+	path, err := inboundPath(change.Path, opts)
+	result := InboundResult{Path: path}
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	var matched *models.SyncPath
+	var matchedRoot string
+	for i := range syncPaths {
+		root := normalizeInboundPath(syncPaths[i].RemotePath)
+		if !models.IsSubPath(root, path) {
+			continue
+		}
+		if matched == nil || len(root) > len(matchedRoot) {
+			matched = &syncPaths[i]
+			matchedRoot = root
+		}
+	}
+	if matched == nil {
+		return result, nil
+	}
+	folder := path
+	if !inboundIsDir(change) {
+		folder = filepath.ToSlash(filepath.Dir(path))
+	}
+	// 使用同步目录的写法拼接子目录，网盘驱动按这个路径查询目录ID
+	folder = joinInboundPath(matchedRoot, relInboundPath(matchedRoot, folder))
+	targetPath := inboundTargetPath(matched, matchedRoot, folder)
+	if targetPath == "" {
+		result.Error = "本地目录不在同步目录中"
+		return result, nil
+	}
+	result.Matched = true
+	result.SyncPathID = matched.ID
+	result.Folder = folder
+	result.TargetPath = targetPath
+	return result, matched
+}
+
+// 子目录同步的本地目录
+// 网盘同步会把完整的网盘路径拼接到本地目录后面，所以和同步目录一致；本地同步只拼接相对路径
+// 相对路径包含 .. 时返回空，不能同步到本地目录之外
+func inboundTargetPath(syncPath *models.SyncPath, root, folder string) string {
+	if syncPath.SourceType != models.SourceTypeLocal {
+		return syncPath.LocalPath
+	}
+	rel := relInboundPath(root, folder)
+	if rel == "" {
+		return syncPath.LocalPath
+	}
+	if hasParentRef(rel) {
+		return ""
+	}
+	return filepath.Join(syncPath.LocalPath, filepath.FromSlash(rel))
+}
+
+// 应用前缀替换并统一分隔符，路径中不能有 ..
+func inboundPath(p string, opts InboundOptions) (string, error) {
+	p = normalizeInboundPath(p)
+	if opts.StripPrefix != "" {
+		prefix := normalizeInboundPath(opts.StripPrefix)
+		if models.IsSubPath(prefix, p) {
+			p = strings.TrimPrefix(p, prefix)
+		}
+	}
+	if opts.AddPrefix != "" {
+		p = normalizeInboundPath(strings.TrimRight(opts.AddPrefix, "/\\") + "/" + strings.TrimLeft(p, "/"))
+	}
+	if hasParentRef(p) {
+		return p, fmt.Errorf("路径中不能包含 ..")
+	}
+	if p == "" {
+		return p, nil
+	}
+	// 去掉重复的斜杠和 . 再匹配同步目录
+	return normalizeInboundPath(path.Clean(p)), nil
+}
+
+// 路径中是否有 .. 部分
+func hasParentRef(p string) bool {
+	return slices.Contains(strings.Split(p, "/"), "..")
+}
+
+// 下载器可能运行在Windows上，反斜杠统一换成斜杠，去掉末尾的斜杠
+func normalizeInboundPath(path string) string {
+	path = strings.TrimSpace(strings.ReplaceAll(path, "\\", "/"))
+	if path == "" || path == "/" {
+		return path
+	}
+	return strings.TrimRight(path, "/")
+}
+
+// path相对于root的部分，path不在root下时返回空
+func relInboundPath(root, path string) string {
+	root = strings.Trim(root, "/")
+	path = strings.Trim(path, "/")
+	if root == "" {
+		return path
+	}
+	if !strings.HasPrefix(path, root+"/") {
+		return ""
+	}
+	return strings.TrimPrefix(path, root+"/")
+}
+
+func joinInboundPath(root, rel string) string {
+	if rel == "" {
+		return root
+	}
+	if root == "" || root == "/" {
+		return root + rel
+	}
+	return root + "/" + rel
+}
+
+func inboundIsDir(change InboundChange) bool {
+	if change.IsDir != nil {
+		return *change.IsDir
+	}
+	ext := strings.ToLower(filepath.Ext(change.Path))
+	if ext == "" {
+		return true
+	}
+	isFile := slices.ContainsFunc(models.SettingsGlobal.VideoExtArr, func(e string) bool { return strings.EqualFold(e, ext) }) ||
+		slices.ContainsFunc(models.SettingsGlobal.MetaExtArr, func(e string) bool { return strings.EqualFold(e, ext) })
+	return !isFile
+}
+
+// 加入等待队列，同一个同步目录下已经有等待的父目录时合并到父目录，新目录是已有目录的父目录时替换已有目录
+func (d *inboundDebouncer) add(syncPath *models.SyncPath, result *InboundResult) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for key, p := range d.pending {
+		if p.syncPathID != syncPath.ID {
+			continue
+		}
+		if models.IsSubPath(p.folder, result.Folder) {
+			if p.task.ID == 0 {
+				p.task.ScrapeIds = mergeIds(p.task.ScrapeIds, result.ScrapeIds)
+			}
+			p.timer.Reset(d.delay)
+			result.Folder = p.folder
+			result.TargetPath = p.task.TargetPath
+			return
+		}
+		if models.IsSubPath(result.Folder, p.folder) {
+			p.timer.Stop()
+			result.ScrapeIds = mergeIds(result.ScrapeIds, p.task.ScrapeIds)
+			delete(d.pending, key)
+		}
+	}
+	task := &NewSyncTask{
+		TaskType:   SyncTaskTypeStrm,
+		SourceType: syncPath.SourceType,
+		AccountId:  syncPath.AccountId,
+	}
+	if result.Folder == normalizeInboundPath(syncPath.RemotePath) {
+		// 整个同步目录都变化了，按同步目录自己的配置同步，同步完成后会自己触发关联的刮削任务
+		task.ID = syncPath.ID
+	} else {
+		task.SourcePath = result.Folder
+		task.TargetPath = result.TargetPath
+		task.ScrapeIds = result.ScrapeIds
+	}
+	key := fmt.Sprintf("%d:%s", syncPath.ID, result.Folder)
+	p := &inboundPending{syncPathID: syncPath.ID, folder: result.Folder, task: task}
+	p.timer = time.AfterFunc(d.delay, func() { d.fire(key, p) })
+	d.pending[key] = p
+}
+
+func (d *inboundDebouncer) fire(key string, p *inboundPending) {
+	d.mu.Lock()
+	if d.pending[key] != p {
+		// 已经被父目录替换
+		d.mu.Unlock()
+		return
+	}
+	delete(d.pending, key)
+	d.mu.Unlock()
+	if err := d.enqueue(p.task); err != nil {
+		logError("入站Webhook: 添加子目录 %s 的同步任务失败: %v", p.folder, err)
+		return
+	}
+	logInfo("入站Webhook: 已添加子目录 %s 的同步任务", p.folder)
+}
+
+func mergeIds(ids []uint, more []uint) []uint {
+	for _, id := range more {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// 合并要扫描的子目录，去掉已经包含在其他子目录中的目录
+func mergeScanFolders(folders []string, more []string) []string {
+	all := append(slices.Clone(folders), more...)
+	result := make([]string, 0, len(all))
+	for i, folder := range all {
+		covered := false
+		for j, other := range all {
+			if i == j {
+				continue
+			}
+			// 相同的目录保留第一个
+			if other == folder && j < i || other != folder && models.IsSubPath(other, folder) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			result = append(result, folder)
+		}
+	}
+	return result
+}
+
+// 同步成功后把关联的刮削目录加入队列
+// folder 是同步的子目录，在刮削目录下时只扫描这个子目录
+func addScrapeTasks(scrapeIds []uint, folder string) {
+	for _, id := range scrapeIds {
+		scrapePath := models.GetScrapePathByID(id)
+		if scrapePath == nil {
+			logError("获取刮削目录失败，ID=%d", id)
+			continue
+		}
+		task := &NewSyncTask{
+			ID:         id,
+			TaskType:   SyncTaskTypeScrape,
+			SourceType: scrapePath.SourceType,
+			AccountId:  scrapePath.AccountId,
+		}
+		if folder != "" && models.IsSubPath(scrapePath.SourcePath, folder) && !models.IsSubPath(folder, scrapePath.SourcePath) {
+			task.ScanFolders = []string{folder}
+		}
+		if err := AddNewSyncTask(task); err != nil {
+			logError("添加刮削任务失败，ID=%d, 错误=%v", id, err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package synccron
+
+import (
+	"Q115-STRM/internal/models"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestResolveInbound(t *testing.T) {
+	models.SettingsGlobal.VideoExtArr = []string{".mkv", ".mp4"}
+	syncPaths := []models.SyncPath{
+		{BaseModel: models.BaseModel{ID: 1}, RemotePath: "/影视", LocalPath: "/strm", SourceType: models.SourceType115},
+		{BaseModel: models.BaseModel{ID: 2}, RemotePath: "/影视/电影", LocalPath: "/strm2", SourceType: models.SourceType115},
+		{BaseModel: models.BaseModel{ID: 3}, RemotePath: "/mnt/media/", LocalPath: "/strm3", SourceType: models.SourceTypeLocal},
+	}
+	isDir := true
+	cases := []struct {
+		change     InboundChange
+		opts       InboundOptions
+		syncPathID uint
+		folder     string
+		target     string
+	}{
+		// 文件使用所在目录，多个同步目录时使用最长的
+		{InboundChange{Path: "/影视/电影/阿凡达 (2009)/阿凡达.mkv"}, InboundOptions{}, 2, "/影视/电影/阿凡达 (2009)", "/strm2"},
+		{InboundChange{Path: "/影视/剧集/某剧/Season 1", IsDir: &isDir}, InboundOptions{}, 1, "/影视/剧集/某剧/Season 1", "/strm"},
+		// 下载器中的路径换成网盘路径
+		{InboundChange{Path: `D:\downloads\电影\新片`}, InboundOptions{StripPrefix: "D:/downloads", AddPrefix: "/影视"}, 2, "/影视/电影/新片", "/strm2"},
+		// 本地同步的目标目录要拼接相对路径
+		{InboundChange{Path: "/mnt/media/剧集/a.mp4"}, InboundOptions{}, 3, "/mnt/media/剧集", filepath.Join("/strm3", "剧集")},
+		{InboundChange{Path: "/mnt/media/a.mp4"}, InboundOptions{}, 3, "/mnt/media", "/strm3"},
+		{InboundChange{Path: "/其他/a.mkv"}, InboundOptions{}, 0, "", ""},
+		// 重复的斜杠和 . 清理后再匹配，不能用 .. 跳出同步目录
+		{InboundChange{Path: "/影视//电影/./新片/"}, InboundOptions{}, 2, "/影视/电影/新片", "/strm2"},
+		{InboundChange{Path: "/mnt/media/../../etc/a.mp4"}, InboundOptions{}, 0, "", ""},
+		{InboundChange{Path: "/../etc"}, InboundOptions{AddPrefix: "/mnt/media"}, 0, "", ""},
+	}
+	for _, c := range cases {
+		result, syncPath := resolveInbound(syncPaths, c.change, c.opts)
+		if c.syncPathID == 0 {
+			if syncPath != nil || result.Matched {
+				t.Errorf("%s 不应该匹配同步目录", c.change.Path)
+			}
+			continue
+		}
+		if syncPath == nil || syncPath.ID != c.syncPathID || result.Folder != c.folder || result.TargetPath != c.target {
+			t.Errorf("%s 期望 %d %s %s，实际 %+v", c.change.Path, c.syncPathID, c.folder, c.target, result)
+		}
+	}
+}
+
+func TestInboundDebouncerMerge(t *testing.T) {
+	var mu sync.Mutex
+	enqueued := make([]*NewSyncTask, 0)
+	d := &inboundDebouncer{
+		delay:   50 * time.Millisecond,
+		pending: make(map[string]*inboundPending),
+		enqueue: func(task *NewSyncTask) error {
+			mu.Lock()
+			defer mu.Unlock()
+			enqueued = append(enqueued, task)
+			return nil
+		},
+	}
+	syncPath := &models.SyncPath{BaseModel: models.BaseModel{ID: 1}, RemotePath: "/影视", LocalPath: "/strm", SourceType: models.SourceType115}
+	d.add(syncPath, &InboundResult{Folder: "/影视/剧集/某剧/Season 1", ScrapeIds: []uint{1}})
+	d.add(syncPath, &InboundResult{Folder: "/影视/剧集/某剧/Season 2", ScrapeIds: []uint{1}})
+	// 父目录替换已有的子目录
+	d.add(syncPath, &InboundResult{Folder: "/影视/剧集/某剧", ScrapeIds: []uint{2}})
+	// 父目录下的通知合并到父目录
+	d.add(syncPath, &InboundResult{Folder: "/影视/剧集/某剧/Season 1"})
+	time.Sleep(200 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(enqueued) != 1 {
+		t.Fatalf("期望只加入一个同步任务，实际 %d 个", len(enqueued))
+	}
+	task := enqueued[0]
+	if task.SourcePath != "/影视/剧集/某剧" || task.ID != 0 || len(task.ScrapeIds) != 2 {
+		t.Errorf("合并后的任务不正确: %+v", task)
+	}
+
+	// 整个同步目录变化时按同步目录同步
+	enqueued = enqueued[:0]
+	mu.Unlock()
+	d.add(syncPath, &InboundResult{Folder: "/影视", ScrapeIds: []uint{1}})
+	time.Sleep(200 * time.Millisecond)
+	mu.Lock()
+	if len(enqueued) != 1 || enqueued[0].ID != 1 || len(enqueued[0].ScrapeIds) != 0 {
+		t.Errorf("整个同步目录变化时应该使用同步目录ID: %+v", enqueued)
+	}
+}
+
+func TestMergeScanFolders(t *testing.T) {
+	merged := mergeScanFolders([]string{"/影视/剧集/某剧/Season 1", "/影视/电影/新片"}, []string{"/影视/剧集/某剧", "/影视/电影/新片"})
+	if len(merged) != 2 || merged[0] != "/影视/电影/新片" || merged[1] != "/影视/剧集/某剧" {
+		t.Errorf("合并后的子目录不正确: %v", merged)
+	}
+}
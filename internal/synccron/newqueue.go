@@ -5,12 +5,14 @@ import (
 	"Q115-STRM/internal/models"
 	"Q115-STRM/internal/scrape"
 	"Q115-STRM/internal/syncstrm"
+	"Q115-STRM/internal/webhook"
 	ws "Q115-STRM/internal/websocket"
 	"context"
 	"fmt"
 	"runtime"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type SyncTaskType string
@@ -53,13 +55,20 @@ type NewSyncTask struct {
 	IsFile       bool
 	SourceType   models.SourceType
 	AccountId    uint
+	SkipScan     bool     // 刮削时跳过扫描，待刮削记录已经在STRM同步时入库
+	FullScan     bool     // 刮削时强制全量扫描
+	ScrapeIds    []uint   // 同步成功后要刮削的目录，入站Webhook触发的子目录同步使用
+	ScanFolders  []string // 刮削时只扫描这些子目录，为空时扫描整个刮削目录
 }
 
 func (t *NewSyncTask) Key() string {
 	if t.ID > 0 {
 		return fmt.Sprintf("%d-%s", t.ID, t.TaskType)
-	} else {
+	} else if t.SourcePathId != "" {
 		return fmt.Sprintf("%s-%s", t.SourcePathId, t.TaskType)
+	} else {
+		// 入站Webhook只知道路径，目录ID在同步开始时才查询
+		return fmt.Sprintf("%s-%s", t.SourcePath, t.TaskType)
 	}
 }
 
@@ -110,6 +119,23 @@ func (q *NewSyncQueuePerType) AddTask(task *NewSyncTask) error {
 	defer q.mutex.Unlock()
 
 	if q.isTaskExistsUnsafe(task) {
+		// 等待中的跳过扫描的刮削任务，再次添加需要扫描的任务时改为扫描，需要全量扫描时改为全量扫描
+		// 只扫描子目录的任务合并子目录，有一个需要扫描整个刮削目录时扫描整个目录
+		if waiting, ok := q.waitingQueue[task.Key()]; ok {
+			if !task.SkipScan {
+				if waiting.SkipScan {
+					waiting.ScanFolders = task.ScanFolders
+				} else if len(task.ScanFolders) == 0 {
+					waiting.ScanFolders = nil
+				} else if len(waiting.ScanFolders) > 0 {
+					waiting.ScanFolders = mergeScanFolders(waiting.ScanFolders, task.ScanFolders)
+				}
+				waiting.SkipScan = false
+			}
+			if task.FullScan {
+				waiting.FullScan = true
+			}
+		}
 		return fmt.Errorf("任务已存在: 类型=%s, ID=%d", task.TaskType, task.ID)
 	}
 
@@ -228,10 +254,14 @@ func (q *NewSyncQueuePerType) executeTask(task *NewSyncTask) {
 func (q *NewSyncQueuePerType) executeStrmSync(task *NewSyncTask) {
 	if task.ID == 0 {
 		// 手动同步
-		account, err := models.GetAccountById(task.AccountId)
-		if err != nil {
-			logError("获取账号失败，ID=%d, 错误=%v", task.AccountId, err)
-			return
+		account := &models.Account{SourceType: models.SourceTypeLocal}
+		if task.AccountId != 0 {
+			var err error
+			account, err = models.GetAccountById(task.AccountId)
+			if err != nil {
+				logError("获取账号失败，ID=%d, 错误=%v", task.AccountId, err)
+				return
+			}
 		}
 		q.strmSync = syncstrm.NewSyncStrmByPath(account, task.SourcePath, task.SourcePathId, task.TargetPath, task.IsFile)
 		if q.strmSync == nil {
@@ -259,28 +289,32 @@ func (q *NewSyncQueuePerType) executeStrmSync(task *NewSyncTask) {
 	}
 
 	// 触发STRM同步任务开始事件
-	ws.BroadcastEvent(ws.EventStrmSyncTaskStart, map[string]any{
-		"task_id": task.ID,
-	})
+	ws.BroadcastEvent(ws.EventStrmSyncTaskStart, ws.StrmSyncTaskData{TaskID: task.ID})
+	webhook.Dispatch(webhook.EventSyncStarted, syncWebhookData(q.strmSync))
 
 	defer func() {
 		q.strmSync = nil
 	}()
-	if startErr := q.strmSync.Start(); startErr == nil {
+	startErr := q.strmSync.Start()
+	if startErr == nil {
 		logInfo("STRM同步任务执行成功: ID=%d", task.ID)
 		// 触发STRM同步任务完成事件
-		ws.BroadcastEvent(ws.EventStrmSyncTaskComplete, map[string]any{
-			"task_id": task.ID,
-			"success": true,
-		})
+		ws.BroadcastEvent(ws.EventStrmSyncTaskComplete, ws.StrmSyncTaskData{TaskID: task.ID, Success: true})
 	} else {
 		logError("STRM同步任务执行失败: ID=%d, 错误=%v", task.ID, startErr)
 		// 触发STRM同步任务完成事件（失败）
-		ws.BroadcastEvent(ws.EventStrmSyncTaskComplete, map[string]any{
-			"task_id": task.ID,
-			"success": false,
-			"error":   startErr.Error(),
-		})
+		ws.BroadcastEvent(ws.EventStrmSyncTaskComplete, ws.StrmSyncTaskData{TaskID: task.ID, Error: startErr.Error()})
+	}
+	// 取消时 Start 也返回nil，以同步记录的状态为准
+	data := syncWebhookData(q.strmSync)
+	if startErr == nil && q.strmSync.Sync != nil && q.strmSync.Sync.Status == models.SyncStatusCompleted {
+		webhook.Dispatch(webhook.EventSyncCompleted, data)
+		addScrapeTasks(task.ScrapeIds, task.SourcePath)
+	} else {
+		if data.Error == "" && startErr != nil {
+			data.Error = startErr.Error()
+		}
+		webhook.Dispatch(webhook.EventSyncFailed, data)
 	}
 }
 
@@ -299,16 +333,18 @@ func (q *NewSyncQueuePerType) executeScrape(task *NewSyncTask) {
 	logInfo("开始执行刮削任务: ID=%d", task.ID)
 
 	// 触发刮削任务开始事件
-	ws.BroadcastEvent(ws.EventScraperTaskStart, map[string]any{
-		"task_id":   task.ID,
-		"path_name": scrapePath.SourcePath,
-	})
+	ws.BroadcastEvent(ws.EventScraperTaskStart, ws.ScrapeTaskData{TaskID: task.ID, PathName: scrapePath.SourcePath})
+	startedAt := time.Now()
+	webhook.Dispatch(webhook.EventScrapeStarted, scrapeWebhookData(scrapePath, startedAt, false))
 
 	q.scrapeInstance = scrape.NewScrape(scrapePath)
 	if q.scrapeInstance == nil {
 		logError("创建刮削任务失败")
 		return
 	}
+	q.scrapeInstance.SkipScan = task.SkipScan
+	q.scrapeInstance.FullScan = task.FullScan
+	q.scrapeInstance.ScanFolders = task.ScanFolders
 	defer func() {
 		q.scrapeInstance = nil
 	}()
@@ -316,19 +352,13 @@ func (q *NewSyncQueuePerType) executeScrape(task *NewSyncTask) {
 	if success := q.scrapeInstance.Start(); success {
 		logInfo("刮削任务执行成功: ID=%d", task.ID)
 		// 触发刮削任务完成事件
-		ws.BroadcastEvent(ws.EventScraperTaskComplete, map[string]any{
-			"task_id":   task.ID,
-			"path_name": scrapePath.SourcePath,
-			"success":   true,
-		})
+		ws.BroadcastEvent(ws.EventScraperTaskComplete, ws.ScrapeTaskData{TaskID: task.ID, PathName: scrapePath.SourcePath, Success: true})
+		webhook.Dispatch(webhook.EventScrapeCompleted, scrapeWebhookData(scrapePath, startedAt, true))
 	} else {
 		logError("刮削任务执行失败: ID=%d", task.ID)
 		// 触发刮削任务完成事件（失败）
-		ws.BroadcastEvent(ws.EventScraperTaskComplete, map[string]any{
-			"task_id":   task.ID,
-			"path_name": scrapePath.SourcePath,
-			"success":   false,
-		})
+		ws.BroadcastEvent(ws.EventScraperTaskComplete, ws.ScrapeTaskData{TaskID: task.ID, PathName: scrapePath.SourcePath})
+		webhook.Dispatch(webhook.EventScrapeFailed, scrapeWebhookData(scrapePath, startedAt, true))
 	}
 }
 
@@ -586,6 +616,7 @@ func (m *NewSyncQueueManager) PauseAll() {
 	for _, queue := range m.queues {
 		queue.Pause()
 	}
+	ws.BroadcastEvent(ws.EventQueueStateChange, ws.QueueStateData{Queue: ws.QueueSync, Running: false})
 }
 
 func (m *NewSyncQueueManager) ResumeAll() {
@@ -596,6 +627,7 @@ func (m *NewSyncQueueManager) ResumeAll() {
 	for _, queue := range m.queues {
 		queue.Resume()
 	}
+	ws.BroadcastEvent(ws.EventQueueStateChange, ws.QueueStateData{Queue: ws.QueueSync, Running: true})
 }
 
 func (m *NewSyncQueueManager) GetAllStatus() map[models.SourceType]map[string]interface{} {
@@ -133,6 +133,7 @@ func RefreshOAuthAccessToken() {
 					Content:   fmt.Sprintf("账号ID：%d\n用户名：%s\n请重新授权\n⏰ 时间: %s", int(account.ID), account.Username, time.Now().Format("2006-01-02 15:04:05")),
 					Timestamp: time.Now(),
 					Priority:  models.HighPriority,
+					DedupKey:  fmt.Sprintf("token_invalid:%d", account.ID),
 				}
 				if notificationmanager.GlobalEnhancedNotificationManager != nil {
 					if err := notificationmanager.GlobalEnhancedNotificationManager.SendNotification(ctx, notif); err != nil {
@@ -171,6 +172,7 @@ func RefreshOAuthAccessToken() {
 					Content:   fmt.Sprintf("账号ID：%d\n用户名：%s\n请重新授权\n⏰ 时间: %s", int(account.ID), account.Username, time.Now().Format("2006-01-02 15:04:05")),
 					Timestamp: time.Now(),
 					Priority:  models.HighPriority,
+					DedupKey:  fmt.Sprintf("token_invalid:%d", account.ID),
 				}
 				if notificationmanager.GlobalEnhancedNotificationManager != nil {
 					if err := notificationmanager.GlobalEnhancedNotificationManager.SendNotification(ctx, notif); err != nil {
@@ -318,6 +320,13 @@ func InitCron() {
 		}
 	})
 
+	GlobalCron.AddFunc("30 5 * * *", func() {
+		// 每天5点30分生成缺集报告，生成后检查是否需要发送缺集通知
+		if err := models.StartBuildEpisodeReport(); err != nil {
+			helpers.AppLogger.Warnf("生成缺集报告失败: %v", err)
+		}
+	})
+
 	addBackupCron()
 
 	GlobalCron.Start()
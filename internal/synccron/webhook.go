@@ -0,0 +1,64 @@
+package synccron
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/syncstrm"
+	"Q115-STRM/internal/webhook"
+	"time"
+)
+
+// 同步任务的Webhook事件数据
+func syncWebhookData(s *syncstrm.SyncStrm) webhook.SyncData {
+	data := webhook.SyncData{
+		SyncPathID:       s.SyncPathId,
+		SourcePath:       s.SourcePath,
+		TargetPath:       s.TargetPath,
+		NewStrmPaths:     s.NewStrmPaths,
+		RemovedStrmPaths: s.RemovedStrmPaths,
+		PathsTruncated:   s.StrmPathsTruncated,
+	}
+	if s.Account != nil {
+		data.SourceType = string(s.Account.SourceType)
+	}
+	if s.Sync != nil {
+		data.Sync = s.Sync
+		data.Error = s.Sync.FailReason
+	}
+	if data.NewStrmPaths == nil {
+		data.NewStrmPaths = []string{}
+	}
+	if data.RemovedStrmPaths == nil {
+		data.RemovedStrmPaths = []string{}
+	}
+	return data
+}
+
+// 刮削任务的Webhook事件数据，结束时统计本次处理过的文件状态
+func scrapeWebhookData(scrapePath *models.ScrapePath, startedAt time.Time, finished bool) webhook.ScrapeData {
+	data := webhook.ScrapeData{
+		ScrapePathID: scrapePath.ID,
+		SourceType:   string(scrapePath.SourceType),
+		MediaType:    string(scrapePath.MediaType),
+		SourcePath:   scrapePath.SourcePath,
+		DestPath:     scrapePath.DestPath,
+		StartedAt:    startedAt.Unix(),
+	}
+	if !finished {
+		return data
+	}
+	data.FinishedAt = time.Now().Unix()
+	var rows []struct {
+		Status string
+		Count  int
+	}
+	db.Db.Model(&models.ScrapeMediaFile{}).
+		Select("status, COUNT(*) AS count").
+		Where("scrape_path_id = ? AND updated_at >= ?", scrapePath.ID, startedAt.Unix()).
+		Group("status").Scan(&rows)
+	data.StatusCounts = make(map[string]int, len(rows))
+	for _, row := range rows {
+		data.StatusCounts[row.Status] = row.Count
+	}
+	return data
+}
@@ -401,6 +401,48 @@ func (c *MemorySyncCache) UpdatePathByParentId(parentId string, newPath string,
 	return nil
 }
 
+// GetBlurayStreams 按所在目录返回蓝光原盘视频流目录中的视频文件
+func (c *MemorySyncCache) GetBlurayStreams() [][]*SyncFileCache {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	discs := make([][]*SyncFileCache, 0)
+	for _, files := range c.parentIndex {
+		streams := make([]*SyncFileCache, 0)
+		for _, file := range files {
+			if file.IsVideo && models.IsBlurayStreamPath(file.GetPath()) {
+				streams = append(streams, file)
+			}
+		}
+		if len(streams) > 0 {
+			discs = append(discs, streams)
+		}
+	}
+	return discs
+}
+
+// UpdateLocalFilePath 修改文件的本地路径并更新本地路径索引
+func (c *MemorySyncCache) UpdateLocalFilePath(file *SyncFileCache, localFilePath string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.localPathIndex[file.LocalFilePath] == file {
+		delete(c.localPathIndex, file.LocalFilePath)
+	}
+	file.LocalFilePath = localFilePath
+	c.localPathIndex[localFilePath] = file
+}
+
+// RemoveLocalPathIndex 从本地路径索引中移除文件，对比本地文件时对应的本地文件会被删除
+func (c *MemorySyncCache) RemoveLocalPathIndex(file *SyncFileCache) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.localPathIndex[file.LocalFilePath] == file {
+		delete(c.localPathIndex, file.LocalFilePath)
+	}
+}
+
 // Count 统计记录数
 func (c *MemorySyncCache) Count() int64 {
 	c.mu.RLock()
@@ -281,3 +281,29 @@ func (d *open115Driver) DeleteFile(ctx context.Context, parentId string, fileIds
 func (d *open115Driver) GetFilesByPathMtime(ctx context.Context, rootPathId string, offset, limit int, mtime int64) (*baidupan.FileListAllResponse, error) {
 	return nil, nil
 }
+
+// 读取蓝光原盘的播放列表
+func (d *open115Driver) ReadBlurayPlaylists(ctx context.Context, playlistPath string) []*models.BlurayPlaylist {
+	pathId, err := d.GetPathIdByPath(ctx, playlistPath)
+	if err != nil || pathId == "" {
+		return nil
+	}
+	resp, err := d.client.GetFsList(ctx, pathId, true, false, false, 0, models.GetFileListPageSize())
+	if err != nil {
+		d.s.Sync.Logger.Warnf("获取蓝光原盘播放列表目录 %s 失败: %v", playlistPath, err)
+		return nil
+	}
+	files := make([]*models.BlurayPlaylistFile, 0)
+	for _, file := range resp.Data {
+		if file.FileCategory != v115open.TypeDir && models.IsBlurayPlaylistFile(file.FileName) {
+			files = append(files, &models.BlurayPlaylistFile{Id: file.PickCode, Name: file.FileName, Size: file.FileSize})
+		}
+	}
+	return models.ReadBlurayPlaylists(files, func(file *models.BlurayPlaylistFile) ([]byte, error) {
+		downloadUrl := d.client.GetDownloadUrl(ctx, file.Id, v115open.DEFAULTUA, false)
+		if downloadUrl == "" {
+			return nil, fmt.Errorf("获取115文件下载链接失败, url为空")
+		}
+		return helpers.ReadFromUrl(downloadUrl, v115open.DEFAULTUA)
+	})
+}
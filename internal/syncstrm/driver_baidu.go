@@ -2,6 +2,7 @@ package syncstrm
 
 import (
 	"Q115-STRM/internal/baidupan"
+	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/models"
 	"Q115-STRM/internal/v115open"
 	"context"
@@ -173,3 +174,25 @@ func (d *BaiduPanDriver) GetFilesByPathMtime(ctx context.Context, rootPathId str
 	}
 	return resp, nil
 }
+
+// 读取蓝光原盘的播放列表
+func (d *BaiduPanDriver) ReadBlurayPlaylists(ctx context.Context, playlistPath string) []*models.BlurayPlaylist {
+	resp, err := d.client.GetFileList(ctx, playlistPath, 0, 1, 0, 1000)
+	if err != nil {
+		return nil
+	}
+	files := make([]*models.BlurayPlaylistFile, 0)
+	for _, file := range resp {
+		if file.IsDir == uint32(1) || !models.IsBlurayPlaylistFile(file.ServerFilename) {
+			continue
+		}
+		files = append(files, &models.BlurayPlaylistFile{Id: fmt.Sprintf("%d", file.FsId), Name: file.ServerFilename, Size: int64(file.Size)})
+	}
+	return models.ReadBlurayPlaylists(files, func(file *models.BlurayPlaylistFile) ([]byte, error) {
+		detail, err := d.client.GetFileDetail(ctx, file.Id, 1)
+		if err != nil || detail == nil || detail.Dlink == "" {
+			return nil, fmt.Errorf("获取百度网盘文件下载链接失败, url为空")
+		}
+		return helpers.ReadFromUrl(detail.Dlink, "pan.baidu.com")
+	})
+}
@@ -131,6 +131,11 @@ func (d *localDriver) DetailByFileId(ctx context.Context, fileId string) (*SyncF
 func (d *localDriver) DeleteFile(ctx context.Context, parentId string, fileIds []string) error {
 	for _, fileId := range fileIds {
 		if err := os.Remove(fileId); err != nil {
+			if d.s == nil {
+				// 不是在同步任务中调用（例如删除重复文件）
+				helpers.AppLogger.Errorf("删除文件 %s 失败，错误: %v", fileId, err)
+				continue
+			}
 			d.s.Sync.Logger.Errorf("删除文件 %s 失败，错误: %v", fileId, err)
 			continue
 		}
@@ -141,3 +146,25 @@ func (d *localDriver) DeleteFile(ctx context.Context, parentId string, fileIds [
 func (d *localDriver) GetFilesByPathMtime(ctx context.Context, rootPathId string, offset, limit int, mtime int64) (*baidupan.FileListAllResponse, error) {
 	return nil, nil
 }
+
+// 读取蓝光原盘的播放列表
+func (d *localDriver) ReadBlurayPlaylists(ctx context.Context, playlistPath string) []*models.BlurayPlaylist {
+	entries, err := os.ReadDir(playlistPath)
+	if err != nil {
+		return nil
+	}
+	files := make([]*models.BlurayPlaylistFile, 0)
+	for _, entry := range entries {
+		if entry.IsDir() || !models.IsBlurayPlaylistFile(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, &models.BlurayPlaylistFile{Id: filepath.Join(playlistPath, entry.Name()), Name: entry.Name(), Size: info.Size()})
+	}
+	return models.ReadBlurayPlaylists(files, func(file *models.BlurayPlaylistFile) ([]byte, error) {
+		return os.ReadFile(file.Id)
+	})
+}
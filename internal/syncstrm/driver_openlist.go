@@ -228,3 +228,25 @@ func (d *openListDriver) DeleteFile(ctx context.Context, parentId string, fileId
 func (d *openListDriver) GetFilesByPathMtime(ctx context.Context, rootPathId string, offset, limit int, mtime int64) (*baidupan.FileListAllResponse, error) {
 	return nil, nil
 }
+
+// 读取蓝光原盘的播放列表
+func (d *openListDriver) ReadBlurayPlaylists(ctx context.Context, playlistPath string) []*models.BlurayPlaylist {
+	resp, err := d.client.FileList(ctx, playlistPath, 1, 1000)
+	if err != nil {
+		return nil
+	}
+	files := make([]*models.BlurayPlaylistFile, 0)
+	for _, file := range resp.Content {
+		if file.IsDir || !models.IsBlurayPlaylistFile(file.Name) {
+			continue
+		}
+		files = append(files, &models.BlurayPlaylistFile{Id: filepath.ToSlash(filepath.Join(playlistPath, file.Name)), Name: file.Name, Size: file.Size})
+	}
+	return models.ReadBlurayPlaylists(files, func(file *models.BlurayPlaylistFile) ([]byte, error) {
+		rawUrl := d.client.GetRawUrl(file.Id)
+		if rawUrl == "" {
+			return nil, fmt.Errorf("获取openlist文件下载链接失败, url为空")
+		}
+		return helpers.ReadFromUrl(rawUrl, v115open.DEFAULTUA)
+	})
+}
@@ -0,0 +1,186 @@
+package syncstrm
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 重复文件查找和删除任务的状态
+type DuplicateJobStatus struct {
+	Running    bool   `json:"running"`     // 是否正在执行
+	Action     string `json:"action"`      // 正在执行的操作：find-查找 delete-删除
+	StartTime  int64  `json:"start_time"`  // 开始时间
+	EndTime    int64  `json:"end_time"`    // 结束时间
+	GroupCount int    `json:"group_count"` // 查找到的重复文件组数，或者要删除的组数
+	Deleted    int    `json:"deleted"`     // 已删除的文件数
+	Failed     int    `json:"failed"`      // 删除失败的文件数
+	Error      string `json:"error"`       // 查找失败的原因
+}
+
+var duplicateJob = &DuplicateJobStatus{}
+var duplicateJobMutex sync.Mutex
+
+func GetDuplicateJobStatus() DuplicateJobStatus {
+	duplicateJobMutex.Lock()
+	defer duplicateJobMutex.Unlock()
+	return *duplicateJob
+}
+
+func startDuplicateJob(action string) error {
+	duplicateJobMutex.Lock()
+	defer duplicateJobMutex.Unlock()
+	if duplicateJob.Running {
+		return errors.New("重复文件任务正在执行，请稍后再试")
+	}
+	duplicateJob = &DuplicateJobStatus{Running: true, Action: action, StartTime: time.Now().Unix()}
+	return nil
+}
+
+func finishDuplicateJob() {
+	duplicateJobMutex.Lock()
+	defer duplicateJobMutex.Unlock()
+	duplicateJob.Running = false
+	duplicateJob.EndTime = time.Now().Unix()
+}
+
+// 在后台查找重复文件，按策略预选保留的文件，等待用户确认
+func StartFindDuplicates(policy models.DuplicatePolicy, preferredAccountId uint) error {
+	if err := startDuplicateJob("find"); err != nil {
+		return err
+	}
+	go func() {
+		defer finishDuplicateJob()
+		count, err := models.FindDuplicates(policy, preferredAccountId)
+		duplicateJobMutex.Lock()
+		duplicateJob.GroupCount = count
+		if err != nil {
+			duplicateJob.Error = err.Error()
+		}
+		duplicateJobMutex.Unlock()
+		if err != nil {
+			helpers.AppLogger.Errorf("查找重复文件失败: %v", err)
+			return
+		}
+		helpers.AppLogger.Infof("查找重复文件完成，共 %d 组", count)
+	}()
+	return nil
+}
+
+// 在后台删除已确认的重复文件组中不保留的文件
+// 通过各来源同步驱动的删除方法删除网盘或者本地文件，成功后清理同步记录和STRM文件
+func StartDeleteDuplicates(groupIds []uint) error {
+	groups := make([]*models.DuplicateGroup, 0, len(groupIds))
+	for _, id := range groupIds {
+		group := models.GetDuplicateGroupById(id)
+		if group == nil {
+			return fmt.Errorf("重复文件组 %d 不存在", id)
+		}
+		if group.Status != models.DuplicateGroupStatusPending && group.Status != models.DuplicateGroupStatusFailed {
+			return fmt.Errorf("重复文件组 %s 不是待确认状态", group.Name)
+		}
+		groups = append(groups, group)
+	}
+	if len(groups) == 0 {
+		return errors.New("请选择要删除的重复文件组")
+	}
+	if err := startDuplicateJob("delete"); err != nil {
+		return err
+	}
+	for _, group := range groups {
+		group.UpdateStatus(models.DuplicateGroupStatusDeleting)
+	}
+	duplicateJobMutex.Lock()
+	duplicateJob.GroupCount = len(groups)
+	duplicateJobMutex.Unlock()
+	go func() {
+		defer finishDuplicateJob()
+		totalDeleted, totalFailed := 0, 0
+		for _, group := range groups {
+			deleted, failed := deleteDuplicateGroup(group)
+			totalDeleted += deleted
+			totalFailed += failed
+			duplicateJobMutex.Lock()
+			duplicateJob.Deleted = totalDeleted
+			duplicateJob.Failed = totalFailed
+			duplicateJobMutex.Unlock()
+		}
+		helpers.AppLogger.Infof("删除重复文件完成，%d 组，删除 %d 个文件，失败 %d 个", len(groups), totalDeleted, totalFailed)
+	}()
+	return nil
+}
+
+func deleteDuplicateGroup(group *models.DuplicateGroup) (deleted, failed int) {
+	kept := 0
+	for _, f := range group.Files {
+		if !f.Keep {
+			continue
+		}
+		if f.IsDeletedElsewhere() {
+			helpers.AppLogger.Warnf("重复文件组 %s 保留的文件 %s 已在其他组中删除，跳过该组", group.Name, f.FileName)
+			group.UpdateStatus(models.DuplicateGroupStatusFailed)
+			return 0, 0
+		}
+		kept++
+	}
+	if kept == 0 {
+		helpers.AppLogger.Warnf("重复文件组 %s 没有保留的文件，跳过该组", group.Name)
+		group.UpdateStatus(models.DuplicateGroupStatusFailed)
+		return 0, 0
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	drivers := make(map[uint]driverImpl)
+	for _, f := range group.Files {
+		if f.Keep || f.Status == models.DuplicateFileStatusDeleted {
+			continue
+		}
+		if err := deleteDuplicateFile(ctx, drivers, f); err != nil {
+			helpers.AppLogger.Errorf("删除重复文件 %s 失败: %v", f.FileName, err)
+			f.UpdateStatus(models.DuplicateFileStatusFailed, err.Error())
+			failed++
+			continue
+		}
+		helpers.AppLogger.Infof("已删除重复文件 %s，保留同组的其他文件", f.FileId)
+		f.UpdateStatus(models.DuplicateFileStatusDeleted, "")
+		f.CleanupRecords()
+		deleted++
+	}
+	if failed > 0 {
+		group.UpdateStatus(models.DuplicateGroupStatusFailed)
+	} else {
+		group.UpdateStatus(models.DuplicateGroupStatusDone)
+	}
+	return deleted, failed
+}
+
+func deleteDuplicateFile(ctx context.Context, drivers map[uint]driverImpl, f *models.DuplicateFile) error {
+	driver, ok := drivers[f.AccountId]
+	if !ok {
+		account := &models.Account{SourceType: models.SourceTypeLocal}
+		if f.AccountId > 0 {
+			var err error
+			account, err = models.GetAccountById(f.AccountId)
+			if err != nil {
+				return fmt.Errorf("账号 %d 不存在", f.AccountId)
+			}
+		}
+		driver = newSyncDriver(account)
+		if driver == nil {
+			return fmt.Errorf("不支持的来源类型 %s", account.SourceType)
+		}
+		drivers[f.AccountId] = driver
+	}
+	if err := driver.DeleteFile(ctx, f.ParentId, []string{f.FileId}); err != nil {
+		return err
+	}
+	// 本地驱动删除失败只记录日志，这里再检查一次
+	if f.SourceType == models.SourceTypeLocal && helpers.PathExists(f.FileId) {
+		return errors.New("文件仍然存在")
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/models"
 	"Q115-STRM/internal/v115open"
+	ws "Q115-STRM/internal/websocket"
 	"context"
 	"errors"
 	"fmt"
@@ -14,10 +15,17 @@ import (
 	"runtime"
 	"slices"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 )
 
+// 文件处理进度事件的最小推送间隔
+const fileProgressInterval = time.Second
+
+// 每次同步最多记录的新增、删除STRM文件路径数量
+const maxRecordedStrmPaths = 5000
+
 type driverImpl interface {
 	GetNetFileFiles(ctx context.Context, parentPath, parentPathId string) ([]*SyncFileCache, error)
 	GetPathIdByPath(ctx context.Context, path string) (string, error)
@@ -32,6 +40,8 @@ type driverImpl interface {
 	DetailByFileId(ctx context.Context, fileId string) (*SyncFileCache, error)
 	// 删除目录下的某些文件
 	DeleteFile(ctx context.Context, parentId string, fileIds []string) error
+	// 读取蓝光原盘BDMV/PLAYLIST目录中的播放列表
+	ReadBlurayPlaylists(ctx context.Context, playlistPath string) []*models.BlurayPlaylist
 }
 
 type SyncStrm struct {
@@ -63,6 +73,15 @@ type SyncStrm struct {
 	NewUpload int64
 	TotalFile int64
 
+	// 上次推送文件进度事件的时间（UnixNano），用来限制推送频率
+	lastProgressAt atomic.Int64
+
+	// 本次同步新增和删除的STRM文件，Webhook使用
+	strmPathsMu        sync.Mutex
+	NewStrmPaths       []string
+	RemovedStrmPaths   []string
+	StrmPathsTruncated bool // 文件太多，只记录了一部分
+
 	// 停止状态：避免多次触发停止
 	stopped atomic.Bool
 
@@ -70,6 +89,40 @@ type SyncStrm struct {
 	sync115 *Sync115
 
 	memSyncCache *MemorySyncCache // 同步缓存
+
+	newVideoFiles []*models.SyncFile // 差异处理时新增的视频文件，用来直接生成关联刮削目录的待刮削记录
+}
+
+// 记录新增或删除的STRM文件
+func (s *SyncStrm) recordStrmPath(removed bool, path string) {
+	s.strmPathsMu.Lock()
+	defer s.strmPathsMu.Unlock()
+	if len(s.NewStrmPaths)+len(s.RemovedStrmPaths) >= maxRecordedStrmPaths {
+		s.StrmPathsTruncated = true
+		return
+	}
+	if removed {
+		s.RemovedStrmPaths = append(s.RemovedStrmPaths, path)
+	} else {
+		s.NewStrmPaths = append(s.NewStrmPaths, path)
+	}
+}
+
+// 推送文件处理进度，文件很多时每秒最多推送一次
+func (s *SyncStrm) broadcastFileProgress(action, fileName string) {
+	now := time.Now().UnixNano()
+	last := s.lastProgressAt.Load()
+	if now-last < int64(fileProgressInterval) || !s.lastProgressAt.CompareAndSwap(last, now) {
+		return
+	}
+	ws.BroadcastEvent(ws.EventStrmSyncFileProgress, ws.StrmSyncFileProgressData{
+		TaskID:    s.SyncPathId,
+		Action:    action,
+		FileName:  fileName,
+		TotalFile: atomic.LoadInt64(&s.TotalFile),
+		NewStrm:   atomic.LoadInt64(&s.NewStrm),
+		NewMeta:   atomic.LoadInt64(&s.NewMeta),
+	})
 }
 
 type pathQueueItem struct {
@@ -79,18 +132,23 @@ type pathQueueItem struct {
 	Mtime  int64  // 最后修改时间
 }
 
-func NewSyncStrm(account *models.Account, syncPathId uint, sourcePath, sourcePathId, targetPath string, config SyncStrmConfig, IsFullSync bool, lastSyncAt int64, isFile bool) *SyncStrm {
-	var syncDriver driverImpl
+// 根据账号类型创建驱动
+func newSyncDriver(account *models.Account) driverImpl {
 	switch account.SourceType {
 	case models.SourceType115:
-		syncDriver = NewOpen115Driver(account.Get115Client())
+		return NewOpen115Driver(account.Get115Client())
 	case models.SourceTypeOpenList:
-		syncDriver = NewOpenListDriver(account.GetOpenListClient())
+		return NewOpenListDriver(account.GetOpenListClient())
 	case models.SourceTypeLocal:
-		syncDriver = NewLocalDriver()
+		return NewLocalDriver()
 	case models.SourceTypeBaiduPan:
-		syncDriver = NewBaiduPanDriver(account.GetBaiDuPanClient())
+		return NewBaiduPanDriver(account.GetBaiDuPanClient())
 	}
+	return nil
+}
+
+func NewSyncStrm(account *models.Account, syncPathId uint, sourcePath, sourcePathId, targetPath string, config SyncStrmConfig, IsFullSync bool, lastSyncAt int64, isFile bool) *SyncStrm {
+	syncDriver := newSyncDriver(account)
 	pathWorkerMax := int64(models.SettingsGlobal.FileDetailThreads)
 	switch account.SourceType {
 	case models.SourceTypeLocal:
@@ -227,6 +285,9 @@ func (s *SyncStrm) Start() error {
 	atomic.StoreInt64(&s.NewStrm, 0)
 	atomic.StoreInt64(&s.NewUpload, 0)
 	atomic.StoreInt64(&s.TotalFile, 0)
+	s.strmPathsMu.Lock()
+	s.NewStrmPaths, s.RemovedStrmPaths, s.StrmPathsTruncated = nil, nil, false
+	s.strmPathsMu.Unlock()
 	s.Sync.Logger.Infof("本次同步的入口目录：%s，目标目录：%s", s.SourcePath, s.TargetPath)
 	s.Sync.Logger.Infof("本次同步使用的STRM配置%+v", s.Config)
 	s.Sync.UpdateStatus(models.SyncStatusInProgress)
@@ -292,6 +353,8 @@ func (s *SyncStrm) Start() error {
 				syncPath.UpdateLastSync()
 			}
 		}
+		// 蓝光原盘只生成一个指向主影片流的STRM文件
+		s.processBlurayDiscStrm()
 		// 开始添加需要下载的文件到下载队列
 		s.Sync.Logger.Info("开始将要下载的任务添加到下载队列")
 		s.AddDownloadTaskFromMemCache()
@@ -320,25 +383,14 @@ func (s *SyncStrm) Start() error {
 				models.RefreshEmbyLibraryBySyncPathId(s.SyncPathId)
 
 			}
-			if s.NewStrm > 0 {
-				s.Sync.Logger.Info("准备触发关联的刮削任务")
-				syncPath := models.GetSyncPathById(s.SyncPathId)
-				scrapePathIds := syncPath.GetScrapePathIds()
-				if len(scrapePathIds) > 0 {
-					// 发送异步消息，防止循环引用
-					helpers.Publish(helpers.StrmSyncCompleteEvent, scrapePathIds)
-				} else {
-					s.Sync.Logger.Info("关联的刮削目录为空，跳过触发刮削任务")
-				}
-			} else {
-				s.Sync.Logger.Info("没有新的strm生成，跳过关联的刮削任务")
-			}
 		}()
 		// 处理差异
 		go func() {
 			s.Sync.Logger.Info("115路径和文件同步完成，开始处理SyncFile表和临时表的数据差异")
-			s.handleTempTableDiff()
+			err := s.handleTempTableDiff()
 			s.Sync.Logger.Info("完成差异比对，并更新了SyncFile表，任务彻底完成")
+			// 差异处理完成后新增的文件都已经写入SyncFile表，再触发关联的刮削任务
+			s.triggerScrapeAfterSync(err == nil)
 		}()
 	}
 	return nil
@@ -374,6 +426,10 @@ func (s *SyncStrm) processNetFile(file *SyncFileCache) error {
 			s.Sync.Logger.Infof("文件ID %s 所在目录 %s 包含 ** 号，跳过生成strm", file.FileId, file.Path)
 			return nil
 		}
+		if models.IsBlurayStreamPath(file.GetPath()) {
+			// 蓝光原盘的视频流在所有文件处理完成后统一生成一个STRM
+			return nil
+		}
 		return s.ProcessStrmFile(file)
 	}
 	// 再处理元数据文件
@@ -442,6 +498,7 @@ func (s *SyncStrm) AddDownloadTaskFromMemCache() {
 		if err == nil {
 			s.Sync.Logger.Infof("添加下载任务成功: %s=>%s", file.Path+"/"+file.FileName, file.GetLocalFilePath(s.TargetPath, s.SourcePath))
 			atomic.AddInt64(&s.NewMeta, 1)
+			s.broadcastFileProgress("meta", file.FileName)
 		}
 	}
 	s.memSyncCache.mu.RUnlock()
@@ -520,6 +577,10 @@ func (s *SyncStrm) compareLocalFilesWithTempTable() error {
 					}
 					// s.Sync.Logger.Warnf("本地文件在网盘不存在，删除本地STRM文件: %s", path)
 					s.RemoveFileAndCheckDirEmtry(path)
+					// 删除空目录失败时也会返回错误，以文件是否还存在为准
+					if _, statErr := os.Stat(path); os.IsNotExist(statErr) {
+						s.recordStrmPath(true, path)
+					}
 					return nil
 				}
 				if isMeta {
@@ -650,6 +711,56 @@ func (s *SyncStrm) compareLocalFilesWithTempTable() error {
 	return nil
 }
 
+// 触发关联的刮削任务
+// 能直接使用同步结果的刮削目录，把新增的视频文件入库为待刮削记录，刮削时跳过扫描，避免再完整列一遍网盘目录
+// 差异处理失败时新增文件可能不完整，全部走扫描
+func (s *SyncStrm) triggerScrapeAfterSync(diffOk bool) {
+	defer func() {
+		s.newVideoFiles = nil
+	}()
+	if atomic.LoadInt64(&s.NewStrm) == 0 {
+		s.Sync.Logger.Info("没有新的strm生成，跳过关联的刮削任务")
+		return
+	}
+	s.Sync.Logger.Info("准备触发关联的刮削任务")
+	syncPath := models.GetSyncPathById(s.SyncPathId)
+	if syncPath == nil {
+		return
+	}
+	scrapePathIds := syncPath.GetScrapePathIds()
+	if len(scrapePathIds) == 0 {
+		s.Sync.Logger.Info("关联的刮削目录为空，跳过触发刮削任务")
+		return
+	}
+	data := helpers.StrmSyncCompleteData{}
+	for _, scrapePathId := range scrapePathIds {
+		scrapePath := models.GetScrapePathByID(scrapePathId)
+		if scrapePath == nil {
+			continue
+		}
+		if !diffOk || !scrapePath.CanEnqueueFromSync(syncPath) {
+			data.ScrapePathIds = append(data.ScrapePathIds, scrapePathId)
+			continue
+		}
+		count, needScan := scrapePath.EnqueueSyncFiles(s.newVideoFiles)
+		if needScan {
+			s.Sync.Logger.Infof("刮削目录 %s 有新增的蓝光原盘，触发完整扫描", scrapePath.SourcePath)
+			data.ScrapePathIds = append(data.ScrapePathIds, scrapePathId)
+			continue
+		}
+		if count == 0 {
+			s.Sync.Logger.Infof("刮削目录 %s 没有新增的视频文件，跳过触发刮削任务", scrapePath.SourcePath)
+			continue
+		}
+		s.Sync.Logger.Infof("已将 %d 个新增的视频文件加入刮削目录 %s 的待刮削列表", count, scrapePath.SourcePath)
+		data.SkipScanIds = append(data.SkipScanIds, scrapePathId)
+	}
+	if len(data.ScrapePathIds) > 0 || len(data.SkipScanIds) > 0 {
+		// 发送异步消息，防止循环引用
+		helpers.Publish(helpers.StrmSyncCompleteEvent, data)
+	}
+}
+
 // 处理SyncFile表和内存同步缓存的数据差异
 // 临时表存在SyncFile没有的插入
 // 临时表没有SyncFile有的删除
@@ -766,6 +877,9 @@ func (s *SyncStrm) handleTempTableDiff() error {
 			continue
 		}
 		// s.Sync.Logger.Infof("插入SyncFile表数据成功 FileID=%s", file.GetFileId())
+		if syncFile.IsVideo {
+			s.newVideoFiles = append(s.newVideoFiles, syncFile)
+		}
 		// 插入成功后，从同步缓存中移除该记录
 		s.memSyncCache.DeleteByFileId(file.GetFileId())
 		if i == 10 {
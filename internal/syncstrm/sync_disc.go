@@ -0,0 +1,76 @@
+package syncstrm
+
+import (
+	"Q115-STRM/internal/models"
+	"path/filepath"
+	"strings"
+)
+
+// 蓝光原盘的主影片流，取最长的播放列表中的主要视频流
+// 没有可读的播放列表或者视频流不在列表中时，取最大的m2ts文件
+func mainBlurayStream(streams []*SyncFileCache, playlists []*models.BlurayPlaylist) *SyncFileCache {
+	if clip := models.MainBlurayClip(playlists); clip != "" {
+		for _, file := range streams {
+			if strings.EqualFold(file.FileName, clip) {
+				return file
+			}
+		}
+	}
+	var mainStream *SyncFileCache
+	for _, file := range streams {
+		if !strings.EqualFold(filepath.Ext(file.FileName), ".m2ts") {
+			continue
+		}
+		if mainStream == nil || file.FileSize > mainStream.FileSize {
+			mainStream = file
+		}
+	}
+	return mainStream
+}
+
+// 蓝光原盘STRM文件的本地路径，放在原盘所在目录中，使用目录名命名
+func (s *SyncStrm) blurayDiscStrmPath(file *SyncFileCache) string {
+	discRoot := models.BlurayDiscRoot(file.GetPath())
+	fileName := filepath.Base(discRoot) + ".strm"
+	fullPath := filepath.Join(s.TargetPath, discRoot, fileName)
+	if file.SourceType == models.SourceTypeLocal {
+		relPath, err := filepath.Rel(s.SourcePath, discRoot)
+		if err != nil {
+			return ""
+		}
+		fullPath = filepath.Join(s.TargetPath, relPath, fileName)
+	}
+	return filepath.ToSlash(fullPath)
+}
+
+// 蓝光原盘 xxx/BDMV/STREAM/*.m2ts 整个原盘只生成一个STRM文件，指向主影片流
+// 其他视频流不生成STRM，之前生成的STRM在对比本地文件时删除
+func (s *SyncStrm) processBlurayDiscStrm() {
+	for _, streams := range s.memSyncCache.GetBlurayStreams() {
+		playlistPath := models.BlurayPlaylistPath(streams[0].GetPath())
+		if streams[0].SourceType != models.SourceTypeLocal {
+			playlistPath = filepath.ToSlash(playlistPath)
+		}
+		mainStream := mainBlurayStream(streams, s.SyncDriver.ReadBlurayPlaylists(s.Context, playlistPath))
+		for _, file := range streams {
+			if file != mainStream {
+				file.GetLocalFilePath(s.TargetPath, s.SourcePath)
+				s.memSyncCache.RemoveLocalPathIndex(file)
+			}
+		}
+		if mainStream == nil {
+			continue
+		}
+		strmPath := s.blurayDiscStrmPath(mainStream)
+		if strmPath == "" {
+			continue
+		}
+		mainStream.GetLocalFilePath(s.TargetPath, s.SourcePath)
+		s.memSyncCache.UpdateLocalFilePath(mainStream, strmPath)
+		if err := s.ProcessStrmFile(mainStream); err != nil {
+			s.Sync.Logger.Errorf("生成蓝光原盘 %s 的STRM文件失败: %v", models.BlurayDiscRoot(mainStream.GetPath()), err)
+			continue
+		}
+		s.Sync.Logger.Infof("蓝光原盘 %s 使用主影片流 %s 生成STRM文件 %s", models.BlurayDiscRoot(mainStream.GetPath()), mainStream.FileName, strmPath)
+	}
+}
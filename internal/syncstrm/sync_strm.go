@@ -53,6 +53,8 @@ func (s *SyncStrm) ProcessStrmFile(sf *SyncFileCache) error {
 	}
 	s.Sync.Logger.Infof("[生成strm] %s => %s", strmFullPath, strmContent)
 	atomic.AddInt64(&s.NewStrm, 1)
+	s.recordStrmPath(false, strmFullPath)
+	s.broadcastFileProgress("strm", sf.FileName)
 	return nil
 }
 
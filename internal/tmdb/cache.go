@@ -0,0 +1,128 @@
+package tmdb
+
+import (
+	"Q115-STRM/internal/helpers"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"resty.dev/v3"
+)
+
+// 离线模式下本地没有缓存时返回该错误
+var ErrOffline = errors.New("离线模式，本地没有缓存的TMDB数据")
+
+// TMDB接口响应缓存，由调用方提供持久化实现
+type Cache interface {
+	// 返回缓存的数据和写入时间（秒级时间戳）
+	Get(key string) ([]byte, int64, bool)
+	Set(key string, data []byte)
+}
+
+// 缓存有效期，按接口类型区分
+// 搜索结果和剧集的季、集信息变化较快，详情、演职人员、图片、分级变化较慢
+var cacheTTLs = []struct {
+	keyword string
+	ttl     time.Duration
+}{
+	{"/search/", 24 * time.Hour},
+	{"/season/", 24 * time.Hour},
+	{"/configuration", 7 * 24 * time.Hour},
+	{"/movie/", 30 * 24 * time.Hour},
+	{"/tv/", 7 * 24 * time.Hour},
+	{"/find/", 30 * 24 * time.Hour},
+}
+
+// 不缓存的接口
+var noCacheUrls = []string{"/authentication"}
+
+func (c *Client) SetCache(cache Cache) {
+	c.cache = cache
+}
+
+// 离线模式只使用本地缓存，不请求TMDB，缓存过期也继续使用
+func (c *Client) SetOffline(offline bool) {
+	c.offline = offline
+}
+
+func (c *Client) IsOffline() bool {
+	return c.offline
+}
+
+func cacheTTL(path string) time.Duration {
+	for _, item := range cacheTTLs {
+		if strings.Contains(path, item.keyword) {
+			return item.ttl
+		}
+	}
+	return 24 * time.Hour
+}
+
+// 缓存键：接口地址 + 排序后的查询参数（包含语言），不包含api_key
+func cacheKey(path string, query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		if k == "api_key" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	sb.WriteString(path)
+	for i, k := range keys {
+		if i == 0 {
+			sb.WriteString("?")
+		} else {
+			sb.WriteString("&")
+		}
+		sb.WriteString(k)
+		sb.WriteString("=")
+		sb.WriteString(strings.Join(query[k], ","))
+	}
+	return sb.String()
+}
+
+func cacheable(path string, req *resty.Request) bool {
+	if req.Method != "GET" || req.Result == nil {
+		return false
+	}
+	for _, u := range noCacheUrls {
+		if strings.HasPrefix(path, u) {
+			return false
+		}
+	}
+	return true
+}
+
+// 从缓存中读取数据写入req.Result，返回一个200的响应，调用方不需要区分是否来自缓存
+func (c *Client) responseFromCache(req *resty.Request, data []byte) (*resty.Response, error) {
+	if err := json.Unmarshal(data, req.Result); err != nil {
+		return nil, err
+	}
+	return &resty.Response{
+		Request: req,
+		Body:    io.NopCloser(bytes.NewReader(data)),
+		RawResponse: &http.Response{
+			Status:     "200 OK",
+			StatusCode: http.StatusOK,
+		},
+	}, nil
+}
+
+// 请求成功后写入缓存，保存原始响应，读取时再按调用方的结构解析
+// 不能保存解析后的结构体，结构体中没有定义的字段会丢失，换一个结构体读取同一个接口时数据不完整
+func (c *Client) saveCache(key string, resp *resty.Response) {
+	data := resp.Bytes()
+	if len(data) == 0 || !json.Valid(data) {
+		helpers.TMDBLog.Warnf("缓存TMDB响应失败 %s: 响应不是有效的JSON", key)
+		return
+	}
+	c.cache.Set(key, data)
+}
@@ -0,0 +1,93 @@
+package tmdb
+
+import (
+	"Q115-STRM/internal/helpers"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+	"resty.dev/v3"
+)
+
+type memoryCache struct {
+	data map[string][]byte
+	at   map[string]int64
+}
+
+func (m *memoryCache) Get(key string) ([]byte, int64, bool) {
+	data, ok := m.data[key]
+	return data, m.at[key], ok
+}
+
+func (m *memoryCache) Set(key string, data []byte) {
+	m.data[key] = data
+	m.at[key] = time.Now().Unix()
+}
+
+func newTestClient(t *testing.T, hits *int32) (*Client, *httptest.Server) {
+	helpers.TMDBLog = &helpers.QLogger{Logger: log.New(io.Discard, "", 0)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":157336,"title":"星际穿越","release_date":"2014-11-07","runtime":169,"not_in_struct":"keep"}`))
+	}))
+	t.Cleanup(server.Close)
+	client := &Client{
+		resty:       resty.New(),
+		rateLimiter: rate.NewLimiter(rate.Inf, 1),
+	}
+	client.SetBaseUrl(server.URL)
+	client.SetCache(&memoryCache{data: map[string][]byte{}, at: map[string]int64{}})
+	return client, server
+}
+
+func TestCacheAndOffline(t *testing.T) {
+	var hits int32
+	client, _ := newTestClient(t, &hits)
+	for i := 0; i < 2; i++ {
+		detail, err := client.GetMovieDetail(157336, "zh-CN")
+		if err != nil || detail.Title != "星际穿越" || detail.Runtime != 169 {
+			t.Fatalf("查询电影详情失败: %+v %v", detail, err)
+		}
+	}
+	if hits != 1 {
+		t.Errorf("第二次查询应该使用缓存, 实际请求次数: %d", hits)
+	}
+	// 缓存原始响应，结构体中没有的字段也要保留
+	for _, data := range client.cache.(*memoryCache).data {
+		if !strings.Contains(string(data), `"not_in_struct":"keep"`) {
+			t.Errorf("缓存的应该是原始响应: %s", string(data))
+		}
+	}
+	client.SetOffline(true)
+	if detail, err := client.GetMovieDetail(157336, "zh-CN"); err != nil || detail.Title != "星际穿越" {
+		t.Errorf("离线模式应该使用缓存: %+v %v", detail, err)
+	}
+	// 语言不同，缓存键不同
+	if _, err := client.GetMovieDetail(157336, "en-US"); err != ErrOffline {
+		t.Errorf("离线模式没有缓存时应该返回ErrOffline, 实际: %v", err)
+	}
+	if hits != 1 {
+		t.Errorf("离线模式不应该请求TMDB, 实际请求次数: %d", hits)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	query := url.Values{}
+	query.Set("query", "星际穿越")
+	query.Set("api_key", "secret")
+	query.Set("language", "zh-CN")
+	if key := cacheKey("/search/movie", query); key != "/search/movie?language=zh-CN&query=星际穿越" {
+		t.Errorf("缓存键不正确: %s", key)
+	}
+	if ttl := cacheTTL("/tv/1399/season/1?language=zh-CN"); ttl != 24*time.Hour {
+		t.Errorf("季信息的缓存有效期不正确: %v", ttl)
+	}
+}
@@ -31,6 +31,8 @@ type Client struct {
 	language    string
 	proxyUrl    string
 	rateLimiter *rate.Limiter
+	cache       Cache // 响应缓存，为nil时不缓存
+	offline     bool  // 离线模式，只使用缓存
 }
 
 var GlobalTmdbClient *Client
@@ -137,6 +139,25 @@ func (c *Client) doRequest(url string, req *resty.Request, options *RequestConfi
 	if options == nil {
 		options = DefaultRequestConfig()
 	}
+	// 优先使用未过期的缓存，离线模式下忽略有效期
+	var cacheKeyStr string
+	var cached []byte
+	useCache := c.cache != nil && cacheable(url, req)
+	if useCache {
+		cacheKeyStr = cacheKey(url, req.QueryParams)
+		data, cachedAt, ok := c.cache.Get(cacheKeyStr)
+		if ok && (c.offline || time.Since(time.Unix(cachedAt, 0)) < cacheTTL(url)) {
+			helpers.TMDBLog.Infof("使用缓存的TMDB数据 %s", cacheKeyStr)
+			return c.responseFromCache(req, data)
+		}
+		cached = data
+		// 自动解析结果后仍然保留响应内容，用于写入缓存
+		req.SetResponseBodyUnlimitedReads(true)
+	}
+	if c.offline {
+		helpers.TMDBLog.Warnf("离线模式，没有缓存的TMDB数据 %s", url)
+		return nil, ErrOffline
+	}
 	// 设置超时时间
 	req.SetTimeout(options.Timeout)
 	var lastErr error
@@ -144,6 +165,9 @@ func (c *Client) doRequest(url string, req *resty.Request, options *RequestConfi
 		resp, err := c.request(url, req)
 		if err == nil {
 			// 正常返回
+			if useCache && resp.IsSuccess() {
+				c.saveCache(cacheKeyStr, resp)
+			}
 			return resp, nil
 		}
 		lastErr = err
@@ -159,6 +183,11 @@ func (c *Client) doRequest(url string, req *resty.Request, options *RequestConfi
 			time.Sleep(options.RetryDelay)
 		}
 	}
+	// 无法访问TMDB时使用过期的缓存
+	if len(cached) > 0 {
+		helpers.TMDBLog.Warnf("请求TMDB失败，使用过期的缓存数据 %s, 错误:%+v", cacheKeyStr, lastErr)
+		return c.responseFromCache(req, cached)
+	}
 	return nil, lastErr
 }
 
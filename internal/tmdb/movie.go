@@ -52,16 +52,24 @@ type Language struct {
 // 电影详情
 type MovieDetail struct {
 	SearchMovie
-	Genres              []Genre             `json:"genres"`               // 流派
-	ProductionCompanies []ProductionCompany `json:"production_companies"` // 生产公司
-	ProductionCountries []Country           `json:"production_countries"` // 生产国家
-	Revenue             int64               `json:"revenue"`              // 票房
-	Runtime             int64               `json:"runtime"`              // 运行时间
-	SpokenLanguages     []Language          `json:"spoken_languages"`     //  口语化语言
-	Status              string              `json:"status"`               // 状态
-	Tagline             string              `json:"tagline"`              // 标语
-	Homepage            string              `json:"homepage"`             // 首页
-	ImdbID              string              `json:"imdb_id"`              // IMDB ID
+	Genres              []Genre             `json:"genres"`                // 流派
+	ProductionCompanies []ProductionCompany `json:"production_companies"`  // 生产公司
+	ProductionCountries []Country           `json:"production_countries"`  // 生产国家
+	Revenue             int64               `json:"revenue"`               // 票房
+	Runtime             int64               `json:"runtime"`               // 运行时间
+	SpokenLanguages     []Language          `json:"spoken_languages"`      //  口语化语言
+	Status              string              `json:"status"`                // 状态
+	Tagline             string              `json:"tagline"`               // 标语
+	Homepage            string              `json:"homepage"`              // 首页
+	ImdbID              string              `json:"imdb_id"`               // IMDB ID
+	BelongsToCollection *Collection         `json:"belongs_to_collection"` // 所属合集
+}
+
+type Collection struct {
+	ID           int64  `json:"id"`            // 合集ID
+	Name         string `json:"name"`          // 合集名称
+	PosterPath   string `json:"poster_path"`   // 合集海报
+	BackdropPath string `json:"backdrop_path"` // 合集背景
 }
 
 type PeopleBase struct {
@@ -66,6 +66,9 @@ type TvDetail struct {
 	Tagline             string              `json:"tagline"`              // 标语
 	Type                string              `json:"type"`                 // 类型
 	Homepage            string              `json:"homepage"`             // 首页
+	InProduction        bool                `json:"in_production"`        // 是否还在制作
+	LastEpisodeToAir    *SeasonEpisode      `json:"last_episode_to_air"`  // 最近播出的一集
+	NextEpisodeToAir    *SeasonEpisode      `json:"next_episode_to_air"`  // 下一集
 }
 
 type TvKeywords struct {
@@ -91,17 +94,26 @@ type Episode struct {
 	Cast           []Cast  `json:"cast"`            // 集 cast 列表
 }
 
+// 集的播出信息，用于统计缺集
+type SeasonEpisode struct {
+	AirDate       string `json:"air_date"`       // 播出时间
+	EpisodeNumber int    `json:"episode_number"` // 集编号
+	SeasonNumber  int    `json:"season_number"`  // 季编号
+	Name          string `json:"name"`           // 集名称
+	EpisodeType   string `json:"episode_type"`   // 集类型，例如：finale
+}
+
 type SeasonDetail struct {
-	ID           int64  `json:"id"`            // 季ID
-	Name         string `json:"name"`          // 季名称
-	Overview     string `json:"overview"`      // 季描述
-	AirDate      string `json:"air_date"`      // 播出时间
-	EpisodeCount int    `json:"episode_count"` // 集数
-	// Episodes     []Episode   `json:"episodes"`      // 集列表，不收集集列表
-	PosterPath   string      `json:"poster_path"`   // 季封面图片
-	SeasonNumber int         `json:"season_number"` // 季编号
-	VoteAverage  float64     `json:"vote_average"`  // 季平均评分
-	Network      []TvNetwork `json:"network"`       // 播放平台
+	ID           int64           `json:"id"`            // 季ID
+	Name         string          `json:"name"`          // 季名称
+	Overview     string          `json:"overview"`      // 季描述
+	AirDate      string          `json:"air_date"`      // 播出时间
+	EpisodeCount int             `json:"episode_count"` // 集数
+	Episodes     []SeasonEpisode `json:"episodes"`      // 集列表，只收集播出信息，不收集完整的集详情
+	PosterPath   string          `json:"poster_path"`   // 季封面图片
+	SeasonNumber int             `json:"season_number"` // 季编号
+	VoteAverage  float64         `json:"vote_average"`  // 季平均评分
+	Network      []TvNetwork     `json:"network"`       // 播放平台
 }
 
 func (c *Client) SearchTv(tvName string, year int, language string, switchLanguage bool) (*SearchTvResponse, error) {
@@ -2,6 +2,7 @@ package v115open
 
 import (
 	"Q115-STRM/internal/helpers"
+	ws "Q115-STRM/internal/websocket"
 	"context"
 	"sync"
 	"time"
@@ -50,10 +51,9 @@ func (tm *ThrottleManager) IsThrottled() bool {
 // MarkThrottled 标记为限流状态，并启动恢复计时器
 func (tm *ThrottleManager) MarkThrottled(stats *RequestStats) {
 	tm.Lock()
-	defer tm.Unlock()
-
 	if tm.isThrottled {
 		// 已经在限流状态，不需要重复标记
+		tm.Unlock()
 		return
 	}
 
@@ -69,6 +69,9 @@ func (tm *ThrottleManager) MarkThrottled(stats *RequestStats) {
 
 	// 启动恢复计时器
 	go tm.startRecoveryTimer()
+	tm.Unlock()
+
+	ws.BroadcastEvent(ws.EventThrottleEnter, ws.ThrottleData{DurationSeconds: tm.throttleDuration.Seconds()})
 }
 
 // startRecoveryTimer 启动恢复计时器
@@ -76,9 +79,8 @@ func (tm *ThrottleManager) startRecoveryTimer() {
 	time.Sleep(tm.throttleDuration)
 
 	tm.Lock()
-	defer tm.Unlock()
-
 	tm.isThrottled = false
+	elapsed := time.Since(tm.throttleStartTime)
 	helpers.V115Log.Infof("限流已恢复，继续处理请求")
 
 	// 发送恢复通知
@@ -87,6 +89,9 @@ func (tm *ThrottleManager) startRecoveryTimer() {
 	default:
 		// 通道已满，不需要发送
 	}
+	tm.Unlock()
+
+	ws.BroadcastEvent(ws.EventThrottleExit, ws.ThrottleData{DurationSeconds: elapsed.Seconds()})
 }
 
 // WaitThrottleRecovery 等待限流恢复，如果当前不在限流状态则立即返回
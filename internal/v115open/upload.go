@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -278,8 +279,65 @@ func (c *OpenClient) GetUploadToken(ctx context.Context) *UploadToken {
 	return respData
 }
 
-// 上传分片
-func (c *OpenClient) UploadResume(ctx context.Context, pickCode string, fileSize int, parentFileId string, fileSha1 string) {
+// 服务器没有相同的文件，不能秒传，需要上传完整文件
+var ErrUploadNeedData = errors.New("不能秒传，需要上传完整文件")
+
+// 只用sha1秒传，文件不在本地时使用（例如从另一个网盘转存过来）
+// readRange读取文件[start, end]的数据（包含end），用来计算pre_id和二次认证
+// 秒传成功返回新的文件ID，服务器没有相同的文件时返回ErrUploadNeedData
+// POST 域名 + /open/upload/init
+func (c *OpenClient) UploadResume(ctx context.Context, fileName string, fileSize int64, parentFileId string, fileSha1 string, readRange func(start, end int64) ([]byte, error)) (string, error) {
+	head, err := readRange(0, 128)
+	if err != nil {
+		helpers.V115Log.Errorf("读取文件 %s 前128位失败: %v", fileName, err)
+		return "", err
+	}
+	params := map[string]string{
+		"file_name": fileName,
+		"file_size": fmt.Sprintf("%d", fileSize),
+		"target":    fmt.Sprintf("U_1_%s", parentFileId),
+		"fileid":    strings.ToUpper(fileSha1),
+		"pre_id":    helpers.SHA1Hash(head),
+		"topupload": "0",
+	}
+	url := fmt.Sprintf("%s/open/upload/init", OPEN_BASE_URL)
+	// 最多二次认证一次
+	for range 2 {
+		req := c.client.R().SetFormData(params).SetMethod("POST")
+		respData := &UploadResult[json.RawMessage]{}
+		_, _, uErr := c.doAuthRequest(ctx, url, req, MakeRequestConfig(1, 1, 15), respData)
+		if uErr != nil {
+			helpers.V115Log.Errorf("秒传 %s 失败: %v", fileName, uErr)
+			return "", uErr
+		}
+		switch respData.Status {
+		case 2:
+			helpers.V115Log.Infof("秒传 %s 成功, 文件ID: %s", fileName, respData.FileId)
+			return respData.FileId, nil
+		case 1:
+			return "", ErrUploadNeedData
+		case 7:
+			signParts := strings.Split(respData.SignCheck, "-")
+			if len(signParts) != 2 {
+				helpers.V115Log.Errorf("签名检查格式错误: %v", signParts)
+				return "", fmt.Errorf("签名检查格式错误: %v", signParts)
+			}
+			data, err := readRange(helpers.StringToInt64(signParts[0]), helpers.StringToInt64(signParts[1]))
+			if err != nil {
+				helpers.V115Log.Errorf("读取文件 %s 的 %s 区间失败: %v", fileName, respData.SignCheck, err)
+				return "", err
+			}
+			params["sign_key"] = respData.SignKey
+			params["sign_val"] = helpers.SHA1Hash(data)
+		case 6:
+			return "", fmt.Errorf("签名验证后失败")
+		case 8:
+			return "", fmt.Errorf("签名认证失败")
+		default:
+			return "", fmt.Errorf("秒传 %s 返回未知状态 %d", fileName, respData.Status)
+		}
+	}
+	return "", fmt.Errorf("秒传 %s 二次认证后仍然需要认证", fileName)
 }
 
 func OssUploadFile(endPoint string, accessKeyId string, accessKeySecret string, securityToken string, bucketName string, objectId string, callback string, callbackVar string, filePath string, fileSize int64, fileSha1 string) (map[string]any, error) {
@@ -0,0 +1,302 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"Q115-STRM/internal/helpers"
+
+	"gorm.io/gorm"
+)
+
+// 投递失败按指数退避重试，程序重启后未完成的投递继续发送
+
+const (
+	queuePollInterval = 5 * time.Second
+	queueBatchSize    = 100
+	sendTimeout       = 15 * time.Second
+	maxSendAttempts   = 6                // 最多尝试次数，超过后标记为失败
+	retryBaseDelay    = 30 * time.Second // 第一次重试的等待时间，之后每次翻倍
+	retryMaxDelay     = 30 * time.Minute
+	responseBodyLimit = 1000                // 投递记录中保存的响应内容长度
+	deliveryRetention = 30 * 24 * time.Hour // 投递记录保留时间
+)
+
+// Dispatcher Webhook发送队列
+type Dispatcher struct {
+	db     *gorm.DB
+	client *http.Client
+	wake   chan struct{}
+	once   sync.Once
+}
+
+// GlobalDispatcher 全局Webhook发送队列
+var GlobalDispatcher *Dispatcher
+
+// NewDispatcher 创建发送队列
+func NewDispatcher(db *gorm.DB) *Dispatcher {
+	return &Dispatcher{
+		db:     db,
+		client: &http.Client{Timeout: sendTimeout},
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Dispatch 把事件放入所有订阅了该事件的Webhook的发送队列，全局队列没有初始化时忽略
+func Dispatch(event string, data any) {
+	if GlobalDispatcher == nil {
+		return
+	}
+	if err := GlobalDispatcher.Dispatch(event, data); err != nil {
+		helpers.AppLogger.Errorf("Webhook事件 %s 写入发送队列失败: %v", event, err)
+	}
+}
+
+// Dispatch 把事件放入所有订阅了该事件的Webhook的发送队列
+func (d *Dispatcher) Dispatch(event string, data any) error {
+	var subs []*Subscription
+	if err := d.db.Where("enabled = ?", true).Find(&subs).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, sub := range subs {
+		if !sub.Match(event) {
+			continue
+		}
+		delivery, err := newDelivery(sub, event, data, now)
+		if err != nil {
+			return err
+		}
+		if err := d.db.Create(delivery).Error; err != nil {
+			return err
+		}
+	}
+	d.wakeQueue()
+	return nil
+}
+
+// 生成投递记录，每个订阅单独一个投递ID
+func newDelivery(sub *Subscription, event string, data any, now time.Time) (*Delivery, error) {
+	deliveryID, err := helpers.UUID()
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(Payload{ID: deliveryID, Event: event, Timestamp: now.Unix(), Data: data})
+	if err != nil {
+		return nil, fmt.Errorf("序列化事件失败: %v", err)
+	}
+	delivery := &Delivery{
+		SubscriptionID: sub.ID,
+		DeliveryID:     deliveryID,
+		Event:          event,
+		Payload:        string(body),
+		Status:         DeliveryPending,
+		NextRetryAt:    now.Unix(),
+	}
+	return delivery, nil
+}
+
+// Ping 给订阅发送一条 ping 事件并等待结果，用来测试地址和签名
+func (d *Dispatcher) Ping(sub *Subscription) (*Delivery, error) {
+	delivery, err := newDelivery(sub, EventPing, map[string]any{"subscription_id": sub.ID, "name": sub.Name}, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	// 发送后才保存投递记录，避免队列同时发送；测试只发送一次，不重试
+	d.deliver(delivery, false)
+	return delivery, nil
+}
+
+// Start 启动发送队列
+func (d *Dispatcher) Start() {
+	d.once.Do(func() {
+		go d.run()
+		helpers.AppLogger.Info("Webhook发送队列已启动")
+	})
+}
+
+func (d *Dispatcher) wakeQueue() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *Dispatcher) run() {
+	helpers.RunQueueLoop(queuePollInterval, d.wake, d.processQueue, d.cleanup)
+}
+
+// 取出到期的投递，每个订阅一个协程按顺序发送，保证同一个订阅收到的事件顺序不变
+// 订阅有等待重试的投递时，后面的投递要等它发送成功或者放弃后才发送
+func (d *Dispatcher) processQueue() {
+	now := time.Now().Unix()
+	var deliveries []*Delivery
+	if err := d.db.Where("status = ? AND next_retry_at <= ?", DeliveryPending, now).
+		Order("id ASC").Limit(queueBatchSize).Find(&deliveries).Error; err != nil {
+		helpers.AppLogger.Errorf("查询待发送Webhook失败: %v", err)
+		return
+	}
+	if len(deliveries) == 0 {
+		return
+	}
+	// 每个订阅还没到重试时间的第一条投递
+	var waiting []struct {
+		SubscriptionID uint
+		FirstID        uint
+	}
+	if err := d.db.Model(&Delivery{}).Select("subscription_id, MIN(id) AS first_id").
+		Where("status = ? AND next_retry_at > ?", DeliveryPending, now).
+		Group("subscription_id").Scan(&waiting).Error; err != nil {
+		helpers.AppLogger.Errorf("查询等待重试的Webhook失败: %v", err)
+		return
+	}
+	blockedFrom := make(map[uint]uint, len(waiting))
+	for _, w := range waiting {
+		blockedFrom[w.SubscriptionID] = w.FirstID
+	}
+	bySub := orderedDeliveries(deliveries, blockedFrom)
+	var wg sync.WaitGroup
+	for _, subDeliveries := range bySub {
+		wg.Add(1)
+		go func(subDeliveries []*Delivery) {
+			defer wg.Done()
+			for _, delivery := range subDeliveries {
+				// 失败后停止发送这个订阅后面的投递，等这条重试
+				if !d.deliver(delivery, true) {
+					return
+				}
+			}
+		}(subDeliveries)
+	}
+	wg.Wait()
+}
+
+// 按订阅分组到期的投递，去掉排在等待重试的投递之后的投递
+func orderedDeliveries(deliveries []*Delivery, blockedFrom map[uint]uint) map[uint][]*Delivery {
+	bySub := make(map[uint][]*Delivery)
+	for _, delivery := range deliveries {
+		if firstID, ok := blockedFrom[delivery.SubscriptionID]; ok && delivery.ID > firstID {
+			continue
+		}
+		bySub[delivery.SubscriptionID] = append(bySub[delivery.SubscriptionID], delivery)
+	}
+	return bySub
+}
+
+// 发送一次并更新投递记录，retry为false时失败后不再重试
+// 返回false表示投递还在等待重试
+func (d *Dispatcher) deliver(delivery *Delivery, retry bool) bool {
+	var sub Subscription
+	if err := d.db.Where("id = ?", delivery.SubscriptionID).First(&sub).Error; err != nil {
+		delivery.Status = DeliveryFailed
+		delivery.LastError = "订阅不存在"
+		d.db.Save(delivery)
+		return true
+	}
+	if !sub.Enabled && delivery.Event != EventPing {
+		delivery.Status = DeliveryFailed
+		delivery.LastError = "订阅已禁用"
+		d.db.Save(delivery)
+		return true
+	}
+
+	start := time.Now()
+	code, body, err := d.post(&sub, delivery)
+	delivery.Attempts++
+	delivery.DurationMs = time.Since(start).Milliseconds()
+	delivery.ResponseCode = code
+	delivery.ResponseBody = body
+	if err == nil {
+		delivery.Status = DeliverySuccess
+		delivery.DeliveredAt = time.Now().Unix()
+		delivery.LastError = ""
+	} else {
+		delivery.LastError = err.Error()
+		if !retry || delivery.Attempts >= maxSendAttempts {
+			delivery.Status = DeliveryFailed
+			helpers.AppLogger.Errorf("Webhook [%s] 事件 %s 发送失败，已尝试 %d 次，不再重试: %v", sub.Name, delivery.Event, delivery.Attempts, err)
+		} else {
+			delay := retryDelay(delivery.Attempts)
+			delivery.NextRetryAt = time.Now().Add(delay).Unix()
+			helpers.AppLogger.Warnf("Webhook [%s] 事件 %s 第 %d 次发送失败，%s 后重试: %v", sub.Name, delivery.Event, delivery.Attempts, delay, err)
+		}
+	}
+	if err := d.db.Save(delivery).Error; err != nil {
+		helpers.AppLogger.Errorf("更新Webhook投递记录失败: %v", err)
+	}
+	return delivery.Status != DeliveryPending
+}
+
+// 发送请求，返回状态码和响应内容，非2xx状态码也返回错误
+func (d *Dispatcher) post(sub *Subscription, delivery *Delivery) (int, string, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", fmt.Errorf("创建请求失败: %v", err)
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "QMediaSync-Webhook")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, delivery.DeliveryID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	if sub.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(sub.Secret, timestamp, body))
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, responseBodyLimit*4))
+	text := string(respBody)
+	if runes := []rune(text); len(runes) > responseBodyLimit {
+		text = string(runes[:responseBodyLimit])
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, text, fmt.Errorf("HTTP状态码 %d", resp.StatusCode)
+	}
+	return resp.StatusCode, text, nil
+}
+
+// 第attempts次失败后的重试等待时间
+func retryDelay(attempts int) time.Duration {
+	return helpers.RetryBackoff(attempts, retryBaseDelay, retryMaxDelay)
+}
+
+// Redeliver 重新发送投递，使用原来的投递ID和请求体
+func (d *Dispatcher) Redeliver(id uint) error {
+	var delivery Delivery
+	if err := d.db.Where("id = ?", id).First(&delivery).Error; err != nil {
+		return fmt.Errorf("投递记录不存在")
+	}
+	if delivery.Status == DeliveryPending {
+		return fmt.Errorf("投递正在等待发送")
+	}
+	delivery.Status = DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextRetryAt = time.Now().Unix()
+	if err := d.db.Save(&delivery).Error; err != nil {
+		return err
+	}
+	d.wakeQueue()
+	return nil
+}
+
+// 删除过期的投递记录，等待发送的保留
+func (d *Dispatcher) cleanup() {
+	result := d.db.Where("status <> ? AND created_at < ?", DeliveryPending, time.Now().Add(-deliveryRetention)).Delete(&Delivery{})
+	if result.Error != nil {
+		helpers.AppLogger.Errorf("清理Webhook投递记录失败: %v", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		helpers.AppLogger.Infof("已清理 %d 条过期的Webhook投递记录", result.RowsAffected)
+	}
+}
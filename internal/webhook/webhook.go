@@ -0,0 +1,165 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============ 自动化Webhook ============
+// 和通知渠道中的自定义Webhook不同，这里推送的是固定结构的JSON，供下载器、Home Assistant等程序处理
+// 每个订阅可以选择关心的事件，请求体使用订阅的密钥做HMAC-SHA256签名
+
+// 事件类型
+const (
+	EventSyncStarted     = "sync.started"
+	EventSyncCompleted   = "sync.completed"
+	EventSyncFailed      = "sync.failed"
+	EventScrapeStarted   = "scrape.started"
+	EventScrapeCompleted = "scrape.completed"
+	EventScrapeFailed    = "scrape.failed"
+	EventPing            = "ping" // 测试订阅时发送，不需要订阅
+)
+
+// Events 可以订阅的事件
+var Events = []string{
+	EventSyncStarted,
+	EventSyncCompleted,
+	EventSyncFailed,
+	EventScrapeStarted,
+	EventScrapeCompleted,
+	EventScrapeFailed,
+}
+
+// 请求头
+const (
+	HeaderEvent     = "X-QMS-Event"     // 事件类型
+	HeaderDelivery  = "X-QMS-Delivery"  // 投递ID，重试时不变，可以用来去重
+	HeaderTimestamp = "X-QMS-Timestamp" // 发送时间，Unix秒
+	HeaderSignature = "X-QMS-Signature" // sha256=HMAC-SHA256(密钥, 时间戳 + "." + 请求体)
+)
+
+// Subscription Webhook订阅
+type Subscription struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name"`
+	URL         string    `json:"url"`
+	Secret      string    `json:"secret"`               // 签名密钥
+	Events      string    `json:"events"`               // 订阅的事件，逗号分隔，* 表示所有事件
+	Enabled     bool      `json:"enabled" gorm:"index"` // 是否启用
+	Description string    `json:"description"`          // 备注
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (*Subscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// EventList 订阅的事件列表
+func (s *Subscription) EventList() []string {
+	events := make([]string, 0)
+	for _, event := range strings.Split(s.Events, ",") {
+		if event = strings.TrimSpace(event); event != "" {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// Match 订阅是否包含事件
+func (s *Subscription) Match(event string) bool {
+	if event == EventPing {
+		return true
+	}
+	events := s.EventList()
+	return slices.Contains(events, "*") || slices.Contains(events, event)
+}
+
+// DeliveryStatus 投递状态
+type DeliveryStatus string
+
+const (
+	DeliveryPending DeliveryStatus = "pending" // 等待发送或者等待重试
+	DeliverySuccess DeliveryStatus = "success" // 对方返回2xx
+	DeliveryFailed  DeliveryStatus = "failed"  // 超过重试次数
+)
+
+// Delivery Webhook投递记录，也是发送队列
+type Delivery struct {
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	SubscriptionID uint           `json:"subscription_id" gorm:"index"`
+	DeliveryID     string         `json:"delivery_id" gorm:"index"` // 对方看到的投递ID
+	Event          string         `json:"event" gorm:"index"`
+	Payload        string         `json:"payload" gorm:"type:text"`       // 请求体
+	Status         DeliveryStatus `json:"status" gorm:"index"`            // 投递状态
+	Attempts       int            `json:"attempts"`                       // 已尝试次数
+	NextRetryAt    int64          `json:"next_retry_at" gorm:"index"`     // 下次发送时间
+	ResponseCode   int            `json:"response_code"`                  // 最后一次的HTTP状态码
+	ResponseBody   string         `json:"response_body" gorm:"type:text"` // 最后一次的响应内容，只保留开头部分
+	LastError      string         `json:"last_error" gorm:"type:text"`    // 最后一次失败原因
+	DurationMs     int64          `json:"duration_ms"`                    // 最后一次请求耗时
+	DeliveredAt    int64          `json:"delivered_at"`                   // 发送成功时间
+	CreatedAt      time.Time      `json:"created_at" gorm:"index"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+func (*Delivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// Payload 请求体
+type Payload struct {
+	ID        string `json:"id"` // 投递ID，和请求头 X-QMS-Delivery 相同
+	Event     string `json:"event"`
+	Timestamp int64  `json:"timestamp"` // 事件发生时间，Unix秒
+	Data      any    `json:"data"`
+}
+
+// SyncData sync.* 事件的数据
+type SyncData struct {
+	SyncPathID       uint     `json:"sync_path_id"` // 同步目录ID，手动同步时为0
+	SourceType       string   `json:"source_type"`
+	SourcePath       string   `json:"source_path"`
+	TargetPath       string   `json:"target_path"`
+	Sync             any      `json:"sync,omitempty"`     // 同步记录，包含完整的统计数据
+	Error            string   `json:"error,omitempty"`    // sync.failed 的失败原因
+	NewStrmPaths     []string `json:"new_strm_paths"`     // 本次生成的STRM文件
+	RemovedStrmPaths []string `json:"removed_strm_paths"` // 本次删除的STRM文件
+	PathsTruncated   bool     `json:"paths_truncated"`    // 文件太多，路径列表只包含一部分
+}
+
+// ScrapeData scrape.* 事件的数据
+type ScrapeData struct {
+	ScrapePathID uint           `json:"scrape_path_id"`
+	SourceType   string         `json:"source_type"`
+	MediaType    string         `json:"media_type"`
+	SourcePath   string         `json:"source_path"`
+	DestPath     string         `json:"dest_path"`
+	StartedAt    int64          `json:"started_at"`
+	FinishedAt   int64          `json:"finished_at,omitempty"`
+	StatusCounts map[string]int `json:"status_counts,omitempty"` // 本次处理的文件按状态统计
+}
+
+// Sign 计算签名，接收方用同样的方法计算后比较
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// GenerateSecret 生成随机的签名密钥
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,80 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestSubscriptionMatch(t *testing.T) {
+	sub := &Subscription{Events: " sync.completed, scrape.failed "}
+	for event, expected := range map[string]bool{
+		EventSyncCompleted:   true,
+		EventScrapeFailed:    true,
+		EventSyncStarted:     false,
+		EventScrapeCompleted: false,
+		EventPing:            true,
+	} {
+		if sub.Match(event) != expected {
+			t.Errorf("事件 %s 期望匹配结果 %v", event, expected)
+		}
+	}
+	all := &Subscription{Events: "*"}
+	if !all.Match(EventScrapeStarted) {
+		t.Error("* 应该匹配所有事件")
+	}
+	if (&Subscription{}).Match(EventSyncCompleted) {
+		t.Error("没有订阅事件时不应该匹配")
+	}
+}
+
+func TestPostSignsPayload(t *testing.T) {
+	const secret = "s3cret"
+	var received bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+		if r.Header.Get(HeaderSignature) != Sign(secret, timestamp, body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get(HeaderEvent) != EventSyncCompleted || r.Header.Get(HeaderDelivery) != "d1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received = true
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	d := NewDispatcher(nil)
+	delivery := &Delivery{DeliveryID: "d1", Event: EventSyncCompleted, Payload: `{"id":"d1"}`}
+	code, body, err := d.post(&Subscription{URL: server.URL, Secret: secret}, delivery)
+	if err != nil || code != http.StatusOK || body != "ok" || !received {
+		t.Fatalf("签名正确时应发送成功: %d %q %v", code, body, err)
+	}
+
+	code, _, err = d.post(&Subscription{URL: server.URL, Secret: "wrong"}, delivery)
+	if err == nil || code != http.StatusUnauthorized {
+		t.Fatalf("非2xx状态码应返回错误: %d %v", code, err)
+	}
+}
+
+func TestOrderedDeliveries(t *testing.T) {
+	deliveries := []*Delivery{
+		{ID: 3, SubscriptionID: 1},
+		{ID: 4, SubscriptionID: 2},
+		{ID: 6, SubscriptionID: 1},
+		{ID: 7, SubscriptionID: 2},
+	}
+	// 订阅1的第5条投递还在等待重试
+	bySub := orderedDeliveries(deliveries, map[uint]uint{1: 5})
+	if len(bySub[1]) != 1 || bySub[1][0].ID != 3 {
+		t.Errorf("订阅1只能发送等待重试之前的投递: %+v", bySub[1])
+	}
+	if len(bySub[2]) != 2 || bySub[2][0].ID != 4 || bySub[2][1].ID != 7 {
+		t.Errorf("订阅2应该按顺序发送所有投递: %+v", bySub[2])
+	}
+}
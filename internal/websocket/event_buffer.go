@@ -0,0 +1,89 @@
+package websocket
+
+import (
+	"sync"
+	"time"
+)
+
+// EventBufferSize 缓冲区保留的事件数量，客户端断线重连后可以从缓冲区重放
+const EventBufferSize = 1000
+
+// 保存最近事件的环形缓冲区
+type eventBuffer struct {
+	mu     sync.Mutex
+	events []WSEvent
+	start  int // 最早的事件在 events 中的位置
+	count  int
+	lastID uint64
+}
+
+var globalEventBuffer = newEventBuffer(EventBufferSize)
+
+func newEventBuffer(size int) *eventBuffer {
+	return &eventBuffer{events: make([]WSEvent, size)}
+}
+
+// 生成新事件并放入缓冲区，缓冲区满时覆盖最早的事件
+func (b *eventBuffer) add(eventType string, data any) WSEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastID++
+	event := WSEvent{
+		ID:        b.lastID,
+		EventType: eventType,
+		Timestamp: time.Now(),
+		Data:      data,
+	}
+	size := len(b.events)
+	if b.count < size {
+		b.events[(b.start+b.count)%size] = event
+		b.count++
+	} else {
+		b.events[b.start] = event
+		b.start = (b.start + 1) % size
+	}
+	return event
+}
+
+// 返回ID大于since的事件
+// 中间有事件已经被覆盖，或者since比最新的ID还大（程序重启过）时，在开头加上 replay_truncated 事件
+func (b *eventBuffer) since(since uint64) []WSEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var oldestID uint64
+	if b.count > 0 {
+		oldestID = b.events[b.start].ID
+	}
+	result := make([]WSEvent, 0)
+	if since > b.lastID || (oldestID > 0 && since+1 < oldestID) {
+		result = append(result, WSEvent{
+			EventType: EventReplayTruncated,
+			Timestamp: time.Now(),
+			Data:      ReplayTruncatedData{Since: since, OldestID: oldestID},
+		})
+		if since > b.lastID {
+			// 客户端的ID属于重启之前，重放所有事件
+			since = 0
+		}
+	}
+	size := len(b.events)
+	for i := 0; i < b.count; i++ {
+		event := b.events[(b.start+i)%size]
+		if event.ID > since {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
+// Replay 返回缓冲区中ID大于since的事件，since为0时返回缓冲区中所有事件
+func Replay(since uint64) []WSEvent {
+	return globalEventBuffer.since(since)
+}
+
+// LastEventID 最新的事件ID
+func LastEventID() uint64 {
+	globalEventBuffer.mu.Lock()
+	defer globalEventBuffer.mu.Unlock()
+	return globalEventBuffer.lastID
+}
@@ -0,0 +1,62 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEventBufferSince(t *testing.T) {
+	b := newEventBuffer(3)
+	for i := 0; i < 5; i++ {
+		b.add(EventQueueStateChange, QueueStateData{Queue: QueueUpload, Running: i%2 == 0})
+	}
+
+	// 缓冲区中只剩3、4、5
+	events := b.since(3)
+	if len(events) != 2 || events[0].ID != 4 || events[1].ID != 5 {
+		t.Fatalf("since(3) 应返回事件4、5，实际 %+v", events)
+	}
+	if events := b.since(5); len(events) != 0 {
+		t.Errorf("since(5) 不应返回事件，实际 %+v", events)
+	}
+
+	// 事件1、2已经被覆盖
+	events = b.since(0)
+	if len(events) != 4 || events[0].EventType != EventReplayTruncated || events[1].ID != 3 {
+		t.Fatalf("since(0) 应返回 replay_truncated 和事件3、4、5，实际 %+v", events)
+	}
+	if data := events[0].Data.(ReplayTruncatedData); data.OldestID != 3 {
+		t.Errorf("OldestID 应为3，实际 %d", data.OldestID)
+	}
+	if events := b.since(2); len(events) != 3 || events[0].ID != 3 {
+		t.Errorf("since(2) 不应截断，实际 %+v", events)
+	}
+
+	// 程序重启后客户端带着更大的ID重连
+	events = b.since(100)
+	if len(events) != 4 || events[0].EventType != EventReplayTruncated {
+		t.Errorf("since(100) 应返回 replay_truncated 和所有事件，实际 %+v", events)
+	}
+}
+
+func TestEventHubReplayTruncated(t *testing.T) {
+	old := globalEventBuffer
+	defer func() { globalEventBuffer = old }()
+	globalEventBuffer = newEventBuffer(10)
+	for i := 0; i < 5; i++ {
+		globalEventBuffer.add(EventQueueStateChange, QueueStateData{Queue: QueueUpload})
+	}
+
+	// Send 只能放3条，应收到 replay_truncated 和最新的事件4、5
+	client := &Client{Send: make(chan []byte, 3), Replay: true}
+	h := NewEventHub()
+	h.clients[client] = true
+	h.replay(client)
+	if len(client.Send) != 3 || client.lastID != 5 {
+		t.Fatalf("应发送3条消息，最后ID为5，实际 %d 条，最后ID %d", len(client.Send), client.lastID)
+	}
+	var first WSEvent
+	if err := json.Unmarshal(<-client.Send, &first); err != nil || first.EventType != EventReplayTruncated {
+		t.Errorf("第一条应为 replay_truncated，实际 %+v", first)
+	}
+}
@@ -8,17 +8,9 @@ import (
 	"github.com/gorilla/websocket"
 )
 
-// 事件类型常量
-const (
-	EventScraperTaskStart     = "scraper_task_start"
-	EventScraperTaskComplete  = "scraper_task_complete"
-	EventScraperItemComplete  = "scraper_item_complete"
-	EventStrmSyncTaskStart    = "strm_sync_task_start"
-	EventStrmSyncTaskComplete = "strm_sync_task_complete"
-)
-
 // WSEvent WebSocket事件结构
 type WSEvent struct {
+	ID        uint64    `json:"id"` // 单调递增的事件ID，程序重启后从1开始
 	EventType string    `json:"event_type"`
 	Timestamp time.Time `json:"timestamp"`
 	Data      any       `json:"data"`
@@ -26,15 +18,24 @@ type WSEvent struct {
 
 // Client WebSocket客户端
 type Client struct {
-	Hub  *EventHub
-	Conn *websocket.Conn
-	Send chan []byte
+	Hub    *EventHub
+	Conn   *websocket.Conn
+	Send   chan []byte
+	Replay bool   // 注册时是否重放缓冲区中的事件
+	Since  uint64 // 重放ID大于Since的事件
+	lastID uint64 // 已经发送的最后一个事件ID，只在hub协程中访问
+}
+
+// 广播给客户端的消息
+type hubMessage struct {
+	id   uint64
+	data []byte
 }
 
 // EventHub WebSocket事件中心，管理所有连接和事件广播
 type EventHub struct {
 	clients    map[*Client]bool
-	broadcast  chan []byte
+	broadcast  chan hubMessage
 	register   chan *Client
 	unregister chan *Client
 	mutex      sync.RWMutex
@@ -43,11 +44,20 @@ type EventHub struct {
 // 全局事件中心实例
 var GlobalEventHub *EventHub
 
+// 进程内的事件订阅者，比如Telegram机器人根据事件更新任务进度、SSE连接
+var (
+	listeners   = make(map[chan WSEvent]struct{})
+	listenersMu sync.RWMutex
+)
+
+// 保证事件按ID顺序写入缓冲区、通知订阅者和广播
+var broadcastMu sync.Mutex
+
 // NewEventHub 创建新的事件中心
 func NewEventHub() *EventHub {
 	return &EventHub{
 		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte, 256),
+		broadcast:  make(chan hubMessage, 256),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 	}
@@ -61,6 +71,9 @@ func (h *EventHub) Run() {
 			h.mutex.Lock()
 			h.clients[client] = true
 			h.mutex.Unlock()
+			if client.Replay {
+				h.replay(client)
+			}
 
 		case client := <-h.unregister:
 			h.mutex.Lock()
@@ -73,8 +86,13 @@ func (h *EventHub) Run() {
 		case message := <-h.broadcast:
 			h.mutex.RLock()
 			for client := range h.clients {
+				// 注册时已经重放过的事件不再发送
+				if message.id <= client.lastID {
+					continue
+				}
 				select {
-				case client.Send <- message:
+				case client.Send <- message.data:
+					client.lastID = message.id
 				default:
 					// 发送失败，关闭连接
 					h.mutex.RUnlock()
@@ -90,21 +108,106 @@ func (h *EventHub) Run() {
 	}
 }
 
-// BroadcastEvent 广播事件到所有客户端
+// 把缓冲区中的事件发给刚注册的客户端
+// Send 的容量不够时只发送最新的事件，并在开头加上 replay_truncated 事件，客户端需要重新拉取完整状态
+func (h *EventHub) replay(client *Client) {
+	events := Replay(client.Since)
+	if free := cap(client.Send) - len(client.Send); len(events) > free && free > 0 {
+		kept := events[len(events)-free+1:]
+		var oldestID uint64
+		if len(kept) > 0 {
+			oldestID = kept[0].ID
+		}
+		events = append([]WSEvent{{
+			EventType: EventReplayTruncated,
+			Timestamp: time.Now(),
+			Data:      ReplayTruncatedData{Since: client.Since, OldestID: oldestID},
+		}}, kept...)
+	}
+	for _, event := range events {
+		msg, err := json.Marshal(event)
+		if err != nil {
+			continue
+		}
+		select {
+		case client.Send <- msg:
+			if event.ID > client.lastID {
+				client.lastID = event.ID
+			}
+		default:
+			// Send 已经满了，关闭连接让客户端用最后收到的事件ID重连
+			h.mutex.Lock()
+			if _, ok := h.clients[client]; ok {
+				delete(h.clients, client)
+				close(client.Send)
+			}
+			h.mutex.Unlock()
+			return
+		}
+	}
+}
+
+// Subscribe 订阅所有广播的事件，返回事件通道和取消订阅的函数
+// 订阅者处理不过来时取消订阅并关闭通道，订阅者可以用最后收到的事件ID重新订阅
+func Subscribe(buffer int) (<-chan WSEvent, func()) {
+	ch := make(chan WSEvent, buffer)
+	listenersMu.Lock()
+	listeners[ch] = struct{}{}
+	listenersMu.Unlock()
+	return ch, func() {
+		removeListener(ch)
+	}
+}
+
+// SubscribeSince 订阅事件，同时返回缓冲区中ID大于since的事件
+// 重放的事件和通道中的事件不会重复也不会遗漏
+func SubscribeSince(since uint64, buffer int) ([]WSEvent, <-chan WSEvent, func()) {
+	broadcastMu.Lock()
+	defer broadcastMu.Unlock()
+	ch, cancel := Subscribe(buffer)
+	return Replay(since), ch, cancel
+}
+
+func removeListener(ch chan WSEvent) {
+	listenersMu.Lock()
+	defer listenersMu.Unlock()
+	if _, ok := listeners[ch]; ok {
+		delete(listeners, ch)
+		close(ch)
+	}
+}
+
+// 通知进程内的订阅者
+func notifyListeners(event WSEvent) {
+	var slow []chan WSEvent
+	listenersMu.RLock()
+	for ch := range listeners {
+		select {
+		case ch <- event:
+		default:
+			slow = append(slow, ch)
+		}
+	}
+	listenersMu.RUnlock()
+	for _, ch := range slow {
+		removeListener(ch)
+	}
+}
+
+// BroadcastEvent 广播事件到所有客户端，data 使用事件目录中对应的结构
 func BroadcastEvent(eventType string, data any) {
+	broadcastMu.Lock()
+	defer broadcastMu.Unlock()
+	event := globalEventBuffer.add(eventType, data)
+	notifyListeners(event)
 	if GlobalEventHub == nil {
 		return
 	}
-	event := WSEvent{
-		EventType: eventType,
-		Timestamp: time.Now(),
-		Data:      data,
-	}
 	msg, err := json.Marshal(event)
 	if err != nil {
 		return
 	}
-	GlobalEventHub.broadcast <- msg
+	GlobalEventHub.broadcast <- hubMessage{id: event.ID, data: msg}
 }
 
 // RegisterClient 注册客户端
@@ -0,0 +1,119 @@
+package websocket
+
+// ============ 事件目录 ============
+// 每种事件都有固定的数据结构，前端按 event_type 解析 data
+
+// 事件类型常量
+const (
+	// 刮削
+	EventScraperTaskStart    = "scraper_task_start"    // ScrapeTaskData
+	EventScraperTaskComplete = "scraper_task_complete" // ScrapeTaskData
+	EventScraperItemComplete = "scraper_item_complete" // ScrapeItemData
+	// STRM同步
+	EventStrmSyncTaskStart    = "strm_sync_task_start"    // StrmSyncTaskData
+	EventStrmSyncTaskComplete = "strm_sync_task_complete" // StrmSyncTaskData
+	EventStrmSyncFileProgress = "strm_sync_file_progress" // StrmSyncFileProgressData
+	// 队列
+	EventQueueStateChange = "queue_state_change" // QueueStateData
+	// 115限流
+	EventThrottleEnter = "throttle_enter" // ThrottleData
+	EventThrottleExit  = "throttle_exit"  // ThrottleData
+	// 账号
+	EventAccountTokenExpired = "account_token_expired" // AccountTokenData
+	// 备份和恢复
+	EventBackupProgress = "backup_progress" // BackupProgressData
+	EventBackupComplete = "backup_complete" // BackupCompleteData
+	// Emby
+	EventEmbySyncStart    = "emby_sync_start"    // EmbySyncData
+	EventEmbySyncComplete = "emby_sync_complete" // EmbySyncData
+	// 重放的事件不完整，客户端需要重新获取完整状态
+	EventReplayTruncated = "replay_truncated" // ReplayTruncatedData
+)
+
+// 队列名称
+const (
+	QueueUpload   = "upload"
+	QueueDownload = "download"
+	QueueSync     = "sync"
+)
+
+// ScrapeTaskData 刮削任务开始、结束
+type ScrapeTaskData struct {
+	TaskID   uint   `json:"task_id"` // 刮削目录ID
+	PathName string `json:"path_name"`
+	Success  bool   `json:"success"` // 只有结束事件有意义
+}
+
+// ScrapeItemData 单个文件刮削完成
+type ScrapeItemData struct {
+	ItemID       uint   `json:"item_id"`
+	ScrapePathID uint   `json:"scrape_path_id"`
+	Name         string `json:"name"`
+	Status       string `json:"status"`
+	Success      bool   `json:"success"`
+}
+
+// StrmSyncTaskData STRM同步任务开始、结束
+type StrmSyncTaskData struct {
+	TaskID  uint   `json:"task_id"` // 同步目录ID
+	Success bool   `json:"success"` // 只有结束事件有意义
+	Error   string `json:"error,omitempty"`
+}
+
+// StrmSyncFileProgressData STRM同步处理文件的进度
+type StrmSyncFileProgressData struct {
+	TaskID    uint   `json:"task_id"`   // 同步目录ID
+	Action    string `json:"action"`    // strm：生成STRM，meta：添加元数据下载任务
+	FileName  string `json:"file_name"` // 最近处理的文件
+	TotalFile int64  `json:"total_file"`
+	NewStrm   int64  `json:"new_strm"`
+	NewMeta   int64  `json:"new_meta"`
+}
+
+// QueueStateData 队列启动或暂停
+type QueueStateData struct {
+	Queue   string `json:"queue"` // upload、download、sync
+	Running bool   `json:"running"`
+}
+
+// ThrottleData 115接口进入或退出限流
+type ThrottleData struct {
+	DurationSeconds float64 `json:"duration_seconds"` // 进入时为限流时长，退出时为实际限流时长
+}
+
+// AccountTokenData 账号的访问凭证失效
+type AccountTokenData struct {
+	AccountID  uint   `json:"account_id"`
+	Name       string `json:"name"`
+	SourceType string `json:"source_type"`
+	Reason     string `json:"reason"`
+}
+
+// BackupProgressData 备份或恢复的进度
+type BackupProgressData struct {
+	Type  string `json:"type"` // backup、restore
+	Desc  string `json:"desc"`
+	Total int    `json:"total"`
+	Count int    `json:"count"`
+	Error string `json:"error,omitempty"`
+}
+
+// BackupCompleteData 备份结束
+type BackupCompleteData struct {
+	BackupType string `json:"backup_type"`
+	Success    bool   `json:"success"`
+	Error      string `json:"error,omitempty"`
+}
+
+// EmbySyncData Emby媒体库同步开始、结束
+type EmbySyncData struct {
+	Processed int    `json:"processed"` // 只有结束事件有意义
+	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ReplayTruncatedData 请求的事件已经不在缓冲区中
+type ReplayTruncatedData struct {
+	Since    uint64 `json:"since"`     // 客户端请求的ID
+	OldestID uint64 `json:"oldest_id"` // 缓冲区中最早的事件ID，0表示没有事件
+}
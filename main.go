@@ -9,6 +9,7 @@ import (
 	"Q115-STRM/internal/db"
 	"Q115-STRM/internal/db/database"
 	"Q115-STRM/internal/github"
+	"Q115-STRM/internal/health"
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/migrate"
 	"Q115-STRM/internal/models"
@@ -437,6 +438,7 @@ func initOthers() {
 	models.InitDQ()                      // 初始化下载队列
 	models.InitUQ()                      // 初始化上传队列
 	models.InitNotificationManager()     // 初始化通知管理器
+	models.InitWebhook()                 // 初始化自动化Webhook发送队列
 	controllers.StartListenTelegramBot() // 初始化TelegramBot监听
 	models.GetEmbyConfig()               // 加载Emby配置
 	helpers.SubscribeSync(helpers.V115TokenInValidEvent, models.HandleV115TokenInvalid)
@@ -469,6 +471,7 @@ func initOthers() {
 	synccron.InitSyncCron()   // 初始化同步目录的定时任务
 	synccron.InitScrapeCron() // 初始化刮削目录的自定义定时任务
 	synccron.InitTokenCron()  // 初始化定时刷新115的访问凭证
+	health.Start()            // 启动健康检查
 	// 初始化备份服务
 	models.InitBackupService()
 	// 将所有刮削中和整理中的记录改为未执行
@@ -486,13 +489,12 @@ func initOthers() {
 	})
 	helpers.Subscribe(helpers.StrmSyncCompleteEvent, func(event helpers.Event) {
 		// 触发关联的刮削任务
-		scrapePathIds := event.Data.([]uint)
-		// 将任务添加到队列中
-		for _, scrapePathId := range scrapePathIds {
+		data := event.Data.(helpers.StrmSyncCompleteData)
+		addScrapeTask := func(scrapePathId uint, skipScan bool) {
 			scrapePath := models.GetScrapePathByID(scrapePathId)
 			if scrapePath == nil {
 				helpers.AppLogger.Errorf("获取刮削目录失败: %v", scrapePathId)
-				continue
+				return
 			}
 			taskObj := &synccron.NewSyncTask{
 				ID:           scrapePathId,
@@ -503,13 +505,21 @@ func initOthers() {
 				SourceType:   scrapePath.SourceType,
 				IsFile:       false,
 				TaskType:     synccron.SyncTaskTypeScrape,
+				SkipScan:     skipScan,
 			}
 			if err := synccron.AddNewSyncTask(taskObj); err != nil {
 				helpers.AppLogger.Errorf("添加刮削任务失败: %v", err)
 			} else {
-				helpers.AppLogger.Infof("创建刮削任务成功并已添加到执行队列，刮削目录ID: %d，", scrapePathId)
+				helpers.AppLogger.Infof("创建刮削任务成功并已添加到执行队列，刮削目录ID: %d，跳过扫描: %v", scrapePathId, skipScan)
 			}
 		}
+		// 将任务添加到队列中
+		for _, scrapePathId := range data.ScrapePathIds {
+			addScrapeTask(scrapePathId, false)
+		}
+		for _, scrapePathId := range data.SkipScanIds {
+			addScrapeTask(scrapePathId, true)
+		}
 	})
 }
 
@@ -538,7 +548,6 @@ func setRouter(r *gin.Engine) {
 	r.GET("/api/scrape/tmp-image", controllers.ScrapeTmpImage)           // 获取临时图片
 	r.GET("/api/scrape/records/export", controllers.ExportScrapeRecords) // 导出刮削记录
 	r.GET("/api/logs/ws", controllers.LogWebSocket)                      // WebSocket日志查看
-	r.GET("/api/events/ws", controllers.EventWebSocket)                  // WebSocket事件推送
 	r.GET("/api/logs/old", controllers.GetOldLogs)                       // HTTP获取旧日志
 	r.GET("/api/logs/download", controllers.DownloadLogFile)             // 下载日志文件
 
@@ -558,6 +567,8 @@ func setRouter(r *gin.Engine) {
 		})
 		api.POST("/database/delete-all-table", controllers.DeleteAllTabble) // 删除所有表
 		api.GET("/announce", controllers.GetAnnounce)                       // 获取公告
+		api.GET("/events/ws", controllers.EventWebSocket)                   // WebSocket事件推送
+		api.GET("/events/sse", controllers.EventStream)                     // SSE事件推送
 		api.POST("/database/repair", controllers.RepairDB)                  // 更新系统设置
 		api.POST("/auth/115-qrcode-open", controllers.GetLoginQrCodeOpen)   // 获取115开放平台登录二维码
 		api.POST("/auth/115-qrcode-status", controllers.GetQrCodeStatus)    // 查询115二维码扫码状态
@@ -592,36 +603,63 @@ func setRouter(r *gin.Engine) {
 		// api.GET("/setting/telegram", controllers.GetTelegram)                                      // 获取telegram消息通知配置
 		// api.POST("/setting/telegram", controllers.UpdateTelegram)                                  // 更改telegram消息通知配置
 		// api.POST("/telegram/test", controllers.TestTelegram)                                       // 测试telegram连通性
-		api.GET("/setting/notification/channels", controllers.GetNotificationChannels)             // 获取所有通知渠道
-		api.POST("/setting/notification/channels/telegram", controllers.CreateTelegramChannel)     // 创建Telegram渠道
-		api.GET("/setting/notification/channels/telegram/:id", controllers.GetTelegramChannel)     // 查询Telegram渠道
-		api.PUT("/setting/notification/channels/telegram", controllers.UpdateTelegramChannel)      // 更新Telegram渠道
-		api.POST("/setting/notification/channels/meow", controllers.CreateMeoWChannel)             // 创建MeoW渠道
-		api.GET("/setting/notification/channels/meow/:id", controllers.GetMeoWChannel)             // 查询MeoW渠道
-		api.PUT("/setting/notification/channels/meow", controllers.UpdateMeoWChannel)              // 更新MeoW渠道
-		api.POST("/setting/notification/channels/bark", controllers.CreateBarkChannel)             // 创建Bark渠道
-		api.GET("/setting/notification/channels/bark/:id", controllers.GetBarkChannel)             // 查询Bark渠道
-		api.PUT("/setting/notification/channels/bark", controllers.UpdateBarkChannel)              // 更新Bark渠道
-		api.POST("/setting/notification/channels/serverchan", controllers.CreateServerChanChannel) // 创建Server酱渠道
-		api.GET("/setting/notification/channels/serverchan/:id", controllers.GetServerChanChannel) // 查询Server酱渠道
-		api.PUT("/setting/notification/channels/serverchan", controllers.UpdateServerChanChannel)  // 更新Server酱渠道
-		api.POST("/setting/notification/channels/webhook", controllers.CreateCustomWebhookChannel) // 创建自定义Webhook渠道
-		api.GET("/setting/notification/channels/webhook/:id", controllers.GetCustomWebhookChannel) // 查询自定义Webhook渠道
-		api.PUT("/setting/notification/channels/webhook", controllers.UpdateCustomWebhookChannel)  // 更新自定义Webhook渠道
-		api.POST("/setting/notification/channels/status", controllers.UpdateChannelStatus)         // 启用/禁用渠道
-		api.DELETE("/setting/notification/channels/:id", controllers.DeleteChannel)                // 删除渠道
-		api.GET("/setting/notification/rules", controllers.GetNotificationRules)                   // 获取通知规则
-		api.PUT("/setting/notification/rules", controllers.UpdateNotificationRule)                 // 更新通知规则
-		api.POST("/setting/notification/channels/test", controllers.TestChannelConnection)         // 测试通知渠道连接
-		api.GET("/setting/strm-config", controllers.GetStrmConfig)                                 // 获取STRM配置
-		api.POST("/setting/strm-config", controllers.UpdateStrmConfig)                             // 更新STRM配置
-		api.GET("/setting/cron", controllers.GetCronNextTime)                                      // 获取Cron表达式的下5次执行时间
-		api.POST("/cron/validate", controllers.ValidateCron)                                       // 验证Cron表达式并返回描述
-		api.POST("/setting/emby/parse", controllers.ParseEmby)                                     // 解析Emby媒体信息
-		api.GET("/setting/emby-config", controllers.GetEmbyConfig)                                 // 获取新的Emby配置
-		api.POST("/setting/emby-config", controllers.UpdateEmbyConfig)                             // 更新新的Emby配置
-		api.POST("/setting/threads", controllers.UpdateThreads)                                    // 更新线程数
-		api.GET("/setting/threads", controllers.GetThreads)                                        // 获取线程数
+		api.GET("/setting/notification/channels", controllers.GetNotificationChannels)                           // 获取所有通知渠道
+		api.POST("/setting/notification/channels/telegram", controllers.CreateTelegramChannel)                   // 创建Telegram渠道
+		api.GET("/setting/notification/channels/telegram/:id", controllers.GetTelegramChannel)                   // 查询Telegram渠道
+		api.PUT("/setting/notification/channels/telegram", controllers.UpdateTelegramChannel)                    // 更新Telegram渠道
+		api.GET("/setting/notification/channels/telegram/:id/users", controllers.GetTelegramUsers)               // 查询Telegram机器人用户
+		api.POST("/setting/notification/channels/telegram/:id/users", controllers.SaveTelegramUser)              // 添加或修改Telegram机器人用户
+		api.DELETE("/setting/notification/channels/telegram/:id/users/:user_id", controllers.DeleteTelegramUser) // 删除Telegram机器人用户
+		api.GET("/setting/notification/channels/telegram/:id/audit", controllers.GetTelegramCommandLogs)         // 查询Telegram机器人命令记录
+		api.POST("/setting/notification/channels/meow", controllers.CreateMeoWChannel)                           // 创建MeoW渠道
+		api.GET("/setting/notification/channels/meow/:id", controllers.GetMeoWChannel)                           // 查询MeoW渠道
+		api.PUT("/setting/notification/channels/meow", controllers.UpdateMeoWChannel)                            // 更新MeoW渠道
+		api.POST("/setting/notification/channels/bark", controllers.CreateBarkChannel)                           // 创建Bark渠道
+		api.GET("/setting/notification/channels/bark/:id", controllers.GetBarkChannel)                           // 查询Bark渠道
+		api.PUT("/setting/notification/channels/bark", controllers.UpdateBarkChannel)                            // 更新Bark渠道
+		api.POST("/setting/notification/channels/serverchan", controllers.CreateServerChanChannel)               // 创建Server酱渠道
+		api.GET("/setting/notification/channels/serverchan/:id", controllers.GetServerChanChannel)               // 查询Server酱渠道
+		api.PUT("/setting/notification/channels/serverchan", controllers.UpdateServerChanChannel)                // 更新Server酱渠道
+		api.POST("/setting/notification/channels/webhook", controllers.CreateCustomWebhookChannel)               // 创建自定义Webhook渠道
+		api.GET("/setting/notification/channels/webhook/:id", controllers.GetCustomWebhookChannel)               // 查询自定义Webhook渠道
+		api.PUT("/setting/notification/channels/webhook", controllers.UpdateCustomWebhookChannel)                // 更新自定义Webhook渠道
+		api.POST("/setting/notification/channels/wecom", controllers.CreateWeComChannel)                         // 创建企业微信渠道
+		api.GET("/setting/notification/channels/wecom/:id", controllers.GetWeComChannel)                         // 查询企业微信渠道
+		api.PUT("/setting/notification/channels/wecom", controllers.UpdateWeComChannel)                          // 更新企业微信渠道
+		api.POST("/setting/notification/channels/dingtalk", controllers.CreateDingTalkChannel)                   // 创建钉钉渠道
+		api.GET("/setting/notification/channels/dingtalk/:id", controllers.GetDingTalkChannel)                   // 查询钉钉渠道
+		api.PUT("/setting/notification/channels/dingtalk", controllers.UpdateDingTalkChannel)                    // 更新钉钉渠道
+		api.POST("/setting/notification/channels/feishu", controllers.CreateFeishuChannel)                       // 创建飞书渠道
+		api.GET("/setting/notification/channels/feishu/:id", controllers.GetFeishuChannel)                       // 查询飞书渠道
+		api.PUT("/setting/notification/channels/feishu", controllers.UpdateFeishuChannel)                        // 更新飞书渠道
+		api.POST("/setting/notification/channels/gotify", controllers.CreateGotifyChannel)                       // 创建Gotify渠道
+		api.GET("/setting/notification/channels/gotify/:id", controllers.GetGotifyChannel)                       // 查询Gotify渠道
+		api.PUT("/setting/notification/channels/gotify", controllers.UpdateGotifyChannel)                        // 更新Gotify渠道
+		api.POST("/setting/notification/channels/ntfy", controllers.CreateNtfyChannel)                           // 创建ntfy渠道
+		api.GET("/setting/notification/channels/ntfy/:id", controllers.GetNtfyChannel)                           // 查询ntfy渠道
+		api.PUT("/setting/notification/channels/ntfy", controllers.UpdateNtfyChannel)                            // 更新ntfy渠道
+		api.POST("/setting/notification/channels/discord", controllers.CreateDiscordChannel)                     // 创建Discord渠道
+		api.GET("/setting/notification/channels/discord/:id", controllers.GetDiscordChannel)                     // 查询Discord渠道
+		api.PUT("/setting/notification/channels/discord", controllers.UpdateDiscordChannel)                      // 更新Discord渠道
+		api.POST("/setting/notification/channels/email", controllers.CreateEmailChannel)                         // 创建邮件渠道
+		api.GET("/setting/notification/channels/email/:id", controllers.GetEmailChannel)                         // 查询邮件渠道
+		api.PUT("/setting/notification/channels/email", controllers.UpdateEmailChannel)                          // 更新邮件渠道
+		api.POST("/setting/notification/channels/status", controllers.UpdateChannelStatus)                       // 启用/禁用渠道
+		api.DELETE("/setting/notification/channels/:id", controllers.DeleteChannel)                              // 删除渠道
+		api.GET("/setting/notification/rules", controllers.GetNotificationRules)                                 // 获取通知规则
+		api.PUT("/setting/notification/rules", controllers.UpdateNotificationRule)                               // 更新通知规则
+		api.POST("/setting/notification/channels/test", controllers.TestChannelConnection)                       // 测试通知渠道连接
+		api.GET("/setting/notification/logs", controllers.GetNotificationLogs)                                   // 获取通知发送记录
+		api.POST("/setting/notification/logs/retry", controllers.RetryNotificationLog)                           // 重新发送通知
+		api.GET("/setting/strm-config", controllers.GetStrmConfig)                                               // 获取STRM配置
+		api.POST("/setting/strm-config", controllers.UpdateStrmConfig)                                           // 更新STRM配置
+		api.GET("/setting/cron", controllers.GetCronNextTime)                                                    // 获取Cron表达式的下5次执行时间
+		api.POST("/cron/validate", controllers.ValidateCron)                                                     // 验证Cron表达式并返回描述
+		api.POST("/setting/emby/parse", controllers.ParseEmby)                                                   // 解析Emby媒体信息
+		api.GET("/setting/emby-config", controllers.GetEmbyConfig)                                               // 获取新的Emby配置
+		api.POST("/setting/emby-config", controllers.UpdateEmbyConfig)                                           // 更新新的Emby配置
+		api.POST("/setting/threads", controllers.UpdateThreads)                                                  // 更新线程数
+		api.GET("/setting/threads", controllers.GetThreads)                                                      // 获取线程数
 
 		api.POST("/emby/sync/start", controllers.StartEmbySync)     // 手动启动Emby同步
 		api.GET("/emby/sync/status", controllers.GetEmbySyncStatus) // 获取Emby同步状态
@@ -645,6 +683,14 @@ func setRouter(r *gin.Engine) {
 		api.POST("/sync/path/scrape-paths", controllers.SaveRelScrapePath)   // 更新同步路径关联的刮削路径
 		api.POST("/sync/manual", controllers.ManualSync)                     // 手动同步
 
+		api.POST("/duplicate/find", controllers.FindDuplicates)          // 查找重复文件
+		api.GET("/duplicate/status", controllers.GetDuplicateStatus)     // 获取重复文件任务状态
+		api.GET("/duplicate/groups", controllers.GetDuplicateGroups)     // 获取重复文件组列表
+		api.GET("/duplicate/summary", controllers.GetDuplicateSummary)   // 获取各账号的重复文件占用
+		api.POST("/duplicate/keep", controllers.SetDuplicateKeepFiles)   // 选择重复文件组中保留的文件
+		api.POST("/duplicate/ignore", controllers.IgnoreDuplicateGroups) // 忽略重复文件组
+		api.POST("/duplicate/delete", controllers.DeleteDuplicates)      // 删除重复文件
+
 		api.GET("/account/list", controllers.GetAccountList)             // 获取开放平台账号列表
 		api.POST("/account/add", controllers.CreateTmpAccount)           // 创建开放平台账号
 		api.POST("/account/delete", controllers.DeleteAccount)           // 删除开放平台账号
@@ -656,39 +702,69 @@ func setRouter(r *gin.Engine) {
 		api.PUT("/api-keys/:id/status", controllers.UpdateAPIKeyStatus) // 更新API Key状态
 		api.DELETE("/api-keys/:id", controllers.DeleteAPIKey)           // 删除API Key
 
-		api.GET("/scrape/movie-genre", controllers.GetMovieGenre)                     // 获取电影类别
-		api.GET("/scrape/tvshow-genre", controllers.GetTvshowGenre)                   // 获取电视剧类别
-		api.GET("/scrape/language", controllers.GetLanguage)                          // 获取语言数组
-		api.GET("/scrape/countries", controllers.GetCountries)                        // 获取国家数组
-		api.GET("/scrape/tmdb", controllers.GetTmdbSettings)                          // 获取TMDB设置
-		api.POST("/scrape/tmdb", controllers.SaveTmdbSettings)                        // 保存TMDB设置
-		api.POST("/scrape/tmdb-test", controllers.TestTmdbSettings)                   // 测试TMDB设置
-		api.GET("/scrape/ai-settings", controllers.GetAiSettings)                     // 获取AI识别设置
-		api.POST("/scrape/ai-settings", controllers.SaveAiSettings)                   // 保存AI识别设置
-		api.POST("/scrape/ai-test", controllers.TestAiSettings)                       // 测试AI识别设置
-		api.GET("/scrape/movie-categories", controllers.GetMovieCategories)           // 获取电影分类列表
-		api.GET("/scrape/tvshow-categories", controllers.GetTvshowCategories)         // 获取电视剧分类列表
-		api.POST("/scrape/movie-categories", controllers.SaveMovieCategory)           // 保存电影分类
-		api.POST("/scrape/tvshow-categories", controllers.SaveTvshowCategory)         // 保存电视剧分类
-		api.DELETE("/scrape/movie-categories/:id", controllers.DeleteMovieCategory)   // 删除电影分类
-		api.DELETE("/scrape/tvshow-categories/:id", controllers.DeleteTvshowCategory) // 删除电视剧分类
-		api.GET("/scrape/pathes", controllers.GetScrapePathes)                        // 获取刮削路径列表
-		api.POST("/scrape/pathes", controllers.SaveScrapePath)                        // 保存刮削路径列表
-		api.DELETE("/scrape/pathes/:id", controllers.DeleteScrapePath)                // 删除刮削路径
-		api.GET("/scrape/pathes/:id", controllers.GetScrapePath)                      // 获取刮削路径详情
-		api.POST("/scrape/pathes/start", controllers.ScanScrapePath)                  // 扫描刮削路径
-		api.POST("/scrape/pathes/stop", controllers.StopScrape)                       // 停止刮削任务
-		api.POST("/scrape/pathes/toggle-cron", controllers.ToggleScrapePathCron)      // 关闭或开启刮削路径的定时刮削
-		api.GET("/scrape/records", controllers.GetScrapeRecords)                      // 获取刮削记录
-		api.POST("/scrape/re-scrape", controllers.ReScrape)                           // 重新刮削记录
-		api.POST("/scrape/clear-failed", controllers.ClearFailedScrapeRecords)        // 清除所有刮削失败的记录
-		api.POST("/scrape/truncate-all", controllers.TruncateAllScrapeRecords)        // 一键清空所有刮削记录
-		api.DELETE("/scrape/records", controllers.DeleteScrapeMediaFile)              // 删除刮削记录
-		api.POST("/scrape/finish", controllers.FinishScrapeMediaFile)                 // 完成刮削记录
-		api.POST("/scrape/rename-failed", controllers.RenameFailedScrapeMediaFile)    // 标记所有失败的记录为待整理
-		api.POST("/scrape/sync-pathes", controllers.SaveScrapeStrmPath)               // 保存刮削目录关联的同步目录
-		api.GET("/scrape/sync-pathes", controllers.GetScrapeStrmPaths)                // 获取刮削目录关联的同步目录
-		api.GET("/scrape/tmdb-search", controllers.TmdbSearch)                        // 搜索TMDB媒体
+		// 自动化Webhook
+		api.GET("/webhooks", controllers.GetWebhookSubscriptions)                    // 获取Webhook订阅列表
+		api.POST("/webhooks", controllers.CreateWebhookSubscription)                 // 添加Webhook订阅
+		api.PUT("/webhooks/:id", controllers.UpdateWebhookSubscription)              // 修改Webhook订阅
+		api.DELETE("/webhooks/:id", controllers.DeleteWebhookSubscription)           // 删除Webhook订阅
+		api.POST("/webhooks/:id/ping", controllers.PingWebhookSubscription)          // 测试Webhook订阅
+		api.GET("/webhooks/deliveries", controllers.GetWebhookDeliveries)            // 获取Webhook投递记录
+		api.POST("/webhooks/deliveries/:id/redeliver", controllers.RedeliverWebhook) // 重新发送Webhook投递
+		api.POST("/webhooks/inbound/:source", controllers.InboundWebhook)            // 入站Webhook，触发子目录同步
+		api.GET("/health", controllers.GetHealth)                                    // 获取健康检查结果
+
+		api.GET("/scrape/movie-genre", controllers.GetMovieGenre)                             // 获取电影类别
+		api.GET("/scrape/tvshow-genre", controllers.GetTvshowGenre)                           // 获取电视剧类别
+		api.GET("/scrape/language", controllers.GetLanguage)                                  // 获取语言数组
+		api.GET("/scrape/countries", controllers.GetCountries)                                // 获取国家数组
+		api.GET("/scrape/tmdb", controllers.GetTmdbSettings)                                  // 获取TMDB设置
+		api.POST("/scrape/tmdb", controllers.SaveTmdbSettings)                                // 保存TMDB设置
+		api.POST("/scrape/tmdb-test", controllers.TestTmdbSettings)                           // 测试TMDB设置
+		api.GET("/scrape/tmdb-cache", controllers.GetTmdbCache)                               // 获取TMDB缓存状态
+		api.POST("/scrape/tmdb-cache/offline", controllers.SetTmdbOffline)                    // 开启或关闭TMDB离线模式
+		api.DELETE("/scrape/tmdb-cache", controllers.ClearTmdbCache)                          // 清空TMDB缓存
+		api.GET("/scrape/ai-settings", controllers.GetAiSettings)                             // 获取AI识别设置
+		api.POST("/scrape/ai-settings", controllers.SaveAiSettings)                           // 保存AI识别设置
+		api.POST("/scrape/ai-test", controllers.TestAiSettings)                               // 测试AI识别设置
+		api.GET("/scrape/subtitle-settings", controllers.GetSubtitleSettings)                 // 获取字幕设置
+		api.POST("/scrape/subtitle-settings", controllers.SaveSubtitleSettings)               // 保存字幕设置
+		api.GET("/scrape/movie-categories", controllers.GetMovieCategories)                   // 获取电影分类列表
+		api.GET("/scrape/tvshow-categories", controllers.GetTvshowCategories)                 // 获取电视剧分类列表
+		api.POST("/scrape/movie-categories", controllers.SaveMovieCategory)                   // 保存电影分类
+		api.POST("/scrape/tvshow-categories", controllers.SaveTvshowCategory)                 // 保存电视剧分类
+		api.DELETE("/scrape/movie-categories/:id", controllers.DeleteMovieCategory)           // 删除电影分类
+		api.DELETE("/scrape/tvshow-categories/:id", controllers.DeleteTvshowCategory)         // 删除电视剧分类
+		api.GET("/scrape/pathes", controllers.GetScrapePathes)                                // 获取刮削路径列表
+		api.POST("/scrape/pathes", controllers.SaveScrapePath)                                // 保存刮削路径列表
+		api.DELETE("/scrape/pathes/:id", controllers.DeleteScrapePath)                        // 删除刮削路径
+		api.GET("/scrape/pathes/:id", controllers.GetScrapePath)                              // 获取刮削路径详情
+		api.POST("/scrape/pathes/start", controllers.ScanScrapePath)                          // 扫描刮削路径
+		api.POST("/scrape/pathes/stop", controllers.StopScrape)                               // 停止刮削任务
+		api.POST("/scrape/pathes/toggle-cron", controllers.ToggleScrapePathCron)              // 关闭或开启刮削路径的定时刮削
+		api.GET("/scrape/records", controllers.GetScrapeRecords)                              // 获取刮削记录
+		api.POST("/scrape/re-scrape", controllers.ReScrape)                                   // 重新刮削记录
+		api.GET("/scrape/review", controllers.GetScrapeReviewRecords)                         // 获取待确认的刮削记录
+		api.POST("/scrape/review/confirm", controllers.ConfirmScrapeReview)                   // 确认待确认记录的识别结果
+		api.POST("/scrape/regenerate-nfo", controllers.RegenerateNfo)                         // 批量重新生成nfo和图片
+		api.GET("/scrape/regenerate-nfo", controllers.GetRegenerateNfoStatus)                 // 获取重新生成nfo和图片的进度
+		api.GET("/scrape/rename-journal/runs", controllers.GetRenameJournalRuns)              // 获取整理批次列表
+		api.GET("/scrape/rename-journal", controllers.GetRenameJournals)                      // 获取整理批次的操作日志
+		api.POST("/scrape/rename-journal/revert", controllers.RevertRenameJournal)            // 撤销整理批次
+		api.GET("/scrape/links", controllers.GetScrapeLinks)                                  // 获取镜像整理的链接列表
+		api.POST("/scrape/links/check", controllers.CheckScrapeLinks)                         // 检查镜像整理的链接
+		api.GET("/scrape/naming-presets", controllers.GetNamingPresets)                       // 获取命名预设
+		api.POST("/scrape/template/check", controllers.CheckNameTemplate)                     // 检查命名模板
+		api.GET("/scrape/missing-episodes", controllers.GetMissingEpisodes)                   // 获取缺集报告
+		api.POST("/scrape/missing-episodes/refresh", controllers.RefreshMissingEpisodes)      // 重新生成缺集报告
+		api.POST("/scrape/missing-episodes/settings", controllers.SaveMissingEpisodeSettings) // 保存缺集通知设置
+		api.POST("/scrape/clear-failed", controllers.ClearFailedScrapeRecords)                // 清除所有刮削失败的记录
+		api.POST("/scrape/truncate-all", controllers.TruncateAllScrapeRecords)                // 一键清空所有刮削记录
+		api.DELETE("/scrape/records", controllers.DeleteScrapeMediaFile)                      // 删除刮削记录
+		api.POST("/scrape/finish", controllers.FinishScrapeMediaFile)                         // 完成刮削记录
+		api.POST("/scrape/rename-failed", controllers.RenameFailedScrapeMediaFile)            // 标记所有失败的记录为待整理
+		api.POST("/scrape/sync-pathes", controllers.SaveScrapeStrmPath)                       // 保存刮削目录关联的同步目录
+		api.GET("/scrape/sync-pathes", controllers.GetScrapeStrmPaths)                        // 获取刮削目录关联的同步目录
+		api.GET("/scrape/tmdb-search", controllers.TmdbSearch)                                // 搜索TMDB媒体
 
 		api.GET("/upload/queue", controllers.UploadList)                                             // 获取上传队列列表
 		api.POST("/upload/queue/clear-pending", controllers.ClearPendingUploadTasks)                 // 清除上传队列中未开始的任务